@@ -0,0 +1,31 @@
+package api
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ActivityDirection string
+
+const (
+	ActivityIn  ActivityDirection = "IN"
+	ActivityOut ActivityDirection = "OUT"
+)
+
+// AccountActivity is an entry of the activity feed of an account, projected from the ledger events.
+type AccountActivity struct {
+	TxID       string            `json:"tx_id"`
+	TransferID string            `json:"transfer_id"`
+	AccountID  string            `json:"account_id"`
+	Currency   string            `json:"currency"`
+	Direction  ActivityDirection `json:"direction"`
+	// CounterpartyID is the other account of the transfer, i.e. the company account for the deposits and withdrawals.
+	CounterpartyID string          `json:"counterparty_id"`
+	Amount         decimal.Decimal `json:"amount"`
+	// RunningBalance is the balance of the account once the entry was posted.
+	RunningBalance decimal.Decimal `json:"running_balance"`
+	Tags           []string        `json:"tags"`
+	Time           time.Time       `json:"time"`
+	Rounding       *RoundingPolicy `json:"rounding,omitempty"`
+}
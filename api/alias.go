@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidAlias  = errors.New("invalid alias")
+	ErrAliasNotFound = errors.New("alias not found")
+	ErrAliasTaken    = errors.New("the alias is registered to another account")
+)
+
+// MaxAliasLength is the maximum length of an alias, long enough for an email address.
+const MaxAliasLength = 254
+
+// AliasKind is what an alias identifies the account by, outside of the wallet.
+type AliasKind string
+
+const (
+	AliasEmail          AliasKind = "EMAIL"
+	AliasCustomerNumber AliasKind = "CUSTOMER_NUMBER"
+	// AliasIBAN is an IBAN-like reference of a bank account.
+	AliasIBAN AliasKind = "IBAN"
+	// AliasReference is any other external identifier, the default.
+	AliasReference AliasKind = "REFERENCE"
+)
+
+// Valid returns whether the kind is one of the known kinds.
+func (k AliasKind) Valid() bool {
+	switch k {
+	case AliasEmail, AliasCustomerNumber, AliasIBAN, AliasReference:
+		return true
+	default:
+		return false
+	}
+}
+
+// RegisterAliasRequest maps an external identifier to the account.
+type RegisterAliasRequest struct {
+	Alias string `json:"alias"`
+	// Kind defaults to REFERENCE.
+	Kind AliasKind `json:"kind,omitempty"`
+}
+
+// AccountAlias is an external identifier resolving to an account, in every currency.
+type AccountAlias struct {
+	Alias     string    `json:"alias"`
+	Kind      AliasKind `json:"kind"`
+	AccountID string    `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NormalizeAlias returns the alias as it's stored and looked up: case-insensitive and without spaces,
+// so "DE89 3704 0044" and "de8937040044" are the same alias.
+func NormalizeAlias(alias string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+
+		return r
+	}, alias))
+}
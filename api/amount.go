@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// MaxAmountDigits is how many significant digits an amount may have, the precision of a NUMERIC(38) column
+	// and of the decimals of the other ledgers the amounts are exported to.
+	MaxAmountDigits = 38
+	// MaxAmountScale is how many decimal places an amount may have, after its trailing zeros.
+	MaxAmountScale = 18
+)
+
+var ErrAmountOutOfRange = errors.New("amount out of range")
+
+// ValidateAmount returns ErrAmountOutOfRange if the amount has more than MaxAmountDigits digits or more than
+// MaxAmountScale decimal places, i.e. 1e400 or 1e-30. The scientific notation is accepted within the range.
+// It never computes with the amount, so a pathological exponent can't make it allocate or spin.
+func ValidateAmount(amount decimal.Decimal) error {
+	coefficient := strings.TrimPrefix(amount.Coefficient().String(), "-")
+	exponent := int64(amount.Exponent())
+
+	// the trailing zeros of the decimals are insignificant, i.e. 1.50000
+	for exponent < 0 && len(coefficient) > 1 && strings.HasSuffix(coefficient, "0") {
+		coefficient = coefficient[:len(coefficient)-1]
+		exponent++
+	}
+
+	if coefficient == "0" {
+		return nil
+	}
+
+	if -exponent > MaxAmountScale {
+		return ErrAmountOutOfRange
+	}
+
+	digits := int64(len(coefficient))
+	if exponent > 0 {
+		digits += exponent
+	}
+
+	if digits > MaxAmountDigits {
+		return ErrAmountOutOfRange
+	}
+
+	return nil
+}
+
+// ParseAmount parses a positive amount from its decimal string, i.e. "10.50", for the callers holding the amounts
+// as strings, so they never build them from floats. It returns ErrInvalidAmount if the string isn't a decimal
+// or the amount is zero, ErrNegativeAmount if it's negative, and ErrAmountOutOfRange, see ValidateAmount.
+func ParseAmount(amount string) (decimal.Decimal, error) {
+	parsed, err := decimal.NewFromString(strings.TrimSpace(amount))
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%w: %w", ErrInvalidAmount, err)
+	}
+
+	switch {
+	case parsed.IsNegative():
+		return decimal.Zero, ErrNegativeAmount
+	case parsed.IsZero():
+		return decimal.Zero, ErrInvalidAmount
+	}
+
+	if err = ValidateAmount(parsed); err != nil {
+		return decimal.Zero, err
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAmount(t *testing.T) {
+	valid := []string{
+		"0.01",
+		"100",
+		"1e2",
+		"1.5E+3",
+		"1.500000000000000000000000000",
+		"12345678901234567890.123456789012345678",
+		"99999999999999999999999999999999999999",
+		"0.000000000000000001",
+		"-250.75",
+		"0e-5000",
+	}
+
+	for _, value := range valid {
+		require.NoError(t, api.ValidateAmount(decimal.RequireFromString(value)), value)
+	}
+
+	outOfRange := []string{
+		"1e38",
+		"123456789012345678901234567890123456789",
+		"1234567890123456789012.12345678901234567",
+		"0.0000000000000000001",
+		"1e-19",
+		"1e2147483647",
+		"1e-2147483648",
+		"-1e400",
+	}
+
+	for _, value := range outOfRange {
+		require.ErrorIs(t, api.ValidateAmount(decimal.RequireFromString(value)), api.ErrAmountOutOfRange, value)
+	}
+}
+
+func FuzzValidateAmount(f *testing.F) {
+	for _, seed := range []string{"10.50", "1e2147483647", "-1e-2147483648", "0.0000000000000000001", "123456789012345678901234567890123456789"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			t.Skip()
+		}
+
+		if api.ValidateAmount(amount) != nil {
+			return
+		}
+
+		// an amount in range is printed, and posted to the NUMERIC columns, within the size of its input
+		printed := amount.String()
+		require.LessOrEqual(t, len(printed), len(value)+api.MaxAmountDigits+2, printed)
+		require.True(t, decimal.RequireFromString(printed).Equal(amount))
+	})
+}
+
+func TestParseAmount(t *testing.T) {
+	for value, expected := range map[string]string{"10.50": "10.5", " 0.01 ": "0.01", "1e2": "100"} {
+		amount, err := api.ParseAmount(value)
+		require.NoError(t, err, value)
+		require.True(t, decimal.RequireFromString(expected).Equal(amount), value)
+	}
+
+	invalid := map[string]error{
+		"":       api.ErrInvalidAmount,
+		"ten":    api.ErrInvalidAmount,
+		"1,000":  api.ErrInvalidAmount,
+		"0":      api.ErrInvalidAmount,
+		"-10.50": api.ErrNegativeAmount,
+		"1e40":   api.ErrAmountOutOfRange,
+		"1e-30":  api.ErrAmountOutOfRange,
+	}
+
+	for value, expected := range invalid {
+		_, err := api.ParseAmount(value)
+		require.ErrorIs(t, err, expected, value)
+	}
+}
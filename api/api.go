@@ -3,6 +3,8 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -22,11 +24,24 @@ var (
 	ErrInsufficientBalance  = errors.New("insufficient balance")
 	ErrTransactionNotFound  = errors.New("transaction not found")
 	ErrDuplicateTransaction = errors.New("duplicate transaction")
+	ErrTransferInProgress   = errors.New("a transfer with the same idempotency key is in progress")
 
 	ErrCompanyAccount = errors.New("cannot use company account")
 
+	ErrParentAlreadySet = errors.New("the account already has another parent")
+	ErrHierarchyCycle   = errors.New("the parent account is a sub-account of the account")
+	ErrOutsideHierarchy = errors.New("a sub-account can only transfer within its account hierarchy")
+
 	ErrMissingIdempotencyKey = errors.New("missing idempotency key")
 
+	ErrInvalidTag = errors.New("invalid tag")
+
+	ErrInvalidRemarks = errors.New("invalid remarks")
+
+	ErrInvalidTransactionFilter = errors.New("invalid transaction filter")
+
+	ErrInvalidTimeZone = errors.New("invalid time zone")
+
 	ErrTransferFailed         = errors.New("transfer failed")
 	ErrFailedToGetTransaction = errors.New("failed to get transaction")
 	ErrIncompleteTransaction  = errors.New("transaction did not complete")
@@ -44,6 +59,8 @@ const (
 )
 
 const (
+	// CompanyAccountID is the default company account, the settlement account funding the deposits
+	// and receiving the withdrawals. A deployment can name another one with COMPANY_ACCOUNT_ID.
 	CompanyAccountID = "company"
 )
 
@@ -51,6 +68,20 @@ type Account struct {
 	AccountID string          `json:"account"`
 	Currency  string          `json:"currency"`
 	Balance   decimal.Decimal `json:"balance"`
+	// only set by the roll-up queries: the sub-accounts holding the currency,
+	// and the balance of the account and all of its sub-accounts
+	Children     []*Account       `json:"children,omitempty"`
+	TotalBalance *decimal.Decimal `json:"total_balance,omitempty"`
+	// the amount reserved by the authorized holds, which the balance can't transfer until they're released,
+	// only set when there's any
+	Held *decimal.Decimal `json:"held,omitempty"`
+	// only set by the stats queries: the number of ledger entries of the account in the currency,
+	// and the times of the first and the last of them, which are nil without any entry
+	TransactionCount *int64     `json:"transaction_count,omitempty"`
+	FirstActivityAt  *time.Time `json:"first_activity_at,omitempty"`
+	LastActivityAt   *time.Time `json:"last_activity_at,omitempty"`
+	// the policy the amounts were rounded with, only set when the currency has one
+	Rounding *RoundingPolicy `json:"rounding,omitempty"`
 }
 
 type Transaction struct {
@@ -60,8 +91,90 @@ type Transaction struct {
 	Amount         decimal.Decimal   `json:"amount"`
 	Currency       string            `json:"currency"`
 	RunningBalance decimal.Decimal   `json:"running_balance"`
-	Remarks        string            `json:"remarks"`
-	Time           string            `json:"time"`
+	// GroupID is the journal id shared by the two legs of the transfer, i.e. its idempotency key.
+	GroupID string `json:"group_id"`
+	// Counterparty is the account of the other leg of the transfer, i.e. the company account for the deposits.
+	Counterparty string `json:"counterparty,omitempty"`
+	// Remarks are the remarks of the entry, or their amendment, see TransactionMetadata.
+	Remarks string `json:"remarks"`
+	// Reference is the reference number amended on the entry, if any.
+	Reference string `json:"reference,omitempty"`
+	// Time is when the entry was posted, in UTC unless rendered in another time zone.
+	Time     time.Time       `json:"time"`
+	Tags     []string        `json:"tags,omitempty"`
+	Rounding *RoundingPolicy `json:"rounding,omitempty"`
+	// FXRate is the rate the cross-currency transfer of the entry was converted at, see TransferRequest.ToCurrency.
+	FXRate *decimal.Decimal `json:"fx_rate,omitempty"`
+	// Fee is the fee charged to the sender on top of the amount, only returned on its debit when the transfer is posted.
+	Fee *Fee `json:"fee,omitempty"`
+}
+
+// TransactionFilter narrows the transactions listing of an account.
+type TransactionFilter struct {
+	// Tags are the tags the transactions must all have.
+	Tags []string
+	// From and To bound the posting times of the transactions, From included and To excluded, unbounded when zero.
+	From time.Time
+	To   time.Time
+	// Type keeps the DEBIT or the CREDIT entries only, both when empty.
+	Type DebitOrCreditType
+}
+
+// IsZero tells whether the filter keeps all of the transactions.
+func (f TransactionFilter) IsZero() bool {
+	return len(f.Tags) == 0 && f.From.IsZero() && f.To.IsZero() && f.Type == ""
+}
+
+// Validate returns ErrInvalidTransactionFilter if the type is unknown or the period is empty.
+// The tags are validated against the taxonomy by the repository.
+func (f TransactionFilter) Validate() error {
+	if f.Type != "" && f.Type != DEBIT && f.Type != CREDIT {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidTransactionFilter, f.Type)
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidTransactionFilter)
+	}
+
+	return nil
+}
+
+// TransactionPage is a page of the transactions of an account, most recent first.
+type TransactionPage struct {
+	Transactions []*Transaction
+	// TotalCount is the number of the transactions matching the filter, across the pages.
+	TotalCount int
+	// NextCursor is the cursor of the next page, empty on the last one.
+	NextCursor string
+}
+
+// LedgerDiscrepancy is an account whose stored balance doesn't match the sum of its ledger entries.
+type LedgerDiscrepancy struct {
+	AccountID     string          `json:"account_id"`
+	Currency      string          `json:"currency"`
+	Balance       decimal.Decimal `json:"balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+}
+
+// BalanceRebuild is the balance of an account recomputed from its ledger entries, replacing the stored balance.
+type BalanceRebuild struct {
+	AccountID       string          `json:"account_id"`
+	Currency        string          `json:"currency"`
+	PreviousBalance decimal.Decimal `json:"previous_balance"`
+	Balance         decimal.Decimal `json:"balance"`
+	// Drift is what the rebuild corrected, the stored balance minus the ledger's. It's zero for a consistent account.
+	Drift     decimal.Decimal `json:"drift"`
+	RebuiltAt time.Time       `json:"rebuilt_at"`
+}
+
+// SetParentRequest makes the account a sub-account of the parent account.
+type SetParentRequest struct {
+	ParentAccountID string `json:"parent_account_id"`
+}
+
+type AccountParent struct {
+	AccountID       string `json:"account_id"`
+	ParentAccountID string `json:"parent_account_id"`
 }
 
 type TransferRequest struct {
@@ -70,6 +183,24 @@ type TransferRequest struct {
 	Amount        decimal.Decimal `json:"amount"`
 	Currency      string          `json:"currency"`
 	Remarks       string          `json:"remarks,omitempty"`
+	// Tags categorize the transfer, i.e. for the spend analytics, see TRANSACTION_TAXONOMY.
+	Tags []string `json:"tags,omitempty"`
+	// FromAlias and ToAlias identify the accounts by their registered alias instead of their id,
+	// they're resolved by the handlers.
+	FromAlias string `json:"from_alias,omitempty"`
+	ToAlias   string `json:"to_alias,omitempty"`
+	// ToCurrency converts the amount to another currency for the recipient, at the rate of the FXRateProvider.
+	// The amount is in the Currency of the sender, the transfer is in a single currency when it's empty.
+	ToCurrency string `json:"to_currency,omitempty"`
+}
+
+// CreditCurrency is the currency the recipient is credited in, ToCurrency for a cross-currency transfer.
+func (t *TransferRequest) CreditCurrency() string {
+	if t.ToCurrency != "" {
+		return t.ToCurrency
+	}
+
+	return t.Currency
 }
 
 type DepositRequest struct {
@@ -77,6 +208,9 @@ type DepositRequest struct {
 	ToAccountID string          `json:"account_id"`
 	Amount      decimal.Decimal `json:"amount"`
 	Remarks     string          `json:"remarks,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	// AccountAlias identifies the account by its registered alias instead of its id.
+	AccountAlias string `json:"account_alias,omitempty"`
 }
 
 type WithdrawRequest struct {
@@ -84,11 +218,34 @@ type WithdrawRequest struct {
 	FromAccountID string          `json:"account_id"`
 	Amount        decimal.Decimal `json:"amount"`
 	Remarks       string          `json:"remarks,omitempty"`
+	Tags          []string        `json:"tags,omitempty"`
+	// AccountAlias identifies the account by its registered alias instead of its id.
+	AccountAlias string `json:"account_alias,omitempty"`
 }
 
 type ErrorResponse struct {
-	ErrorCode int    `json:"error_code"`
-	Message   string `json:"message"`
+	// ErrorCode is the HTTP status code.
+	ErrorCode int `json:"error_code"`
+	// Code identifies the error, the clients should check it rather than the message.
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// GroupID is the group of the transfer already posted with the idempotency key, for a duplicate transaction.
+	GroupID string `json:"group_id,omitempty"`
+	// Index is the transfer failing a batch, see BatchTransferError.
+	Index *int `json:"index,omitempty"`
+}
+
+// DuplicateTransactionError is ErrDuplicateTransaction, identifying the transfer already posted with the idempotency key.
+type DuplicateTransactionError struct {
+	GroupID string
+}
+
+func (e *DuplicateTransactionError) Error() string {
+	return ErrDuplicateTransaction.Error()
+}
+
+func (e *DuplicateTransactionError) Unwrap() error {
+	return ErrDuplicateTransaction
 }
 
 type AccountReader interface {
@@ -100,7 +257,7 @@ type AccountReader interface {
 type AccountOperator interface {
 	Deposit(ctx context.Context, request *DepositRequest, idempotencyKey string) (*Transaction, error)
 	Withdraw(ctx context.Context, request *WithdrawRequest, idempotencyKey string) (*Transaction, error)
-	Transfer(ctx context.Context, request *TransferRequest, idempotencyKey string) (*Transaction, error)
+	Transfer(ctx context.Context, request *TransferRequest, idempotencyKey string) (*TransferReceipt, error)
 }
 
 func OppositeType(t DebitOrCreditType) DebitOrCreditType {
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrTransferPendingApproval = errors.New("transfer is pending approval")
+	ErrPendingTransferNotFound = errors.New("pending transfer not found")
+	ErrPendingTransferDecided  = errors.New("pending transfer was already decided")
+)
+
+// PendingTransfer is a transfer above the approval threshold of its currency,
+// posted once an operator approves it.
+type PendingTransfer struct {
+	ID string `json:"id"`
+	// IdempotencyKey identifies the ledger entries once approved.
+	IdempotencyKey string          `json:"idempotency_key"`
+	FromAccountID  string          `json:"from_account_id"`
+	ToAccountID    string          `json:"to_account_id"`
+	Currency       string          `json:"currency"`
+	Amount         decimal.Decimal `json:"amount"`
+	Remarks        string          `json:"remarks,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Status         ReviewStatus    `json:"status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DecidedAt      *time.Time      `json:"decided_at,omitempty"`
+	// DecidedBy is the operator who approved or rejected it, identified by its admin key.
+	DecidedBy string `json:"decided_by,omitempty"`
+	// ToCurrency is the currency the amount is converted to once approved, see TransferRequest.ToCurrency.
+	ToCurrency string `json:"to_currency,omitempty"`
+}
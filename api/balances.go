@@ -0,0 +1,28 @@
+package api
+
+// MaxBalancesQuery is how many balances a BalancesQuery may read at once.
+const MaxBalancesQuery = 100
+
+// AccountKey is an account in a currency.
+type AccountKey struct {
+	AccountID string `json:"account"`
+	Currency  string `json:"currency"`
+}
+
+// BalancesQuery reads the balances of many accounts at once, i.e. for a dashboard rendering many wallets.
+type BalancesQuery struct {
+	Accounts []AccountKey `json:"accounts"`
+}
+
+// Balances are the balances of a BalancesQuery, in the order of the query without its duplicates.
+// The accounts that don't exist are listed in NotFound instead.
+type Balances struct {
+	Balances []*Account   `json:"balances"`
+	NotFound []AccountKey `json:"not_found"`
+}
+
+// AccountSummary are the balances of an account in every currency it holds, by currency.
+type AccountSummary struct {
+	AccountID string     `json:"account"`
+	Balances  []*Account `json:"balances"`
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxBatchTransfers bounds the transfers of a batch, as they're all posted within a single database transaction.
+const MaxBatchTransfers = 1000
+
+var (
+	ErrInvalidBatch = errors.New("invalid transfer batch")
+	// ErrBatchTransferHeld is a transfer of the batch that the screening or the approval threshold would hold,
+	// which can't be posted apart from the rest of the batch.
+	ErrBatchTransferHeld = errors.New("a transfer of the batch requires a review or an approval, post it on its own")
+)
+
+// TransferBatchRequest are the transfers posted together, i.e. a payroll: either all of them are posted or none is.
+type TransferBatchRequest struct {
+	Transfers []TransferRequest `json:"transfers"`
+}
+
+// TransferBatchReceipt is the response of a batch: the receipts of its transfers, in the order of the request.
+type TransferBatchReceipt struct {
+	// IdempotencyKey is the key the batch was posted with, the group of each transfer is derived from it, see BatchGroupID.
+	IdempotencyKey string             `json:"idempotency_key"`
+	Transfers      []*TransferReceipt `json:"transfers"`
+}
+
+// BatchGroupID is the group of the ledger entries of the transfer at the index of the batch, i.e. payroll-2024-05:12.
+func BatchGroupID(idempotencyKey string, index int) string {
+	return fmt.Sprintf("%s:%d", idempotencyKey, index)
+}
+
+// BatchTransferError is the error of the transfer at the index of the batch, which failed the whole batch.
+type BatchTransferError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchTransferError) Error() string {
+	return fmt.Sprintf("transfer %d of the batch: %v", e.Index, e.Err)
+}
+
+func (e *BatchTransferError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,21 @@
+package api
+
+import "errors"
+
+// ErrInvalidCachePattern is a cache invalidation without any pattern, or with a path pattern not starting with a slash,
+// which could match the other keys of the Redis.
+var ErrInvalidCachePattern = errors.New("invalid cache pattern")
+
+// InvalidateCacheRequest deletes the cached responses matching any of its patterns, before they expire.
+type InvalidateCacheRequest struct {
+	// Paths are Redis glob patterns of the cached request URIs, i.e. /transactions/* or /transactions/tx1*
+	// for a transaction rendered in every time zone.
+	Paths []string `json:"paths,omitempty"`
+	// AccountIDs delete the cached transactions of the accounts.
+	AccountIDs []string `json:"account_ids,omitempty"`
+}
+
+// CacheInvalidation is the number of cached responses deleted.
+type CacheInvalidation struct {
+	Deleted int64 `json:"deleted"`
+}
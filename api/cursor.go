@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransactionEventType is the type of the server-sent events of the transactions subscriptions.
+const TransactionEventType = "transaction"
+
+// LastEventIDHeader is the cursor of the last event received, sent when reconnecting to resume after it.
+const LastEventIDHeader = "Last-Event-ID"
+
+// TransactionStreamContentType is the media type of the transactions streams, newline-delimited JSON,
+// a transaction per line.
+const TransactionStreamContentType = "application/x-ndjson"
+
+// StreamStatusTrailer is the trailer ending the transactions streams, StreamComplete once their last entry is sent.
+// A stream cut short doesn't have it, or has the code of the error instead.
+const StreamStatusTrailer = "X-Stream-Status"
+
+// StreamComplete is the StreamStatusTrailer of the streams sent whole.
+const StreamComplete = "complete"
+
+// TransactionCursor is the position of the ledger entry among the entries of its account,
+// i.e. to resume reading them after it, the oldest or the most recent first. It's opaque to the clients.
+func TransactionCursor(transaction *Transaction) string {
+	return NewTransactionCursor(transaction.Time, transaction.TxID)
+}
+
+// NewTransactionCursor is the position after the entries posted before the time, and the ones posted at the time
+// whose id comes before txID. An empty txID is before all of them.
+func NewTransactionCursor(at time.Time, txID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(at.UnixMicro(), 10) + "/" + txID))
+}
+
+// ParseTransactionCursor returns the time and the id of the cursor, or ErrInvalidCursor.
+func ParseTransactionCursor(cursor string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	micros, txID, found := strings.Cut(string(decoded), "/")
+	if !found {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	at, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return time.UnixMicro(at).UTC(), txID, nil
+}
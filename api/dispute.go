@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrDisputeNotFound = errors.New("dispute not found")
+	ErrDisputeOpen     = errors.New("the transaction already has an open dispute")
+	ErrDisputeResolved = errors.New("dispute was already resolved")
+	ErrDisputedAmount  = errors.New("the disputed amount exceeds the transaction")
+	ErrDisputedDispute = errors.New("cannot dispute the entries of a dispute")
+	ErrNotDisputable   = errors.New("cannot dispute the entries of a conversion or of a fee")
+)
+
+// DisputesAccountID holds the disputed amounts until the disputes are resolved.
+// The transfers can't use it, only the disputes move money in and out of it.
+const DisputesAccountID = "company:disputes"
+
+// DisputeStatus is the state of a dispute, open until it's either reversed or released.
+type DisputeStatus string
+
+const (
+	// DisputeOpen holds the disputed amount on the disputes account.
+	DisputeOpen DisputeStatus = "OPEN"
+	// DisputeReversed returns the disputed amount to the sender, i.e. a chargeback.
+	DisputeReversed DisputeStatus = "REVERSED"
+	// DisputeReleased returns the disputed amount to the recipient.
+	DisputeReleased DisputeStatus = "RELEASED"
+)
+
+// OpenDisputeRequest disputes the transfer of the ledger entry.
+type OpenDisputeRequest struct {
+	TxID string `json:"tx_id"`
+	// Amount defaults to the whole amount of the transfer, less its reversed disputes.
+	Amount decimal.Decimal `json:"amount"`
+	Reason string          `json:"reason,omitempty"`
+}
+
+// Dispute is a chargeback opened against a transfer.
+type Dispute struct {
+	ID string `json:"id"`
+	// TxID is the disputed ledger entry, either side of the transfer.
+	TxID          string          `json:"tx_id"`
+	FromAccountID string          `json:"from_account_id"`
+	ToAccountID   string          `json:"to_account_id"`
+	Currency      string          `json:"currency"`
+	Amount        decimal.Decimal `json:"amount"`
+	Reason        string          `json:"reason,omitempty"`
+	Status        DisputeStatus   `json:"status"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ResolvedAt    *time.Time      `json:"resolved_at,omitempty"`
+	// History is the status changes of the dispute, the oldest first. It's not set by the listings.
+	History []*DisputeEvent `json:"history,omitempty"`
+}
+
+// DisputeEvent is a status change of a dispute, with the ledger entries it posted.
+type DisputeEvent struct {
+	Status DisputeStatus `json:"status"`
+	// IdempotencyKey is the group of the posted ledger entries.
+	IdempotencyKey string    `json:"idempotency_key"`
+	Operator       string    `json:"operator,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
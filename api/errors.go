@@ -0,0 +1,226 @@
+package api
+
+import "errors"
+
+// ErrorCode is the stable, machine-readable identifier of an error in the responses.
+// The messages may be reworded, the codes never change once released.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest              ErrorCode = "INVALID_REQUEST"
+	CodeAccountNotFound             ErrorCode = "ACCOUNT_NOT_FOUND"
+	CodeInvalidAmount               ErrorCode = "INVALID_AMOUNT"
+	CodeInvalidCurrency             ErrorCode = "INVALID_CURRENCY"
+	CodeInvalidAccountID            ErrorCode = "INVALID_ACCOUNT_ID"
+	CodeNegativeAmount              ErrorCode = "NEGATIVE_AMOUNT"
+	CodeSameAccountIDs              ErrorCode = "SAME_ACCOUNT_IDS"
+	CodeInvalidTxID                 ErrorCode = "INVALID_TX_ID"
+	CodeInvalidAccount              ErrorCode = "INVALID_ACCOUNT"
+	CodeInsufficientBalance         ErrorCode = "INSUFFICIENT_BALANCE"
+	CodeTransactionNotFound         ErrorCode = "TRANSACTION_NOT_FOUND"
+	CodeDuplicateTransaction        ErrorCode = "DUPLICATE_TRANSACTION"
+	CodeTransferInProgress          ErrorCode = "TRANSFER_IN_PROGRESS"
+	CodeCompanyAccount              ErrorCode = "COMPANY_ACCOUNT"
+	CodeParentAlreadySet            ErrorCode = "PARENT_ALREADY_SET"
+	CodeHierarchyCycle              ErrorCode = "HIERARCHY_CYCLE"
+	CodeOutsideHierarchy            ErrorCode = "OUTSIDE_HIERARCHY"
+	CodeMissingIdempotencyKey       ErrorCode = "MISSING_IDEMPOTENCY_KEY"
+	CodeInvalidTag                  ErrorCode = "INVALID_TAG"
+	CodeInvalidRemarks              ErrorCode = "INVALID_REMARKS"
+	CodeInvalidTransactionFilter    ErrorCode = "INVALID_TRANSACTION_FILTER"
+	CodeInvalidTimeZone             ErrorCode = "INVALID_TIME_ZONE"
+	CodeInvalidMetadata             ErrorCode = "INVALID_METADATA"
+	CodeTransferFailed              ErrorCode = "TRANSFER_FAILED"
+	CodeFailedToGetTransaction      ErrorCode = "FAILED_TO_GET_TRANSACTION"
+	CodeIncompleteTransaction       ErrorCode = "INCOMPLETE_TRANSACTION"
+	CodeUnexpected                  ErrorCode = "UNEXPECTED"
+	CodeInvalidAlias                ErrorCode = "INVALID_ALIAS"
+	CodeAliasNotFound               ErrorCode = "ALIAS_NOT_FOUND"
+	CodeAliasTaken                  ErrorCode = "ALIAS_TAKEN"
+	CodeTransferPendingApproval     ErrorCode = "TRANSFER_PENDING_APPROVAL"
+	CodePendingTransferNotFound     ErrorCode = "PENDING_TRANSFER_NOT_FOUND"
+	CodePendingTransferDecided      ErrorCode = "PENDING_TRANSFER_DECIDED"
+	CodeDisputeNotFound             ErrorCode = "DISPUTE_NOT_FOUND"
+	CodeDisputeOpen                 ErrorCode = "DISPUTE_OPEN"
+	CodeDisputeResolved             ErrorCode = "DISPUTE_RESOLVED"
+	CodeDisputedAmount              ErrorCode = "DISPUTED_AMOUNT_EXCEEDED"
+	CodeDisputedDispute             ErrorCode = "DISPUTED_DISPUTE"
+	CodeNotDisputable               ErrorCode = "NOT_DISPUTABLE"
+	CodeErasureNotFound             ErrorCode = "ERASURE_NOT_FOUND"
+	CodeProtectedAccount            ErrorCode = "PROTECTED_ACCOUNT"
+	CodeInvalidStatement            ErrorCode = "INVALID_STATEMENT"
+	CodeReconciliationNotFound      ErrorCode = "RECONCILIATION_NOT_FOUND"
+	CodeReconciliationFailed        ErrorCode = "RECONCILIATION_FAILED"
+	CodeSandboxQuotaExceeded        ErrorCode = "SANDBOX_QUOTA_EXCEEDED"
+	CodeTransferUnderReview         ErrorCode = "TRANSFER_UNDER_REVIEW"
+	CodeTransferRejected            ErrorCode = "TRANSFER_REJECTED"
+	CodeScreeningFailed             ErrorCode = "SCREENING_FAILED"
+	CodeReviewNotFound              ErrorCode = "REVIEW_NOT_FOUND"
+	CodeReviewDecided               ErrorCode = "REVIEW_DECIDED"
+	CodeInvalidStatementChannel     ErrorCode = "INVALID_STATEMENT_CHANNEL"
+	CodeInvalidStatementFormat      ErrorCode = "INVALID_STATEMENT_FORMAT"
+	CodeInvalidStatementPeriod      ErrorCode = "INVALID_STATEMENT_PERIOD"
+	CodeSubscriptionNotFound        ErrorCode = "SUBSCRIPTION_NOT_FOUND"
+	CodeInvalidWebhook              ErrorCode = "INVALID_WEBHOOK"
+	CodeWebhookNotFound             ErrorCode = "WEBHOOK_NOT_FOUND"
+	CodeEventNotFound               ErrorCode = "EVENT_NOT_FOUND"
+	CodeInvalidCursor               ErrorCode = "INVALID_CURSOR"
+	CodeAccountNotProvisioned       ErrorCode = "ACCOUNT_NOT_PROVISIONED"
+	CodeAccountRequiresDeposit      ErrorCode = "ACCOUNT_REQUIRES_DEPOSIT"
+	CodeRateLimited                 ErrorCode = "RATE_LIMITED"
+	CodeReadOnly                    ErrorCode = "READ_ONLY"
+	CodeAmountOutOfRange            ErrorCode = "AMOUNT_OUT_OF_RANGE"
+	CodeInvalidGracePeriod          ErrorCode = "INVALID_GRACE_PERIOD"
+	CodeInvalidReceiptDestination   ErrorCode = "INVALID_RECEIPT_DESTINATION"
+	CodeReceiptSubscriptionNotFound ErrorCode = "RECEIPT_SUBSCRIPTION_NOT_FOUND"
+	CodeInvalidCachePattern         ErrorCode = "INVALID_CACHE_PATTERN"
+	CodeInvalidBatch                ErrorCode = "INVALID_BATCH"
+	CodeBatchTransferHeld           ErrorCode = "BATCH_TRANSFER_HELD"
+	CodeHoldNotFound                ErrorCode = "HOLD_NOT_FOUND"
+	CodeHoldClosed                  ErrorCode = "HOLD_CLOSED"
+	CodeHoldRequiresApproval        ErrorCode = "HOLD_REQUIRES_APPROVAL"
+	CodeQueuedTransferNotFound      ErrorCode = "QUEUED_TRANSFER_NOT_FOUND"
+	CodeTransactionReversed         ErrorCode = "TRANSACTION_REVERSED"
+	CodeTransactionDisputed         ErrorCode = "TRANSACTION_DISPUTED"
+	CodeNotReversible               ErrorCode = "NOT_REVERSIBLE"
+	CodeScheduledTransferNotFound   ErrorCode = "SCHEDULED_TRANSFER_NOT_FOUND"
+	CodeScheduledTransferClosed     ErrorCode = "SCHEDULED_TRANSFER_CLOSED"
+	CodeInvalidReportPeriod         ErrorCode = "INVALID_REPORT_PERIOD"
+	CodeUnsupportedCurrencyPair     ErrorCode = "UNSUPPORTED_CURRENCY_PAIR"
+	CodeFXRateUnavailable           ErrorCode = "FX_RATE_UNAVAILABLE"
+	CodeInvalidFeeRule              ErrorCode = "INVALID_FEE_RULE"
+	CodeFeeRuleNotFound             ErrorCode = "FEE_RULE_NOT_FOUND"
+
+	// CodeUnauthorized is a missing or unknown admin key.
+	CodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// CodeUnknown is an error missing from the registry, which is a bug.
+	CodeUnknown ErrorCode = "UNKNOWN"
+)
+
+type registeredError struct {
+	err  error
+	code ErrorCode
+}
+
+// errorRegistry are the codes of the domain errors. An error wrapping several of them gets the code of the first one,
+// so the generic errors, i.e. ErrInvalidRequest, come last.
+//
+//nolint:gochecknoglobals // constant
+var errorRegistry = []registeredError{
+	{ErrInsufficientBalance, CodeInsufficientBalance},
+	{ErrDuplicateTransaction, CodeDuplicateTransaction},
+	{ErrTransferInProgress, CodeTransferInProgress},
+	{ErrSandboxQuotaExceeded, CodeSandboxQuotaExceeded},
+	{ErrAccountNotProvisioned, CodeAccountNotProvisioned},
+	{ErrAccountRequiresDeposit, CodeAccountRequiresDeposit},
+	{ErrTransferUnderReview, CodeTransferUnderReview},
+	{ErrTransferPendingApproval, CodeTransferPendingApproval},
+	{ErrTransferRejected, CodeTransferRejected},
+	{ErrBatchTransferHeld, CodeBatchTransferHeld},
+	{ErrHoldRequiresApproval, CodeHoldRequiresApproval},
+	{ErrHoldClosed, CodeHoldClosed},
+	{ErrTransactionReversed, CodeTransactionReversed},
+	{ErrTransactionDisputed, CodeTransactionDisputed},
+	{ErrNotReversible, CodeNotReversible},
+	{ErrScheduledTransferClosed, CodeScheduledTransferClosed},
+	{ErrUnsupportedCurrencyPair, CodeUnsupportedCurrencyPair},
+	{ErrSameAccountIDs, CodeSameAccountIDs},
+	{ErrCompanyAccount, CodeCompanyAccount},
+	{ErrProtectedAccount, CodeProtectedAccount},
+	{ErrParentAlreadySet, CodeParentAlreadySet},
+	{ErrHierarchyCycle, CodeHierarchyCycle},
+	{ErrOutsideHierarchy, CodeOutsideHierarchy},
+	{ErrMissingIdempotencyKey, CodeMissingIdempotencyKey},
+	{ErrAliasTaken, CodeAliasTaken},
+	{ErrPendingTransferDecided, CodePendingTransferDecided},
+	{ErrReviewDecided, CodeReviewDecided},
+	{ErrDisputeOpen, CodeDisputeOpen},
+	{ErrDisputeResolved, CodeDisputeResolved},
+	{ErrDisputedAmount, CodeDisputedAmount},
+	{ErrDisputedDispute, CodeDisputedDispute},
+	{ErrNotDisputable, CodeNotDisputable},
+	{ErrAccountNotFound, CodeAccountNotFound},
+	{ErrTransactionNotFound, CodeTransactionNotFound},
+	{ErrAliasNotFound, CodeAliasNotFound},
+	{ErrPendingTransferNotFound, CodePendingTransferNotFound},
+	{ErrReviewNotFound, CodeReviewNotFound},
+	{ErrDisputeNotFound, CodeDisputeNotFound},
+	{ErrErasureNotFound, CodeErasureNotFound},
+	{ErrReconciliationNotFound, CodeReconciliationNotFound},
+	{ErrSubscriptionNotFound, CodeSubscriptionNotFound},
+	{ErrReceiptSubscriptionNotFound, CodeReceiptSubscriptionNotFound},
+	{ErrWebhookNotFound, CodeWebhookNotFound},
+	{ErrEventNotFound, CodeEventNotFound},
+	{ErrHoldNotFound, CodeHoldNotFound},
+	{ErrQueuedTransferNotFound, CodeQueuedTransferNotFound},
+	{ErrScheduledTransferNotFound, CodeScheduledTransferNotFound},
+	{ErrFeeRuleNotFound, CodeFeeRuleNotFound},
+	{ErrNegativeAmount, CodeNegativeAmount},
+	{ErrAmountOutOfRange, CodeAmountOutOfRange},
+	{ErrInvalidAmount, CodeInvalidAmount},
+	{ErrInvalidCurrency, CodeInvalidCurrency},
+	{ErrInvalidAccountID, CodeInvalidAccountID},
+	{ErrInvalidAccount, CodeInvalidAccount},
+	{ErrInvalidTxID, CodeInvalidTxID},
+	{ErrInvalidTag, CodeInvalidTag},
+	{ErrInvalidRemarks, CodeInvalidRemarks},
+	{ErrInvalidTransactionFilter, CodeInvalidTransactionFilter},
+	{ErrInvalidTimeZone, CodeInvalidTimeZone},
+	{ErrInvalidMetadata, CodeInvalidMetadata},
+	{ErrInvalidAlias, CodeInvalidAlias},
+	{ErrInvalidStatement, CodeInvalidStatement},
+	{ErrInvalidStatementChannel, CodeInvalidStatementChannel},
+	{ErrInvalidStatementFormat, CodeInvalidStatementFormat},
+	{ErrInvalidStatementPeriod, CodeInvalidStatementPeriod},
+	{ErrInvalidReportPeriod, CodeInvalidReportPeriod},
+	{ErrInvalidWebhook, CodeInvalidWebhook},
+	{ErrInvalidGracePeriod, CodeInvalidGracePeriod},
+	{ErrInvalidReceiptDestination, CodeInvalidReceiptDestination},
+	{ErrInvalidCursor, CodeInvalidCursor},
+	{ErrInvalidCachePattern, CodeInvalidCachePattern},
+	{ErrInvalidBatch, CodeInvalidBatch},
+	{ErrInvalidFeeRule, CodeInvalidFeeRule},
+	{ErrInvalidRequest, CodeInvalidRequest},
+	{ErrRateLimited, CodeRateLimited},
+	{ErrReadOnly, CodeReadOnly},
+	{ErrScreeningFailed, CodeScreeningFailed},
+	{ErrFXRateUnavailable, CodeFXRateUnavailable},
+	{ErrReconciliationFailed, CodeReconciliationFailed},
+	{ErrTransferFailed, CodeTransferFailed},
+	{ErrFailedToGetTransaction, CodeFailedToGetTransaction},
+	{ErrIncompleteTransaction, CodeIncompleteTransaction},
+	{ErrUnexpected, CodeUnexpected},
+}
+
+// CodeOf returns the code of the domain error, or CodeUnknown.
+func CodeOf(err error) ErrorCode {
+	for _, registered := range errorRegistry {
+		if errors.Is(err, registered.err) {
+			return registered.code
+		}
+	}
+
+	return CodeUnknown
+}
+
+// ErrorOf returns the domain error of the code, i.e. for the clients to check it with errors.Is, or nil if it's unknown.
+func ErrorOf(code ErrorCode) error {
+	for _, registered := range errorRegistry {
+		if registered.code == code {
+			return registered.err
+		}
+	}
+
+	return nil
+}
+
+// ErrorCodes returns the codes of the error responses, the registered ones then CodeUnauthorized and CodeUnknown.
+func ErrorCodes() []ErrorCode {
+	codes := make([]ErrorCode, 0, len(errorRegistry)+2)
+
+	for _, registered := range errorRegistry {
+		codes = append(codes, registered.code)
+	}
+
+	return append(codes, CodeUnauthorized, CodeUnknown)
+}
@@ -0,0 +1,20 @@
+package api_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCodes(t *testing.T) {
+	require.Equal(t, api.CodeInsufficientBalance, api.CodeOf(api.ErrInsufficientBalance))
+	require.Equal(t, api.CodeDuplicateTransaction, api.CodeOf(fmt.Errorf("transfer: %w", api.ErrDuplicateTransaction)), "wrapped")
+	require.Equal(t, api.CodeInvalidTag, api.CodeOf(fmt.Errorf("%w: %w", api.ErrInvalidRequest, api.ErrInvalidTag)), "the specific one first")
+	require.Equal(t, api.CodeUnknown, api.CodeOf(errors.New("unregistered")))
+
+	require.Equal(t, api.ErrSandboxQuotaExceeded, api.ErrorOf(api.CodeSandboxQuotaExceeded))
+	require.NoError(t, api.ErrorOf("NOT_A_CODE"))
+}
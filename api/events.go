@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrEventNotFound = errors.New("event not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// The types of the ledger events, published to the topic or subject of the same name after the prefix,
+// i.e. wallet.transfer.created.
+const (
+	EventTransferCreated = "transfer.created"
+	EventAccountCreated  = "account.created"
+)
+
+// The schema versions of the event data. A version is only bumped on a breaking change,
+// the consumers must ignore the fields they don't know.
+const (
+	TransferCreatedVersion = 1
+	AccountCreatedVersion  = 1
+)
+
+// Event is the envelope of every ledger event.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	// Key orders the events of the same account, i.e. the Kafka partition key.
+	Key  string          `json:"key"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// TransferCreated is the data of the transfer.created event, emitted for every committed double entry,
+// including the deposits and withdrawals which are transfers with the company account.
+type TransferCreated struct {
+	// TransferID is the idempotency key of the transfer, shared by both entries.
+	TransferID    string          `json:"transfer_id"`
+	DebitTxID     string          `json:"debit_tx_id"`
+	CreditTxID    string          `json:"credit_tx_id"`
+	FromAccountID string          `json:"from_account_id"`
+	ToAccountID   string          `json:"to_account_id"`
+	Currency      string          `json:"currency"`
+	Amount        decimal.Decimal `json:"amount"`
+	Remarks       string          `json:"remarks,omitempty"`
+	Tags          []string        `json:"tags,omitempty"`
+}
+
+// AccountCreated is the data of the account.created event, emitted the first time an account receives or sends a currency.
+type AccountCreated struct {
+	AccountID string `json:"account_id"`
+	Currency  string `json:"currency"`
+}
+
+// LoggedEvent is an event of the outbox, with its delivery status.
+type LoggedEvent struct {
+	Event
+	// Cursor is the position of the event in the log, the next page starts after it.
+	Cursor string `json:"cursor"`
+	// PublishedAt is the last delivery, not set while the event is pending.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// EventLog is a page of the events of the outbox, the oldest first.
+type EventLog struct {
+	Events []*LoggedEvent `json:"events"`
+	// NextCursor is the cursor of the last event of the page, passed as since to get the next page.
+	// It's the cursor given when the page is empty, so the consumers can poll with it.
+	NextCursor string `json:"next_cursor"`
+}
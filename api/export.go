@@ -0,0 +1,19 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidEvent is an event of the ledger export that can't be restored.
+var ErrInvalidEvent = errors.New("invalid ledger event")
+
+// LedgerFilter selects the transfers of the ledger export.
+type LedgerFilter struct {
+	// AccountID selects the transfers from or to the account, all of them when empty.
+	AccountID string
+	// Currency selects the transfers in the currency, all of them when empty.
+	Currency string
+	// Until selects the transfers posted at or before it, a point in time of the ledger.
+	Until time.Time
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidFeeRule  = errors.New("invalid fee rule")
+	ErrFeeRuleNotFound = errors.New("fee rule not found")
+)
+
+// FeesAccountID collects the fees charged to the senders. The transfers can't use it, only the fees move money into it.
+const FeesAccountID = "company:fees"
+
+// FeeOperation is the kind of transfer a fee rule is charged on. The deposits are never charged.
+type FeeOperation string
+
+const (
+	FeeTransfer   FeeOperation = "TRANSFER"
+	FeeWithdrawal FeeOperation = "WITHDRAWAL"
+)
+
+// ParseFeeOperation parses the operation case-insensitively, i.e. from a path.
+func ParseFeeOperation(value string) (FeeOperation, error) {
+	switch operation := FeeOperation(strings.ToUpper(strings.TrimSpace(value))); operation {
+	case FeeTransfer, FeeWithdrawal:
+		return operation, nil
+	default:
+		return "", ErrInvalidFeeRule
+	}
+}
+
+// SetFeeRuleRequest sets the fee of an operation in a currency, i.e. 0.30 plus 1.5 percent of the amount.
+type SetFeeRuleRequest struct {
+	Flat decimal.Decimal `json:"flat"`
+	// Percentage of the amount, from 0 to 100.
+	Percentage decimal.Decimal `json:"percentage"`
+}
+
+// FeeRule is the fee charged on an operation in a currency.
+type FeeRule struct {
+	Operation  FeeOperation    `json:"operation"`
+	Currency   string          `json:"currency"`
+	Flat       decimal.Decimal `json:"flat"`
+	Percentage decimal.Decimal `json:"percentage"`
+	UpdatedBy  string          `json:"updated_by"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Fee is the breakdown of the fee charged to the sender of a transfer, posted as its own double entry
+// from the sender to the FeesAccountID, grouped under FeeGroupID.
+type Fee struct {
+	// TxID is the ledger entry debiting the sender.
+	TxID     string          `json:"tx_id"`
+	Currency string          `json:"currency"`
+	Amount   decimal.Decimal `json:"amount"`
+	// Flat and Percentage are the rule the fee was computed with.
+	Flat       decimal.Decimal `json:"flat"`
+	Percentage decimal.Decimal `json:"percentage"`
+}
+
+// FeeGroupID is the group of the ledger entries charging the fee of the transfer grouped under the idempotency key.
+func FeeGroupID(groupID string) string {
+	return "fee:" + groupID
+}
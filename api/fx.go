@@ -0,0 +1,27 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnsupportedCurrencyPair = errors.New("unsupported currency pair")
+	ErrFXRateUnavailable       = errors.New("fx rate unavailable")
+)
+
+// FXRateProvider quotes the rates of the cross-currency transfers, see TransferRequest.ToCurrency.
+// It returns ErrUnsupportedCurrencyPair for a pair it doesn't quote, any other error fails the transfer
+// with ErrFXRateUnavailable, so a provider that can't be reached never converts at a stale rate.
+type FXRateProvider interface {
+	// Rate is how much of the currency to is bought by one unit of the currency from.
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// FXGroupID is the group of the ledger entries crediting the recipient of the cross-currency transfer, in the currency
+// it's converted to. The ledger entries debiting the sender are grouped under the idempotency key, like any transfer.
+func FXGroupID(groupID string) string {
+	return "fx:" + groupID
+}
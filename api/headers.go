@@ -0,0 +1,22 @@
+package api
+
+import "errors"
+
+// ErrRateLimited is a request above the rate limit of the client, retried once the window resets.
+var ErrRateLimited = errors.New("too many requests, retry once the rate limit resets")
+
+// The metadata of the lists and of the rate limit, set in the response headers, so the clients don't parse the payloads
+// to page through them or to back off.
+const (
+	// TotalCountHeader is the number of items of a list returned whole, i.e. the aliases of an account,
+	// or across the pages of the transactions. The other lists capped by a limit don't know their total, so they don't set it.
+	TotalCountHeader = "X-Total-Count"
+	// NextCursorHeader is the cursor of the next page of a list read by cursor, i.e. the event log.
+	NextCursorHeader = "X-Next-Cursor"
+	// RateLimitLimitHeader is the number of requests allowed in a window.
+	RateLimitLimitHeader = "X-RateLimit-Limit"
+	// RateLimitRemainingHeader is the number of requests left in the current window.
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	// RateLimitResetHeader is when the current window ends, in unix seconds.
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
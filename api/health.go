@@ -0,0 +1,13 @@
+package api
+
+const (
+	ReadinessReady    = "ready"
+	ReadinessDegraded = "degraded"
+)
+
+// Readiness is the status of the service, degraded when a dependency it can do without, i.e. the cache, is down.
+type Readiness struct {
+	Status string `json:"status"`
+	// Degraded are the names of the dependencies down, if any.
+	Degraded []string `json:"degraded,omitempty"`
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrHoldNotFound = errors.New("hold not found")
+	ErrHoldClosed   = errors.New("the hold was already captured or released")
+	// ErrHoldRequiresApproval is a hold the screening or the approval threshold would hold as a transfer,
+	// which can't be captured without a decision.
+	ErrHoldRequiresApproval = errors.New("the transfer requires a review or an approval, post it instead of holding it")
+)
+
+// HoldStatus is the state of a hold, it's closed once captured or released.
+type HoldStatus string
+
+const (
+	// HoldAuthorized reserves the amount on the sender, it's not available to the other transfers.
+	HoldAuthorized HoldStatus = "AUTHORIZED"
+	// HoldCaptured posted the transfer.
+	HoldCaptured HoldStatus = "CAPTURED"
+	// HoldReleased gave the amount back to the sender, without posting anything.
+	HoldReleased HoldStatus = "RELEASED"
+)
+
+// Hold is an authorized transfer, i.e. a card authorization: its amount is reserved on the sender,
+// without any ledger entry, until it's captured as a transfer or released.
+type Hold struct {
+	ID string `json:"id"`
+	// IdempotencyKey is the key the hold was placed with, it's the group of the ledger entries once captured.
+	IdempotencyKey string          `json:"idempotency_key"`
+	FromAccountID  string          `json:"from_account_id"`
+	ToAccountID    string          `json:"to_account_id"`
+	Currency       string          `json:"currency"`
+	Amount         decimal.Decimal `json:"amount"`
+	Remarks        string          `json:"remarks,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Status         HoldStatus      `json:"status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// ClosedAt is when the hold was captured or released.
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	// Fee is the fee quoted when the hold was placed, reserved along with the amount and charged once it's captured,
+	// whatever the rule then. Its TxID is only set on the receipt of the capture.
+	Fee *Fee `json:"fee,omitempty"`
+}
+
+// Transfer is the transfer the hold is captured as.
+func (h *Hold) Transfer() *TransferRequest {
+	return &TransferRequest{
+		FromAccountID: h.FromAccountID,
+		ToAccountID:   h.ToAccountID,
+		Currency:      h.Currency,
+		Amount:        h.Amount,
+		Remarks:       h.Remarks,
+		Tags:          h.Tags,
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+)
+
+// IdempotencyConflict is a transfer rejected because its idempotency key was already used,
+// recorded for the partners to debug their retries, see GET /admin/idempotency-conflicts.
+type IdempotencyConflict struct {
+	ID             string `json:"id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	// Code is DUPLICATE_TRANSACTION once the transfer of the key is posted, or TRANSFER_IN_PROGRESS.
+	Code ErrorCode `json:"code"`
+	// Fingerprint identifies the content of the conflicting request, see TransferRequest.Fingerprint and BatchFingerprint,
+	// so a retry of the same request can be told from another request reusing the key.
+	Fingerprint string `json:"fingerprint"`
+	ClientIP    string `json:"client_ip"`
+	// APIKeyID is the fingerprint of the API key presented by the client, never the key itself.
+	APIKeyID  string `json:"api_key_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// OriginalCreatedAt is when the transfer of the key was posted, unset while it's in progress.
+	OriginalCreatedAt *time.Time `json:"original_created_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// Fingerprint is the SHA-256 of the accounts, the currencies, the amount, the remarks and the tags of the request,
+// as they're posted, so the retries of a request have the same fingerprint.
+func (r *TransferRequest) Fingerprint() string {
+	tags := make([]string, 0, len(r.Tags))
+	for _, tag := range r.Tags {
+		tags = append(tags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	slices.Sort(tags)
+
+	fields := []any{
+		strings.TrimSpace(r.FromAccountID),
+		strings.TrimSpace(r.ToAccountID),
+		strings.ToUpper(strings.TrimSpace(r.Currency)),
+		r.Amount.String(),
+		strings.TrimSpace(r.Remarks),
+		slices.Compact(tags),
+	}
+
+	// only the conversions add the currency, so the fingerprints of the other transfers never change
+	if toCurrency := strings.ToUpper(strings.TrimSpace(r.ToCurrency)); toCurrency != "" {
+		fields = append(fields, toCurrency)
+	}
+
+	// marshaling strings can't fail
+	canonical, _ := json.Marshal(fields)
+
+	digest := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(digest[:])
+}
+
+// BatchFingerprint is the SHA-256 of the fingerprints of the transfers of the batch, in their order.
+func BatchFingerprint(requests []*TransferRequest) string {
+	fingerprints := make([]string, 0, len(requests))
+	for _, request := range requests {
+		fingerprints = append(fingerprints, request.Fingerprint())
+	}
+
+	// marshaling strings can't fail
+	canonical, _ := json.Marshal(fingerprints)
+
+	digest := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(digest[:])
+}
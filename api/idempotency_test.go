@@ -0,0 +1,49 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferRequestFingerprint(t *testing.T) {
+	request := &api.TransferRequest{
+		FromAccountID: "user1",
+		ToAccountID:   "user2",
+		Currency:      "USD",
+		Amount:        decimal.RequireFromString("10.50"),
+		Tags:          []string{"rent", "food"},
+	}
+
+	retry := &api.TransferRequest{
+		FromAccountID: " user1",
+		ToAccountID:   "user2 ",
+		Currency:      "usd",
+		Amount:        decimal.RequireFromString("10.5"),
+		Tags:          []string{"Food", "rent", "food"},
+	}
+
+	require.Len(t, request.Fingerprint(), 64)
+	require.Equal(t, request.Fingerprint(), retry.Fingerprint(), "the same request as posted")
+
+	other := *request
+	other.Amount = decimal.NewFromInt(11)
+	require.NotEqual(t, request.Fingerprint(), other.Fingerprint())
+
+	swapped := *request
+	swapped.FromAccountID, swapped.ToAccountID = request.ToAccountID, request.FromAccountID
+	require.NotEqual(t, request.Fingerprint(), swapped.Fingerprint())
+}
+
+func TestBatchFingerprint(t *testing.T) {
+	first := &api.TransferRequest{FromAccountID: "acme", ToAccountID: "user1", Currency: "USD", Amount: decimal.NewFromInt(1200)}
+	second := &api.TransferRequest{FromAccountID: "acme", ToAccountID: "user2", Currency: "USD", Amount: decimal.NewFromInt(900)}
+
+	fingerprint := api.BatchFingerprint([]*api.TransferRequest{first, second})
+	require.Len(t, fingerprint, 64)
+	require.Equal(t, fingerprint, api.BatchFingerprint([]*api.TransferRequest{first, second}))
+	require.NotEqual(t, fingerprint, api.BatchFingerprint([]*api.TransferRequest{second, first}), "the order of the transfers matters")
+	require.NotEqual(t, first.Fingerprint(), api.BatchFingerprint([]*api.TransferRequest{first}))
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidMetadata = errors.New("the remarks and the reference are limited to 255 characters")
+
+// the amendable fields of the ledger entries
+const (
+	MetadataRemarks   = "remarks"
+	MetadataReference = "reference"
+)
+
+// AmendMetadataRequest amends the non-financial fields of a ledger entry, the fields left out are kept as they are.
+type AmendMetadataRequest struct {
+	Remarks   *string `json:"remarks,omitempty"`
+	Reference *string `json:"reference,omitempty"`
+}
+
+// TransactionMetadata is the non-financial fields of a ledger entry, as amended, and the history of their amendments.
+type TransactionMetadata struct {
+	TxID      string `json:"tx_id"`
+	Remarks   string `json:"remarks"`
+	Reference string `json:"reference,omitempty"`
+	// UpdatedAt is the last amendment, not set until the entry is first amended.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// Edits are the amendments, the oldest first.
+	Edits []*MetadataEdit `json:"edits"`
+}
+
+// MetadataEdit is the amendment of a field of a ledger entry by an operator.
+type MetadataEdit struct {
+	Field     string    `json:"field"`
+	Previous  string    `json:"previous"`
+	Value     string    `json:"value"`
+	Operator  string    `json:"operator"`
+	CreatedAt time.Time `json:"created_at"`
+}
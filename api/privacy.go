@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrErasureNotFound = errors.New("erasure not found")
+	// ErrProtectedAccount is an account of the company, which holds no personal data.
+	ErrProtectedAccount = errors.New("the account can't be erased")
+)
+
+type ErasureStatus string
+
+const (
+	// ErasurePending is an erasure whose key is shredded, its other data are being removed.
+	ErasurePending   ErasureStatus = "PENDING"
+	ErasureCompleted ErasureStatus = "COMPLETED"
+)
+
+// ErasureRequest is a data subject request to erase the personal data of an account.
+// The ledger amounts are kept, only the remarks and the metadata of the account are erased.
+type ErasureRequest struct {
+	ID          string        `json:"id"`
+	AccountID   string        `json:"account_id"`
+	Status      ErasureStatus `json:"status"`
+	RequestedBy string        `json:"requested_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+// PersonalData is everything the wallet keeps about an account, for a data subject access request.
+type PersonalData struct {
+	AccountID              string                   `json:"account_id"`
+	ExportedAt             time.Time                `json:"exported_at"`
+	Balances               []*Account               `json:"balances"`
+	Transactions           []*Transaction           `json:"transactions"`
+	Aliases                []*AccountAlias          `json:"aliases"`
+	StatementSubscriptions []*StatementSubscription `json:"statement_subscriptions"`
+	ReceiptSubscriptions   []*ReceiptSubscription   `json:"receipt_subscriptions"`
+	// Holds are the holds placed or received by the account, whatever their status.
+	Holds []*Hold `json:"holds"`
+	// QueuedTransfers are the transfers queued by or to the account, whatever their status.
+	QueuedTransfers []*QueuedTransfer `json:"queued_transfers"`
+	// ScheduledTransfers are the transfers scheduled by or to the account, whatever their status.
+	ScheduledTransfers []*ScheduledTransfer `json:"scheduled_transfers"`
+	// Erasure is the latest erasure of the account, if any.
+	Erasure *ErasureRequest `json:"erasure,omitempty"`
+}
@@ -0,0 +1,28 @@
+package api
+
+import "errors"
+
+var (
+	// ErrAccountNotProvisioned is a transfer involving an account that must be opened first, see ProvisionStrict.
+	ErrAccountNotProvisioned = errors.New("the account is not opened in the currency")
+	// ErrAccountRequiresDeposit is a transfer to or from an account that only a deposit can open, see ProvisionDepositOnly.
+	ErrAccountRequiresDeposit = errors.New("the account can only be opened by a deposit")
+)
+
+// ProvisioningPolicy is how the accounts are opened in a currency, on their first transfer or beforehand.
+// The company account is always opened on its first transfer, since it funds the deposits.
+type ProvisioningPolicy string
+
+const (
+	// ProvisionAuto opens both accounts of a transfer as needed.
+	ProvisionAuto ProvisioningPolicy = "auto"
+	// ProvisionDepositOnly only opens the accounts funded by the company account, i.e. with a deposit.
+	ProvisionDepositOnly ProvisioningPolicy = "deposit-only"
+	// ProvisionStrict never opens an account on a transfer, it must be opened beforehand.
+	ProvisionStrict ProvisioningPolicy = "strict"
+)
+
+// Valid reports whether the policy is known.
+func (p ProvisioningPolicy) Valid() bool {
+	return p == ProvisionAuto || p == ProvisionDepositOnly || p == ProvisionStrict
+}
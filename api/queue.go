@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrQueuedTransferNotFound = errors.New("queued transfer not found")
+
+// QueuedTransferStatus is the state of a transfer posted asynchronously.
+type QueuedTransferStatus string
+
+const (
+	// QueuedTransferQueued is waiting for a worker to post it.
+	QueuedTransferQueued QueuedTransferStatus = "QUEUED"
+	// QueuedTransferCompleted was posted, its ledger entries are grouped under its idempotency key.
+	QueuedTransferCompleted QueuedTransferStatus = "COMPLETED"
+	// QueuedTransferHeld was held for a review or an approval, it's posted under its idempotency key once approved.
+	QueuedTransferHeld QueuedTransferStatus = "HELD"
+	// QueuedTransferFailed was rejected, i.e. for an insufficient balance, the error tells why.
+	QueuedTransferFailed QueuedTransferStatus = "FAILED"
+)
+
+// QueuedTransfer is a transfer accepted to be posted asynchronously, its status is polled until it's processed.
+type QueuedTransfer struct {
+	ID string `json:"id"`
+	// IdempotencyKey is the key the transfer was queued with, the group of its ledger entries once posted.
+	IdempotencyKey string               `json:"idempotency_key"`
+	FromAccountID  string               `json:"from_account_id"`
+	ToAccountID    string               `json:"to_account_id"`
+	Currency       string               `json:"currency"`
+	Amount         decimal.Decimal      `json:"amount"`
+	Remarks        string               `json:"remarks,omitempty"`
+	Tags           []string             `json:"tags,omitempty"`
+	Status         QueuedTransferStatus `json:"status"`
+	// Code and Error are the error failing or holding the transfer.
+	Code      ErrorCode `json:"code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ProcessedAt is when a worker posted, held or failed the transfer.
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
+
+// Transfer is the transfer the worker posts.
+func (q *QueuedTransfer) Transfer() *TransferRequest {
+	return &TransferRequest{
+		FromAccountID: q.FromAccountID,
+		ToAccountID:   q.ToAccountID,
+		Currency:      q.Currency,
+		Amount:        q.Amount,
+		Remarks:       q.Remarks,
+		Tags:          q.Tags,
+	}
+}
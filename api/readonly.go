@@ -0,0 +1,18 @@
+package api
+
+import "errors"
+
+// ErrReadOnly is a mutation rejected while the wallet is read-only, i.e. during a failover, retried once it's writable.
+var ErrReadOnly = errors.New("the wallet is read-only, retry later")
+
+// ReadOnlyStatus is whether the mutations are rejected.
+type ReadOnlyStatus struct {
+	Enabled bool `json:"enabled"`
+	// Forced is the READ_ONLY setting of the server, which the operators can't turn off.
+	Forced bool `json:"forced"`
+}
+
+// SetReadOnlyRequest switches the wallet to read-only, or back to writable.
+type SetReadOnlyRequest struct {
+	Enabled bool `json:"enabled"`
+}
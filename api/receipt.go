@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidReceiptDestination   = errors.New("invalid receipt destination")
+	ErrReceiptSubscriptionNotFound = errors.New("receipt subscription not found")
+)
+
+// TransferReceipt is the response of a transfer: both legs of its double entry, and the request they were posted for.
+type TransferReceipt struct {
+	// GroupID ties the two legs together, it's the idempotency key of the transfer.
+	GroupID string `json:"group_id"`
+	// Debit is the leg of the sender, and Credit the leg of the recipient.
+	Debit  *Transaction `json:"debit"`
+	Credit *Transaction `json:"credit"`
+	// Request echoes the transfer as posted, i.e. with the aliases resolved to the account ids.
+	Request   TransferRequest `json:"request"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewTransferReceipt pairs the ledger entries of the transfer, or returns ErrIncompleteTransaction if a leg is missing.
+func NewTransferReceipt(request *TransferRequest, groupID string, entries []*Transaction) (*TransferReceipt, error) {
+	receipt := &TransferReceipt{
+		GroupID: groupID,
+		Request: *request,
+	}
+
+	for _, entry := range entries {
+		switch entry.Type {
+		case DEBIT:
+			receipt.Debit = entry
+		case CREDIT:
+			receipt.Credit = entry
+		}
+	}
+
+	if receipt.Debit == nil || receipt.Credit == nil {
+		return nil, ErrIncompleteTransaction
+	}
+
+	// both legs are posted at once
+	receipt.CreatedAt = receipt.Debit.Time
+
+	return receipt, nil
+}
+
+// ReceiptKind is what a receipt notification confirms, from the point of view of its account.
+type ReceiptKind string
+
+const (
+	ReceiptDeposit    ReceiptKind = "DEPOSIT"
+	ReceiptWithdrawal ReceiptKind = "WITHDRAWAL"
+	// ReceiptTransferSent confirms a transfer to another account, and ReceiptTransferReceived one from another account.
+	ReceiptTransferSent     ReceiptKind = "TRANSFER_SENT"
+	ReceiptTransferReceived ReceiptKind = "TRANSFER_RECEIVED"
+)
+
+// ReceiptStatus is the delivery state of a receipt notification.
+type ReceiptStatus string
+
+const (
+	// ReceiptPending is waiting for its first or next delivery attempt.
+	ReceiptPending ReceiptStatus = "PENDING"
+	ReceiptSent    ReceiptStatus = "SENT"
+	// ReceiptFailed has exhausted its delivery attempts.
+	ReceiptFailed ReceiptStatus = "FAILED"
+)
+
+// SubscribeReceiptsRequest subscribes the account to the receipts of its deposits, withdrawals and transfers, in every currency.
+type SubscribeReceiptsRequest struct {
+	AccountID string `json:"account_id"`
+	// Destination is the email address the receipts are sent to.
+	Destination string `json:"destination"`
+}
+
+type ReceiptSubscription struct {
+	ID          string    `json:"id"`
+	AccountID   string    `json:"account_id"`
+	Destination string    `json:"destination"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReceiptNotification is the receipt of a ledger entry sent to a subscription, with its delivery status.
+type ReceiptNotification struct {
+	ID          string      `json:"id"`
+	TxID        string      `json:"tx_id"`
+	TransferID  string      `json:"transfer_id"`
+	Kind        ReceiptKind `json:"kind"`
+	AccountID   string      `json:"account_id"`
+	Destination string      `json:"destination"`
+	// CounterpartyID is the other account of the transfer, the company account for the deposits and withdrawals.
+	CounterpartyID string          `json:"counterparty_id"`
+	Currency       string          `json:"currency"`
+	Amount         decimal.Decimal `json:"amount"`
+	Status         ReceiptStatus   `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	SentAt         *time.Time      `json:"sent_at,omitempty"`
+	// CreatedAt is the time of the transfer.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewReceiptKind returns the kind of the receipt of the debit, or of the credit, of the transfer between the accounts.
+func NewReceiptKind(debit bool, fromAccountID, toAccountID, companyAccountID string) ReceiptKind {
+	switch {
+	case debit && toAccountID == companyAccountID:
+		return ReceiptWithdrawal
+	case debit:
+		return ReceiptTransferSent
+	case fromAccountID == companyAccountID:
+		return ReceiptDeposit
+	default:
+		return ReceiptTransferReceived
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidStatement       = errors.New("invalid statement")
+	ErrReconciliationNotFound = errors.New("reconciliation not found")
+	ErrReconciliationFailed   = errors.New("reconciliation failed")
+)
+
+// ReconciliationStatus is the outcome of an entry of a reconciliation.
+type ReconciliationStatus string
+
+const (
+	// Matched is a statement entry matching a ledger entry of the company account.
+	Matched ReconciliationStatus = "MATCHED"
+	// UnmatchedStatement is a statement entry without a ledger entry, i.e. a deposit never credited.
+	UnmatchedStatement ReconciliationStatus = "UNMATCHED_STATEMENT"
+	// UnmatchedLedger is a ledger entry of the period without a statement entry, i.e. a withdrawal never paid out.
+	UnmatchedLedger ReconciliationStatus = "UNMATCHED_LEDGER"
+)
+
+// Reconciliation is the report of an external statement matched against the company account ledger.
+type Reconciliation struct {
+	ID       string `json:"id"`
+	Format   string `json:"format"`
+	FileName string `json:"file_name,omitempty"`
+	Currency string `json:"currency"`
+	// the booking dates of the statement entries
+	PeriodStart        time.Time `json:"period_start"`
+	PeriodEnd          time.Time `json:"period_end"`
+	Matched            int       `json:"matched"`
+	UnmatchedStatement int       `json:"unmatched_statement"`
+	UnmatchedLedger    int       `json:"unmatched_ledger"`
+	CreatedAt          time.Time `json:"created_at"`
+	// only returned with a single reconciliation
+	Items []*ReconciliationItem `json:"items,omitempty"`
+}
+
+// ReconciliationItem is a statement entry and its ledger entry, either of them missing when unmatched.
+// The types are from the statement's point of view, the opposite of the company account's:
+// a deposit credits the bank account and debits the company account.
+type ReconciliationItem struct {
+	Status ReconciliationStatus `json:"status"`
+	// Reference is the reference of the statement entry.
+	Reference string            `json:"reference,omitempty"`
+	Type      DebitOrCreditType `json:"type"`
+	Amount    decimal.Decimal   `json:"amount"`
+	BookedAt  time.Time         `json:"booked_at"`
+	// TxID is the company account's ledger entry.
+	TxID    string `json:"tx_id,omitempty"`
+	Remarks string `json:"remarks,omitempty"`
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrInvalidReportPeriod = errors.New("invalid report period, expecting RFC 3339 times or dates, the end after the start")
+
+// TrialBalance is the debits and the credits of every account, summed over the ledger entries posted before AsOf.
+// The debits and the credits of each currency are equal as long as every transfer was posted as a double entry.
+type TrialBalance struct {
+	// Currency restricts the report to one currency, all of them when empty.
+	Currency string               `json:"currency,omitempty"`
+	AsOf     time.Time            `json:"as_of"`
+	Totals   []*TrialBalanceTotal `json:"totals"`
+	Accounts []*TrialBalanceLine  `json:"accounts"`
+}
+
+// TrialBalanceLine is the debits and the credits of an account in a currency, its balance being the credits less the debits.
+type TrialBalanceLine struct {
+	AccountID string          `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Debits    decimal.Decimal `json:"debits"`
+	Credits   decimal.Decimal `json:"credits"`
+	Balance   decimal.Decimal `json:"balance"`
+}
+
+// TrialBalanceTotal is the debits and the credits of every account in a currency.
+type TrialBalanceTotal struct {
+	Currency string          `json:"currency"`
+	Debits   decimal.Decimal `json:"debits"`
+	Credits  decimal.Decimal `json:"credits"`
+	// Balanced is false if the debits and the credits differ, the ledger is then inconsistent.
+	Balanced bool `json:"balanced"`
+}
+
+// GeneralLedgerLine is the debits and the credits of an account in a currency, posted on a day in UTC.
+type GeneralLedgerLine struct {
+	Date      time.Time       `json:"date"`
+	AccountID string          `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Debits    decimal.Decimal `json:"debits"`
+	Credits   decimal.Decimal `json:"credits"`
+	// Entries is the number of ledger entries of the account that day.
+	Entries int64 `json:"entries"`
+}
+
+// GeneralLedgerWriter renders the general ledger as its lines are read, by day then by currency and account,
+// so the ledger of any period is streamed.
+type GeneralLedgerWriter interface {
+	Line(line *GeneralLedgerLine) error
+}
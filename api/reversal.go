@@ -0,0 +1,28 @@
+package api
+
+import "errors"
+
+var (
+	ErrTransactionReversed = errors.New("the transaction was already reversed")
+	ErrTransactionDisputed = errors.New("the transaction is disputed, resolve its disputes instead")
+	ErrNotReversible       = errors.New("cannot reverse the entries of a dispute, of a reversal, of a conversion or of a fee")
+)
+
+// ReverseRequest are the optional details of a reversal, i.e. a refund.
+type ReverseRequest struct {
+	// Remarks default to "reversal of" the reversed ledger entry.
+	Remarks string `json:"remarks,omitempty"`
+}
+
+// ReversalReceipt is the compensating transfer of a reversal, returning the amount of the reversed transfer to its sender.
+type ReversalReceipt struct {
+	// ReversalOf is the ledger entry the reversal was requested for, either leg of the reversed transfer.
+	ReversalOf string `json:"reversal_of"`
+	*TransferReceipt
+}
+
+// ReversalGroupID is the group of the ledger entries reversing the transfer of the debit entry.
+// It's derived from the entry, so the transfer is reversed at most once.
+func ReversalGroupID(debitTxID string) string {
+	return "reversal:" + debitTxID
+}
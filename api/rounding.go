@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode is how an amount is rounded to the scale of its currency.
+type RoundingMode string
+
+const (
+	// RoundHalfEven rounds the halves to the even digit, i.e. the banker's rounding, so the errors even out over many amounts.
+	RoundHalfEven RoundingMode = "HALF_EVEN"
+	// RoundHalfUp rounds the halves away from zero.
+	RoundHalfUp RoundingMode = "HALF_UP"
+)
+
+// RoundingPolicy is how the amounts of a currency are rounded, reported in the responses
+// so the integrators can reconcile to the smallest unit.
+type RoundingPolicy struct {
+	Mode RoundingMode `json:"mode"`
+	// Scale is the number of decimal places, i.e. 2 for the cents.
+	Scale int32 `json:"scale"`
+}
+
+// Valid reports whether the mode is known and the scale isn't negative.
+func (p RoundingPolicy) Valid() bool {
+	return (p.Mode == RoundHalfEven || p.Mode == RoundHalfUp) && p.Scale >= 0
+}
+
+// Round rounds the amount to the scale of the policy.
+func (p RoundingPolicy) Round(amount decimal.Decimal) decimal.Decimal {
+	if p.Mode == RoundHalfEven {
+		return amount.RoundBank(p.Scale)
+	}
+
+	return amount.Round(p.Scale)
+}
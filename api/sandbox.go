@@ -0,0 +1,9 @@
+package api
+
+import "errors"
+
+// ErrSandboxQuotaExceeded is a deposit above the quota of the account in its currency, in the sandbox.
+var ErrSandboxQuotaExceeded = errors.New("the sandbox deposit quota is exceeded")
+
+// SandboxHeader is set to true on the responses of a sandbox, whose money is fake.
+const SandboxHeader = "X-Wallet-Sandbox"
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+	ErrScheduledTransferClosed   = errors.New("the scheduled transfer was already executed or cancelled")
+)
+
+// ScheduledTransferStatus is the state of a transfer posted once it's due.
+type ScheduledTransferStatus string
+
+const (
+	// ScheduledTransferScheduled is waiting to be due, it can still be cancelled.
+	ScheduledTransferScheduled ScheduledTransferStatus = "SCHEDULED"
+	// ScheduledTransferCompleted was posted, its ledger entries are grouped under its idempotency key.
+	ScheduledTransferCompleted ScheduledTransferStatus = "COMPLETED"
+	// ScheduledTransferHeld was held for a review or an approval, it's posted under its idempotency key once approved.
+	ScheduledTransferHeld ScheduledTransferStatus = "HELD"
+	// ScheduledTransferFailed was rejected when it was due, i.e. for an insufficient balance, the error tells why.
+	ScheduledTransferFailed ScheduledTransferStatus = "FAILED"
+	// ScheduledTransferCancelled was cancelled before it was due.
+	ScheduledTransferCancelled ScheduledTransferStatus = "CANCELLED"
+)
+
+// ScheduleTransferRequest is a transfer to post at ExecuteAt.
+type ScheduleTransferRequest struct {
+	TransferRequest
+	ExecuteAt time.Time `json:"execute_at"`
+}
+
+// ScheduledTransfer is a transfer posted by the workers once it's due.
+type ScheduledTransfer struct {
+	ID string `json:"id"`
+	// IdempotencyKey is the key the transfer was scheduled with, the group of its ledger entries once executed.
+	IdempotencyKey string                  `json:"idempotency_key"`
+	FromAccountID  string                  `json:"from_account_id"`
+	ToAccountID    string                  `json:"to_account_id"`
+	Currency       string                  `json:"currency"`
+	Amount         decimal.Decimal         `json:"amount"`
+	Remarks        string                  `json:"remarks,omitempty"`
+	Tags           []string                `json:"tags,omitempty"`
+	ExecuteAt      time.Time               `json:"execute_at"`
+	Status         ScheduledTransferStatus `json:"status"`
+	// Code and Error are the error failing or holding the transfer.
+	Code      ErrorCode `json:"code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ClosedAt is when a worker executed the transfer, or when it was cancelled.
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+// Transfer is the transfer the worker posts.
+func (s *ScheduledTransfer) Transfer() *TransferRequest {
+	return &TransferRequest{
+		FromAccountID: s.FromAccountID,
+		ToAccountID:   s.ToAccountID,
+		Currency:      s.Currency,
+		Amount:        s.Amount,
+		Remarks:       s.Remarks,
+		Tags:          s.Tags,
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrTransferUnderReview = errors.New("transfer is pending review")
+	ErrTransferRejected    = errors.New("transfer was rejected")
+	ErrScreeningFailed     = errors.New("screening failed")
+	ErrReviewNotFound      = errors.New("review not found")
+	ErrReviewDecided       = errors.New("review was already decided")
+)
+
+// ScreeningProvider screens the transfers before they are posted, i.e. against a sanctions list.
+// An error fails the transfer, so a provider that can't be reached never lets a transfer through.
+type ScreeningProvider interface {
+	Screen(ctx context.Context, request *TransferRequest) (ScreeningResult, error)
+}
+
+// ScreeningResult is the outcome of the screening of a transfer.
+type ScreeningResult struct {
+	// Flagged holds the transfer for a manual review instead of posting it.
+	Flagged bool
+	// Reason is kept with the review, it's never returned to the client.
+	Reason string
+}
+
+// ReviewStatus is the state of a held transfer, flagged by the screening or above the approval threshold.
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "PENDING"
+	ReviewApproved ReviewStatus = "APPROVED"
+	ReviewRejected ReviewStatus = "REJECTED"
+)
+
+// TransferReview is a transfer held by the screening, until it's approved and posted, or rejected.
+type TransferReview struct {
+	// IdempotencyKey identifies the transfer, and its ledger entries once approved.
+	IdempotencyKey string          `json:"idempotency_key"`
+	FromAccountID  string          `json:"from_account_id"`
+	ToAccountID    string          `json:"to_account_id"`
+	Currency       string          `json:"currency"`
+	Amount         decimal.Decimal `json:"amount"`
+	Remarks        string          `json:"remarks,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+	Reason         string          `json:"reason"`
+	Status         ReviewStatus    `json:"status"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DecidedAt      *time.Time      `json:"decided_at,omitempty"`
+	// ToCurrency is the currency the amount is converted to once approved, see TransferRequest.ToCurrency.
+	ToCurrency string `json:"to_currency,omitempty"`
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidStatementChannel = errors.New("invalid statement channel or destination")
+	ErrSubscriptionNotFound    = errors.New("statement subscription not found")
+	ErrInvalidStatementFormat  = errors.New("invalid statement format")
+	ErrInvalidStatementPeriod  = errors.New("invalid statement period")
+)
+
+// StatementChannel is how the statements of a subscription are delivered.
+type StatementChannel string
+
+const (
+	// StatementWebhook posts the statement as signed JSON to the destination URL.
+	StatementWebhook StatementChannel = "WEBHOOK"
+	// StatementEmail mails the statement to the destination address.
+	StatementEmail StatementChannel = "EMAIL"
+)
+
+// StatementFormat is how a downloaded statement is rendered.
+type StatementFormat string
+
+const (
+	// StatementCSV is a row per entry, with its running balance.
+	StatementCSV StatementFormat = "csv"
+	// StatementPDF is a printable text document, with the opening and closing balances.
+	StatementPDF StatementFormat = "pdf"
+)
+
+// Valid reports whether the format is known.
+func (f StatementFormat) Valid() bool {
+	return f == StatementCSV || f == StatementPDF
+}
+
+// StatementWriter renders a downloaded statement as its entries are read from the ledger, the oldest first,
+// so the statements of any size are streamed.
+type StatementWriter interface {
+	// Begin is called before the entries, with the period and the opening balance of the statement.
+	Begin(statement *Statement) error
+	// Entry is called for each entry of the period, with its running balance.
+	Entry(transaction *Transaction) error
+	// End is called after the entries, with the closing balance of the statement.
+	End(statement *Statement) error
+}
+
+// StatementStatus is the delivery state of a statement.
+type StatementStatus string
+
+const (
+	// StatementPending is waiting for its first or next delivery attempt.
+	StatementPending   StatementStatus = "PENDING"
+	StatementDelivered StatementStatus = "DELIVERED"
+	// StatementFailed has exhausted its delivery attempts.
+	StatementFailed StatementStatus = "FAILED"
+)
+
+// SubscribeStatementsRequest subscribes the account to its monthly statements in the currency.
+type SubscribeStatementsRequest struct {
+	AccountID string           `json:"account_id"`
+	Currency  string           `json:"currency"`
+	Channel   StatementChannel `json:"channel"`
+	// Destination is the URL of a webhook, or the address of an email.
+	Destination string `json:"destination"`
+}
+
+type StatementSubscription struct {
+	ID          string           `json:"id"`
+	AccountID   string           `json:"account_id"`
+	Currency    string           `json:"currency"`
+	Channel     StatementChannel `json:"channel"`
+	Destination string           `json:"destination"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// Statement is the ledger entries of an account in a currency over a period, with its delivery status.
+type Statement struct {
+	ID          string           `json:"id"`
+	AccountID   string           `json:"account_id"`
+	Currency    string           `json:"currency"`
+	Channel     StatementChannel `json:"channel"`
+	Destination string           `json:"destination"`
+	// the period is from PeriodStart included to PeriodEnd excluded
+	PeriodStart    time.Time       `json:"period_start"`
+	PeriodEnd      time.Time       `json:"period_end"`
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	ClosingBalance decimal.Decimal `json:"closing_balance"`
+	// Transactions are the entries of the period, the oldest first, with their running balance.
+	// They're only set for the delivery.
+	Transactions []*Transaction  `json:"transactions,omitempty"`
+	Status       StatementStatus `json:"status"`
+	Attempts     int             `json:"attempts"`
+	LastError    string          `json:"last_error,omitempty"`
+	DeliveredAt  *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidWebhook     = errors.New("invalid webhook url")
+	ErrWebhookNotFound    = errors.New("webhook subscription not found")
+	ErrInvalidGracePeriod = errors.New("invalid grace period")
+)
+
+const (
+	// DefaultWebhookGracePeriod is how long the previous secret of a subscription keeps signing its deliveries.
+	DefaultWebhookGracePeriod = 24 * time.Hour
+	// MaxWebhookGracePeriod bounds the grace period, a leaked secret can't be kept valid for long.
+	MaxWebhookGracePeriod = 7 * 24 * time.Hour
+)
+
+// SubscribeWebhookRequest subscribes the url to the ledger events. Without an account, the subscription is global
+// and gets the events of every account, otherwise only the events of the account, in the currency if any.
+type SubscribeWebhookRequest struct {
+	URL       string `json:"url"`
+	AccountID string `json:"account_id,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+}
+
+// WebhookSubscription is a url the ledger events are posted to, either global or scoped to an account.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	AccountID string    `json:"account_id,omitempty"`
+	Currency  string    `json:"currency,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret signs the deliveries once rotated, they're signed with the secret of the server until then.
+	Secret string `json:"-"`
+	// PreviousSecret keeps signing the deliveries along with the Secret until PreviousSecretExpiresAt.
+	// It's empty for the secret of the server, when the secret is rotated for the first time.
+	PreviousSecret          string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+// SigningSecrets returns the secrets signing the deliveries at the time, the newest first.
+// A nil secret is the secret of the server, and there's none for a subscription that was never rotated.
+func (s *WebhookSubscription) SigningSecrets(now time.Time) [][]byte {
+	if s.Secret == "" {
+		return nil
+	}
+
+	secrets := [][]byte{[]byte(s.Secret)}
+
+	if s.PreviousSecretExpiresAt != nil && now.Before(*s.PreviousSecretExpiresAt) {
+		if s.PreviousSecret == "" {
+			secrets = append(secrets, nil)
+		} else {
+			secrets = append(secrets, []byte(s.PreviousSecret))
+		}
+	}
+
+	return secrets
+}
+
+// RotateWebhookSecretRequest rotates the signing secret of a subscription. The previous secret keeps signing
+// the deliveries along with the new one for the grace period, so the receiver can switch without missing any.
+type RotateWebhookSecretRequest struct {
+	// GracePeriodSeconds is DefaultWebhookGracePeriod when omitted, and 0 revokes the previous secret right away,
+	// i.e. when it's leaked.
+	GracePeriodSeconds *int64 `json:"grace_period_seconds,omitempty"`
+}
+
+// WebhookSecretRotation is the audit record of the rotation of the secret of a subscription.
+type WebhookSecretRotation struct {
+	ID             int64  `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	// Secret is only returned by the rotation, it can't be read afterwards.
+	Secret            string     `json:"secret,omitempty"`
+	Operator          string     `json:"operator"`
+	RotatedAt         time.Time  `json:"rotated_at"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// Matches tells whether the subscription gets an event of the accounts in the currency.
+func (s *WebhookSubscription) Matches(accountIDs []string, currency string) bool {
+	if s.Currency != "" && s.Currency != currency {
+		return false
+	}
+
+	if s.AccountID == "" {
+		return true
+	}
+
+	for _, accountID := range accountIDs {
+		if accountID == s.AccountID {
+			return true
+		}
+	}
+
+	return false
+}
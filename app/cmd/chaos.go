@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/devshark/wallet/app/internal/chaos"
+)
+
+const (
+	chaosRepository = "repository"
+	chaosRedis      = "redis"
+)
+
+type ChaosConfig struct {
+	// Faults delay or fail a percentage of the calls, never in production.
+	Faults chaos.Faults
+	// Targets are the faulted dependencies, the repository and Redis.
+	Targets []string
+}
+
+func (c ChaosConfig) Enabled() bool {
+	return c.Faults.Enabled()
+}
+
+// Targeted tells whether the dependency is faulted.
+func (c ChaosConfig) Targeted(target string) bool {
+	return c.Enabled() && slices.Contains(c.Targets, target)
+}
+
+func (c ChaosConfig) Validate() error {
+	if c.Faults.FailurePercent < 0 || c.Faults.FailurePercent > 100 {
+		return fmt.Errorf("%w: CHAOS_FAILURE_PERCENT=%d", ErrInvalidSetting, c.Faults.FailurePercent)
+	}
+
+	if c.Faults.DelayPercent < 0 || c.Faults.DelayPercent > 100 {
+		return fmt.Errorf("%w: CHAOS_DELAY_PERCENT=%d", ErrInvalidSetting, c.Faults.DelayPercent)
+	}
+
+	if c.Faults.Delay < 0 {
+		return fmt.Errorf("%w: CHAOS_DELAY=%s", ErrInvalidSetting, c.Faults.Delay)
+	}
+
+	for _, target := range c.Targets {
+		if target != chaosRepository && target != chaosRedis {
+			return fmt.Errorf("%w: CHAOS_TARGETS=%s", ErrInvalidSetting, strings.Join(c.Targets, ","))
+		}
+	}
+
+	return nil
+}
+
+// parseChaosTargets splits the comma-separated targets, ignoring the blanks.
+func parseChaosTargets(value string) []string {
+	targets := []string{}
+
+	for _, target := range strings.Split(value, ",") {
+		if target = strings.ToLower(strings.TrimSpace(target)); target != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
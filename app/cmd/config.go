@@ -0,0 +1,965 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/chaos"
+	"github.com/devshark/wallet/app/internal/fx"
+	"github.com/devshark/wallet/app/internal/i18n"
+	"github.com/devshark/wallet/app/internal/receipts"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	"github.com/devshark/wallet/app/internal/remarks"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/devshark/wallet/pkg/env"
+	"github.com/devshark/wallet/pkg/events"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/scheduler"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidSetting is returned when a tunable setting is out of its valid range.
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// ErrMissingAdminKeys is returned when the debug endpoints are enabled without any admin key to protect them.
+var ErrMissingAdminKeys = errors.New("the debug endpoints require ADMIN_API_KEY_HASHES")
+
+// ErrMissingApprovers is returned when the transfers are held for an approval that no operator could give.
+var ErrMissingApprovers = errors.New("the approval thresholds require ADMIN_API_KEY_HASHES")
+
+// ErrMissingAccountOpeners is returned when the accounts must be opened beforehand, and no operator could open them.
+var ErrMissingAccountOpeners = errors.New("the strict ACCOUNT_PROVISIONING requires ADMIN_API_KEY_HASHES")
+
+// ErrActivityWithoutOutbox is returned when the activity feeds are enabled without the outbox they're projected from.
+var ErrActivityWithoutOutbox = errors.New("the activity feeds require EVENTS_BROKER")
+
+// maxAccountIDLength is the length of the accounts.user_id column.
+const maxAccountIDLength = 255
+
+// ConfigFileEnv points to the YAML config file when the --config flag is not given.
+const ConfigFileEnv = "WALLET_CONFIG"
+
+// configKeys are the settings that can be given as env variables, command-line flags, or in the config file.
+// The flag name is the lowercase key with "-" i.e. POSTGRES_HOST is --postgres-host,
+// and the config file key is the nested lowercase key i.e. postgres.host.
+func configKeys() []string {
+	return []string{
+		"WALLET_MODE",
+		"COMPANY_ACCOUNT_ID",
+		"PORT",
+		"GRPC_PORT",
+		"POSTGRES_HOST",
+		"POSTGRES_PORT",
+		"POSTGRES_USER",
+		"POSTGRES_PASSWORD",
+		"POSTGRES_DATABASE",
+		"DB_WAIT_TIMEOUT",
+		"DB_MAX_OPEN_CONNS",
+		"DB_MAX_IDLE_CONNS",
+		"DB_CONN_MAX_LIFETIME",
+		"DB_CONN_MAX_IDLE_TIME",
+		"DB_EXPLAIN_SAMPLE_PERCENT",
+		"DB_LOCK_STRATEGY",
+		"REDIS_MODE",
+		"REDIS_ADDRESS",
+		"REDIS_USERNAME",
+		"REDIS_PASSWORD",
+		"REDIS_DB",
+		"REDIS_MASTER_NAME",
+		"REDIS_SENTINEL_USERNAME",
+		"REDIS_SENTINEL_PASSWORD",
+		"REDIS_TLS",
+		"REDIS_TLS_CA_FILE",
+		"REDIS_TLS_SERVER_NAME",
+		"HTTP_READ_TIMEOUT",
+		"HTTP_WRITE_TIMEOUT",
+		"HTTP_READ_HEADER_TIMEOUT",
+		"HTTP_IDLE_TIMEOUT",
+		"HTTP_DRAIN_TIMEOUT",
+		"HTTP_H2C",
+		"SHUTDOWN_TIMEOUT",
+		"CACHE_EXPIRY",
+		"RATE_LIMIT_REQUESTS",
+		"RATE_LIMIT_WINDOW",
+		"READ_ONLY",
+		"SUBSCRIPTIONS_POLL_INTERVAL",
+		"TLS_CERT_FILE",
+		"TLS_KEY_FILE",
+		"TLS_CLIENT_CA_FILE",
+		"LOG_LEVEL",
+		"LOG_FORMAT",
+		"LEDGER_CHECK_INTERVAL",
+		"LEDGER_CHECK_SCHEDULE",
+		"FEATURE_FLAGS_REFRESH_INTERVAL",
+		"EVENTS_BROKER",
+		"EVENTS_URL",
+		"EVENTS_TOPIC_PREFIX",
+		"EVENTS_RELAY_INTERVAL",
+		"EVENTS_RETENTION",
+		"EVENTS_WEBHOOK_SECRET",
+		"DEBUG_ENDPOINTS",
+		"ADMIN_API_KEY_HASHES",
+		"RECONCILIATION_DATE_TOLERANCE",
+		"SCREENING_DENYLIST_ACCOUNTS",
+		"SCREENING_DENYLIST_TERMS",
+		"APPROVAL_THRESHOLDS",
+		"IDEMPOTENCY_RESERVATION_TTL",
+		"ROUNDING_POLICIES",
+		"TRANSACTION_TAXONOMY",
+		"ACCOUNT_PROVISIONING",
+		"ACCOUNT_ID_PATTERN",
+		"ACCOUNT_ID_MIN_LENGTH",
+		"ACCOUNT_ID_MAX_LENGTH",
+		"REMARKS_MAX_LENGTH",
+		"REMARKS_DENIED_WORDS",
+		"REMARKS_REJECT_PII",
+		"FX_RATES",
+		"FX_RATES_URL",
+		"FEES_ENABLED",
+		"ERROR_MESSAGES_ENABLED",
+		"ERROR_MESSAGES_DIR",
+		"TRANSFER_ERROR_STATUSES",
+		"STATEMENTS_INTERVAL",
+		"STATEMENTS_MAX_ATTEMPTS",
+		"STATEMENTS_WEBHOOK_SECRET",
+		"SMTP_ADDRESS",
+		"SMTP_FROM",
+		"SMTP_USERNAME",
+		"SMTP_PASSWORD",
+		"RECEIPTS_INTERVAL",
+		"RECEIPTS_MAX_ATTEMPTS",
+		"RECEIPTS_TEMPLATES",
+		"ENCRYPTION_KEYS",
+		"ENCRYPTION_ACTIVE_KEY",
+		"ERASURE_INTERVAL",
+		"SANDBOX_ENABLED",
+		"SANDBOX_SCHEMA",
+		"SANDBOX_DEPOSIT_QUOTAS",
+		"ACTIVITY_FEED_ENABLED",
+		"ACTIVITY_FEED_INTERVAL",
+		"TRANSFER_QUEUE_ENABLED",
+		"TRANSFER_QUEUE_INTERVAL",
+		"TRANSFER_SCHEDULED_ENABLED",
+		"TRANSFER_SCHEDULED_INTERVAL",
+		"WORKER_LOCKS",
+		"WORKER_LOCK_TTL",
+		"WORKER_JITTER",
+		"CHAOS_FAILURE_PERCENT",
+		"CHAOS_DELAY_PERCENT",
+		"CHAOS_DELAY",
+		"CHAOS_TARGETS",
+	}
+}
+
+// NewLoader layers the configuration sources. From the highest priority:
+// the subcommand, command-line flags, env variables, then the config file.
+func NewLoader(args []string) (*env.Loader, error) {
+	subcommand, args := splitSubcommand(args)
+
+	flagSet := flag.NewFlagSet("wallet", flag.ContinueOnError)
+
+	configFile := flagSet.String("config", os.Getenv(ConfigFileEnv), "path to a YAML config file")
+
+	flagKeys := make(map[string]string, len(configKeys()))
+	for _, key := range configKeys() {
+		name := flagName(key)
+		flagKeys[name] = key
+
+		flagSet.String(name, "", fmt.Sprintf("overrides the %s env variable", key))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	// only the flags explicitly given must take precedence, not their empty defaults
+	flagValues := map[string]string{}
+
+	flagSet.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			flagValues[key] = f.Value.String()
+		}
+	})
+
+	if subcommand != "" {
+		flagValues["WALLET_MODE"] = subcommand
+	}
+
+	fileValues := map[string]string{}
+
+	if *configFile != "" {
+		values, err := env.LoadYAMLFile(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+
+		fileValues = values
+	}
+
+	return env.NewLoader(
+		env.MapLookup(flagValues),
+		os.LookupEnv,
+		env.MapLookup(fileValues),
+	), nil
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+type DBConfig struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Database string
+
+	// the connection pool settings, see sql.DB. 0 means unlimited.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ExplainSamplePercent of the GetTransactions and Transfer calls log their query plans, from 0 to 100.
+	ExplainSamplePercent float64
+
+	// LockStrategy is how the transfers lock their accounts, see repository.WithLockStrategy.
+	LockStrategy repository.LockStrategy
+}
+
+type HTTPConfig struct {
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	ReadHeaderTimeout time.Duration
+	IdleTimeout       time.Duration
+	// DrainTimeout is how long the in-flight requests are waited for on shutdown, once the listener is closed.
+	DrainTimeout time.Duration
+	// H2C serves HTTP/2 without TLS, i.e. behind a proxy terminating it. HTTP/2 is always negotiated over TLS.
+	H2C bool
+}
+
+type Config struct {
+	mode                Mode
+	ledgerCheckInterval time.Duration
+	dbWaitTimeout       time.Duration
+	shutdownTimeout     time.Duration
+	cacheExpiry         time.Duration
+	http                HTTPConfig
+	port                int64
+	grpcPort            int64
+	postgres            DBConfig
+	redis               RedisConfig
+	events              EventsConfig
+	statements          StatementsConfig
+	receipts            ReceiptsConfig
+	sandbox             SandboxConfig
+	tls                 TLSConfig
+	logLevel            string
+	logFormat           string
+	debugEndpoints      bool
+	// featureLookup reads the FEATURE_<FLAG> settings, which aren't known in advance
+	featureLookup        env.LookupFunc
+	featureFlagsInterval time.Duration
+	adminAPIKeyHashes    []string
+	// reconciliationTolerance is how far apart a statement entry and its ledger entry may be booked
+	reconciliationTolerance time.Duration
+	// the transfers from or to these accounts, or with these terms in the remarks, are held for a review
+	denylistAccounts []string
+	denylistTerms    []string
+	// approvalThresholds are the amounts, by currency, above which the transfers wait for an operator's approval
+	approvalThresholds map[string]decimal.Decimal
+	// idempotencyReservationTTL is how long Redis keeps the idempotency keys of the posted transfers, 0 disables the reservations
+	idempotencyReservationTTL time.Duration
+	// roundingPolicies are how the amounts are rounded in the responses, and the converted amounts and fees, by currency
+	roundingPolicies map[string]api.RoundingPolicy
+	// taxonomy are the tags allowed on the transfers, any well-formed tag is allowed when it's empty
+	taxonomy []string
+	// provisioningPolicy is which accounts the transfers may open
+	provisioningPolicy api.ProvisioningPolicy
+	// accountIDs is the format of the ids of the accounts the transfers may open, any id is allowed by default
+	accountIDs accountid.Policy
+	// remarks is the length and the content of the remarks of the transfers, up to remarks.MaxLength characters by default
+	remarks remarks.Policy
+	// the cross-currency transfers are converted at the rates of the static table, or of the service at fxRatesURL
+	fxRates    map[fx.Pair]decimal.Decimal
+	fxRatesURL string
+	// fees charges the fees of the fee rules on the transfers and withdrawals, the rules are set under /admin/fees
+	fees bool
+	// errorMessages translate the messages of the REST errors to the languages of the clients, nil keeps them in English
+	errorMessages *i18n.Catalog
+	// transferStatuses override the HTTP status of the failed transfers, by error code
+	transferStatuses map[api.ErrorCode]int
+	// rateLimitRequests are allowed per client in every rateLimitWindow, 0 disables the rate limit
+	rateLimitRequests int64
+	rateLimitWindow   time.Duration
+	// readOnly rejects the mutations of every request, whatever the switch of the operators
+	readOnly bool
+	// subscriptionsInterval is how often the ledger is polled for each subscriber to the transactions of an account
+	subscriptionsInterval time.Duration
+	// encryption wraps the data keys encrypting the remarks, the remarks are kept as they are when it's nil
+	encryption *crypt.Keyring
+	// erasureInterval is how often the worker erases the data of the accounts, 0 disables the job
+	erasureInterval time.Duration
+	// activityFeed serves the activity feeds of the accounts, projected from the outbox every activityInterval by the worker
+	activityFeed     bool
+	activityInterval time.Duration
+	// transferQueue accepts the asynchronous transfers, posted every transferQueueInterval by the worker
+	transferQueue         bool
+	transferQueueInterval time.Duration
+	// scheduledTransfers accepts the transfers to post later, executed every scheduledInterval by the worker once they're due
+	scheduledTransfers bool
+	scheduledInterval  time.Duration
+	// chaos faults the calls to the dependencies of the server, i.e. in staging
+	chaos ChaosConfig
+	// companyAccountID is the settlement account funding the deposits and receiving the withdrawals
+	companyAccountID string
+	// workerLocks runs each job of the worker on a single instance at a time, holding its lock in Redis for workerLockTTL
+	workerLocks   bool
+	workerLockTTL time.Duration
+	// ledgerCheckCron schedules the ledger check instead of its interval, i.e. at 3:00 every day
+	ledgerCheckCron scheduler.Schedule
+	// workerJitter delays each run of the jobs by up to that long, so the workers started together spread their runs
+	workerJitter time.Duration
+}
+
+func NewConfig(loader *env.Loader) (Config, error) {
+	mode, err := ParseMode(loader.GetEnv("WALLET_MODE", string(ModeAll)))
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := Config{
+		mode:                mode,
+		ledgerCheckInterval: loader.GetEnvDuration("LEDGER_CHECK_INTERVAL", defaultLedgerCheckInterval),
+		dbWaitTimeout:       loader.GetEnvDuration("DB_WAIT_TIMEOUT", defaultDBWaitTimeout),
+		shutdownTimeout:     loader.GetEnvDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		postgres: DBConfig{
+			Host:            loader.RequireEnv("POSTGRES_HOST"),
+			Port:            loader.RequireEnv("POSTGRES_PORT"),
+			User:            loader.RequireEnv("POSTGRES_USER"),
+			Password:        loader.RequireEnv("POSTGRES_PASSWORD"),
+			Database:        loader.RequireEnv("POSTGRES_DATABASE"),
+			MaxOpenConns:    int(loader.GetEnvInt64("DB_MAX_OPEN_CONNS", defaultMaxOpenConns)),
+			MaxIdleConns:    int(loader.GetEnvInt64("DB_MAX_IDLE_CONNS", defaultMaxIdleConns)),
+			ConnMaxLifetime: loader.GetEnvDuration("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime),
+			ConnMaxIdleTime: loader.GetEnvDuration("DB_CONN_MAX_IDLE_TIME", defaultConnMaxIdleTime),
+		},
+		logLevel:  loader.GetEnv("LOG_LEVEL", "info"),
+		logFormat: loader.GetEnv("LOG_FORMAT", logging.FormatText),
+		// both the server and the worker post to the company account, i.e. the disputes and the restores
+		companyAccountID: strings.TrimSpace(loader.GetEnv("COMPANY_ACCOUNT_ID", api.CompanyAccountID)),
+	}
+
+	config.postgres.ExplainSamplePercent, err = parseSamplePercent("DB_EXPLAIN_SAMPLE_PERCENT", loader.GetEnv("DB_EXPLAIN_SAMPLE_PERCENT", "0"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.postgres.LockStrategy = repository.LockStrategy(strings.ToLower(strings.TrimSpace(loader.GetEnv("DB_LOCK_STRATEGY", string(repository.LockRows)))))
+	if !config.postgres.LockStrategy.Valid() {
+		return Config{}, fmt.Errorf("%w: DB_LOCK_STRATEGY=%q", ErrInvalidSetting, config.postgres.LockStrategy)
+	}
+
+	// the server writes the events to the outbox, and the worker relays them, so both need the broker
+	broker, err := ParseEventsBroker(loader.GetEnv("EVENTS_BROKER", string(EventsNone)))
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.events = EventsConfig{
+		Broker:        broker,
+		URL:           loader.GetEnv("EVENTS_URL", ""), // required with a broker
+		TopicPrefix:   loader.GetEnv("EVENTS_TOPIC_PREFIX", events.DefaultTopicPrefix),
+		WebhookSecret: loader.GetEnv("EVENTS_WEBHOOK_SECRET", ""), // required with the webhook broker
+		RelayInterval: loader.GetEnvDuration("EVENTS_RELAY_INTERVAL", defaultEventsRelayInterval),
+		Retention:     loader.GetEnvDuration("EVENTS_RETENTION", defaultEventsRetention),
+	}
+
+	// the worker delivers the statements
+	config.statements = StatementsConfig{
+		Interval:      loader.GetEnvDuration("STATEMENTS_INTERVAL", 0),
+		MaxAttempts:   int(loader.GetEnvInt64("STATEMENTS_MAX_ATTEMPTS", statements.DefaultMaxAttempts)),
+		WebhookSecret: loader.GetEnv("STATEMENTS_WEBHOOK_SECRET", ""), // optional, disables the webhooks if empty
+		SMTP: SMTPConfig{
+			Address:  loader.GetEnv("SMTP_ADDRESS", ""), // optional, disables the emails if empty
+			From:     loader.GetEnv("SMTP_FROM", ""),    // required with an address
+			Username: loader.GetEnv("SMTP_USERNAME", ""),
+			Password: loader.GetEnv("SMTP_PASSWORD", ""),
+		},
+	}
+
+	// the worker sends the receipts, and the server serves their subscriptions
+	config.receipts = ReceiptsConfig{
+		Interval:    loader.GetEnvDuration("RECEIPTS_INTERVAL", 0),
+		MaxAttempts: int(loader.GetEnvInt64("RECEIPTS_MAX_ATTEMPTS", receipts.DefaultMaxAttempts)),
+		SMTP:        config.statements.SMTP,
+	}
+
+	if config.receipts.Enabled() {
+		config.receipts.Templates, err = parseReceiptTemplates(loader.GetEnv("RECEIPTS_TEMPLATES", ""))
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	// both the server and the worker connect to the schema of the sandbox
+	config.sandbox = SandboxConfig{
+		Enabled: loader.GetEnvBool("SANDBOX_ENABLED", false),
+		Schema:  loader.GetEnv("SANDBOX_SCHEMA", defaultSandboxSchema),
+	}
+
+	// the worker erases the data of the accounts
+	config.erasureInterval = loader.GetEnvDuration("ERASURE_INTERVAL", defaultErasureInterval)
+
+	// the server serves the activity feeds, and the worker projects them
+	config.activityFeed = loader.GetEnvBool("ACTIVITY_FEED_ENABLED", false)
+	config.activityInterval = loader.GetEnvDuration("ACTIVITY_FEED_INTERVAL", defaultActivityInterval)
+
+	// the server queues the asynchronous transfers, and the worker posts them
+	config.transferQueue = loader.GetEnvBool("TRANSFER_QUEUE_ENABLED", false)
+	config.transferQueueInterval = loader.GetEnvDuration("TRANSFER_QUEUE_INTERVAL", defaultTransferQueueInterval)
+
+	// the server schedules the transfers, and the worker executes them
+	config.scheduledTransfers = loader.GetEnvBool("TRANSFER_SCHEDULED_ENABLED", false)
+	config.scheduledInterval = loader.GetEnvDuration("TRANSFER_SCHEDULED_INTERVAL", defaultScheduledInterval)
+
+	// both the server and the worker read the remarks
+	config.encryption, err = parseEncryptionKeys(loader.GetEnv("ENCRYPTION_ACTIVE_KEY", ""), loader.GetEnv("ENCRYPTION_KEYS", ""))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// the worker schedules the jobs
+	config.workerJitter = loader.GetEnvDuration("WORKER_JITTER", 0)
+
+	if expr := loader.GetEnv("LEDGER_CHECK_SCHEDULE", ""); expr != "" {
+		config.ledgerCheckCron, err = scheduler.Cron(expr)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: LEDGER_CHECK_SCHEDULE: %w", ErrInvalidSetting, err)
+		}
+	}
+
+	// the worker locks its jobs, so a single instance runs each of them at a time
+	config.workerLocks = loader.GetEnvBool("WORKER_LOCKS", false)
+	config.workerLockTTL = loader.GetEnvDuration("WORKER_LOCK_TTL", defaultWorkerLockTTL)
+
+	// the server caches in Redis, and the worker locks its jobs in it, or purges the cache of the erased accounts if shared
+	if mode.RunsServer() || config.locksWorkers() || loader.GetEnv("REDIS_ADDRESS", "") != "" {
+		config.redis, err = parseRedisConfig(loader)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	// the worker rounds the fees of the transfers it posts
+	config.fees = loader.GetEnvBool("FEES_ENABLED", false)
+
+	config.roundingPolicies, err = parseRoundingPolicies(loader.GetEnv("ROUNDING_POLICIES", ""))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// the worker doesn't listen nor cache, so it doesn't require their settings
+	if mode.RunsServer() {
+		config.port = loader.RequireEnvInt64("PORT")
+		config.grpcPort = loader.GetEnvInt64("GRPC_PORT", 0) // optional, the gRPC server is disabled if 0
+		config.cacheExpiry = loader.GetEnvDuration("CACHE_EXPIRY", defaultCacheExpiry)
+		config.rateLimitRequests = loader.GetEnvInt64("RATE_LIMIT_REQUESTS", 0)
+		config.rateLimitWindow = loader.GetEnvDuration("RATE_LIMIT_WINDOW", defaultRateLimitWindow)
+		config.readOnly = loader.GetEnvBool("READ_ONLY", false)
+		config.subscriptionsInterval = loader.GetEnvDuration("SUBSCRIPTIONS_POLL_INTERVAL", defaultSubscriptionsInterval)
+		config.http = HTTPConfig{
+			ReadTimeout:       loader.GetEnvDuration("HTTP_READ_TIMEOUT", defaultReadTimeout),
+			WriteTimeout:      loader.GetEnvDuration("HTTP_WRITE_TIMEOUT", defaultWriteTimeout),
+			ReadHeaderTimeout: loader.GetEnvDuration("HTTP_READ_HEADER_TIMEOUT", defaultHeaderTimeout),
+			IdleTimeout:       loader.GetEnvDuration("HTTP_IDLE_TIMEOUT", defaultIdleTimeout),
+			// the whole shutdown timeout by default
+			DrainTimeout: loader.GetEnvDuration("HTTP_DRAIN_TIMEOUT", config.shutdownTimeout),
+			H2C:          loader.GetEnvBool("HTTP_H2C", false),
+		}
+
+		config.tls = TLSConfig{
+			CertFile:     loader.GetEnv("TLS_CERT_FILE", ""),      // optional, serves plain HTTP if empty
+			KeyFile:      loader.GetEnv("TLS_KEY_FILE", ""),       // optional, serves plain HTTP if empty
+			ClientCAFile: loader.GetEnv("TLS_CLIENT_CA_FILE", ""), // optional, enables mTLS
+		}
+		config.featureLookup = loader.Lookup
+		config.featureFlagsInterval = loader.GetEnvDuration("FEATURE_FLAGS_REFRESH_INTERVAL", defaultFeatureFlagsInterval)
+		config.debugEndpoints = loader.GetEnvBool("DEBUG_ENDPOINTS", false)
+		// separated by whitespace, because the PHC encoded hashes contain commas
+		config.adminAPIKeyHashes = strings.Fields(loader.GetEnv("ADMIN_API_KEY_HASHES", ""))
+		config.reconciliationTolerance = loader.GetEnvDuration("RECONCILIATION_DATE_TOLERANCE", reconciliation.DefaultDateTolerance)
+		// comma-separated, the denylist ignores the blanks
+		config.denylistAccounts = strings.Split(loader.GetEnv("SCREENING_DENYLIST_ACCOUNTS", ""), ",")
+		config.denylistTerms = strings.Split(loader.GetEnv("SCREENING_DENYLIST_TERMS", ""), ",")
+		config.idempotencyReservationTTL = loader.GetEnvDuration("IDEMPOTENCY_RESERVATION_TTL", 0)
+		config.taxonomy = strings.Split(loader.GetEnv("TRANSACTION_TAXONOMY", ""), ",")
+		config.chaos = ChaosConfig{
+			Faults: chaos.Faults{
+				FailurePercent: int(loader.GetEnvInt64("CHAOS_FAILURE_PERCENT", 0)),
+				DelayPercent:   int(loader.GetEnvInt64("CHAOS_DELAY_PERCENT", 0)),
+				Delay:          loader.GetEnvDuration("CHAOS_DELAY", 0),
+			},
+			Targets: parseChaosTargets(loader.GetEnv("CHAOS_TARGETS", chaosRepository+","+chaosRedis)),
+		}
+
+		if err := validateAdminKeys(config.debugEndpoints, config.adminAPIKeyHashes); err != nil {
+			return Config{}, err
+		}
+
+		config.approvalThresholds, err = parseCurrencyAmounts("APPROVAL_THRESHOLDS", loader.GetEnv("APPROVAL_THRESHOLDS", ""))
+		if err != nil {
+			return Config{}, err
+		}
+
+		// only the sandbox deposits are capped
+		if config.sandbox.Enabled {
+			config.sandbox.DepositQuotas, err = parseCurrencyAmounts("SANDBOX_DEPOSIT_QUOTAS", loader.GetEnv("SANDBOX_DEPOSIT_QUOTAS", ""))
+			if err != nil {
+				return Config{}, err
+			}
+		}
+
+		if len(config.approvalThresholds) > 0 && len(config.adminAPIKeyHashes) == 0 {
+			return Config{}, ErrMissingApprovers
+		}
+
+		config.transferStatuses, err = parseTransferStatuses(loader.GetEnv("TRANSFER_ERROR_STATUSES", ""))
+		if err != nil {
+			return Config{}, err
+		}
+
+		config.provisioningPolicy = api.ProvisioningPolicy(strings.ToLower(strings.TrimSpace(loader.GetEnv("ACCOUNT_PROVISIONING", string(api.ProvisionAuto)))))
+		if !config.provisioningPolicy.Valid() {
+			return Config{}, fmt.Errorf("%w: ACCOUNT_PROVISIONING=%q", ErrInvalidSetting, config.provisioningPolicy)
+		}
+
+		// only the operators open the accounts then
+		if config.provisioningPolicy == api.ProvisionStrict && len(config.adminAPIKeyHashes) == 0 {
+			return Config{}, ErrMissingAccountOpeners
+		}
+
+		config.accountIDs, err = accountid.New(strings.TrimSpace(loader.GetEnv("ACCOUNT_ID_PATTERN", "")),
+			int(loader.GetEnvInt64("ACCOUNT_ID_MIN_LENGTH", 0)), int(loader.GetEnvInt64("ACCOUNT_ID_MAX_LENGTH", accountid.MaxLength)))
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %w", ErrInvalidSetting, err)
+		}
+
+		config.remarks, err = parseRemarksPolicy(loader)
+		if err != nil {
+			return Config{}, err
+		}
+
+		config.fxRates, config.fxRatesURL, err = parseFXRates(loader.GetEnv("FX_RATES", ""), strings.TrimSpace(loader.GetEnv("FX_RATES_URL", "")))
+		if err != nil {
+			return Config{}, err
+		}
+
+		config.errorMessages, err = parseErrorMessages(loader.GetEnvBool("ERROR_MESSAGES_ENABLED", false),
+			strings.TrimSpace(loader.GetEnv("ERROR_MESSAGES_DIR", "")))
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := config.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// validate rejects the settings that would otherwise be silently ignored or misbehave at runtime,
+// i.e. a negative pool size, or a timeout of 0 that disables the timeout altogether.
+func (c Config) validate() error {
+	durations := []durationSetting{
+		{"DB_CONN_MAX_LIFETIME", c.postgres.ConnMaxLifetime, nonNegative},
+		{"DB_CONN_MAX_IDLE_TIME", c.postgres.ConnMaxIdleTime, nonNegative},
+		{"DB_WAIT_TIMEOUT", c.dbWaitTimeout, nonNegative},
+		{"LEDGER_CHECK_INTERVAL", c.ledgerCheckInterval, nonNegative},
+		{"SHUTDOWN_TIMEOUT", c.shutdownTimeout, positive},
+		{"FEATURE_FLAGS_REFRESH_INTERVAL", c.featureFlagsInterval, nonNegative},
+		{"EVENTS_RETENTION", c.events.Retention, nonNegative},
+		{"STATEMENTS_INTERVAL", c.statements.Interval, nonNegative},
+		{"RECEIPTS_INTERVAL", c.receipts.Interval, nonNegative},
+		{"ERASURE_INTERVAL", c.erasureInterval, nonNegative},
+		{"WORKER_JITTER", c.workerJitter, nonNegative},
+	}
+
+	// the relay only runs with a broker, and never stops once enabled
+	if c.events.Enabled() {
+		durations = append(durations, durationSetting{"EVENTS_RELAY_INTERVAL", c.events.RelayInterval, positive})
+	}
+
+	if c.locksWorkers() {
+		durations = append(durations, durationSetting{"WORKER_LOCK_TTL", c.workerLockTTL, positive})
+	}
+
+	// the projection never stops once enabled
+	if c.activityFeed {
+		durations = append(durations, durationSetting{"ACTIVITY_FEED_INTERVAL", c.activityInterval, positive})
+	}
+
+	// the queue is never left unprocessed once enabled
+	if c.transferQueue {
+		durations = append(durations, durationSetting{"TRANSFER_QUEUE_INTERVAL", c.transferQueueInterval, positive})
+	}
+
+	// the due transfers are never left unexecuted once enabled
+	if c.scheduledTransfers {
+		durations = append(durations, durationSetting{"TRANSFER_SCHEDULED_INTERVAL", c.scheduledInterval, positive})
+	}
+
+	// only the server listens and caches
+	if c.mode.RunsServer() {
+		durations = append(durations,
+			durationSetting{"HTTP_READ_TIMEOUT", c.http.ReadTimeout, positive},
+			durationSetting{"HTTP_WRITE_TIMEOUT", c.http.WriteTimeout, positive},
+			durationSetting{"HTTP_READ_HEADER_TIMEOUT", c.http.ReadHeaderTimeout, positive},
+			durationSetting{"HTTP_IDLE_TIMEOUT", c.http.IdleTimeout, positive},
+			durationSetting{"HTTP_DRAIN_TIMEOUT", c.http.DrainTimeout, positive},
+			durationSetting{"CACHE_EXPIRY", c.cacheExpiry, positive},
+			durationSetting{"SUBSCRIPTIONS_POLL_INTERVAL", c.subscriptionsInterval, positive},
+			durationSetting{"RECONCILIATION_DATE_TOLERANCE", c.reconciliationTolerance, nonNegative},
+			durationSetting{"IDEMPOTENCY_RESERVATION_TTL", c.idempotencyReservationTTL, nonNegative},
+		)
+	}
+
+	// the windows are only counted with a limit
+	if c.rateLimitRequests > 0 {
+		durations = append(durations, durationSetting{"RATE_LIMIT_WINDOW", c.rateLimitWindow, positive})
+	}
+
+	var errs []error
+
+	for _, setting := range durations {
+		if !setting.allowed(setting.value) {
+			errs = append(errs, fmt.Errorf("%w: %s=%s", ErrInvalidSetting, setting.key, setting.value))
+		}
+	}
+
+	if c.postgres.MaxOpenConns < 0 {
+		errs = append(errs, fmt.Errorf("%w: DB_MAX_OPEN_CONNS must not be negative", ErrInvalidSetting))
+	}
+
+	if c.postgres.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("%w: DB_MAX_IDLE_CONNS must not be negative", ErrInvalidSetting))
+	}
+
+	if c.rateLimitRequests < 0 {
+		errs = append(errs, fmt.Errorf("%w: RATE_LIMIT_REQUESTS must not be negative", ErrInvalidSetting))
+	}
+
+	if c.usesRedis() {
+		if err := c.redis.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.mode.RunsServer() {
+		// HTTP/2 is negotiated over TLS already, and the h2c connections would keep it from being advertised
+		if c.http.H2C && c.tls.Enabled() {
+			errs = append(errs, fmt.Errorf("%w: HTTP_H2C is only for the plain HTTP servers", ErrInvalidSetting))
+		}
+
+		// the shutdown gives up on the server past its own timeout, so a longer drain would be cut short
+		if c.http.DrainTimeout > c.shutdownTimeout {
+			errs = append(errs, fmt.Errorf("%w: HTTP_DRAIN_TIMEOUT must not exceed SHUTDOWN_TIMEOUT", ErrInvalidSetting))
+		}
+	}
+
+	if err := c.events.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.statements.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.receipts.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.sandbox.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.chaos.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateCompanyAccountID(c.companyAccountID); err != nil {
+		errs = append(errs, err)
+	}
+
+	// the outbox is only written with a broker
+	if c.activityFeed && !c.events.Enabled() {
+		errs = append(errs, ErrActivityWithoutOutbox)
+	}
+
+	if c.receipts.Enabled() && !c.events.Enabled() {
+		errs = append(errs, ErrReceiptsWithoutOutbox)
+	}
+
+	// sql.DB silently lowers the idle connections to the open ones, so the setting would be misleading
+	if c.postgres.MaxOpenConns > 0 && c.postgres.MaxIdleConns > c.postgres.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("%w: DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS", ErrInvalidSetting))
+	}
+
+	return errors.Join(errs...)
+}
+
+type durationSetting struct {
+	key     string
+	value   time.Duration
+	allowed func(time.Duration) bool
+}
+
+func nonNegative(d time.Duration) bool {
+	return d >= 0
+}
+
+// a timeout of 0 means no timeout at all for the http.Server, which is never intended.
+func positive(d time.Duration) bool {
+	return d > 0
+}
+
+// parseEncryptionKeys parses the master keys, separated by whitespace, as "<key id>:<base64 key>".
+// The encryption is disabled without any key, otherwise the active key must be one of them.
+func parseEncryptionKeys(activeKeyID, value string) (*crypt.Keyring, error) {
+	specs := strings.Fields(value)
+	if len(specs) == 0 && activeKeyID == "" {
+		return nil, nil //nolint:nilnil // the encryption is disabled
+	}
+
+	keyring, err := crypt.ParseKeyring(activeKeyID, specs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ENCRYPTION_KEYS: %w", ErrInvalidSetting, err)
+	}
+
+	return keyring, nil
+}
+
+// validateCompanyAccountID rejects a company account that the users could not be told apart from,
+// i.e. with blanks that the requests would trim, or the accounts holding the disputed amounts and the fees.
+func validateCompanyAccountID(accountID string) error {
+	switch {
+	case accountID == "", len(accountID) > maxAccountIDLength, strings.ContainsFunc(accountID, unicode.IsSpace):
+		return fmt.Errorf("%w: COMPANY_ACCOUNT_ID=%q", ErrInvalidSetting, accountID)
+	case strings.EqualFold(accountID, api.DisputesAccountID):
+		return fmt.Errorf("%w: COMPANY_ACCOUNT_ID must not be the disputes account %s", ErrInvalidSetting, api.DisputesAccountID)
+	case strings.EqualFold(accountID, api.FeesAccountID):
+		return fmt.Errorf("%w: COMPANY_ACCOUNT_ID must not be the fees account %s", ErrInvalidSetting, api.FeesAccountID)
+	}
+
+	return nil
+}
+
+// validateAdminKeys fails early on a typo in the hashes, instead of locking the operators out when they need the endpoints.
+func validateAdminKeys(debugEndpoints bool, hashes []string) error {
+	if debugEndpoints && len(hashes) == 0 {
+		return ErrMissingAdminKeys
+	}
+
+	for _, hash := range hashes {
+		// only the format matters here, not whether the empty key matches
+		if _, err := crypt.VerifyAPIKey("", hash); err != nil {
+			return fmt.Errorf("%w: ADMIN_API_KEY_HASHES: %w", ErrInvalidSetting, err)
+		}
+	}
+
+	return nil
+}
+
+// parseSamplePercent parses the percent of the key, from 0 to 100, with decimals for the small samples, i.e. 0.1.
+func parseSamplePercent(key, value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("%w: %s=%s", ErrInvalidSetting, key, value)
+	}
+
+	return percent, nil
+}
+
+// parseCurrencyAmounts parses the comma-separated CURRENCY:AMOUNT pairs of the key, i.e. USD:10000,EUR:9000.
+func parseCurrencyAmounts(key, value string) (map[string]decimal.Decimal, error) {
+	amounts := map[string]decimal.Decimal{}
+
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		currency, amount, found := strings.Cut(pair, ":")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+
+		parsed, err := decimal.NewFromString(strings.TrimSpace(amount))
+		if !found || currency == "" || err != nil || parsed.IsNegative() {
+			return nil, fmt.Errorf("%w: %s=%s", ErrInvalidSetting, key, pair)
+		}
+
+		amounts[currency] = parsed
+	}
+
+	return amounts, nil
+}
+
+// parseTransferStatuses parses the comma-separated CODE:STATUS overrides, i.e. DUPLICATE_TRANSACTION:422.
+// Only the client errors can be mapped, so the codes stay errors and the server errors stay hidden.
+func parseTransferStatuses(value string) (map[api.ErrorCode]int, error) {
+	statuses := map[api.ErrorCode]int{}
+
+	for _, setting := range strings.Split(value, ",") {
+		if setting = strings.TrimSpace(setting); setting == "" {
+			continue
+		}
+
+		code, status, found := strings.Cut(setting, ":")
+		errorCode := api.ErrorCode(strings.ToUpper(strings.TrimSpace(code)))
+
+		parsed, err := strconv.Atoi(strings.TrimSpace(status))
+		if !found || err != nil || api.ErrorOf(errorCode) == nil || parsed < 400 || parsed > 499 {
+			return nil, fmt.Errorf("%w: TRANSFER_ERROR_STATUSES=%s", ErrInvalidSetting, setting)
+		}
+
+		statuses[errorCode] = parsed
+	}
+
+	return statuses, nil
+}
+
+// parseErrorMessages returns the catalog of the messages of the errors when they're translated, the default one
+// or the one overridden by the files of the directory.
+func parseErrorMessages(enabled bool, dir string) (*i18n.Catalog, error) {
+	switch {
+	case !enabled && dir != "":
+		return nil, fmt.Errorf("%w: ERROR_MESSAGES_DIR requires ERROR_MESSAGES_ENABLED", ErrInvalidSetting)
+	case !enabled:
+		return nil, nil //nolint:nilnil // the messages aren't translated
+	case dir == "":
+		return i18n.DefaultCatalog(), nil
+	}
+
+	catalog, err := i18n.ParseCatalog(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ERROR_MESSAGES_DIR: %w", ErrInvalidSetting, err)
+	}
+
+	return catalog, nil
+}
+
+// parseFXRates parses the comma-separated FROM/TO:RATE rates of the static table, i.e. USD/EUR:0.92,USD/JPY:151.3,
+// and checks the url of the rates service, which can't be set along with the table.
+func parseFXRates(value, rawURL string) (map[fx.Pair]decimal.Decimal, string, error) {
+	rates := map[fx.Pair]decimal.Decimal{}
+
+	for _, setting := range strings.Split(value, ",") {
+		if setting = strings.TrimSpace(setting); setting == "" {
+			continue
+		}
+
+		currencies, rate, found := strings.Cut(setting, ":")
+		from, to, isPair := strings.Cut(currencies, "/")
+		pair := fx.Pair{From: strings.ToUpper(strings.TrimSpace(from)), To: strings.ToUpper(strings.TrimSpace(to))}
+
+		parsed, err := decimal.NewFromString(strings.TrimSpace(rate))
+		if !found || !isPair || pair.From == "" || pair.To == "" || pair.From == pair.To || err != nil || !parsed.IsPositive() {
+			return nil, "", fmt.Errorf("%w: FX_RATES=%s", ErrInvalidSetting, setting)
+		}
+
+		rates[pair] = parsed
+	}
+
+	if rawURL == "" {
+		return rates, "", nil
+	}
+
+	if len(rates) > 0 {
+		return nil, "", fmt.Errorf("%w: FX_RATES and FX_RATES_URL are exclusive", ErrInvalidSetting)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, "", fmt.Errorf("%w: FX_RATES_URL=%s", ErrInvalidSetting, rawURL)
+	}
+
+	return nil, rawURL, nil
+}
+
+// newFXRates is the provider of the rates of the cross-currency transfers, nil when they aren't converted.
+func newFXRates(config Config) api.FXRateProvider {
+	if config.fxRatesURL != "" {
+		return fx.NewHTTP(config.fxRatesURL)
+	}
+
+	if len(config.fxRates) > 0 {
+		return fx.NewStatic(config.fxRates)
+	}
+
+	return nil
+}
+
+// parseRemarksPolicy builds the policy of the remarks from REMARKS_MAX_LENGTH, the comma-separated REMARKS_DENIED_WORDS,
+// and REMARKS_REJECT_PII, which rejects the card numbers and the email addresses.
+func parseRemarksPolicy(loader *env.Loader) (remarks.Policy, error) {
+	var checks []remarks.Check
+
+	if words := remarks.NewWords(strings.Split(loader.GetEnv("REMARKS_DENIED_WORDS", ""), ",")); words != nil {
+		checks = append(checks, words)
+	}
+
+	if loader.GetEnvBool("REMARKS_REJECT_PII", false) {
+		checks = append(checks, remarks.PII{})
+	}
+
+	policy, err := remarks.New(int(loader.GetEnvInt64("REMARKS_MAX_LENGTH", remarks.MaxLength)), checks...)
+	if err != nil {
+		return remarks.Policy{}, fmt.Errorf("%w: REMARKS_MAX_LENGTH: %w", ErrInvalidSetting, err)
+	}
+
+	return policy, nil
+}
+
+// parseRoundingPolicies parses the comma-separated CURRENCY:SCALE:MODE policies, i.e. USD:2:HALF_EVEN,JPY:0:HALF_UP,
+// where the currency * applies to the others.
+func parseRoundingPolicies(value string) (map[string]api.RoundingPolicy, error) {
+	policies := map[string]api.RoundingPolicy{}
+
+	for _, setting := range strings.Split(value, ",") {
+		if setting = strings.TrimSpace(setting); setting == "" {
+			continue
+		}
+
+		parts := strings.Split(setting, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: ROUNDING_POLICIES=%s", ErrInvalidSetting, setting)
+		}
+
+		currency := strings.ToUpper(strings.TrimSpace(parts[0]))
+
+		scale, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 32)
+		policy := api.RoundingPolicy{
+			Mode:  api.RoundingMode(strings.ToUpper(strings.TrimSpace(parts[2]))),
+			Scale: int32(scale),
+		}
+
+		if currency == "" || err != nil || !policy.Valid() {
+			return nil, fmt.Errorf("%w: ROUNDING_POLICIES=%s", ErrInvalidSetting, setting)
+		}
+
+		policies[currency] = policy
+	}
+
+	return policies, nil
+}
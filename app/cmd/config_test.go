@@ -0,0 +1,1504 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/chaos"
+	"github.com/devshark/wallet/app/internal/fx"
+	"github.com/devshark/wallet/app/internal/receipts"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/screening"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/devshark/wallet/app/internal/tagging"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestNewLoader(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "wallet.yaml")
+	err := os.WriteFile(configFile, []byte(`
+port: 8080
+postgres:
+  host: file-host
+  port: 5432
+  user: file-user
+  password: file-password
+  database: file-database
+redis:
+  address: file-redis:6379
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("POSTGRES_USER", "env-user")
+	t.Setenv("POSTGRES_HOST", "env-host")
+
+	loader, err := NewLoader([]string{"--config", configFile, "--postgres-host", "flag-host"})
+	require.NoError(t, err)
+
+	config, err := NewConfig(loader)
+	require.NoError(t, err)
+
+	require.Equal(t, ModeAll, config.mode)
+	require.Equal(t, int64(8080), config.port)
+	require.Equal(t, "flag-host", config.postgres.Host)
+	require.Equal(t, "env-user", config.postgres.User)
+	require.Equal(t, "file-password", config.postgres.Password)
+	require.Equal(t, []string{"file-redis:6379"}, config.redis.Addresses)
+
+	_, err = NewLoader([]string{"--unknown"})
+	require.Error(t, err)
+
+	_, err = NewLoader([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	require.Error(t, err)
+}
+
+func TestWorkerMode(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+
+	t.Run("Subcommand", func(t *testing.T) {
+		t.Setenv("WALLET_MODE", "server")
+
+		loader, err := NewLoader([]string{"worker", "--ledger-check-interval", "1m"})
+		require.NoError(t, err)
+
+		// the worker doesn't require the PORT and REDIS_ADDRESS settings
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, ModeWorker, config.mode)
+		require.False(t, config.mode.RunsServer())
+		require.True(t, config.mode.RunsWorkers())
+		require.Equal(t, time.Minute, config.ledgerCheckInterval)
+	})
+
+	t.Run("Env variable", func(t *testing.T) {
+		t.Setenv("WALLET_MODE", "WORKER")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, ModeWorker, config.mode)
+		require.Equal(t, defaultLedgerCheckInterval, config.ledgerCheckInterval)
+	})
+
+	t.Run("Ledger check schedule", func(t *testing.T) {
+		loader, err := NewLoader([]string{"worker", "--ledger-check-schedule", "0 3 * * *", "--worker-jitter", "30s"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, config.workerJitter)
+
+		from := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+		require.Equal(t, time.Date(2024, time.May, 2, 3, 0, 0, 0, time.UTC), config.ledgerCheckSchedule().Next(from), "instead of the interval")
+	})
+
+	t.Run("Disabled ledger check", func(t *testing.T) {
+		loader, err := NewLoader([]string{"worker", "--ledger-check-interval", "0s"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Nil(t, config.ledgerCheckSchedule())
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		loader, err := NewLoader([]string{"scheduler"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidMode)
+	})
+}
+
+func TestDebugEndpointsConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	hash, err := crypt.HashAPIKey("wk_admin")
+	require.NoError(t, err)
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.debugEndpoints)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--debug-endpoints", "true", "--admin-api-key-hashes", hash})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.debugEndpoints)
+		require.Equal(t, []string{hash}, config.adminAPIKeyHashes)
+	})
+
+	t.Run("Missing admin keys", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--debug-endpoints", "true"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingAdminKeys)
+	})
+
+	t.Run("Malformed admin key hash", func(t *testing.T) {
+		for _, hash := range []string{"wk_admin", "$argon2id$v=19$m=65536,t=3,p=0$c2FsdA$aGFzaA"} {
+			loader, err := NewLoader([]string{"--debug-endpoints", "true", "--admin-api-key-hashes", hash})
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, crypt.ErrInvalidHash, hash)
+			require.ErrorIs(t, err, ErrInvalidSetting, hash)
+		}
+	})
+}
+
+func TestTunableSettings(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Defaults", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, defaultMaxOpenConns, config.postgres.MaxOpenConns)
+		require.Equal(t, defaultMaxIdleConns, config.postgres.MaxIdleConns)
+		require.Equal(t, defaultConnMaxLifetime, config.postgres.ConnMaxLifetime)
+		require.Equal(t, defaultConnMaxIdleTime, config.postgres.ConnMaxIdleTime)
+		require.Zero(t, config.postgres.ExplainSamplePercent)
+		require.Equal(t, repository.LockRows, config.postgres.LockStrategy)
+		require.Equal(t, defaultReadTimeout, config.http.ReadTimeout)
+		require.Equal(t, defaultWriteTimeout, config.http.WriteTimeout)
+		require.Equal(t, defaultHeaderTimeout, config.http.ReadHeaderTimeout)
+		require.Equal(t, defaultIdleTimeout, config.http.IdleTimeout)
+		require.Equal(t, defaultShutdownTimeout, config.http.DrainTimeout, "the whole shutdown")
+		require.False(t, config.http.H2C)
+		require.Equal(t, defaultShutdownTimeout, config.shutdownTimeout)
+		require.Equal(t, defaultCacheExpiry, config.cacheExpiry)
+		require.Equal(t, defaultSubscriptionsInterval, config.subscriptionsInterval)
+	})
+
+	t.Run("Overridden", func(t *testing.T) {
+		loader, err := NewLoader([]string{
+			"--db-max-open-conns", "20",
+			"--db-max-idle-conns", "10",
+			"--db-conn-max-lifetime", "30m",
+			"--db-conn-max-idle-time", "1m",
+			"--db-explain-sample-percent", "0.5",
+			"--db-lock-strategy", "Conditional-Update",
+			"--http-read-timeout", "2s",
+			"--http-write-timeout", "30s",
+			"--http-read-header-timeout", "1s",
+			"--http-idle-timeout", "2m",
+			"--http-drain-timeout", "10s",
+			"--http-h2c", "true",
+			"--shutdown-timeout", "15s",
+			"--cache-expiry", "1m",
+			"--subscriptions-poll-interval", "500ms",
+		})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, 20, config.postgres.MaxOpenConns)
+		require.Equal(t, 10, config.postgres.MaxIdleConns)
+		require.Equal(t, 30*time.Minute, config.postgres.ConnMaxLifetime)
+		require.Equal(t, time.Minute, config.postgres.ConnMaxIdleTime)
+		require.InDelta(t, 0.5, config.postgres.ExplainSamplePercent, 0)
+		require.Equal(t, repository.LockConditionalUpdate, config.postgres.LockStrategy)
+		require.Equal(t, 2*time.Second, config.http.ReadTimeout)
+		require.Equal(t, 30*time.Second, config.http.WriteTimeout)
+		require.Equal(t, time.Second, config.http.ReadHeaderTimeout)
+		require.Equal(t, 2*time.Minute, config.http.IdleTimeout)
+		require.Equal(t, 10*time.Second, config.http.DrainTimeout)
+		require.True(t, config.http.H2C)
+		require.Equal(t, 15*time.Second, config.shutdownTimeout)
+		require.Equal(t, time.Minute, config.cacheExpiry)
+		require.Equal(t, 500*time.Millisecond, config.subscriptionsInterval)
+	})
+
+	invalid := map[string][]string{
+		"Negative pool size":          {"--db-max-open-conns", "-1"},
+		"More idle than open":         {"--db-max-open-conns", "5", "--db-max-idle-conns", "10"},
+		"Negative lifetime":           {"--db-conn-max-lifetime", "-1m"},
+		"Disabled write timeout":      {"--http-write-timeout", "0s"},
+		"Disabled shutdown timeout":   {"--shutdown-timeout", "0s"},
+		"Disabled idle timeout":       {"--http-idle-timeout", "0s"},
+		"Drain past the shutdown":     {"--http-drain-timeout", "10s"},
+		"Negative cache expiry":       {"--cache-expiry", "-5m"},
+		"Disabled subscriptions poll": {"--subscriptions-poll-interval", "0s"},
+		"Negative ledger check":       {"--ledger-check-interval", "-1h"},
+		"Negative database wait time": {"--db-wait-timeout", "-1s"},
+		"Negative reservation TTL":    {"--idempotency-reservation-ttl", "-1h"},
+		"Explain sample over 100":     {"--db-explain-sample-percent", "150"},
+		"Malformed explain sample":    {"--db-explain-sample-percent", "1%"},
+		"Unknown lock strategy":       {"--db-lock-strategy", "optimistic"},
+		"Negative worker jitter":      {"--worker-jitter", "-1s"},
+		"Malformed ledger schedule":   {"--ledger-check-schedule", "3 * *"},
+	}
+
+	for name, args := range invalid {
+		t.Run(name, func(t *testing.T) {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting)
+		})
+	}
+
+	t.Run("Worker ignores the server settings", func(t *testing.T) {
+		loader, err := NewLoader([]string{"worker", "--http-write-timeout", "0s"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.NoError(t, err)
+	})
+}
+
+func TestRedisTopologyConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+
+	t.Run("Sentinel", func(t *testing.T) {
+		t.Setenv("REDIS_MODE", "sentinel")
+		t.Setenv("REDIS_ADDRESS", "sentinel-1:26379, sentinel-2:26379")
+		t.Setenv("REDIS_MASTER_NAME", "wallet")
+		t.Setenv("REDIS_SENTINEL_PASSWORD", "secret")
+		t.Setenv("REDIS_DB", "2")
+		t.Setenv("REDIS_TLS", "true")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, RedisSentinel, config.redis.Mode)
+		require.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, config.redis.Addresses)
+		require.Equal(t, "wallet", config.redis.MasterName)
+		require.Equal(t, "secret", config.redis.SentinelPassword)
+		require.Equal(t, 2, config.redis.DB)
+		require.True(t, config.redis.TLS.Enabled)
+	})
+
+	t.Run("Invalid topology", func(t *testing.T) {
+		t.Setenv("REDIS_MODE", "sentinel")
+		t.Setenv("REDIS_ADDRESS", "sentinel-1:26379")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingMasterName)
+	})
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		t.Setenv("REDIS_MODE", "replica")
+		t.Setenv("REDIS_ADDRESS", "redis:6379")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidRedisMode)
+	})
+}
+
+func TestEventsConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.events.Enabled())
+		require.Equal(t, "wallet", config.events.TopicPrefix)
+	})
+
+	t.Run("Kafka", func(t *testing.T) {
+		t.Setenv("EVENTS_BROKER", "Kafka")
+		t.Setenv("EVENTS_URL", "kafka-1:9092,kafka-2:9092")
+
+		loader, err := NewLoader([]string{"--events-relay-interval", "500ms", "--events-retention", "0"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, EventsKafka, config.events.Broker)
+		require.Equal(t, 500*time.Millisecond, config.events.RelayInterval)
+		require.Zero(t, config.events.Retention)
+
+		publisher, err := NewEventsPublisher(config.events, nil)
+		require.NoError(t, err)
+		require.NoError(t, publisher.Close())
+	})
+
+	t.Run("Missing URL", func(t *testing.T) {
+		t.Setenv("EVENTS_BROKER", "nats")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingEventsURL)
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		t.Setenv("EVENTS_BROKER", "webhook")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingEventsWebhookSecret)
+
+		t.Setenv("EVENTS_WEBHOOK_SECRET", "whsec")
+
+		loader, err = NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err, "without EVENTS_URL")
+		require.Equal(t, EventsWebhook, config.events.Broker)
+
+		publisher, err := NewEventsPublisher(config.events, nil)
+		require.NoError(t, err)
+		require.NoError(t, publisher.Close())
+	})
+
+	t.Run("Invalid relay interval", func(t *testing.T) {
+		t.Setenv("EVENTS_BROKER", "nats")
+		t.Setenv("EVENTS_URL", "nats://localhost:4222")
+		t.Setenv("EVENTS_RELAY_INTERVAL", "0s")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+
+	t.Run("Invalid broker", func(t *testing.T) {
+		t.Setenv("EVENTS_BROKER", "rabbitmq")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidEventsBroker)
+	})
+}
+
+func TestReconciliationConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Default tolerance", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, reconciliation.DefaultDateTolerance, config.reconciliationTolerance)
+	})
+
+	t.Run("Negative tolerance", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--reconciliation-date-tolerance", "-1h"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestScreeningConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, screening.NewDenylist(config.denylistAccounts, config.denylistTerms).Empty())
+	})
+
+	t.Run("Denylist", func(t *testing.T) {
+		t.Setenv("SCREENING_DENYLIST_ACCOUNTS", "sanctioned1, sanctioned2")
+
+		loader, err := NewLoader([]string{"--screening-denylist-terms", "crimea"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, []string{"sanctioned1", " sanctioned2"}, config.denylistAccounts)
+		require.Equal(t, []string{"crimea"}, config.denylistTerms)
+		require.False(t, screening.NewDenylist(config.denylistAccounts, config.denylistTerms).Empty())
+	})
+}
+
+func TestApprovalThresholdsConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	hash, err := crypt.HashAPIKey("wk_test")
+	require.NoError(t, err)
+
+	t.Run("Thresholds", func(t *testing.T) {
+		t.Setenv("ADMIN_API_KEY_HASHES", hash)
+
+		loader, err := NewLoader([]string{"--approval-thresholds", "usd:10000, EUR:9000.50,"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Len(t, config.approvalThresholds, 2)
+		require.True(t, decimal.NewFromInt(10000).Equal(config.approvalThresholds["USD"]))
+		require.True(t, decimal.RequireFromString("9000.50").Equal(config.approvalThresholds["EUR"]))
+	})
+
+	t.Run("Invalid thresholds", func(t *testing.T) {
+		t.Setenv("ADMIN_API_KEY_HASHES", hash)
+
+		for _, value := range []string{"USD", "USD:lots", ":100", "USD:-1"} {
+			loader, err := NewLoader([]string{"--approval-thresholds", value})
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, value)
+		}
+	})
+
+	t.Run("Without approvers", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--approval-thresholds", "USD:10000"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingApprovers)
+	})
+}
+
+func TestRoundingPoliciesConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Policies", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--rounding-policies", "usd:2:half_even, JPY:0:HALF_UP,*:4:HALF_UP,"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, map[string]api.RoundingPolicy{
+			"USD": {Mode: api.RoundHalfEven, Scale: 2},
+			"JPY": {Mode: api.RoundHalfUp, Scale: 0},
+			"*":   {Mode: api.RoundHalfUp, Scale: 4},
+		}, config.roundingPolicies)
+	})
+
+	t.Run("Invalid policies", func(t *testing.T) {
+		for _, value := range []string{"USD:2", "USD:two:HALF_UP", "USD:-1:HALF_UP", "USD:2:DOWN", ":2:HALF_UP"} {
+			loader, err := NewLoader([]string{"--rounding-policies", value})
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, value)
+		}
+	})
+}
+
+func TestProvisioningConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Auto by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, api.ProvisionAuto, config.provisioningPolicy)
+	})
+
+	t.Run("Deposit only", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--account-provisioning", "Deposit-Only"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, api.ProvisionDepositOnly, config.provisioningPolicy)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--account-provisioning", "lazy"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+
+	t.Run("Strict without admin keys", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--account-provisioning", "strict"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingAccountOpeners)
+	})
+}
+
+func TestAccountIDPolicyConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Any by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.NoError(t, config.accountIDs.Validate("alice@example.com"))
+	})
+
+	t.Run("UUID", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--account-id-pattern", "uuid"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.NoError(t, config.accountIDs.Validate("5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f"))
+		require.ErrorIs(t, config.accountIDs.Validate("alice@example.com"), api.ErrInvalidAccountID)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--account-id-pattern", "[a-z"},
+			{"--account-id-min-length", "10", "--account-id-max-length", "5"},
+			{"--account-id-max-length", "256"},
+		} {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, args)
+		}
+	})
+}
+
+func TestRemarksPolicyConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Any by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.NoError(t, config.remarks.Validate("refund alice@example.com"))
+		require.ErrorIs(t, config.remarks.Validate(strings.Repeat("a", 256)), api.ErrInvalidRemarks)
+	})
+
+	t.Run("Screened", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--remarks-max-length", "20", "--remarks-denied-words", "darn, heck", "--remarks-reject-pii", "true"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.NoError(t, config.remarks.Validate("rent"))
+		require.ErrorIs(t, config.remarks.Validate(strings.Repeat("a", 21)), api.ErrInvalidRemarks)
+		require.ErrorIs(t, config.remarks.Validate("oh Heck"), api.ErrInvalidRemarks)
+		require.ErrorIs(t, config.remarks.Validate("to bob@example.com"), api.ErrInvalidRemarks)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--remarks-max-length", "256"},
+			{"--remarks-max-length", "-1"},
+		} {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, args)
+		}
+	})
+}
+
+func TestErrorMessagesConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Nil(t, config.errorMessages)
+	})
+
+	t.Run("Default messages", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--error-messages-enabled", "true"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.NotNil(t, config.errorMessages)
+		require.Equal(t, language.Spanish, config.errorMessages.Negotiate("es"))
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "it.json"), []byte(`{"INSUFFICIENT_BALANCE": "Saldo insufficiente"}`), 0o600))
+
+		loader, err := NewLoader([]string{"--error-messages-enabled", "true", "--error-messages-dir", dir})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, language.Italian, config.errorMessages.Negotiate("it-IT"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "it.json"), []byte(`{"NOT_A_CODE": "sconosciuto"}`), 0o600))
+
+		for _, args := range [][]string{
+			{"--error-messages-dir", t.TempDir()},
+			{"--error-messages-enabled", "true", "--error-messages-dir", dir},
+		} {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, args)
+		}
+	})
+}
+
+func TestFXRatesConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Empty(t, config.fxRates)
+		require.Nil(t, newFXRates(config))
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--fx-rates", "usd/eur:0.92, USD/JPY:151.3"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, map[fx.Pair]decimal.Decimal{
+			{From: "USD", To: "EUR"}: decimal.RequireFromString("0.92"),
+			{From: "USD", To: "JPY"}: decimal.RequireFromString("151.3"),
+		}, config.fxRates)
+		require.IsType(t, &fx.Static{}, newFXRates(config))
+	})
+
+	t.Run("Service", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--fx-rates-url", "https://rates.example.com/v1/rate"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, "https://rates.example.com/v1/rate", config.fxRatesURL)
+		require.IsType(t, &fx.HTTP{}, newFXRates(config))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--fx-rates", "USD/EUR"},
+			{"--fx-rates", "USD:0.92"},
+			{"--fx-rates", "USD/USD:1"},
+			{"--fx-rates", "USD/EUR:0"},
+			{"--fx-rates", "USD/EUR:abc"},
+			{"--fx-rates-url", "rates.example.com"},
+			{"--fx-rates", "USD/EUR:0.92", "--fx-rates-url", "https://rates.example.com"},
+		} {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, args)
+		}
+	})
+}
+
+func TestTransferStatusesConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Statuses", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--transfer-error-statuses", "duplicate_transaction:422, INSUFFICIENT_BALANCE:402,"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, map[api.ErrorCode]int{
+			api.CodeDuplicateTransaction: http.StatusUnprocessableEntity,
+			api.CodeInsufficientBalance:  http.StatusPaymentRequired,
+		}, config.transferStatuses)
+	})
+
+	t.Run("Invalid statuses", func(t *testing.T) {
+		for _, value := range []string{"DUPLICATE_TRANSACTION", "DUPLICATE_TRANSACTION:200", "DUPLICATE_TRANSACTION:500", "NOT_A_CODE:409", "DUPLICATE_TRANSACTION:conflict"} {
+			loader, err := NewLoader([]string{"--transfer-error-statuses", value})
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, value)
+		}
+	})
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Zero(t, config.rateLimitRequests)
+		require.Equal(t, defaultRateLimitWindow, config.rateLimitWindow)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--rate-limit-requests", "100", "--rate-limit-window", "10s"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, int64(100), config.rateLimitRequests)
+		require.Equal(t, 10*time.Second, config.rateLimitWindow)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--rate-limit-requests", "-1"},
+			{"--rate-limit-requests", "100", "--rate-limit-window", "0s"},
+		} {
+			loader, err := NewLoader(args)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, args)
+		}
+	})
+}
+
+func TestReadOnlyConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	loader, err := NewLoader(nil)
+	require.NoError(t, err)
+
+	config, err := NewConfig(loader)
+	require.NoError(t, err)
+	require.False(t, config.readOnly)
+
+	loader, err = NewLoader([]string{"--read-only", "true"})
+	require.NoError(t, err)
+
+	config, err = NewConfig(loader)
+	require.NoError(t, err)
+	require.True(t, config.readOnly)
+}
+
+func TestTaxonomyConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Any tag by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Empty(t, tagging.New(config.taxonomy).Tags())
+	})
+
+	t.Run("Taxonomy", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--transaction-taxonomy", "food:groceries, Travel,payroll,"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, []string{"food:groceries", "payroll", "travel"}, tagging.New(config.taxonomy).Tags())
+	})
+}
+
+func TestStatementsConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.statements.Enabled())
+		require.Equal(t, statements.DefaultMaxAttempts, config.statements.MaxAttempts)
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		t.Setenv("STATEMENTS_WEBHOOK_SECRET", "whsec")
+
+		loader, err := NewLoader([]string{"--statements-interval", "1h", "--statements-max-attempts", "3"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.statements.Enabled())
+		require.Equal(t, time.Hour, config.statements.Interval)
+		require.Equal(t, 3, config.statements.MaxAttempts)
+		require.NotNil(t, NewStatementScheduler(config.statements, nil))
+	})
+
+	t.Run("Missing channels", func(t *testing.T) {
+		t.Setenv("STATEMENTS_INTERVAL", "1h")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingStatementChannels)
+	})
+
+	t.Run("Missing SMTP sender", func(t *testing.T) {
+		t.Setenv("STATEMENTS_INTERVAL", "1h")
+		t.Setenv("SMTP_ADDRESS", "smtp.example.com:587")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingSMTPFrom)
+	})
+
+	t.Run("Invalid max attempts", func(t *testing.T) {
+		t.Setenv("STATEMENTS_INTERVAL", "1h")
+		t.Setenv("STATEMENTS_WEBHOOK_SECRET", "whsec")
+		t.Setenv("STATEMENTS_MAX_ATTEMPTS", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestReceiptsConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.receipts.Enabled())
+		require.Equal(t, receipts.DefaultMaxAttempts, config.receipts.MaxAttempts)
+	})
+
+	t.Run("Without outbox", func(t *testing.T) {
+		t.Setenv("RECEIPTS_INTERVAL", "10s")
+		t.Setenv("SMTP_ADDRESS", "smtp.example.com:587")
+		t.Setenv("SMTP_FROM", "wallet@example.com")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrReceiptsWithoutOutbox)
+	})
+
+	t.Setenv("EVENTS_BROKER", "nats")
+	t.Setenv("EVENTS_URL", "nats://localhost:4222")
+
+	t.Run("Missing SMTP", func(t *testing.T) {
+		t.Setenv("RECEIPTS_INTERVAL", "10s")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrMissingReceiptsSMTP)
+	})
+
+	t.Setenv("SMTP_ADDRESS", "smtp.example.com:587")
+	t.Setenv("SMTP_FROM", "wallet@example.com")
+
+	t.Run("Receipts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "receipts.tmpl")
+		require.NoError(t, os.WriteFile(path, []byte(`{{define "DEPOSIT.subject"}}Deposited{{end}}`), 0o600))
+
+		loader, err := NewLoader([]string{"--receipts-interval", "10s", "--receipts-max-attempts", "3", "--receipts-templates", path})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.receipts.Enabled())
+		require.Equal(t, 10*time.Second, config.receipts.Interval)
+		require.Equal(t, 3, config.receipts.MaxAttempts)
+		require.NotNil(t, config.receipts.Templates)
+		require.NotNil(t, NewReceiptsNotifier(config.receipts, nil))
+	})
+
+	t.Run("Invalid templates", func(t *testing.T) {
+		t.Setenv("RECEIPTS_INTERVAL", "10s")
+		t.Setenv("RECEIPTS_TEMPLATES", filepath.Join(t.TempDir(), "missing.tmpl"))
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+
+	t.Run("Invalid max attempts", func(t *testing.T) {
+		t.Setenv("RECEIPTS_INTERVAL", "10s")
+		t.Setenv("RECEIPTS_MAX_ATTEMPTS", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestEncryptionConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	key, err := crypt.GenerateFieldKey()
+	require.NoError(t, err)
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Nil(t, config.encryption)
+		require.Equal(t, defaultErasureInterval, config.erasureInterval)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		t.Setenv("ENCRYPTION_KEYS", "2024:"+key+"\n2025:"+key)
+		t.Setenv("ENCRYPTION_ACTIVE_KEY", "2025")
+
+		loader, err := NewLoader([]string{"--erasure-interval", "0"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, "2025", config.encryption.ActiveKeyID())
+		require.Zero(t, config.erasureInterval)
+	})
+
+	for name, values := range map[string][2]string{
+		"missing active key": {"2024:" + key, ""},
+		"unknown active key": {"2024:" + key, "2025"},
+		"missing keys":       {"", "2024"},
+		"invalid key":        {"2024:c2hvcnQ=", "2024"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("ENCRYPTION_KEYS", values[0])
+			t.Setenv("ENCRYPTION_ACTIVE_KEY", values[1])
+
+			loader, err := NewLoader(nil)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting)
+		})
+	}
+
+	t.Run("Negative erasure interval", func(t *testing.T) {
+		t.Setenv("ERASURE_INTERVAL", "-1m")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestSandboxConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		t.Setenv("SANDBOX_DEPOSIT_QUOTAS", "USD:1000")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.sandbox.Enabled)
+		require.Empty(t, config.sandbox.DepositQuotas, "the quotas only apply to the sandbox")
+		require.Empty(t, config.sandbox.connectionOptions())
+	})
+
+	t.Run("Sandbox", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--sandbox-enabled", "true", "--sandbox-deposit-quotas", "usd:1000,EUR:500"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.sandbox.Enabled)
+		require.Equal(t, defaultSandboxSchema, config.sandbox.Schema)
+		require.Equal(t, " search_path=sandbox,public", config.sandbox.connectionOptions())
+		require.True(t, decimal.NewFromInt(1000).Equal(config.sandbox.DepositQuotas["USD"]))
+	})
+
+	for _, value := range []string{"public", "Sandbox", "sandbox; DROP SCHEMA public", ""} {
+		t.Run("Invalid schema "+value, func(t *testing.T) {
+			t.Setenv("SANDBOX_ENABLED", "true")
+			t.Setenv("SANDBOX_SCHEMA", value)
+
+			loader, err := NewLoader(nil)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting)
+		})
+	}
+
+	t.Run("Invalid quotas", func(t *testing.T) {
+		t.Setenv("SANDBOX_ENABLED", "true")
+
+		loader, err := NewLoader([]string{"--sandbox-deposit-quotas", "USD:-1"})
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestActivityFeedConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.activityFeed)
+	})
+
+	t.Run("Without outbox", func(t *testing.T) {
+		t.Setenv("ACTIVITY_FEED_ENABLED", "true")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrActivityWithoutOutbox)
+	})
+
+	t.Setenv("EVENTS_BROKER", "nats")
+	t.Setenv("EVENTS_URL", "nats://localhost:4222")
+
+	t.Run("Activity feed", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--activity-feed-enabled", "true", "--activity-feed-interval", "5s"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.activityFeed)
+		require.Equal(t, 5*time.Second, config.activityInterval)
+	})
+
+	t.Run("Zero interval", func(t *testing.T) {
+		t.Setenv("ACTIVITY_FEED_ENABLED", "true")
+		t.Setenv("ACTIVITY_FEED_INTERVAL", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestTransferQueueConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.transferQueue)
+		require.Equal(t, time.Second, config.transferQueueInterval)
+	})
+
+	t.Run("Transfer queue", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--transfer-queue-enabled", "true", "--transfer-queue-interval", "200ms"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.transferQueue)
+		require.Equal(t, 200*time.Millisecond, config.transferQueueInterval)
+	})
+
+	t.Run("Zero interval", func(t *testing.T) {
+		t.Setenv("TRANSFER_QUEUE_ENABLED", "true")
+		t.Setenv("TRANSFER_QUEUE_INTERVAL", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestScheduledTransfersConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.scheduledTransfers)
+		require.Equal(t, 10*time.Second, config.scheduledInterval)
+	})
+
+	t.Run("Scheduled transfers", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--transfer-scheduled-enabled", "true", "--transfer-scheduled-interval", "1m"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.scheduledTransfers)
+		require.Equal(t, time.Minute, config.scheduledInterval)
+	})
+
+	t.Run("Zero interval", func(t *testing.T) {
+		t.Setenv("TRANSFER_SCHEDULED_ENABLED", "true")
+		t.Setenv("TRANSFER_SCHEDULED_INTERVAL", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+}
+
+func TestWorkerLocksConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("WALLET_MODE", "worker")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.locksWorkers())
+	})
+
+	t.Run("Without redis", func(t *testing.T) {
+		t.Setenv("WORKER_LOCKS", "true")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		// like the server, the workers locking their jobs require REDIS_ADDRESS
+		require.Panics(t, func() {
+			_, _ = NewConfig(loader)
+		})
+	})
+
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Worker locks", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--worker-locks", "true", "--worker-lock-ttl", "1m"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.locksWorkers())
+		require.Equal(t, time.Minute, config.workerLockTTL)
+		require.Equal(t, []string{"localhost:6379"}, config.redis.Addresses)
+	})
+
+	t.Run("Zero TTL", func(t *testing.T) {
+		t.Setenv("WORKER_LOCKS", "true")
+		t.Setenv("WORKER_LOCK_TTL", "0")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		_, err = NewConfig(loader)
+		require.ErrorIs(t, err, ErrInvalidSetting)
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		t.Setenv("WALLET_MODE", "server")
+		t.Setenv("PORT", "8080")
+		t.Setenv("WORKER_LOCKS", "true")
+
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.locksWorkers(), "the server runs no jobs")
+	})
+}
+
+func TestChaosConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.False(t, config.chaos.Enabled())
+		require.False(t, config.chaos.Targeted(chaosRepository))
+	})
+
+	t.Run("Faults", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--chaos-failure-percent", "5", "--chaos-delay-percent", "20", "--chaos-delay", "2s", "--chaos-targets", " Redis,"})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.True(t, config.chaos.Enabled())
+		require.Equal(t, chaos.Faults{FailurePercent: 5, DelayPercent: 20, Delay: 2 * time.Second}, config.chaos.Faults)
+		require.True(t, config.chaos.Targeted(chaosRedis))
+		require.False(t, config.chaos.Targeted(chaosRepository))
+	})
+
+	for name, setting := range map[string][2]string{
+		"failure above 100": {"CHAOS_FAILURE_PERCENT", "101"},
+		"negative delay":    {"CHAOS_DELAY", "-1s"},
+		"delay percent":     {"CHAOS_DELAY_PERCENT", "-5"},
+		"unknown target":    {"CHAOS_TARGETS", "repository,kafka"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(setting[0], setting[1])
+
+			loader, err := NewLoader(nil)
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting)
+		})
+	}
+}
+
+func TestCompanyAccountConfig(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_PORT", "5432")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_PASSWORD", "postgres")
+	t.Setenv("POSTGRES_DATABASE", "postgres")
+	t.Setenv("PORT", "8080")
+	t.Setenv("REDIS_ADDRESS", "localhost:6379")
+
+	t.Run("Default", func(t *testing.T) {
+		loader, err := NewLoader(nil)
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, api.CompanyAccountID, config.companyAccountID)
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		loader, err := NewLoader([]string{"--company-account-id", " settlement-2024 "})
+		require.NoError(t, err)
+
+		config, err := NewConfig(loader)
+		require.NoError(t, err)
+		require.Equal(t, "settlement-2024", config.companyAccountID)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, value := range []string{"the company", api.DisputesAccountID, "COMPANY:Disputes", strings.Repeat("a", 256)} {
+			loader, err := NewLoader([]string{"--company-account-id", value})
+			require.NoError(t, err)
+
+			_, err = NewConfig(loader)
+			require.ErrorIs(t, err, ErrInvalidSetting, value)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/pkg/events"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/webhook"
+	"github.com/nats-io/nats.go"
+)
+
+// EventsBroker is where the ledger events are published. None disables the outbox.
+type EventsBroker string
+
+const (
+	EventsNone  EventsBroker = ""
+	EventsKafka EventsBroker = "kafka"
+	EventsNATS  EventsBroker = "nats"
+	// EventsWebhook posts the events to the webhook subscriptions instead of a broker.
+	EventsWebhook EventsBroker = "webhook"
+)
+
+const (
+	defaultEventsRelayInterval = time.Second
+	defaultEventsRetention     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidEventsBroker        = errors.New("invalid EVENTS_BROKER, must be one of kafka, nats, webhook, or empty")
+	ErrMissingEventsURL           = errors.New("EVENTS_BROKER requires EVENTS_URL")
+	ErrMissingEventsWebhookSecret = errors.New("EVENTS_BROKER=webhook requires EVENTS_WEBHOOK_SECRET")
+)
+
+func ParseEventsBroker(value string) (EventsBroker, error) {
+	switch broker := EventsBroker(strings.ToLower(strings.TrimSpace(value))); broker {
+	case EventsNone, EventsKafka, EventsNATS, EventsWebhook:
+		return broker, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidEventsBroker, value)
+	}
+}
+
+type EventsConfig struct {
+	Broker EventsBroker
+	// URL is the comma-separated brokers for Kafka, or the server URL for NATS, which may itself list several servers.
+	URL         string
+	TopicPrefix string
+	// WebhookSecret signs the events posted to the webhook subscriptions.
+	WebhookSecret string
+	// RelayInterval is how often the worker publishes the pending events.
+	RelayInterval time.Duration
+	// Retention is how long the published events are kept in the outbox. 0 keeps them.
+	Retention time.Duration
+}
+
+func (c EventsConfig) Enabled() bool {
+	return c.Broker != EventsNone
+}
+
+func (c EventsConfig) Validate() error {
+	switch {
+	case c.Broker == EventsWebhook && c.WebhookSecret == "":
+		return ErrMissingEventsWebhookSecret
+	case c.Enabled() && c.Broker != EventsWebhook && c.URL == "":
+		return ErrMissingEventsURL
+	default:
+		return nil
+	}
+}
+
+// NewEventsPublisher connects to the configured broker, or posts to the webhook subscriptions.
+func NewEventsPublisher(config EventsConfig, subscriptions events.WebhookSubscriptions) (events.Publisher, error) {
+	switch config.Broker {
+	case EventsWebhook:
+		sender := webhook.NewSender([]byte(config.WebhookSecret))
+
+		return events.NewWebhookPublisher(subscriptions, sender).
+			WithLogger(logging.Component(slog.Default(), "webhooks")), nil
+	case EventsKafka:
+		writer := events.NewKafkaWriter(splitAddresses(config.URL)...)
+
+		return events.NewKafkaPublisher(writer).WithTopicPrefix(config.TopicPrefix), nil
+	case EventsNATS:
+		conn, err := nats.Connect(config.URL, nats.Name("wallet"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to nats: %w", err)
+		}
+
+		return events.NewNATSPublisher(conn).WithTopicPrefix(config.TopicPrefix), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEventsBroker, config.Broker)
+	}
+}
@@ -0,0 +1,261 @@
+// loadtest drives the transfer endpoint with a mix of contended and uncontended transfers,
+// and reports the latency percentiles and, given the database, the sessions waiting on the row locks.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/loadtest"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/devshark/wallet/client"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUsage           = errors.New("usage error")
+	ErrSeedingFailed   = errors.New("failed to fund the accounts")
+	ErrTooManyFailures = errors.New("too many failed transfers")
+)
+
+const (
+	urlEnv = "LOADTEST_URL"
+	dsnEnv = "LOADTEST_DSN"
+
+	defaultURL = "http://localhost:8080"
+	clientName = "loadtest"
+
+	requestTimeout = 10 * time.Second
+)
+
+const usage = `usage: loadtest [flags]
+
+Funds a fresh set of accounts, then transfers between them for the duration.
+A share of the transfers, the contention, only moves money between the few hot accounts.
+
+flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type options struct {
+	baseURL      string
+	dsn          string
+	currency     string
+	amount       decimal.Decimal
+	seedBalance  decimal.Decimal
+	mix          loadtest.Mix
+	run          loadtest.Options
+	lockInterval time.Duration
+	maxFailures  float64
+}
+
+func parseOptions(args []string, stderr io.Writer) (options, error) {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	baseURL := flags.String("url", envOr(urlEnv, defaultURL), "the wallet API url")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the database, to sample the lock waits (optional)")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send transfers")
+	concurrency := flags.Int("concurrency", 16, "the number of concurrent requests")
+	rate := flags.Int("rate", 0, "the maximum transfers per second, 0 is unlimited")
+	hot := flags.Int("hot-accounts", 2, "the number of contended accounts")
+	cold := flags.Int("cold-accounts", 200, "the number of uncontended accounts")
+	contention := flags.Float64("contention", 0.2, "the share of transfers between the hot accounts, from 0 to 1")
+	currency := flags.String("currency", "LTX", "the currency of the transfers, to keep them apart from the real ones")
+	amount := flags.String("amount", "0.01", "the amount of each transfer")
+	seed := flags.String("seed-balance", "1000000", "the balance deposited in every account before the run")
+	lockInterval := flags.Duration("lock-sample-interval", 100*time.Millisecond, "how often the lock waits are sampled")
+	maxFailures := flags.Float64("max-failures", 0.01, "the share of failed transfers above which the run fails")
+
+	if err := flags.Parse(args); err != nil {
+		return options{}, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	if *concurrency < 1 || *duration <= 0 || *rate < 0 || *lockInterval <= 0 {
+		return options{}, fmt.Errorf("%w: the concurrency, duration and lock sample interval must be positive", ErrUsage)
+	}
+
+	parsedAmount, err := decimal.NewFromString(*amount)
+	if err != nil || !parsedAmount.IsPositive() {
+		return options{}, fmt.Errorf("%w: invalid amount %q", ErrUsage, *amount)
+	}
+
+	seedBalance, err := decimal.NewFromString(*seed)
+	if err != nil || !seedBalance.IsPositive() {
+		return options{}, fmt.Errorf("%w: invalid seed balance %q", ErrUsage, *seed)
+	}
+
+	// every run has its own accounts, so the balances of the previous runs don't matter
+	mix, err := loadtest.NewMix("loadtest-"+uuid.NewString()[:8], *hot, *cold, *contention)
+	if err != nil {
+		return options{}, fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	return options{
+		baseURL:     strings.TrimSuffix(*baseURL, "/"),
+		dsn:         *dsn,
+		currency:    *currency,
+		amount:      parsedAmount,
+		seedBalance: seedBalance,
+		mix:         mix,
+		run: loadtest.Options{
+			Duration:    *duration,
+			Concurrency: *concurrency,
+			Rate:        *rate,
+		},
+		lockInterval: *lockInterval,
+		maxFailures:  *maxFailures,
+	}, nil
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: opts.run.Concurrency,
+		},
+	}
+	defer httpClient.CloseIdleConnections()
+
+	fmt.Fprintf(stderr, "funding %d accounts\n", len(opts.mix.Accounts()))
+
+	if err = seed(ctx, opts); err != nil {
+		return err
+	}
+
+	var sampler *loadtest.LockSampler
+
+	if opts.dsn != "" {
+		db, err := sql.Open("postgres", opts.dsn)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer db.Close()
+
+		sampler = loadtest.NewLockSampler(db, opts.lockInterval)
+
+		samplerCtx, stopSampler := context.WithCancel(ctx)
+		defer stopSampler()
+
+		go sampler.Run(samplerCtx)
+	}
+
+	fmt.Fprintf(stderr, "transferring for %s with %d concurrent requests, %.0f%% contended\n",
+		opts.run.Duration, opts.run.Concurrency, opts.mix.Contention*100)
+
+	report := loadtest.Run(ctx, opts.run, transferTarget(httpClient, opts))
+
+	report.Print(stdout)
+
+	if sampler != nil {
+		sampler.Stats().Print(stdout)
+	}
+
+	failed := report.Requests - report.Statuses[http.StatusCreated]
+	if report.Requests == 0 || float64(failed)/float64(report.Requests) > opts.maxFailures {
+		return fmt.Errorf("%w: %d of %d", ErrTooManyFailures, failed, report.Requests)
+	}
+
+	return nil
+}
+
+// seed deposits the seed balance in every account, so the transfers never fail for insufficient balance.
+func seed(ctx context.Context, opts options) error {
+	operator := client.NewAccountOperatorClient(opts.baseURL).WithName(clientName)
+
+	for _, account := range opts.mix.Accounts() {
+		_, err := operator.Deposit(ctx, &api.DepositRequest{
+			ToAccountID: account,
+			Currency:    opts.currency,
+			Amount:      opts.seedBalance,
+			Remarks:     "loadtest seed",
+		}, "loadtest-"+uuid.NewString())
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrSeedingFailed, account, err)
+		}
+	}
+
+	return nil
+}
+
+// transferTarget posts a transfer between the next pair of accounts of the mix.
+// The response is only drained, as the latency is what's measured.
+func transferTarget(httpClient *http.Client, opts options) loadtest.Target {
+	return func(ctx context.Context, rng *rand.Rand) (int, error) {
+		from, to := opts.mix.Next(rng)
+
+		payload, err := json.Marshal(&api.TransferRequest{
+			FromAccountID: from,
+			ToAccountID:   to,
+			Currency:      opts.currency,
+			Amount:        opts.amount,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.baseURL+"/transfer", bytes.NewReader(payload))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(rest.IdempotencyKeyHeader, "loadtest-"+uuid.NewString())
+		req.Header.Set("User-Agent", clientName)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("failed to transfer: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// reuses the connection
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return resp.StatusCode, nil
+	}
+}
+
+func envOr(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return defaultValue
+}
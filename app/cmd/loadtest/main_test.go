@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWallet records the funded accounts, and accepts the transfers between them.
+type fakeWallet struct {
+	mu     sync.Mutex
+	funded map[string]bool
+}
+
+func newFakeWallet(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	wallet := &fakeWallet{funded: map[string]bool{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /deposit", func(w http.ResponseWriter, r *http.Request) {
+		request := &api.DepositRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+		require.NotEmpty(t, r.Header.Get(rest.IdempotencyKeyHeader))
+
+		wallet.mu.Lock()
+		wallet.funded[request.ToAccountID] = true
+		wallet.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&api.Transaction{AccountID: request.ToAccountID, Type: api.CREDIT})
+	})
+	mux.HandleFunc("POST /transfer", func(w http.ResponseWriter, r *http.Request) {
+		request := &api.TransferRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+
+		wallet.mu.Lock()
+		funded := wallet.funded[request.FromAccountID] && wallet.funded[request.ToAccountID]
+		wallet.mu.Unlock()
+
+		if !funded || request.FromAccountID == request.ToAccountID {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`[]`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRun(t *testing.T) {
+	server := newFakeWallet(t)
+
+	var stdout, stderr bytes.Buffer
+
+	err := run(context.Background(), []string{
+		"--url", server.URL,
+		"--duration", "200ms",
+		"--concurrency", "4",
+		"--hot-accounts", "2",
+		"--cold-accounts", "5",
+		"--contention", "0.5",
+	}, &stdout, &stderr)
+	require.NoError(t, err, stderr.String())
+
+	require.Contains(t, stderr.String(), "funding 7 accounts")
+	require.Contains(t, stdout.String(), "status 201")
+	require.NotContains(t, stdout.String(), "status 422")
+	require.Contains(t, stdout.String(), "p99")
+}
+
+func TestRunFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/deposit" {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := run(context.Background(), []string{"--url", server.URL, "--duration", "100ms", "--cold-accounts", "2", "--contention", "0"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrTooManyFailures)
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"--unknown"},
+		{"--concurrency", "0"},
+		{"--amount", "-1"},
+		{"--seed-balance", "abc"},
+		{"--contention", "2"},
+	} {
+		err := run(context.Background(), args, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage, args)
+	}
+}
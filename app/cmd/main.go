@@ -3,152 +3,239 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/devshark/wallet/app/internal/migration"
+	"github.com/devshark/wallet/app/internal/declines"
 	"github.com/devshark/wallet/app/internal/repository"
-	"github.com/devshark/wallet/app/rest"
-	"github.com/devshark/wallet/pkg/env"
-	"github.com/go-redis/redis/v8"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/app/internal/screening"
+	"github.com/devshark/wallet/app/internal/tagging"
+	"github.com/devshark/wallet/pkg/buildinfo"
+	"github.com/devshark/wallet/pkg/lifecycle"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/retry"
 )
 
+// the defaults of the settings that can be tuned without recompiling, see NewConfig.
 const (
-	shutdownTimeout = 5 * time.Second
-	readTimeout     = 5 * time.Second
-	writeTimeout    = 10 * time.Second
+	defaultShutdownTimeout = 5 * time.Second
+	defaultReadTimeout     = 5 * time.Second
+	defaultWriteTimeout    = 10 * time.Second
+	defaultHeaderTimeout   = 2 * time.Second
+	defaultIdleTimeout     = 60 * time.Second
 
-	cacheExpiry = 5 * time.Minute
+	defaultCacheExpiry = 5 * time.Minute
 
-	maxIdleConns    = 5
-	connMaxLifetime = 60 * time.Minute
-	connMaxIdleTime = 10 * time.Minute
+	defaultRateLimitWindow = time.Minute
+
+	defaultSubscriptionsInterval = time.Second
+
+	defaultMaxOpenConns    = 0 // unlimited
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 60 * time.Minute
+	defaultConnMaxIdleTime = 10 * time.Minute
+
+	defaultDBWaitTimeout = 60 * time.Second
+
+	defaultFeatureFlagsInterval = 30 * time.Second
 )
 
 func main() {
 	ctx := context.Background()
 
-	config := NewConfig()
-	logger := log.Default()
+	// used until the configured logger is available
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	loader, err := NewLoader(os.Args[1:])
+	if err != nil {
+		fatal(ctx, logger, "failed to load configuration", err)
+	}
+
+	config, err := NewConfig(loader)
+	if err != nil {
+		fatal(ctx, logger, "invalid configuration", err)
+	}
+
+	logger, err = logging.New(os.Stderr, config.logLevel, config.logFormat)
+	if err != nil {
+		fatal(ctx, slog.New(slog.NewTextHandler(os.Stderr, nil)), "failed to configure logger", err)
+	}
+
+	// also routes the standard library's log package through the structured logger
+	slog.SetDefault(logger)
 
-	connStr := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=disable",
+	logger = logging.Component(logger, "main")
+
+	// served with the other expvar variables under /debug/vars
+	buildinfo.Publish()
+
+	connStr := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=disable%s",
 		config.postgres.User,
 		config.postgres.Password,
 		config.postgres.Host,
 		config.postgres.Port,
 		config.postgres.Database,
+		config.sandbox.connectionOptions(),
 	)
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		logger.Fatalf("Failed to connect to database: %v", err)
+		fatal(ctx, logger, "failed to connect to database", err)
+	}
+
+	db.SetMaxOpenConns(config.postgres.MaxOpenConns)
+	db.SetMaxIdleConns(config.postgres.MaxIdleConns)
+	db.SetConnMaxLifetime(config.postgres.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.postgres.ConnMaxIdleTime)
+
+	// the database may still be starting i.e. with docker compose or kubernetes, so wait for it instead of failing right away
+	if err = waitForDatabase(ctx, db, config.dbWaitTimeout, logger); err != nil {
+		fatal(ctx, logger, "failed to reach database", err)
+	}
+
+	repo := repository.NewPostgresRepository(db).
+		WithCustomLogger(slog.Default()).
+		WithCompanyAccount(config.companyAccountID)
+
+	if config.postgres.ExplainSamplePercent > 0 {
+		repo.WithQueryPlanSampling(config.postgres.ExplainSamplePercent)
+	}
+
+	if config.postgres.LockStrategy != "" {
+		repo.WithLockStrategy(config.postgres.LockStrategy)
+	}
+
+	// the events are only written to the outbox when there is a broker to relay them to
+	if config.events.Enabled() {
+		repo.WithOutbox()
+	}
+
+	// the relay keeps the events until they're projected
+	if config.activityFeed {
+		repo.WithActivityFeed()
 	}
 
-	db.SetMaxIdleConns(maxIdleConns)
-	db.SetConnMaxLifetime(connMaxLifetime)
-	db.SetConnMaxIdleTime(connMaxIdleTime)
+	// the relay keeps the events until their receipts are queued
+	if config.receipts.Enabled() {
+		repo.WithReceipts()
+	}
+
+	// only the server posts transfers, the lists are empty otherwise
+	if denylist := screening.NewDenylist(config.denylistAccounts, config.denylistTerms); !denylist.Empty() {
+		repo.WithScreening(denylist)
+	}
+
+	if len(config.approvalThresholds) > 0 {
+		repo.WithApprovalThresholds(config.approvalThresholds)
+	}
+
+	// the queued and scheduled transfers aren't converted, the worker leaves it nil
+	if provider := newFXRates(config); provider != nil {
+		repo.WithFXRates(provider)
+	}
 
-	if err = db.Ping(); err != nil {
-		logger.Fatalf("Failed to reach database: %v", err)
+	// the worker charges the fees of the queued and scheduled transfers as well
+	if config.fees {
+		repo.WithFees()
 	}
 
-	migrator := migration.NewMigrator(db, "migrations").
-		WithCustomLogger(logger)
-	if err = migrator.Up(ctx); err != nil {
-		logger.Fatalf("Failed to migrate database: %v", err)
+	// only the server transfers, the worker leaves it empty
+	if config.provisioningPolicy != "" {
+		repo.WithProvisioningPolicy(config.provisioningPolicy)
 	}
 
-	logger.Println("Database migrated successfully")
+	repo.WithTaxonomy(tagging.New(config.taxonomy)).
+		WithAccountIDPolicy(config.accountIDs).
+		WithRounding(rounding.New(config.roundingPolicies))
 
-	repo := repository.NewPostgresRepository(db)
+	// served with the other expvar variables under /debug/vars
+	repo.WithTransferHooks(declines.NewCounter())
 
-	redisClient := redis.NewClient(&config.redisOptions)
+	if config.encryption != nil {
+		repo.WithFieldEncryption(config.encryption)
+	}
+
+	if config.sandbox.Enabled {
+		repo.WithSandboxQuotas(config.sandbox.DepositQuotas)
+
+		logger.WarnContext(ctx, "sandbox mode, the money is fake", slog.String("schema", config.sandbox.Schema))
+	}
+
+	// the closers registered first are closed last, after the components using them have stopped
+	manager := lifecycle.NewManager(config.shutdownTimeout).
+		WithLogger(logging.Component(slog.Default(), "lifecycle")).
+		OnShutdown("postgres", lifecycle.Closer(db))
+
+	if config.mode.RunsServer() {
+		if err = registerServer(ctx, manager, config, db, repo); err != nil {
+			fatal(ctx, logger, "failed to start the server", err)
+		}
+	}
 
-	server := rest.NewAPIServer(repo).
-		AddPinger(func(ctx context.Context) error {
-			return db.PingContext(ctx)
-		}).
-		AddPinger(func(ctx context.Context) error {
-			return redisClient.Ping(ctx).Err()
-		}).
-		WithCustomLogger(logger).
-		WithCacheMiddleware(redisClient, cacheExpiry).
-		HTTPServer(config.port, readTimeout, writeTimeout)
+	if config.mode.RunsWorkers() {
+		if err = registerWorkers(manager, config, repo); err != nil {
+			fatal(ctx, logger, "failed to start the workers", err)
+		}
+	}
 
-	// subscribe for the shutdown signals
-	stop := make(chan os.Signal, 1)
-	signal.Notify(
-		stop,
+	// cancel the context on the shutdown signals
+	ctx, stop := signal.NotifyContext(ctx,
 		os.Interrupt,
 		syscall.SIGHUP,
 		syscall.SIGINT,
 		syscall.SIGTERM,
 		syscall.SIGQUIT,
 	)
+	defer stop()
 
-	// run the http server in a goroutine
-	go func() {
-		logger.Printf("listening on port %d", config.port)
-
-		if err := server.ListenAndServe(); !errors.Is(err, nil) && !errors.Is(err, http.ErrServerClosed) {
-			logger.Fatalf("http server failed to start: %v", err)
-		}
-
-		logger.Println("http server stopped")
-	}()
-
-	logger.Print("the app is running")
+	logger.InfoContext(ctx, "the app is running", slog.String("mode", string(config.mode)), buildinfo.Get().Attr())
 
-	// block and listen for the shutdown signals
-	<-stop
-
-	log.Print("Shutting down...")
-	// if Shutdown takes too long, cancel the context
-	ctx, cancel := context.WithTimeout(ctx, shutdownTimeout)
-
-	if err := server.Shutdown(ctx); err != nil {
-		cancel()
-		log.Fatal("Shutdown", err)
+	// blocks until a signal is received or a task fails, then drains and closes everything
+	if err := manager.Run(ctx); err != nil {
+		stop()
+		fatal(ctx, logger, "failed to shut down gracefully", err)
 	}
 
-	cancel()
-	log.Print("Gracefully stopped.")
+	logger.InfoContext(ctx, "gracefully stopped")
 }
 
-type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Database string
-}
+// waitForDatabase pings the database with backoff until it responds or the timeout is reached.
+// The listeners are only started afterwards, so the process doesn't report ready before its database.
+func waitForDatabase(ctx context.Context, db *sql.DB, timeout time.Duration, logger *slog.Logger) error {
+	start := time.Now()
+	attempts := 0
+
+	policy := retry.DefaultPolicy().
+		WithMaxElapsed(timeout).
+		WithOnRetry(func(attempt int, err error, next time.Duration) {
+			logger.WarnContext(ctx, "database not reachable yet",
+				slog.Int("attempt", attempt),
+				slog.Duration("retry_in", next),
+				slog.Any("error", err),
+			)
+		})
+
+	err := retry.Do(ctx, policy, func(ctx context.Context) error {
+		attempts++
+
+		return db.PingContext(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("database not reachable within %s: %w", timeout, err)
+	}
+
+	logger.InfoContext(ctx, "database is ready", slog.Int("attempts", attempts), slog.Duration("waited", time.Since(start)))
 
-type Config struct {
-	port         int64
-	postgres     DBConfig
-	redisOptions redis.Options
+	return nil
 }
 
-func NewConfig() Config {
-	return Config{
-		port: env.RequireEnvInt64("PORT"),
-		postgres: DBConfig{
-			Host:     env.RequireEnv("POSTGRES_HOST"),
-			Port:     env.RequireEnv("POSTGRES_PORT"),
-			User:     env.RequireEnv("POSTGRES_USER"),
-			Password: env.RequireEnv("POSTGRES_PASSWORD"),
-			Database: env.RequireEnv("POSTGRES_DATABASE"),
-		},
-		redisOptions: redis.Options{
-			Addr:     env.RequireEnv("REDIS_ADDRESS"),
-			Username: env.GetEnv("REDIS_USERNAME", ""), // optional
-			Password: env.GetEnv("REDIS_PASSWORD", ""), // optional
-		},
-	}
+// fatal logs the error and exits, the structured equivalent of log.Fatal.
+func fatal(ctx context.Context, logger *slog.Logger, msg string, err error) {
+	logger.ErrorContext(ctx, msg, slog.Any("error", err))
+	os.Exit(1)
 }
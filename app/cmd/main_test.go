@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/retry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDatabaseTimeout(t *testing.T) {
+	// a port where nothing listens
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	db, err := sql.Open("postgres", "postgres://postgres:postgres@"+addr+"/postgres?sslmode=disable&connect_timeout=1")
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	start := time.Now()
+
+	err = waitForDatabase(context.Background(), db, 300*time.Millisecond, logging.Discard())
+	require.ErrorIs(t, err, retry.ErrExhausted)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidMode = errors.New("invalid mode")
+
+// Mode selects which subsystems the process runs, so the API and the background jobs can be scaled independently.
+type Mode string
+
+const (
+	// ModeAll runs the HTTP API and the background jobs in the same process.
+	ModeAll Mode = "all"
+	// ModeServer runs the HTTP API only.
+	ModeServer Mode = "server"
+	// ModeWorker runs the background jobs only, without the HTTP listener.
+	ModeWorker Mode = "worker"
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case ModeAll, ModeServer, ModeWorker:
+		return mode, nil
+	case "":
+		return ModeAll, nil
+	default:
+		return "", fmt.Errorf("%w: %q, expected one of all, server, worker", ErrInvalidMode, value)
+	}
+}
+
+func (m Mode) RunsServer() bool {
+	return m == ModeAll || m == ModeServer
+}
+
+func (m Mode) RunsWorkers() bool {
+	return m == ModeAll || m == ModeWorker
+}
+
+// splitSubcommand separates the optional leading subcommand i.e. `wallet worker --config wallet.yaml`.
+func splitSubcommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args
+	}
+
+	return args[0], args[1:]
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/receipts"
+)
+
+var (
+	ErrMissingReceiptsSMTP   = errors.New("RECEIPTS_INTERVAL requires SMTP_ADDRESS")
+	ErrReceiptsWithoutOutbox = errors.New("the receipts require EVENTS_BROKER")
+)
+
+type ReceiptsConfig struct {
+	// Interval is how often the worker queues the receipts of the new events and sends the due ones. 0 disables the receipts.
+	Interval time.Duration
+	// MaxAttempts is the number of delivery attempts before a receipt fails for good.
+	MaxAttempts int
+	// Templates override the default templates of the receipts, see receipts.ParseTemplates. Nil keeps the defaults.
+	Templates *receipts.Templates
+	// SMTP sends the receipts, shared with the statements.
+	SMTP SMTPConfig
+}
+
+func (c ReceiptsConfig) Enabled() bool {
+	return c.Interval > 0
+}
+
+func (c ReceiptsConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.SMTP.Address == "" {
+		return ErrMissingReceiptsSMTP
+	}
+
+	if c.SMTP.From == "" {
+		return ErrMissingSMTPFrom
+	}
+
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("%w: RECEIPTS_MAX_ATTEMPTS=%d", ErrInvalidSetting, c.MaxAttempts)
+	}
+
+	return nil
+}
+
+// parseReceiptTemplates loads the templates overriding the defaults from the file, if any.
+func parseReceiptTemplates(path string) (*receipts.Templates, error) {
+	if path == "" {
+		return nil, nil //nolint:nilnil // the defaults are kept
+	}
+
+	templates, err := receipts.ParseTemplates(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: RECEIPTS_TEMPLATES: %w", ErrInvalidSetting, err)
+	}
+
+	return templates, nil
+}
+
+// NewReceiptsNotifier sends the receipts through the SMTP server.
+func NewReceiptsNotifier(config ReceiptsConfig, store receipts.Store) *receipts.Notifier {
+	sender := receipts.NewSMTP(config.SMTP.Address, config.SMTP.From)
+
+	if config.SMTP.Username != "" {
+		sender.WithAuth(config.SMTP.Username, config.SMTP.Password)
+	}
+
+	notifier := receipts.NewNotifier(store, sender).WithMaxAttempts(config.MaxAttempts)
+
+	if config.Templates != nil {
+		notifier.WithTemplates(config.Templates)
+	}
+
+	return notifier
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/devshark/wallet/pkg/env"
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisMode is the topology of the Redis deployment.
+type RedisMode string
+
+const (
+	// RedisStandalone connects to a single Redis server.
+	RedisStandalone RedisMode = "standalone"
+	// RedisSentinel discovers the master through the sentinels, and follows the failovers.
+	RedisSentinel RedisMode = "sentinel"
+	// RedisCluster shards the keys across the nodes of a Redis Cluster.
+	RedisCluster RedisMode = "cluster"
+)
+
+var (
+	ErrInvalidRedisMode      = errors.New("invalid REDIS_MODE, must be one of standalone, sentinel, cluster")
+	ErrMissingRedisAddress   = errors.New("REDIS_ADDRESS requires at least one address")
+	ErrTooManyRedisAddresses = errors.New("the standalone mode requires a single REDIS_ADDRESS")
+	ErrMissingMasterName     = errors.New("the sentinel mode requires REDIS_MASTER_NAME")
+	ErrRedisDBInCluster      = errors.New("REDIS_DB is not supported in the cluster mode")
+	ErrInvalidRedisServerCA  = errors.New("no certificates found in the REDIS_TLS_CA_FILE")
+)
+
+func ParseRedisMode(value string) (RedisMode, error) {
+	switch mode := RedisMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case RedisStandalone, RedisSentinel, RedisCluster:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidRedisMode, value)
+	}
+}
+
+type RedisConfig struct {
+	Mode RedisMode
+	// Addresses are the server in the standalone mode, the sentinels in the sentinel mode, or the seed nodes in the cluster mode.
+	Addresses []string
+	Username  string
+	Password  string
+	DB        int
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+	TLS              RedisTLSConfig
+}
+
+type RedisTLSConfig struct {
+	Enabled bool
+	// CAFile verifies the server certificate with a private CA instead of the system pool.
+	CAFile string
+	// ServerName overrides the name verified in the server certificate, i.e. when connecting through an IP address.
+	ServerName string
+}
+
+// Validate checks that the settings match the mode, so a misconfiguration fails at startup
+// instead of on the first cached request.
+func (c RedisConfig) Validate() error {
+	if len(c.Addresses) == 0 {
+		return ErrMissingRedisAddress
+	}
+
+	switch c.Mode {
+	case RedisStandalone:
+		if len(c.Addresses) > 1 {
+			return ErrTooManyRedisAddresses
+		}
+	case RedisSentinel:
+		if c.MasterName == "" {
+			return ErrMissingMasterName
+		}
+	case RedisCluster:
+		// the cluster only has the database 0
+		if c.DB != 0 {
+			return ErrRedisDBInCluster
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidRedisMode, c.Mode)
+	}
+
+	return nil
+}
+
+// NewRedisClient connects to the Redis topology given by the config.
+// The client is lazy, so it doesn't fail if Redis isn't reachable yet.
+func NewRedisClient(config RedisConfig) (redis.UniversalClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newRedisTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Mode {
+	case RedisStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:      config.Addresses[0],
+			Username:  config.Username,
+			Password:  config.Password,
+			DB:        config.DB,
+			TLSConfig: tlsConfig,
+		}), nil
+	case RedisSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.Addresses,
+			SentinelUsername: config.SentinelUsername,
+			SentinelPassword: config.SentinelPassword,
+			Username:         config.Username,
+			Password:         config.Password,
+			DB:               config.DB,
+			TLSConfig:        tlsConfig,
+		}), nil
+	case RedisCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     config.Addresses,
+			Username:  config.Username,
+			Password:  config.Password,
+			TLSConfig: tlsConfig,
+		}), nil
+	default:
+		// unreachable, the mode has been validated
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRedisMode, config.Mode)
+	}
+}
+
+func newRedisTLSConfig(config RedisTLSConfig) (*tls.Config, error) {
+	if !config.Enabled {
+		return nil, nil //nolint:nilnil // no TLS config means plain TCP for go-redis
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config.ServerName,
+	}
+
+	if config.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(config.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read REDIS_TLS_CA_FILE: %w", err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, ErrInvalidRedisServerCA
+	}
+
+	tlsConfig.RootCAs = rootCAs
+
+	return tlsConfig, nil
+}
+
+// parseRedisConfig reads the REDIS_* settings.
+func parseRedisConfig(loader *env.Loader) (RedisConfig, error) {
+	mode, err := ParseRedisMode(loader.GetEnv("REDIS_MODE", string(RedisStandalone)))
+	if err != nil {
+		return RedisConfig{}, err
+	}
+
+	return RedisConfig{
+		Mode:             mode,
+		Addresses:        splitAddresses(loader.RequireEnv("REDIS_ADDRESS")), // comma-separated in the sentinel and cluster modes
+		Username:         loader.GetEnv("REDIS_USERNAME", ""),                // optional
+		Password:         loader.GetEnv("REDIS_PASSWORD", ""),                // optional
+		DB:               int(loader.GetEnvInt64("REDIS_DB", 0)),
+		MasterName:       loader.GetEnv("REDIS_MASTER_NAME", ""),       // required in the sentinel mode
+		SentinelUsername: loader.GetEnv("REDIS_SENTINEL_USERNAME", ""), // optional
+		SentinelPassword: loader.GetEnv("REDIS_SENTINEL_PASSWORD", ""), // optional
+		TLS: RedisTLSConfig{
+			Enabled:    loader.GetEnvBool("REDIS_TLS", false),
+			CAFile:     loader.GetEnv("REDIS_TLS_CA_FILE", ""),     // optional, uses the system pool if empty
+			ServerName: loader.GetEnv("REDIS_TLS_SERVER_NAME", ""), // optional
+		},
+	}, nil
+}
+
+// splitAddresses splits the comma-separated addresses, ignoring the blanks.
+func splitAddresses(value string) []string {
+	addresses := []string{}
+
+	for _, address := range strings.Split(value, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
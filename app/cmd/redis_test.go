@@ -0,0 +1,117 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedisMode(t *testing.T) {
+	for value, expected := range map[string]RedisMode{
+		"standalone": RedisStandalone,
+		"Sentinel":   RedisSentinel,
+		" cluster ":  RedisCluster,
+	} {
+		mode, err := ParseRedisMode(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, mode)
+	}
+
+	_, err := ParseRedisMode("replica")
+	require.ErrorIs(t, err, ErrInvalidRedisMode)
+}
+
+func TestRedisConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RedisConfig
+		expected error
+	}{
+		{"Standalone", RedisConfig{Mode: RedisStandalone, Addresses: []string{"redis:6379"}, DB: 2}, nil},
+		{"No address", RedisConfig{Mode: RedisStandalone}, ErrMissingRedisAddress},
+		{"Standalone with many addresses", RedisConfig{Mode: RedisStandalone, Addresses: []string{"a:6379", "b:6379"}}, ErrTooManyRedisAddresses},
+		{"Sentinel", RedisConfig{Mode: RedisSentinel, Addresses: []string{"a:26379", "b:26379"}, MasterName: "mymaster"}, nil},
+		{"Sentinel without master", RedisConfig{Mode: RedisSentinel, Addresses: []string{"a:26379"}}, ErrMissingMasterName},
+		{"Cluster", RedisConfig{Mode: RedisCluster, Addresses: []string{"a:6379", "b:6379", "c:6379"}}, nil},
+		{"Cluster with a database", RedisConfig{Mode: RedisCluster, Addresses: []string{"a:6379"}, DB: 1}, ErrRedisDBInCluster},
+		{"Unknown mode", RedisConfig{Mode: "replica", Addresses: []string{"a:6379"}}, ErrInvalidRedisMode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expected == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestNewRedisClient(t *testing.T) {
+	t.Run("Standalone", func(t *testing.T) {
+		client, err := NewRedisClient(RedisConfig{Mode: RedisStandalone, Addresses: []string{"redis:6379"}, DB: 3})
+		require.NoError(t, err)
+		defer client.Close()
+
+		standalone, ok := client.(*redis.Client)
+		require.True(t, ok)
+		require.Equal(t, "redis:6379", standalone.Options().Addr)
+		require.Equal(t, 3, standalone.Options().DB)
+		require.Nil(t, standalone.Options().TLSConfig)
+	})
+
+	t.Run("Sentinel", func(t *testing.T) {
+		client, err := NewRedisClient(RedisConfig{Mode: RedisSentinel, Addresses: []string{"a:26379"}, MasterName: "mymaster"})
+		require.NoError(t, err)
+		defer client.Close()
+
+		// the failover client is a regular client dialing the master given by the sentinels
+		_, ok := client.(*redis.Client)
+		require.True(t, ok)
+	})
+
+	t.Run("Cluster with TLS", func(t *testing.T) {
+		ca := newTestCertificate(t, "redis-ca", nil, true)
+		caFile := writeTestFile(t, "ca.pem", ca.certPEM)
+
+		client, err := NewRedisClient(RedisConfig{
+			Mode:      RedisCluster,
+			Addresses: []string{"a:6379", "b:6379"},
+			TLS:       RedisTLSConfig{Enabled: true, CAFile: caFile, ServerName: "redis.internal"},
+		})
+		require.NoError(t, err)
+		defer client.Close()
+
+		cluster, ok := client.(*redis.ClusterClient)
+		require.True(t, ok)
+		require.NotNil(t, cluster.Options().TLSConfig.RootCAs)
+		require.Equal(t, "redis.internal", cluster.Options().TLSConfig.ServerName)
+	})
+
+	t.Run("Invalid CA file", func(t *testing.T) {
+		_, err := NewRedisClient(RedisConfig{
+			Mode:      RedisStandalone,
+			Addresses: []string{"redis:6379"},
+			TLS:       RedisTLSConfig{Enabled: true, CAFile: writeTestFile(t, "ca.pem", []byte("not a certificate"))},
+		})
+		require.ErrorIs(t, err, ErrInvalidRedisServerCA)
+
+		_, err = NewRedisClient(RedisConfig{
+			Mode:      RedisStandalone,
+			Addresses: []string{"redis:6379"},
+			TLS:       RedisTLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestSplitAddresses(t *testing.T) {
+	require.Equal(t, []string{"a:26379", "b:26379"}, splitAddresses(" a:26379, ,b:26379,"))
+	require.Empty(t, splitAddresses(""))
+}
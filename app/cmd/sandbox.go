@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+const defaultSandboxSchema = "sandbox"
+
+// the schema is interpolated in the connection string and the CREATE SCHEMA, so it's a plain identifier
+var sandboxSchemaPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+type SandboxConfig struct {
+	// Enabled serves fake money to the partners integrating with the wallet.
+	Enabled bool
+	// Schema is the Postgres schema holding all of the data of the sandbox, apart from the real ledgers.
+	Schema string
+	// DepositQuotas cap the deposits of each account by currency, see repository.SandboxQuotaPeriod.
+	DepositQuotas map[string]decimal.Decimal
+}
+
+func (c SandboxConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	// the real ledgers are in the public schema
+	if !sandboxSchemaPattern.MatchString(c.Schema) || c.Schema == "public" {
+		return fmt.Errorf("%w: SANDBOX_SCHEMA=%s", ErrInvalidSetting, c.Schema)
+	}
+
+	return nil
+}
+
+// connectionOptions returns the options of the connection string isolating the sandbox in its schema.
+// The tables of the sandbox hide the public ones, which is only searched for the extensions, i.e. uuid-ossp.
+func (c SandboxConfig) connectionOptions() string {
+	if !c.Enabled {
+		return ""
+	}
+
+	return " search_path=" + c.Schema + ",public"
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/chaos"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/idempotency"
+	"github.com/devshark/wallet/app/internal/migration"
+	"github.com/devshark/wallet/app/internal/readonly"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/devshark/wallet/app/rpc"
+	"github.com/devshark/wallet/pkg/featureflags"
+	"github.com/devshark/wallet/pkg/lifecycle"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// registerServer migrates the database and adds the HTTP API to the manager.
+// Only the server migrates, so the workers never race with it on the schema.
+func registerServer(ctx context.Context, manager *lifecycle.Manager, config Config, db *sql.DB, repo *repository.PostgresRepository) error {
+	logger := logging.Component(slog.Default(), "main")
+
+	migrator := migration.NewMigrator(db, "migrations").
+		WithCustomLogger(slog.Default())
+
+	// the connections of the sandbox search its schema only, which must exist before the migrations
+	if config.sandbox.Enabled {
+		if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+config.sandbox.Schema); err != nil {
+			return fmt.Errorf("failed to create the sandbox schema: %w", err)
+		}
+
+		migrator.WithSchema(config.sandbox.Schema)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	logger.InfoContext(ctx, "database migrated successfully")
+
+	redisClient, err := NewRedisClient(config.redis)
+	if err != nil {
+		return fmt.Errorf("failed to configure redis: %w", err)
+	}
+
+	manager.OnShutdown("redis", lifecycle.Closer(redisClient))
+
+	// shared by the REST and gRPC APIs and the repository, so they apply the same policies
+	flags := newFeatures(config, redisClient)
+	repo.WithFeatures(flags)
+
+	pingDB := db.PingContext
+
+	var transfers repository.Repository = repo
+
+	// the batches and the holds post transfers too, so they're decorated alike
+	var (
+		batches rest.TransferBatches = repo
+		holds   rest.Holds           = repo
+	)
+
+	// the faults are injected below the reservations, so they're exercised too
+	if config.chaos.Enabled() {
+		injector := chaos.NewInjector(config.chaos.Faults).WithLogger(logging.Component(slog.Default(), "chaos"))
+
+		logger.WarnContext(ctx, "injecting faults", slog.Any("targets", config.chaos.Targets),
+			slog.Int("failure_percent", config.chaos.Faults.FailurePercent),
+			slog.Int("delay_percent", config.chaos.Faults.DelayPercent), slog.Duration("delay", config.chaos.Faults.Delay))
+
+		if config.chaos.Targeted(chaosRepository) {
+			faulty := chaos.NewRepository(transfers, injector)
+			transfers, batches, holds = faulty, faulty.Batches(batches), faulty.Holds(holds)
+			pingDB = func(ctx context.Context) error {
+				if err := injector.Inject(ctx, "db.ping"); err != nil {
+					return err
+				}
+
+				return db.PingContext(ctx)
+			}
+		}
+
+		if config.chaos.Targeted(chaosRedis) {
+			redisClient.AddHook(chaos.NewRedisHook(injector))
+		}
+	}
+
+	// the reservations reject the duplicates before Postgres, consistently across the servers sharing the Redis
+	if config.idempotencyReservationTTL > 0 {
+		reservations := idempotency.NewRepository(transfers, redisClient, config.idempotencyReservationTTL).
+			WithLogger(logging.Component(slog.Default(), "idempotency"))
+		transfers, batches, holds = reservations, reservations.Batches(batches), reservations.Holds(holds)
+	}
+
+	apiServer := rest.NewAPIServer(transfers).
+		WithFeatures(flags).
+		AddPinger(pingDB).
+		// the cache misses and the reservations are skipped while Redis is down, so it only degrades the service
+		AddDegradedPinger("redis", func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}).
+		WithCustomLogger(slog.Default()).
+		WithRounding(rounding.New(config.roundingPolicies)).
+		WithCompanyAccount(config.companyAccountID).
+		WithTransferStatuses(config.transferStatuses).
+		WithRemarksPolicy(config.remarks).
+		WithCacheMiddleware(redisClient, config.cacheExpiry)
+
+	if config.rateLimitRequests > 0 {
+		apiServer.WithRateLimit(redisClient, config.rateLimitRequests, config.rateLimitWindow)
+	}
+
+	// the operators switch it in Redis, so it applies to every server
+	readOnly := readonly.New(redisClient, readonly.DefaultRedisKey, config.readOnly).
+		WithLogger(logging.Component(slog.Default(), "read-only"))
+
+	apiServer.WithReadOnly(readOnly)
+
+	if config.readOnly {
+		logger.WarnContext(ctx, "read-only mode forced")
+	}
+
+	apiServer.WithStatementDownloads(repo).
+		WithTransactionSubscriptions(repo, config.subscriptionsInterval).
+		WithTransactionStreams(repo).
+		WithTransferBatches(batches).
+		WithHolds(holds)
+
+	if config.activityFeed {
+		apiServer.WithActivityFeed(repo)
+	}
+
+	if config.transferQueue {
+		apiServer.WithTransferQueue(repo)
+	}
+
+	if config.scheduledTransfers {
+		apiServer.WithScheduledTransfers(repo)
+	}
+
+	if config.sandbox.Enabled {
+		apiServer.WithSandbox()
+	}
+
+	if config.errorMessages != nil {
+		apiServer.WithErrorMessages(config.errorMessages)
+	}
+
+	// the admin endpoints are only served when there's a key to protect them
+	if len(config.adminAPIKeyHashes) > 0 {
+		adminAuth := middlewares.NewAPIKeyAuth(config.adminAPIKeyHashes)
+
+		reconciler := reconciliation.NewReconciler(repo).
+			WithDateTolerance(config.reconciliationTolerance).
+			WithLogger(logging.Component(slog.Default(), "reconciliation"))
+
+		apiServer.WithReconciliations(adminAuth, reconciler).
+			WithTransferReviews(adminAuth, repo).
+			WithPendingTransfers(adminAuth, repo).
+			WithDisputes(adminAuth, repo).
+			WithTransactionMetadata(adminAuth, repo).
+			WithReversals(adminAuth, repo).
+			WithReports(adminAuth, repo).
+			WithStatements(adminAuth, repo).
+			WithReceipts(adminAuth, repo).
+			WithWebhooks(adminAuth, repo).
+			WithPersonalData(adminAuth, repo).
+			WithAccountProvisioning(adminAuth, repo).
+			WithBalanceRebuilds(adminAuth, repo).
+			WithIdempotencyConflicts(adminAuth, repo).
+			WithCacheInvalidation(adminAuth, redisClient)
+
+		// the outbox is only written with a broker
+		if config.events.Enabled() {
+			apiServer.WithEventLog(adminAuth, repo)
+		}
+
+		if config.fees {
+			apiServer.WithFeeRules(adminAuth, repo)
+		}
+
+		if config.debugEndpoints {
+			apiServer.WithDebugEndpoints(adminAuth)
+
+			logger.WarnContext(ctx, "debug endpoints enabled", slog.Int("admin_keys", len(config.adminAPIKeyHashes)))
+		}
+	}
+
+	apiServer.WithConnectionTimeouts(config.http.ReadHeaderTimeout, config.http.IdleTimeout)
+
+	if config.http.H2C {
+		apiServer.WithH2C()
+	}
+
+	server := apiServer.HTTPServer(config.port, config.http.ReadTimeout, config.http.WriteTimeout)
+
+	if config.tls.Enabled() {
+		tlsConfig, err := NewTLSConfig(config.tls)
+		if err != nil {
+			return fmt.Errorf("failed to configure TLS: %w", err)
+		}
+
+		server.TLSConfig = tlsConfig
+	}
+
+	manager.Go("http server", lifecycle.HTTPServer(server, func() error {
+		logger.InfoContext(ctx, "listening", slog.Int64("port", config.port), slog.Bool("tls", config.tls.Enabled()),
+			slog.Bool("h2c", config.http.H2C))
+
+		if config.tls.Enabled() {
+			return server.ListenAndServeTLS(config.tls.CertFile, config.tls.KeyFile)
+		}
+
+		return server.ListenAndServe()
+	}, config.http.DrainTimeout))
+
+	if config.grpcPort > 0 {
+		if err := registerGRPCServer(manager, config, transfers, readOnly, flags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// registerGRPCServer serves the gRPC API on its own port, sharing the repository and the TLS settings of the HTTP server.
+func registerGRPCServer(manager *lifecycle.Manager, config Config, repo repository.Repository, readOnly rpc.ReadOnlyMode, flags features.Features) error {
+	logger := logging.Component(slog.Default(), "main")
+
+	var opts []grpc.ServerOption
+
+	if config.tls.Enabled() {
+		tlsConfig, err := NewTLSConfig(config.tls)
+		if err != nil {
+			return fmt.Errorf("failed to configure gRPC TLS: %w", err)
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := rpc.NewServer(repo).
+		WithCustomLogger(slog.Default()).
+		WithRounding(rounding.New(config.roundingPolicies)).
+		WithCompanyAccount(config.companyAccountID).
+		WithRemarksPolicy(config.remarks).
+		WithReadOnly(readOnly).
+		WithFeatures(flags).
+		GRPCServer(opts...)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.grpcPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+
+	manager.Go("grpc server", func(ctx context.Context) error {
+		logger.InfoContext(ctx, "gRPC listening", slog.Int64("port", config.grpcPort), slog.Bool("tls", config.tls.Enabled()))
+
+		serveErr := make(chan error, 1)
+
+		go func() {
+			serveErr <- server.Serve(listener)
+		}()
+
+		select {
+		case err := <-serveErr:
+			return fmt.Errorf("grpc server failed: %w", err)
+		case <-ctx.Done():
+		}
+
+		// GracefulStop waits for the in-flight calls, force the stop if they take longer than the timeout
+		stopped := make(chan struct{})
+
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(config.shutdownTimeout):
+			server.Stop()
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+// newFeatures reads the flags from the Redis hash first, so they can be toggled at runtime,
+// then from the FEATURE_<FLAG> settings. The flags switch the policies of the deployment.
+func newFeatures(config Config, redisClient redis.UniversalClient) features.Features {
+	sources := []featureflags.Source{}
+
+	if config.featureFlagsInterval > 0 {
+		sources = append(sources, featureflags.NewRedisSource(redisClient, featureflags.DefaultRedisKey, config.featureFlagsInterval))
+	}
+
+	sources = append(sources, featureflags.NewEnvSource(config.featureLookup))
+
+	return features.New(featureflags.New(sources...).
+		WithLogger(logging.Component(slog.Default(), "features"))).
+		WithProvisioningPolicy(config.provisioningPolicy).
+		WithAccountIDPolicy(config.accountIDs)
+}
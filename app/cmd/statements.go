@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/devshark/wallet/pkg/webhook"
+)
+
+var (
+	ErrMissingStatementChannels = errors.New("STATEMENTS_INTERVAL requires STATEMENTS_WEBHOOK_SECRET or SMTP_ADDRESS")
+	ErrMissingSMTPFrom          = errors.New("SMTP_ADDRESS requires SMTP_FROM")
+)
+
+type SMTPConfig struct {
+	// Address is the host:port of the SMTP server, i.e. smtp.example.com:587.
+	Address string
+	From    string
+	// the credentials are optional, i.e. for a relay on the same network
+	Username string
+	Password string
+}
+
+type StatementsConfig struct {
+	// Interval is how often the worker generates and delivers the statements. 0 disables the statements.
+	Interval time.Duration
+	// MaxAttempts is the number of delivery attempts before a statement fails for good.
+	MaxAttempts int
+	// WebhookSecret signs the statements posted to the webhooks, which are disabled without it.
+	WebhookSecret string
+	// SMTP mails the statements, the emails are disabled without its address.
+	SMTP SMTPConfig
+}
+
+func (c StatementsConfig) Enabled() bool {
+	return c.Interval > 0
+}
+
+func (c StatementsConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.WebhookSecret == "" && c.SMTP.Address == "" {
+		return ErrMissingStatementChannels
+	}
+
+	if c.SMTP.Address != "" && c.SMTP.From == "" {
+		return ErrMissingSMTPFrom
+	}
+
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("%w: STATEMENTS_MAX_ATTEMPTS=%d", ErrInvalidSetting, c.MaxAttempts)
+	}
+
+	return nil
+}
+
+// NewStatementScheduler delivers the statements through the configured channels.
+func NewStatementScheduler(config StatementsConfig, store statements.Store) *statements.Scheduler {
+	scheduler := statements.NewScheduler(store).WithMaxAttempts(config.MaxAttempts)
+
+	if config.WebhookSecret != "" {
+		scheduler.WithChannel(api.StatementWebhook, statements.NewWebhook(webhook.NewSender([]byte(config.WebhookSecret))))
+	}
+
+	if config.SMTP.Address != "" {
+		email := statements.NewEmail(config.SMTP.Address, config.SMTP.From)
+
+		if config.SMTP.Username != "" {
+			email.WithAuth(config.SMTP.Username, config.SMTP.Password)
+		}
+
+		scheduler.WithChannel(api.StatementEmail, email)
+	}
+
+	return scheduler
+}
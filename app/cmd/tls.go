@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrIncompleteTLSConfig = errors.New("both TLS_CERT_FILE and TLS_KEY_FILE are required")
+	ErrInvalidClientCA     = errors.New("no certificates found in the client CA file")
+)
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS, only clients with a certificate signed by this CA are accepted.
+	ClientCAFile string
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// NewTLSConfig builds the server TLS settings: TLS 1.2 at least, with only AEAD cipher suites with forward secrecy.
+// The certificate itself is loaded by http.Server.ListenAndServeTLS.
+func NewTLSConfig(config TLSConfig) (*tls.Config, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, ErrIncompleteTLSConfig
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// only applies to TLS 1.2, TLS 1.3 suites are not configurable and are all safe
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		},
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+		},
+	}
+
+	if config.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, ErrInvalidClientCA
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, isCA bool) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+
+	return path
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	ca := newTestCertificate(t, "wallet-ca", nil, true)
+	serverCert := newTestCertificate(t, "wallet", ca, false)
+	clientCert := newTestCertificate(t, "client", ca, false)
+
+	config := TLSConfig{
+		CertFile:     writeTestFile(t, "server.pem", serverCert.certPEM),
+		KeyFile:      writeTestFile(t, "server-key.pem", serverCert.keyPEM),
+		ClientCAFile: writeTestFile(t, "ca.pem", ca.certPEM),
+	}
+
+	t.Run("Incomplete", func(t *testing.T) {
+		require.False(t, TLSConfig{}.Enabled())
+
+		_, err := NewTLSConfig(TLSConfig{CertFile: config.CertFile})
+		require.ErrorIs(t, err, ErrIncompleteTLSConfig)
+	})
+
+	t.Run("Invalid client CA", func(t *testing.T) {
+		_, err := NewTLSConfig(TLSConfig{
+			CertFile:     config.CertFile,
+			KeyFile:      config.KeyFile,
+			ClientCAFile: writeTestFile(t, "invalid.pem", []byte("not a certificate")),
+		})
+		require.ErrorIs(t, err, ErrInvalidClientCA)
+
+		_, err = NewTLSConfig(TLSConfig{
+			CertFile:     config.CertFile,
+			KeyFile:      config.KeyFile,
+			ClientCAFile: filepath.Join(t.TempDir(), "missing.pem"),
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("mTLS", func(t *testing.T) {
+		tlsConfig, err := NewTLSConfig(config)
+		require.NoError(t, err)
+		require.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+		require.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+
+		serverKeyPair, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+		require.NoError(t, err)
+
+		tlsConfig.Certificates = []tls.Certificate{serverKeyPair}
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = tlsConfig
+		server.StartTLS()
+
+		defer server.Close()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.cert)
+
+		clientKeyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+		require.NoError(t, err)
+
+		withCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientKeyPair},
+			MinVersion:   tls.VersionTLS12,
+		}}}
+		defer withCert.CloseIdleConnections()
+
+		resp, err := withCert.Get(server.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		withoutCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}}}
+		defer withoutCert.CloseIdleConnections()
+
+		resp, err = withoutCert.Get(server.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+
+		require.Error(t, err)
+	})
+}
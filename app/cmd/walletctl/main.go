@@ -0,0 +1,690 @@
+// walletctl is the admin CLI of the wallet. It talks to the API for the day-to-day operations,
+// and to the database directly for the break-glass ones.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/ledgerexport"
+	"github.com/devshark/wallet/app/internal/ledgerimport"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/client"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/devshark/wallet/pkg/idgen"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUsage              = errors.New("usage error")
+	ErrLedgerInconsistent = errors.New("ledger is inconsistent")
+	ErrAPIKeyMismatch     = errors.New("api key doesn't match the hash")
+)
+
+const (
+	urlEnv = "WALLETCTL_URL"
+	dsnEnv = "WALLETCTL_DSN"
+
+	// the same master keys as the wallet, to read and write the encrypted remarks
+	encryptionKeysEnv      = "ENCRYPTION_KEYS"
+	encryptionActiveKeyEnv = "ENCRYPTION_ACTIVE_KEY"
+	// the same company account as the wallet, the counterparty of the imported deposits and withdrawals
+	companyAccountEnv = "COMPANY_ACCOUNT_ID"
+
+	defaultURL = "http://localhost:8080"
+	clientName = "walletctl"
+
+	idempotencyKeyPrefix = "walletctl-"
+
+	// distinguishes a failed check from a failed command
+	exitInconsistent = 2
+)
+
+const usage = `usage: walletctl [--url URL] <command> [flags]
+
+commands:
+  balance        --account ID --currency CUR
+  transactions   --account ID --currency CUR
+  transaction    --id TXID
+  deposit        --account ID --currency CUR --amount AMT [--remarks TEXT] [--idempotency-key KEY]
+  withdraw       --account ID --currency CUR --amount AMT [--remarks TEXT] [--idempotency-key KEY]
+  transfer       --from ID --to ID --currency CUR --amount AMT [--remarks TEXT] [--idempotency-key KEY]
+  verify-ledger  [--dsn DSN]    connects to the database directly, defaults to $WALLETCTL_DSN
+  export-ledger  [--dsn DSN] [--account ID] [--currency CUR] [--until TIME] [--output LOCATION]
+  snapshot-ledger [--dsn DSN] [--at TIME] [--output LOCATION]
+  restore-ledger [--dsn DSN] [--input LOCATION] [--batch-size N]
+  import-ledger  [--dsn DSN] [--input LOCATION] [--format jsonl|csv] [--batch-size N] [--dry-run]
+  apikey generate               prints a new api key and its hash
+  apikey hash KEY
+  apikey verify KEY HASH
+
+The API url defaults to $WALLETCTL_URL or http://localhost:8080.
+A random idempotency key is generated when none is given, and printed so the command can be safely retried.
+The ledger locations are - for stdout or stdin (the default), s3://bucket/key, or a file path. TIME is RFC 3339.
+import-ledger reads the records of a legacy ledger, in csv when the input ends with .csv, jsonl otherwise.
+The ledger commands read and write the encrypted remarks with the keys of $ENCRYPTION_KEYS and $ENCRYPTION_ACTIVE_KEY.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+
+	stop()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrLedgerInconsistent):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitInconsistent)
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+type cli struct {
+	baseURL string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	global := flag.NewFlagSet("walletctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+
+	baseURL := global.String("url", envOr(urlEnv, defaultURL), "the wallet API url")
+
+	if err := global.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	if global.NArg() == 0 {
+		global.Usage()
+
+		return ErrUsage
+	}
+
+	c := &cli{
+		baseURL: strings.TrimSuffix(*baseURL, "/"),
+		stdin:   os.Stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+
+	command, rest := global.Arg(0), global.Args()[1:]
+
+	switch command {
+	case "balance":
+		return c.balance(ctx, rest)
+	case "transactions":
+		return c.transactions(ctx, rest)
+	case "transaction":
+		return c.transaction(ctx, rest)
+	case "deposit":
+		return c.deposit(ctx, rest)
+	case "withdraw":
+		return c.withdraw(ctx, rest)
+	case "transfer":
+		return c.transfer(ctx, rest)
+	case "verify-ledger":
+		return c.verifyLedger(ctx, rest)
+	case "export-ledger":
+		return c.exportLedger(ctx, rest)
+	case "snapshot-ledger":
+		return c.snapshotLedger(ctx, rest)
+	case "restore-ledger":
+		return c.restoreLedger(ctx, rest)
+	case "import-ledger":
+		return c.importLedger(ctx, rest)
+	case "apikey":
+		return c.apiKey(rest)
+	default:
+		global.Usage()
+
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, command)
+	}
+}
+
+func (c *cli) balance(ctx context.Context, args []string) error {
+	flags := c.flagSet("balance")
+	account := flags.String("account", "", "the account id")
+	currency := flags.String("currency", "", "the currency")
+
+	if err := parse(flags, args, "account", "currency"); err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountReaderClient(c.baseURL).GetAccountBalance(ctx, *currency, *account)
+	if err != nil {
+		return fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	return c.print(result)
+}
+
+func (c *cli) transactions(ctx context.Context, args []string) error {
+	flags := c.flagSet("transactions")
+	account := flags.String("account", "", "the account id")
+	currency := flags.String("currency", "", "the currency")
+
+	if err := parse(flags, args, "account", "currency"); err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountReaderClient(c.baseURL).GetTransactions(ctx, *currency, *account)
+	if err != nil {
+		return fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return c.print(result)
+}
+
+func (c *cli) transaction(ctx context.Context, args []string) error {
+	flags := c.flagSet("transaction")
+	txID := flags.String("id", "", "the transaction id")
+
+	if err := parse(flags, args, "id"); err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountReaderClient(c.baseURL).GetTransaction(ctx, *txID)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return c.print(result)
+}
+
+// operationFlags are shared by the commands moving money.
+type operationFlags struct {
+	currency       *string
+	amount         *string
+	remarks        *string
+	idempotencyKey *string
+}
+
+func (c *cli) operationFlags(flags *flag.FlagSet) operationFlags {
+	return operationFlags{
+		currency:       flags.String("currency", "", "the currency"),
+		amount:         flags.String("amount", "", "the positive amount"),
+		remarks:        flags.String("remarks", "", "optional remarks"),
+		idempotencyKey: flags.String("idempotency-key", "", "generated when empty"),
+	}
+}
+
+// resolve parses the amount and generates the idempotency key if needed.
+func (c *cli) resolve(op operationFlags) (decimal.Decimal, string, error) {
+	amount, err := decimal.NewFromString(*op.amount)
+	if err != nil || !amount.IsPositive() {
+		return decimal.Zero, "", fmt.Errorf("%w: %w: %q", ErrUsage, api.ErrInvalidAmount, *op.amount)
+	}
+
+	key := *op.idempotencyKey
+	if key == "" {
+		key = idempotencyKeyPrefix + idgen.NewUUIDGenerator().NewID()
+	}
+
+	// the key is needed to safely retry the same operation if the outcome is unknown i.e. a timeout
+	fmt.Fprintf(c.stderr, "idempotency key: %s\n", key)
+
+	return amount, key, nil
+}
+
+func (c *cli) deposit(ctx context.Context, args []string) error {
+	flags := c.flagSet("deposit")
+	account := flags.String("account", "", "the account id")
+	op := c.operationFlags(flags)
+
+	if err := parse(flags, args, "account", "currency", "amount"); err != nil {
+		return err
+	}
+
+	amount, key, err := c.resolve(op)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountOperatorClient(c.baseURL).WithName(clientName).Deposit(ctx, &api.DepositRequest{
+		ToAccountID: *account,
+		Currency:    *op.currency,
+		Amount:      amount,
+		Remarks:     *op.remarks,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to deposit: %w", err)
+	}
+
+	return c.print(result)
+}
+
+func (c *cli) withdraw(ctx context.Context, args []string) error {
+	flags := c.flagSet("withdraw")
+	account := flags.String("account", "", "the account id")
+	op := c.operationFlags(flags)
+
+	if err := parse(flags, args, "account", "currency", "amount"); err != nil {
+		return err
+	}
+
+	amount, key, err := c.resolve(op)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountOperatorClient(c.baseURL).WithName(clientName).Withdraw(ctx, &api.WithdrawRequest{
+		FromAccountID: *account,
+		Currency:      *op.currency,
+		Amount:        amount,
+		Remarks:       *op.remarks,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw: %w", err)
+	}
+
+	return c.print(result)
+}
+
+func (c *cli) transfer(ctx context.Context, args []string) error {
+	flags := c.flagSet("transfer")
+	from := flags.String("from", "", "the source account id")
+	to := flags.String("to", "", "the destination account id")
+	op := c.operationFlags(flags)
+
+	if err := parse(flags, args, "from", "to", "currency", "amount"); err != nil {
+		return err
+	}
+
+	amount, key, err := c.resolve(op)
+	if err != nil {
+		return err
+	}
+
+	result, err := client.NewAccountOperatorClient(c.baseURL).WithName(clientName).Transfer(ctx, &api.TransferRequest{
+		FromAccountID: *from,
+		ToAccountID:   *to,
+		Currency:      *op.currency,
+		Amount:        amount,
+		Remarks:       *op.remarks,
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to transfer: %w", err)
+	}
+
+	return c.print(result)
+}
+
+func (c *cli) verifyLedger(ctx context.Context, args []string) error {
+	flags := c.flagSet("verify-ledger")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the postgres connection string")
+
+	if err := parse(flags, args, "dsn"); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	discrepancies, err := repository.NewPostgresRepository(db).VerifyLedger(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	if err = c.print(discrepancies); err != nil {
+		return err
+	}
+
+	if len(discrepancies) > 0 {
+		return fmt.Errorf("%w: %d accounts", ErrLedgerInconsistent, len(discrepancies))
+	}
+
+	return nil
+}
+
+func (c *cli) exportLedger(ctx context.Context, args []string) error {
+	flags := c.flagSet("export-ledger")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the postgres connection string")
+	account := flags.String("account", "", "only the transfers from or to the account")
+	currency := flags.String("currency", "", "only the transfers in the currency")
+	until := flags.String("until", "", "only the transfers posted at or before the time, now by default")
+	output := flags.String("output", ledgerexport.Stdio, "where to write the events")
+
+	if err := parse(flags, args, "dsn", "output"); err != nil {
+		return err
+	}
+
+	filter := api.LedgerFilter{AccountID: *account, Currency: *currency}
+
+	if *until != "" {
+		parsed, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return fmt.Errorf("%w: --until: %w", ErrUsage, err)
+		}
+
+		filter.Until = parsed.UTC()
+	}
+
+	return c.withLedger(*dsn, func(exporter *ledgerexport.Exporter) error {
+		return c.writeLedger(ctx, *output, "events", func(w io.Writer) (int, error) {
+			return exporter.Export(ctx, filter, w)
+		})
+	})
+}
+
+func (c *cli) snapshotLedger(ctx context.Context, args []string) error {
+	flags := c.flagSet("snapshot-ledger")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the postgres connection string")
+	at := flags.String("at", "", "the point in time of the balances, now by default")
+	output := flags.String("output", ledgerexport.Stdio, "where to write the balances")
+
+	if err := parse(flags, args, "dsn", "output"); err != nil {
+		return err
+	}
+
+	pointInTime := time.Now().UTC()
+
+	if *at != "" {
+		parsed, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("%w: --at: %w", ErrUsage, err)
+		}
+
+		pointInTime = parsed.UTC()
+	}
+
+	return c.withLedger(*dsn, func(exporter *ledgerexport.Exporter) error {
+		return c.writeLedger(ctx, *output, "accounts", func(w io.Writer) (int, error) {
+			return exporter.Snapshot(ctx, pointInTime, w)
+		})
+	})
+}
+
+func (c *cli) restoreLedger(ctx context.Context, args []string) error {
+	flags := c.flagSet("restore-ledger")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the postgres connection string")
+	input := flags.String("input", ledgerexport.Stdio, "where to read the events")
+	batchSize := flags.Int("batch-size", ledgerexport.DefaultBatchSize, "the events restored per database transaction")
+
+	if err := parse(flags, args, "dsn", "input"); err != nil {
+		return err
+	}
+
+	if *batchSize < 1 {
+		return fmt.Errorf("%w: --batch-size must be positive", ErrUsage)
+	}
+
+	return c.withLedger(*dsn, func(exporter *ledgerexport.Exporter) error {
+		reader, err := ledgerexport.Open(ctx, *input, c.stdin)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		result, err := exporter.WithBatchSize(*batchSize).Restore(ctx, reader)
+		if err != nil {
+			return fmt.Errorf("failed to restore ledger: %w", err)
+		}
+
+		if err = c.print(result); err != nil {
+			return err
+		}
+
+		if len(result.Discrepancies) > 0 {
+			return fmt.Errorf("%w: %d accounts", ErrLedgerInconsistent, len(result.Discrepancies))
+		}
+
+		return nil
+	})
+}
+
+func (c *cli) importLedger(ctx context.Context, args []string) error {
+	flags := c.flagSet("import-ledger")
+	dsn := flags.String("dsn", os.Getenv(dsnEnv), "the postgres connection string")
+	input := flags.String("input", ledgerexport.Stdio, "where to read the records")
+	formatName := flags.String("format", "", "the format of the records, jsonl or csv, from the input extension by default")
+	batchSize := flags.Int("batch-size", ledgerimport.DefaultBatchSize, "the records imported per database transaction")
+	dryRun := flags.Bool("dry-run", false, "only validates the records, nothing is written")
+
+	if err := parse(flags, args, "input"); err != nil {
+		return err
+	}
+
+	// a dry run doesn't connect to the database
+	if !*dryRun && strings.TrimSpace(*dsn) == "" {
+		return fmt.Errorf("%w: %s: --dsn is required", ErrUsage, flags.Name())
+	}
+
+	if *batchSize < 1 {
+		return fmt.Errorf("%w: --batch-size must be positive", ErrUsage)
+	}
+
+	if *formatName == "" {
+		*formatName = string(ledgerimport.FormatJSONL)
+		if strings.HasSuffix(strings.ToLower(*input), ".csv") {
+			*formatName = string(ledgerimport.FormatCSV)
+		}
+	}
+
+	format, err := ledgerimport.ParseFormat(*formatName)
+	if err != nil {
+		return fmt.Errorf("%w: --format: %w", ErrUsage, err)
+	}
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	reader, err := ledgerexport.Open(ctx, *input, c.stdin)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	repo, err := ledgerRepository(db)
+	if err != nil {
+		return err
+	}
+
+	// the progress goes to stderr, like the other ledger commands
+	logger := slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	result, err := ledgerimport.NewImporter(repo).
+		WithBatchSize(*batchSize).
+		WithDryRun(*dryRun).
+		WithCompanyAccount(companyAccountID()).
+		WithLogger(logger).
+		Import(ctx, format, reader)
+	if err != nil {
+		return fmt.Errorf("failed to import ledger: %w", err)
+	}
+
+	if err = c.print(result); err != nil {
+		return err
+	}
+
+	if len(result.Discrepancies) > 0 || len(result.Mismatches) > 0 {
+		return fmt.Errorf("%w: %d accounts, %d closing balances", ErrLedgerInconsistent, len(result.Discrepancies), len(result.Mismatches))
+	}
+
+	return nil
+}
+
+// withLedger connects to the database directly, for the ledger export commands.
+func (c *cli) withLedger(dsn string, run func(exporter *ledgerexport.Exporter) error) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	repo, err := ledgerRepository(db)
+	if err != nil {
+		return err
+	}
+
+	// the progress goes to stderr, as stdout may be the export itself
+	logger := slog.New(slog.NewTextHandler(c.stderr, nil))
+
+	return run(ledgerexport.NewExporter(repo).WithLogger(logger))
+}
+
+// ledgerRepository encrypts and decrypts the remarks with the master keys of $ENCRYPTION_KEYS, if any,
+// otherwise the encrypted remarks are exported blank.
+func ledgerRepository(db *sql.DB) (*repository.PostgresRepository, error) {
+	repo := repository.NewPostgresRepository(db).WithCompanyAccount(companyAccountID())
+
+	specs := strings.Fields(os.Getenv(encryptionKeysEnv))
+	if len(specs) == 0 {
+		return repo, nil
+	}
+
+	keyring, err := crypt.ParseKeyring(os.Getenv(encryptionActiveKeyEnv), specs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: $%s: %w", ErrUsage, encryptionKeysEnv, err)
+	}
+
+	return repo.WithFieldEncryption(keyring), nil
+}
+
+// companyAccountID is the company account of $COMPANY_ACCOUNT_ID, or api.CompanyAccountID.
+func companyAccountID() string {
+	if accountID := strings.TrimSpace(os.Getenv(companyAccountEnv)); accountID != "" {
+		return accountID
+	}
+
+	return api.CompanyAccountID
+}
+
+// writeLedger writes to the location, which is only replaced once the write succeeds.
+func (c *cli) writeLedger(ctx context.Context, location, unit string, write func(w io.Writer) (int, error)) error {
+	writer, err := ledgerexport.Create(ctx, location, c.stdout)
+	if err != nil {
+		return err
+	}
+
+	count, err := write(writer)
+	if err != nil {
+		writer.Abort(err)
+
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "%d %s written to %s\n", count, unit, location)
+
+	return nil
+}
+
+type generatedAPIKey struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+func (c *cli) apiKey(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: apikey requires one of generate, hash, verify", ErrUsage)
+	}
+
+	switch {
+	case args[0] == "generate" && len(args) == 1:
+		key, err := crypt.GenerateAPIKey()
+		if err != nil {
+			return fmt.Errorf("failed to generate api key: %w", err)
+		}
+
+		hash, err := crypt.HashAPIKey(key)
+		if err != nil {
+			return fmt.Errorf("failed to hash api key: %w", err)
+		}
+
+		// only the hash is configured on the server, the key is shown once to its owner
+		return c.print(generatedAPIKey{Key: key, Hash: hash})
+	case args[0] == "hash" && len(args) == 2:
+		hash, err := crypt.HashAPIKey(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to hash api key: %w", err)
+		}
+
+		fmt.Fprintln(c.stdout, hash)
+
+		return nil
+	case args[0] == "verify" && len(args) == 3:
+		ok, err := crypt.VerifyAPIKey(args[1], args[2])
+		if err != nil {
+			return fmt.Errorf("failed to verify api key: %w", err)
+		}
+
+		if !ok {
+			return ErrAPIKeyMismatch
+		}
+
+		fmt.Fprintln(c.stdout, "ok")
+
+		return nil
+	default:
+		return fmt.Errorf("%w: invalid apikey arguments", ErrUsage)
+	}
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+
+	return flags
+}
+
+func (c *cli) print(v any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to print result: %w", err)
+	}
+
+	return nil
+}
+
+// parse parses the flags and checks that the required ones are set.
+func parse(flags *flag.FlagSet, args []string, required ...string) error {
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", ErrUsage, err)
+	}
+
+	for _, name := range required {
+		if strings.TrimSpace(flags.Lookup(name).Value.String()) == "" {
+			return fmt.Errorf("%w: %s: --%s is required", ErrUsage, flags.Name(), name)
+		}
+	}
+
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
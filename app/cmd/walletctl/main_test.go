@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	keys := &[]string{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /account/{accountId}/{currency}", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(api.Account{
+			AccountID: r.PathValue("accountId"),
+			Currency:  r.PathValue("currency"),
+			Balance:   decimal.NewFromInt(42),
+		})
+	})
+
+	mux.HandleFunc("POST /transfer", func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get("X-Idempotency-Key"))
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(&api.TransferReceipt{
+			GroupID: r.Header.Get("X-Idempotency-Key"),
+			Debit:   &api.Transaction{TxID: "tx1", Type: api.DEBIT},
+			Credit:  &api.Transaction{TxID: "tx2", Type: api.CREDIT},
+		})
+	})
+
+	mux.HandleFunc("POST /deposit", func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get("X-Idempotency-Key"))
+
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(api.ErrorResponse{ErrorCode: http.StatusUnprocessableEntity, Message: "duplicate transaction"})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server, keys
+}
+
+func TestRun(t *testing.T) {
+	server, keys := newTestAPI(t)
+	ctx := context.Background()
+
+	t.Run("Balance", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+
+		err := run(ctx, []string{"--url", server.URL, "balance", "--account", "user1", "--currency", "USD"}, stdout, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"account":"user1","currency":"USD","balance":"42"}`, stdout.String())
+	})
+
+	t.Run("Transfer with generated idempotency key", func(t *testing.T) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+		err := run(ctx, []string{"--url", server.URL, "transfer", "--from", "a", "--to", "b", "--currency", "USD", "--amount", "1.5"}, stdout, stderr)
+		require.NoError(t, err)
+		require.Contains(t, stdout.String(), `"tx2"`)
+
+		require.NotEmpty(t, *keys)
+		key := (*keys)[len(*keys)-1]
+		require.True(t, strings.HasPrefix(key, idempotencyKeyPrefix))
+		require.Contains(t, stderr.String(), key)
+	})
+
+	t.Run("Given idempotency key and API error", func(t *testing.T) {
+		err := run(ctx, []string{"--url", server.URL, "deposit", "--account", "a", "--currency", "USD", "--amount", "10", "--idempotency-key", "retry-1"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.Error(t, err)
+		require.Equal(t, "retry-1", (*keys)[len(*keys)-1])
+	})
+
+	t.Run("Usage errors", func(t *testing.T) {
+		err := run(ctx, []string{}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"unknown"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"balance", "--account", "user1"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"withdraw", "--account", "a", "--currency", "USD", "--amount", "-1"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, api.ErrInvalidAmount)
+
+		err = run(ctx, []string{"export-ledger", "--dsn", "postgres://localhost", "--until", "yesterday"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"restore-ledger", "--dsn", "postgres://localhost", "--batch-size", "0"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"import-ledger", "--dsn", "postgres://localhost", "--format", "xlsx"}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage)
+
+		err = run(ctx, []string{"import-ledger", "--dsn", ""}, &bytes.Buffer{}, &bytes.Buffer{})
+		require.ErrorIs(t, err, ErrUsage, "only a dry run doesn't need the database")
+	})
+}
+
+func TestImportLedgerDryRun(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "legacy.csv")
+	require.NoError(t, os.WriteFile(input, []byte("id,kind,account_id,currency,amount,time\nLEG-1,OPENING_BALANCE,user1,USD,100,2024-01-01\n"), 0o600))
+
+	stdout := &bytes.Buffer{}
+
+	err := run(context.Background(), []string{"import-ledger", "--dsn", "", "--input", input, "--dry-run"}, stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.Contains(t, stdout.String(), `"records": 1`)
+}
+
+func TestAPIKeyCommands(t *testing.T) {
+	ctx := context.Background()
+	stdout := &bytes.Buffer{}
+
+	err := run(ctx, []string{"apikey", "generate"}, stdout, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	generated := generatedAPIKey{}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &generated))
+	require.True(t, strings.HasPrefix(generated.Key, crypt.APIKeyPrefix))
+
+	err = run(ctx, []string{"apikey", "verify", generated.Key, generated.Hash}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	err = run(ctx, []string{"apikey", "verify", "wk_other", generated.Hash}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrAPIKeyMismatch)
+
+	err = run(ctx, []string{"apikey"}, &bytes.Buffer{}, &bytes.Buffer{})
+	require.ErrorIs(t, err, ErrUsage)
+}
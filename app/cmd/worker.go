@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/devshark/wallet/pkg/lifecycle"
+	"github.com/devshark/wallet/pkg/lock"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/scheduler"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultLedgerCheckInterval   = time.Hour
+	defaultErasureInterval       = time.Minute
+	defaultActivityInterval      = time.Second
+	defaultTransferQueueInterval = time.Second
+	defaultScheduledInterval     = 10 * time.Second
+	// the locks are renewed every third of it, and held that long by the crashed workers
+	defaultWorkerLockTTL = 30 * time.Second
+)
+
+// locksWorkers is whether the jobs of this instance are locked, only the worker runs them.
+func (c Config) locksWorkers() bool {
+	return c.mode.RunsWorkers() && c.workerLocks
+}
+
+// usesRedis is whether this instance connects to Redis: the server caches in it, and the worker locks its jobs in it,
+// or purges the cache of the erased accounts when it's given the one of the servers.
+func (c Config) usesRedis() bool {
+	return c.mode.RunsServer() || c.locksWorkers() || len(c.redis.Addresses) > 0
+}
+
+// ledgerCheckSchedule is the cron schedule of the ledger check if any, or its interval, nil if disabled.
+func (c Config) ledgerCheckSchedule() scheduler.Schedule {
+	if c.ledgerCheckCron != nil {
+		return c.ledgerCheckCron
+	}
+
+	if c.ledgerCheckInterval > 0 {
+		return scheduler.Every(c.ledgerCheckInterval)
+	}
+
+	return nil
+}
+
+// registerWorkers adds the background jobs to the manager, run by a scheduler. A zero interval disables the job.
+func registerWorkers(manager *lifecycle.Manager, config Config, repo *repository.PostgresRepository) error {
+	jobs := scheduler.New().WithLogger(logging.Component(slog.Default(), "scheduler"))
+
+	// every instance runs every job unless they're locked
+	exclusive := func(_ string, job lifecycle.Task) lifecycle.Task {
+		return job
+	}
+
+	var redisClient redis.UniversalClient
+
+	if config.usesRedis() {
+		var err error
+
+		redisClient, err = NewRedisClient(config.redis)
+		if err != nil {
+			return fmt.Errorf("failed to configure redis: %w", err)
+		}
+
+		// closed after the jobs have stopped, and released their locks
+		manager.OnShutdown("worker redis", lifecycle.Closer(redisClient))
+	}
+
+	if config.locksWorkers() {
+		locker := lock.NewLocker(redisClient).WithLogger(logging.Component(slog.Default(), "locks"))
+
+		exclusive = func(name string, job lifecycle.Task) lifecycle.Task {
+			return locker.Exclusive(name, config.workerLockTTL, job)
+		}
+	}
+
+	schedule := func(name string, every scheduler.Schedule, job lifecycle.Task) {
+		jobs.Add(name, every, exclusive(name, job), scheduler.WithJitter(config.workerJitter))
+	}
+
+	if every := config.ledgerCheckSchedule(); every != nil {
+		check := worker.NewLedgerCheck(repo).WithLogger(logging.Component(slog.Default(), "ledger-check"))
+
+		schedule("ledger-check", every, check.Run)
+	}
+
+	if config.events.Enabled() {
+		publisher, err := NewEventsPublisher(config.events, repo)
+		if err != nil {
+			return fmt.Errorf("failed to create the events publisher: %w", err)
+		}
+
+		// closed after the relay has stopped
+		manager.OnShutdown(string(config.events.Broker), lifecycle.Closer(publisher))
+
+		relay := worker.NewOutboxRelay(repo, publisher).
+			WithRetention(config.events.Retention).
+			WithLogger(logging.Component(slog.Default(), "outbox-relay"))
+
+		schedule("outbox-relay", scheduler.Every(config.events.RelayInterval), relay.Run)
+	}
+
+	if config.statements.Enabled() {
+		statements := NewStatementScheduler(config.statements, repo).WithLogger(logging.Component(slog.Default(), "statements"))
+
+		schedule("statements", scheduler.Every(config.statements.Interval), statements.Run)
+	}
+
+	if config.receipts.Enabled() {
+		notifier := NewReceiptsNotifier(config.receipts, repo).WithLogger(logging.Component(slog.Default(), "receipts"))
+
+		schedule("receipts", scheduler.Every(config.receipts.Interval), notifier.Run)
+	}
+
+	if config.erasureInterval > 0 {
+		erasures := worker.NewErasures(repo).WithLogger(logging.Component(slog.Default(), "erasures"))
+
+		// the responses cached by the servers before the erasure completed
+		if redisClient != nil {
+			erasures.WithCachePurge(func(ctx context.Context, accountIDs ...string) (int64, error) {
+				return rest.InvalidateTransactions(ctx, redisClient, accountIDs...) //nolint:wrapcheck // the error names the cache
+			})
+		}
+
+		schedule("erasures", scheduler.Every(config.erasureInterval), erasures.Run)
+	}
+
+	if config.activityFeed {
+		projection := worker.NewActivityProjection(repo).WithLogger(logging.Component(slog.Default(), "activity-projection"))
+
+		schedule("activity-projection", scheduler.Every(config.activityInterval), projection.Run)
+	}
+
+	if config.transferQueue {
+		queue := worker.NewTransferQueue(repo).WithLogger(logging.Component(slog.Default(), "transfer-queue"))
+
+		schedule("transfer-queue", scheduler.Every(config.transferQueueInterval), queue.Run)
+	}
+
+	if config.scheduledTransfers {
+		scheduled := worker.NewScheduledTransfers(repo).WithLogger(logging.Component(slog.Default(), "scheduled-transfers"))
+
+		schedule("scheduled-transfers", scheduler.Every(config.scheduledInterval), scheduled.Run)
+	}
+
+	manager.Go("scheduler", jobs.Run)
+
+	return nil
+}
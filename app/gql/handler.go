@@ -0,0 +1,91 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/graphql-go/graphql"
+)
+
+// maxQueryBytes bounds the size of the query documents, the read queries are expected to be small.
+const maxQueryBytes = 64 << 10
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Handler serves the read-only GraphQL API over the reads of the repository.
+type Handler struct {
+	schema graphql.Schema
+	logger *slog.Logger
+}
+
+func NewHandler(repo repository.ReadRepository, logger *slog.Logger) (*Handler, error) {
+	logger = logging.Component(logger, "graphql")
+
+	schema, err := newSchema(&resolver{repo: repo, logger: logger})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		schema: schema,
+		logger: logger,
+	}, nil
+}
+
+// ServeHTTP accepts the queries as a JSON body on POST, or as the query parameter on GET.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req := &request{}
+
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, fmt.Sprintf("invalid variables: %v", err), http.StatusBadRequest)
+
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBytes)).Decode(req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+
+	// per the GraphQL over HTTP convention, the errors are in the payload with a 200 status
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,162 @@
+package gql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/gql"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func query(t *testing.T, handler http.Handler, q string, variables map[string]any) map[string]any {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]any{"query": q, "variables": variables})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	result := map[string]any{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+
+	return result
+}
+
+func transactions() []*api.Transaction {
+	return []*api.Transaction{
+		{TxID: "tx3", AccountID: "user1", Type: api.CREDIT, Amount: decimal.RequireFromString("0.1"), Currency: "USD", GroupID: "transfer-3", Counterparty: "user2"},
+		{TxID: "tx2", AccountID: "user1", Type: api.DEBIT, Amount: decimal.NewFromInt(2), Currency: "USD"},
+		{TxID: "tx1", AccountID: "user1", Type: api.CREDIT, Amount: decimal.NewFromInt(5), Currency: "USD"},
+	}
+}
+
+func TestAccountQuery(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockRepo := repository.NewMockRepository(t)
+	handler, err := gql.NewHandler(mockRepo, logging.Discard())
+	require.NoError(t, err)
+
+	mockRepo.EXPECT().GetAccountBalance(mock.Anything, "USD", "user1").Return(&api.Account{
+		AccountID: "user1",
+		Currency:  "USD",
+		Balance:   decimal.RequireFromString("3.1"),
+	}, nil).Once()
+	mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(transactions(), nil).Once()
+
+	result := query(t, handler, `query ($id: String!) {
+		account(accountId: $id, currency: "USD") {
+			balance
+			transactions(type: CREDIT, limit: 1) { total hasMore items { txId type amount groupId counterparty } }
+		}
+	}`, map[string]any{"id": "user1"})
+
+	require.Nil(t, result["errors"])
+	require.Equal(t, map[string]any{
+		"account": map[string]any{
+			"balance": "3.1",
+			"transactions": map[string]any{
+				"total":   float64(2),
+				"hasMore": true,
+				"items": []any{
+					map[string]any{"txId": "tx3", "type": "CREDIT", "amount": "0.1", "groupId": "transfer-3", "counterparty": "user2"},
+				},
+			},
+		},
+	}, result["data"])
+}
+
+func TestTransactionsQuery(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockRepo := repository.NewMockRepository(t)
+	handler, err := gql.NewHandler(mockRepo, logging.Discard())
+	require.NoError(t, err)
+
+	t.Run("Pagination", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(transactions(), nil).Once()
+
+		result := query(t, handler, `{ transactions(accountId: "user1", currency: "USD", limit: 2, offset: 2) { total hasMore items { txId } } }`, nil)
+
+		require.Nil(t, result["errors"])
+		require.Equal(t, map[string]any{
+			"total":   float64(3),
+			"hasMore": false,
+			"items":   []any{map[string]any{"txId": "tx1"}},
+		}, result["data"].(map[string]any)["transactions"])
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		result := query(t, handler, `{ transactions(accountId: "user1", currency: "USD", limit: 1000) { total } }`, nil)
+		require.NotNil(t, result["errors"])
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		tagged := []*api.Transaction{{TxID: "tx4", AccountID: "user1", Type: api.DEBIT, Currency: "USD", Tags: []string{"food", "travel"}}}
+
+		mockRepo.EXPECT().FilterTransactions(mock.Anything, "USD", "user1", api.TransactionFilter{Tags: []string{"food"}}).Return(tagged, nil).Once()
+
+		result := query(t, handler, `{ transactions(accountId: "user1", currency: "USD", tags: ["food"]) { items { txId tags } } }`, nil)
+
+		require.Nil(t, result["errors"])
+		require.Equal(t, map[string]any{
+			"items": []any{map[string]any{"txId": "tx4", "tags": []any{"food", "travel"}}},
+		}, result["data"].(map[string]any)["transactions"])
+	})
+}
+
+func TestTransactionQuery(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	mockRepo := repository.NewMockRepository(t)
+	handler, err := gql.NewHandler(mockRepo, logging.Discard())
+	require.NoError(t, err)
+
+	t.Run("Not found is null", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransaction(mock.Anything, "missing").Return(nil, api.ErrTransactionNotFound).Once()
+
+		result := query(t, handler, `{ transaction(txId: "missing") { txId } }`, nil)
+		require.Nil(t, result["errors"])
+		require.Equal(t, map[string]any{"transaction": nil}, result["data"])
+	})
+
+	t.Run("Unknown errors are hidden", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransaction(mock.Anything, "tx1").Return(nil, api.ErrUnhandledDatabaseError).Once()
+
+		result := query(t, handler, `{ transaction(txId: "tx1") { txId } }`, nil)
+		require.Contains(t, result["errors"].([]any)[0].(map[string]any)["message"], api.ErrFailedToGetTransaction.Error())
+	})
+
+	t.Run("GET request", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransaction(mock.Anything, "tx1").Return(&api.Transaction{TxID: "tx1", Type: api.DEBIT}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ transaction(txId: "tx1") { txId type } }`), nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"data":{"transaction":{"txId":"tx1","type":"DEBIT"}}}`, rr.Body.String())
+	})
+
+	t.Run("Invalid method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/graphql", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	})
+}
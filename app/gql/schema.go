@@ -0,0 +1,287 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/graphql-go/graphql"
+)
+
+var ErrInvalidPagination = errors.New("invalid pagination")
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// resolver holds the dependencies of the field resolvers.
+type resolver struct {
+	repo   repository.ReadRepository
+	logger *slog.Logger
+}
+
+// transactionPage is a window of the account transactions, newest first.
+type transactionPage struct {
+	total int
+	items []*api.Transaction
+	more  bool
+}
+
+func newSchema(r *resolver) (graphql.Schema, error) {
+	entryType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "EntryType",
+		Values: graphql.EnumValueConfigMap{
+			"DEBIT":  &graphql.EnumValueConfig{Value: api.DEBIT},
+			"CREDIT": &graphql.EnumValueConfig{Value: api.CREDIT},
+		},
+	})
+
+	transactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transaction",
+		Fields: graphql.Fields{
+			"txId":      transactionField(graphql.String, func(tx *api.Transaction) any { return tx.TxID }),
+			"accountId": transactionField(graphql.String, func(tx *api.Transaction) any { return tx.AccountID }),
+			"type":      transactionField(entryType, func(tx *api.Transaction) any { return tx.Type }),
+			// decimals are strings, to avoid the precision loss of the GraphQL Float
+			"amount":         transactionField(graphql.String, func(tx *api.Transaction) any { return tx.Amount.String() }),
+			"currency":       transactionField(graphql.String, func(tx *api.Transaction) any { return tx.Currency }),
+			"runningBalance": transactionField(graphql.String, func(tx *api.Transaction) any { return tx.RunningBalance.String() }),
+			"groupId":        transactionField(graphql.String, func(tx *api.Transaction) any { return tx.GroupID }),
+			"counterparty":   transactionField(graphql.String, func(tx *api.Transaction) any { return tx.Counterparty }),
+			"remarks":        transactionField(graphql.String, func(tx *api.Transaction) any { return tx.Remarks }),
+			"reference":      transactionField(graphql.String, func(tx *api.Transaction) any { return tx.Reference }),
+			"time":           transactionField(graphql.DateTime, func(tx *api.Transaction) any { return tx.Time }),
+			"tags": transactionField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(tx *api.Transaction) any {
+				if tx.Tags == nil {
+					return []string{}
+				}
+
+				return tx.Tags
+			}),
+		},
+	})
+
+	pageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TransactionPage",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*transactionPage).total, nil //nolint:forcetypeassert // always a page
+				},
+			},
+			"hasMore": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*transactionPage).more, nil //nolint:forcetypeassert // always a page
+				},
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*transactionPage).items, nil //nolint:forcetypeassert // always a page
+				},
+			},
+		},
+	})
+
+	transactionsArgs := graphql.FieldConfigArgument{
+		"type":   &graphql.ArgumentConfig{Type: entryType, Description: "only the debits or the credits"},
+		"tags":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Description: "only the transactions with all of the tags"},
+		"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+		"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+	}
+
+	accountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Account",
+		Fields: graphql.Fields{
+			"accountId": accountField(graphql.String, func(a *api.Account) any { return a.AccountID }),
+			"currency":  accountField(graphql.String, func(a *api.Account) any { return a.Currency }),
+			"balance":   accountField(graphql.String, func(a *api.Account) any { return a.Balance.String() }),
+			"transactions": &graphql.Field{
+				Type: graphql.NewNonNull(pageType),
+				Args: transactionsArgs,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					account := p.Source.(*api.Account) //nolint:forcetypeassert // always an account
+
+					return r.transactions(p, account.AccountID, account.Currency)
+				},
+			},
+		},
+	})
+
+	accountArgs := graphql.FieldConfigArgument{
+		"accountId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		"currency":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"account": &graphql.Field{
+				Type:    accountType,
+				Args:    accountArgs,
+				Resolve: r.account,
+			},
+			"transaction": &graphql.Field{
+				Type: transactionType,
+				Args: graphql.FieldConfigArgument{
+					"txId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.transaction,
+			},
+			"transactions": &graphql.Field{
+				Type: graphql.NewNonNull(pageType),
+				Args: mergeArgs(accountArgs, transactionsArgs),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return r.transactions(p, p.Args["accountId"].(string), p.Args["currency"].(string)) //nolint:forcetypeassert // non-null args
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		return graphql.Schema{}, fmt.Errorf("failed to build graphql schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+func (r *resolver) account(p graphql.ResolveParams) (any, error) {
+	accountID := p.Args["accountId"].(string) //nolint:forcetypeassert // non-null arg
+	currency := p.Args["currency"].(string)   //nolint:forcetypeassert // non-null arg
+
+	account, err := r.repo.GetAccountBalance(p.Context, currency, accountID)
+	if errors.Is(err, api.ErrAccountNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		r.logger.ErrorContext(p.Context, "failed to get account balance", slog.Any("error", err))
+
+		return nil, api.ErrFailedToGetTransaction
+	}
+
+	return account, nil
+}
+
+func (r *resolver) transaction(p graphql.ResolveParams) (any, error) {
+	tx, err := r.repo.GetTransaction(p.Context, p.Args["txId"].(string)) //nolint:forcetypeassert // non-null arg
+	if errors.Is(err, api.ErrTransactionNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		r.logger.ErrorContext(p.Context, "failed to get transaction", slog.Any("error", err))
+
+		return nil, api.ErrFailedToGetTransaction
+	}
+
+	return tx, nil
+}
+
+// transactions filters and paginates the account transactions. The repository doesn't paginate yet,
+// so the window is cut after the query.
+func (r *resolver) transactions(p graphql.ResolveParams, accountID, currency string) (*transactionPage, error) {
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+
+	if limit < 1 || limit > maxLimit || offset < 0 {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d, offset must not be negative", ErrInvalidPagination, maxLimit)
+	}
+
+	var (
+		txs []*api.Transaction
+		err error
+	)
+
+	if tags := stringArgs(p.Args["tags"]); len(tags) > 0 {
+		txs, err = r.repo.FilterTransactions(p.Context, currency, accountID, api.TransactionFilter{Tags: tags})
+	} else {
+		txs, err = r.repo.GetTransactions(p.Context, currency, accountID)
+	}
+
+	if errors.Is(err, api.ErrInvalidTag) {
+		return nil, err
+	}
+
+	if err != nil && !errors.Is(err, api.ErrTransactionNotFound) {
+		r.logger.ErrorContext(p.Context, "failed to get transactions", slog.Any("error", err))
+
+		return nil, api.ErrFailedToGetTransaction
+	}
+
+	if entryType, ok := p.Args["type"].(api.DebitOrCreditType); ok {
+		filtered := make([]*api.Transaction, 0, len(txs))
+
+		for _, tx := range txs {
+			if strings.EqualFold(string(tx.Type), string(entryType)) {
+				filtered = append(filtered, tx)
+			}
+		}
+
+		txs = filtered
+	}
+
+	page := &transactionPage{
+		total: len(txs),
+		items: []*api.Transaction{},
+	}
+
+	if offset < len(txs) {
+		end := min(offset+limit, len(txs))
+		page.items = txs[offset:end]
+		page.more = end < len(txs)
+	}
+
+	return page, nil
+}
+
+func transactionField(t graphql.Output, get func(*api.Transaction) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(*api.Transaction)), nil //nolint:forcetypeassert // always a transaction
+		},
+	}
+}
+
+func accountField(t graphql.Output, get func(*api.Account) any) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(*api.Account)), nil //nolint:forcetypeassert // always an account
+		},
+	}
+}
+
+func mergeArgs(args ...graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	merged := graphql.FieldConfigArgument{}
+
+	for _, a := range args {
+		for name, config := range a {
+			merged[name] = config
+		}
+	}
+
+	return merged
+}
+
+// stringArgs converts a list argument, given as a slice of any.
+func stringArgs(arg any) []string {
+	values, _ := arg.([]any)
+	strs := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
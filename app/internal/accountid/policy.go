@@ -0,0 +1,79 @@
+// Package accountid enforces the format of the account ids configured by the deployment,
+// so that i.e. the emails and the UUIDs don't mix in the same ledger.
+package accountid
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// UUID is the shorthand of the pattern of the lowercase UUIDs, like 5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f.
+	UUID = "uuid"
+	// MaxLength is the length of the account id column.
+	MaxLength = 255
+)
+
+const uuidPattern = `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+
+// Policy is the format of the account ids. The zero policy allows any id up to MaxLength characters.
+type Policy struct {
+	pattern   *regexp.Regexp
+	minLength int
+	maxLength int
+}
+
+// New returns the policy matching the whole id against the pattern, or UUID, with minLength to maxLength characters.
+// The empty pattern allows any characters, and a zero maxLength is MaxLength.
+func New(pattern string, minLength, maxLength int) (Policy, error) {
+	if maxLength == 0 {
+		maxLength = MaxLength
+	}
+
+	if minLength < 0 || maxLength < 0 || maxLength > MaxLength || minLength > maxLength {
+		return Policy{}, fmt.Errorf("invalid account id lengths %d to %d, at most %d", minLength, maxLength, MaxLength)
+	}
+
+	policy := Policy{minLength: minLength, maxLength: maxLength}
+
+	if pattern == UUID {
+		pattern = uuidPattern
+	}
+
+	if pattern != "" {
+		compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid account id pattern: %w", err)
+		}
+
+		policy.pattern = compiled
+	}
+
+	return policy, nil
+}
+
+// Any returns the policy allowing any id up to MaxLength characters.
+func Any() Policy {
+	return Policy{maxLength: MaxLength}
+}
+
+// Validate returns api.ErrInvalidAccountID, explaining the format, when the id doesn't follow the policy.
+func (p Policy) Validate(accountID string) error {
+	maxLength := p.maxLength
+	if maxLength == 0 {
+		maxLength = MaxLength
+	}
+
+	if length := utf8.RuneCountInString(accountID); length < p.minLength || length > maxLength {
+		return fmt.Errorf("%w: %q must have %d to %d characters", api.ErrInvalidAccountID, accountID, p.minLength, maxLength)
+	}
+
+	if p.pattern != nil && !p.pattern.MatchString(accountID) {
+		return fmt.Errorf("%w: %q must match %s", api.ErrInvalidAccountID, accountID, p.pattern)
+	}
+
+	return nil
+}
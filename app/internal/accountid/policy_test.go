@@ -0,0 +1,58 @@
+package accountid_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("Any", func(t *testing.T) {
+		for _, id := range []string{"user1", "alice@example.com", "5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f"} {
+			require.NoError(t, accountid.Any().Validate(id), id)
+		}
+
+		require.ErrorIs(t, accountid.Any().Validate(strings.Repeat("a", accountid.MaxLength+1)), api.ErrInvalidAccountID)
+	})
+
+	t.Run("UUID", func(t *testing.T) {
+		policy, err := accountid.New(accountid.UUID, 0, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, policy.Validate("5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f"))
+
+		for _, id := range []string{"alice@example.com", "5F0C8A1E-3B7D-4C2A-9E6F-1D2B3C4D5E6F", "x5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f"} {
+			err = policy.Validate(id)
+			require.ErrorIs(t, err, api.ErrInvalidAccountID, id)
+			require.Contains(t, err.Error(), "must match", id)
+		}
+	})
+
+	t.Run("Pattern and lengths", func(t *testing.T) {
+		policy, err := accountid.New(`[a-z0-9_]+`, 3, 8)
+		require.NoError(t, err)
+
+		require.NoError(t, policy.Validate("user_1"))
+		require.ErrorIs(t, policy.Validate("ab"), api.ErrInvalidAccountID)
+		require.ErrorIs(t, policy.Validate("user_12345"), api.ErrInvalidAccountID)
+		require.ErrorIs(t, policy.Validate("user-1"), api.ErrInvalidAccountID, "the whole id must match")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, args := range []struct {
+			pattern              string
+			minLength, maxLength int
+		}{
+			{pattern: "[a-z"},
+			{minLength: -1},
+			{maxLength: accountid.MaxLength + 1},
+			{minLength: 10, maxLength: 5},
+		} {
+			_, err := accountid.New(args.pattern, args.minLength, args.maxLength)
+			require.Error(t, err, args)
+		}
+	})
+}
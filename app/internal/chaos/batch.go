@@ -0,0 +1,34 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/devshark/wallet/api"
+)
+
+// TransferBatches is implemented by repository.PostgresRepository.
+type TransferBatches interface {
+	TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error)
+}
+
+// FaultyBatches faults the transfer batches like Repository does the transfers.
+type FaultyBatches struct {
+	TransferBatches
+	faults *Repository
+}
+
+// Batches wraps the transfer batches with the faults of the repository.
+func (r *Repository) Batches(batches TransferBatches) *FaultyBatches {
+	return &FaultyBatches{
+		TransferBatches: batches,
+		faults:          r,
+	}
+}
+
+func (b *FaultyBatches) TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	if err := b.faults.inject(ctx, "TransferBatch"); err != nil {
+		return nil, err
+	}
+
+	return b.TransferBatches.TransferBatch(ctx, requests, idempotencyKey)
+}
@@ -0,0 +1,87 @@
+// Package chaos injects faults into the calls of the repository and the Redis operations, i.e. in staging,
+// so the operators can check how the retries, the circuit breakers and the health checks behave.
+// It is never enabled unless configured, see the CHAOS_* settings.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrInjectedFault is the error of the calls failed on purpose.
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults are the percentages of the calls delayed or failed. A call can be delayed, then failed.
+type Faults struct {
+	// FailurePercent of the calls fail with ErrInjectedFault, from 0 to 100.
+	FailurePercent int
+	// DelayPercent of the calls are delayed by Delay, from 0 to 100.
+	DelayPercent int
+	Delay        time.Duration
+}
+
+// Enabled is true when any call may be faulted.
+func (f Faults) Enabled() bool {
+	return f.FailurePercent > 0 || (f.DelayPercent > 0 && f.Delay > 0)
+}
+
+// Injector decides which calls are faulted, at random.
+type Injector struct {
+	faults Faults
+	random func() float64
+	logger *slog.Logger
+}
+
+func NewInjector(faults Faults) *Injector {
+	return &Injector{
+		faults: faults,
+		random: rand.Float64,
+		logger: slog.Default(),
+	}
+}
+
+// WithRandom replaces the source of the random numbers in [0, 1), i.e. to fault every call in the tests.
+func (i *Injector) WithRandom(random func() float64) *Injector {
+	i.random = random
+
+	return i
+}
+
+func (i *Injector) WithLogger(logger *slog.Logger) *Injector {
+	i.logger = logger
+
+	return i
+}
+
+// Inject delays the operation and returns ErrInjectedFault, each with its percentage of chance.
+// The delay is cut short when the context is done, and its error is returned instead.
+func (i *Injector) Inject(ctx context.Context, operation string) error {
+	if i.faults.Delay > 0 && i.roll(i.faults.DelayPercent) {
+		i.logger.DebugContext(ctx, "delaying", slog.String("operation", operation), slog.Duration("delay", i.faults.Delay))
+
+		timer := time.NewTimer(i.faults.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", operation, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	if i.roll(i.faults.FailurePercent) {
+		i.logger.DebugContext(ctx, "failing", slog.String("operation", operation))
+
+		return fmt.Errorf("%s: %w", operation, ErrInjectedFault)
+	}
+
+	return nil
+}
+
+func (i *Injector) roll(percent int) bool {
+	return percent > 0 && i.random()*100 < float64(percent)
+}
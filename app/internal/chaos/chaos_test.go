@@ -0,0 +1,129 @@
+package chaos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/chaos"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fixed always rolls the same number, i.e. 0.5 faults the calls above 50%.
+func fixed(value float64) func() float64 {
+	return func() float64 {
+		return value
+	}
+}
+
+func TestInjector(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Disabled", func(t *testing.T) {
+		faults := chaos.Faults{}
+		require.False(t, faults.Enabled())
+
+		injector := chaos.NewInjector(faults).WithRandom(fixed(0))
+		require.NoError(t, injector.Inject(ctx, "op"))
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		faults := chaos.Faults{FailurePercent: 30}
+		require.True(t, faults.Enabled())
+
+		injector := chaos.NewInjector(faults).WithLogger(logging.Discard()).WithRandom(fixed(0.29))
+		require.ErrorIs(t, injector.Inject(ctx, "op"), chaos.ErrInjectedFault)
+
+		injector = chaos.NewInjector(faults).WithLogger(logging.Discard()).WithRandom(fixed(0.3))
+		require.NoError(t, injector.Inject(ctx, "op"))
+	})
+
+	t.Run("Delays", func(t *testing.T) {
+		injector := chaos.NewInjector(chaos.Faults{DelayPercent: 100, Delay: 20 * time.Millisecond}).
+			WithLogger(logging.Discard()).
+			WithRandom(fixed(0.99))
+
+		start := time.Now()
+		require.NoError(t, injector.Inject(ctx, "op"))
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("Delay cut short", func(t *testing.T) {
+		injector := chaos.NewInjector(chaos.Faults{DelayPercent: 100, Delay: time.Hour}).
+			WithLogger(logging.Discard()).
+			WithRandom(fixed(0))
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, injector.Inject(ctx, "op"), context.DeadlineExceeded)
+	})
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	request := &api.TransferRequest{FromAccountID: "user1", ToAccountID: "user2", Currency: "USD"}
+
+	t.Run("Failure", func(t *testing.T) {
+		injector := chaos.NewInjector(chaos.Faults{FailurePercent: 100}).WithLogger(logging.Discard())
+		faulty := chaos.NewRepository(repository.NewMockRepository(t), injector)
+
+		_, err := faulty.Transfer(ctx, request, "key1")
+		require.ErrorIs(t, err, chaos.ErrInjectedFault)
+		require.ErrorIs(t, err, api.ErrUnhandledDatabaseError, "like the database being unavailable")
+
+		require.ErrorIs(t, faulty.DeleteAlias(ctx, "alias1"), chaos.ErrInjectedFault)
+
+		_, err = faulty.Batches(nil).TransferBatch(ctx, []*api.TransferRequest{request}, "payroll")
+		require.ErrorIs(t, err, chaos.ErrInjectedFault)
+
+		holds := faulty.Holds(nil)
+
+		_, err = holds.PlaceHold(ctx, request, "card-1")
+		require.ErrorIs(t, err, chaos.ErrInjectedFault)
+
+		_, err = holds.CaptureHold(ctx, "hold1")
+		require.ErrorIs(t, err, chaos.ErrInjectedFault)
+	})
+
+	t.Run("Passed through", func(t *testing.T) {
+		repo := repository.NewMockRepository(t)
+		repo.EXPECT().Transfer(mock.Anything, request, "key1").Return([]*api.Transaction{{TxID: "tx1"}}, nil).Once()
+		repo.EXPECT().GetAccountBalance(mock.Anything, "USD", "user1").Return(nil, api.ErrAccountNotFound).Once()
+
+		faulty := chaos.NewRepository(repo, chaos.NewInjector(chaos.Faults{FailurePercent: 50}).WithRandom(fixed(0.5)))
+
+		txs, err := faulty.Transfer(ctx, request, "key1")
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+
+		_, err = faulty.GetAccountBalance(ctx, "USD", "user1")
+		require.ErrorIs(t, err, api.ErrAccountNotFound)
+	})
+}
+
+func TestRedisHook(t *testing.T) {
+	// nothing listens there, the commands fail before dialing
+	client := redis.NewClient(&redis.Options{Addr: "localhost:1", MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+
+	client.AddHook(chaos.NewRedisHook(chaos.NewInjector(chaos.Faults{FailurePercent: 100}).WithLogger(logging.Discard())))
+
+	ctx := context.Background()
+
+	err := client.Get(ctx, "key1").Err()
+	require.ErrorIs(t, err, chaos.ErrInjectedFault)
+	require.ErrorContains(t, err, "redis.get")
+
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "key1", "value", time.Minute)
+
+		return nil
+	})
+	require.ErrorIs(t, err, chaos.ErrInjectedFault)
+}
@@ -0,0 +1,61 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/devshark/wallet/api"
+)
+
+// Holds is implemented by repository.PostgresRepository.
+type Holds interface {
+	PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error)
+	GetHold(ctx context.Context, id string) (*api.Hold, error)
+	CaptureHold(ctx context.Context, id string) (*api.TransferReceipt, error)
+	ReleaseHold(ctx context.Context, id string) (*api.Hold, error)
+}
+
+// FaultyHolds faults the holds like Repository does the transfers.
+type FaultyHolds struct {
+	Holds
+	faults *Repository
+}
+
+// Holds wraps the holds with the faults of the repository.
+func (r *Repository) Holds(holds Holds) *FaultyHolds {
+	return &FaultyHolds{
+		Holds:  holds,
+		faults: r,
+	}
+}
+
+func (h *FaultyHolds) PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error) {
+	if err := h.faults.inject(ctx, "PlaceHold"); err != nil {
+		return nil, err
+	}
+
+	return h.Holds.PlaceHold(ctx, request, idempotencyKey)
+}
+
+func (h *FaultyHolds) GetHold(ctx context.Context, id string) (*api.Hold, error) {
+	if err := h.faults.inject(ctx, "GetHold"); err != nil {
+		return nil, err
+	}
+
+	return h.Holds.GetHold(ctx, id)
+}
+
+func (h *FaultyHolds) CaptureHold(ctx context.Context, id string) (*api.TransferReceipt, error) {
+	if err := h.faults.inject(ctx, "CaptureHold"); err != nil {
+		return nil, err
+	}
+
+	return h.Holds.CaptureHold(ctx, id)
+}
+
+func (h *FaultyHolds) ReleaseHold(ctx context.Context, id string) (*api.Hold, error) {
+	if err := h.faults.inject(ctx, "ReleaseHold"); err != nil {
+		return nil, err
+	}
+
+	return h.Holds.ReleaseHold(ctx, id)
+}
@@ -0,0 +1,33 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisHook faults the Redis commands before they're sent, see redis.UniversalClient.AddHook.
+// A pipeline is faulted as a whole.
+type RedisHook struct {
+	injector *Injector
+}
+
+func NewRedisHook(injector *Injector) *RedisHook {
+	return &RedisHook{injector: injector}
+}
+
+func (h *RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, h.injector.Inject(ctx, "redis."+cmd.Name())
+}
+
+func (h *RedisHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *RedisHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, h.injector.Inject(ctx, "redis.pipeline")
+}
+
+func (h *RedisHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
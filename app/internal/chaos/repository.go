@@ -0,0 +1,151 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+)
+
+// Repository faults the calls before passing them to the wrapped repository.
+// The failures look like the database being unavailable, i.e. they're answered with a 500.
+type Repository struct {
+	repository.Repository
+	injector *Injector
+}
+
+func NewRepository(repo repository.Repository, injector *Injector) *Repository {
+	return &Repository{
+		Repository: repo,
+		injector:   injector,
+	}
+}
+
+func (r *Repository) inject(ctx context.Context, operation string) error {
+	if err := r.injector.Inject(ctx, "repository."+operation); err != nil {
+		return fmt.Errorf("%w: %w", api.ErrUnhandledDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error) {
+	if err := r.inject(ctx, "Transfer"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.Transfer(ctx, request, idempotencyKey)
+}
+
+func (r *Repository) GetAccountBalance(ctx context.Context, currency, accountID string) (*api.Account, error) {
+	if err := r.inject(ctx, "GetAccountBalance"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountBalance(ctx, currency, accountID)
+}
+
+func (r *Repository) GetAccountBalances(ctx context.Context, accounts []api.AccountKey) (*api.Balances, error) {
+	if err := r.inject(ctx, "GetAccountBalances"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountBalances(ctx, accounts)
+}
+
+func (r *Repository) GetAccountStats(ctx context.Context, currency, accountID string) (*api.Account, error) {
+	if err := r.inject(ctx, "GetAccountStats"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountStats(ctx, currency, accountID)
+}
+
+func (r *Repository) GetAccountSummary(ctx context.Context, accountID string) (*api.AccountSummary, error) {
+	if err := r.inject(ctx, "GetAccountSummary"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountSummary(ctx, accountID)
+}
+
+func (r *Repository) GetTransaction(ctx context.Context, txID string) (*api.Transaction, error) {
+	if err := r.inject(ctx, "GetTransaction"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetTransaction(ctx, txID)
+}
+
+func (r *Repository) GetTransactions(ctx context.Context, currency, accountID string) ([]*api.Transaction, error) {
+	if err := r.inject(ctx, "GetTransactions"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetTransactions(ctx, currency, accountID)
+}
+
+func (r *Repository) FilterTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter) ([]*api.Transaction, error) {
+	if err := r.inject(ctx, "FilterTransactions"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.FilterTransactions(ctx, currency, accountID, filter)
+}
+
+func (r *Repository) GetTransactionsPage(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, limit int) (*api.TransactionPage, error) {
+	if err := r.inject(ctx, "GetTransactionsPage"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetTransactionsPage(ctx, currency, accountID, filter, cursor, limit)
+}
+
+func (r *Repository) SetParentAccount(ctx context.Context, accountID, parentID string) error {
+	if err := r.inject(ctx, "SetParentAccount"); err != nil {
+		return err
+	}
+
+	return r.Repository.SetParentAccount(ctx, accountID, parentID)
+}
+
+func (r *Repository) GetAccountTree(ctx context.Context, currency, accountID string) (*api.Account, error) {
+	if err := r.inject(ctx, "GetAccountTree"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountTree(ctx, currency, accountID)
+}
+
+func (r *Repository) RegisterAlias(ctx context.Context, accountID string, request *api.RegisterAliasRequest) (*api.AccountAlias, error) {
+	if err := r.inject(ctx, "RegisterAlias"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.RegisterAlias(ctx, accountID, request)
+}
+
+func (r *Repository) ResolveAlias(ctx context.Context, alias string) (*api.AccountAlias, error) {
+	if err := r.inject(ctx, "ResolveAlias"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.ResolveAlias(ctx, alias)
+}
+
+func (r *Repository) GetAccountAliases(ctx context.Context, accountID string) ([]*api.AccountAlias, error) {
+	if err := r.inject(ctx, "GetAccountAliases"); err != nil {
+		return nil, err
+	}
+
+	return r.Repository.GetAccountAliases(ctx, accountID)
+}
+
+func (r *Repository) DeleteAlias(ctx context.Context, alias string) error {
+	if err := r.inject(ctx, "DeleteAlias"); err != nil {
+		return err
+	}
+
+	return r.Repository.DeleteAlias(ctx, alias)
+}
@@ -0,0 +1,95 @@
+// Package declines counts the transfers declined by the ledger, i.e. for an insufficient balance or a duplicate,
+// so the decline rates can be monitored without scraping the logs.
+package declines
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"sync"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// MaxCurrencies is how many currencies are counted apart for each reason, the requests set them,
+	// so the others are counted under OtherCurrency.
+	MaxCurrencies = 64
+	// OtherCurrency counts the currencies beyond MaxCurrencies, and the invalid ones.
+	OtherCurrency = "OTHER"
+)
+
+//nolint:gochecknoglobals // expvar panics when a name is published twice
+var (
+	declinedOnce sync.Once
+	declined     *expvar.Map
+)
+
+// declinedTransfers is the declined_transfers expvar variable, served under /debug/vars:
+// the number of declined transfers by error code and currency, i.e. {"INSUFFICIENT_BALANCE": {"USD": 3}}.
+func declinedTransfers() *expvar.Map {
+	declinedOnce.Do(func() {
+		declined = expvar.NewMap("declined_transfers")
+	})
+
+	return declined
+}
+
+// Counter is a repository.TransferHook counting the declined transfers in the declined_transfers expvar variable.
+// The transfers held for a review or an approval aren't declined, nor are the failures of the database.
+type Counter struct {
+	mu     sync.Mutex
+	counts *expvar.Map
+}
+
+func NewCounter() *Counter {
+	return &Counter{counts: declinedTransfers()}
+}
+
+// BeforeTransfer doesn't hold the transfers.
+func (c *Counter) BeforeTransfer(context.Context, *sql.Tx, *api.TransferRequest, string) error {
+	return nil
+}
+
+// AfterTransfer counts the transfer under the code of its error and its currency, if it's been declined.
+func (c *Counter) AfterTransfer(_ context.Context, request *api.TransferRequest, _ string, _ []*api.Transaction, err error) {
+	if err == nil || errors.Is(err, api.ErrTransferUnderReview) || errors.Is(err, api.ErrTransferPendingApproval) {
+		return
+	}
+
+	code := api.CodeOf(err)
+	if code == api.CodeUnknown {
+		return
+	}
+
+	currency := request.Currency
+	if currency == "" || code == api.CodeInvalidCurrency {
+		currency = OtherCurrency
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	currencies, ok := c.counts.Get(string(code)).(*expvar.Map)
+	if !ok {
+		currencies = new(expvar.Map)
+		c.counts.Set(string(code), currencies)
+	}
+
+	if currencies.Get(currency) == nil && countKeys(currencies) >= MaxCurrencies {
+		currency = OtherCurrency
+	}
+
+	currencies.Add(currency, 1)
+}
+
+func countKeys(m *expvar.Map) int {
+	count := 0
+
+	m.Do(func(expvar.KeyValue) {
+		count++
+	})
+
+	return count
+}
@@ -0,0 +1,69 @@
+package declines_test
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/declines"
+	"github.com/stretchr/testify/require"
+)
+
+func declined(code api.ErrorCode, currency string) int64 {
+	currencies, ok := expvar.Get("declined_transfers").(*expvar.Map).Get(string(code)).(*expvar.Map)
+	if !ok {
+		return 0
+	}
+
+	count, ok := currencies.Get(currency).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+
+	return count.Value()
+}
+
+func TestCounter(t *testing.T) {
+	ctx := context.Background()
+	counter := declines.NewCounter()
+
+	decline := func(currency string, err error) {
+		request := &api.TransferRequest{FromAccountID: "user1", ToAccountID: "user2", Currency: currency}
+
+		require.NoError(t, counter.BeforeTransfer(ctx, nil, request, "key"))
+		counter.AfterTransfer(ctx, request, "key", nil, err)
+	}
+
+	decline("USD", fmt.Errorf("%w: 10 USD", api.ErrInsufficientBalance))
+	decline("USD", api.ErrInsufficientBalance)
+	decline("EUR", &api.DuplicateTransactionError{GroupID: "key"})
+
+	require.Equal(t, int64(2), declined(api.CodeInsufficientBalance, "USD"))
+	require.Equal(t, int64(1), declined(api.CodeDuplicateTransaction, "EUR"))
+
+	t.Run("Not declined", func(t *testing.T) {
+		decline("USD", nil)
+		decline("USD", api.ErrTransferPendingApproval)
+		decline("USD", api.ErrTransferUnderReview)
+		decline("USD", fmt.Errorf("%w: connection reset", api.ErrUnhandledDatabaseError))
+
+		require.Equal(t, int64(2), declined(api.CodeInsufficientBalance, "USD"))
+		require.Zero(t, declined(api.CodeTransferPendingApproval, "USD"))
+		require.Zero(t, declined(api.CodeTransferUnderReview, "USD"))
+	})
+
+	t.Run("Currencies capped", func(t *testing.T) {
+		decline("NOT A CURRENCY", api.ErrInvalidCurrency)
+		require.Equal(t, int64(1), declined(api.CodeInvalidCurrency, declines.OtherCurrency))
+
+		for i := range declines.MaxCurrencies + 1 {
+			decline(fmt.Sprintf("C%d", i), api.ErrInvalidAmount)
+		}
+
+		require.Equal(t, int64(1), declined(api.CodeInvalidAmount, "C0"))
+		require.Zero(t, declined(api.CodeInvalidAmount, fmt.Sprintf("C%d", declines.MaxCurrencies)))
+		require.Equal(t, int64(1), declined(api.CodeInvalidAmount, declines.OtherCurrency))
+	})
+}
@@ -0,0 +1,87 @@
+package features
+
+import (
+	"context"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/pkg/featureflags"
+)
+
+// The flags of the risky behaviors, all disabled by default so they can be rolled out gradually.
+const (
+	// StrictAccountCreation enforces the account provisioning policy of the deployment, see WithProvisioningPolicy,
+	// so a transfer to a mistyped recipient is rejected instead of creating an orphan account holding the funds.
+	StrictAccountCreation featureflags.Flag = "strict_account_creation"
+	// StrictAccountIDs enforces the account id policy of the deployment, see WithAccountIDPolicy.
+	StrictAccountIDs featureflags.Flag = "strict_account_ids"
+)
+
+// Features is the typed accessor of the flags, injected into the handlers.
+type Features struct {
+	flags        *featureflags.Flags
+	provisioning api.ProvisioningPolicy
+	accountIDs   accountid.Policy
+}
+
+func New(flags *featureflags.Flags) Features {
+	return Features{
+		flags: flags,
+	}
+}
+
+// Disabled returns the features with every flag at its default, i.e. disabled.
+func Disabled() Features {
+	return New(featureflags.New())
+}
+
+// WithProvisioningPolicy sets which accounts the transfers may open while StrictAccountCreation is enabled,
+// which is then enabled by default so the policy applies unless the flag is switched off.
+func (f Features) WithProvisioningPolicy(policy api.ProvisioningPolicy) Features {
+	f.provisioning = policy
+	f.flags.WithDefault(StrictAccountCreation, true)
+
+	return f
+}
+
+// WithAccountIDPolicy sets the format of the account ids enforced by StrictAccountIDs, which is then enabled by default
+// so the policy applies unless the flag is switched off.
+func (f Features) WithAccountIDPolicy(policy accountid.Policy) Features {
+	f.accountIDs = policy
+	f.flags.WithDefault(StrictAccountIDs, true)
+
+	return f
+}
+
+func (f Features) StrictAccountCreation(ctx context.Context) bool {
+	return f.flags.Enabled(ctx, StrictAccountCreation)
+}
+
+func (f Features) StrictAccountIDs(ctx context.Context) bool {
+	return f.flags.Enabled(ctx, StrictAccountIDs)
+}
+
+// ProvisioningPolicy is the provisioning policy of the deployment while StrictAccountCreation is enabled,
+// api.ProvisionAuto otherwise.
+func (f Features) ProvisioningPolicy(ctx context.Context) api.ProvisioningPolicy {
+	if f.provisioning == "" || !f.StrictAccountCreation(ctx) {
+		return api.ProvisionAuto
+	}
+
+	return f.provisioning
+}
+
+// AccountIDPolicy is the account id policy of the deployment while StrictAccountIDs is enabled, any id otherwise.
+func (f Features) AccountIDPolicy(ctx context.Context) accountid.Policy {
+	if !f.StrictAccountIDs(ctx) {
+		return accountid.Any()
+	}
+
+	return f.accountIDs
+}
+
+// ValidateAccountID returns api.ErrInvalidAccountID, explaining the format, when the account id doesn't follow
+// the AccountIDPolicy.
+func (f Features) ValidateAccountID(ctx context.Context, accountID string) error {
+	return f.AccountIDPolicy(ctx).Validate(accountID) //nolint:wrapcheck // the domain errors are returned as is
+}
@@ -0,0 +1,55 @@
+package features_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/pkg/featureflags"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatures(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := accountid.New(accountid.UUID, 0, 0)
+	require.NoError(t, err)
+
+	t.Run("Disabled", func(t *testing.T) {
+		f := features.Disabled()
+
+		require.False(t, f.StrictAccountCreation(ctx))
+		require.False(t, f.StrictAccountIDs(ctx))
+		require.NoError(t, f.ValidateAccountID(ctx, "user 1; DROP TABLE"))
+	})
+
+	t.Run("Provisioning policy", func(t *testing.T) {
+		require.Equal(t, api.ProvisionAuto, features.Disabled().ProvisioningPolicy(ctx))
+
+		f := features.Disabled().WithProvisioningPolicy(api.ProvisionStrict)
+		require.Equal(t, api.ProvisionStrict, f.ProvisioningPolicy(ctx), "enabled by default once configured")
+
+		f = features.New(featureflags.New(featureflags.MapSource{features.StrictAccountCreation: false})).
+			WithProvisioningPolicy(api.ProvisionStrict)
+		require.Equal(t, api.ProvisionAuto, f.ProvisioningPolicy(ctx), "switched off")
+	})
+
+	t.Run("Account id policy", func(t *testing.T) {
+		f := features.Disabled().WithAccountIDPolicy(policy)
+
+		require.True(t, f.StrictAccountIDs(ctx), "enabled by default once configured")
+		require.NoError(t, f.ValidateAccountID(ctx, "5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f"))
+
+		err := f.ValidateAccountID(ctx, "alice@example.com")
+		require.ErrorIs(t, err, api.ErrInvalidAccountID)
+		require.Contains(t, err.Error(), "must match")
+	})
+
+	t.Run("Account id policy switched off", func(t *testing.T) {
+		f := features.New(featureflags.New(featureflags.MapSource{features.StrictAccountIDs: false})).WithAccountIDPolicy(policy)
+
+		require.NoError(t, f.ValidateAccountID(ctx, "alice@example.com"))
+	})
+}
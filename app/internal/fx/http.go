@@ -0,0 +1,83 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTimeout bounds a quote, the transfer waits for it.
+const DefaultTimeout = 5 * time.Second
+
+// HTTP quotes the rates from an external service: GET <url>?from=USD&to=EUR answered with {"rate": "0.92"},
+// or 404 Not Found for a pair it doesn't quote. The rates are quoted for every conversion, so they're never stale.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		url:    url,
+		client: &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// WithHTTPClient overrides the client requesting the rates, i.e. for its transport or its timeout.
+func (h *HTTP) WithHTTPClient(client *http.Client) *HTTP {
+	h.client = client
+
+	return h
+}
+
+// quote is the response of the service.
+type quote struct {
+	Rate decimal.Decimal `json:"rate"`
+}
+
+func (h *HTTP) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	pair := Pair{From: strings.ToUpper(from), To: strings.ToUpper(to)}
+
+	query := url.Values{"from": {pair.From}, "to": {pair.To}}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to create the rate request: %w", err)
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to request the rate of %s: %w", pair, err)
+	}
+
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		// drained so the connection can be reused
+		_, _ = io.Copy(io.Discard, response.Body)
+
+		return decimal.Zero, fmt.Errorf("%w: %s", api.ErrUnsupportedCurrencyPair, pair)
+	case response.StatusCode != http.StatusOK:
+		_, _ = io.Copy(io.Discard, response.Body)
+
+		return decimal.Zero, fmt.Errorf("the rate of %s was answered with %d", pair, response.StatusCode)
+	}
+
+	var quoted quote
+	if err = json.NewDecoder(response.Body).Decode(&quoted); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to decode the rate of %s: %w", pair, err)
+	}
+
+	return quoted.Rate, nil
+}
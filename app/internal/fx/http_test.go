@@ -0,0 +1,41 @@
+package fx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/fx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("from") + "/" + r.URL.Query().Get("to") {
+		case "USD/EUR":
+			_, _ = w.Write([]byte(`{"rate": "0.92"}`))
+		case "USD/JPY":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	provider := fx.NewHTTP(server.URL).WithHTTPClient(server.Client())
+
+	rate, err := provider.Rate(ctx, "usd", "eur")
+	require.NoError(t, err)
+	require.Equal(t, "0.92", rate.String())
+
+	_, err = provider.Rate(ctx, "USD", "JPY")
+	require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+
+	_, err = provider.Rate(ctx, "USD", "GBP")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, api.ErrUnsupportedCurrencyPair, "the service failed")
+}
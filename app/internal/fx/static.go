@@ -0,0 +1,52 @@
+// Package fx quotes the rates of the cross-currency transfers, from a static table or from an external service.
+package fx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// Pair is a currency pair, the rate of the pair is the amount of To bought by one unit of From.
+type Pair struct {
+	From string
+	To   string
+}
+
+func (p Pair) String() string {
+	return p.From + "/" + p.To
+}
+
+// Static quotes the rates of a fixed table, i.e. for the sandboxes, or a deployment updating its rates on restart.
+type Static struct {
+	rates map[Pair]decimal.Decimal
+}
+
+// NewStatic keys the rates by pair, case-insensitively.
+func NewStatic(rates map[Pair]decimal.Decimal) *Static {
+	static := &Static{rates: make(map[Pair]decimal.Decimal, len(rates))}
+
+	for pair, rate := range rates {
+		static.rates[Pair{From: strings.ToUpper(pair.From), To: strings.ToUpper(pair.To)}] = rate
+	}
+
+	return static
+}
+
+// Rate quotes the rate of the pair, or the inverse of the rate of the reverse pair when only that one is listed.
+func (s *Static) Rate(_ context.Context, from, to string) (decimal.Decimal, error) {
+	pair := Pair{From: strings.ToUpper(from), To: strings.ToUpper(to)}
+
+	if rate, ok := s.rates[pair]; ok {
+		return rate, nil
+	}
+
+	if rate, ok := s.rates[Pair{From: pair.To, To: pair.From}]; ok && rate.IsPositive() {
+		return decimal.NewFromInt(1).DivRound(rate, api.MaxAmountScale), nil
+	}
+
+	return decimal.Zero, fmt.Errorf("%w: %s", api.ErrUnsupportedCurrencyPair, pair)
+}
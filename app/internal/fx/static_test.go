@@ -0,0 +1,30 @@
+package fx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/fx"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	ctx := context.Background()
+
+	static := fx.NewStatic(map[fx.Pair]decimal.Decimal{
+		{From: "usd", To: "EUR"}: decimal.RequireFromString("0.8"),
+	})
+
+	rate, err := static.Rate(ctx, "USD", "eur")
+	require.NoError(t, err)
+	require.Equal(t, "0.8", rate.String())
+
+	rate, err = static.Rate(ctx, "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "1.25", rate.String(), "the inverse of the reverse pair")
+
+	_, err = static.Rate(ctx, "USD", "JPY")
+	require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+}
@@ -0,0 +1,155 @@
+// Package i18n translates the messages of the error responses by their api.ErrorCode, in the language negotiated
+// with the Accept-Language header, so the apps of the end users display them without their own mapping tables.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"golang.org/x/text/language"
+)
+
+var ErrInvalidMessages = errors.New("invalid error messages")
+
+//go:embed messages/*.json
+var defaultMessages embed.FS
+
+// Source is the language the errors are written in, their messages are never translated to it.
+//
+//nolint:gochecknoglobals // constant
+var Source = language.English
+
+// Catalog holds the messages of the error codes by language, one JSON object of codes to messages per language.
+type Catalog struct {
+	// languages are Source, the fallback of the negotiation, then the languages of the messages
+	languages []language.Tag
+	matcher   language.Matcher
+	messages  map[language.Tag]map[api.ErrorCode]string
+}
+
+// DefaultCatalog holds the messages embedded in the binary, in Spanish and French.
+func DefaultCatalog() *Catalog {
+	catalog, err := newCatalog(defaultMessages, "messages")
+	if err != nil {
+		panic(err)
+	}
+
+	return catalog
+}
+
+// ParseCatalog overrides the default messages with the files of the directory, named after their language,
+// i.e. pt-BR.json. A file may translate some codes only, the others keep the default messages of its language, if any.
+func ParseCatalog(dir string) (*Catalog, error) {
+	defaults, err := newCatalog(defaultMessages, "messages")
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := newCatalog(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range overrides.languages[1:] {
+		defaults.add(tag, overrides.messages[tag])
+	}
+
+	return defaults, nil
+}
+
+func newCatalog(fsys fs.FS, dir string) (*Catalog, error) {
+	catalog := &Catalog{
+		languages: []language.Tag{Source},
+		matcher:   language.NewMatcher([]language.Tag{Source}),
+		messages:  map[language.Tag]map[api.ErrorCode]string{},
+	}
+
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessages, err)
+	}
+
+	known := map[api.ErrorCode]bool{}
+	for _, code := range api.ErrorCodes() {
+		known[code] = true
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".json")
+
+		tag, err := language.Parse(name)
+		if err != nil || tag == Source {
+			return nil, fmt.Errorf("%w: %s isn't named after a language other than %s", ErrInvalidMessages, file, Source)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidMessages, err)
+		}
+
+		messages := map[api.ErrorCode]string{}
+
+		if err = json.Unmarshal(content, &messages); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidMessages, file, err)
+		}
+
+		for code, message := range messages {
+			if !known[code] || strings.TrimSpace(message) == "" {
+				return nil, fmt.Errorf("%w: %s: unknown code or empty message for %q", ErrInvalidMessages, file, code)
+			}
+		}
+
+		catalog.add(tag, messages)
+	}
+
+	return catalog, nil
+}
+
+// add merges the messages of the language into the catalog.
+func (c *Catalog) add(tag language.Tag, messages map[api.ErrorCode]string) {
+	if _, ok := c.messages[tag]; !ok {
+		c.languages = append(c.languages, tag)
+		c.messages[tag] = map[api.ErrorCode]string{}
+	}
+
+	for code, message := range messages {
+		c.messages[tag][code] = message
+	}
+
+	c.matcher = language.NewMatcher(c.languages)
+}
+
+// Languages returns the languages of the catalog, Source first.
+func (c *Catalog) Languages() []language.Tag {
+	return c.languages
+}
+
+// Negotiate returns the language of the catalog matching the Accept-Language header best, i.e. es for es-MX,
+// or Source when none does or the header is malformed.
+func (c *Catalog) Negotiate(acceptLanguage string) language.Tag {
+	preferred, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(preferred) == 0 {
+		return Source
+	}
+
+	_, index, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		return Source
+	}
+
+	return c.languages[index]
+}
+
+// Message returns the message of the code in the language, false when it isn't translated.
+func (c *Catalog) Message(tag language.Tag, code api.ErrorCode) (string, bool) {
+	message, ok := c.messages[tag][code]
+
+	return message, ok
+}
@@ -0,0 +1,97 @@
+package i18n_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/i18n"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	catalog := i18n.DefaultCatalog()
+
+	require.Equal(t, []language.Tag{i18n.Source, language.Spanish, language.French}, catalog.Languages())
+
+	for _, tag := range catalog.Languages()[1:] {
+		for _, code := range api.ErrorCodes() {
+			message, ok := catalog.Message(tag, code)
+			require.True(t, ok, "%s is missing %s", tag, code)
+			require.NotEmpty(t, message)
+		}
+	}
+
+	_, ok := catalog.Message(i18n.Source, api.CodeInsufficientBalance)
+	require.False(t, ok, "the errors are written in the source language")
+}
+
+func TestNegotiate(t *testing.T) {
+	catalog := i18n.DefaultCatalog()
+
+	for acceptLanguage, expected := range map[string]language.Tag{
+		"":                         i18n.Source,
+		"*":                        i18n.Source,
+		"es":                       language.Spanish,
+		"es-MX":                    language.Spanish,
+		"fr-CA, en;q=0.8":          language.French,
+		"en, fr;q=0.8":             i18n.Source,
+		"de, fr;q=0.5, es;q=0.7":   language.Spanish,
+		"ja":                       i18n.Source,
+		"es;q=x":                   i18n.Source,
+		"zz-invalid-language-tag!": i18n.Source,
+	} {
+		require.Equal(t, expected, catalog.Negotiate(acceptLanguage), acceptLanguage)
+	}
+}
+
+func TestParseCatalog(t *testing.T) {
+	write := func(t *testing.T, dir, name, content string) {
+		t.Helper()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	t.Run("Overridden", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "es.json", `{"INSUFFICIENT_BALANCE": "No tienes saldo suficiente"}`)
+		write(t, dir, "pt-BR.json", `{"INSUFFICIENT_BALANCE": "Saldo insuficiente"}`)
+		write(t, dir, "README.md", "ignored")
+
+		catalog, err := i18n.ParseCatalog(dir)
+		require.NoError(t, err)
+
+		message, _ := catalog.Message(language.Spanish, api.CodeInsufficientBalance)
+		require.Equal(t, "No tienes saldo suficiente", message)
+
+		message, _ = catalog.Message(language.Spanish, api.CodeAccountNotFound)
+		require.Equal(t, "No se encontró la cuenta", message, "the other codes keep their default message")
+
+		portuguese := language.MustParse("pt-BR")
+		require.Equal(t, portuguese, catalog.Negotiate("pt-BR,pt;q=0.9"))
+
+		message, _ = catalog.Message(portuguese, api.CodeInsufficientBalance)
+		require.Equal(t, "Saldo insuficiente", message)
+
+		_, ok := catalog.Message(portuguese, api.CodeAccountNotFound)
+		require.False(t, ok)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"es.json":          `["not", "an", "object"]`,
+			"fr.json":          `{"NOT_A_CODE": "inconnu"}`,
+			"de.json":          `{"INSUFFICIENT_BALANCE": " "}`,
+			"en.json":          `{"INSUFFICIENT_BALANCE": "not enough money"}`,
+			"not-a-tag!!.json": `{}`,
+		} {
+			dir := t.TempDir()
+			write(t, dir, name, content)
+
+			_, err := i18n.ParseCatalog(dir)
+			require.ErrorIs(t, err, i18n.ErrInvalidMessages, name)
+		}
+	})
+}
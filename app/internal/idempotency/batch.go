@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/devshark/wallet/api"
+)
+
+// TransferBatches is implemented by repository.PostgresRepository.
+type TransferBatches interface {
+	TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error)
+}
+
+// ReservedBatches reserves the idempotency keys of the transfer batches like Repository does for the transfers.
+type ReservedBatches struct {
+	TransferBatches
+	reservations *Repository
+}
+
+// Batches wraps the transfer batches with the reservations of the repository, sharing its TTL and its lease.
+func (r *Repository) Batches(batches TransferBatches) *ReservedBatches {
+	return &ReservedBatches{
+		TransferBatches: batches,
+		reservations:    r,
+	}
+}
+
+// TransferBatch reserves the group of the first transfer of the batch, which Postgres rejects the duplicates of too.
+func (b *ReservedBatches) TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	var receipt *api.TransferBatchReceipt
+
+	err := b.reservations.reserve(ctx, api.BatchGroupID(idempotencyKey, 0), func(ctx context.Context) (err error) {
+		receipt, err = b.TransferBatches.TransferBatch(ctx, requests, idempotencyKey)
+
+		return err
+	})
+
+	return receipt, err
+}
@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/devshark/wallet/api"
+)
+
+// Holds is implemented by repository.PostgresRepository.
+type Holds interface {
+	PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error)
+	GetHold(ctx context.Context, id string) (*api.Hold, error)
+	CaptureHold(ctx context.Context, id string) (*api.TransferReceipt, error)
+	ReleaseHold(ctx context.Context, id string) (*api.Hold, error)
+}
+
+// ReservedHolds reserves the idempotency keys of the holds like Repository does for the transfers.
+// The captures and the releases are keyed by the id of the hold, they're passed through.
+type ReservedHolds struct {
+	Holds
+	reservations *Repository
+}
+
+// Holds wraps the holds with the reservations of the repository, sharing its TTL and its lease.
+func (r *Repository) Holds(holds Holds) *ReservedHolds {
+	return &ReservedHolds{
+		Holds:        holds,
+		reservations: r,
+	}
+}
+
+// PlaceHold reserves the idempotency key of the hold, which is the group of its transfer once captured,
+// so it's reserved against the transfers too.
+func (h *ReservedHolds) PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error) {
+	var hold *api.Hold
+
+	err := h.reservations.reserve(ctx, idempotencyKey, func(ctx context.Context) (err error) {
+		hold, err = h.Holds.PlaceHold(ctx, request, idempotencyKey)
+
+		return err
+	})
+
+	return hold, err
+}
@@ -0,0 +1,130 @@
+// Package idempotency reserves the idempotency keys of the transfers in Redis before they reach Postgres,
+// so the deployments sharing the Redis, i.e. in several regions, reject the duplicates quickly and consistently.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// KeyPrefix namespaces the reservations in Redis.
+	KeyPrefix = "wallet:idempotency:"
+
+	// DefaultLease is how long a transfer in progress holds its key, so a crashed server doesn't hold it for the whole TTL.
+	DefaultLease = 30 * time.Second
+
+	// the values of the reservations
+	inProgress = "in_progress"
+	posted     = "posted"
+)
+
+// Reserver is implemented by redis.UniversalClient.
+type Reserver interface {
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Repository reserves the idempotency key of a transfer before passing it to the wrapped repository.
+// A posted transfer keeps its key reserved for the TTL, the other outcomes release it, so the retries are still served,
+// i.e. a held transfer is still reported as held. Postgres remains the source of truth:
+// the transfers go through unreserved when Redis is unavailable, and the expired keys are checked by Postgres again.
+type Repository struct {
+	repository.Repository
+	reserver Reserver
+	ttl      time.Duration
+	lease    time.Duration
+	logger   *slog.Logger
+}
+
+func NewRepository(repo repository.Repository, reserver Reserver, ttl time.Duration) *Repository {
+	return &Repository{
+		Repository: repo,
+		reserver:   reserver,
+		ttl:        ttl,
+		lease:      DefaultLease,
+		logger:     slog.Default(),
+	}
+}
+
+// WithLease sets how long a transfer in progress holds its key, it should exceed the time a transfer takes.
+func (r *Repository) WithLease(lease time.Duration) *Repository {
+	r.lease = lease
+
+	return r
+}
+
+func (r *Repository) WithLogger(logger *slog.Logger) *Repository {
+	r.logger = logger
+
+	return r
+}
+
+func (r *Repository) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error) {
+	var txs []*api.Transaction
+
+	err := r.reserve(ctx, idempotencyKey, func(ctx context.Context) (err error) {
+		txs, err = r.Repository.Transfer(ctx, request, idempotencyKey)
+
+		return err
+	})
+
+	return txs, err
+}
+
+// reserve reserves the group id while post runs, then keeps it reserved for the TTL if it's posted, or releases it.
+func (r *Repository) reserve(ctx context.Context, groupID string, post func(ctx context.Context) error) error {
+	key := KeyPrefix + groupID
+
+	reserved, err := r.reserver.SetNX(ctx, key, inProgress, r.lease).Result()
+	if err != nil {
+		r.logger.WarnContext(ctx, "failed to reserve idempotency key, transferring unreserved",
+			slog.String("idempotency_key", groupID), slog.Any("error", err))
+
+		return post(ctx)
+	}
+
+	if !reserved {
+		return r.reservedError(ctx, key, groupID)
+	}
+
+	err = post(ctx)
+
+	// the client may be gone, the reservation must be settled regardless
+	ctx = context.WithoutCancel(ctx)
+
+	if err == nil || errors.Is(err, api.ErrDuplicateTransaction) {
+		if errSet := r.reserver.Set(ctx, key, posted, r.ttl).Err(); errSet != nil {
+			// a retry is rejected by Postgres instead
+			r.logger.WarnContext(ctx, "failed to keep idempotency key", slog.String("idempotency_key", groupID), slog.Any("error", errSet))
+		}
+
+		return err
+	}
+
+	if errDel := r.reserver.Del(ctx, key).Err(); errDel != nil {
+		// the retries are rejected until the lease expires
+		r.logger.WarnContext(ctx, "failed to release idempotency key", slog.String("idempotency_key", groupID), slog.Any("error", errDel))
+	}
+
+	return err
+}
+
+// reservedError tells a posted transfer from one in progress, i.e. in another region.
+func (r *Repository) reservedError(ctx context.Context, key, idempotencyKey string) error {
+	value, err := r.reserver.Get(ctx, key).Result()
+	if err == nil && value == posted {
+		return &api.DuplicateTransactionError{GroupID: idempotencyKey}
+	}
+
+	// also when the reservation was released in the meantime, the client can retry either way
+	return api.ErrTransferInProgress
+}
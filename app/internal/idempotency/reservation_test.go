@@ -0,0 +1,148 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/idempotency"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservations(t *testing.T) {
+	ctx := context.Background()
+	request := &api.TransferRequest{FromAccountID: "user1", ToAccountID: "user2", Currency: "USD"}
+	ttl := 24 * time.Hour
+
+	newRepository := func(t *testing.T) (*idempotency.Repository, *repository.MockRepository, *idempotency.MockReserver) {
+		t.Helper()
+
+		repo := repository.NewMockRepository(t)
+		reserver := idempotency.NewMockReserver(t)
+
+		return idempotency.NewRepository(repo, reserver, ttl).
+			WithLease(time.Minute).
+			WithLogger(logging.Discard()), repo, reserver
+	}
+
+	t.Run("Posted", func(t *testing.T) {
+		reservations, repo, reserver := newRepository(t)
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"key1", mock.Anything, time.Minute).Return(redis.NewBoolResult(true, nil)).Once()
+		repo.EXPECT().Transfer(mock.Anything, request, "key1").Return([]*api.Transaction{{TxID: "tx1"}}, nil).Once()
+		reserver.EXPECT().Set(mock.Anything, idempotency.KeyPrefix+"key1", mock.Anything, ttl).Return(redis.NewStatusResult("OK", nil)).Once()
+
+		txs, err := reservations.Transfer(ctx, request, "key1")
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		reservations, _, reserver := newRepository(t)
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"key1", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+		reserver.EXPECT().Get(mock.Anything, idempotency.KeyPrefix+"key1").Return(redis.NewStringResult("posted", nil)).Once()
+
+		_, err := reservations.Transfer(ctx, request, "key1")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "rejected without reaching Postgres")
+
+		duplicate := &api.DuplicateTransactionError{}
+		require.ErrorAs(t, err, &duplicate)
+		require.Equal(t, "key1", duplicate.GroupID)
+	})
+
+	t.Run("In progress", func(t *testing.T) {
+		reservations, _, reserver := newRepository(t)
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"key2", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+		reserver.EXPECT().Get(mock.Anything, idempotency.KeyPrefix+"key2").Return(redis.NewStringResult("in_progress", nil)).Once()
+
+		_, err := reservations.Transfer(ctx, request, "key2")
+		require.ErrorIs(t, err, api.ErrTransferInProgress)
+	})
+
+	t.Run("Not posted", func(t *testing.T) {
+		reservations, repo, reserver := newRepository(t)
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"key3", mock.Anything, time.Minute).Return(redis.NewBoolResult(true, nil)).Once()
+		repo.EXPECT().Transfer(mock.Anything, request, "key3").Return(nil, api.ErrTransferUnderReview).Once()
+		reserver.EXPECT().Del(mock.Anything, idempotency.KeyPrefix+"key3").Return(redis.NewIntResult(1, nil)).Once()
+
+		_, err := reservations.Transfer(ctx, request, "key3")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview, "released so the retries are still reported as held")
+	})
+
+	t.Run("Redis unavailable", func(t *testing.T) {
+		reservations, repo, reserver := newRepository(t)
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"key4", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, errors.New("connection refused"))).Once()
+		repo.EXPECT().Transfer(mock.Anything, request, "key4").Return(nil, api.ErrDuplicateTransaction).Once()
+
+		_, err := reservations.Transfer(ctx, request, "key4")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "Postgres still rejects the duplicates")
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		reservations, _, reserver := newRepository(t)
+
+		calls := 0
+		batches := reservations.Batches(batchFunc(func(context.Context, []*api.TransferRequest, string) (*api.TransferBatchReceipt, error) {
+			calls++
+
+			return &api.TransferBatchReceipt{IdempotencyKey: "payroll"}, nil
+		}))
+
+		key := idempotency.KeyPrefix + api.BatchGroupID("payroll", 0)
+
+		reserver.EXPECT().SetNX(mock.Anything, key, mock.Anything, time.Minute).Return(redis.NewBoolResult(true, nil)).Once()
+		reserver.EXPECT().Set(mock.Anything, key, mock.Anything, ttl).Return(redis.NewStatusResult("OK", nil)).Once()
+
+		receipt, err := batches.TransferBatch(ctx, []*api.TransferRequest{request}, "payroll")
+		require.NoError(t, err)
+		require.Equal(t, "payroll", receipt.IdempotencyKey)
+
+		reserver.EXPECT().SetNX(mock.Anything, key, mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+		reserver.EXPECT().Get(mock.Anything, key).Return(redis.NewStringResult("posted", nil)).Once()
+
+		_, err = batches.TransferBatch(ctx, []*api.TransferRequest{request}, "payroll")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
+		require.Equal(t, 1, calls, "rejected without reaching Postgres")
+	})
+
+	t.Run("Hold", func(t *testing.T) {
+		reservations, _, reserver := newRepository(t)
+
+		holds := reservations.Holds(&stubHolds{})
+
+		reserver.EXPECT().SetNX(mock.Anything, idempotency.KeyPrefix+"card-1", mock.Anything, time.Minute).Return(redis.NewBoolResult(false, nil)).Once()
+		reserver.EXPECT().Get(mock.Anything, idempotency.KeyPrefix+"card-1").Return(redis.NewStringResult("in_progress", nil)).Once()
+
+		_, err := holds.PlaceHold(ctx, request, "card-1")
+		require.ErrorIs(t, err, api.ErrTransferInProgress)
+
+		hold, err := holds.GetHold(ctx, "hold1")
+		require.NoError(t, err)
+		require.Equal(t, "hold1", hold.ID, "passed through")
+	})
+}
+
+type batchFunc func(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error)
+
+func (f batchFunc) TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	return f(ctx, requests, idempotencyKey)
+}
+
+// stubHolds only gets the holds, placing one would panic.
+type stubHolds struct {
+	idempotency.Holds
+}
+
+func (s *stubHolds) GetHold(_ context.Context, id string) (*api.Hold, error) {
+	return &api.Hold{ID: id}, nil
+}
@@ -0,0 +1,176 @@
+// Package ledgerexport streams the ledger as an ordered event log, i.e. for the audits and the disaster recovery,
+// and restores the event log into a database, rebuilding the account balances from the ledger entries.
+package ledgerexport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+// DefaultBatchSize is the number of events restored per database transaction.
+const DefaultBatchSize = 1000
+
+// Store is implemented by repository.PostgresRepository.
+type Store interface {
+	ExportLedger(ctx context.Context, filter api.LedgerFilter, emit func(event *api.Event) error) error
+	SnapshotBalances(ctx context.Context, at time.Time, emit func(account *api.Account) error) error
+	RestoreLedger(ctx context.Context, events []*api.Event) (int, error)
+	RebuildBalances(ctx context.Context) (int64, error)
+	VerifyLedger(ctx context.Context) ([]*api.LedgerDiscrepancy, error)
+}
+
+type Exporter struct {
+	store     Store
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewExporter(store Store) *Exporter {
+	return &Exporter{
+		store:     store,
+		batchSize: DefaultBatchSize,
+		logger:    slog.Default(),
+	}
+}
+
+// WithBatchSize sets the number of events restored per database transaction.
+func (e *Exporter) WithBatchSize(size int) *Exporter {
+	e.batchSize = size
+
+	return e
+}
+
+func (e *Exporter) WithLogger(logger *slog.Logger) *Exporter {
+	e.logger = logger
+
+	return e
+}
+
+// Export writes the transfers selected by the filter to w as transfer.created events, one JSON object per line,
+// in the order they were posted. It returns the number of events written.
+func (e *Exporter) Export(ctx context.Context, filter api.LedgerFilter, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+
+	err := e.store.ExportLedger(ctx, filter, func(event *api.Event) error {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to write event %s: %w", event.ID, err)
+		}
+
+		count++
+
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export ledger: %w", err)
+	}
+
+	return count, nil
+}
+
+// Snapshot writes the balance of every account at the point in time to w, one JSON object per line.
+// It returns the number of accounts written.
+func (e *Exporter) Snapshot(ctx context.Context, at time.Time, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+
+	err := e.store.SnapshotBalances(ctx, at, func(account *api.Account) error {
+		if err := encoder.Encode(account); err != nil {
+			return fmt.Errorf("failed to write account %s: %w", account.AccountID, err)
+		}
+
+		count++
+
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to snapshot balances: %w", err)
+	}
+
+	return count, nil
+}
+
+// RestoreResult is the outcome of a restore.
+type RestoreResult struct {
+	Events int `json:"events"`
+	// Entries is the number of ledger entries written, the ones that already existed are skipped.
+	Entries int `json:"entries"`
+	// RebuiltAccounts is the number of accounts whose balance was set from their ledger entries.
+	RebuiltAccounts int64 `json:"rebuilt_accounts"`
+	// Discrepancies are the accounts still inconsistent after the rebuild, it's empty on success.
+	Discrepancies []*api.LedgerDiscrepancy `json:"discrepancies"`
+}
+
+// Restore writes the events read from r, as written by Export, in batches,
+// then rebuilds the balances of the accounts from their ledger entries and verifies the ledger.
+// The restore can be run again after a failure, the entries already restored are skipped.
+func (e *Exporter) Restore(ctx context.Context, r io.Reader) (*RestoreResult, error) {
+	decoder := json.NewDecoder(r)
+	result := &RestoreResult{}
+	batch := make([]*api.Event, 0, e.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		entries, err := e.store.RestoreLedger(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to restore events %d to %d: %w", result.Events-len(batch)+1, result.Events, err)
+		}
+
+		result.Entries += entries
+		batch = batch[:0]
+
+		e.logger.InfoContext(ctx, "events restored", slog.Int("events", result.Events), slog.Int("entries", result.Entries))
+
+		return nil
+	}
+
+	for {
+		event := &api.Event{}
+
+		err := decoder.Decode(event)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", api.ErrInvalidEvent, result.Events+1, err)
+		}
+
+		batch = append(batch, event)
+		result.Events++
+
+		if len(batch) >= e.batchSize {
+			if err = flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	rebuilt, err := e.store.RebuildBalances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild balances: %w", err)
+	}
+
+	result.RebuiltAccounts = rebuilt
+
+	result.Discrepancies, err = e.store.VerifyLedger(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package ledgerexport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/ledgerexport"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore exports its events, and keeps the restored batches.
+type memoryStore struct {
+	events   []*api.Event
+	batches  [][]*api.Event
+	rebuilt  int64
+	restored bool
+}
+
+func (s *memoryStore) ExportLedger(_ context.Context, filter api.LedgerFilter, emit func(event *api.Event) error) error {
+	for _, event := range s.events {
+		if !filter.Until.IsZero() && event.Time.After(filter.Until) {
+			continue
+		}
+
+		if err := emit(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) SnapshotBalances(_ context.Context, _ time.Time, emit func(account *api.Account) error) error {
+	return emit(&api.Account{AccountID: "user1", Currency: "USD", Balance: decimal.NewFromInt(10)})
+}
+
+func (s *memoryStore) RestoreLedger(_ context.Context, events []*api.Event) (int, error) {
+	s.batches = append(s.batches, append([]*api.Event{}, events...))
+
+	return 2 * len(events), nil
+}
+
+func (s *memoryStore) RebuildBalances(context.Context) (int64, error) {
+	s.restored = true
+
+	return s.rebuilt, nil
+}
+
+func (s *memoryStore) VerifyLedger(context.Context) ([]*api.LedgerDiscrepancy, error) {
+	return []*api.LedgerDiscrepancy{}, nil
+}
+
+func transferEvent(t *testing.T, id string, at time.Time) *api.Event {
+	t.Helper()
+
+	data, err := json.Marshal(api.TransferCreated{TransferID: id, FromAccountID: "company", ToAccountID: "user1", Currency: "USD", Amount: decimal.NewFromInt(5)})
+	require.NoError(t, err)
+
+	return &api.Event{ID: id, Type: api.EventTransferCreated, Version: api.TransferCreatedVersion, Key: "company", Time: at, Data: data}
+}
+
+func TestExportAndRestore(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	source := &memoryStore{events: []*api.Event{
+		transferEvent(t, "first", start),
+		transferEvent(t, "second", start.Add(time.Hour)),
+		transferEvent(t, "third", start.Add(2*time.Hour)),
+	}}
+
+	exported := &bytes.Buffer{}
+
+	count, err := ledgerexport.NewExporter(source).Export(ctx, api.LedgerFilter{}, exported)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+	require.Equal(t, 3, strings.Count(exported.String(), "\n"), "one event per line")
+
+	t.Run("Point in time", func(t *testing.T) {
+		count, err := ledgerexport.NewExporter(source).Export(ctx, api.LedgerFilter{Until: start.Add(time.Hour)}, &bytes.Buffer{})
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("Restore in batches", func(t *testing.T) {
+		target := &memoryStore{rebuilt: 2}
+
+		result, err := ledgerexport.NewExporter(target).
+			WithBatchSize(2).
+			WithLogger(logging.Discard()).
+			Restore(ctx, bytes.NewReader(exported.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Events)
+		require.Equal(t, 6, result.Entries)
+		require.Equal(t, int64(2), result.RebuiltAccounts)
+		require.Empty(t, result.Discrepancies)
+
+		require.Len(t, target.batches, 2)
+		require.Len(t, target.batches[0], 2)
+		require.Equal(t, "third", target.batches[1][0].ID, "the order is kept")
+		require.True(t, target.restored)
+	})
+
+	t.Run("Invalid line", func(t *testing.T) {
+		target := &memoryStore{}
+
+		_, err := ledgerexport.NewExporter(target).
+			WithLogger(logging.Discard()).
+			Restore(ctx, strings.NewReader(exported.String()+"{not json\n"))
+		require.ErrorIs(t, err, api.ErrInvalidEvent)
+		require.False(t, target.restored, "the balances aren't rebuilt from a partial log")
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		snapshot := &bytes.Buffer{}
+
+		count, err := ledgerexport.NewExporter(source).Snapshot(ctx, start, snapshot)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		require.JSONEq(t, `{"account":"user1","currency":"USD","balance":"10"}`, snapshot.String())
+	})
+}
+
+func TestLocations(t *testing.T) {
+	ctx := context.Background()
+	location := filepath.Join(t.TempDir(), "ledger.jsonl")
+
+	t.Run("File", func(t *testing.T) {
+		writer, err := ledgerexport.Create(ctx, location, nil)
+		require.NoError(t, err)
+
+		_, err = writer.Write([]byte("{}\n"))
+		require.NoError(t, err)
+
+		_, err = os.Stat(location)
+		require.ErrorIs(t, err, os.ErrNotExist, "the file is only written once complete")
+
+		require.NoError(t, writer.Close())
+
+		reader, err := ledgerexport.Open(ctx, location, nil)
+		require.NoError(t, err)
+
+		defer reader.Close()
+
+		content, err := os.ReadFile(location)
+		require.NoError(t, err)
+		require.Equal(t, "{}\n", string(content))
+	})
+
+	t.Run("Aborted file", func(t *testing.T) {
+		writer, err := ledgerexport.Create(ctx, location, nil)
+		require.NoError(t, err)
+
+		_, err = writer.Write([]byte("partial"))
+		require.NoError(t, err)
+
+		writer.Abort(errors.New("export failed"))
+
+		content, err := os.ReadFile(location)
+		require.NoError(t, err)
+		require.Equal(t, "{}\n", string(content), "the previous export is kept")
+
+		_, err = os.Stat(location + ".partial")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("Stdio", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+
+		writer, err := ledgerexport.Create(ctx, ledgerexport.Stdio, stdout)
+		require.NoError(t, err)
+
+		_, err = writer.Write([]byte("{}\n"))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		require.Equal(t, "{}\n", stdout.String())
+	})
+
+	t.Run("Invalid S3 location", func(t *testing.T) {
+		_, err := ledgerexport.Create(ctx, "s3://bucket-only", nil)
+		require.ErrorIs(t, err, ledgerexport.ErrInvalidLocation)
+
+		_, err = ledgerexport.Open(ctx, "s3:///key", nil)
+		require.ErrorIs(t, err, ledgerexport.ErrInvalidLocation)
+	})
+}
@@ -0,0 +1,199 @@
+package ledgerexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var ErrInvalidLocation = errors.New("invalid location")
+
+const (
+	// Stdio is the location of the standard output or input.
+	Stdio = "-"
+
+	s3Scheme = "s3://"
+
+	// the files are written next to their location, then renamed once complete
+	partialSuffix = ".partial"
+)
+
+// Writer is an export being written to its location. It's only complete once closed,
+// an aborted export never replaces the file or the object at the location.
+type Writer interface {
+	io.Writer
+	Close() error
+	Abort(err error)
+}
+
+// Create opens the location for writing: Stdio for stdout, s3://bucket/key for an S3 object, otherwise a file path.
+// The S3 credentials and region are read from the usual AWS environment variables and files.
+func Create(ctx context.Context, location string, stdout io.Writer) (Writer, error) {
+	switch {
+	case location == Stdio:
+		return &stdioWriter{Writer: stdout}, nil
+	case strings.HasPrefix(location, s3Scheme):
+		return createObject(ctx, location)
+	default:
+		return createFile(location)
+	}
+}
+
+// Open opens the location for reading, see Create.
+func Open(ctx context.Context, location string, stdin io.Reader) (io.ReadCloser, error) {
+	switch {
+	case location == Stdio:
+		return io.NopCloser(stdin), nil
+	case strings.HasPrefix(location, s3Scheme):
+		return openObject(ctx, location)
+	default:
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", location, err)
+		}
+
+		return file, nil
+	}
+}
+
+type stdioWriter struct {
+	io.Writer
+}
+
+func (w *stdioWriter) Close() error {
+	return nil
+}
+
+func (w *stdioWriter) Abort(error) {}
+
+type fileWriter struct {
+	*os.File
+	location string
+}
+
+func createFile(location string) (*fileWriter, error) {
+	file, err := os.Create(location + partialSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", location, err)
+	}
+
+	return &fileWriter{File: file, location: location}, nil
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.location, err)
+	}
+
+	if err := os.Rename(w.File.Name(), w.location); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.location, err)
+	}
+
+	return nil
+}
+
+func (w *fileWriter) Abort(error) {
+	_ = w.File.Close()
+	_ = os.Remove(w.File.Name())
+}
+
+// objectWriter streams the export to an S3 multipart upload, so it's never held in memory nor on disk.
+type objectWriter struct {
+	*io.PipeWriter
+	location string
+	done     chan error
+}
+
+func createObject(ctx context.Context, location string) (*objectWriter, error) {
+	bucket, key, err := parseObject(location)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+
+	w := &objectWriter{PipeWriter: writer, location: location, done: make(chan error, 1)}
+
+	go func() {
+		// the upload is aborted if the pipe is closed with an error
+		_, err := manager.NewUploader(client).Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   reader,
+		})
+
+		_ = reader.CloseWithError(err)
+		w.done <- err
+	}()
+
+	return w, nil
+}
+
+func (w *objectWriter) Close() error {
+	_ = w.PipeWriter.Close()
+
+	if err := <-w.done; err != nil {
+		return fmt.Errorf("failed to upload %s: %w", w.location, err)
+	}
+
+	return nil
+}
+
+func (w *objectWriter) Abort(err error) {
+	_ = w.PipeWriter.CloseWithError(err)
+	<-w.done
+}
+
+func openObject(ctx context.Context, location string) (io.ReadCloser, error) {
+	bucket, key, err := parseObject(location)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newS3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", location, err)
+	}
+
+	return object.Body, nil
+}
+
+func newS3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the aws config: %w", err)
+	}
+
+	return s3.NewFromConfig(cfg), nil
+}
+
+// parseObject splits s3://bucket/key.
+func parseObject(location string) (string, string, error) {
+	bucket, key, found := strings.Cut(strings.TrimPrefix(location, s3Scheme), "/")
+	if !found || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("%w: %s, expected s3://bucket/key", ErrInvalidLocation, location)
+	}
+
+	return bucket, key, nil
+}
@@ -0,0 +1,301 @@
+// Package ledgerimport migrates the wallets of a legacy ledger, i.e. the opening balances and the history of
+// the transactions, posting them as double entries through the restore of the ledger, then verifying its integrity.
+package ledgerimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultBatchSize is the number of records imported per database transaction.
+const DefaultBatchSize = 1000
+
+// namespace derives the ids of the ledger entries from the ids of the records.
+//
+//nolint:gochecknoglobals // constant
+var namespace = uuid.NewSHA1(uuid.NameSpaceOID, []byte("wallet.ledgerimport"))
+
+// Store is implemented by repository.PostgresRepository.
+type Store interface {
+	RestoreLedger(ctx context.Context, events []*api.Event) (int, error)
+	RebuildBalances(ctx context.Context) (int64, error)
+	VerifyLedger(ctx context.Context) ([]*api.LedgerDiscrepancy, error)
+	SnapshotBalances(ctx context.Context, at time.Time, emit func(account *api.Account) error) error
+}
+
+type Importer struct {
+	store     Store
+	batchSize int
+	dryRun    bool
+	logger    *slog.Logger
+	// companyAccountID is the counterparty of the deposits, withdrawals and opening balances
+	companyAccountID string
+}
+
+func NewImporter(store Store) *Importer {
+	return &Importer{
+		store:     store,
+		batchSize: DefaultBatchSize,
+		logger:    slog.Default(),
+
+		companyAccountID: api.CompanyAccountID,
+	}
+}
+
+// WithBatchSize sets the number of records imported per database transaction.
+func (i *Importer) WithBatchSize(size int) *Importer {
+	i.batchSize = size
+
+	return i
+}
+
+// WithDryRun only validates the records, nothing is written.
+func (i *Importer) WithDryRun(dryRun bool) *Importer {
+	i.dryRun = dryRun
+
+	return i
+}
+
+// WithCompanyAccount overrides the counterparty of the deposits, withdrawals and opening balances,
+// api.CompanyAccountID by default. It must be the company account of the wallet.
+func (i *Importer) WithCompanyAccount(accountID string) *Importer {
+	i.companyAccountID = accountID
+
+	return i
+}
+
+func (i *Importer) WithLogger(logger *slog.Logger) *Importer {
+	i.logger = logger
+
+	return i
+}
+
+// BalanceMismatch is an account whose balance doesn't match the closing balance of the legacy ledger.
+type BalanceMismatch struct {
+	RecordID  string          `json:"record_id"`
+	AccountID string          `json:"account_id"`
+	Currency  string          `json:"currency"`
+	At        time.Time       `json:"at"`
+	Expected  decimal.Decimal `json:"expected"`
+	Actual    decimal.Decimal `json:"actual"`
+}
+
+// Result is the outcome of an import.
+type Result struct {
+	Records int `json:"records"`
+	// Entries is the number of ledger entries written, the ones already imported are skipped.
+	Entries int `json:"entries"`
+	// RebuiltAccounts is the number of accounts whose balance was set from their ledger entries.
+	RebuiltAccounts int64 `json:"rebuilt_accounts"`
+	// Discrepancies are the accounts whose balance doesn't match their ledger entries, it's empty on success.
+	Discrepancies []*api.LedgerDiscrepancy `json:"discrepancies"`
+	// Mismatches are the closing balances not matched by the imported ledger, it's empty on success.
+	Mismatches []*BalanceMismatch `json:"mismatches"`
+}
+
+// Import posts the records read from r in batches, then rebuilds the balances of the accounts from their ledger entries,
+// verifies the ledger, and checks the closing balances of the records.
+// The import can be run again after a failure, the records already imported are skipped.
+func (i *Importer) Import(ctx context.Context, format Format, r io.Reader) (*Result, error) {
+	next, err := newRecordReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	seen := map[string]struct{}{}
+	closing := []*Record{}
+	batch := make([]*api.Event, 0, i.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if !i.dryRun {
+			entries, err := i.store.RestoreLedger(ctx, batch)
+			if err != nil {
+				return fmt.Errorf("failed to import records %d to %d: %w", result.Records-len(batch)+1, result.Records, err)
+			}
+
+			result.Entries += entries
+
+			i.logger.InfoContext(ctx, "records imported", slog.Int("records", result.Records), slog.Int("entries", result.Entries))
+		}
+
+		batch = batch[:0]
+
+		return nil
+	}
+
+	for {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[record.ID]; ok {
+			return nil, fmt.Errorf("%w: record %s is duplicated", ErrInvalidRecord, record.ID)
+		}
+
+		seen[record.ID] = struct{}{}
+		result.Records++
+
+		if strings.EqualFold(record.AccountID, i.companyAccountID) {
+			return nil, fmt.Errorf("%w: record %s: the company account is the counterparty of the deposits and withdrawals", ErrInvalidRecord, record.ID)
+		}
+
+		if record.Kind == KindClosingBalance {
+			closing = append(closing, record)
+
+			continue
+		}
+
+		event, err := i.recordEvent(record)
+		if err != nil {
+			return nil, err
+		}
+
+		batch = append(batch, event)
+
+		if len(batch) >= i.batchSize {
+			if err = flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = flush(); err != nil {
+		return nil, err
+	}
+
+	if i.dryRun {
+		return result, nil
+	}
+
+	if err = i.verify(ctx, result, closing); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// verify rebuilds the balances, then checks them against the ledger entries and the closing balances.
+func (i *Importer) verify(ctx context.Context, result *Result, closing []*Record) error {
+	rebuilt, err := i.store.RebuildBalances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild balances: %w", err)
+	}
+
+	result.RebuiltAccounts = rebuilt
+
+	result.Discrepancies, err = i.store.VerifyLedger(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	result.Mismatches = []*BalanceMismatch{}
+
+	// the balances are snapshotted once per point in time
+	snapshots := map[time.Time]map[string]decimal.Decimal{}
+
+	for _, record := range closing {
+		balances, ok := snapshots[record.Time]
+		if !ok {
+			balances = map[string]decimal.Decimal{}
+
+			err = i.store.SnapshotBalances(ctx, record.Time, func(account *api.Account) error {
+				balances[account.Currency+"/"+account.AccountID] = account.Balance
+
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to snapshot balances: %w", err)
+			}
+
+			snapshots[record.Time] = balances
+		}
+
+		// an account without entries has a zero balance
+		actual := balances[record.Currency+"/"+record.AccountID]
+
+		if !actual.Equal(record.Amount) {
+			result.Mismatches = append(result.Mismatches, &BalanceMismatch{
+				RecordID:  record.ID,
+				AccountID: record.AccountID,
+				Currency:  record.Currency,
+				At:        record.Time,
+				Expected:  record.Amount,
+				Actual:    actual,
+			})
+		}
+	}
+
+	return nil
+}
+
+// recordEvent returns the event restoring the record, a transfer with the company account for the deposits,
+// withdrawals and opening balances, or the creation of the account for a zero opening balance.
+func (i *Importer) recordEvent(record *Record) (*api.Event, error) {
+	if record.Kind == KindOpeningBalance && record.Amount.IsZero() {
+		return newEvent(uuid.NewSHA1(namespace, []byte(record.ID)).String(), api.EventAccountCreated, api.AccountCreatedVersion,
+			record.AccountID, record.Time, api.AccountCreated{AccountID: record.AccountID, Currency: record.Currency})
+	}
+
+	from, to := record.AccountID, record.ToAccountID
+
+	switch record.Kind {
+	case KindOpeningBalance:
+		from, to = i.companyAccountID, record.AccountID
+		if record.Amount.IsNegative() {
+			from, to = record.AccountID, i.companyAccountID
+		}
+	case KindDeposit:
+		from, to = i.companyAccountID, record.AccountID
+	case KindWithdrawal:
+		to = i.companyAccountID
+	}
+
+	transfer := api.TransferCreated{
+		TransferID:    uuid.NewSHA1(namespace, []byte(record.ID)).String(),
+		DebitTxID:     uuid.NewSHA1(namespace, []byte(record.ID+"/debit")).String(),
+		CreditTxID:    uuid.NewSHA1(namespace, []byte(record.ID+"/credit")).String(),
+		FromAccountID: from,
+		ToAccountID:   to,
+		Currency:      record.Currency,
+		Amount:        record.Amount.Abs(),
+		Remarks:       record.Remarks,
+	}
+
+	return newEvent(transfer.DebitTxID, api.EventTransferCreated, api.TransferCreatedVersion, from, record.Time, transfer)
+}
+
+func newEvent(id, eventType string, version int, key string, at time.Time, data any) (*api.Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	return &api.Event{
+		ID:      id,
+		Type:    eventType,
+		Version: version,
+		Key:     key,
+		Time:    at,
+		Data:    payload,
+	}, nil
+}
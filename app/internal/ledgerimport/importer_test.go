@@ -0,0 +1,199 @@
+package ledgerimport_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/ledgerimport"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore posts the restored transfers to in-memory balances, skipping the entries already posted.
+type memoryStore struct {
+	batches [][]*api.Event
+	posted  map[string]bool
+	entries []*api.TransferCreated
+	times   []time.Time
+	rebuilt bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{posted: map[string]bool{}}
+}
+
+func (s *memoryStore) RestoreLedger(_ context.Context, events []*api.Event) (int, error) {
+	s.batches = append(s.batches, append([]*api.Event{}, events...))
+	restored := 0
+
+	for _, event := range events {
+		if event.Type != api.EventTransferCreated || s.posted[event.ID] {
+			continue
+		}
+
+		transfer := &api.TransferCreated{}
+		if err := json.Unmarshal(event.Data, transfer); err != nil {
+			return 0, err
+		}
+
+		s.posted[event.ID] = true
+		s.entries = append(s.entries, transfer)
+		s.times = append(s.times, event.Time)
+		restored += 2
+	}
+
+	return restored, nil
+}
+
+func (s *memoryStore) RebuildBalances(context.Context) (int64, error) {
+	s.rebuilt = true
+
+	return 0, nil
+}
+
+func (s *memoryStore) VerifyLedger(context.Context) ([]*api.LedgerDiscrepancy, error) {
+	return []*api.LedgerDiscrepancy{}, nil
+}
+
+func (s *memoryStore) SnapshotBalances(_ context.Context, at time.Time, emit func(account *api.Account) error) error {
+	balances := map[string]decimal.Decimal{}
+
+	for i, transfer := range s.entries {
+		if s.times[i].After(at) {
+			continue
+		}
+
+		balances[transfer.FromAccountID] = balances[transfer.FromAccountID].Sub(transfer.Amount)
+		balances[transfer.ToAccountID] = balances[transfer.ToAccountID].Add(transfer.Amount)
+	}
+
+	for accountID, balance := range balances {
+		if err := emit(&api.Account{AccountID: accountID, Currency: "USD", Balance: balance}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const legacyCSV = `id,kind,account_id,to_account_id,currency,amount,remarks,time
+LEG-1,OPENING_BALANCE,user1,,usd,100.00,,2024-01-01
+LEG-2,OPENING_BALANCE,user2,,USD,-20,overdraft,2024-01-01
+LEG-3,opening_balance,user3,,USD,0,,2024-01-01
+LEG-4,TRANSFER,user1,user2,USD,25.50,rent,2024-01-02T10:00:00Z
+LEG-5,WITHDRAWAL,user1,,USD,4.50,,2024-01-03
+LEG-6,CLOSING_BALANCE,user1,,USD,70,,2024-01-31
+LEG-7,CLOSING_BALANCE,user2,,USD,5.5,,2024-01-31
+`
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CSV", func(t *testing.T) {
+		store := newMemoryStore()
+
+		result, err := ledgerimport.NewImporter(store).
+			WithBatchSize(2).
+			WithLogger(logging.Discard()).
+			Import(ctx, ledgerimport.FormatCSV, strings.NewReader(legacyCSV))
+		require.NoError(t, err)
+		require.Equal(t, 7, result.Records)
+		require.Equal(t, 8, result.Entries, "the zero opening balance only creates the account")
+		require.Empty(t, result.Discrepancies)
+		require.Empty(t, result.Mismatches)
+		require.True(t, store.rebuilt)
+		require.Len(t, store.batches, 3)
+		require.Equal(t, api.EventAccountCreated, store.batches[1][0].Type)
+
+		overdraft := store.entries[1]
+		require.Equal(t, "user2", overdraft.FromAccountID, "a negative opening balance debits the account")
+		require.Equal(t, api.CompanyAccountID, overdraft.ToAccountID)
+		require.True(t, decimal.NewFromInt(20).Equal(overdraft.Amount))
+
+		again, err := ledgerimport.NewImporter(store).
+			WithLogger(logging.Discard()).
+			Import(ctx, ledgerimport.FormatCSV, strings.NewReader(legacyCSV))
+		require.NoError(t, err)
+		require.Zero(t, again.Entries, "the records already imported are skipped")
+		require.Equal(t, store.batches[0][0].ID, store.batches[3][0].ID, "the ids are derived from the records")
+	})
+
+	t.Run("JSONL", func(t *testing.T) {
+		store := newMemoryStore()
+
+		result, err := ledgerimport.NewImporter(store).
+			WithLogger(logging.Discard()).
+			Import(ctx, ledgerimport.FormatJSONL, strings.NewReader(`
+{"id":"1","kind":"DEPOSIT","account_id":"user1","currency":"USD","amount":"10","time":"2024-01-01T00:00:00Z"}
+{"id":"2","kind":"CLOSING_BALANCE","account_id":"user1","currency":"USD","amount":"12","time":"2024-01-31T00:00:00Z"}
+`))
+		require.NoError(t, err)
+		require.Len(t, result.Mismatches, 1)
+		require.Equal(t, "2", result.Mismatches[0].RecordID)
+		require.True(t, decimal.NewFromInt(10).Equal(result.Mismatches[0].Actual))
+	})
+
+	t.Run("Configured company account", func(t *testing.T) {
+		store := newMemoryStore()
+
+		_, err := ledgerimport.NewImporter(store).
+			WithCompanyAccount("settlement").
+			WithLogger(logging.Discard()).
+			Import(ctx, ledgerimport.FormatCSV, strings.NewReader("id,kind,account_id,currency,amount,time\n1,DEPOSIT,user1,USD,1,2024-01-01\n"))
+		require.NoError(t, err)
+		require.Equal(t, "settlement", store.entries[0].FromAccountID)
+
+		_, err = ledgerimport.NewImporter(newMemoryStore()).
+			WithCompanyAccount("settlement").
+			WithLogger(logging.Discard()).
+			Import(ctx, ledgerimport.FormatCSV, strings.NewReader("id,kind,account_id,currency,amount,time\n1,DEPOSIT,settlement,USD,1,2024-01-01\n"))
+		require.ErrorIs(t, err, ledgerimport.ErrInvalidRecord)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		store := newMemoryStore()
+
+		result, err := ledgerimport.NewImporter(store).
+			WithDryRun(true).
+			Import(ctx, ledgerimport.FormatCSV, strings.NewReader(legacyCSV))
+		require.NoError(t, err)
+		require.Equal(t, 7, result.Records)
+		require.Empty(t, store.batches)
+		require.False(t, store.rebuilt)
+	})
+
+	t.Run("Invalid records", func(t *testing.T) {
+		for name, input := range map[string]string{
+			"duplicated id":     "id,kind,account_id,currency,amount,time\n1,DEPOSIT,user1,USD,1,2024-01-01\n1,DEPOSIT,user1,USD,1,2024-01-01\n",
+			"missing column":    "id,kind,account_id,currency,amount\n1,DEPOSIT,user1,USD,1\n",
+			"negative deposit":  "id,kind,account_id,currency,amount,time\n1,DEPOSIT,user1,USD,-1,2024-01-01\n",
+			"self transfer":     "id,kind,account_id,to_account_id,currency,amount,time\n1,TRANSFER,user1,user1,USD,1,2024-01-01\n",
+			"unknown kind":      "id,kind,account_id,currency,amount,time\n1,REFUND,user1,USD,1,2024-01-01\n",
+			"company account":   "id,kind,account_id,currency,amount,time\n1,DEPOSIT,company,USD,1,2024-01-01\n",
+			"invalid time":      "id,kind,account_id,currency,amount,time\n1,DEPOSIT,user1,USD,1,yesterday\n",
+			"misplaced account": "id,kind,account_id,to_account_id,currency,amount,time\n1,DEPOSIT,user1,user2,USD,1,2024-01-01\n",
+		} {
+			store := newMemoryStore()
+
+			_, err := ledgerimport.NewImporter(store).
+				WithLogger(logging.Discard()).
+				Import(ctx, ledgerimport.FormatCSV, strings.NewReader(input))
+			require.ErrorIs(t, err, ledgerimport.ErrInvalidRecord, name)
+			require.False(t, store.rebuilt, name)
+		}
+	})
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ledgerimport.ParseFormat(" NDJSON ")
+	require.NoError(t, err)
+	require.Equal(t, ledgerimport.FormatJSONL, format)
+
+	_, err = ledgerimport.ParseFormat("xlsx")
+	require.ErrorIs(t, err, ledgerimport.ErrInvalidFormat)
+}
@@ -0,0 +1,264 @@
+package ledgerimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format is the format of the legacy ledger files.
+type Format string
+
+const (
+	// FormatJSONL is a Record per line.
+	FormatJSONL Format = "jsonl"
+	// FormatCSV is a CSV file with a header, see csvReader.
+	FormatCSV Format = "csv"
+)
+
+var (
+	ErrInvalidFormat = errors.New("invalid import format, must be one of jsonl, csv")
+	ErrInvalidRecord = errors.New("invalid import record")
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatJSONL, FormatCSV:
+		return format, nil
+	case "json", "ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidFormat, value)
+	}
+}
+
+// Kind is what a legacy record posts to the ledger.
+type Kind string
+
+const (
+	// KindOpeningBalance credits the account with its balance in the legacy ledger, debits it if negative.
+	KindOpeningBalance Kind = "OPENING_BALANCE"
+	// KindDeposit credits the account, from the company account.
+	KindDeposit Kind = "DEPOSIT"
+	// KindWithdrawal debits the account, to the company account.
+	KindWithdrawal Kind = "WITHDRAWAL"
+	// KindTransfer debits the account and credits the to account.
+	KindTransfer Kind = "TRANSFER"
+	// KindClosingBalance isn't posted, it's the balance the account must have at the time once imported.
+	KindClosingBalance Kind = "CLOSING_BALANCE"
+)
+
+// Record is a line of the legacy ledger.
+type Record struct {
+	// ID is the reference of the record in the legacy ledger, unique in the file.
+	// The ledger entries are derived from it, so importing the same record again is a no-op.
+	ID          string          `json:"id"`
+	Kind        Kind            `json:"kind"`
+	AccountID   string          `json:"account_id"`
+	ToAccountID string          `json:"to_account_id,omitempty"`
+	Currency    string          `json:"currency"`
+	Amount      decimal.Decimal `json:"amount"`
+	Remarks     string          `json:"remarks,omitempty"`
+	Time        time.Time       `json:"time"`
+}
+
+// validate normalizes the record, and checks it can be posted.
+func (r *Record) validate() error {
+	r.ID = strings.TrimSpace(r.ID)
+	r.Kind = Kind(strings.ToUpper(strings.TrimSpace(string(r.Kind))))
+	r.AccountID = strings.TrimSpace(r.AccountID)
+	r.ToAccountID = strings.TrimSpace(r.ToAccountID)
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	r.Time = r.Time.UTC()
+
+	switch {
+	case r.ID == "":
+		return errors.New("the id is required")
+	case r.AccountID == "" || r.Currency == "" || r.Time.IsZero():
+		return fmt.Errorf("record %s: the account_id, currency and time are required", r.ID)
+	case len(r.Remarks) > 255:
+		return fmt.Errorf("record %s: the remarks are longer than 255 characters", r.ID)
+	}
+
+	switch r.Kind {
+	case KindOpeningBalance, KindClosingBalance:
+	case KindDeposit, KindWithdrawal:
+		if !r.Amount.IsPositive() {
+			return fmt.Errorf("record %s: the amount must be positive", r.ID)
+		}
+	case KindTransfer:
+		if !r.Amount.IsPositive() {
+			return fmt.Errorf("record %s: the amount must be positive", r.ID)
+		}
+
+		if r.ToAccountID == "" || r.ToAccountID == r.AccountID {
+			return fmt.Errorf("record %s: the to_account_id is required, and must be another account", r.ID)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("record %s: invalid kind %q", r.ID, r.Kind)
+	}
+
+	if r.ToAccountID != "" {
+		return fmt.Errorf("record %s: the to_account_id is only for the transfers", r.ID)
+	}
+
+	return nil
+}
+
+// recordReader returns the next record, or io.EOF after the last one.
+type recordReader func() (*Record, error)
+
+func newRecordReader(format Format, r io.Reader) (recordReader, error) {
+	switch format {
+	case FormatJSONL:
+		return jsonlReader(r), nil
+	case FormatCSV:
+		return csvReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+}
+
+func jsonlReader(r io.Reader) recordReader {
+	decoder := json.NewDecoder(r)
+	line := 0
+
+	return func() (*Record, error) {
+		record := &Record{}
+
+		err := decoder.Decode(record)
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		line++
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRecord, line, err)
+		}
+
+		if err = record.validate(); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRecord, line, err)
+		}
+
+		return record, nil
+	}
+}
+
+// the columns of the CSV files, in any order. The to_account_id and remarks are optional.
+const (
+	csvID          = "id"
+	csvKind        = "kind"
+	csvAccountID   = "account_id"
+	csvToAccountID = "to_account_id"
+	csvCurrency    = "currency"
+	csvAmount      = "amount"
+	csvRemarks     = "remarks"
+	csvTime        = "time"
+)
+
+// csvReader reads a file with a header row naming the columns, i.e.
+//
+//	id,kind,account_id,to_account_id,currency,amount,remarks,time
+//	LEG-1,OPENING_BALANCE,user1,,USD,100.00,,2024-01-01
+//	LEG-2,TRANSFER,user1,user2,USD,25.50,rent,2024-01-02T10:00:00Z
+//
+// The times are RFC 3339, or dates at midnight UTC.
+func csvReader(r io.Reader) (recordReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidRecord, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// the byte order mark of the spreadsheet exports
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, required := range []string{csvID, csvKind, csvAccountID, csvCurrency, csvAmount, csvTime} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing the %s column", ErrInvalidRecord, required)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+
+		return ""
+	}
+
+	return func() (*Record, error) {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		record, err := csvRecord(fields, column)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRecord, line, err)
+		}
+
+		return record, nil
+	}, nil
+}
+
+func csvRecord(fields []string, column func(record []string, name string) string) (*Record, error) {
+	amount, err := decimal.NewFromString(column(fields, csvAmount))
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q", column(fields, csvAmount))
+	}
+
+	at, err := parseTime(column(fields, csvTime))
+	if err != nil {
+		return nil, err
+	}
+
+	record := &Record{
+		ID:          column(fields, csvID),
+		Kind:        Kind(column(fields, csvKind)),
+		AccountID:   column(fields, csvAccountID),
+		ToAccountID: column(fields, csvToAccountID),
+		Currency:    column(fields, csvCurrency),
+		Amount:      amount,
+		Remarks:     column(fields, csvRemarks),
+		Time:        at,
+	}
+
+	if err = record.validate(); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+
+	if at, err := time.Parse(time.DateOnly, value); err == nil {
+		return at, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
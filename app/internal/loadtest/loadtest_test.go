@@ -0,0 +1,114 @@
+package loadtest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/loadtest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestPercentile(t *testing.T) {
+	require.Zero(t, loadtest.Percentile(nil, 99))
+
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	require.Equal(t, 50*time.Millisecond, loadtest.Percentile(latencies, 50))
+	require.Equal(t, 99*time.Millisecond, loadtest.Percentile(latencies, 99))
+	require.Equal(t, 100*time.Millisecond, loadtest.Percentile(latencies, 100))
+	require.Equal(t, time.Millisecond, loadtest.Percentile(latencies, 0))
+}
+
+func TestMix(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	t.Run("Uncontended", func(t *testing.T) {
+		mix, err := loadtest.NewMix("run", 2, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, mix.Accounts(), 12)
+
+		for range 100 {
+			from, to := mix.Next(rng)
+			require.NotEqual(t, from, to)
+			require.Contains(t, mix.Cold, from)
+			require.Contains(t, mix.Cold, to)
+		}
+	})
+
+	t.Run("Fully contended", func(t *testing.T) {
+		mix, err := loadtest.NewMix("run", 2, 0, 1)
+		require.NoError(t, err)
+
+		for range 100 {
+			from, to := mix.Next(rng)
+			require.NotEqual(t, from, to)
+			require.Contains(t, []string{"run-hot-0", "run-hot-1"}, from)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := loadtest.NewMix("run", 2, 10, 1.5)
+		require.ErrorIs(t, err, loadtest.ErrInvalidMix)
+
+		_, err = loadtest.NewMix("run", 1, 10, 0.5)
+		require.ErrorIs(t, err, loadtest.ErrInvalidMix)
+
+		_, err = loadtest.NewMix("run", 2, 1, 0.5)
+		require.ErrorIs(t, err, loadtest.ErrInvalidMix)
+	})
+}
+
+func TestRun(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Records the outcomes", func(t *testing.T) {
+		var calls atomic.Int64
+
+		report := loadtest.Run(context.Background(), loadtest.Options{Duration: 100 * time.Millisecond, Concurrency: 4},
+			func(_ context.Context, _ *rand.Rand) (int, error) {
+				switch calls.Add(1) % 3 {
+				case 0:
+					return 0, errors.New("timeout")
+				case 1:
+					return http.StatusCreated, nil
+				default:
+					time.Sleep(time.Millisecond)
+
+					return http.StatusUnprocessableEntity, nil
+				}
+			})
+
+		require.Positive(t, report.Requests)
+		require.Positive(t, report.Statuses[http.StatusCreated])
+		require.Positive(t, report.Statuses[http.StatusUnprocessableEntity])
+		require.Positive(t, report.Errors)
+		require.Equal(t, report.Requests, report.Statuses[http.StatusCreated]+report.Statuses[http.StatusUnprocessableEntity]+report.Errors)
+		require.LessOrEqual(t, report.P50, report.P99)
+		require.LessOrEqual(t, report.P99, report.Max)
+
+		var out bytes.Buffer
+		report.Print(&out)
+		require.Contains(t, out.String(), "status 201")
+		require.Contains(t, out.String(), "p99")
+	})
+
+	t.Run("Rate limited", func(t *testing.T) {
+		report := loadtest.Run(context.Background(), loadtest.Options{Duration: 200 * time.Millisecond, Concurrency: 4, Rate: 50},
+			func(_ context.Context, _ *rand.Rand) (int, error) {
+				return http.StatusCreated, nil
+			})
+
+		// 10 ticks in 200ms, give or take the scheduling
+		require.LessOrEqual(t, report.Requests, 12)
+	})
+}
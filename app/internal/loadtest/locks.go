@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const selectLockWaiters = `SELECT count(1) FROM pg_stat_activity WHERE wait_event_type = 'Lock' AND datname = current_database()`
+
+// LockSampler counts the sessions waiting on a lock at a regular interval,
+// which shows how much the transfers queue on the row locks of the hot accounts.
+type LockSampler struct {
+	db       *sql.DB
+	interval time.Duration
+
+	mu    sync.Mutex
+	stats LockStats
+}
+
+type LockStats struct {
+	Samples int
+	// WithWaiters is the number of samples where at least one session was waiting.
+	WithWaiters int
+	MaxWaiters  int
+	sumWaiters  int
+	Failures    int
+}
+
+// AvgWaiters is the average number of sessions waiting on a lock.
+func (s LockStats) AvgWaiters() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+
+	return float64(s.sumWaiters) / float64(s.Samples)
+}
+
+func (s LockStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "lock waits    %d/%d samples  avg %.2f  max %d waiting sessions\n", s.WithWaiters, s.Samples, s.AvgWaiters(), s.MaxWaiters)
+
+	if s.Failures > 0 {
+		fmt.Fprintf(w, "lock sampling failed %d times\n", s.Failures)
+	}
+}
+
+func NewLockSampler(db *sql.DB, interval time.Duration) *LockSampler {
+	return &LockSampler{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Run samples until the context is canceled.
+func (s *LockSampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sample(ctx)
+		}
+	}
+}
+
+func (s *LockSampler) sample(ctx context.Context) {
+	var waiters int
+
+	err := s.db.QueryRowContext(ctx, selectLockWaiters).Scan(&waiters)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		// canceled at the end of the run
+		if ctx.Err() == nil {
+			s.stats.Failures++
+		}
+
+		return
+	}
+
+	s.stats.Samples++
+	s.stats.sumWaiters += waiters
+	s.stats.MaxWaiters = max(s.stats.MaxWaiters, waiters)
+
+	if waiters > 0 {
+		s.stats.WithWaiters++
+	}
+}
+
+func (s *LockSampler) Stats() LockStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
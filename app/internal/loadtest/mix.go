@@ -0,0 +1,65 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+var ErrInvalidMix = errors.New("invalid account mix")
+
+// Mix picks the accounts of each transfer. A share of the transfers, the contention, only moves money between
+// the few hot accounts, so they queue on the same row locks. The others are spread over the cold accounts.
+type Mix struct {
+	Hot        []string
+	Cold       []string
+	Contention float64
+}
+
+// NewMix names the accounts with the prefix, i.e. prefix-hot-0 and prefix-cold-0.
+func NewMix(prefix string, hot, cold int, contention float64) (Mix, error) {
+	if contention < 0 || contention > 1 {
+		return Mix{}, fmt.Errorf("%w: the contention must be between 0 and 1", ErrInvalidMix)
+	}
+
+	// a transfer needs two distinct accounts
+	if (contention > 0 && hot < 2) || (contention < 1 && cold < 2) {
+		return Mix{}, fmt.Errorf("%w: at least 2 hot and 2 cold accounts are required", ErrInvalidMix)
+	}
+
+	mix := Mix{
+		Hot:        make([]string, hot),
+		Cold:       make([]string, cold),
+		Contention: contention,
+	}
+
+	for i := range mix.Hot {
+		mix.Hot[i] = fmt.Sprintf("%s-hot-%d", prefix, i)
+	}
+
+	for i := range mix.Cold {
+		mix.Cold[i] = fmt.Sprintf("%s-cold-%d", prefix, i)
+	}
+
+	return mix, nil
+}
+
+// Accounts are all the accounts of the mix, to be funded before the run.
+func (m Mix) Accounts() []string {
+	return append(append([]string{}, m.Hot...), m.Cold...)
+}
+
+// Next returns the source and destination of the next transfer.
+func (m Mix) Next(rng *rand.Rand) (string, string) {
+	accounts := m.Cold
+	if rng.Float64() < m.Contention {
+		accounts = m.Hot
+	}
+
+	from := rng.IntN(len(accounts))
+
+	// any other account, without retrying
+	to := (from + 1 + rng.IntN(len(accounts)-1)) % len(accounts)
+
+	return accounts[from], accounts[to]
+}
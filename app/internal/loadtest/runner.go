@@ -0,0 +1,77 @@
+package loadtest
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Target sends one request and returns its status code, or an error if there was no response.
+type Target func(ctx context.Context, rng *rand.Rand) (int, error)
+
+type Options struct {
+	Duration    time.Duration
+	Concurrency int
+	// Rate caps the requests per second across the workers, 0 is as fast as possible.
+	Rate int
+}
+
+// Run sends requests to the target from the concurrent workers until the duration is elapsed or the context is canceled.
+func Run(ctx context.Context, options Options, target Target) Report {
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+
+	var tokens <-chan time.Time
+
+	if options.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(options.Rate))
+		defer ticker.Stop()
+
+		tokens = ticker.C
+	}
+
+	recorder := NewRecorder()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+
+	for worker := range options.Concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			//nolint:gosec // predictable is fine to pick the accounts
+			rng := rand.New(rand.NewPCG(uint64(start.UnixNano()), uint64(worker)))
+
+			for {
+				if tokens != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tokens:
+					}
+				}
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				sent := time.Now()
+				status, err := target(ctx, rng)
+
+				// the requests interrupted by the end of the run are not representative
+				if ctx.Err() != nil {
+					return
+				}
+
+				recorder.Record(time.Since(sent), status, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return recorder.Report(time.Since(start))
+}
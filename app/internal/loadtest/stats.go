@@ -0,0 +1,123 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Recorder collects the outcome of every request. It's safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	statuses  map[int]int
+	errors    int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		statuses: map[int]int{},
+	}
+}
+
+// Record adds a request that completed with the status, or failed without one with err.
+func (r *Recorder) Record(latency time.Duration, status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+
+	if err != nil {
+		r.errors++
+
+		return
+	}
+
+	r.statuses[status]++
+}
+
+// Report summarizes the recorded requests over the elapsed time.
+func (r *Recorder) Report(elapsed time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latencies := slices.Clone(r.latencies)
+	slices.Sort(latencies)
+
+	report := Report{
+		Requests: len(latencies),
+		Errors:   r.errors,
+		Statuses: make(map[int]int, len(r.statuses)),
+		Elapsed:  elapsed,
+		P50:      Percentile(latencies, 50),
+		P90:      Percentile(latencies, 90),
+		P99:      Percentile(latencies, 99),
+	}
+
+	if len(latencies) > 0 {
+		report.Max = latencies[len(latencies)-1]
+	}
+
+	for status, count := range r.statuses {
+		report.Statuses[status] = count
+	}
+
+	return report
+}
+
+type Report struct {
+	Requests int
+	// Errors are the requests that failed without a response, i.e. timeouts.
+	Errors   int
+	Statuses map[int]int
+	Elapsed  time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+// Throughput is the number of requests per second.
+func (r Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "requests      %d in %s (%.1f/s)\n", r.Requests, r.Elapsed.Round(time.Millisecond), r.Throughput())
+	fmt.Fprintf(w, "latency       p50 %s  p90 %s  p99 %s  max %s\n", r.P50, r.P90, r.P99, r.Max)
+
+	statuses := make([]int, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+
+	sort.Ints(statuses)
+
+	for _, status := range statuses {
+		fmt.Fprintf(w, "status %d    %d\n", status, r.Statuses[status])
+	}
+
+	if r.Errors > 0 {
+		fmt.Fprintf(w, "errors        %d\n", r.Errors)
+	}
+}
+
+// Percentile returns the nearest-rank percentile of the sorted latencies, 0 without any.
+func Percentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	rank = max(1, min(rank, len(sorted)))
+
+	return sorted[rank-1]
+}
@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/logging"
 	_ "github.com/lib/pq" // Or your database driver
 )
 
@@ -27,22 +29,32 @@ const (
 
 type Migrator struct {
 	db            *sql.DB
-	logger        *log.Logger
+	logger        *slog.Logger
 	migrationPath string
-	globFunc      GlobFunc // makes testing easier
+	// schema replaces the public schema the migrations are written for, if set
+	schema   string
+	globFunc GlobFunc // makes testing easier
 }
 
 func NewMigrator(db *sql.DB, migrationPath string) *Migrator {
 	return &Migrator{
 		db:            db,
-		logger:        log.Default(),
+		logger:        logging.Component(slog.Default(), "migration"),
 		migrationPath: migrationPath,
 		globFunc:      filepath.Glob,
 	}
 }
 
-func (m *Migrator) WithCustomLogger(logger *log.Logger) *Migrator {
-	m.logger = logger
+func (m *Migrator) WithCustomLogger(logger *slog.Logger) *Migrator {
+	m.logger = logging.Component(logger, "migration")
+
+	return m
+}
+
+// WithSchema applies the migrations to the schema instead of the public one, i.e. a sandbox.
+// The connections must search the schema, so the migrations table and the queries find its tables.
+func (m *Migrator) WithSchema(schema string) *Migrator {
+	m.schema = schema
 
 	return m
 }
@@ -59,7 +71,7 @@ func (m *Migrator) Up(ctx context.Context) error {
 
 	sort.Strings(files)
 
-	m.logger.Printf("Found %d migrations\n", len(files))
+	m.logger.InfoContext(ctx, "found migrations", slog.Int("count", len(files)))
 
 	for _, file := range files {
 		exists, err := m.exists(ctx, filepath.Base(file))
@@ -68,7 +80,7 @@ func (m *Migrator) Up(ctx context.Context) error {
 		}
 
 		if exists {
-			m.logger.Println("SKIP: Migration already applied:", filepath.Base(file))
+			m.logger.DebugContext(ctx, "migration already applied, skipping", slog.String("file", filepath.Base(file)))
 
 			continue
 		}
@@ -78,7 +90,7 @@ func (m *Migrator) Up(ctx context.Context) error {
 			return formatUnknownError(err)
 		}
 
-		m.logger.Printf("Migration applied: %s\n", filepath.Base(file))
+		m.logger.InfoContext(ctx, "migration applied", slog.String("file", filepath.Base(file)))
 	}
 
 	return nil
@@ -120,12 +132,18 @@ func (m *Migrator) applyMigration(ctx context.Context, file string) error {
 		return formatUnknownError(err)
 	}
 
+	statements := string(content)
+	if m.schema != "" {
+		// the migrations qualify their tables with the public schema
+		statements = strings.ReplaceAll(statements, `public."`, fmt.Sprintf(`"%s".`, m.schema))
+	}
+
 	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
 		return formatUnknownError(err)
 	}
 
-	_, err = tx.ExecContext(ctx, string(content))
+	_, err = tx.ExecContext(ctx, statements)
 	if err != nil {
 		_ = tx.Rollback()
 
@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
@@ -123,11 +123,40 @@ func TestMigrator(t *testing.T) {
 
 		defer cleanTestMigrations(t, db)
 
-		customLogger := log.New(os.Stdout, "TEST: ", log.Ldate|log.Ltime|log.Lshortfile)
+		customLogger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 		migrator.WithCustomLogger(customLogger)
 		err = migrator.Up(context.Background())
 		require.NoError(t, err)
 	})
+
+	// Test the migrations qualified with the public schema applied to another one
+	t.Run("Schema", func(t *testing.T) {
+		db := setupTestDB(t)
+		defer db.Close()
+
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "001_create_schema_table.up.sql"), []byte(`CREATE TABLE public."schema_table" (id INT);`), 0644)
+		require.NoError(t, err)
+
+		_, err = db.Exec("CREATE SCHEMA IF NOT EXISTS test_sandbox")
+		require.NoError(t, err)
+
+		defer func() {
+			_, err = db.Exec("DROP SCHEMA test_sandbox CASCADE")
+			require.NoError(t, err)
+
+			_, err = db.Exec("TRUNCATE TABLE migrations")
+			require.NoError(t, err)
+		}()
+
+		err = NewMigrator(db, dir).WithSchema("test_sandbox").Up(context.Background())
+		require.NoError(t, err)
+
+		var schema string
+		err = db.QueryRow("SELECT table_schema FROM information_schema.tables WHERE table_name = 'schema_table'").Scan(&schema)
+		require.NoError(t, err)
+		require.Equal(t, "test_sandbox", schema)
+	})
 }
 
 func TestMigratorErrors(t *testing.T) {
@@ -0,0 +1,107 @@
+// Package readonly switches the API to read-only, i.e. during the failovers and the ledger migrations,
+// for every server at once.
+package readonly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey holds the switch of the operators, set while the wallet is read-only.
+const DefaultRedisKey = "wallet:read_only"
+
+// Store is implemented by the Redis client.
+type Store interface {
+	Exists(ctx context.Context, keys ...string) *redis.IntCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Mode is read-only when forced by the setting of the server, or switched on by an operator in Redis,
+// so the switch applies to every server sharing the Redis.
+type Mode struct {
+	store  Store
+	key    string
+	forced bool
+	logger *slog.Logger
+}
+
+// New is read-only while the key is set in the store, or always if forced.
+func New(store Store, key string, forced bool) *Mode {
+	return &Mode{
+		store:  store,
+		key:    key,
+		forced: forced,
+		logger: slog.Default(),
+	}
+}
+
+func (m *Mode) WithLogger(logger *slog.Logger) *Mode {
+	m.logger = logger
+
+	return m
+}
+
+// ReadOnly reports whether the mutations are rejected. The switch of the operators is read on every call,
+// so it applies right away, and it's ignored while Redis is down, which shouldn't take the writes down with it.
+func (m *Mode) ReadOnly(ctx context.Context) bool {
+	if m.forced {
+		return true
+	}
+
+	enabled, err := m.switched(ctx)
+	if err != nil {
+		m.logger.WarnContext(ctx, "failed to read the read-only switch, writable", slog.Any("error", err))
+
+		return false
+	}
+
+	return enabled
+}
+
+// Status returns whether the wallet is read-only, and if it's forced.
+func (m *Mode) Status(ctx context.Context) (*api.ReadOnlyStatus, error) {
+	if m.forced {
+		return &api.ReadOnlyStatus{Enabled: true, Forced: true}, nil
+	}
+
+	enabled, err := m.switched(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.ReadOnlyStatus{Enabled: enabled}, nil
+}
+
+// Set switches the wallet to read-only, or back to writable, and returns the resulting status.
+// A forced mode stays read-only whatever the switch.
+func (m *Mode) Set(ctx context.Context, enabled bool) (*api.ReadOnlyStatus, error) {
+	var err error
+
+	if enabled {
+		err = m.store.Set(ctx, m.key, "1", 0).Err()
+	} else {
+		err = m.store.Del(ctx, m.key).Err()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch %s: %w", m.key, err)
+	}
+
+	return m.Status(ctx)
+}
+
+func (m *Mode) switched(ctx context.Context) (bool, error) {
+	count, err := m.store.Exists(ctx, m.key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, fmt.Errorf("failed to read %s: %w", m.key, err)
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,96 @@
+package readonly_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/readonly"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps the keys in memory, failing while err is set.
+type memoryStore struct {
+	keys map[string]bool
+	err  error
+}
+
+func (s *memoryStore) Exists(_ context.Context, keys ...string) *redis.IntCmd {
+	var count int64
+
+	for _, key := range keys {
+		if s.keys[key] {
+			count++
+		}
+	}
+
+	return redis.NewIntResult(count, s.err)
+}
+
+func (s *memoryStore) Set(_ context.Context, key string, _ interface{}, _ time.Duration) *redis.StatusCmd {
+	if s.err == nil {
+		s.keys[key] = true
+	}
+
+	return redis.NewStatusResult("OK", s.err)
+}
+
+func (s *memoryStore) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	if s.err == nil {
+		for _, key := range keys {
+			delete(s.keys, key)
+		}
+	}
+
+	return redis.NewIntResult(int64(len(keys)), s.err)
+}
+
+func TestMode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Switched", func(t *testing.T) {
+		store := &memoryStore{keys: map[string]bool{}}
+		mode := readonly.New(store, readonly.DefaultRedisKey, false).WithLogger(logging.Discard())
+
+		require.False(t, mode.ReadOnly(ctx))
+
+		status, err := mode.Set(ctx, true)
+		require.NoError(t, err)
+		require.True(t, status.Enabled)
+		require.False(t, status.Forced)
+		require.True(t, mode.ReadOnly(ctx))
+
+		status, err = mode.Set(ctx, false)
+		require.NoError(t, err)
+		require.False(t, status.Enabled)
+		require.False(t, mode.ReadOnly(ctx))
+	})
+
+	t.Run("Forced", func(t *testing.T) {
+		store := &memoryStore{keys: map[string]bool{}}
+		mode := readonly.New(store, readonly.DefaultRedisKey, true).WithLogger(logging.Discard())
+
+		require.True(t, mode.ReadOnly(ctx))
+
+		status, err := mode.Set(ctx, false)
+		require.NoError(t, err)
+		require.True(t, status.Enabled, "the setting can't be turned off")
+		require.True(t, status.Forced)
+	})
+
+	t.Run("Redis down", func(t *testing.T) {
+		store := &memoryStore{keys: map[string]bool{readonly.DefaultRedisKey: true}, err: errors.New("connection refused")}
+		mode := readonly.New(store, readonly.DefaultRedisKey, false).WithLogger(logging.Discard())
+
+		require.False(t, mode.ReadOnly(ctx), "the writes aren't taken down with Redis")
+
+		_, err := mode.Status(ctx)
+		require.Error(t, err)
+
+		_, err = mode.Set(ctx, true)
+		require.Error(t, err)
+	})
+}
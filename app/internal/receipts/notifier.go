@@ -0,0 +1,155 @@
+// Package receipts sends the receipts of the deposits, withdrawals and transfers to the subscribed accounts.
+// The receipts are queued from the outbox events, rendered from templates, and the failed deliveries are retried
+// with a backoff.
+package receipts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/devshark/wallet/pkg/clock"
+)
+
+const (
+	defaultBatchSize   = 100
+	DefaultMaxAttempts = 5
+)
+
+// Store is implemented by repository.PostgresRepository.
+type Store interface {
+	QueueReceipts(ctx context.Context, limit int) (int, error)
+	DueReceipts(ctx context.Context, at time.Time, limit int) ([]*api.ReceiptNotification, error)
+	MarkReceiptSent(ctx context.Context, id string) error
+	MarkReceiptFailed(ctx context.Context, id, reason string, retryAt *time.Time) error
+}
+
+// Notifier queues the receipts of the new outbox events, then sends the due ones.
+type Notifier struct {
+	store       Store
+	sender      Sender
+	templates   *Templates
+	batchSize   int
+	maxAttempts int
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+func NewNotifier(store Store, sender Sender) *Notifier {
+	return &Notifier{
+		store:       store,
+		sender:      sender,
+		templates:   DefaultTemplates(),
+		batchSize:   defaultBatchSize,
+		maxAttempts: DefaultMaxAttempts,
+		clock:       clock.NewSystemClock(),
+		logger:      slog.Default(),
+	}
+}
+
+// WithTemplates overrides the templates rendering the receipts, see ParseTemplates.
+func (n *Notifier) WithTemplates(templates *Templates) *Notifier {
+	n.templates = templates
+
+	return n
+}
+
+// WithMaxAttempts sets the number of delivery attempts before a receipt fails for good.
+func (n *Notifier) WithMaxAttempts(attempts int) *Notifier {
+	n.maxAttempts = attempts
+
+	return n
+}
+
+// WithClock overrides the clock deciding the retries.
+func (n *Notifier) WithClock(c clock.Clock) *Notifier {
+	n.clock = c
+
+	return n
+}
+
+func (n *Notifier) WithLogger(logger *slog.Logger) *Notifier {
+	n.logger = logger
+
+	return n
+}
+
+// Run queues the receipts of the pending events in batches, until there is none left, then attempts the due deliveries.
+// A failed delivery is retried by a later run, the other receipts are still sent.
+func (n *Notifier) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		queued, err := n.store.QueueReceipts(ctx, n.batchSize)
+		total += queued
+
+		if err != nil {
+			return fmt.Errorf("failed to queue receipts: %w", err)
+		}
+
+		if queued < n.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		n.logger.DebugContext(ctx, "receipts queued", slog.Int("events", total))
+	}
+
+	due, err := n.store.DueReceipts(ctx, n.clock.Now(), n.batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due receipts: %w", err)
+	}
+
+	for _, receipt := range due {
+		if err = n.send(ctx, receipt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send attempts the delivery of the receipt, and records the outcome.
+// It only fails if the outcome can't be recorded.
+func (n *Notifier) send(ctx context.Context, receipt *api.ReceiptNotification) error {
+	logger := n.logger.With(slog.String("receipt_id", receipt.ID), slog.String("kind", string(receipt.Kind)))
+
+	subject, body, sendErr := n.templates.Render(receipt)
+	if sendErr == nil {
+		sendErr = n.sender.Send(ctx, &Message{To: receipt.Destination, Subject: subject, Body: body})
+	}
+
+	if sendErr == nil {
+		if err := n.store.MarkReceiptSent(ctx, receipt.ID); err != nil {
+			return fmt.Errorf("failed to mark receipt %s sent: %w", receipt.ID, err)
+		}
+
+		logger.InfoContext(ctx, "receipt sent")
+
+		return nil
+	}
+
+	attempts := receipt.Attempts + 1
+
+	var retryAt *time.Time
+	if attempts < n.maxAttempts {
+		next := n.clock.Now().Add(statements.Backoff(attempts))
+		retryAt = &next
+	}
+
+	if err := n.store.MarkReceiptFailed(ctx, receipt.ID, sendErr.Error(), retryAt); err != nil {
+		return fmt.Errorf("failed to mark receipt %s failed: %w", receipt.ID, err)
+	}
+
+	logger.WarnContext(ctx, "receipt delivery failed",
+		slog.Any("error", sendErr),
+		slog.Int("attempts", attempts),
+		slog.Bool("retried", retryAt != nil),
+	)
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package receipts_test
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/receipts"
+	"github.com/devshark/wallet/pkg/logging"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps the outcomes of the deliveries.
+type memoryStore struct {
+	// pending is the number of events left to queue
+	pending int
+	due     []*api.ReceiptNotification
+	sent    []string
+	failed  map[string]*time.Time
+}
+
+func (s *memoryStore) QueueReceipts(_ context.Context, limit int) (int, error) {
+	queued := min(s.pending, limit)
+	s.pending -= queued
+
+	return queued, nil
+}
+
+func (s *memoryStore) DueReceipts(context.Context, time.Time, int) ([]*api.ReceiptNotification, error) {
+	return s.due, nil
+}
+
+func (s *memoryStore) MarkReceiptSent(_ context.Context, id string) error {
+	s.sent = append(s.sent, id)
+
+	return nil
+}
+
+func (s *memoryStore) MarkReceiptFailed(_ context.Context, id, _ string, retryAt *time.Time) error {
+	s.failed[id] = retryAt
+
+	return nil
+}
+
+type senderFunc func(ctx context.Context, message *receipts.Message) error
+
+func (f senderFunc) Send(ctx context.Context, message *receipts.Message) error {
+	return f(ctx, message)
+}
+
+func receipt(id string, kind api.ReceiptKind, attempts int) *api.ReceiptNotification {
+	return &api.ReceiptNotification{
+		ID:             id,
+		TxID:           "tx-" + id,
+		TransferID:     "transfer-" + id,
+		Kind:           kind,
+		AccountID:      "alice",
+		Destination:    id + "@example.com",
+		CounterpartyID: "bob",
+		Currency:       "USD",
+		Amount:         decimal.RequireFromString("12.50"),
+		Attempts:       attempts,
+		CreatedAt:      time.Date(2024, 7, 3, 9, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	now := time.Date(2024, 7, 3, 10, 0, 0, 0, time.UTC)
+
+	store := &memoryStore{
+		pending: 250,
+		due: []*api.ReceiptNotification{
+			receipt("sent", api.ReceiptDeposit, 0),
+			receipt("retried", api.ReceiptWithdrawal, 1),
+			receipt("exhausted", api.ReceiptTransferSent, 4),
+			receipt("unknown", "REFUND", 0),
+		},
+		failed: map[string]*time.Time{},
+	}
+
+	var messages []*receipts.Message
+
+	sender := senderFunc(func(_ context.Context, message *receipts.Message) error {
+		messages = append(messages, message)
+
+		if message.To != "sent@example.com" {
+			return errors.New("mailbox full")
+		}
+
+		return nil
+	})
+
+	notifier := receipts.NewNotifier(store, sender).
+		WithMaxAttempts(5).
+		WithClock(wallettesting.NewFakeClock(now)).
+		WithLogger(logging.Discard())
+
+	require.NoError(t, notifier.Run(context.Background()))
+
+	require.Zero(t, store.pending, "every batch is queued")
+	require.Equal(t, []string{"sent"}, store.sent)
+
+	require.NotNil(t, store.failed["retried"])
+	require.Equal(t, now.Add(2*time.Minute), *store.failed["retried"], "the second attempt backs off twice as long")
+	require.Nil(t, store.failed["exhausted"], "failed for good")
+	require.Contains(t, store.failed, "unknown", "a kind without a template fails")
+
+	require.Len(t, messages, 3, "the unknown kind isn't sent")
+	require.Equal(t, "Deposit of 12.5 USD to alice", messages[0].Subject)
+	require.Contains(t, messages[0].Body, "12.5 USD were deposited to your account alice.")
+	require.Contains(t, messages[0].Body, "Date: 2024-07-03 09:30:00 UTC")
+	require.Contains(t, messages[0].Body, "Reference: transfer-sent")
+	require.Equal(t, "Withdrawal of 12.5 USD from alice", messages[1].Subject)
+	require.Equal(t, "Transfer of 12.5 USD to bob", messages[2].Subject)
+}
+
+func TestParseTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "receipts.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{define "DEPOSIT.subject"}}You got {{.Amount}} {{.Currency}}
+{{end}}`), 0o600))
+
+	templates, err := receipts.ParseTemplates(path)
+	require.NoError(t, err)
+
+	subject, body, err := templates.Render(receipt("overridden", api.ReceiptDeposit, 0))
+	require.NoError(t, err)
+	require.Equal(t, "You got 12.5 USD", subject, "trimmed to a single line")
+	require.Contains(t, body, "were deposited", "the body is kept from the defaults")
+
+	_, err = receipts.ParseTemplates(filepath.Join(t.TempDir(), "missing.tmpl"))
+	require.Error(t, err)
+}
+
+func TestSMTP(t *testing.T) {
+	var (
+		sentTo []string
+		sent   []byte
+	)
+
+	sender := receipts.NewSMTP("smtp.example.com:587", "wallet@example.com").
+		WithSendMail(func(_ string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+			sentTo = to
+			sent = msg
+
+			return nil
+		})
+
+	err := sender.Send(context.Background(), &receipts.Message{
+		To:      "alice@example.com",
+		Subject: "Transfer from bob\r\nBcc: mallory@example.com",
+		Body:    "12.5 USD were transferred to your account alice from bob.\n",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"alice@example.com"}, sentTo)
+	require.Contains(t, string(sent), "Subject: Transfer from bobBcc: mallory@example.com\r\n", "the header can't be injected")
+	require.Contains(t, string(sent), "\r\n\r\n12.5 USD were transferred")
+}
+
+func TestReceiptKind(t *testing.T) {
+	require.Equal(t, api.ReceiptDeposit, api.NewReceiptKind(false, api.CompanyAccountID, "alice", api.CompanyAccountID))
+	require.Equal(t, api.ReceiptWithdrawal, api.NewReceiptKind(true, "alice", api.CompanyAccountID, api.CompanyAccountID))
+	require.Equal(t, api.ReceiptTransferSent, api.NewReceiptKind(true, "alice", "bob", api.CompanyAccountID))
+	require.Equal(t, api.ReceiptTransferReceived, api.NewReceiptKind(false, "alice", "bob", api.CompanyAccountID))
+}
@@ -0,0 +1,77 @@
+package receipts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"github.com/devshark/wallet/app/internal/statements"
+)
+
+//nolint:gochecknoglobals // stateless
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// Message is a rendered receipt.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers the rendered receipts, i.e. through an SMTP server or the API of an email provider.
+type Sender interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// SMTP sends the receipts as plain text emails through the SMTP server.
+type SMTP struct {
+	address  string
+	from     string
+	auth     smtp.Auth
+	sendMail statements.SendMailFunc
+}
+
+// NewSMTP sends through the SMTP server at address, i.e. smtp.example.com:587, from the sender address.
+// The connection is upgraded to TLS when the server supports it.
+func NewSMTP(address, from string) *SMTP {
+	return &SMTP{
+		address:  address,
+		from:     from,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// WithAuth authenticates to the SMTP server, which smtp.PlainAuth only allows over TLS or to localhost.
+func (s *SMTP) WithAuth(username, password string) *SMTP {
+	host, _, _ := strings.Cut(s.address, ":")
+	s.auth = smtp.PlainAuth("", username, password, host)
+
+	return s
+}
+
+// WithSendMail overrides how the messages are sent, i.e. in the tests.
+func (s *SMTP) WithSendMail(sendMail statements.SendMailFunc) *SMTP {
+	s.sendMail = sendMail
+
+	return s
+}
+
+func (s *SMTP) Send(_ context.Context, message *Message) error {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", headerValue.Replace(message.Subject))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(buf, "\r\n")
+	buf.WriteString(message.Body)
+
+	if err := s.sendMail(s.address, s.auth, s.from, []string{message.To}, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to mail the receipt: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package receipts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/devshark/wallet/api"
+)
+
+var ErrMissingTemplate = errors.New("missing receipt template")
+
+//go:embed templates/receipts.tmpl
+var defaultTemplates embed.FS
+
+// Templates render the subject and the body of the receipts, from the templates <KIND>.subject and <KIND>.body
+// of their kind, i.e. DEPOSIT.subject, with the api.ReceiptNotification.
+type Templates struct {
+	template *template.Template
+}
+
+// DefaultTemplates are the plain text receipts embedded in the binary.
+func DefaultTemplates() *Templates {
+	return &Templates{
+		template: template.Must(template.New("receipts").Option("missingkey=error").ParseFS(defaultTemplates, "templates/*.tmpl")),
+	}
+}
+
+// ParseTemplates overrides the default templates with the ones defined in the file, the others are kept.
+func ParseTemplates(path string) (*Templates, error) {
+	defaults := DefaultTemplates()
+
+	overridden, err := defaults.template.ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the receipt templates: %w", err)
+	}
+
+	return &Templates{template: overridden}, nil
+}
+
+// Render returns the subject and the body of the receipt.
+func (t *Templates) Render(receipt *api.ReceiptNotification) (string, string, error) {
+	subject, err := t.execute(string(receipt.Kind)+".subject", receipt)
+	if err != nil {
+		return "", "", err
+	}
+
+	body, err := t.execute(string(receipt.Kind)+".body", receipt)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the account ids are free-form, they can't inject headers
+	return headerValue.Replace(strings.TrimSpace(subject)), body, nil
+}
+
+func (t *Templates) execute(name string, receipt *api.ReceiptNotification) (string, error) {
+	if t.template.Lookup(name) == nil {
+		return "", fmt.Errorf("%w: %s", ErrMissingTemplate, name)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := t.template.ExecuteTemplate(buf, name, receipt); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
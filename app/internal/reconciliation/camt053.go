@@ -0,0 +1,135 @@
+package reconciliation
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// the subset of the camt.053 document used for the matching. The elements are matched by their local name,
+// so every version of the camt.053.001 namespace is accepted.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	// BOOK, or PDNG and INFO which aren't settled yet
+	Status      camtStatus `xml:"Sts"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	ServicerReference string   `xml:"AcctSvcrRef"`
+	EndToEndIDs       []string `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	Information       string   `xml:"AddtlNtryInf"`
+}
+
+// camtStatus is the entry status, a plain code before camt.053.001.08 and a Cd element since.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+func (s camtStatus) code() string {
+	if s.Code != "" {
+		return strings.TrimSpace(s.Code)
+	}
+
+	return strings.TrimSpace(s.Value)
+}
+
+// the end to end id set by the payer, which is not a reference
+const camtNotProvided = "NOTPROVIDED"
+
+// parseCAMT053 reads the booked entries of every statement of the document.
+func parseCAMT053(r io.Reader) ([]Entry, error) {
+	var document camtDocument
+
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, ErrEmptyStatement)
+		}
+
+		return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, err)
+	}
+
+	entries := []Entry{}
+
+	for _, statement := range document.Statements {
+		for i, camt := range statement.Entries {
+			if status := camt.Status.code(); status != "" && status != "BOOK" {
+				continue
+			}
+
+			entry, err := camtToEntry(camt)
+			if err != nil {
+				return nil, fmt.Errorf("%w: entry %d: %w", api.ErrInvalidStatement, i+1, err)
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func camtToEntry(camt camtEntry) (Entry, error) {
+	amount, err := decimal.NewFromString(strings.TrimSpace(camt.Amount.Value))
+	if err != nil || !amount.IsPositive() {
+		return Entry{}, fmt.Errorf("invalid amount %q", camt.Amount.Value)
+	}
+
+	entryType, ok := parseEntryType(camt.CreditDebit)
+	if !ok {
+		return Entry{}, fmt.Errorf("invalid credit debit indicator %q", camt.CreditDebit)
+	}
+
+	date := camt.BookingDate.DateTime
+	if date == "" {
+		date = camt.BookingDate.Date
+	}
+
+	bookedAt, err := parseBookingDate(date)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(camt.Amount.Currency))
+	if currency == "" {
+		return Entry{}, errors.New("missing the amount currency")
+	}
+
+	// the end to end id is the reference given by the wallet with the payment, if any
+	reference := strings.TrimSpace(camt.ServicerReference)
+
+	for _, id := range camt.EndToEndIDs {
+		if id = strings.TrimSpace(id); id != "" && id != camtNotProvided {
+			reference = id
+
+			break
+		}
+	}
+
+	return Entry{
+		Reference:   reference,
+		Type:        entryType,
+		Amount:      amount,
+		Currency:    currency,
+		BookedAt:    bookedAt,
+		Description: strings.TrimSpace(camt.Information),
+	}, nil
+}
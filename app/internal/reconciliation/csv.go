@@ -0,0 +1,125 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// the columns of the CSV statements, in any order. The reference, type and description are optional.
+// Without the type column, a negative amount is a debit.
+const (
+	csvDate        = "date"
+	csvAmount      = "amount"
+	csvCurrency    = "currency"
+	csvReference   = "reference"
+	csvType        = "type"
+	csvDescription = "description"
+)
+
+// parseCSV reads a statement with a header row naming the columns, i.e.
+//
+//	date,reference,amount,currency,type,description
+//	2024-07-01,DEP-1001,100.00,USD,CREDIT,deposit of user1
+func parseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, ErrEmptyStatement)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// the byte order mark of the spreadsheet exports
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, required := range []string{csvDate, csvAmount, csvCurrency} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing the %s column", api.ErrInvalidStatement, required)
+		}
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+
+		return ""
+	}
+
+	entries := []Entry{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		entry, err := csvEntry(record, column)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", api.ErrInvalidStatement, line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func csvEntry(record []string, column func(record []string, name string) string) (Entry, error) {
+	bookedAt, err := parseBookingDate(column(record, csvDate))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	amount, err := decimal.NewFromString(column(record, csvAmount))
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid amount %q", column(record, csvAmount))
+	}
+
+	entryType := api.CREDIT
+	if amount.IsNegative() {
+		entryType = api.DEBIT
+	}
+
+	if value := column(record, csvType); value != "" {
+		parsed, ok := parseEntryType(value)
+		if !ok {
+			return Entry{}, fmt.Errorf("invalid type %q", value)
+		}
+
+		entryType = parsed
+	}
+
+	currency := strings.ToUpper(column(record, csvCurrency))
+	if currency == "" || amount.IsZero() {
+		return Entry{}, errors.New("the currency and a non-zero amount are required")
+	}
+
+	return Entry{
+		Reference:   column(record, csvReference),
+		Type:        entryType,
+		Amount:      amount.Abs(),
+		Currency:    currency,
+		BookedAt:    bookedAt,
+		Description: column(record, csvDescription),
+	}, nil
+}
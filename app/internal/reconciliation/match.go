@@ -0,0 +1,143 @@
+package reconciliation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+// ledgerEntry is a ledger entry of the company account, and whether a statement entry matched it.
+type ledgerEntry struct {
+	tx      *api.Transaction
+	time    time.Time
+	matched bool
+}
+
+// match pairs the statement entries with the ledger entries of the company account, and reports both sides unmatched.
+// An entry is first matched by its reference, equal to the tx id or the remarks of the ledger entry,
+// then by its amount, to the closest ledger entry booked within the tolerance.
+// Only the unmatched ledger entries booked within the statement days are reported, the others belong to another statement.
+func match(statement *Statement, ledger []*api.Transaction, tolerance time.Duration) []*api.ReconciliationItem {
+	entries := make([]*ledgerEntry, 0, len(ledger))
+
+	for _, tx := range ledger {
+		entries = append(entries, &ledgerEntry{tx: tx, time: tx.Time})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+
+	items := make([]*api.ReconciliationItem, len(statement.Entries))
+	for i := range statement.Entries {
+		items[i] = statementItem(&statement.Entries[i])
+	}
+
+	// the references first, so a match by amount doesn't take the ledger entry of another reference
+	for i := range statement.Entries {
+		if statement.Entries[i].Reference == "" {
+			continue
+		}
+
+		if found := findByReference(entries, &statement.Entries[i]); found != nil {
+			matchItem(items[i], found)
+		}
+	}
+
+	for i := range statement.Entries {
+		if items[i].Status == api.Matched {
+			continue
+		}
+
+		if found := findByAmount(entries, &statement.Entries[i], tolerance); found != nil {
+			matchItem(items[i], found)
+		}
+	}
+
+	start, end := statement.Period()
+	start = start.Truncate(24 * time.Hour)
+	end = end.Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	for _, entry := range entries {
+		if entry.matched || entry.time.Before(start) || !entry.time.Before(end) {
+			continue
+		}
+
+		items = append(items, &api.ReconciliationItem{
+			Status:   api.UnmatchedLedger,
+			Type:     api.OppositeType(entry.tx.Type),
+			Amount:   entry.tx.Amount,
+			BookedAt: entry.time,
+			TxID:     entry.tx.TxID,
+			Remarks:  entry.tx.Remarks,
+		})
+	}
+
+	return items
+}
+
+func statementItem(entry *Entry) *api.ReconciliationItem {
+	return &api.ReconciliationItem{
+		Status:    api.UnmatchedStatement,
+		Reference: entry.Reference,
+		Type:      entry.Type,
+		Amount:    entry.Amount,
+		BookedAt:  entry.BookedAt,
+		Remarks:   entry.Description,
+	}
+}
+
+func matchItem(item *api.ReconciliationItem, entry *ledgerEntry) {
+	entry.matched = true
+
+	item.Status = api.Matched
+	item.TxID = entry.tx.TxID
+
+	if item.Remarks == "" {
+		item.Remarks = entry.tx.Remarks
+	}
+}
+
+// matches reports whether the ledger entry is the company side of the statement entry:
+// money received by the company is credited on the statement, and debited from the company account.
+func matches(entry *ledgerEntry, statementEntry *Entry) bool {
+	return !entry.matched &&
+		entry.tx.Type == api.OppositeType(statementEntry.Type) &&
+		entry.tx.Amount.Equal(statementEntry.Amount)
+}
+
+func findByReference(entries []*ledgerEntry, statementEntry *Entry) *ledgerEntry {
+	for _, entry := range entries {
+		if (entry.tx.TxID == statementEntry.Reference || entry.tx.Remarks == statementEntry.Reference) && matches(entry, statementEntry) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+func findByAmount(entries []*ledgerEntry, statementEntry *Entry, tolerance time.Duration) *ledgerEntry {
+	var (
+		closest  *ledgerEntry
+		distance time.Duration
+	)
+
+	for _, entry := range entries {
+		if !matches(entry, statementEntry) {
+			continue
+		}
+
+		d := entry.time.Sub(statementEntry.BookedAt).Abs()
+		if d > tolerance {
+			continue
+		}
+
+		if closest == nil || d < distance {
+			closest = entry
+			distance = d
+		}
+	}
+
+	return closest
+}
@@ -0,0 +1,141 @@
+// Package reconciliation matches the external settlement statements, i.e. the bank statements,
+// against the ledger entries of the company account.
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/idgen"
+)
+
+// DefaultDateTolerance is how far apart the booking date of a statement entry and its ledger entry may be.
+// The banks book the transfers on the settlement day, which can be days after the ledger entry.
+const DefaultDateTolerance = 72 * time.Hour
+
+// Store is implemented by repository.PostgresRepository.
+type Store interface {
+	// CompanyLedgerEntries returns the ledger entries of the company account in the currency, created in [from, to).
+	CompanyLedgerEntries(ctx context.Context, currency string, from, to time.Time) ([]*api.Transaction, error)
+	SaveReconciliation(ctx context.Context, reconciliation *api.Reconciliation) error
+	GetReconciliation(ctx context.Context, id string) (*api.Reconciliation, error)
+	ListReconciliations(ctx context.Context, limit int) ([]*api.Reconciliation, error)
+}
+
+type Reconciler struct {
+	store       Store
+	tolerance   time.Duration
+	clock       clock.Clock
+	idGenerator idgen.Generator
+	logger      *slog.Logger
+}
+
+func NewReconciler(store Store) *Reconciler {
+	return &Reconciler{
+		store:       store,
+		tolerance:   DefaultDateTolerance,
+		clock:       clock.NewSystemClock(),
+		idGenerator: idgen.NewUUIDGenerator(),
+		logger:      slog.Default(),
+	}
+}
+
+// WithDateTolerance sets how far apart the booking dates of matching entries may be.
+func (r *Reconciler) WithDateTolerance(tolerance time.Duration) *Reconciler {
+	r.tolerance = tolerance
+
+	return r
+}
+
+// WithClock overrides the clock used for the creation time of the reports.
+func (r *Reconciler) WithClock(c clock.Clock) *Reconciler {
+	r.clock = c
+
+	return r
+}
+
+// WithIDGenerator overrides the generator of the report ids. The ids must be valid UUIDs.
+func (r *Reconciler) WithIDGenerator(generator idgen.Generator) *Reconciler {
+	r.idGenerator = generator
+
+	return r
+}
+
+func (r *Reconciler) WithLogger(logger *slog.Logger) *Reconciler {
+	r.logger = logger
+
+	return r
+}
+
+// Reconcile parses the statement, matches it against the company account ledger of its period, and saves the report.
+// The errors wrapping api.ErrInvalidStatement or ErrInvalidFormat are caused by the file.
+func (r *Reconciler) Reconcile(ctx context.Context, format Format, fileName string, statementFile io.Reader) (*api.Reconciliation, error) {
+	statement, err := ParseStatement(format, statementFile)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := statement.Period()
+
+	// the ledger entries booked a few days away from the statement can still match
+	ledger, err := r.store.CompanyLedgerEntries(ctx, statement.Currency,
+		start.Truncate(24*time.Hour).Add(-r.tolerance),
+		end.Truncate(24*time.Hour).Add(24*time.Hour+r.tolerance),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the company ledger: %w", err)
+	}
+
+	items := match(statement, ledger, r.tolerance)
+
+	reconciliation := &api.Reconciliation{
+		ID:          r.idGenerator.NewID(),
+		Format:      string(format),
+		FileName:    fileName,
+		Currency:    statement.Currency,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		CreatedAt:   r.clock.Now(),
+		Items:       items,
+	}
+
+	for _, item := range items {
+		switch item.Status {
+		case api.Matched:
+			reconciliation.Matched++
+		case api.UnmatchedStatement:
+			reconciliation.UnmatchedStatement++
+		case api.UnmatchedLedger:
+			reconciliation.UnmatchedLedger++
+		}
+	}
+
+	if err = r.store.SaveReconciliation(ctx, reconciliation); err != nil {
+		return nil, fmt.Errorf("failed to save the reconciliation: %w", err)
+	}
+
+	r.logger.InfoContext(ctx, "statement reconciled",
+		slog.String("id", reconciliation.ID),
+		slog.String("currency", reconciliation.Currency),
+		slog.Int("matched", reconciliation.Matched),
+		slog.Int("unmatched_statement", reconciliation.UnmatchedStatement),
+		slog.Int("unmatched_ledger", reconciliation.UnmatchedLedger),
+	)
+
+	return reconciliation, nil
+}
+
+// Get returns the report with its items, or api.ErrReconciliationNotFound.
+func (r *Reconciler) Get(ctx context.Context, id string) (*api.Reconciliation, error) {
+	return r.store.GetReconciliation(ctx, id) //nolint:wrapcheck // the caller checks the sentinel errors
+}
+
+// List returns the latest reports first, without their items.
+func (r *Reconciler) List(ctx context.Context, limit int) ([]*api.Reconciliation, error) {
+	return r.store.ListReconciliations(ctx, limit) //nolint:wrapcheck // the caller checks the sentinel errors
+}
@@ -0,0 +1,146 @@
+package reconciliation_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore returns its ledger entries within the queried range, and keeps the saved reports.
+type fakeStore struct {
+	ledger   []*api.Transaction
+	from, to time.Time
+	saved    []*api.Reconciliation
+	err      error
+}
+
+func (s *fakeStore) CompanyLedgerEntries(_ context.Context, _ string, from, to time.Time) ([]*api.Transaction, error) {
+	s.from, s.to = from, to
+
+	return s.ledger, s.err
+}
+
+func (s *fakeStore) SaveReconciliation(_ context.Context, reconciliation *api.Reconciliation) error {
+	s.saved = append(s.saved, reconciliation)
+
+	return nil
+}
+
+func (s *fakeStore) GetReconciliation(context.Context, string) (*api.Reconciliation, error) {
+	return nil, api.ErrReconciliationNotFound
+}
+
+func (s *fakeStore) ListReconciliations(context.Context, int) ([]*api.Reconciliation, error) {
+	return s.saved, nil
+}
+
+func companyEntry(txID string, entryType api.DebitOrCreditType, amount, remarks, at string) *api.Transaction {
+	booked, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		panic(err)
+	}
+
+	return &api.Transaction{
+		TxID:      txID,
+		AccountID: api.CompanyAccountID,
+		Type:      entryType,
+		Amount:    decimal.RequireFromString(amount),
+		Currency:  "USD",
+		Remarks:   remarks,
+		Time:      booked,
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 7, 3, 9, 0, 0, 0, time.UTC)
+
+	statement := "date,reference,amount,currency,type\n" +
+		// by reference, in the remarks of the deposit
+		"2024-07-01,DEP-1001,100.00,USD,CREDIT\n" +
+		// by amount, booked the day after the withdrawal
+		"2024-07-02,BANK-REF-2,25.50,USD,DEBIT\n" +
+		// never credited
+		"2024-07-02,DEP-1003,70,USD,CREDIT\n"
+
+	store := &fakeStore{
+		ledger: []*api.Transaction{
+			companyEntry("tx-1", api.DEBIT, "100", "DEP-1001", "2024-07-01T08:00:00Z"),
+			// the same amount without the reference, must not steal the first match
+			companyEntry("tx-2", api.DEBIT, "100", "", "2024-07-01T07:00:00Z"),
+			companyEntry("tx-3", api.CREDIT, "25.5", "payout", "2024-07-01T16:00:00Z"),
+			// outside of the statement days, belongs to the next statement
+			companyEntry("tx-4", api.DEBIT, "5", "", "2024-07-04T10:00:00Z"),
+		},
+	}
+
+	reconciler := reconciliation.NewReconciler(store).
+		WithDateTolerance(48 * time.Hour).
+		WithClock(wallettesting.NewFakeClock(now)).
+		WithIDGenerator(wallettesting.NewSequentialIDs())
+
+	report, err := reconciler.Reconcile(ctx, reconciliation.FormatCSV, "july.csv", strings.NewReader(statement))
+	require.NoError(t, err)
+
+	require.Equal(t, time.Date(2024, 6, 29, 0, 0, 0, 0, time.UTC), store.from)
+	require.Equal(t, time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC), store.to)
+
+	require.Equal(t, wallettesting.SequentialID(1), report.ID)
+	require.Equal(t, "july.csv", report.FileName)
+	require.Equal(t, "USD", report.Currency)
+	require.Equal(t, now, report.CreatedAt)
+	require.Equal(t, 2, report.Matched)
+	require.Equal(t, 1, report.UnmatchedStatement)
+	require.Equal(t, 1, report.UnmatchedLedger)
+	require.Len(t, store.saved, 1)
+
+	require.Equal(t, api.Matched, report.Items[0].Status)
+	require.Equal(t, "tx-1", report.Items[0].TxID)
+	require.Equal(t, api.Matched, report.Items[1].Status)
+	require.Equal(t, "tx-3", report.Items[1].TxID)
+	require.Equal(t, "payout", report.Items[1].Remarks)
+	require.Equal(t, api.UnmatchedStatement, report.Items[2].Status)
+	require.Equal(t, "DEP-1003", report.Items[2].Reference)
+	require.Equal(t, api.UnmatchedLedger, report.Items[3].Status)
+	require.Equal(t, "tx-2", report.Items[3].TxID)
+	require.Equal(t, api.CREDIT, report.Items[3].Type)
+
+	t.Run("Outside the tolerance", func(t *testing.T) {
+		store := &fakeStore{
+			ledger: []*api.Transaction{companyEntry("tx-1", api.DEBIT, "100", "", "2024-07-01T08:00:00Z")},
+		}
+
+		report, err := reconciliation.NewReconciler(store).
+			WithDateTolerance(time.Hour).
+			Reconcile(ctx, reconciliation.FormatCSV, "", strings.NewReader("date,amount,currency\n2024-07-01T10:00:00Z,100,USD\n"))
+		require.NoError(t, err)
+		require.Zero(t, report.Matched)
+		require.Equal(t, 1, report.UnmatchedStatement)
+		require.Equal(t, 1, report.UnmatchedLedger)
+	})
+
+	t.Run("Invalid statement", func(t *testing.T) {
+		store := &fakeStore{}
+
+		_, err := reconciliation.NewReconciler(store).Reconcile(ctx, reconciliation.FormatCSV, "", strings.NewReader("date\n"))
+		require.ErrorIs(t, err, api.ErrInvalidStatement)
+		require.Empty(t, store.saved)
+	})
+
+	t.Run("Store failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+		store := &fakeStore{err: errDB}
+
+		_, err := reconciliation.NewReconciler(store).Reconcile(ctx, reconciliation.FormatCSV, "", strings.NewReader(statement))
+		require.ErrorIs(t, err, errDB)
+		require.NotErrorIs(t, err, api.ErrInvalidStatement)
+	})
+}
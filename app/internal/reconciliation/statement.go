@@ -0,0 +1,140 @@
+package reconciliation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// Format is the format of the external statement files.
+type Format string
+
+const (
+	// FormatCSV is a CSV file with a header, see parseCSV.
+	FormatCSV Format = "csv"
+	// FormatCAMT053 is an ISO 20022 bank to customer statement, camt.053.
+	FormatCAMT053 Format = "camt053"
+)
+
+var (
+	ErrInvalidFormat   = errors.New("invalid statement format, must be one of csv, camt053")
+	ErrEmptyStatement  = errors.New("the statement has no entries")
+	ErrMixedCurrencies = errors.New("the statement has entries in several currencies")
+)
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatCSV, FormatCAMT053:
+		return format, nil
+	case "camt.053", "xml":
+		return FormatCAMT053, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidFormat, value)
+	}
+}
+
+// Entry is a booked entry of the statement, from the statement's point of view:
+// a credit is money received by the company, i.e. a deposit.
+type Entry struct {
+	Reference   string
+	Type        api.DebitOrCreditType
+	Amount      decimal.Decimal
+	Currency    string
+	BookedAt    time.Time
+	Description string
+}
+
+// Statement is the entries of a single currency.
+type Statement struct {
+	Currency string
+	Entries  []Entry
+}
+
+// Period returns the earliest and the latest booking dates of the entries.
+func (s *Statement) Period() (time.Time, time.Time) {
+	var start, end time.Time
+
+	for i, entry := range s.Entries {
+		if i == 0 || entry.BookedAt.Before(start) {
+			start = entry.BookedAt
+		}
+
+		if i == 0 || entry.BookedAt.After(end) {
+			end = entry.BookedAt
+		}
+	}
+
+	return start, end
+}
+
+// ParseStatement reads the statement in the given format.
+func ParseStatement(format Format, r io.Reader) (*Statement, error) {
+	var (
+		entries []Entry
+		err     error
+	)
+
+	switch format {
+	case FormatCSV:
+		entries, err = parseCSV(r)
+	case FormatCAMT053:
+		entries, err = parseCAMT053(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newStatement(entries)
+}
+
+func newStatement(entries []Entry) (*Statement, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %w", api.ErrInvalidStatement, ErrEmptyStatement)
+	}
+
+	currency := entries[0].Currency
+
+	for _, entry := range entries {
+		if entry.Currency != currency {
+			return nil, fmt.Errorf("%w: %w: %s and %s", api.ErrInvalidStatement, ErrMixedCurrencies, currency, entry.Currency)
+		}
+	}
+
+	return &Statement{
+		Currency: currency,
+		Entries:  entries,
+	}, nil
+}
+
+// parseEntryType reads the debit or credit indicator, in the ledger or the ISO 20022 notation.
+func parseEntryType(value string) (api.DebitOrCreditType, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case string(api.CREDIT), "CRDT", "CR", "C":
+		return api.CREDIT, true
+	case string(api.DEBIT), "DBIT", "DR", "D":
+		return api.DEBIT, true
+	default:
+		return "", false
+	}
+}
+
+// parseBookingDate reads a date or a timestamp, in UTC when it has no zone.
+func parseBookingDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid booking date %q", value)
+}
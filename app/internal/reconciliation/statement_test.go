@@ -0,0 +1,118 @@
+package reconciliation_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	for value, expected := range map[string]reconciliation.Format{
+		"csv":      reconciliation.FormatCSV,
+		" CSV ":    reconciliation.FormatCSV,
+		"camt053":  reconciliation.FormatCAMT053,
+		"camt.053": reconciliation.FormatCAMT053,
+		"xml":      reconciliation.FormatCAMT053,
+	} {
+		format, err := reconciliation.ParseFormat(value)
+		require.NoError(t, err)
+		require.Equal(t, expected, format)
+	}
+
+	_, err := reconciliation.ParseFormat("mt940")
+	require.ErrorIs(t, err, reconciliation.ErrInvalidFormat)
+}
+
+func TestParseCSV(t *testing.T) {
+	t.Run("Entries", func(t *testing.T) {
+		statement, err := reconciliation.ParseStatement(reconciliation.FormatCSV, strings.NewReader(
+			"\ufeffDate,Reference,Amount,Currency,Type,Description\n"+
+				"2024-07-01,DEP-1001,100.00,usd,CRDT,deposit of user1\n"+
+				"2024-07-02T10:00:00Z,,-25.5,USD,,payout\n"))
+		require.NoError(t, err)
+		require.Equal(t, "USD", statement.Currency)
+		require.Len(t, statement.Entries, 2)
+
+		require.Equal(t, "DEP-1001", statement.Entries[0].Reference)
+		require.Equal(t, api.CREDIT, statement.Entries[0].Type)
+		require.True(t, decimal.NewFromInt(100).Equal(statement.Entries[0].Amount))
+		require.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), statement.Entries[0].BookedAt)
+
+		// the sign gives the type without the type column
+		require.Equal(t, api.DEBIT, statement.Entries[1].Type)
+		require.True(t, decimal.RequireFromString("25.5").Equal(statement.Entries[1].Amount))
+
+		start, end := statement.Period()
+		require.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), start)
+		require.Equal(t, time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC), end)
+	})
+
+	for name, content := range map[string]string{
+		"Empty":            "",
+		"Header only":      "date,amount,currency\n",
+		"Missing column":   "date,amount\n2024-07-01,10\n",
+		"Invalid amount":   "date,amount,currency\n2024-07-01,ten,USD\n",
+		"Zero amount":      "date,amount,currency\n2024-07-01,0,USD\n",
+		"Invalid date":     "date,amount,currency\n01/07/2024,10,USD\n",
+		"Invalid type":     "date,amount,currency,type\n2024-07-01,10,USD,REFUND\n",
+		"Mixed currencies": "date,amount,currency\n2024-07-01,10,USD\n2024-07-01,10,EUR\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := reconciliation.ParseStatement(reconciliation.FormatCSV, strings.NewReader(content))
+			require.ErrorIs(t, err, api.ErrInvalidStatement)
+		})
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	t.Run("Booked entries", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.camt053.xml")
+		require.NoError(t, err)
+		defer file.Close()
+
+		statement, err := reconciliation.ParseStatement(reconciliation.FormatCAMT053, file)
+		require.NoError(t, err)
+		require.Equal(t, "USD", statement.Currency)
+
+		// the pending entry isn't settled yet
+		require.Len(t, statement.Entries, 2)
+
+		require.Equal(t, "DEP-1001", statement.Entries[0].Reference)
+		require.Equal(t, api.CREDIT, statement.Entries[0].Type)
+		require.Equal(t, "deposit of user1", statement.Entries[0].Description)
+
+		// without an end to end id, the bank's reference
+		require.Equal(t, "BANK-REF-2", statement.Entries[1].Reference)
+		require.Equal(t, api.DEBIT, statement.Entries[1].Type)
+		require.True(t, decimal.RequireFromString("25.50").Equal(statement.Entries[1].Amount))
+		require.Equal(t, time.Date(2024, 7, 1, 13, 30, 0, 0, time.UTC), statement.Entries[1].BookedAt)
+	})
+
+	t.Run("Plain status code", func(t *testing.T) {
+		statement, err := reconciliation.ParseStatement(reconciliation.FormatCAMT053, strings.NewReader(`
+			<Document><BkToCstmrStmt><Stmt><Ntry>
+				<Amt Ccy="EUR">10</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2024-07-01</Dt></BookgDt>
+			</Ntry></Stmt></BkToCstmrStmt></Document>`))
+		require.NoError(t, err)
+		require.Equal(t, "EUR", statement.Currency)
+	})
+
+	for name, content := range map[string]string{
+		"Empty":          "",
+		"Not XML":        "date,amount,currency",
+		"No entries":     `<Document><BkToCstmrStmt><Stmt></Stmt></BkToCstmrStmt></Document>`,
+		"Invalid amount": `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="EUR">-1</Amt><CdtDbtInd>CRDT</CdtDbtInd><BookgDt><Dt>2024-07-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>`,
+		"No date":        `<Document><BkToCstmrStmt><Stmt><Ntry><Amt Ccy="EUR">1</Amt><CdtDbtInd>CRDT</CdtDbtInd></Ntry></Stmt></BkToCstmrStmt></Document>`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := reconciliation.ParseStatement(reconciliation.FormatCAMT053, strings.NewReader(content))
+			require.ErrorIs(t, err, api.ErrInvalidStatement)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Package remarks enforces the length and the content of the remarks of the transfers, before they're posted,
+// as they're copied into the ledger entries, the webhooks and the statements.
+package remarks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/devshark/wallet/api"
+)
+
+// MaxLength is the length of the remarks columns, before the encryption of the ledger entries.
+const MaxLength = 255
+
+// Check screens the content of the remarks, i.e. against a profanity list or a PII detector.
+// It returns the reason the remarks are rejected, or an empty string.
+type Check interface {
+	Check(remarks string) string
+}
+
+// Policy is the length and the content of the remarks. The zero policy allows any remarks up to MaxLength characters.
+type Policy struct {
+	maxLength int
+	checks    []Check
+}
+
+// New returns the policy of the remarks with at most maxLength characters, passing every check.
+// A zero maxLength is MaxLength.
+func New(maxLength int, checks ...Check) (Policy, error) {
+	if maxLength == 0 {
+		maxLength = MaxLength
+	}
+
+	if maxLength < 0 || maxLength > MaxLength {
+		return Policy{}, fmt.Errorf("invalid remarks length %d, at most %d", maxLength, MaxLength)
+	}
+
+	return Policy{maxLength: maxLength, checks: checks}, nil
+}
+
+// Validate returns api.ErrInvalidRemarks, explaining why, when the remarks don't follow the policy.
+func (p Policy) Validate(remarks string) error {
+	maxLength := p.maxLength
+	if maxLength == 0 {
+		maxLength = MaxLength
+	}
+
+	if utf8.RuneCountInString(remarks) > maxLength {
+		return fmt.Errorf("%w: at most %d characters", api.ErrInvalidRemarks, maxLength)
+	}
+
+	if remarks == "" {
+		return nil
+	}
+
+	for _, check := range p.checks {
+		if reason := check.Check(remarks); reason != "" {
+			return fmt.Errorf("%w: %s", api.ErrInvalidRemarks, reason)
+		}
+	}
+
+	return nil
+}
+
+// Words rejects the remarks containing any of the words, i.e. the profanities, matched case-insensitively as whole words.
+type Words struct {
+	pattern *regexp.Regexp
+}
+
+// NewWords lists the words to reject, ignoring the blanks. It's nil without any word.
+func NewWords(words []string) *Words {
+	quoted := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	return &Words{pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)}
+}
+
+// Check doesn't echo the word, the remarks are rejected as they are.
+func (w *Words) Check(remarks string) string {
+	if w.pattern.MatchString(remarks) {
+		return "contains a denied word"
+	}
+
+	return ""
+}
+
+//nolint:gochecknoglobals // constant
+var (
+	// the 13 to 19 digits of a card number, optionally grouped by spaces or dashes, told from the other numbers by their checksum
+	cardNumberPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	emailPattern      = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+)
+
+// PII rejects the remarks containing a card number or an email address, which the ledger must not keep.
+type PII struct{}
+
+func (PII) Check(remarks string) string {
+	for _, match := range cardNumberPattern.FindAllString(remarks, -1) {
+		if luhn(match) {
+			return "contains a card number"
+		}
+	}
+
+	if emailPattern.MatchString(remarks) {
+		return "contains an email address"
+	}
+
+	return ""
+}
+
+// luhn reports whether the digits of the number pass the Luhn checksum of the card numbers.
+func luhn(number string) bool {
+	sum := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+
+		digit := int(number[i] - '0')
+
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
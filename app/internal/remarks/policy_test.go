@@ -0,0 +1,67 @@
+package remarks_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/remarks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("Any", func(t *testing.T) {
+		var policy remarks.Policy
+
+		for _, text := range []string{"", "rent", "refund alice@example.com", strings.Repeat("é", remarks.MaxLength)} {
+			require.NoError(t, policy.Validate(text), text)
+		}
+
+		require.ErrorIs(t, policy.Validate(strings.Repeat("a", remarks.MaxLength+1)), api.ErrInvalidRemarks)
+	})
+
+	t.Run("Length", func(t *testing.T) {
+		policy, err := remarks.New(10)
+		require.NoError(t, err)
+
+		require.NoError(t, policy.Validate("0123456789"))
+
+		err = policy.Validate("0123456789a")
+		require.ErrorIs(t, err, api.ErrInvalidRemarks)
+		require.Contains(t, err.Error(), "at most 10 characters")
+
+		for _, maxLength := range []int{-1, remarks.MaxLength + 1} {
+			_, err = remarks.New(maxLength)
+			require.Error(t, err, maxLength)
+		}
+	})
+
+	t.Run("Words", func(t *testing.T) {
+		require.Nil(t, remarks.NewWords([]string{"", " "}))
+
+		policy, err := remarks.New(0, remarks.NewWords([]string{"darn", " heck "}))
+		require.NoError(t, err)
+
+		for _, text := range []string{"darn", "oh HECK!", "well, darn it"} {
+			require.ErrorIs(t, policy.Validate(text), api.ErrInvalidRemarks, text)
+		}
+
+		for _, text := range []string{"darned", "checks", "rent"} {
+			require.NoError(t, policy.Validate(text), text)
+		}
+	})
+
+	t.Run("PII", func(t *testing.T) {
+		policy, err := remarks.New(0, remarks.PII{})
+		require.NoError(t, err)
+
+		for _, text := range []string{"card 4111 1111 1111 1111", "card 4111-1111-1111-1111", "4111111111111111", "to bob@example.com"} {
+			require.ErrorIs(t, policy.Validate(text), api.ErrInvalidRemarks, text)
+		}
+
+		// the other numbers fail the checksum of the card numbers
+		for _, text := range []string{"invoice 4111 1111 1111 1112", "order 20240115", "rent @ home"} {
+			require.NoError(t, policy.Validate(text), text)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// a single projector runs at a time, as the running balances are projected in the order of the events
+	lockActivityProjection = `SELECT pg_try_advisory_xact_lock(hashtext('account_activity'))`
+
+	selectUnprojectedEvents = `SELECT seq, id, type, version, payload, created_at
+		FROM outbox_events
+		WHERE projected_at IS NULL
+		ORDER BY seq
+		LIMIT $1`
+
+	updateEventsProjected = `UPDATE outbox_events SET projected_at = $2 WHERE id = ANY($1)`
+
+	selectLastRunningBalance = `SELECT running_balance FROM account_activity
+		WHERE account_id = $1 AND currency = $2
+		ORDER BY seq DESC
+		LIMIT 1`
+
+	// the first activity of an account starts from its ledger, i.e. the entries posted before the feed was enabled, up to the entry $3
+	selectLedgerRunningBalance = `
+		SELECT COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0)
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE accounts.user_id = $1 AND accounts.currency = $2
+			AND (transactions.created_at < (SELECT created_at FROM transactions WHERE id = $3) OR transactions.id = $3)`
+
+	insertActivity = `INSERT INTO account_activity
+		(tx_id, seq, transfer_id, account_id, currency, direction, counterparty_id, amount, running_balance, tags, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (tx_id) DO NOTHING`
+
+	selectAccountActivity = `SELECT tx_id, transfer_id, account_id, currency, direction, counterparty_id,
+			amount, running_balance, tags, created_at
+		FROM account_activity
+		WHERE account_id = $1 AND currency = $2
+		ORDER BY seq DESC
+		LIMIT $3`
+)
+
+// WithActivityFeed keeps the outbox events until they're projected to the activity feeds, see ProjectActivity.
+// The outbox must be enabled, as the feeds are projected from its events.
+func (r *PostgresRepository) WithActivityFeed() *PostgresRepository {
+	r.activityFeed = true
+
+	return r
+}
+
+// ProjectActivity projects at most limit outbox events to the activity feeds of their accounts, the oldest first,
+// and returns the number of events projected. It returns 0 while another projection is running.
+func (r *PostgresRepository) ProjectActivity(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	var locked bool
+	if err = tx.QueryRowContext(ctx, lockActivityProjection).Scan(&locked); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if !locked {
+		return 0, nil
+	}
+
+	events, seqs, err := pendingEvents(ctx, tx, selectUnprojectedEvents, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	// the running balances of the accounts already projected in the batch
+	balances := map[string]decimal.Decimal{}
+	ids := make([]string, len(events))
+
+	for i, event := range events {
+		ids[i] = event.ID
+
+		// the other events, and the later versions, aren't part of the feeds
+		if event.Type != api.EventTransferCreated || event.Version != api.TransferCreatedVersion {
+			continue
+		}
+
+		transfer := &api.TransferCreated{}
+		if err = json.Unmarshal(event.Data, transfer); err != nil {
+			return 0, fmt.Errorf("failed to decode event %s: %w", event.ID, err)
+		}
+
+		for _, activity := range transferActivities(transfer, event) {
+			if err = r.projectActivity(ctx, tx, balances, activity, seqs[i]); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, updateEventsProjected, pq.Array(ids), r.clock.Now()); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return len(events), nil
+}
+
+// pendingEvents returns the outbox events of the query, at most limit, with their sequence.
+func pendingEvents(ctx context.Context, tx *sql.Tx, query string, limit int) ([]*api.Event, []int64, error) {
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	events := make([]*api.Event, 0, limit)
+	seqs := make([]int64, 0, limit)
+
+	for rows.Next() {
+		var (
+			seq     int64
+			payload []byte
+		)
+
+		event := &api.Event{}
+
+		if err = rows.Scan(&seq, &event.ID, &event.Type, &event.Version, &payload, &event.Time); err != nil {
+			return nil, nil, formatUnknownError(err)
+		}
+
+		event.Data = payload
+		events = append(events, event)
+		seqs = append(seqs, seq)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, formatUnknownError(err)
+	}
+
+	return events, seqs, nil
+}
+
+// transferActivities returns the activities of both accounts of the transfer, without their running balance.
+func transferActivities(transfer *api.TransferCreated, event *api.Event) []*api.AccountActivity {
+	tags := transfer.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return []*api.AccountActivity{
+		{
+			TxID:           transfer.DebitTxID,
+			TransferID:     transfer.TransferID,
+			AccountID:      transfer.FromAccountID,
+			Currency:       transfer.Currency,
+			Direction:      api.ActivityOut,
+			CounterpartyID: transfer.ToAccountID,
+			Amount:         transfer.Amount,
+			Tags:           tags,
+			Time:           event.Time,
+		},
+		{
+			TxID:           transfer.CreditTxID,
+			TransferID:     transfer.TransferID,
+			AccountID:      transfer.ToAccountID,
+			Currency:       transfer.Currency,
+			Direction:      api.ActivityIn,
+			CounterpartyID: transfer.FromAccountID,
+			Amount:         transfer.Amount,
+			Tags:           tags,
+			Time:           event.Time,
+		},
+	}
+}
+
+// projectActivity records the activity with the running balance of its account, following its previous activity.
+func (r *PostgresRepository) projectActivity(ctx context.Context, tx *sql.Tx, balances map[string]decimal.Decimal, activity *api.AccountActivity, seq int64) error {
+	key := activity.Currency + "/" + activity.AccountID
+
+	signed := activity.Amount
+	if activity.Direction == api.ActivityOut {
+		signed = signed.Neg()
+	}
+
+	balance, ok := balances[key]
+	if ok {
+		balance = balance.Add(signed)
+	} else {
+		var err error
+
+		if balance, err = runningBalance(ctx, tx, activity, signed); err != nil {
+			return err
+		}
+	}
+
+	balances[key] = balance
+
+	_, err := tx.ExecContext(ctx, insertActivity, activity.TxID, seq, activity.TransferID, activity.AccountID, activity.Currency,
+		activity.Direction, activity.CounterpartyID, activity.Amount, balance, pq.Array(activity.Tags), activity.Time)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// runningBalance returns the balance of the account once the activity is posted, from its previous activity,
+// or from its ledger for its first activity, which already includes the entry.
+func runningBalance(ctx context.Context, tx *sql.Tx, activity *api.AccountActivity, signed decimal.Decimal) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+
+	err := tx.QueryRowContext(ctx, selectLastRunningBalance, activity.AccountID, activity.Currency).Scan(&balance)
+	if err == nil {
+		return balance.Add(signed), nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return decimal.Zero, formatUnknownError(err)
+	}
+
+	err = tx.QueryRowContext(ctx, selectLedgerRunningBalance, activity.AccountID, activity.Currency, activity.TxID).Scan(&balance)
+	if err != nil {
+		return decimal.Zero, formatUnknownError(err)
+	}
+
+	return balance, nil
+}
+
+// GetAccountActivity returns the latest activity of the account in the currency, at most limit entries.
+func (r *PostgresRepository) GetAccountActivity(ctx context.Context, accountID, currency string, limit int) ([]*api.AccountActivity, error) {
+	accountID = strings.TrimSpace(accountID)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectAccountActivity, accountID, currency, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	activities := []*api.AccountActivity{}
+
+	for rows.Next() {
+		activity := &api.AccountActivity{}
+
+		err = rows.Scan(&activity.TxID, &activity.TransferID, &activity.AccountID, &activity.Currency, &activity.Direction,
+			&activity.CounterpartyID, &activity.Amount, &activity.RunningBalance, pq.Array(&activity.Tags), &activity.Time)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		activities = append(activities, activity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return activities, nil
+}
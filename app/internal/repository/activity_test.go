@@ -0,0 +1,109 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityFeed(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE outbox_events, account_activity;")
+		require.NoError(t, err)
+	})
+
+	// posted before the feed was enabled, so its first running balance starts from the ledger
+	_, err := repository.NewPostgresRepository(db).Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "activity_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(50),
+	}, "activity-key-1")
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresRepository(db).WithOutbox().WithActivityFeed()
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "activity_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+		Tags:          []string{"payroll"},
+	}, "activity-key-2")
+	require.NoError(t, err)
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "activity_user",
+		ToAccountID:   api.CompanyAccountID,
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(40),
+	}, "activity-key-3")
+	require.NoError(t, err)
+
+	t.Run("Unprojected events are kept", func(t *testing.T) {
+		published, err := repo.RelayEvents(ctx, 10, func(context.Context, []api.Event) error {
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, published)
+
+		purged, err := repo.PurgePublishedEvents(ctx, time.Now().UTC().Add(time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, purged)
+	})
+
+	t.Run("Projection", func(t *testing.T) {
+		projected, err := repo.ProjectActivity(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, projected)
+
+		projected, err = repo.ProjectActivity(ctx, 10)
+		require.NoError(t, err)
+		require.Zero(t, projected)
+
+		activities, err := repo.GetAccountActivity(ctx, "activity_user", "usd", 10)
+		require.NoError(t, err)
+		require.Len(t, activities, 2)
+
+		// the latest first
+		require.Equal(t, api.ActivityOut, activities[0].Direction)
+		require.Equal(t, "activity-key-3", activities[0].TransferID)
+		require.Equal(t, api.CompanyAccountID, activities[0].CounterpartyID)
+		require.True(t, decimal.NewFromInt(40).Equal(activities[0].Amount))
+		require.True(t, decimal.NewFromInt(110).Equal(activities[0].RunningBalance))
+
+		require.Equal(t, api.ActivityIn, activities[1].Direction)
+		require.Equal(t, []string{"payroll"}, activities[1].Tags)
+		require.True(t, decimal.NewFromInt(150).Equal(activities[1].RunningBalance))
+
+		activities, err = repo.GetAccountActivity(ctx, "activity_user", "USD", 1)
+		require.NoError(t, err)
+		require.Len(t, activities, 1)
+
+		activities, err = repo.GetAccountActivity(ctx, "unknown_user", "USD", 10)
+		require.NoError(t, err)
+		require.Empty(t, activities)
+	})
+
+	t.Run("Projected events are purged", func(t *testing.T) {
+		purged, err := repo.PurgePublishedEvents(ctx, time.Now().UTC().Add(time.Hour))
+		require.NoError(t, err)
+		require.EqualValues(t, 2, purged)
+	})
+
+	t.Run("Invalid account", func(t *testing.T) {
+		_, err := repo.GetAccountActivity(ctx, " ", "USD", 10)
+		require.ErrorIs(t, err, api.ErrInvalidAccountID)
+	})
+}
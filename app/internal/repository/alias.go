@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// an existing alias is kept, the caller compares its account
+	insertAlias = `INSERT INTO account_aliases (alias, kind, account_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (alias) DO NOTHING`
+
+	selectAlias = `SELECT alias, kind, account_id, created_at FROM account_aliases WHERE alias = $1`
+
+	selectAccountAliases = `SELECT alias, kind, account_id, created_at FROM account_aliases
+		WHERE account_id = $1
+		ORDER BY created_at, alias`
+
+	deleteAlias = `DELETE FROM account_aliases WHERE alias = $1`
+)
+
+// RegisterAlias maps the external identifier to the account, in every currency.
+// Registering the same alias again for the account is a no-op, but an alias can't move to another account.
+func (r *PostgresRepository) RegisterAlias(ctx context.Context, accountID string, request *api.RegisterAliasRequest) (*api.AccountAlias, error) {
+	accountID = strings.TrimSpace(accountID)
+
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	if r.isCompanyAccount(accountID) {
+		return nil, api.ErrCompanyAccount
+	}
+
+	alias := api.NormalizeAlias(request.Alias)
+	if alias == "" || len(alias) > api.MaxAliasLength {
+		return nil, api.ErrInvalidAlias
+	}
+
+	kind := api.AliasKind(strings.ToUpper(strings.TrimSpace(string(request.Kind))))
+	if kind == "" {
+		kind = api.AliasReference
+	}
+
+	if !kind.Valid() {
+		return nil, api.ErrInvalidAlias
+	}
+
+	if _, err := r.db.ExecContext(ctx, insertAlias, alias, kind, accountID, r.clock.Now()); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	registered, err := r.ResolveAlias(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if registered.AccountID != accountID {
+		return nil, api.ErrAliasTaken
+	}
+
+	return registered, nil
+}
+
+// ResolveAlias returns the alias with the account it's registered to.
+func (r *PostgresRepository) ResolveAlias(ctx context.Context, alias string) (*api.AccountAlias, error) {
+	alias = api.NormalizeAlias(alias)
+	if alias == "" || len(alias) > api.MaxAliasLength {
+		return nil, api.ErrAliasNotFound
+	}
+
+	registered, err := scanAlias(r.db.QueryRowContext(ctx, selectAlias, alias))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrAliasNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return registered, nil
+}
+
+// GetAccountAliases returns the aliases registered to the account, the oldest first.
+func (r *PostgresRepository) GetAccountAliases(ctx context.Context, accountID string) ([]*api.AccountAlias, error) {
+	rows, err := r.db.QueryContext(ctx, selectAccountAliases, strings.TrimSpace(accountID))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	aliases := []*api.AccountAlias{}
+
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		aliases = append(aliases, alias)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return aliases, nil
+}
+
+// DeleteAlias unregisters the alias, so it can be registered to another account.
+func (r *PostgresRepository) DeleteAlias(ctx context.Context, alias string) error {
+	result, err := r.db.ExecContext(ctx, deleteAlias, api.NormalizeAlias(alias))
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deleted == 0 {
+		return api.ErrAliasNotFound
+	}
+
+	return nil
+}
+
+func scanAlias(row rowScanner) (*api.AccountAlias, error) {
+	alias := &api.AccountAlias{}
+
+	err := row.Scan(&alias.Alias, &alias.Kind, &alias.AccountID, &alias.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	return alias, nil
+}
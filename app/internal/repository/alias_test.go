@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountAliases(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE account_aliases;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	t.Run("Register", func(t *testing.T) {
+		alias, err := repo.RegisterAlias(ctx, "aliased_user", &api.RegisterAliasRequest{Alias: " Jane@Example.com ", Kind: "email"})
+		require.NoError(t, err)
+		require.Equal(t, "jane@example.com", alias.Alias)
+		require.Equal(t, api.AliasEmail, alias.Kind)
+
+		again, err := repo.RegisterAlias(ctx, "aliased_user", &api.RegisterAliasRequest{Alias: "jane@example.com"})
+		require.NoError(t, err)
+		require.Equal(t, alias, again, "registering the alias again is a no-op")
+
+		_, err = repo.RegisterAlias(ctx, "aliased_user", &api.RegisterAliasRequest{Alias: "DE89 3704 0044 0532 0130 00", Kind: api.AliasIBAN})
+		require.NoError(t, err)
+
+		_, err = repo.RegisterAlias(ctx, "other_user", &api.RegisterAliasRequest{Alias: "JANE@example.com"})
+		require.ErrorIs(t, err, api.ErrAliasTaken)
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		alias, err := repo.ResolveAlias(ctx, "de89370400440532013000")
+		require.NoError(t, err)
+		require.Equal(t, "aliased_user", alias.AccountID)
+
+		_, err = repo.ResolveAlias(ctx, "unknown")
+		require.ErrorIs(t, err, api.ErrAliasNotFound)
+
+		aliases, err := repo.GetAccountAliases(ctx, "aliased_user")
+		require.NoError(t, err)
+		require.Len(t, aliases, 2)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repo.DeleteAlias(ctx, "Jane@Example.com"))
+		require.ErrorIs(t, repo.DeleteAlias(ctx, "jane@example.com"), api.ErrAliasNotFound)
+
+		_, err := repo.RegisterAlias(ctx, "other_user", &api.RegisterAliasRequest{Alias: "jane@example.com"})
+		require.NoError(t, err, "a deleted alias can be registered to another account")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := repo.RegisterAlias(ctx, "aliased_user", &api.RegisterAliasRequest{Alias: "   "})
+		require.ErrorIs(t, err, api.ErrInvalidAlias)
+
+		_, err = repo.RegisterAlias(ctx, "aliased_user", &api.RegisterAliasRequest{Alias: "12345", Kind: "PHONE"})
+		require.ErrorIs(t, err, api.ErrInvalidAlias)
+
+		_, err = repo.RegisterAlias(ctx, api.CompanyAccountID, &api.RegisterAliasRequest{Alias: "12345"})
+		require.ErrorIs(t, err, api.ErrCompanyAccount)
+	})
+}
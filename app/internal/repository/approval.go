@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	selectPendingTransferStatus = `SELECT status FROM pending_transfers WHERE idempotency_key = $1`
+
+	// a retried transfer finds its pending transfer by the idempotency key, so it's never held twice
+	insertPendingTransfer = `INSERT INTO pending_transfers
+		(id, idempotency_key, from_account_id, to_account_id, currency, amount, remarks, status, created_at, tags, to_currency)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, NULLIF($11, ''))
+		ON CONFLICT (idempotency_key) DO NOTHING`
+
+	selectPendingTransfer = `SELECT id, idempotency_key, from_account_id, to_account_id, currency, amount,
+			COALESCE(remarks, ''), tags, status, created_at, decided_at, COALESCE(decided_by, ''), COALESCE(to_currency, '')
+		FROM pending_transfers
+		WHERE id = $1`
+
+	selectPendingTransfers = `SELECT id, idempotency_key, from_account_id, to_account_id, currency, amount,
+			COALESCE(remarks, ''), tags, status, created_at, decided_at, COALESCE(decided_by, ''), COALESCE(to_currency, '')
+		FROM pending_transfers
+		WHERE status = $1
+		ORDER BY created_at
+		LIMIT $2`
+
+	// only a pending transfer can be decided, so two operators can't both decide it
+	decidePendingTransfer = `UPDATE pending_transfers SET status = $2, decided_at = $3, decided_by = $4
+		WHERE id = $1 AND status = 'PENDING'
+		RETURNING idempotency_key, from_account_id, to_account_id, currency, amount, COALESCE(remarks, ''), tags, COALESCE(to_currency, '')`
+
+	reopenPendingTransfer = `UPDATE pending_transfers SET status = 'PENDING', decided_at = NULL, decided_by = NULL WHERE id = $1`
+)
+
+// WithApprovalThresholds holds the transfers above the threshold of their currency until an operator approves them.
+// The currencies without a threshold are posted right away.
+func (r *PostgresRepository) WithApprovalThresholds(thresholds map[string]decimal.Decimal) *PostgresRepository {
+	r.approvalThresholds = make(map[string]decimal.Decimal, len(thresholds))
+
+	for currency, threshold := range thresholds {
+		r.approvalThresholds[strings.ToUpper(strings.TrimSpace(currency))] = threshold
+	}
+
+	return r
+}
+
+// holdForApproval returns api.ErrTransferPendingApproval if the transfer is above the threshold of its currency,
+// and api.ErrTransferRejected if it was rejected.
+func (r *PostgresRepository) holdForApproval(ctx context.Context, request *api.TransferRequest, idempotencyKey string) error {
+	threshold, ok := r.approvalThresholds[request.Currency]
+	if !ok || request.Amount.LessThanOrEqual(threshold) {
+		return nil
+	}
+
+	var status api.ReviewStatus
+
+	err := r.db.QueryRowContext(ctx, selectPendingTransferStatus, idempotencyKey).Scan(&status)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return formatUnknownError(err)
+	case status == api.ReviewRejected:
+		return api.ErrTransferRejected
+	default:
+		// an approved transfer is being posted, the retry finds it posted once it's done
+		return api.ErrTransferPendingApproval
+	}
+
+	_, err = r.db.ExecContext(ctx, insertPendingTransfer, r.idGenerator.NewID(), idempotencyKey, request.FromAccountID, request.ToAccountID,
+		request.Currency, request.Amount, request.Remarks, api.ReviewPending, r.clock.Now(), pq.Array(tagsOf(request.Tags)), request.ToCurrency)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	return api.ErrTransferPendingApproval
+}
+
+// GetPendingTransfer returns the transfer held for its approval.
+func (r *PostgresRepository) GetPendingTransfer(ctx context.Context, id string) (*api.PendingTransfer, error) {
+	if !validID(id) {
+		return nil, api.ErrPendingTransferNotFound
+	}
+
+	transfer, err := scanPendingTransfer(r.db.QueryRowContext(ctx, selectPendingTransfer, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrPendingTransferNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return transfer, nil
+}
+
+// ListPendingTransfers returns the transfers held for their approval in the status, at most limit, the oldest first.
+func (r *PostgresRepository) ListPendingTransfers(ctx context.Context, status api.ReviewStatus, limit int) ([]*api.PendingTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, selectPendingTransfers, status, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	transfers := []*api.PendingTransfer{}
+
+	for rows.Next() {
+		transfer, err := scanPendingTransfer(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return transfers, nil
+}
+
+// ApprovePendingTransfer posts the held transfer on behalf of the operator, and returns its ledger entries.
+// If the transfer fails, i.e. the balance is now insufficient, it stays pending.
+func (r *PostgresRepository) ApprovePendingTransfer(ctx context.Context, id, operator string) ([]*api.Transaction, error) {
+	request, idempotencyKey, err := r.decidePendingTransfer(ctx, id, operator, api.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	// the transfer was screened before it was held
+	txs, err := r.transfer(ctx, request, idempotencyKey, holds{})
+	if err != nil {
+		if _, reopenErr := r.db.ExecContext(ctx, reopenPendingTransfer, id); reopenErr != nil {
+			return nil, errors.Join(err, formatUnknownError(reopenErr))
+		}
+
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// RejectPendingTransfer rejects the held transfer for good on behalf of the operator,
+// its retries fail with api.ErrTransferRejected.
+func (r *PostgresRepository) RejectPendingTransfer(ctx context.Context, id, operator string) error {
+	_, _, err := r.decidePendingTransfer(ctx, id, operator, api.ReviewRejected)
+
+	return err
+}
+
+// decidePendingTransfer moves the pending transfer to the status, and returns the transfer and its idempotency key.
+func (r *PostgresRepository) decidePendingTransfer(ctx context.Context, id, operator string, status api.ReviewStatus) (*api.TransferRequest, string, error) {
+	if !validID(id) {
+		return nil, "", api.ErrPendingTransferNotFound
+	}
+
+	request := &api.TransferRequest{}
+
+	var idempotencyKey string
+
+	err := r.db.QueryRowContext(ctx, decidePendingTransfer, id, status, r.clock.Now(), operator).
+		Scan(&idempotencyKey, &request.FromAccountID, &request.ToAccountID, &request.Currency, &request.Amount, &request.Remarks, pq.Array(&request.Tags), &request.ToCurrency)
+	if err == nil {
+		return request, idempotencyKey, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, "", formatUnknownError(err)
+	}
+
+	// either there's no such transfer, or it's no longer pending
+	if _, err = r.GetPendingTransfer(ctx, id); err != nil {
+		return nil, "", err
+	}
+
+	return nil, "", api.ErrPendingTransferDecided
+}
+
+func scanPendingTransfer(row rowScanner) (*api.PendingTransfer, error) {
+	transfer := &api.PendingTransfer{}
+
+	var decidedAt sql.NullTime
+
+	err := row.Scan(&transfer.ID, &transfer.IdempotencyKey, &transfer.FromAccountID, &transfer.ToAccountID, &transfer.Currency,
+		&transfer.Amount, &transfer.Remarks, pq.Array(&transfer.Tags), &transfer.Status, &transfer.CreatedAt, &decidedAt, &transfer.DecidedBy, &transfer.ToCurrency)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if decidedAt.Valid {
+		transfer.DecidedAt = &decidedAt.Time
+	}
+
+	return transfer, nil
+}
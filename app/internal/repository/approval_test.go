@@ -0,0 +1,160 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/screening"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalThresholds(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE pending_transfers, transfer_reviews;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).
+		WithApprovalThresholds(map[string]decimal.Decimal{"usd": decimal.NewFromInt(1000)})
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "large_sender",
+		Currency:      "EUR",
+		Amount:        decimal.NewFromInt(5000),
+	}, "approval-deposit-eur")
+	require.NoError(t, err, "the currencies without a threshold are posted")
+
+	large := func(amount int64) *api.TransferRequest {
+		return &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "large_sender",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(amount),
+		}
+	}
+
+	t.Run("At the threshold", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, large(1000), "approval-at-threshold")
+		require.NoError(t, err)
+	})
+
+	var pendingID string
+
+	t.Run("Above the threshold", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, large(5000), "approval-large")
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+
+		_, err = repo.Transfer(ctx, large(5000), "approval-large")
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+
+		transfers, err := repo.ListPendingTransfers(ctx, api.ReviewPending, 10)
+		require.NoError(t, err)
+		require.Len(t, transfers, 1)
+		require.Equal(t, "approval-large", transfers[0].IdempotencyKey)
+
+		pendingID = transfers[0].ID
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "large_sender")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(1000).Equal(account.Balance))
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		txs, err := repo.ApprovePendingTransfer(ctx, pendingID, "key-operator2")
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+
+		transfer, err := repo.GetPendingTransfer(ctx, pendingID)
+		require.NoError(t, err)
+		require.Equal(t, api.ReviewApproved, transfer.Status)
+		require.Equal(t, "key-operator2", transfer.DecidedBy)
+		require.NotNil(t, transfer.DecidedAt)
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "large_sender")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(6000).Equal(account.Balance))
+
+		_, err = repo.ApprovePendingTransfer(ctx, pendingID, "key-operator2")
+		require.ErrorIs(t, err, api.ErrPendingTransferDecided)
+
+		_, err = repo.Transfer(ctx, large(5000), "approval-large")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
+	})
+
+	t.Run("Approve with insufficient balance", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "large_sender",
+			ToAccountID:   "large_recipient",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(6000),
+		}, "approval-spend")
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+
+		_, err = repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "large_sender",
+			ToAccountID:   "large_recipient",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(500),
+		}, "approval-small-spend")
+		require.NoError(t, err)
+
+		transfers, err := repo.ListPendingTransfers(ctx, api.ReviewPending, 10)
+		require.NoError(t, err)
+		require.Len(t, transfers, 1)
+
+		_, err = repo.ApprovePendingTransfer(ctx, transfers[0].ID, "key-operator2")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+		transfer, err := repo.GetPendingTransfer(ctx, transfers[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, api.ReviewPending, transfer.Status)
+		require.Empty(t, transfer.DecidedBy)
+
+		require.NoError(t, repo.RejectPendingTransfer(ctx, transfers[0].ID, "key-operator2"))
+
+		_, err = repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "large_sender",
+			ToAccountID:   "large_recipient",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(6000),
+		}, "approval-spend")
+		require.ErrorIs(t, err, api.ErrTransferRejected)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := repo.GetPendingTransfer(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrPendingTransferNotFound)
+
+		require.ErrorIs(t, repo.RejectPendingTransfer(ctx, "00000000-0000-4000-8000-000000000000", "key-operator2"), api.ErrPendingTransferNotFound)
+	})
+
+	t.Run("Screened first", func(t *testing.T) {
+		repo := repository.NewPostgresRepository(db).
+			WithScreening(screening.NewDenylist([]string{"flagged_recipient"}, nil)).
+			WithApprovalThresholds(map[string]decimal.Decimal{"USD": decimal.NewFromInt(1000)})
+
+		request := large(2000)
+		request.ToAccountID = "flagged_recipient"
+
+		_, err := repo.Transfer(ctx, request, "approval-screened")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview)
+
+		// the reviewed transfer still waits for its approval
+		_, err = repo.ApproveTransferReview(ctx, "approval-screened")
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+
+		review, err := repo.GetTransferReview(ctx, "approval-screened")
+		require.NoError(t, err)
+		require.Equal(t, api.ReviewApproved, review.Status)
+	})
+}
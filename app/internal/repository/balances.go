@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// the pairs are matched in GetAccountBalances, the query only narrows down the rows with the index of the accounts
+	selectBalancesOf = `SELECT user_id, currency, balance
+		FROM accounts
+		WHERE user_id = ANY($1) AND currency = ANY($2)`
+
+	selectCurrencyBalances = `SELECT currency, balance FROM accounts WHERE user_id = $1 ORDER BY currency`
+)
+
+// GetAccountBalances reads the balances of the accounts in a single query, up to api.MaxBalancesQuery of them.
+// The accounts that don't exist are listed in the NotFound of the result, except the company account, whose balance is 0.
+func (r *PostgresRepository) GetAccountBalances(ctx context.Context, accounts []api.AccountKey) (*api.Balances, error) {
+	if len(accounts) == 0 || len(accounts) > api.MaxBalancesQuery {
+		return nil, api.ErrInvalidRequest
+	}
+
+	keys := make([]api.AccountKey, 0, len(accounts))
+	seen := make(map[api.AccountKey]bool, len(accounts))
+
+	for _, account := range accounts {
+		key := api.AccountKey{
+			AccountID: strings.TrimSpace(account.AccountID),
+			Currency:  strings.ToUpper(strings.TrimSpace(account.Currency)),
+		}
+
+		if err := validateCurrencyAndAccount(key.Currency, key.AccountID); err != nil {
+			return nil, err
+		}
+
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	accountIDs := make([]string, 0, len(keys))
+	currencies := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		accountIDs = append(accountIDs, key.AccountID)
+		currencies = append(currencies, key.Currency)
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectBalancesOf, pq.Array(accountIDs), pq.Array(currencies))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	balances := make(map[api.AccountKey]decimal.Decimal, len(keys))
+
+	for rows.Next() {
+		var (
+			key     api.AccountKey
+			balance decimal.Decimal
+		)
+
+		if err = rows.Scan(&key.AccountID, &key.Currency, &balance); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		// the rows of the other pairs of the ids and the currencies are skipped
+		if seen[key] {
+			balances[key] = balance
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	result := &api.Balances{
+		Balances: make([]*api.Account, 0, len(keys)),
+		NotFound: []api.AccountKey{},
+	}
+
+	for _, key := range keys {
+		balance, found := balances[key]
+
+		// like GetAccountBalance, the company account exists before its first transfer
+		if !found && !r.isCompanyAccount(key.AccountID) {
+			result.NotFound = append(result.NotFound, key)
+
+			continue
+		}
+
+		result.Balances = append(result.Balances, &api.Account{
+			AccountID: key.AccountID,
+			Currency:  key.Currency,
+			Balance:   balance,
+		})
+	}
+
+	return result, nil
+}
+
+// GetAccountSummary reads the balances of the account in every currency it holds, in a single query, by currency.
+// An account without any balance doesn't exist, except the company account, which holds none before its first transfer.
+func (r *PostgresRepository) GetAccountSummary(ctx context.Context, accountID string) (*api.AccountSummary, error) {
+	accountID = strings.TrimSpace(accountID)
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectCurrencyBalances, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	summary := &api.AccountSummary{
+		AccountID: accountID,
+		Balances:  []*api.Account{},
+	}
+
+	for rows.Next() {
+		account := &api.Account{AccountID: accountID}
+
+		if err = rows.Scan(&account.Currency, &account.Balance); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		summary.Balances = append(summary.Balances, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if len(summary.Balances) == 0 && !r.isCompanyAccount(accountID) {
+		return nil, fmt.Errorf("failed to get the balances of %s: %w", accountID, api.ErrAccountNotFound)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,108 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalances(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	for _, deposit := range []struct {
+		accountID, currency, idempotencyKey string
+		amount                              int64
+	}{
+		{"balances_a", "USD", "balances-1", 10},
+		{"balances_a", "EUR", "balances-2", 20},
+		{"balances_b", "USD", "balances-3", 30},
+	} {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   deposit.accountID,
+			Currency:      deposit.currency,
+			Amount:        decimal.NewFromInt(deposit.amount),
+		}, deposit.idempotencyKey)
+		require.NoError(t, err)
+	}
+
+	balances, err := repo.GetAccountBalances(ctx, []api.AccountKey{
+		{AccountID: "balances_b", Currency: "usd"},
+		{AccountID: " balances_a ", Currency: "EUR"},
+		{AccountID: "balances_b", Currency: "EUR"},
+		{AccountID: "balances_b", Currency: "USD"},
+		{AccountID: "balances_unknown", Currency: "USD"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, balances.Balances, 2, "the duplicates are read once")
+	require.Equal(t, "balances_b", balances.Balances[0].AccountID)
+	require.Equal(t, "USD", balances.Balances[0].Currency)
+	require.True(t, balances.Balances[0].Balance.Equal(decimal.NewFromInt(30)))
+	require.Equal(t, "balances_a", balances.Balances[1].AccountID)
+	require.True(t, balances.Balances[1].Balance.Equal(decimal.NewFromInt(20)))
+
+	require.Equal(t, []api.AccountKey{
+		{AccountID: "balances_b", Currency: "EUR"},
+		{AccountID: "balances_unknown", Currency: "USD"},
+	}, balances.NotFound, "not matched by the other pairs")
+
+	_, err = repo.GetAccountBalances(ctx, nil)
+	require.ErrorIs(t, err, api.ErrInvalidRequest)
+
+	_, err = repo.GetAccountBalances(ctx, make([]api.AccountKey, api.MaxBalancesQuery+1))
+	require.ErrorIs(t, err, api.ErrInvalidRequest)
+
+	_, err = repo.GetAccountBalances(ctx, []api.AccountKey{{AccountID: "balances_a"}})
+	require.ErrorIs(t, err, api.ErrInvalidCurrency)
+}
+
+func TestGetAccountSummary(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	for _, deposit := range []struct {
+		currency, idempotencyKey string
+		amount                   int64
+	}{
+		{"USD", "summary-1", 10},
+		{"EUR", "summary-2", 20},
+		{"USD", "summary-3", 5},
+	} {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "summary_a",
+			Currency:      deposit.currency,
+			Amount:        decimal.NewFromInt(deposit.amount),
+		}, deposit.idempotencyKey)
+		require.NoError(t, err)
+	}
+
+	summary, err := repo.GetAccountSummary(ctx, " summary_a ")
+	require.NoError(t, err)
+	require.Equal(t, "summary_a", summary.AccountID)
+	require.Len(t, summary.Balances, 2)
+	require.Equal(t, "EUR", summary.Balances[0].Currency, "by currency")
+	require.True(t, summary.Balances[0].Balance.Equal(decimal.NewFromInt(20)))
+	require.Equal(t, "USD", summary.Balances[1].Currency)
+	require.True(t, summary.Balances[1].Balance.Equal(decimal.NewFromInt(15)))
+
+	_, err = repo.GetAccountSummary(ctx, "summary_unknown")
+	require.ErrorIs(t, err, api.ErrAccountNotFound)
+
+	_, err = repo.GetAccountSummary(ctx, " ")
+	require.ErrorIs(t, err, api.ErrInvalidAccountID)
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	// the ledger entries of the transfers of a batch
+	selectTransactionsByIDs = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE transactions.id = ANY($1)`
+
+	// the size of transactions.group_id
+	maxGroupIDLength = 50
+)
+
+// TransferBatch posts the transfers within a single database transaction, so either all of them are posted or none is,
+// i.e. a payroll. The transfer at index i is grouped under api.BatchGroupID(idempotencyKey, i), and the transfer
+// failing the batch is identified by an api.BatchTransferError.
+// A batch can't be held for a decision: a transfer the screening or the approval threshold would hold fails it
+// with api.ErrBatchTransferHeld, without being recorded for a review. Each transfer is charged its fee, see WithFees,
+// grouped under api.FeeGroupID of its group.
+func (r *PostgresRepository) TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	receipt, err := r.transferBatch(ctx, requests, idempotencyKey)
+	if errors.Is(err, api.ErrUnhandledDatabaseError) {
+		r.logger.ErrorContext(ctx, "failed to post the transfer batch", slog.String("idempotency_key", idempotencyKey),
+			slog.Int("transfers", len(requests)), slog.Any("error", err))
+	}
+
+	return receipt, err
+}
+
+func (r *PostgresRepository) transferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (receipt *api.TransferBatchReceipt, err error) {
+	if strings.TrimSpace(idempotencyKey) == "" {
+		return nil, api.ErrMissingIdempotencyKey
+	}
+
+	if len(requests) == 0 || len(requests) > api.MaxBatchTransfers {
+		return nil, fmt.Errorf("%w: from 1 to %d transfers", api.ErrInvalidBatch, api.MaxBatchTransfers)
+	}
+
+	if len(api.BatchGroupID(idempotencyKey, len(requests)-1)) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is too long for %d transfers", api.ErrInvalidBatch, len(requests))
+	}
+
+	groupIDs := make([]string, len(requests))
+	for i := range requests {
+		groupIDs[i] = api.BatchGroupID(idempotencyKey, i)
+	}
+
+	defer func() {
+		r.afterTransferBatch(ctx, requests, groupIDs, receipt, err)
+	}()
+
+	for i, request := range requests {
+		if request == nil {
+			return nil, &api.BatchTransferError{Index: i, Err: api.ErrInvalidRequest}
+		}
+
+		if err = r.validateTransfer(ctx, request); err != nil {
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		if err = rejectConversion(request, "batches"); err != nil {
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+	}
+
+	// the transfers of a batch are posted together, so the first one tells whether the batch is
+	var existingCount int
+	if err = r.db.QueryRowContext(ctx, selectGroupExists, groupIDs[0]).Scan(&existingCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		return nil, &api.DuplicateTransactionError{GroupID: groupIDs[0]}
+	}
+
+	fees := make([]*charge, len(requests))
+
+	for i, request := range requests {
+		if err = r.checkUnheldTransfer(ctx, request, api.ErrBatchTransferHeld); err != nil {
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		if fees[i], err = r.quoteFee(ctx, request, groupIDs[i]); err != nil {
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		if err = r.openFeesAccount(ctx, fees[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	txIDs, err := r.postBatch(ctx, requests, groupIDs, fees)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.batchReceipt(ctx, requests, idempotencyKey, groupIDs, txIDs, fees)
+}
+
+// checkUnheldTransfer checks the quota of a transfer that can't be held for a decision, then opens its accounts.
+// It fails with the held error when the screening or the approval threshold would hold it.
+func (r *PostgresRepository) checkUnheldTransfer(ctx context.Context, request *api.TransferRequest, held error) error {
+	if err := r.checkSandboxQuota(ctx, request); err != nil {
+		return err
+	}
+
+	if threshold, ok := r.approvalThresholds[request.Currency]; ok && request.Amount.GreaterThan(threshold) {
+		return held
+	}
+
+	if r.screening != nil {
+		result, err := r.screening.Screen(ctx, request)
+		if err != nil {
+			return fmt.Errorf("%w: %w", api.ErrScreeningFailed, err)
+		}
+
+		if result.Flagged {
+			return fmt.Errorf("%w: %s", held, result.Reason)
+		}
+	}
+
+	return r.upsertAccounts(ctx, request)
+}
+
+// postBatch posts the double entries of the transfers and of their fees within a single transaction, and returns the ids
+// of the entries of the transfers. The fees account is locked last, like by the single transfers.
+func (r *PostgresRepository) postBatch(ctx context.Context, requests []*api.TransferRequest, groupIDs []string, fees []*charge) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.lockBatchAccounts(ctx, tx, requests); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	txIDs := make([]string, 0, 2*len(requests)) //nolint:mnd // the double entries
+
+	for i, request := range requests {
+		if err = r.beforeTransfer(ctx, tx, request, groupIDs[i]); err != nil {
+			_ = tx.Rollback()
+
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		fromTxID, toTxID, err := r.postDoubleEntry(ctx, tx, request, groupIDs[i])
+		if err != nil {
+			_ = tx.Rollback()
+
+			// the same batch posted concurrently
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return nil, &api.DuplicateTransactionError{GroupID: groupIDs[0]}
+			}
+
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		if err = r.postFee(ctx, tx, fees[i], groupIDs[i]); err != nil {
+			_ = tx.Rollback()
+
+			return nil, &api.BatchTransferError{Index: i, Err: err}
+		}
+
+		txIDs = append(txIDs, fromTxID, toTxID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return txIDs, nil
+}
+
+// lockBatchAccounts locks every account of the batch up front, in the order of their ids, so two batches crossing
+// the same accounts don't deadlock whatever the order of their transfers. The transfers then lock them again,
+// which they already hold.
+func (r *PostgresRepository) lockBatchAccounts(ctx context.Context, tx *sql.Tx, requests []*api.TransferRequest) error {
+	keys := make([]api.AccountKey, 0, 2*len(requests)) //nolint:mnd // both accounts of each transfer
+
+	for _, request := range requests {
+		keys = append(keys,
+			api.AccountKey{AccountID: request.FromAccountID, Currency: request.Currency},
+			api.AccountKey{AccountID: request.ToAccountID, Currency: request.Currency})
+	}
+
+	slices.SortFunc(keys, func(a, b api.AccountKey) int {
+		return strings.Compare(a.AccountID+"/"+a.Currency, b.AccountID+"/"+b.Currency)
+	})
+
+	query := selectLockAccount
+	if r.lockStrategy == LockAdvisory {
+		query = lockAccountAdvisory
+	}
+
+	for _, key := range slices.Compact(keys) {
+		if _, err := tx.ExecContext(ctx, query, key.AccountID, key.Currency); err != nil {
+			return formatUnknownError(err)
+		}
+	}
+
+	return nil
+}
+
+// batchReceipt reads the posted entries, and pairs them by transfer, the debits with the breakdown of their fee.
+func (r *PostgresRepository) batchReceipt(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string, groupIDs, txIDs []string, fees []*charge) (*api.TransferBatchReceipt, error) {
+	rows, err := r.db.QueryContext(ctx, selectTransactionsByIDs, pq.Array(txIDs))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	transactions, err := r.readTransactions(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]*api.Transaction, len(requests))
+	for _, transaction := range transactions {
+		entries[transaction.GroupID] = append(entries[transaction.GroupID], transaction)
+	}
+
+	receipt := &api.TransferBatchReceipt{
+		IdempotencyKey: idempotencyKey,
+		Transfers:      make([]*api.TransferReceipt, len(requests)),
+	}
+
+	for i, request := range requests {
+		// the entries of the transfer at i are txIDs[2*i], its debit, and txIDs[2*i+1]
+		txs := withFee(entries[groupIDs[i]], txIDs[2*i], fees[i]) //nolint:mnd // the double entries
+
+		if receipt.Transfers[i], err = api.NewTransferReceipt(request, groupIDs[i], txs); err != nil {
+			return nil, err //nolint:wrapcheck // the domain errors are returned as is
+		}
+	}
+
+	return receipt, nil
+}
+
+// afterTransferBatch calls the hooks with the outcome of each transfer of the batch, i.e. the error of the batch.
+func (r *PostgresRepository) afterTransferBatch(ctx context.Context, requests []*api.TransferRequest, groupIDs []string, receipt *api.TransferBatchReceipt, err error) {
+	if len(r.hooks) == 0 {
+		return
+	}
+
+	for i, request := range requests {
+		if request == nil {
+			continue
+		}
+
+		var txs []*api.Transaction
+		if receipt != nil {
+			txs = []*api.Transaction{receipt.Transfers[i].Debit, receipt.Transfers[i].Credit}
+		}
+
+		r.afterTransfer(ctx, request, groupIDs[i], txs, err)
+	}
+}
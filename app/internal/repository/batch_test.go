@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferBatch(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "batch_payer",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "batch-funding")
+	require.NoError(t, err)
+
+	balance := func(accountID string) decimal.Decimal {
+		t.Helper()
+
+		account, err := repo.GetAccountBalance(ctx, "USD", accountID)
+		if err != nil {
+			return decimal.Zero
+		}
+
+		return account.Balance
+	}
+
+	t.Run("Posts every transfer", func(t *testing.T) {
+		receipt, err := repo.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_1", Currency: "usd", Amount: decimal.NewFromInt(30), Remarks: "May"},
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_2", Currency: "USD", Amount: decimal.NewFromInt(20)},
+			// an account paid by the batch may pay in turn
+			{FromAccountID: "batch_payee_1", ToAccountID: "batch_payee_2", Currency: "USD", Amount: decimal.NewFromInt(5)},
+		}, "payroll-1")
+		require.NoError(t, err)
+
+		require.Equal(t, "payroll-1", receipt.IdempotencyKey)
+		require.Len(t, receipt.Transfers, 3)
+
+		for i, transfer := range receipt.Transfers {
+			require.Equal(t, api.BatchGroupID("payroll-1", i), transfer.GroupID)
+			require.Equal(t, transfer.GroupID, transfer.Debit.GroupID)
+			require.Equal(t, transfer.GroupID, transfer.Credit.GroupID)
+		}
+
+		require.Equal(t, "batch_payee_1", receipt.Transfers[0].Credit.AccountID)
+		require.Equal(t, "batch_payer", receipt.Transfers[0].Credit.Counterparty)
+		require.Equal(t, "May", receipt.Transfers[0].Credit.Remarks)
+
+		require.True(t, decimal.NewFromInt(50).Equal(balance("batch_payer")))
+		require.True(t, decimal.NewFromInt(25).Equal(balance("batch_payee_1")))
+		require.True(t, decimal.NewFromInt(25).Equal(balance("batch_payee_2")))
+	})
+
+	t.Run("Posts none when a transfer fails", func(t *testing.T) {
+		_, err := repo.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_3", Currency: "USD", Amount: decimal.NewFromInt(40)},
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_4", Currency: "USD", Amount: decimal.NewFromInt(40)},
+		}, "payroll-2")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+		failed := &api.BatchTransferError{}
+		require.ErrorAs(t, err, &failed)
+		require.Equal(t, 1, failed.Index)
+
+		require.True(t, decimal.NewFromInt(50).Equal(balance("batch_payer")), "rolled back")
+		require.True(t, balance("batch_payee_3").IsZero())
+	})
+
+	t.Run("Validates every transfer first", func(t *testing.T) {
+		_, err := repo.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_5", Currency: "USD", Amount: decimal.NewFromInt(1)},
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payer", Currency: "USD", Amount: decimal.NewFromInt(1)},
+		}, "payroll-3")
+		require.ErrorIs(t, err, api.ErrSameAccountIDs)
+
+		failed := &api.BatchTransferError{}
+		require.ErrorAs(t, err, &failed)
+		require.Equal(t, 1, failed.Index)
+
+		_, err = repo.GetAccountBalance(ctx, "USD", "batch_payee_5")
+		require.ErrorIs(t, err, api.ErrAccountNotFound, "not opened")
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		_, err := repo.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_1", Currency: "USD", Amount: decimal.NewFromInt(1)},
+		}, "payroll-1")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
+
+		duplicate := &api.DuplicateTransactionError{}
+		require.ErrorAs(t, err, &duplicate)
+		require.Equal(t, "payroll-1:0", duplicate.GroupID)
+	})
+
+	t.Run("Invalid batch", func(t *testing.T) {
+		_, err := repo.TransferBatch(ctx, nil, "payroll-4")
+		require.ErrorIs(t, err, api.ErrInvalidBatch)
+
+		_, err = repo.TransferBatch(ctx, make([]*api.TransferRequest, api.MaxBatchTransfers+1), "payroll-4")
+		require.ErrorIs(t, err, api.ErrInvalidBatch)
+
+		_, err = repo.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_1", Currency: "USD", Amount: decimal.NewFromInt(1)},
+		}, strings.Repeat("k", 49))
+		require.ErrorIs(t, err, api.ErrInvalidBatch, "the group ids don't fit")
+
+		_, err = repo.TransferBatch(ctx, []*api.TransferRequest{nil}, "payroll-4")
+		require.ErrorIs(t, err, api.ErrInvalidRequest)
+	})
+
+	t.Run("Held transfers fail the batch", func(t *testing.T) {
+		held := repository.NewPostgresRepository(db).
+			WithApprovalThresholds(map[string]decimal.Decimal{"USD": decimal.NewFromInt(10)})
+
+		_, err := held.TransferBatch(ctx, []*api.TransferRequest{
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_1", Currency: "USD", Amount: decimal.NewFromInt(5)},
+			{FromAccountID: "batch_payer", ToAccountID: "batch_payee_2", Currency: "USD", Amount: decimal.NewFromInt(15)},
+		}, "payroll-5")
+		require.ErrorIs(t, err, api.ErrBatchTransferHeld)
+
+		pending, err := held.ListPendingTransfers(ctx, api.ReviewPending, 10)
+		require.NoError(t, err)
+		require.Empty(t, pending, "not held")
+	})
+}
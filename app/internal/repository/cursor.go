@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/google/uuid"
+)
+
+const (
+	// the entries after the cursor, by the time then the id, so the entries posted at the same time are all read once
+	selectTransactionsAfter = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.currency = $1 AND accounts.user_id = $2
+			AND (transactions.created_at, transactions.id) > ($3::TIMESTAMP, $4::UUID)
+			AND transactions.created_at < $5
+		ORDER BY transactions.created_at, transactions.id
+		LIMIT $6`
+
+	// the page of the entries matching the filter before the cursor, most recent first
+	selectTransactionsPage = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND ` + transactionFilterConditions + `
+			AND ($7::BOOLEAN OR (transactions.created_at, transactions.id) < ($8::TIMESTAMP, $9::UUID))
+		ORDER BY transactions.created_at DESC, transactions.id DESC
+		LIMIT $10`
+
+	// the entries matching the filter after the cursor, the oldest first
+	selectTransactionsStream = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND ` + transactionFilterConditions + `
+			AND (transactions.created_at, transactions.id) > ($7::TIMESTAMP, $8::UUID)
+		ORDER BY transactions.created_at, transactions.id`
+
+	countTransactions = `
+		SELECT COUNT(*)
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND ` + transactionFilterConditions
+)
+
+// GetTransactionsAfter returns the entries of the account in the currency after the cursor, see api.TransactionCursor,
+// and posted before until, at most limit, the oldest first.
+// The entries are timed before they're committed, so the readers following the ledger leave the recent ones out
+// with until, for the transfers in flight to commit.
+func (r *PostgresRepository) GetTransactionsAfter(ctx context.Context, currency, accountID, cursor string, until time.Time, limit int) ([]*api.Transaction, error) {
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	after, txID, err := api.ParseTransactionCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	afterID := uuid.Nil
+	if txID != "" {
+		if afterID, err = uuid.Parse(txID); err != nil {
+			return nil, api.ErrInvalidCursor
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectTransactionsAfter, currency, accountID, after.UTC(), afterID.String(), until.UTC(), limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.readTransactions(ctx, rows)
+}
+
+// GetTransactionsPage returns at most limit entries of the account in the currency matching the filter, most recent first,
+// from the cursor of the previous page, see api.TransactionCursor, or from the most recent without a cursor.
+// The page and its total count are read from the same snapshot.
+func (r *PostgresRepository) GetTransactionsPage(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, limit int) (*api.TransactionPage, error) {
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	args, err := r.filterArgs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var before time.Time
+
+	beforeID := uuid.Nil
+
+	if cursor != "" {
+		var txID string
+
+		if before, txID, err = api.ParseTransactionCursor(cursor); err != nil {
+			return nil, err
+		}
+
+		if beforeID, err = uuid.Parse(txID); err != nil {
+			return nil, api.ErrInvalidCursor
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	// read-only, there is nothing to commit
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	page := &api.TransactionPage{}
+
+	args = append([]any{currency, accountID}, args...)
+
+	if err = tx.QueryRowContext(ctx, countTransactions, args...).Scan(&page.TotalCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	// one more than the page, to tell whether there's a next one
+	rows, err := tx.QueryContext(ctx, selectTransactionsPage, append(args, cursor == "", before.UTC(), beforeID.String(), limit+1)...)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if page.Transactions, err = scanTransactions(rows); err != nil {
+		return nil, err
+	}
+
+	if len(page.Transactions) > limit {
+		page.Transactions = page.Transactions[:limit]
+		page.NextCursor = api.TransactionCursor(page.Transactions[limit-1])
+	}
+
+	// the remarks are decrypted outside of the snapshot, which holds its connection
+	_ = tx.Rollback()
+
+	if err = r.openTransactions(ctx, page.Transactions...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// StreamTransactions calls fn with each entry of the account in the currency matching the filter, the oldest first,
+// after the cursor, see api.TransactionCursor, or from the first one without a cursor. The entries are read from
+// a snapshot of the ledger as fn consumes them, so the ledgers of any size aren't held in memory.
+// It stops at the first error of fn, and returns it.
+func (r *PostgresRepository) StreamTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, fn func(*api.Transaction) error) error {
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return err
+	}
+
+	args, err := r.filterArgs(filter)
+	if err != nil {
+		return err
+	}
+
+	var after time.Time
+
+	afterID := uuid.Nil
+
+	if cursor != "" {
+		var txID string
+
+		if after, txID, err = api.ParseTransactionCursor(cursor); err != nil {
+			return err //nolint:wrapcheck // the domain errors are returned as is
+		}
+
+		if afterID, err = uuid.Parse(txID); err != nil {
+			return api.ErrInvalidCursor
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	// read-only, there is nothing to commit
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, selectTransactionsStream, append([]any{currency, accountID}, append(args, after.UTC(), afterID.String())...)...)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	// the entries of an account are encrypted with the same keys, loaded once
+	keys := dataKeys{}
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return formatUnknownError(err)
+		}
+
+		if err = r.openRemarks(ctx, keys, &transaction.Remarks); err != nil {
+			return err
+		}
+
+		if err = fn(transaction); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTransactionsAfter(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+	start := time.Now().Add(-time.Minute)
+
+	for i := 1; i <= 2; i++ {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "cursor_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(int64(i)),
+		}, fmt.Sprintf("cursor-%d", i))
+		require.NoError(t, err)
+	}
+
+	until := time.Now().Add(time.Minute)
+
+	first, err := repo.GetTransactionsAfter(ctx, "USD", "cursor_user", api.NewTransactionCursor(start, ""), until, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	require.True(t, decimal.NewFromInt(1).Equal(first[0].Amount))
+
+	second, err := repo.GetTransactionsAfter(ctx, "USD", "cursor_user", api.TransactionCursor(first[0]), until, 10)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	require.True(t, decimal.NewFromInt(2).Equal(second[0].Amount))
+
+	none, err := repo.GetTransactionsAfter(ctx, "USD", "cursor_user", api.TransactionCursor(second[0]), until, 10)
+	require.NoError(t, err)
+	require.Empty(t, none)
+
+	// the entries posted since until are left out
+	settling, err := repo.GetTransactionsAfter(ctx, "USD", "cursor_user", api.NewTransactionCursor(start, ""), start, 10)
+	require.NoError(t, err)
+	require.Empty(t, settling)
+
+	_, err = repo.GetTransactionsAfter(ctx, "USD", "cursor_user", "not-a-cursor!", until, 10)
+	require.ErrorIs(t, err, api.ErrInvalidCursor)
+
+	_, err = repo.GetTransactionsAfter(ctx, "USD", "", api.NewTransactionCursor(start, ""), until, 10)
+	require.ErrorIs(t, err, api.ErrInvalidAccountID)
+}
+
+func TestGetTransactionsPage(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	for i := 1; i <= 3; i++ {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "page_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(int64(i)),
+			Tags:          []string{fmt.Sprintf("tag-%d", i%2)},
+		}, fmt.Sprintf("page-%d", i))
+		require.NoError(t, err)
+	}
+
+	var amounts []int64
+
+	cursor := ""
+
+	for {
+		page, err := repo.GetTransactionsPage(ctx, "USD", "page_user", api.TransactionFilter{}, cursor, 2)
+		require.NoError(t, err)
+		require.Equal(t, 3, page.TotalCount)
+
+		for _, transaction := range page.Transactions {
+			amounts = append(amounts, transaction.Amount.IntPart())
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+
+		cursor = page.NextCursor
+	}
+
+	require.Equal(t, []int64{3, 2, 1}, amounts, "every entry once, most recent first")
+
+	tagged, err := repo.GetTransactionsPage(ctx, "USD", "page_user", api.TransactionFilter{Tags: []string{"tag-1"}}, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, tagged.TotalCount)
+	require.Len(t, tagged.Transactions, 2)
+	require.Empty(t, tagged.NextCursor)
+
+	_, err = repo.GetTransactionsPage(ctx, "USD", "page_user", api.TransactionFilter{}, "not-a-cursor!", 10)
+	require.ErrorIs(t, err, api.ErrInvalidCursor)
+}
+
+func TestStreamTransactions(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	for i := 1; i <= 3; i++ {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "stream_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(int64(i)),
+			Remarks:       fmt.Sprintf("stream %d", i),
+		}, fmt.Sprintf("stream-%d", i))
+		require.NoError(t, err)
+	}
+
+	var (
+		amounts []int64
+		cursor  string
+	)
+
+	err := repo.StreamTransactions(ctx, "USD", "stream_user", api.TransactionFilter{}, "", func(transaction *api.Transaction) error {
+		amounts = append(amounts, transaction.Amount.IntPart())
+		require.Equal(t, fmt.Sprintf("stream %d", transaction.Amount.IntPart()), transaction.Remarks)
+
+		if cursor == "" {
+			cursor = api.TransactionCursor(transaction)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3}, amounts, "every entry once, the oldest first")
+
+	var resumed []int64
+
+	err = repo.StreamTransactions(ctx, "USD", "stream_user", api.TransactionFilter{}, cursor, func(transaction *api.Transaction) error {
+		resumed = append(resumed, transaction.Amount.IntPart())
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 3}, resumed, "after the cursor")
+
+	stop := errors.New("stop")
+
+	err = repo.StreamTransactions(ctx, "USD", "stream_user", api.TransactionFilter{}, "", func(*api.Transaction) error {
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+
+	err = repo.StreamTransactions(ctx, "USD", "stream_user", api.TransactionFilter{}, "not-a-cursor!", func(*api.Transaction) error {
+		return nil
+	})
+	require.ErrorIs(t, err, api.ErrInvalidCursor)
+}
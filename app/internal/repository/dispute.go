@@ -0,0 +1,439 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// uniqueViolation is the postgres error code of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// validID reports whether the id is a UUID, like the ids of the rows, so the lookups of the other ids are answered
+// as not found without querying.
+func validID(id string) bool {
+	_, err := uuid.Parse(id)
+
+	return err == nil
+}
+
+const (
+	// both entries of the transfer of the ledger entry
+	selectDisputedTransfer = `
+		SELECT transactions.group_id, accounts.user_id, accounts.currency, transactions.amount, transactions.debit_credit,
+			transactions.fx_rate IS NOT NULL
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE transactions.group_id = (SELECT group_id FROM transactions WHERE id = $1)`
+
+	selectReversedAmount = `SELECT COALESCE(SUM(amount), 0) FROM disputes WHERE group_id = $1 AND status = 'REVERSED'`
+
+	// the partial unique index rejects a second open dispute of the transfer
+	insertDispute = `INSERT INTO disputes
+		(id, group_id, tx_id, from_account_id, to_account_id, currency, amount, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10)`
+
+	insertDisputeEvent = `INSERT INTO dispute_events (dispute_id, status, group_id, operator, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)`
+
+	// only an open dispute can be resolved, so two operators can't both resolve it
+	resolveDispute = `UPDATE disputes SET status = $2, resolved_at = $3
+		WHERE id = $1 AND status = 'OPEN'
+		RETURNING from_account_id, to_account_id, currency, amount`
+
+	selectDispute = `SELECT id, tx_id, from_account_id, to_account_id, currency, amount,
+			COALESCE(reason, ''), status, created_at, resolved_at
+		FROM disputes
+		WHERE id = $1`
+
+	selectDisputes = `SELECT id, tx_id, from_account_id, to_account_id, currency, amount,
+			COALESCE(reason, ''), status, created_at, resolved_at
+		FROM disputes
+		WHERE status = $1
+		ORDER BY created_at
+		LIMIT $2`
+
+	selectTransferDisputes = `SELECT id, tx_id, from_account_id, to_account_id, currency, amount,
+			COALESCE(reason, ''), status, created_at, resolved_at
+		FROM disputes
+		WHERE group_id = (SELECT group_id FROM transactions WHERE id = $1)
+		ORDER BY created_at`
+
+	selectDisputeEvents = `SELECT dispute_id, status, group_id, COALESCE(operator, ''), created_at
+		FROM dispute_events
+		WHERE dispute_id = ANY($1)
+		ORDER BY id`
+)
+
+// OpenDispute disputes the transfer of the ledger entry on behalf of the operator,
+// and holds the disputed amount by moving it from the recipient to the disputes account.
+// The recipient must still have the disputed amount.
+func (r *PostgresRepository) OpenDispute(ctx context.Context, request *api.OpenDisputeRequest, operator string) (*api.Dispute, error) {
+	request.TxID = strings.TrimSpace(request.TxID)
+
+	if request.Amount.IsNegative() {
+		return nil, api.ErrNegativeAmount
+	}
+
+	if err := api.ValidateAmount(request.Amount); err != nil {
+		return nil, err
+	}
+
+	if !validID(request.TxID) {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	groupID, transfer, err := r.disputedTransfer(ctx, request.TxID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the amount of a reversed transfer was already returned to the sender
+	var reversedTransfer bool
+	if err = r.db.QueryRowContext(ctx, selectGroupReversed, groupID).Scan(&reversedTransfer); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if reversedTransfer {
+		return nil, api.ErrTransactionReversed
+	}
+
+	var reversed decimal.Decimal
+	if err = r.db.QueryRowContext(ctx, selectReversedAmount, groupID).Scan(&reversed); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	remaining := transfer.Amount.Sub(reversed)
+
+	amount := request.Amount
+	if amount.IsZero() {
+		amount = remaining
+	}
+
+	if !amount.IsPositive() || amount.GreaterThan(remaining) {
+		return nil, api.ErrDisputedAmount
+	}
+
+	id := r.idGenerator.NewID()
+
+	hold := &api.TransferRequest{
+		FromAccountID: transfer.ToAccountID,
+		ToAccountID:   api.DisputesAccountID,
+		Currency:      transfer.Currency,
+		Amount:        amount,
+		Remarks:       "hold of dispute " + id,
+	}
+
+	if err = r.upsertAccounts(ctx, hold); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertDispute, id, groupID, request.TxID, transfer.FromAccountID, transfer.ToAccountID,
+		transfer.Currency, amount, strings.TrimSpace(request.Reason), api.DisputeOpen, r.clock.Now())
+	if err != nil {
+		_ = tx.Rollback()
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, api.ErrDisputeOpen
+		}
+
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.postDisputeChange(ctx, tx, id, api.DisputeOpen, hold, operator); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.GetDispute(ctx, id)
+}
+
+// ReverseDispute resolves the dispute on behalf of the operator by returning the held amount to the sender.
+func (r *PostgresRepository) ReverseDispute(ctx context.Context, id, operator string) (*api.Dispute, error) {
+	return r.resolveDispute(ctx, id, operator, api.DisputeReversed)
+}
+
+// ReleaseDispute resolves the dispute on behalf of the operator by returning the held amount to the recipient.
+func (r *PostgresRepository) ReleaseDispute(ctx context.Context, id, operator string) (*api.Dispute, error) {
+	return r.resolveDispute(ctx, id, operator, api.DisputeReleased)
+}
+
+func (r *PostgresRepository) resolveDispute(ctx context.Context, id, operator string, status api.DisputeStatus) (*api.Dispute, error) {
+	if !validID(id) {
+		return nil, api.ErrDisputeNotFound
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	dispute := &api.Dispute{}
+
+	err = tx.QueryRowContext(ctx, resolveDispute, id, status, r.clock.Now()).
+		Scan(&dispute.FromAccountID, &dispute.ToAccountID, &dispute.Currency, &dispute.Amount)
+	if err != nil {
+		_ = tx.Rollback()
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, formatUnknownError(err)
+		}
+
+		// either there's no such dispute, or it's no longer open
+		if _, err = r.GetDispute(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, api.ErrDisputeResolved
+	}
+
+	release := &api.TransferRequest{
+		FromAccountID: api.DisputesAccountID,
+		ToAccountID:   dispute.ToAccountID,
+		Currency:      dispute.Currency,
+		Amount:        dispute.Amount,
+		Remarks:       "release of dispute " + id,
+	}
+
+	if status == api.DisputeReversed {
+		release.ToAccountID = dispute.FromAccountID
+		release.Remarks = "reversal of dispute " + id
+	}
+
+	if err = r.postDisputeChange(ctx, tx, id, status, release, operator); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.GetDispute(ctx, id)
+}
+
+// postDisputeChange posts the ledger entries moving the disputed amount, and records the status change with them.
+func (r *PostgresRepository) postDisputeChange(ctx context.Context, tx *sql.Tx, id string, status api.DisputeStatus, request *api.TransferRequest, operator string) error {
+	// i.e. 7c9e6679-7425-40de-944b-e07fc1f90ae7:reversed, within the 50 characters of the group ids
+	groupID := fmt.Sprintf("%s:%s", id, strings.ToLower(string(status)))
+
+	if _, _, err := r.postDoubleEntry(ctx, tx, request, groupID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, insertDisputeEvent, id, status, groupID, operator, r.clock.Now()); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// disputedTransfer returns the group id and the transfer of the ledger entry.
+func (r *PostgresRepository) disputedTransfer(ctx context.Context, txID string) (string, *api.TransferRequest, error) {
+	rows, err := r.db.QueryContext(ctx, selectDisputedTransfer, txID)
+	if err != nil {
+		return "", nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	var groupID string
+
+	transfer := &api.TransferRequest{}
+
+	isConversion := false
+
+	for rows.Next() {
+		var accountID string
+
+		var entryType api.DebitOrCreditType
+
+		var converted bool
+
+		if err = rows.Scan(&groupID, &accountID, &transfer.Currency, &transfer.Amount, &entryType, &converted); err != nil {
+			return "", nil, formatUnknownError(err)
+		}
+
+		isConversion = isConversion || converted
+
+		if entryType == api.DEBIT {
+			transfer.FromAccountID = accountID
+		} else {
+			transfer.ToAccountID = accountID
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return "", nil, formatUnknownError(err)
+	}
+
+	if transfer.FromAccountID == "" || transfer.ToAccountID == "" {
+		return "", nil, api.ErrTransactionNotFound
+	}
+
+	if transfer.FromAccountID == api.DisputesAccountID || transfer.ToAccountID == api.DisputesAccountID {
+		return "", nil, api.ErrDisputedDispute
+	}
+
+	// like their reversals, a leg of a conversion would be given back without the other, and a fee has no recipient
+	if isConversion || transfer.ToAccountID == api.FeesAccountID {
+		return "", nil, api.ErrNotDisputable
+	}
+
+	return groupID, transfer, nil
+}
+
+// GetDispute returns the dispute with its status history.
+func (r *PostgresRepository) GetDispute(ctx context.Context, id string) (*api.Dispute, error) {
+	if !validID(id) {
+		return nil, api.ErrDisputeNotFound
+	}
+
+	dispute, err := scanDispute(r.db.QueryRowContext(ctx, selectDispute, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrDisputeNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.withDisputeHistory(ctx, []*api.Dispute{dispute}); err != nil {
+		return nil, err
+	}
+
+	return dispute, nil
+}
+
+// ListDisputes returns the disputes in the status, at most limit, the oldest first, without their history.
+func (r *PostgresRepository) ListDisputes(ctx context.Context, status api.DisputeStatus, limit int) ([]*api.Dispute, error) {
+	return r.queryDisputes(ctx, selectDisputes, status, limit)
+}
+
+// GetTransactionDisputes returns the disputes of the transfer of the ledger entry with their status history, the oldest first.
+func (r *PostgresRepository) GetTransactionDisputes(ctx context.Context, txID string) ([]*api.Dispute, error) {
+	txID = strings.TrimSpace(txID)
+
+	if !validID(txID) {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	if _, err := r.GetTransaction(ctx, txID); err != nil {
+		return nil, err
+	}
+
+	disputes, err := r.queryDisputes(ctx, selectTransferDisputes, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.withDisputeHistory(ctx, disputes); err != nil {
+		return nil, err
+	}
+
+	return disputes, nil
+}
+
+func (r *PostgresRepository) queryDisputes(ctx context.Context, query string, args ...any) ([]*api.Dispute, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	disputes := []*api.Dispute{}
+
+	for rows.Next() {
+		dispute, err := scanDispute(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		disputes = append(disputes, dispute)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return disputes, nil
+}
+
+// withDisputeHistory sets the status history of the disputes.
+func (r *PostgresRepository) withDisputeHistory(ctx context.Context, disputes []*api.Dispute) error {
+	if len(disputes) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*api.Dispute, len(disputes))
+	ids := make([]string, 0, len(disputes))
+
+	for _, dispute := range disputes {
+		dispute.History = []*api.DisputeEvent{}
+		byID[dispute.ID] = dispute
+		ids = append(ids, dispute.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectDisputeEvents, pq.Array(ids))
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var disputeID string
+
+		event := &api.DisputeEvent{}
+
+		if err = rows.Scan(&disputeID, &event.Status, &event.IdempotencyKey, &event.Operator, &event.CreatedAt); err != nil {
+			return formatUnknownError(err)
+		}
+
+		byID[disputeID].History = append(byID[disputeID].History, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+func scanDispute(row rowScanner) (*api.Dispute, error) {
+	dispute := &api.Dispute{}
+
+	var resolvedAt sql.NullTime
+
+	err := row.Scan(&dispute.ID, &dispute.TxID, &dispute.FromAccountID, &dispute.ToAccountID, &dispute.Currency, &dispute.Amount,
+		&dispute.Reason, &dispute.Status, &dispute.CreatedAt, &resolvedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if resolvedAt.Valid {
+		dispute.ResolvedAt = &resolvedAt.Time
+	}
+
+	return dispute, nil
+}
@@ -0,0 +1,158 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisputes(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE disputes CASCADE;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	balance := func(t *testing.T, accountID string) decimal.Decimal {
+		t.Helper()
+
+		account, err := repo.GetAccountBalance(ctx, "USD", accountID)
+		require.NoError(t, err)
+
+		return account.Balance
+	}
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "cardholder",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "dispute-deposit")
+	require.NoError(t, err)
+
+	txs, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "cardholder",
+		ToAccountID:   "merchant",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(60),
+	}, "dispute-purchase")
+	require.NoError(t, err)
+
+	txID := txs[0].TxID
+
+	var disputeID string
+
+	t.Run("Open", func(t *testing.T) {
+		_, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txID, Amount: decimal.NewFromInt(61)}, "key-operator")
+		require.ErrorIs(t, err, api.ErrDisputedAmount)
+
+		dispute, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txID, Amount: decimal.NewFromInt(20), Reason: "not received"}, "key-operator")
+		require.NoError(t, err)
+		require.Equal(t, api.DisputeOpen, dispute.Status)
+		require.Equal(t, "cardholder", dispute.FromAccountID)
+		require.Equal(t, "merchant", dispute.ToAccountID)
+		require.Len(t, dispute.History, 1)
+		require.Equal(t, "key-operator", dispute.History[0].Operator)
+
+		disputeID = dispute.ID
+
+		require.True(t, decimal.NewFromInt(40).Equal(balance(t, "merchant")), "the disputed amount is held")
+		require.True(t, decimal.NewFromInt(20).Equal(balance(t, api.DisputesAccountID)))
+
+		_, err = repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txs[1].TxID}, "key-operator")
+		require.ErrorIs(t, err, api.ErrDisputeOpen)
+
+		disputes, err := repo.ListDisputes(ctx, api.DisputeOpen, 10)
+		require.NoError(t, err)
+		require.Len(t, disputes, 1)
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		dispute, err := repo.ReverseDispute(ctx, disputeID, "key-operator2")
+		require.NoError(t, err)
+		require.Equal(t, api.DisputeReversed, dispute.Status)
+		require.NotNil(t, dispute.ResolvedAt)
+		require.Len(t, dispute.History, 2)
+		require.Equal(t, "key-operator2", dispute.History[1].Operator)
+
+		require.True(t, decimal.NewFromInt(60).Equal(balance(t, "cardholder")), "the disputed amount is returned to the sender")
+		require.True(t, balance(t, api.DisputesAccountID).IsZero())
+
+		_, err = repo.ReleaseDispute(ctx, disputeID, "key-operator2")
+		require.ErrorIs(t, err, api.ErrDisputeResolved)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		_, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txID, Amount: decimal.NewFromInt(41)}, "key-operator")
+		require.ErrorIs(t, err, api.ErrDisputedAmount, "the reversed amount can't be disputed again")
+
+		dispute, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txID}, "key-operator")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(40).Equal(dispute.Amount))
+		require.True(t, balance(t, "merchant").IsZero())
+
+		dispute, err = repo.ReleaseDispute(ctx, dispute.ID, "key-operator2")
+		require.NoError(t, err)
+		require.Equal(t, api.DisputeReleased, dispute.Status)
+		require.True(t, decimal.NewFromInt(40).Equal(balance(t, "merchant")), "the disputed amount is returned to the recipient")
+	})
+
+	t.Run("History by transaction", func(t *testing.T) {
+		disputes, err := repo.GetTransactionDisputes(ctx, txs[1].TxID)
+		require.NoError(t, err)
+		require.Len(t, disputes, 2)
+		require.Equal(t, api.DisputeReversed, disputes[0].Status)
+		require.Equal(t, api.DisputeReleased, disputes[1].Status)
+		require.Len(t, disputes[1].History, 2)
+		require.Equal(t, api.DisputeOpen, disputes[1].History[0].Status)
+	})
+
+	t.Run("Insufficient balance", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "merchant",
+			ToAccountID:   "supplier",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(30),
+		}, "dispute-spend")
+		require.NoError(t, err)
+
+		_, err = repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txID}, "key-operator")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+		disputes, err := repo.ListDisputes(ctx, api.DisputeOpen, 10)
+		require.NoError(t, err)
+		require.Empty(t, disputes)
+	})
+
+	t.Run("Disputes account", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.DisputesAccountID,
+			ToAccountID:   "merchant",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "dispute-steal")
+		require.ErrorIs(t, err, api.ErrCompanyAccount)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: "00000000-0000-4000-8000-000000000000"}, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionNotFound)
+
+		_, err = repo.GetDispute(ctx, "unknown")
+		require.ErrorIs(t, err, api.ErrDisputeNotFound)
+
+		_, err = repo.ReverseDispute(ctx, "00000000-0000-4000-8000-000000000000", "key-operator")
+		require.ErrorIs(t, err, api.ErrDisputeNotFound)
+	})
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/lib/pq"
+)
+
+const (
+	selectAccountKey = `SELECT key_id, wrapped_key FROM account_keys WHERE account_id = $1`
+
+	// a concurrent transfer may have created the key, which is then selected again
+	insertAccountKey = `INSERT INTO account_keys (key_id, account_id, wrapped_key, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (account_id) DO NOTHING`
+
+	selectDataKeys = `SELECT key_id, wrapped_key FROM account_keys WHERE key_id = ANY($1)`
+)
+
+// WithFieldEncryption encrypts the remarks of the ledger entries with a data key per account, wrapped by the keyring.
+// Deleting the data key of an account, see RequestErasure, makes its remarks unreadable everywhere, including the backups.
+// The remarks written before are still read as they are.
+func (r *PostgresRepository) WithFieldEncryption(keyring *crypt.Keyring) *PostgresRepository {
+	r.keyring = keyring
+
+	return r
+}
+
+// sealRemarks encrypts the remarks with the data key of the account, created on its first entry.
+// The remarks are kept as they are without encryption.
+func (r *PostgresRepository) sealRemarks(ctx context.Context, tx *sql.Tx, accountID, remarks string) (string, error) {
+	if r.keyring == nil || remarks == "" {
+		return remarks, nil
+	}
+
+	keyID, wrapped, err := accountKey(ctx, tx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		keyID, wrapped, err = r.createAccountKey(ctx, tx, accountID)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	keyring, err := r.unwrapKey(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := keyring.Encrypt(remarks)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt remarks: %w", err)
+	}
+
+	return sealed, nil
+}
+
+//nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+func accountKey(ctx context.Context, tx *sql.Tx, accountID string) (string, string, error) {
+	var keyID, wrapped string
+
+	err := tx.QueryRowContext(ctx, selectAccountKey, accountID).Scan(&keyID, &wrapped)
+
+	return keyID, wrapped, err
+}
+
+func (r *PostgresRepository) createAccountKey(ctx context.Context, tx *sql.Tx, accountID string) (string, string, error) {
+	key, err := crypt.GenerateFieldKey()
+	if err != nil {
+		return "", "", err //nolint:wrapcheck // the error names the key generation
+	}
+
+	wrapped, err := r.keyring.Encrypt(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, insertAccountKey, r.idGenerator.NewID(), accountID, wrapped, r.clock.Now()); err != nil {
+		return "", "", formatUnknownError(err)
+	}
+
+	keyID, wrapped, err := accountKey(ctx, tx, accountID)
+	if err != nil {
+		return "", "", formatUnknownError(err)
+	}
+
+	return keyID, wrapped, nil
+}
+
+// unwrapKey returns a keyring of the data key, decrypted with the master keys.
+func (r *PostgresRepository) unwrapKey(keyID, wrapped string) (*crypt.Keyring, error) {
+	encoded, err := r.keyring.Decrypt(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", keyID, err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", keyID, err)
+	}
+
+	keyring, err := crypt.NewKeyring(keyID, map[string][]byte{keyID: key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %s: %w", keyID, err)
+	}
+
+	return keyring, nil
+}
+
+// dataKeys are the data keys already unwrapped, by key id. A nil keyring is a shredded key.
+type dataKeys map[string]*crypt.Keyring
+
+// openRemarks decrypts the remarks in place, loading the data keys not in keys yet in a single query.
+// The remarks of a shredded key are erased, and so are all of the encrypted remarks without the master keys.
+func (r *PostgresRepository) openRemarks(ctx context.Context, keys dataKeys, remarks ...*string) error {
+	missing := []string{}
+
+	for _, value := range remarks {
+		if !crypt.IsEncrypted(*value) {
+			continue
+		}
+
+		keyID, err := crypt.EnvelopeKeyID(*value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt remarks: %w", err)
+		}
+
+		if _, ok := keys[keyID]; !ok {
+			keys[keyID] = nil
+			missing = append(missing, keyID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return r.decryptRemarks(keys, remarks)
+	}
+
+	if r.keyring == nil {
+		r.logger.WarnContext(ctx, "encrypted remarks without the encryption keys", slog.Int("keys", len(missing)))
+
+		return r.decryptRemarks(keys, remarks)
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectDataKeys, pq.Array(missing))
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyID, wrapped string
+
+		if err = rows.Scan(&keyID, &wrapped); err != nil {
+			return formatUnknownError(err)
+		}
+
+		if keys[keyID], err = r.unwrapKey(keyID, wrapped); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return r.decryptRemarks(keys, remarks)
+}
+
+func (r *PostgresRepository) decryptRemarks(keys dataKeys, remarks []*string) error {
+	for _, value := range remarks {
+		if !crypt.IsEncrypted(*value) {
+			continue
+		}
+
+		keyID, _ := crypt.EnvelopeKeyID(*value)
+
+		keyring := keys[keyID]
+		if keyring == nil {
+			*value = ""
+
+			continue
+		}
+
+		plaintext, err := keyring.Decrypt(*value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt remarks: %w", err)
+		}
+
+		*value = plaintext
+	}
+
+	return nil
+}
+
+// openTransactions decrypts the remarks of the transactions.
+func (r *PostgresRepository) openTransactions(ctx context.Context, transactions ...*api.Transaction) error {
+	remarks := make([]*string, len(transactions))
+	for i, transaction := range transactions {
+		remarks[i] = &transaction.Remarks
+	}
+
+	return r.openRemarks(ctx, dataKeys{}, remarks...)
+}
+
+// readTransactions reads the transactions of the rows, then closes them, and decrypts their remarks.
+func (r *PostgresRepository) readTransactions(ctx context.Context, rows *sql.Rows) ([]*api.Transaction, error) {
+	transactions, err := scanTransactions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.openTransactions(ctx, transactions...); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+// explainPrefix plans the query and runs it, so the plan carries the actual rows, timings and buffers.
+const explainPrefix = `EXPLAIN (ANALYZE, BUFFERS) `
+
+// plannedQuery is a query explained with the arguments of the sampled call.
+type plannedQuery struct {
+	name  string
+	query string
+	args  []any
+}
+
+// WithQueryPlanSampling logs the EXPLAIN (ANALYZE, BUFFERS) plans of the queries of a percent of the GetTransactions
+// and Transfer calls, from 0 to 100, i.e. 0.1, to detect the missing indexes as the ledger grows.
+// The queries are run again to be analyzed, once the call is done, so only the reads are explained:
+// the transfers have the plans of their idempotency check, the lock of their debited account and the select of their entries.
+func (r *PostgresRepository) WithQueryPlanSampling(percent float64) *PostgresRepository {
+	r.planSampling = percent
+
+	return r
+}
+
+// samplePlans logs the plans of the queries of the operation, if it's sampled.
+func (r *PostgresRepository) samplePlans(ctx context.Context, operation string, queries ...plannedQuery) {
+	if r.planSampling <= 0 || rand.Float64()*100 >= r.planSampling { //nolint:gosec // sampling, not security
+		return
+	}
+
+	// the client may be gone, the plans are still worth logging
+	ctx = context.WithoutCancel(ctx)
+
+	for _, query := range queries {
+		start := time.Now()
+
+		plan, err := r.explain(ctx, query)
+		if err != nil {
+			r.logger.WarnContext(ctx, "failed to explain query", slog.String("operation", operation),
+				slog.String("query", query.name), slog.Any("error", err))
+
+			continue
+		}
+
+		r.logger.InfoContext(ctx, "query plan", slog.String("operation", operation), slog.String("query", query.name),
+			slog.Duration("elapsed", time.Since(start)), slog.String("plan", plan))
+	}
+}
+
+// explain returns the lines of the plan of the query, joined.
+func (r *PostgresRepository) explain(ctx context.Context, query plannedQuery) (string, error) {
+	rows, err := r.db.QueryContext(ctx, explainPrefix+query.query, query.args...)
+	if err != nil {
+		return "", formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	lines := []string{}
+
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return "", formatUnknownError(err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return "", formatUnknownError(err)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// transferPlans are the reads of the posted transfer.
+func transferPlans(request *api.TransferRequest, idempotencyKey string, txs []*api.Transaction) []plannedQuery {
+	queries := []plannedQuery{
+		{name: "select_group_exists", query: selectGroupExists, args: []any{idempotencyKey}},
+		{name: "lock_account", query: selectLockAccountAvailable, args: []any{request.FromAccountID, request.Currency}},
+	}
+
+	if len(txs) == 2 { //nolint:mnd // the double entry
+		queries = append(queries, plannedQuery{name: "select_transaction_pair", query: selectTransactionPair, args: []any{txs[0].TxID, txs[1].TxID}})
+	}
+
+	return queries
+}
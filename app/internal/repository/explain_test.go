@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPlanSampling(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	var logs bytes.Buffer
+
+	repo := repository.NewPostgresRepository(db).
+		WithCustomLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		WithQueryPlanSampling(100)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "explained_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}, "explain-1")
+	require.NoError(t, err)
+
+	require.Contains(t, logs.String(), "operation=Transfer query=select_group_exists")
+	require.Contains(t, logs.String(), "operation=Transfer query=lock_account")
+	require.Contains(t, logs.String(), "operation=Transfer query=select_transaction_pair")
+	require.Contains(t, logs.String(), "actual time=", "analyzed")
+	require.NotContains(t, logs.String(), "failed to explain")
+
+	logs.Reset()
+
+	_, err = repo.GetTransactions(ctx, "USD", "explained_user")
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), "operation=GetTransactions query=select_transactions")
+
+	t.Run("Not sampled", func(t *testing.T) {
+		logs.Reset()
+
+		_, err = repo.WithQueryPlanSampling(0).GetTransactions(ctx, "USD", "explained_user")
+		require.NoError(t, err)
+		require.Empty(t, logs.String())
+	})
+}
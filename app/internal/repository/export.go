@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// both legs of every transfer, in the order they were posted.
+	// the empty filters select everything, so a single statement streams a consistent view of the ledger
+	selectLedgerTransfers = `
+		SELECT debit.id, credit.id, debit.group_id, debit_account.user_id, credit_account.user_id,
+			debit_account.currency, debit.amount, COALESCE(debit.description, ''), debit.created_at, debit.tags
+		FROM transactions debit
+		JOIN transactions credit ON credit.group_id = debit.group_id AND credit.debit_credit = 'CREDIT'
+		JOIN accounts debit_account ON debit_account.id = debit.account_id
+		JOIN accounts credit_account ON credit_account.id = credit.account_id
+		WHERE debit.debit_credit = 'DEBIT'
+			AND ($1 = '' OR debit_account.user_id = $1 OR credit_account.user_id = $1)
+			AND ($2 = '' OR debit_account.currency = $2)
+			AND debit.created_at <= $3
+		ORDER BY debit.created_at, debit.group_id`
+
+	// credits add to and debits subtract from the account balance, like selectLedgerDiscrepancies
+	selectBalancesAt = `
+		SELECT accounts.user_id, accounts.currency,
+			SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END)
+		FROM accounts
+		JOIN transactions ON transactions.account_id = accounts.id
+		WHERE transactions.created_at <= $1
+		GROUP BY accounts.id
+		ORDER BY accounts.currency, accounts.user_id`
+
+	// the restored entries keep their ids, so restoring the same events twice is a no-op
+	insertRestoredEntry = `INSERT INTO transactions (id, account_id, amount, debit_credit, description, group_id, created_at, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING`
+
+	// blocks the transfers until the balances are rebuilt, the reads go on
+	lockAccountsExclusive = `LOCK TABLE accounts IN EXCLUSIVE MODE`
+
+	rebuildBalances = `
+		UPDATE accounts SET balance = ledger.balance
+		FROM (
+			SELECT accounts.id,
+				COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0) AS balance
+			FROM accounts
+			LEFT JOIN transactions ON transactions.account_id = accounts.id
+			GROUP BY accounts.id
+		) AS ledger
+		WHERE accounts.id = ledger.id AND accounts.balance <> ledger.balance`
+)
+
+// ExportLedger hands the transfers selected by the filter to emit as transfer.created events, in the order they were posted.
+// The events are rebuilt from the ledger entries, so the export doesn't depend on the outbox,
+// and the id of an event is the id of its debit entry, the same in every export.
+func (r *PostgresRepository) ExportLedger(ctx context.Context, filter api.LedgerFilter, emit func(event *api.Event) error) error {
+	until := filter.Until
+	if until.IsZero() {
+		until = r.clock.Now()
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectLedgerTransfers, strings.TrimSpace(filter.AccountID),
+		strings.ToUpper(strings.TrimSpace(filter.Currency)), until)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	// the remarks of the debit entries are decrypted with the keys of the senders
+	keys := dataKeys{}
+
+	for rows.Next() {
+		transfer := api.TransferCreated{}
+
+		var (
+			createdAt time.Time
+			tags      pq.StringArray
+		)
+
+		err = rows.Scan(&transfer.DebitTxID, &transfer.CreditTxID, &transfer.TransferID, &transfer.FromAccountID, &transfer.ToAccountID,
+			&transfer.Currency, &transfer.Amount, &transfer.Remarks, &createdAt, &tags)
+		if err != nil {
+			return formatUnknownError(err)
+		}
+
+		if len(tags) > 0 {
+			transfer.Tags = tags
+		}
+
+		if err = r.openRemarks(ctx, keys, &transfer.Remarks); err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(transfer)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", api.EventTransferCreated, err)
+		}
+
+		err = emit(&api.Event{
+			ID:      transfer.DebitTxID,
+			Type:    api.EventTransferCreated,
+			Version: api.TransferCreatedVersion,
+			Key:     transfer.FromAccountID,
+			Time:    createdAt,
+			Data:    payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// SnapshotBalances hands the balance of every account at the point in time to emit, computed from the ledger entries.
+func (r *PostgresRepository) SnapshotBalances(ctx context.Context, at time.Time, emit func(account *api.Account) error) error {
+	rows, err := r.db.QueryContext(ctx, selectBalancesAt, at)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		account := &api.Account{}
+
+		if err = rows.Scan(&account.AccountID, &account.Currency, &account.Balance); err != nil {
+			return formatUnknownError(err)
+		}
+
+		if err = emit(account); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// RestoreLedger writes the ledger entries of the transfer.created events, and creates the accounts of the account.created ones,
+// in a single transaction. It returns the number of entries written, the ones that already exist are skipped.
+// The balances aren't updated, see RebuildBalances, and no event is written to the outbox.
+func (r *PostgresRepository) RestoreLedger(ctx context.Context, events []*api.Event) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	restored, err := r.restoreEvents(ctx, tx, events)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return restored, nil
+}
+
+func (r *PostgresRepository) restoreEvents(ctx context.Context, tx *sql.Tx, events []*api.Event) (int, error) {
+	restored := 0
+
+	for _, event := range events {
+		switch event.Type {
+		case api.EventAccountCreated:
+			if event.Version > api.AccountCreatedVersion {
+				return 0, fmt.Errorf("%w: event %s: unknown version %d", api.ErrInvalidEvent, event.ID, event.Version)
+			}
+
+			account := api.AccountCreated{}
+			if err := json.Unmarshal(event.Data, &account); err != nil {
+				return 0, fmt.Errorf("%w: event %s: %w", api.ErrInvalidEvent, event.ID, err)
+			}
+
+			if _, err := restoreAccount(ctx, tx, account.AccountID, account.Currency); err != nil {
+				return 0, err
+			}
+		case api.EventTransferCreated:
+			if event.Version > api.TransferCreatedVersion {
+				return 0, fmt.Errorf("%w: event %s: unknown version %d", api.ErrInvalidEvent, event.ID, event.Version)
+			}
+
+			transfer := api.TransferCreated{}
+			if err := json.Unmarshal(event.Data, &transfer); err != nil {
+				return 0, fmt.Errorf("%w: event %s: %w", api.ErrInvalidEvent, event.ID, err)
+			}
+
+			count, err := r.restoreTransfer(ctx, tx, &transfer, event.Time)
+			if err != nil {
+				return 0, err
+			}
+
+			restored += count
+		default:
+			return 0, fmt.Errorf("%w: event %s: unknown type %q", api.ErrInvalidEvent, event.ID, event.Type)
+		}
+	}
+
+	return restored, nil
+}
+
+func (r *PostgresRepository) restoreTransfer(ctx context.Context, tx *sql.Tx, transfer *api.TransferCreated, createdAt time.Time) (int, error) {
+	// the entry ids are UUIDs, like the ones generated by the transfers
+	_, debitErr := uuid.Parse(transfer.DebitTxID)
+	_, creditErr := uuid.Parse(transfer.CreditTxID)
+
+	if transfer.TransferID == "" || len(transfer.TransferID) > 50 || debitErr != nil || creditErr != nil || !transfer.Amount.IsPositive() {
+		return 0, fmt.Errorf("%w: transfer %s is incomplete", api.ErrInvalidEvent, transfer.TransferID)
+	}
+
+	restored := 0
+
+	for _, entry := range []struct {
+		txID      string
+		accountID string
+		entryType api.DebitOrCreditType
+	}{
+		{txID: transfer.DebitTxID, accountID: transfer.FromAccountID, entryType: api.DEBIT},
+		{txID: transfer.CreditTxID, accountID: transfer.ToAccountID, entryType: api.CREDIT},
+	} {
+		accountID, err := restoreAccount(ctx, tx, entry.accountID, transfer.Currency)
+		if err != nil {
+			return 0, err
+		}
+
+		remarks, err := r.sealRemarks(ctx, tx, strings.TrimSpace(entry.accountID), transfer.Remarks)
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := tx.ExecContext(ctx, insertRestoredEntry, entry.txID, accountID, transfer.Amount, entry.entryType,
+			remarks, transfer.TransferID, createdAt, pq.Array(tagsOf(transfer.Tags)))
+		if err != nil {
+			return 0, formatUnknownError(err)
+		}
+
+		if count, err := result.RowsAffected(); err == nil {
+			restored += int(count)
+		}
+	}
+
+	return restored, nil
+}
+
+// restoreAccount creates the account with a zero balance if it doesn't exist, and returns its database id.
+func restoreAccount(ctx context.Context, tx *sql.Tx, accountID, currency string) (string, error) {
+	accountID = strings.TrimSpace(accountID)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return "", fmt.Errorf("%w: %w", api.ErrInvalidEvent, err)
+	}
+
+	var (
+		id      string
+		balance decimal.Decimal
+	)
+
+	if err := tx.QueryRowContext(ctx, upsertAccount, accountID, currency).Scan(&id, &balance); err != nil {
+		return "", formatUnknownError(err)
+	}
+
+	return id, nil
+}
+
+// RebuildBalances sets the balance of every account to the sum of its ledger entries, and returns the number of accounts fixed.
+// The transfers wait until it's done.
+func (r *PostgresRepository) RebuildBalances(ctx context.Context) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, lockAccountsExclusive); err != nil {
+		_ = tx.Rollback()
+
+		return 0, formatUnknownError(err)
+	}
+
+	result, err := tx.ExecContext(ctx, rebuildBalances)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	fixed, err := result.RowsAffected()
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return fixed, nil
+}
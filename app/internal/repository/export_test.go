@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAndRestoreLedger(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	fixed := wallettesting.NewFakeClock(start)
+
+	repo := repository.NewPostgresRepository(db).WithClock(fixed)
+
+	for i, request := range []*api.TransferRequest{
+		{FromAccountID: api.CompanyAccountID, ToAccountID: "exported1", Currency: "USD", Amount: decimal.NewFromInt(100)},
+		{FromAccountID: "exported1", ToAccountID: "exported2", Currency: "USD", Amount: decimal.NewFromInt(30), Remarks: "rent"},
+		{FromAccountID: api.CompanyAccountID, ToAccountID: "exported2", Currency: "EUR", Amount: decimal.NewFromInt(7)},
+	} {
+		fixed.Set(start.Add(time.Duration(i) * time.Hour))
+
+		_, err := repo.Transfer(ctx, request, "export-"+request.ToAccountID+request.Currency)
+		require.NoError(t, err)
+	}
+
+	export := func(t *testing.T, filter api.LedgerFilter) []*api.Event {
+		t.Helper()
+
+		events := []*api.Event{}
+
+		err := repo.ExportLedger(ctx, filter, func(event *api.Event) error {
+			events = append(events, event)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		return events
+	}
+
+	events := export(t, api.LedgerFilter{Until: start.Add(time.Hour)})
+
+	t.Run("Export", func(t *testing.T) {
+		require.Len(t, events, 2, "the transfers after the point in time are excluded")
+		require.Equal(t, api.EventTransferCreated, events[0].Type)
+		require.True(t, start.Equal(events[0].Time))
+		require.Contains(t, string(events[1].Data), `"remarks":"rent"`)
+
+		require.Len(t, export(t, api.LedgerFilter{AccountID: "exported2"}), 2)
+		require.Len(t, export(t, api.LedgerFilter{AccountID: "exported2", Currency: "eur"}), 1)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		balances := map[string]string{}
+
+		err := repo.SnapshotBalances(ctx, start, func(account *api.Account) error {
+			balances[account.AccountID+"/"+account.Currency] = account.Balance.String()
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"company/USD": "-100", "exported1/USD": "100"}, balances)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE accounts CASCADE;")
+		require.NoError(t, err)
+
+		restored, err := repo.RestoreLedger(ctx, events)
+		require.NoError(t, err)
+		require.Equal(t, 4, restored)
+
+		restored, err = repo.RestoreLedger(ctx, events)
+		require.NoError(t, err)
+		require.Zero(t, restored, "the restored entries are skipped")
+
+		rebuilt, err := repo.RebuildBalances(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), rebuilt)
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "exported2")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(30).Equal(account.Balance))
+
+		discrepancies, err := repo.VerifyLedger(ctx)
+		require.NoError(t, err)
+		require.Empty(t, discrepancies)
+
+		require.Equal(t, events, export(t, api.LedgerFilter{Until: start.Add(time.Hour)}), "the restored ledger exports the same events")
+	})
+
+	t.Run("Invalid events", func(t *testing.T) {
+		_, err := repo.RestoreLedger(ctx, []*api.Event{{ID: "unknown", Type: "account.deleted", Version: 1}})
+		require.ErrorIs(t, err, api.ErrInvalidEvent)
+
+		_, err = repo.RestoreLedger(ctx, []*api.Event{{ID: "newer", Type: api.EventTransferCreated, Version: api.TransferCreatedVersion + 1}})
+		require.ErrorIs(t, err, api.ErrInvalidEvent)
+	})
+}
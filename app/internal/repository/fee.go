@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	feeRuleColumns = `operation, currency, flat, percentage, updated_by, updated_at`
+
+	selectFeeRule = `SELECT ` + feeRuleColumns + ` FROM fee_rules WHERE operation = $1 AND currency = $2`
+
+	selectFeeRules = `SELECT ` + feeRuleColumns + ` FROM fee_rules ORDER BY operation, currency`
+
+	upsertFeeRule = `INSERT INTO fee_rules (operation, currency, flat, percentage, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (operation, currency) DO UPDATE
+		SET flat = EXCLUDED.flat, percentage = EXCLUDED.percentage, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING ` + feeRuleColumns
+
+	deleteFeeRule = `DELETE FROM fee_rules WHERE operation = $1 AND currency = $2`
+)
+
+//nolint:gochecknoglobals // constant
+var hundred = decimal.NewFromInt(100)
+
+// WithFees charges the fees of the fee rules, see SetFeeRule, on the transfers and withdrawals posted by Transfer,
+// including the queued, scheduled and approved ones, and by TransferBatch, on each of its transfers. The fee is posted
+// along with the transfer, as a double entry from the sender to api.FeesAccountID, and rounded with the policy of its
+// currency, see WithRounding. A hold reserves its fee along with its amount, and is charged it once captured.
+// The deposits, reversals and disputes are never charged.
+func (r *PostgresRepository) WithFees() *PostgresRepository {
+	r.fees = true
+
+	return r
+}
+
+// SetFeeRule sets the fee of the operation in the currency on behalf of the operator, replacing its rule if any.
+// It's charged on the transfers posted from then on.
+func (r *PostgresRepository) SetFeeRule(ctx context.Context, operation api.FeeOperation, currency string, request *api.SetFeeRuleRequest, operator string) (*api.FeeRule, error) {
+	operation, err := api.ParseFeeOperation(string(operation))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || len(currency) > 10 {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	if request == nil {
+		return nil, api.ErrInvalidFeeRule
+	}
+
+	if request.Flat.IsNegative() || request.Percentage.IsNegative() || request.Percentage.GreaterThan(hundred) {
+		return nil, fmt.Errorf("%w: the flat fee can't be negative, and the percentage is from 0 to 100", api.ErrInvalidFeeRule)
+	}
+
+	// checked before the NUMERIC columns, whose errors would be unhandled database errors
+	if !request.Flat.IsZero() {
+		if err = api.ValidateAmount(request.Flat); err != nil {
+			return nil, err //nolint:wrapcheck // the domain errors are returned as is
+		}
+	}
+
+	rule, err := scanFeeRule(r.db.QueryRowContext(ctx, upsertFeeRule, operation, currency, request.Flat, request.Percentage,
+		operator, r.clock.Now()))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return rule, nil
+}
+
+// GetFeeRules returns the fee rules, by operation then currency.
+func (r *PostgresRepository) GetFeeRules(ctx context.Context) ([]*api.FeeRule, error) {
+	rows, err := r.db.QueryContext(ctx, selectFeeRules)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	rules := []*api.FeeRule{}
+
+	for rows.Next() {
+		rule, err := scanFeeRule(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return rules, nil
+}
+
+// DeleteFeeRule stops charging the fee of the operation in the currency.
+func (r *PostgresRepository) DeleteFeeRule(ctx context.Context, operation api.FeeOperation, currency string) error {
+	operation, err := api.ParseFeeOperation(string(operation))
+	if err != nil {
+		return api.ErrFeeRuleNotFound
+	}
+
+	result, err := r.db.ExecContext(ctx, deleteFeeRule, operation, strings.ToUpper(strings.TrimSpace(currency)))
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deleted == 0 {
+		return api.ErrFeeRuleNotFound
+	}
+
+	return nil
+}
+
+// charge is the fee of a transfer, and the double entry posting it.
+type charge struct {
+	fee     *api.Fee
+	request *api.TransferRequest
+}
+
+// feeOperation is the operation the transfer is charged as, none for a deposit.
+func (r *PostgresRepository) feeOperation(request *api.TransferRequest) (api.FeeOperation, bool) {
+	switch {
+	case r.isCompanyAccount(request.FromAccountID):
+		return "", false
+	case r.isCompanyAccount(request.ToAccountID):
+		return api.FeeWithdrawal, true
+	default:
+		return api.FeeTransfer, true
+	}
+}
+
+// quoteFee computes the fee of the transfer with the rule of its operation and currency, nil when it's free.
+// The fee is in the currency of the sender, whatever the currency the transfer is converted to.
+func (r *PostgresRepository) quoteFee(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*charge, error) {
+	operation, charged := r.feeOperation(request)
+	if !r.fees || !charged {
+		return nil, nil //nolint:nilnil // not charged
+	}
+
+	rule, err := scanFeeRule(r.db.QueryRowContext(ctx, selectFeeRule, operation, request.Currency))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil //nolint:nilnil // without a rule, the operation is free
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	percentage := request.Amount.Mul(rule.Percentage).DivRound(hundred, api.MaxAmountScale)
+
+	amount := r.rounding.Round(request.Currency, rule.Flat.Add(percentage))
+	if !amount.IsPositive() {
+		return nil, nil //nolint:nilnil // rounded to a free fee
+	}
+
+	if err = api.ValidateAmount(amount); err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	if len(api.FeeGroupID(idempotencyKey)) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is too long for a fee", api.ErrInvalidRequest)
+	}
+
+	return newCharge(request, &api.Fee{
+		Currency:   request.Currency,
+		Amount:     amount,
+		Flat:       rule.Flat,
+		Percentage: rule.Percentage,
+	}, idempotencyKey), nil
+}
+
+// newCharge is the double entry posting the fee of the transfer grouped under the idempotency key.
+func newCharge(request *api.TransferRequest, fee *api.Fee, idempotencyKey string) *charge {
+	return &charge{
+		fee: fee,
+		request: &api.TransferRequest{
+			FromAccountID: request.FromAccountID,
+			ToAccountID:   api.FeesAccountID,
+			Currency:      request.Currency,
+			Amount:        fee.Amount,
+			Remarks:       "fee of " + idempotencyKey,
+			Tags:          request.Tags,
+		},
+	}
+}
+
+// holdCharge is the fee quoted when the hold was placed, charged on its capture, nil when it's free.
+func holdCharge(hold *api.Hold) *charge {
+	if hold.Fee == nil {
+		return nil
+	}
+
+	fee := *hold.Fee
+
+	return newCharge(hold.Transfer(), &fee, hold.IdempotencyKey)
+}
+
+// total is the amount of the transfer plus its fee, which the sender must cover.
+func (c *charge) total(amount decimal.Decimal) decimal.Decimal {
+	if c == nil {
+		return amount
+	}
+
+	return amount.Add(c.fee.Amount)
+}
+
+// openFeesAccount opens the fees account in the currency of the fee, whatever the provisioning policy.
+func (r *PostgresRepository) openFeesAccount(ctx context.Context, fee *charge) error {
+	if fee == nil {
+		return nil
+	}
+
+	return r.openAccount(ctx, api.FeesAccountID, fee.request.Currency)
+}
+
+// postFee posts the double entry of the fee within the transaction of its transfer, grouped under api.FeeGroupID,
+// so the transfer fails with api.ErrInsufficientBalance when the sender can't cover both.
+func (r *PostgresRepository) postFee(ctx context.Context, tx *sql.Tx, fee *charge, idempotencyKey string) error {
+	if fee == nil {
+		return nil
+	}
+
+	txID, _, err := r.postDoubleEntry(ctx, tx, fee.request, api.FeeGroupID(idempotencyKey))
+	if err != nil {
+		return err
+	}
+
+	fee.fee.TxID = txID
+
+	return nil
+}
+
+// withFee returns the ledger entries of the transfer, the debit of the sender with the breakdown of its fee.
+func withFee(txs []*api.Transaction, debitTxID string, fee *charge) []*api.Transaction {
+	if fee == nil {
+		return txs
+	}
+
+	for _, tx := range txs {
+		if tx.TxID == debitTxID {
+			tx.Fee = fee.fee
+		}
+	}
+
+	return txs
+}
+
+func scanFeeRule(row rowScanner) (*api.FeeRule, error) {
+	rule := &api.FeeRule{}
+
+	err := row.Scan(&rule.Operation, &rule.Currency, &rule.Flat, &rule.Percentage, &rule.UpdatedBy, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	rule.UpdatedAt = rule.UpdatedAt.UTC()
+
+	return rule, nil
+}
@@ -0,0 +1,240 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferFees(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE fee_rules, holds;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).
+		WithFees().
+		WithRounding(rounding.New(map[string]api.RoundingPolicy{"USD": {Scale: 2, Mode: api.RoundHalfEven}}))
+
+	balance := func(t *testing.T, accountID string) decimal.Decimal {
+		t.Helper()
+
+		account, err := repo.GetAccountBalance(ctx, "USD", accountID)
+		require.NoError(t, err)
+
+		return account.Balance
+	}
+
+	_, err := repo.SetFeeRule(ctx, api.FeeTransfer, "usd", &api.SetFeeRuleRequest{
+		Flat:       decimal.RequireFromString("0.30"),
+		Percentage: decimal.RequireFromString("1.5"),
+	}, "operator")
+	require.NoError(t, err)
+
+	_, err = repo.SetFeeRule(ctx, api.FeeWithdrawal, "USD", &api.SetFeeRuleRequest{Flat: decimal.NewFromInt(2)}, "operator")
+	require.NoError(t, err)
+
+	t.Run("Deposits are free", func(t *testing.T) {
+		txs, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "fee_sender",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(100),
+		}, "fee-deposit")
+		require.NoError(t, err)
+
+		for _, tx := range txs {
+			require.Nil(t, tx.Fee)
+		}
+	})
+
+	t.Run("Transfer", func(t *testing.T) {
+		txs, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fee_sender",
+			ToAccountID:   "fee_recipient",
+			Currency:      "USD",
+			Amount:        decimal.RequireFromString("10.10"),
+		}, "fee-transfer")
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+
+		var debit *api.Transaction
+
+		for _, tx := range txs {
+			if tx.Type == api.DEBIT {
+				debit = tx
+			} else {
+				require.Nil(t, tx.Fee, "only the sender is charged")
+			}
+		}
+
+		// 0.30 + 1.5% of 10.10 = 0.4515, rounded half even to the cents
+		require.NotNil(t, debit.Fee)
+		require.True(t, decimal.RequireFromString("0.45").Equal(debit.Fee.Amount), debit.Fee.Amount)
+		require.True(t, decimal.RequireFromString("1.5").Equal(debit.Fee.Percentage))
+
+		fee, err := repo.GetTransaction(ctx, debit.Fee.TxID)
+		require.NoError(t, err)
+		require.Equal(t, api.FeeGroupID("fee-transfer"), fee.GroupID)
+		require.Equal(t, api.FeesAccountID, fee.Counterparty)
+
+		require.True(t, decimal.RequireFromString("89.45").Equal(balance(t, "fee_sender")))
+		require.True(t, decimal.RequireFromString("10.10").Equal(balance(t, "fee_recipient")))
+		require.True(t, decimal.RequireFromString("0.45").Equal(balance(t, api.FeesAccountID)))
+	})
+
+	t.Run("Withdrawal", func(t *testing.T) {
+		txs, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fee_sender",
+			ToAccountID:   api.CompanyAccountID,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, "fee-withdrawal")
+		require.NoError(t, err)
+
+		for _, tx := range txs {
+			if tx.Type == api.DEBIT {
+				require.True(t, decimal.NewFromInt(2).Equal(tx.Fee.Amount))
+			}
+		}
+
+		require.True(t, decimal.RequireFromString("77.45").Equal(balance(t, "fee_sender")))
+		require.True(t, decimal.RequireFromString("2.45").Equal(balance(t, api.FeesAccountID)))
+	})
+
+	t.Run("Covering the fee", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fee_sender",
+			ToAccountID:   "fee_recipient",
+			Currency:      "USD",
+			Amount:        decimal.RequireFromString("77.45"),
+		}, "fee-overdraft")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+		require.True(t, decimal.RequireFromString("77.45").Equal(balance(t, "fee_sender")), "rolled back with its fee")
+	})
+
+	t.Run("Not reversible or disputable", func(t *testing.T) {
+		txs, err := repo.GetTransactions(ctx, "USD", api.FeesAccountID)
+		require.NoError(t, err)
+		require.NotEmpty(t, txs)
+
+		_, err = repo.ReverseTransaction(ctx, txs[0].TxID, nil, "operator")
+		require.ErrorIs(t, err, api.ErrNotReversible)
+
+		_, err = repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txs[0].TxID}, "operator")
+		require.ErrorIs(t, err, api.ErrNotDisputable)
+
+		_, err = repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.FeesAccountID,
+			ToAccountID:   "fee_recipient",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "fee-sweep")
+		require.ErrorIs(t, err, api.ErrCompanyAccount)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		receipt, err := repo.TransferBatch(ctx, []*api.TransferRequest{{
+			FromAccountID: "fee_sender",
+			ToAccountID:   "fee_recipient",
+			Currency:      "USD",
+			Amount:        decimal.RequireFromString("10.10"),
+		}}, "fee-batch")
+		require.NoError(t, err)
+
+		// the same fee as the single transfer
+		fee := receipt.Transfers[0].Debit.Fee
+		require.NotNil(t, fee)
+		require.True(t, decimal.RequireFromString("0.45").Equal(fee.Amount), fee.Amount)
+		require.Nil(t, receipt.Transfers[0].Credit.Fee)
+
+		entry, err := repo.GetTransaction(ctx, fee.TxID)
+		require.NoError(t, err)
+		require.Equal(t, api.FeeGroupID(api.BatchGroupID("fee-batch", 0)), entry.GroupID)
+
+		require.True(t, decimal.RequireFromString("66.90").Equal(balance(t, "fee_sender")))
+		require.True(t, decimal.RequireFromString("2.90").Equal(balance(t, api.FeesAccountID)))
+	})
+
+	t.Run("Hold", func(t *testing.T) {
+		request := func() *api.TransferRequest {
+			return &api.TransferRequest{
+				FromAccountID: "fee_sender",
+				ToAccountID:   "fee_recipient",
+				Currency:      "USD",
+				Amount:        decimal.RequireFromString("10.10"),
+			}
+		}
+
+		released, err := repo.PlaceHold(ctx, request(), "fee-hold-released")
+		require.NoError(t, err)
+		require.NotNil(t, released.Fee)
+		require.True(t, decimal.RequireFromString("0.45").Equal(released.Fee.Amount))
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "fee_sender")
+		require.NoError(t, err)
+		require.True(t, decimal.RequireFromString("10.55").Equal(*account.Held), "the fee is reserved too")
+
+		_, err = repo.ReleaseHold(ctx, released.ID)
+		require.NoError(t, err)
+
+		account, err = repo.GetAccountBalance(ctx, "USD", "fee_sender")
+		require.NoError(t, err)
+		require.Nil(t, account.Held)
+
+		hold, err := repo.PlaceHold(ctx, request(), "fee-hold")
+		require.NoError(t, err)
+
+		// charged the quoted fee, whatever the rule once captured
+		_, err = repo.SetFeeRule(ctx, api.FeeTransfer, "USD", &api.SetFeeRuleRequest{Flat: decimal.NewFromInt(5)}, "operator")
+		require.NoError(t, err)
+
+		receipt, err := repo.CaptureHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.NotNil(t, receipt.Debit.Fee)
+		require.True(t, decimal.RequireFromString("0.45").Equal(receipt.Debit.Fee.Amount))
+
+		require.True(t, decimal.RequireFromString("56.35").Equal(balance(t, "fee_sender")))
+		require.True(t, decimal.RequireFromString("3.35").Equal(balance(t, api.FeesAccountID)))
+
+		_, err = repo.SetFeeRule(ctx, api.FeeTransfer, "USD", &api.SetFeeRuleRequest{
+			Flat:       decimal.RequireFromString("0.30"),
+			Percentage: decimal.RequireFromString("1.5"),
+		}, "operator")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		rules, err := repo.GetFeeRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 2)
+		require.Equal(t, api.FeeTransfer, rules[0].Operation)
+		require.Equal(t, "USD", rules[0].Currency)
+		require.Equal(t, "operator", rules[0].UpdatedBy)
+
+		for _, request := range []*api.SetFeeRuleRequest{
+			{Flat: decimal.NewFromInt(-1)},
+			{Percentage: decimal.NewFromInt(101)},
+		} {
+			_, err = repo.SetFeeRule(ctx, api.FeeTransfer, "USD", request, "operator")
+			require.ErrorIs(t, err, api.ErrInvalidFeeRule)
+		}
+
+		_, err = repo.SetFeeRule(ctx, "DEPOSIT", "USD", &api.SetFeeRuleRequest{}, "operator")
+		require.ErrorIs(t, err, api.ErrInvalidFeeRule)
+
+		require.NoError(t, repo.DeleteFeeRule(ctx, api.FeeWithdrawal, "usd"))
+		require.ErrorIs(t, repo.DeleteFeeRule(ctx, api.FeeWithdrawal, "USD"), api.ErrFeeRuleNotFound)
+	})
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// WithFXRates converts the cross-currency transfers at the rates quoted by the provider, see api.TransferRequest.ToCurrency.
+// The converted amounts are rounded with the policy of their currency, see WithRounding. Without a provider,
+// the transfers are only posted in a single currency.
+func (r *PostgresRepository) WithFXRates(provider api.FXRateProvider) *PostgresRepository {
+	r.fxRates = provider
+
+	return r
+}
+
+// conversion is a cross-currency transfer, posted as a double entry in each currency through the company account,
+// so the balances of every currency still sum to zero, and the company account holds the position of the conversions.
+type conversion struct {
+	rate decimal.Decimal
+	// debit moves the amount from the sender to the company account, in the currency of the sender
+	debit *api.TransferRequest
+	// credit moves the converted amount from the company account to the recipient, in the currency it's converted to
+	credit *api.TransferRequest
+}
+
+// validateConversion normalizes the currency the transfer is converted to. A transfer to its own currency converts nothing.
+func (r *PostgresRepository) validateConversion(request *api.TransferRequest) error {
+	request.ToCurrency = strings.ToUpper(strings.TrimSpace(request.ToCurrency))
+	if request.ToCurrency == request.Currency {
+		request.ToCurrency = ""
+	}
+
+	if request.ToCurrency == "" {
+		return nil
+	}
+
+	if len(request.ToCurrency) > 10 {
+		return api.ErrInvalidCurrency
+	}
+
+	// the company account is the counterparty of both legs, the deposits and the withdrawals aren't converted
+	if r.isCompanyAccount(request.FromAccountID) || r.isCompanyAccount(request.ToAccountID) {
+		return api.ErrCompanyAccount
+	}
+
+	return nil
+}
+
+// rejectConversion fails the cross-currency transfers that are recorded to be posted later, i.e. the holds,
+// as their rate would be quoted when they're posted rather than when they're accepted.
+func rejectConversion(request *api.TransferRequest, recorded string) error {
+	if request.ToCurrency != "" {
+		return fmt.Errorf("%w: the %s are posted in a single currency", api.ErrUnsupportedCurrencyPair, recorded)
+	}
+
+	return nil
+}
+
+// quoteConversion quotes the rate of the cross-currency transfer, and converts its amount.
+func (r *PostgresRepository) quoteConversion(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*conversion, error) {
+	if len(api.FXGroupID(idempotencyKey)) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is too long for a conversion", api.ErrInvalidRequest)
+	}
+
+	if r.fxRates == nil {
+		return nil, fmt.Errorf("%w: %s to %s", api.ErrUnsupportedCurrencyPair, request.Currency, request.ToCurrency)
+	}
+
+	rate, err := r.fxRates.Rate(ctx, request.Currency, request.ToCurrency)
+
+	switch {
+	case errors.Is(err, api.ErrUnsupportedCurrencyPair):
+		return nil, err //nolint:wrapcheck // the provider names the pair
+	case err != nil:
+		return nil, fmt.Errorf("%w: %w", api.ErrFXRateUnavailable, err)
+	case !rate.IsPositive():
+		return nil, fmt.Errorf("%w: %s to %s quoted at %s", api.ErrFXRateUnavailable, request.Currency, request.ToCurrency, rate)
+	}
+
+	// within the range of the ledger, whatever the precision of the rate
+	amount := r.rounding.Round(request.ToCurrency, request.Amount.Mul(rate).RoundBank(api.MaxAmountScale))
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: converted to 0 %s", api.ErrInvalidAmount, request.ToCurrency)
+	}
+
+	if err = api.ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	return &conversion{
+		rate: rate,
+		debit: &api.TransferRequest{
+			FromAccountID: request.FromAccountID,
+			ToAccountID:   r.companyAccountID,
+			Currency:      request.Currency,
+			Amount:        request.Amount,
+			Remarks:       request.Remarks,
+			Tags:          request.Tags,
+		},
+		credit: &api.TransferRequest{
+			FromAccountID: r.companyAccountID,
+			ToAccountID:   request.ToAccountID,
+			Currency:      request.ToCurrency,
+			Amount:        amount,
+			Remarks:       request.Remarks,
+			Tags:          request.Tags,
+		},
+	}, nil
+}
+
+// openAccounts opens the accounts of the transfer, or of both legs of its conversion.
+func (r *PostgresRepository) openAccounts(ctx context.Context, request *api.TransferRequest, fx *conversion) error {
+	if fx == nil {
+		return r.upsertAccounts(ctx, request)
+	}
+
+	// checked as a transfer between the sender and the recipient, the leg of the recipient would pass for a deposit
+	if err := r.checkProvisioning(ctx, request); err != nil {
+		return err
+	}
+
+	if err := r.upsertAccounts(ctx, fx.debit); err != nil {
+		return err
+	}
+
+	return r.upsertAccounts(ctx, fx.credit)
+}
+
+// postTransfer posts the double entry of the transfer, or both double entries of its conversion, and returns the ids
+// of the entries of the sender and of the recipient. Both legs of a conversion record its rate, the leg of the recipient
+// is grouped under api.FXGroupID.
+func (r *PostgresRepository) postTransfer(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, fx *conversion, idempotencyKey string) (string, string, error) {
+	if fx == nil {
+		return r.postDoubleEntry(ctx, tx, request, idempotencyKey)
+	}
+
+	rate := decimal.NewNullDecimal(fx.rate)
+
+	var debitTxID, creditTxID string
+
+	postDebit := func() (err error) {
+		debitTxID, _, err = r.postEntries(ctx, tx, fx.debit, idempotencyKey, rate)
+
+		return err
+	}
+
+	postCredit := func() (err error) {
+		_, creditTxID, err = r.postEntries(ctx, tx, fx.credit, api.FXGroupID(idempotencyKey), rate)
+
+		return err
+	}
+
+	// the company accounts are locked in the order of their currencies, like inLockOrder,
+	// so the conversions in opposite directions don't deadlock on them
+	legs := []func() error{postDebit, postCredit}
+	if fx.credit.Currency < fx.debit.Currency {
+		legs = []func() error{postCredit, postDebit}
+	}
+
+	for _, post := range legs {
+		if err := post(); err != nil {
+			return "", "", err
+		}
+	}
+
+	return debitTxID, creditTxID, nil
+}
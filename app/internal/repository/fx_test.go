@@ -0,0 +1,155 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/fx"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferConversion(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	rates := fx.NewStatic(map[fx.Pair]decimal.Decimal{{From: "USD", To: "EUR"}: decimal.RequireFromString("0.92")})
+
+	repo := repository.NewPostgresRepository(db).
+		WithFXRates(rates).
+		WithRounding(rounding.New(map[string]api.RoundingPolicy{"EUR": {Scale: 2, Mode: api.RoundHalfEven}}))
+
+	balance := func(t *testing.T, currency, accountID string) decimal.Decimal {
+		t.Helper()
+
+		account, err := repo.GetAccountBalance(ctx, currency, accountID)
+		require.NoError(t, err)
+
+		return account.Balance
+	}
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "fx_sender",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "fx-funding")
+	require.NoError(t, err)
+
+	conversion := &api.TransferRequest{
+		FromAccountID: "fx_sender",
+		ToAccountID:   "fx_recipient",
+		Currency:      "USD",
+		ToCurrency:    "eur",
+		Amount:        decimal.RequireFromString("10.55"),
+		Remarks:       "abroad",
+	}
+
+	t.Run("Converted", func(t *testing.T) {
+		txs, err := repo.Transfer(ctx, conversion, "fx-1")
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+
+		require.Equal(t, "fx_sender", txs[0].AccountID)
+		require.Equal(t, "USD", txs[0].Currency)
+		require.Equal(t, "fx-1", txs[0].GroupID)
+		require.Equal(t, "fx_recipient", txs[1].AccountID)
+		require.Equal(t, "EUR", txs[1].Currency)
+		require.Equal(t, api.FXGroupID("fx-1"), txs[1].GroupID)
+
+		for _, tx := range txs {
+			require.NotNil(t, tx.FXRate)
+			require.True(t, decimal.RequireFromString("0.92").Equal(*tx.FXRate))
+		}
+
+		// 10.55 * 0.92 = 9.706, rounded half even to the cents
+		require.True(t, decimal.RequireFromString("89.45").Equal(balance(t, "USD", "fx_sender")))
+		require.True(t, decimal.RequireFromString("9.71").Equal(balance(t, "EUR", "fx_recipient")))
+		require.True(t, decimal.RequireFromString("-89.45").Equal(balance(t, "USD", api.CompanyAccountID)))
+		require.True(t, decimal.RequireFromString("-9.71").Equal(balance(t, "EUR", api.CompanyAccountID)))
+	})
+
+	t.Run("Posted once", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, conversion, "fx-1")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
+	})
+
+	t.Run("Inverse rate", func(t *testing.T) {
+		txs, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fx_recipient",
+			ToAccountID:   "fx_sender",
+			Currency:      "EUR",
+			ToCurrency:    "USD",
+			Amount:        decimal.RequireFromString("9.2"),
+		}, "fx-2")
+		require.NoError(t, err)
+		require.Equal(t, "USD", txs[1].Currency)
+		require.True(t, decimal.NewFromInt(10).Sub(txs[1].Amount).Abs().LessThan(decimal.RequireFromString("0.0001")), txs[1].Amount)
+	})
+
+	t.Run("Unsupported pair", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fx_sender",
+			ToAccountID:   "fx_recipient",
+			Currency:      "USD",
+			ToCurrency:    "JPY",
+			Amount:        decimal.NewFromInt(1),
+		}, "fx-3")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+
+		_, err = repository.NewPostgresRepository(db).Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "fx_sender",
+			ToAccountID:   "fx_recipient",
+			Currency:      "USD",
+			ToCurrency:    "EUR",
+			Amount:        decimal.NewFromInt(1),
+		}, "fx-4")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair, "without any rates")
+	})
+
+	t.Run("Not reversible or disputable", func(t *testing.T) {
+		txs, err := repo.GetTransactions(ctx, "EUR", "fx_recipient")
+		require.NoError(t, err)
+		require.NotEmpty(t, txs)
+
+		_, err = repo.ReverseTransaction(ctx, txs[0].TxID, nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrNotReversible)
+
+		_, err = repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: txs[0].TxID}, "key-operator")
+		require.ErrorIs(t, err, api.ErrNotDisputable)
+	})
+
+	t.Run("Posted later", func(t *testing.T) {
+		request := func() *api.TransferRequest {
+			return &api.TransferRequest{
+				FromAccountID: "fx_sender",
+				ToAccountID:   "fx_recipient",
+				Currency:      "USD",
+				ToCurrency:    "EUR",
+				Amount:        decimal.NewFromInt(1),
+			}
+		}
+
+		_, err := repo.EnqueueTransfer(ctx, request(), "fx-queued")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+
+		_, err = repo.PlaceHold(ctx, request(), "fx-hold")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+
+		_, err = repo.ScheduleTransfer(ctx, &api.ScheduleTransferRequest{
+			TransferRequest: *request(),
+			ExecuteAt:       time.Now().Add(time.Hour),
+		}, "fx-scheduled")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+
+		_, err = repo.TransferBatch(ctx, []*api.TransferRequest{request()}, "fx-batch")
+		require.ErrorIs(t, err, api.ErrUnsupportedCurrencyPair)
+	})
+}
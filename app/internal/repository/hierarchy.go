@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// maxHierarchyDepth bounds the recursive queries, the hierarchies are a few levels deep in practice.
+const maxHierarchyDepth = 32
+
+const (
+	// serializes the changes of the hierarchy, so two concurrent changes can't create a cycle
+	lockAccountParents = `LOCK TABLE account_parents IN SHARE ROW EXCLUSIVE MODE`
+
+	selectParent = `SELECT parent_id FROM account_parents WHERE account_id = $1`
+
+	insertParent = `INSERT INTO account_parents (account_id, parent_id, created_at) VALUES ($1, $2, $3)`
+
+	// the ancestors of the account, the nearest first
+	selectAncestors = `
+		WITH RECURSIVE ancestors AS (
+			SELECT parent_id, 1 AS depth FROM account_parents WHERE account_id = $1
+			UNION ALL
+			SELECT account_parents.parent_id, ancestors.depth + 1
+			FROM account_parents
+			JOIN ancestors ON account_parents.account_id = ancestors.parent_id
+			WHERE ancestors.depth < $2
+		)
+		SELECT parent_id FROM ancestors ORDER BY depth`
+
+	// the descendants of the account, with their balance in the currency if they hold it
+	selectDescendantBalances = `
+		WITH RECURSIVE descendants AS (
+			SELECT account_id, parent_id, 1 AS depth FROM account_parents WHERE parent_id = $1
+			UNION ALL
+			SELECT account_parents.account_id, account_parents.parent_id, descendants.depth + 1
+			FROM account_parents
+			JOIN descendants ON account_parents.parent_id = descendants.account_id
+			WHERE descendants.depth < $3
+		)
+		SELECT descendants.account_id, descendants.parent_id, COALESCE(accounts.balance, 0), accounts.id IS NOT NULL
+		FROM descendants
+		LEFT JOIN accounts ON accounts.user_id = descendants.account_id AND accounts.currency = $2
+		ORDER BY descendants.depth, descendants.account_id`
+)
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// SetParentAccount makes the account a sub-account of the parent, in every currency.
+// Setting the same parent again is a no-op, but an account can't move to another parent,
+// as its past transfers were constrained by its current hierarchy.
+func (r *PostgresRepository) SetParentAccount(ctx context.Context, accountID, parentID string) error {
+	accountID = strings.TrimSpace(accountID)
+	parentID = strings.TrimSpace(parentID)
+
+	if accountID == "" || len(accountID) > 255 || parentID == "" || len(parentID) > 255 {
+		return api.ErrInvalidAccountID
+	}
+
+	if accountID == parentID {
+		return api.ErrSameAccountIDs
+	}
+
+	if r.isCompanyAccount(accountID) || r.isCompanyAccount(parentID) {
+		return api.ErrCompanyAccount
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err = tx.ExecContext(ctx, lockAccountParents); err != nil {
+		return formatUnknownError(err)
+	}
+
+	var existing string
+
+	err = tx.QueryRowContext(ctx, selectParent, accountID).Scan(&existing)
+
+	switch {
+	case err == nil && existing == parentID:
+		return nil
+	case err == nil:
+		return api.ErrParentAlreadySet
+	case !errors.Is(err, sql.ErrNoRows):
+		return formatUnknownError(err)
+	}
+
+	ancestors, err := ancestorsOf(ctx, tx, parentID)
+	if err != nil {
+		return err
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor == accountID {
+			return api.ErrHierarchyCycle
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, insertParent, accountID, parentID, r.clock.Now()); err != nil {
+		return formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// GetAccountTree returns the balance of the account with its sub-accounts, rolled up in the total balances.
+// The sub-accounts that don't hold the currency have a zero balance, and so does the account
+// when only its sub-accounts hold the currency.
+func (r *PostgresRepository) GetAccountTree(ctx context.Context, currency, accountID string) (*api.Account, error) {
+	root, err := r.GetAccountBalance(ctx, currency, accountID)
+	if err != nil && !errors.Is(err, api.ErrAccountNotFound) {
+		return nil, err
+	}
+
+	rootNotFound := err
+
+	if root == nil {
+		root = &api.Account{
+			Currency:  strings.ToUpper(strings.TrimSpace(currency)),
+			AccountID: strings.TrimSpace(accountID),
+			Balance:   decimal.Zero,
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectDescendantBalances, root.AccountID, root.Currency, maxHierarchyDepth)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	accounts := map[string]*api.Account{root.AccountID: root}
+	heldCurrency := rootNotFound == nil
+
+	for rows.Next() {
+		var (
+			parentID string
+			held     bool
+		)
+
+		child := &api.Account{Currency: root.Currency}
+
+		if err = rows.Scan(&child.AccountID, &parentID, &child.Balance, &held); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		// ordered by depth, so the parent is always known
+		parent := accounts[parentID]
+		parent.Children = append(parent.Children, child)
+		accounts[child.AccountID] = child
+
+		heldCurrency = heldCurrency || held
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	// none of the hierarchy holds the currency
+	if !heldCurrency {
+		return nil, rootNotFound
+	}
+
+	rollUp(root)
+
+	return root, nil
+}
+
+// rollUp sets the total balances of the account and its sub-accounts.
+func rollUp(account *api.Account) decimal.Decimal {
+	total := account.Balance
+
+	for _, child := range account.Children {
+		total = total.Add(rollUp(child))
+	}
+
+	account.TotalBalance = &total
+
+	return total
+}
+
+// checkHierarchy rejects the transfers moving money out of a sub-account's hierarchy, i.e. a merchant's sub-balance
+// can only move to the merchant or its other sub-balances. The accounts without a parent aren't constrained.
+func (r *PostgresRepository) checkHierarchy(ctx context.Context, request *api.TransferRequest) error {
+	fromAncestors, err := ancestorsOf(ctx, r.db, request.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	if len(fromAncestors) == 0 {
+		return nil
+	}
+
+	toAncestors, err := ancestorsOf(ctx, r.db, request.ToAccountID)
+	if err != nil {
+		return err
+	}
+
+	toRoot := request.ToAccountID
+	if len(toAncestors) > 0 {
+		toRoot = toAncestors[len(toAncestors)-1]
+	}
+
+	if fromAncestors[len(fromAncestors)-1] != toRoot {
+		return api.ErrOutsideHierarchy
+	}
+
+	return nil
+}
+
+// ancestorsOf returns the parent of the account, its parent, and so on up to the root.
+func ancestorsOf(ctx context.Context, q queryer, accountID string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, selectAncestors, accountID, maxHierarchyDepth)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	ancestors := []string{}
+
+	for rows.Next() {
+		var ancestor string
+
+		if err = rows.Scan(&ancestor); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		ancestors = append(ancestors, ancestor)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return ancestors, nil
+}
@@ -0,0 +1,120 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHierarchy(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE account_parents;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	deposit := func(t *testing.T, accountID string, amount int64) {
+		t.Helper()
+
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   accountID,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(amount),
+		}, "hierarchy-deposit-"+accountID)
+		require.NoError(t, err)
+	}
+
+	deposit(t, "merchant", 100)
+	deposit(t, "merchant-shop", 30)
+	deposit(t, "merchant-shop-till", 5)
+	deposit(t, "outsider", 10)
+
+	t.Run("Set parent", func(t *testing.T) {
+		require.NoError(t, repo.SetParentAccount(ctx, "merchant-shop", "merchant"))
+		require.NoError(t, repo.SetParentAccount(ctx, "merchant-shop-till", "merchant-shop"))
+		// a sub-account without a balance yet
+		require.NoError(t, repo.SetParentAccount(ctx, "merchant-online", "merchant"))
+
+		// setting the same parent again is a no-op
+		require.NoError(t, repo.SetParentAccount(ctx, "merchant-shop", "merchant"))
+	})
+
+	t.Run("Invalid parents", func(t *testing.T) {
+		require.ErrorIs(t, repo.SetParentAccount(ctx, "merchant-shop", "outsider"), api.ErrParentAlreadySet)
+		require.ErrorIs(t, repo.SetParentAccount(ctx, "merchant", "merchant-shop-till"), api.ErrHierarchyCycle)
+		require.ErrorIs(t, repo.SetParentAccount(ctx, "merchant", "merchant"), api.ErrSameAccountIDs)
+		require.ErrorIs(t, repo.SetParentAccount(ctx, api.CompanyAccountID, "merchant"), api.ErrCompanyAccount)
+		require.ErrorIs(t, repo.SetParentAccount(ctx, "outsider", api.CompanyAccountID), api.ErrCompanyAccount)
+	})
+
+	t.Run("Roll-up balance", func(t *testing.T) {
+		tree, err := repo.GetAccountTree(ctx, "USD", "merchant")
+		require.NoError(t, err)
+
+		require.True(t, decimal.NewFromInt(100).Equal(tree.Balance))
+		require.True(t, decimal.NewFromInt(135).Equal(*tree.TotalBalance))
+		require.Len(t, tree.Children, 2)
+
+		// the children are sorted by account id
+		require.Equal(t, "merchant-online", tree.Children[0].AccountID)
+		require.True(t, decimal.Zero.Equal(*tree.Children[0].TotalBalance))
+
+		shop := tree.Children[1]
+		require.Equal(t, "merchant-shop", shop.AccountID)
+		require.True(t, decimal.NewFromInt(35).Equal(*shop.TotalBalance))
+		require.Len(t, shop.Children, 1)
+		require.Equal(t, "merchant-shop-till", shop.Children[0].AccountID)
+
+		_, err = repo.GetAccountTree(ctx, "EUR", "merchant")
+		require.ErrorIs(t, err, api.ErrAccountNotFound)
+	})
+
+	t.Run("Transfers within the hierarchy", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "merchant-shop-till",
+			ToAccountID:   "merchant",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(5),
+		}, "hierarchy-till-to-merchant")
+		require.NoError(t, err)
+
+		// the root isn't constrained
+		_, err = repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "merchant",
+			ToAccountID:   "outsider",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "hierarchy-merchant-to-outsider")
+		require.NoError(t, err)
+	})
+
+	t.Run("Transfers outside the hierarchy", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "merchant-shop",
+			ToAccountID:   "outsider",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "hierarchy-shop-to-outsider")
+		require.ErrorIs(t, err, api.ErrOutsideHierarchy)
+
+		_, err = repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "merchant-shop",
+			ToAccountID:   api.CompanyAccountID,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "hierarchy-shop-withdrawal")
+		require.ErrorIs(t, err, api.ErrOutsideHierarchy)
+	})
+}
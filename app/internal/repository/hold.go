@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	holdColumns = `id, idempotency_key, from_account_id, to_account_id, currency, amount,
+		COALESCE(remarks, ''), tags, status, created_at, closed_at, fee, fee_flat, fee_percentage`
+
+	insertHold = `INSERT INTO holds
+		(id, idempotency_key, from_account_id, to_account_id, currency, amount, remarks, tags, status, created_at,
+			fee, fee_flat, fee_percentage)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13)`
+
+	selectHold = `SELECT ` + holdColumns + ` FROM holds WHERE id = $1`
+
+	// the holds of the account, either placed or received
+	selectAccountHolds = `SELECT ` + holdColumns + ` FROM holds
+		WHERE from_account_id = $1 OR to_account_id = $1
+		ORDER BY created_at, id`
+
+	// only an authorized hold can be closed, so it's never captured twice, nor captured once released
+	closeHold = `UPDATE holds SET status = $2, closed_at = $3
+		WHERE id = $1 AND status = 'AUTHORIZED'
+		RETURNING ` + holdColumns
+
+	// the amount is reserved on the account locked by selectLockAccountAvailable
+	reserveHeldAmount = `UPDATE accounts SET held = held + $2 WHERE id = $1`
+
+	releaseHeldAmount = `UPDATE accounts SET held = held - $3 WHERE user_id = $1 AND currency = $2`
+)
+
+// PlaceHold authorizes the transfer: its amount is reserved on the sender, so the other transfers can't spend it,
+// without any ledger entry until the hold is captured with CaptureHold, or released with ReleaseHold.
+// The idempotency key is the group of the ledger entries once captured, so it can't be the one of a transfer.
+// The fee of the transfer, see WithFees, is quoted and reserved along with the amount, and charged once captured.
+// The remarks are encrypted with the data key of the sender, see WithFieldEncryption.
+// A hold can't wait for a decision: a transfer the screening or the approval threshold would hold
+// fails with api.ErrHoldRequiresApproval.
+func (r *PostgresRepository) PlaceHold(
+	ctx context.Context, request *api.TransferRequest, idempotencyKey string,
+) (*api.Hold, error) {
+	hold, err := r.placeHold(ctx, request, idempotencyKey)
+	if errors.Is(err, api.ErrUnhandledDatabaseError) {
+		r.logger.ErrorContext(ctx, "failed to place the hold", slog.String("idempotency_key", idempotencyKey),
+			slog.String("from", request.FromAccountID), slog.String("to", request.ToAccountID),
+			slog.String("currency", request.Currency), slog.Any("error", err))
+	}
+
+	return hold, err
+}
+
+func (r *PostgresRepository) placeHold(
+	ctx context.Context, request *api.TransferRequest, idempotencyKey string,
+) (*api.Hold, error) {
+	if strings.TrimSpace(idempotencyKey) == "" {
+		return nil, api.ErrMissingIdempotencyKey
+	}
+
+	if len(idempotencyKey) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is longer than %d characters", api.ErrInvalidRequest,
+			maxGroupIDLength)
+	}
+
+	if err := r.validateTransfer(ctx, request); err != nil {
+		return nil, err
+	}
+
+	if err := rejectConversion(request, "holds"); err != nil {
+		return nil, err
+	}
+
+	var existingCount int
+	if err := r.db.QueryRowContext(ctx, selectGroupExists, idempotencyKey).Scan(&existingCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+	}
+
+	if err := r.checkUnheldTransfer(ctx, request, api.ErrHoldRequiresApproval); err != nil {
+		return nil, err
+	}
+
+	fee, err := r.quoteFee(ctx, request, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.openFeesAccount(ctx, fee); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.reserve(ctx, tx, request, fee.total(request.Amount)); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	hold := &api.Hold{
+		ID:             r.idGenerator.NewID(),
+		IdempotencyKey: idempotencyKey,
+		FromAccountID:  request.FromAccountID,
+		ToAccountID:    request.ToAccountID,
+		Currency:       request.Currency,
+		Amount:         request.Amount,
+		Remarks:        request.Remarks,
+		Tags:           request.Tags,
+		Status:         api.HoldAuthorized,
+		CreatedAt:      r.clock.Now(),
+	}
+
+	// the erasure of the recipient erases them too, see RequestErasure
+	remarks, err := r.sealRemarks(ctx, tx, hold.FromAccountID, hold.Remarks)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	var feeAmount, feeFlat, feePercentage decimal.NullDecimal
+
+	if fee != nil {
+		hold.Fee = fee.fee
+		feeAmount = decimal.NewNullDecimal(fee.fee.Amount)
+		feeFlat = decimal.NewNullDecimal(fee.fee.Flat)
+		feePercentage = decimal.NewNullDecimal(fee.fee.Percentage)
+	}
+
+	_, err = tx.ExecContext(ctx, insertHold, hold.ID, hold.IdempotencyKey, hold.FromAccountID, hold.ToAccountID,
+		hold.Currency, hold.Amount, remarks, pq.Array(tagsOf(hold.Tags)), hold.Status, hold.CreatedAt,
+		feeAmount, feeFlat, feePercentage)
+	if err != nil {
+		_ = tx.Rollback()
+
+		// the same hold placed concurrently
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+		}
+
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return hold, nil
+}
+
+// reserve locks the sender, and reserves the amount, the one of the transfer plus its fee, if its available balance
+// covers it. Only the company account may reserve more than its balance, as it may go negative.
+func (r *PostgresRepository) reserve(
+	ctx context.Context, tx *sql.Tx, request *api.TransferRequest, amount decimal.Decimal,
+) error {
+	from := account{}
+
+	row := tx.QueryRowContext(ctx, selectLockAccountAvailable, request.FromAccountID, request.Currency)
+	if err := row.Scan(&from.id, &from.balance); err != nil {
+		return formatUnknownError(err)
+	}
+
+	if from.balance.LessThan(amount) && !r.isCompanyAccount(request.FromAccountID) {
+		return api.ErrInsufficientBalance
+	}
+
+	if _, err := tx.ExecContext(ctx, reserveHeldAmount, from.id, amount); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// GetHold returns the hold, whatever its status.
+func (r *PostgresRepository) GetHold(ctx context.Context, id string) (*api.Hold, error) {
+	if !validID(id) {
+		return nil, api.ErrHoldNotFound
+	}
+
+	hold, err := scanHold(r.db.QueryRowContext(ctx, selectHold, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrHoldNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &hold.Remarks); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// getAccountHolds returns the holds placed or received by the account, the oldest first, with their remarks decrypted.
+func (r *PostgresRepository) getAccountHolds(ctx context.Context, accountID string) ([]*api.Hold, error) {
+	rows, err := r.db.QueryContext(ctx, selectAccountHolds, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	holds := []*api.Hold{}
+	remarks := []*string{}
+
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		holds = append(holds, hold)
+		remarks = append(remarks, &hold.Remarks)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, remarks...); err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// CaptureHold posts the transfer of the authorized hold, grouped under its idempotency key, along with the fee quoted
+// when it was placed, and returns its receipt. The reserved amount is given back to the sender within the transaction
+// of the ledger entries, which the transfer hooks intercept like the ones of a transfer. If the transfer fails,
+// the hold stays authorized.
+func (r *PostgresRepository) CaptureHold(ctx context.Context, id string) (*api.TransferReceipt, error) {
+	hold, txs, err := r.captureHold(ctx, id)
+	if errors.Is(err, api.ErrUnhandledDatabaseError) {
+		r.logger.ErrorContext(ctx, "failed to capture the hold", slog.String("id", id), slog.Any("error", err))
+	}
+
+	// the closed or unknown holds aren't transfers
+	if hold != nil {
+		r.afterTransfer(ctx, hold.Transfer(), hold.IdempotencyKey, txs, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:wrapcheck // the domain errors are returned as is
+	return api.NewTransferReceipt(hold.Transfer(), hold.IdempotencyKey, txs)
+}
+
+func (r *PostgresRepository) captureHold(ctx context.Context, id string) (*api.Hold, []*api.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, formatUnknownError(err)
+	}
+
+	hold, err := r.closeHold(ctx, tx, id, api.HoldCaptured)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return nil, nil, err
+	}
+
+	request := hold.Transfer()
+
+	if err = r.beforeTransfer(ctx, tx, request, hold.IdempotencyKey); err != nil {
+		_ = tx.Rollback()
+
+		return hold, nil, err
+	}
+
+	fromTxID, toTxID, err := r.postDoubleEntry(ctx, tx, request, hold.IdempotencyKey)
+	if err != nil {
+		_ = tx.Rollback()
+
+		// a transfer posted with the idempotency key since the hold was placed
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return hold, nil, &api.DuplicateTransactionError{GroupID: hold.IdempotencyKey}
+		}
+
+		return hold, nil, err
+	}
+
+	fee := holdCharge(hold)
+
+	if err = r.postFee(ctx, tx, fee, hold.IdempotencyKey); err != nil {
+		_ = tx.Rollback()
+
+		return hold, nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return hold, nil, formatUnknownError(err)
+	}
+
+	txs, err := r.getTransactionsByIDs(ctx, fromTxID, toTxID)
+	if err != nil {
+		return hold, nil, err
+	}
+
+	return hold, withFee(txs, fromTxID, fee), nil
+}
+
+// ReleaseHold cancels the authorized hold, its amount is available to the transfers of the sender again.
+func (r *PostgresRepository) ReleaseHold(ctx context.Context, id string) (*api.Hold, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	hold, err := r.closeHold(ctx, tx, id, api.HoldReleased)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return hold, nil
+}
+
+// closeHold moves the authorized hold to the status within the transaction, and gives its amount back to the sender.
+// It returns api.ErrHoldNotFound if there's no such hold, and api.ErrHoldClosed if it's no longer authorized.
+func (r *PostgresRepository) closeHold(
+	ctx context.Context, tx *sql.Tx, id string, status api.HoldStatus,
+) (*api.Hold, error) {
+	if !validID(id) {
+		return nil, api.ErrHoldNotFound
+	}
+
+	hold, err := scanHold(tx.QueryRowContext(ctx, closeHold, id, status, r.clock.Now()))
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// either there's no such hold, or it's already closed
+		if _, err = r.GetHold(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, api.ErrHoldClosed
+	case err != nil:
+		return nil, formatUnknownError(err)
+	}
+
+	// posted as they are by the capture, which encrypts them again
+	if err = r.openRemarks(ctx, dataKeys{}, &hold.Remarks); err != nil {
+		return nil, err
+	}
+
+	// the reserved fee is given back too, the capture charges it
+	reserved := holdCharge(hold).total(hold.Amount)
+
+	if _, err = tx.ExecContext(ctx, releaseHeldAmount, hold.FromAccountID, hold.Currency, reserved); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return hold, nil
+}
+
+func scanHold(row rowScanner) (*api.Hold, error) {
+	hold := &api.Hold{}
+
+	var (
+		closedAt                          sql.NullTime
+		feeAmount, feeFlat, feePercentage decimal.NullDecimal
+	)
+
+	err := row.Scan(&hold.ID, &hold.IdempotencyKey, &hold.FromAccountID, &hold.ToAccountID, &hold.Currency,
+		&hold.Amount, &hold.Remarks, pq.Array(&hold.Tags), &hold.Status, &hold.CreatedAt, &closedAt,
+		&feeAmount, &feeFlat, &feePercentage)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if closedAt.Valid {
+		hold.ClosedAt = &closedAt.Time
+	}
+
+	if feeAmount.Valid {
+		hold.Fee = &api.Fee{
+			Currency:   hold.Currency,
+			Amount:     feeAmount.Decimal,
+			Flat:       feeFlat.Decimal,
+			Percentage: feePercentage.Decimal,
+		}
+	}
+
+	return hold, nil
+}
@@ -0,0 +1,151 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolds(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE holds;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "hold_payer",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "hold-funding")
+	require.NoError(t, err)
+
+	authorize := func(amount int64, idempotencyKey string) (*api.Hold, error) {
+		return repo.PlaceHold(ctx, &api.TransferRequest{
+			FromAccountID: "hold_payer",
+			ToAccountID:   "hold_merchant",
+			Currency:      "usd",
+			Amount:        decimal.NewFromInt(amount),
+			Remarks:       "fuel",
+		}, idempotencyKey)
+	}
+
+	t.Run("Reserves the amount", func(t *testing.T) {
+		hold, err := authorize(60, "hold-1")
+		require.NoError(t, err)
+		require.Equal(t, api.HoldAuthorized, hold.Status)
+		require.Equal(t, "USD", hold.Currency)
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "hold_payer")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(100).Equal(account.Balance), "no ledger entry")
+		require.True(t, decimal.NewFromInt(60).Equal(*account.Held))
+
+		_, err = authorize(50, "hold-2")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+		for _, strategy := range []repository.LockStrategy{repository.LockRows, repository.LockAdvisory, repository.LockConditionalUpdate} {
+			_, err = repository.NewPostgresRepository(db).WithLockStrategy(strategy).Transfer(ctx, &api.TransferRequest{
+				FromAccountID: "hold_payer",
+				ToAccountID:   "hold_other",
+				Currency:      "USD",
+				Amount:        decimal.NewFromInt(50),
+			}, "hold-spend-"+string(strategy))
+			require.ErrorIs(t, err, api.ErrInsufficientBalance, strategy)
+		}
+
+		got, err := repo.GetHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Equal(t, "fuel", got.Remarks)
+		require.Nil(t, got.ClosedAt)
+	})
+
+	t.Run("Captures the hold", func(t *testing.T) {
+		hold, err := authorize(30, "hold-3")
+		require.NoError(t, err)
+
+		receipt, err := repo.CaptureHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Equal(t, "hold-3", receipt.GroupID)
+		require.Equal(t, "hold_merchant", receipt.Credit.AccountID)
+		require.Equal(t, "fuel", receipt.Credit.Remarks)
+
+		payer, err := repo.GetAccountBalance(ctx, "USD", "hold_payer")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(70).Equal(payer.Balance))
+		require.True(t, decimal.NewFromInt(60).Equal(*payer.Held), "the other hold is still reserved")
+
+		captured, err := repo.GetHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.HoldCaptured, captured.Status)
+		require.NotNil(t, captured.ClosedAt)
+
+		_, err = repo.CaptureHold(ctx, hold.ID)
+		require.ErrorIs(t, err, api.ErrHoldClosed)
+
+		_, err = repo.ReleaseHold(ctx, hold.ID)
+		require.ErrorIs(t, err, api.ErrHoldClosed)
+	})
+
+	t.Run("Releases the hold", func(t *testing.T) {
+		hold, err := authorize(10, "hold-4")
+		require.NoError(t, err)
+
+		released, err := repo.ReleaseHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.HoldReleased, released.Status)
+
+		payer, err := repo.GetAccountBalance(ctx, "USD", "hold_payer")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(70).Equal(payer.Balance))
+		require.True(t, decimal.NewFromInt(60).Equal(*payer.Held))
+
+		_, err = repo.CaptureHold(ctx, hold.ID)
+		require.ErrorIs(t, err, api.ErrHoldClosed)
+	})
+
+	t.Run("Unknown hold", func(t *testing.T) {
+		_, err := repo.GetHold(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrHoldNotFound)
+
+		_, err = repo.CaptureHold(ctx, "00000000-0000-0000-0000-000000000000")
+		require.ErrorIs(t, err, api.ErrHoldNotFound)
+
+		_, err = repo.ReleaseHold(ctx, "00000000-0000-0000-0000-000000000000")
+		require.ErrorIs(t, err, api.ErrHoldNotFound)
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		_, err := authorize(1, "hold-1")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "the key of a hold")
+
+		_, err = authorize(1, "hold-3")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "the key of a captured hold")
+
+		_, err = authorize(1, "hold-funding")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "the key of a transfer")
+	})
+
+	t.Run("Requires an approval", func(t *testing.T) {
+		_, err := repo.WithApprovalThresholds(map[string]decimal.Decimal{"USD": decimal.NewFromInt(5)}).
+			PlaceHold(ctx, &api.TransferRequest{
+				FromAccountID: "hold_payer",
+				ToAccountID:   "hold_merchant",
+				Currency:      "USD",
+				Amount:        decimal.NewFromInt(6),
+			}, "hold-5")
+		require.ErrorIs(t, err, api.ErrHoldRequiresApproval)
+	})
+}
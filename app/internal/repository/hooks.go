@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/devshark/wallet/api"
+)
+
+// TransferHook intercepts the transfers posted by the repository, so the audits, the metrics, the velocity checks
+// or the outbox writes can be layered on the ledger without changing the repository.
+// The approved transfers are posted through the hooks as well.
+type TransferHook interface {
+	// BeforeTransfer is called with the validated request, within the transaction of the ledger entries,
+	// before they're posted. An error rolls the transfer back, and is returned as is.
+	BeforeTransfer(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, idempotencyKey string) error
+	// AfterTransfer is called with the outcome of every transfer, once it's been committed or it's failed,
+	// i.e. the ledger entries or the error of a rejected request. It can't fail the transfer.
+	AfterTransfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string, txs []*api.Transaction, err error)
+}
+
+// WithTransferHooks adds the hooks to the transfers, called in the order they're added.
+func (r *PostgresRepository) WithTransferHooks(hooks ...TransferHook) *PostgresRepository {
+	r.hooks = append(r.hooks, hooks...)
+
+	return r
+}
+
+// beforeTransfer calls the hooks until one of them fails the transfer.
+func (r *PostgresRepository) beforeTransfer(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, idempotencyKey string) error {
+	for _, hook := range r.hooks {
+		if err := hook.BeforeTransfer(ctx, tx, request, idempotencyKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// afterTransfer calls every hook with the outcome of the transfer.
+func (r *PostgresRepository) afterTransfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string, txs []*api.Transaction, err error) {
+	for _, hook := range r.hooks {
+		hook.AfterTransfer(ctx, request, idempotencyKey, txs, err)
+	}
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+var errVelocityExceeded = errors.New("velocity exceeded")
+
+// recordingHook fails the transfers of the blocked account, and records the outcome of every transfer.
+type recordingHook struct {
+	blocked  string
+	outcomes []error
+	entries  int
+}
+
+func (h *recordingHook) BeforeTransfer(_ context.Context, _ *sql.Tx, request *api.TransferRequest, _ string) error {
+	if request.ToAccountID == h.blocked {
+		return errVelocityExceeded
+	}
+
+	return nil
+}
+
+func (h *recordingHook) AfterTransfer(_ context.Context, _ *api.TransferRequest, _ string, txs []*api.Transaction, err error) {
+	h.outcomes = append(h.outcomes, err)
+	h.entries += len(txs)
+}
+
+func TestTransferHooks(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	hook := &recordingHook{blocked: "hooked_blocked"}
+
+	repo := repository.NewPostgresRepository(db).WithTransferHooks(hook)
+
+	deposit := func(accountID string, amount int64, idempotencyKey string) error {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   accountID,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(amount),
+		}, idempotencyKey)
+
+		return err
+	}
+
+	require.NoError(t, deposit("hooked_user", 100, "hooks-1"))
+	require.ErrorIs(t, deposit("hooked_blocked", 100, "hooks-2"), errVelocityExceeded)
+	require.ErrorIs(t, deposit("hooked_user", 0, "hooks-3"), api.ErrInvalidAmount)
+
+	require.Len(t, hook.outcomes, 3, "the hooks see every transfer, the rejected ones too")
+	require.NoError(t, hook.outcomes[0])
+	require.ErrorIs(t, hook.outcomes[1], errVelocityExceeded)
+	require.ErrorIs(t, hook.outcomes[2], api.ErrInvalidAmount)
+	require.Equal(t, 2, hook.entries)
+
+	// the failed hook rolled the transfer back, the account was opened before it
+	account, err := repo.GetAccountBalance(ctx, "USD", "hooked_blocked")
+	require.NoError(t, err)
+	require.True(t, account.Balance.IsZero())
+
+	txs, err := repo.GetTransactions(ctx, "USD", "hooked_blocked")
+	require.NoError(t, err)
+	require.Empty(t, txs)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// maxConflictKeyLength fits the idempotency keys reserved in Redis, which Postgres hasn't checked
+	maxConflictKeyLength = 255
+
+	// the original transfer is the one posted with the key, if any
+	insertIdempotencyConflict = `INSERT INTO idempotency_conflicts (id, idempotency_key, code, fingerprint, client_ip, api_key_id,
+			request_id, original_created_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT min(created_at) FROM transactions WHERE group_id = $2), $8)
+		RETURNING original_created_at`
+
+	idempotencyConflictColumns = `id, idempotency_key, code, fingerprint, client_ip, api_key_id, request_id,
+			original_created_at, created_at`
+
+	selectIdempotencyConflicts = `SELECT ` + idempotencyConflictColumns + `
+		FROM idempotency_conflicts
+		WHERE $1 = '' OR idempotency_key = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2`
+)
+
+// RecordIdempotencyConflict records the transfer rejected because its idempotency key was already used,
+// along with when the transfer of the key was posted. It sets the id and the timestamps of the conflict.
+func (r *PostgresRepository) RecordIdempotencyConflict(ctx context.Context, conflict *api.IdempotencyConflict) error {
+	conflict.ID = r.idGenerator.NewID()
+	conflict.CreatedAt = r.clock.Now()
+
+	if len(conflict.IdempotencyKey) > maxConflictKeyLength {
+		conflict.IdempotencyKey = conflict.IdempotencyKey[:maxConflictKeyLength]
+	}
+
+	var originalCreatedAt sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, insertIdempotencyConflict, conflict.ID, conflict.IdempotencyKey, conflict.Code,
+		conflict.Fingerprint, conflict.ClientIP, conflict.APIKeyID, conflict.RequestID, conflict.CreatedAt).
+		Scan(&originalCreatedAt)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if originalCreatedAt.Valid {
+		conflict.OriginalCreatedAt = &originalCreatedAt.Time
+	}
+
+	return nil
+}
+
+// GetIdempotencyConflicts returns at most limit conflicts of the idempotency key, or of every key if it's empty, the latest first.
+func (r *PostgresRepository) GetIdempotencyConflicts(ctx context.Context, idempotencyKey string, limit int) ([]*api.IdempotencyConflict, error) {
+	rows, err := r.db.QueryContext(ctx, selectIdempotencyConflicts, strings.TrimSpace(idempotencyKey), limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	conflicts := []*api.IdempotencyConflict{}
+
+	for rows.Next() {
+		conflict := &api.IdempotencyConflict{}
+
+		var originalCreatedAt sql.NullTime
+
+		err = rows.Scan(&conflict.ID, &conflict.IdempotencyKey, &conflict.Code, &conflict.Fingerprint, &conflict.ClientIP,
+			&conflict.APIKeyID, &conflict.RequestID, &originalCreatedAt, &conflict.CreatedAt)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		if originalCreatedAt.Valid {
+			conflict.OriginalCreatedAt = &originalCreatedAt.Time
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyConflicts(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE idempotency_conflicts;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	request := &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "conflict_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}
+
+	_, err := repo.Transfer(ctx, request, "conflict-key-1")
+	require.NoError(t, err)
+
+	duplicate := &api.IdempotencyConflict{
+		IdempotencyKey: "conflict-key-1",
+		Code:           api.CodeDuplicateTransaction,
+		Fingerprint:    request.Fingerprint(),
+		ClientIP:       "203.0.113.7",
+		APIKeyID:       "key-0123456789ab",
+		RequestID:      "request-1",
+	}
+	require.NoError(t, repo.RecordIdempotencyConflict(ctx, duplicate))
+	require.NotEmpty(t, duplicate.ID)
+	require.NotNil(t, duplicate.OriginalCreatedAt, "the transfer of the key is posted")
+
+	inProgress := &api.IdempotencyConflict{
+		IdempotencyKey: "conflict-key-2",
+		Code:           api.CodeTransferInProgress,
+		Fingerprint:    request.Fingerprint(),
+		ClientIP:       "203.0.113.7",
+	}
+	require.NoError(t, repo.RecordIdempotencyConflict(ctx, inProgress))
+	require.Nil(t, inProgress.OriginalCreatedAt)
+
+	conflicts, err := repo.GetIdempotencyConflicts(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 2)
+
+	conflicts, err = repo.GetIdempotencyConflicts(ctx, "conflict-key-1", 10)
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	require.Equal(t, duplicate.ID, conflicts[0].ID)
+	require.Equal(t, "key-0123456789ab", conflicts[0].APIKeyID)
+	require.Equal(t, request.Fingerprint(), conflicts[0].Fingerprint)
+	require.NotNil(t, conflicts[0].OriginalCreatedAt)
+}
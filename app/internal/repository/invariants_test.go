@@ -0,0 +1,280 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"testing/quick"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// the accounts the generated operations move the money between, besides the company account
+var propertyAccounts = []string{"prop_alice", "prop_bob", "prop_carol", "prop_dave"}
+
+const (
+	opDeposit = iota
+	opWithdraw
+	opTransfer
+	opKinds
+)
+
+// operation is a deposit, a withdrawal or a transfer generated by testing/quick.
+type operation struct {
+	Kind  uint8
+	From  uint8
+	To    uint8
+	Cents uint16
+	// Replay posts the previous operation again, with its idempotency key
+	Replay bool
+}
+
+// request is the transfer of the operation, between the accounts picked by its From and To.
+func (o operation) request() *api.TransferRequest {
+	request := &api.TransferRequest{
+		FromAccountID: propertyAccounts[int(o.From)%len(propertyAccounts)],
+		ToAccountID:   propertyAccounts[int(o.To)%len(propertyAccounts)],
+		Currency:      "USD",
+		// from 0.01 to 100.00, never zero
+		Amount: decimal.New(int64(o.Cents%10000)+1, -2),
+	}
+
+	switch o.Kind % opKinds {
+	case opDeposit:
+		request.FromAccountID = api.CompanyAccountID
+	case opWithdraw:
+		request.ToAccountID = api.CompanyAccountID
+	}
+
+	return request
+}
+
+// ledgerModel is the in-memory reference of the balances the repository must agree with.
+type ledgerModel map[string]decimal.Decimal
+
+// apply moves the amount if the source can cover it, the company account funding the deposits.
+func (m ledgerModel) apply(request *api.TransferRequest) error {
+	if request.FromAccountID == request.ToAccountID {
+		return api.ErrSameAccountIDs
+	}
+
+	if request.FromAccountID != api.CompanyAccountID && m[request.FromAccountID].LessThan(request.Amount) {
+		return api.ErrInsufficientBalance
+	}
+
+	m[request.FromAccountID] = m[request.FromAccountID].Sub(request.Amount)
+	m[request.ToAccountID] = m[request.ToAccountID].Add(request.Amount)
+
+	return nil
+}
+
+// checkLedgerInvariants verifies that no user balance is negative, that the balances sum to zero with the company
+// account, and that every balance is the sum of the ledger entries of its account, i.e. the entries sum to zero.
+func checkLedgerInvariants(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT accounts.user_id, accounts.balance,
+			COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0)
+		FROM accounts
+		LEFT JOIN transactions ON transactions.account_id = accounts.id
+		WHERE accounts.currency = 'USD'
+		GROUP BY accounts.id`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	total := decimal.Zero
+
+	for rows.Next() {
+		var (
+			accountID        string
+			balance, entries decimal.Decimal
+		)
+
+		if err = rows.Scan(&accountID, &balance, &entries); err != nil {
+			return err
+		}
+
+		if balance.IsNegative() && accountID != api.CompanyAccountID {
+			return fmt.Errorf("the balance of %s is negative: %s", accountID, balance)
+		}
+
+		if !balance.Equal(entries) {
+			return fmt.Errorf("the balance of %s is %s, its ledger entries sum to %s", accountID, balance, entries)
+		}
+
+		total = total.Add(balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if !total.IsZero() {
+		return fmt.Errorf("the balances sum to %s", total)
+	}
+
+	return nil
+}
+
+func truncateAccounts(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "TRUNCATE TABLE accounts CASCADE;")
+
+	return err
+}
+
+func TestLedgerInvariants(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	run := 0
+
+	property := func(operations []operation) bool {
+		run++
+
+		if err := truncateAccounts(ctx, db); err != nil {
+			t.Log(err)
+
+			return false
+		}
+
+		model := ledgerModel{}
+
+		var previous string
+
+		for i, o := range operations {
+			request := o.request()
+			idempotencyKey := fmt.Sprintf("prop-%d-%d", run, i)
+
+			// a replay never posts again, whatever the request
+			if o.Replay && previous != "" {
+				_, err := repo.Transfer(ctx, request, previous)
+				if !errors.Is(err, api.ErrDuplicateTransaction) {
+					t.Logf("operation %d: the replay of %s answered %v", i, previous, err)
+
+					return false
+				}
+
+				continue
+			}
+
+			want := model.apply(request)
+
+			txs, err := repo.Transfer(ctx, request, idempotencyKey)
+			if !errors.Is(err, want) {
+				t.Logf("operation %d: %+v answered %v, want %v", i, request, err, want)
+
+				return false
+			}
+
+			if err == nil {
+				previous = idempotencyKey
+
+				if len(txs) != 2 {
+					t.Logf("operation %d: posted %d ledger entries", i, len(txs))
+
+					return false
+				}
+			}
+		}
+
+		for accountID, want := range model {
+			account, err := repo.GetAccountBalance(ctx, "USD", accountID)
+			if err != nil || !account.Balance.Equal(want) {
+				t.Logf("the balance of %s is %v (%v), want %s", accountID, account, err, want)
+
+				return false
+			}
+		}
+
+		if err := checkLedgerInvariants(ctx, db); err != nil {
+			t.Log(err)
+
+			return false
+		}
+
+		return true
+	}
+
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 20}))
+}
+
+func TestLedgerInvariantsConcurrent(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	run := 0
+
+	property := func(operations []operation, seed int64) bool {
+		run++
+
+		if err := truncateAccounts(ctx, db); err != nil {
+			t.Log(err)
+
+			return false
+		}
+
+		// every operation is posted twice at once, as a client retrying before the first answer
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+
+		posted := make([]int, len(operations))
+		random := rand.New(rand.NewSource(seed)) //nolint:gosec // only shuffles the operations
+
+		for _, i := range random.Perm(len(operations)) {
+			request := operations[i].request()
+			idempotencyKey := fmt.Sprintf("prop-concurrent-%d-%d", run, i)
+
+			for range 2 {
+				wg.Add(1)
+
+				go func(request api.TransferRequest) {
+					defer wg.Done()
+
+					if _, err := repo.Transfer(ctx, &request, idempotencyKey); err == nil {
+						mu.Lock()
+						posted[i]++
+						mu.Unlock()
+					}
+				}(*request)
+			}
+		}
+
+		wg.Wait()
+
+		for i, count := range posted {
+			if count > 1 {
+				t.Logf("operation %d was posted %d times", i, count)
+
+				return false
+			}
+		}
+
+		if err := checkLedgerInvariants(ctx, db); err != nil {
+			t.Log(err)
+
+			return false
+		}
+
+		return true
+	}
+
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 10}))
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// credits add to and debits subtract from the account balance
+	selectLedgerDiscrepancies = `
+		SELECT accounts.user_id, accounts.currency, accounts.balance,
+			COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0) AS ledger_balance
+		FROM accounts
+		LEFT JOIN transactions ON transactions.account_id = accounts.id
+		GROUP BY accounts.id
+		HAVING accounts.balance <> COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0)
+		ORDER BY accounts.currency, accounts.user_id`
+
+	selectLedgerBalance = `SELECT COALESCE(SUM(CASE WHEN debit_credit = 'CREDIT' THEN amount ELSE -amount END), 0)
+		FROM transactions
+		WHERE account_id = $1`
+
+	updateRebuiltBalance = `UPDATE accounts SET balance = $2 WHERE id = $1`
+)
+
+// VerifyLedger returns the accounts whose balance drifted from their ledger entries.
+// An empty result means the ledger is consistent.
+func (r *PostgresRepository) VerifyLedger(ctx context.Context) ([]*api.LedgerDiscrepancy, error) {
+	rows, err := r.db.QueryContext(ctx, selectLedgerDiscrepancies)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	discrepancies := []*api.LedgerDiscrepancy{}
+
+	for rows.Next() {
+		discrepancy := &api.LedgerDiscrepancy{}
+
+		err = rows.Scan(&discrepancy.AccountID, &discrepancy.Currency, &discrepancy.Balance, &discrepancy.LedgerBalance)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return discrepancies, nil
+}
+
+// RebuildBalance recomputes the balance of the account from its ledger entries, and stores it in place of the drifted one.
+// The account is locked like for a transfer, so the transfers of the account wait for the rebuild, and the entries
+// can't change while they're summed.
+func (r *PostgresRepository) RebuildBalance(ctx context.Context, accountID, currency string) (*api.BalanceRebuild, error) {
+	accountID = strings.TrimSpace(accountID)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	rebuild := &api.BalanceRebuild{AccountID: accountID, Currency: currency}
+
+	var id string
+
+	err = tx.QueryRowContext(ctx, selectLockAccount, accountID, currency).Scan(&id, &rebuild.PreviousBalance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrAccountNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.QueryRowContext(ctx, selectLedgerBalance, id).Scan(&rebuild.Balance); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, updateRebuiltBalance, id, rebuild.Balance); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	rebuild.Drift = rebuild.PreviousBalance.Sub(rebuild.Balance)
+	rebuild.RebuiltAt = r.clock.Now()
+
+	return rebuild, nil
+}
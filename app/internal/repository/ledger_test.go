@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyLedger(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "ledger_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "verify-ledger-key")
+	require.NoError(t, err)
+
+	discrepancies, err := repo.VerifyLedger(ctx)
+	require.NoError(t, err)
+	require.Empty(t, discrepancies)
+
+	// tamper with the balance, bypassing the ledger
+	_, err = db.ExecContext(ctx, `UPDATE accounts SET balance = balance + 1 WHERE user_id = 'ledger_user'`)
+	require.NoError(t, err)
+
+	discrepancies, err = repo.VerifyLedger(ctx)
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	require.Equal(t, "ledger_user", discrepancies[0].AccountID)
+	require.True(t, decimal.NewFromInt(101).Equal(discrepancies[0].Balance))
+	require.True(t, decimal.NewFromInt(100).Equal(discrepancies[0].LedgerBalance))
+}
+
+func TestRebuildBalance(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "rebuild_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "rebuild-balance-key")
+	require.NoError(t, err)
+
+	t.Run("Consistent", func(t *testing.T) {
+		rebuild, err := repo.RebuildBalance(ctx, "rebuild_user", "usd")
+		require.NoError(t, err)
+		require.Equal(t, "USD", rebuild.Currency)
+		require.True(t, decimal.NewFromInt(100).Equal(rebuild.Balance))
+		require.True(t, rebuild.Drift.IsZero())
+	})
+
+	t.Run("Drifted", func(t *testing.T) {
+		// tamper with the balance, bypassing the ledger
+		_, err := db.ExecContext(ctx, `UPDATE accounts SET balance = balance - 7 WHERE user_id = 'rebuild_user'`)
+		require.NoError(t, err)
+
+		rebuild, err := repo.RebuildBalance(ctx, "rebuild_user", "USD")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(93).Equal(rebuild.PreviousBalance))
+		require.True(t, decimal.NewFromInt(100).Equal(rebuild.Balance))
+		require.True(t, decimal.NewFromInt(-7).Equal(rebuild.Drift))
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "rebuild_user")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(100).Equal(account.Balance))
+
+		discrepancies, err := repo.VerifyLedger(ctx)
+		require.NoError(t, err)
+		require.Empty(t, discrepancies)
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		_, err := repo.RebuildBalance(ctx, "rebuild_nobody", "USD")
+		require.ErrorIs(t, err, api.ErrAccountNotFound)
+
+		_, err = repo.RebuildBalance(ctx, " ", "USD")
+		require.ErrorIs(t, err, api.ErrInvalidAccountID)
+	})
+}
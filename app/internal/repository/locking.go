@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/devshark/wallet/api"
+)
+
+// LockStrategy is how the transfers serialize on the balances of their accounts, see WithLockStrategy.
+type LockStrategy string
+
+const (
+	// LockRows locks the debited then the credited account with SELECT ... FOR NO KEY UPDATE, then updates them.
+	LockRows LockStrategy = "rows"
+	// LockAdvisory takes a transaction-level advisory lock on both accounts, in the order of their ids,
+	// then reads and updates them, so the opposite transfers between the same accounts don't deadlock.
+	LockAdvisory LockStrategy = "advisory"
+	// LockConditionalUpdate debits the account only if it covers the amount, in a single UPDATE,
+	// and credits the other, in the order of their ids, without locking them beforehand.
+	LockConditionalUpdate LockStrategy = "conditional-update"
+)
+
+// Valid reports whether the strategy is known.
+func (s LockStrategy) Valid() bool {
+	return s == LockRows || s == LockAdvisory || s == LockConditionalUpdate
+}
+
+const (
+	// the available balance, less the amount of the authorized holds
+	selectAccount = `SELECT id, balance - held FROM accounts WHERE user_id = $1 AND currency = $2;`
+
+	// the lock is released on commit or rollback. hashtext may collide, which only serializes unrelated accounts.
+	lockAccountAdvisory = `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2));`
+
+	// the debit only applies if the available balance covers it, or the account may go negative
+	updateAccountBalanceIfCovered = `UPDATE accounts SET balance = balance + $1
+		WHERE user_id = $2 AND currency = $3 AND (balance - held + $1 >= 0 OR $4)
+		RETURNING id, balance - held;`
+)
+
+// WithLockStrategy overrides how the transfers lock their accounts, LockRows by default.
+// BenchmarkTransfer compares them on the hot-account workloads of loadtest.Mix, by contention level.
+// Without contention, they only differ by their round trips: LockConditionalUpdate saves the select of each account.
+// As the contention grows, the sessions queue on the hot accounts for as long as their locks are held,
+// which LockConditionalUpdate shortens the most, and LockRows fails the opposite transfers between two hot accounts
+// with deadlocks, which LockAdvisory and LockConditionalUpdate avoid by locking the accounts in the order of their ids.
+// The balances are checked again once updated whatever the strategy, so none lets an account go negative.
+func (r *PostgresRepository) WithLockStrategy(strategy LockStrategy) *PostgresRepository {
+	r.lockStrategy = strategy
+
+	return r
+}
+
+// lockAccounts locks both accounts of the request with the strategy, and returns their ids and balances,
+// or api.ErrInsufficientBalance if the source account can't cover the amount.
+// With LockConditionalUpdate, the balances are already updated.
+func (r *PostgresRepository) lockAccounts(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, allowNegative bool) (*accountPairBalance, error) {
+	switch r.lockStrategy {
+	case LockAdvisory:
+		return lockAccountsAdvisory(ctx, tx, request, allowNegative)
+	case LockConditionalUpdate:
+		return updateBalancesIfCovered(ctx, tx, request, allowNegative)
+	default:
+		return lockAccountRows(ctx, tx, request, allowNegative)
+	}
+}
+
+// lockAccountsAdvisory serializes the transfers of both accounts on advisory locks, then reads their balances.
+func lockAccountsAdvisory(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, allowNegative bool) (*accountPairBalance, error) {
+	for _, accountID := range inLockOrder(request.FromAccountID, request.ToAccountID) {
+		if _, err := tx.ExecContext(ctx, lockAccountAdvisory, accountID, request.Currency); err != nil {
+			return nil, formatUnknownError(err)
+		}
+	}
+
+	from := account{}
+	if err := tx.QueryRowContext(ctx, selectAccount, request.FromAccountID, request.Currency).Scan(&from.id, &from.balance); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if from.balance.LessThan(request.Amount) && !allowNegative {
+		return nil, api.ErrInsufficientBalance
+	}
+
+	to := account{}
+	if err := tx.QueryRowContext(ctx, selectAccount, request.ToAccountID, request.Currency).Scan(&to.id, &to.balance); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return &accountPairBalance{from: from, to: to}, nil
+}
+
+// updateBalancesIfCovered debits and credits both accounts, in the order of their ids.
+// The debit matches no row when the balance doesn't cover it, the accounts being opened beforehand.
+func updateBalancesIfCovered(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, allowNegative bool) (*accountPairBalance, error) {
+	balances := &accountPairBalance{}
+
+	for _, accountID := range inLockOrder(request.FromAccountID, request.ToAccountID) {
+		amount, target, mayGoNegative := request.Amount, &balances.to, true
+		if accountID == request.FromAccountID {
+			amount, target, mayGoNegative = request.Amount.Neg(), &balances.from, allowNegative
+		}
+
+		err := tx.QueryRowContext(ctx, updateAccountBalanceIfCovered, amount, accountID, request.Currency, mayGoNegative).Scan(&target.id, &target.balance)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, api.ErrInsufficientBalance
+		case err != nil:
+			return nil, formatUnknownError(err)
+		}
+	}
+
+	return balances, nil
+}
+
+// inLockOrder returns the account ids in a stable order, so two transfers lock their accounts in the same order.
+func inLockOrder(a, b string) []string {
+	if b < a {
+		return []string{b, a}
+	}
+
+	return []string{a, b}
+}
+
+// balancesUpdated reports whether lockAccounts already updated the balances.
+func (r *PostgresRepository) balancesUpdated() bool {
+	return r.lockStrategy == LockConditionalUpdate
+}
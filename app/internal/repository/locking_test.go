@@ -0,0 +1,103 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockStrategies(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	for _, strategy := range []repository.LockStrategy{repository.LockRows, repository.LockAdvisory, repository.LockConditionalUpdate} {
+		t.Run(string(strategy), func(t *testing.T) {
+			repo := repository.NewPostgresRepository(db).WithLockStrategy(strategy)
+
+			alice, bob := "lock_alice_"+string(strategy), "lock_bob_"+string(strategy)
+
+			for _, accountID := range []string{alice, bob} {
+				_, err := repo.Transfer(ctx, &api.TransferRequest{
+					FromAccountID: api.CompanyAccountID,
+					ToAccountID:   accountID,
+					Currency:      "USD",
+					Amount:        decimal.NewFromInt(100),
+				}, accountID+"-deposit")
+				require.NoError(t, err)
+			}
+
+			_, err := repo.Transfer(ctx, &api.TransferRequest{
+				FromAccountID: alice,
+				ToAccountID:   bob,
+				Currency:      "USD",
+				Amount:        decimal.NewFromInt(101),
+			}, alice+"-overdraft")
+			require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+			txs, err := repo.Transfer(ctx, &api.TransferRequest{
+				FromAccountID: alice,
+				ToAccountID:   bob,
+				Currency:      "USD",
+				Amount:        decimal.NewFromInt(30),
+			}, alice+"-transfer")
+			require.NoError(t, err)
+			require.Len(t, txs, 2)
+
+			// the opposite transfers between the same accounts, only LockRows may deadlock
+			var wg sync.WaitGroup
+
+			errs := make(chan error, 20)
+
+			for i := range 20 {
+				from, to := alice, bob
+				if i%2 == 1 {
+					from, to = bob, alice
+				}
+
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					_, err := repo.Transfer(ctx, &api.TransferRequest{
+						FromAccountID: from,
+						ToAccountID:   to,
+						Currency:      "USD",
+						Amount:        decimal.NewFromInt(1),
+					}, fmt.Sprintf("%s-concurrent-%d", alice, i))
+					errs <- err
+				}()
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if strategy != repository.LockRows {
+					require.NoError(t, err)
+				}
+			}
+
+			aliceBalance, err := repo.GetAccountBalance(ctx, "USD", alice)
+			require.NoError(t, err)
+
+			bobBalance, err := repo.GetAccountBalance(ctx, "USD", bob)
+			require.NoError(t, err)
+
+			require.True(t, decimal.NewFromInt(200).Equal(aliceBalance.Balance.Add(bobBalance.Balance)), "the transfers are balanced")
+
+			if strategy != repository.LockRows {
+				require.True(t, decimal.NewFromInt(70).Equal(aliceBalance.Balance))
+			}
+		})
+	}
+}
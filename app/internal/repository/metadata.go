@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// maxMetadataLength is the length of the amended fields, before the encryption of the remarks.
+const maxMetadataLength = 255
+
+const (
+	selectEntryMetadata = `
+		SELECT accounts.user_id, COALESCE(transaction_metadata.remarks, transactions.description),
+			COALESCE(transaction_metadata.reference, ''), transaction_metadata.updated_at
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		WHERE transactions.id = $1`
+
+	// the amendments of an entry are serialized, the ledger entry itself is never locked nor updated
+	lockEntryMetadata = `SELECT pg_advisory_xact_lock(hashtext('transaction_metadata:' || $1::text))`
+
+	// the fields left out, i.e. NULL, are kept
+	upsertMetadata = `INSERT INTO transaction_metadata (tx_id, remarks, reference, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tx_id) DO UPDATE SET
+			remarks = COALESCE(EXCLUDED.remarks, transaction_metadata.remarks),
+			reference = COALESCE(EXCLUDED.reference, transaction_metadata.reference),
+			updated_at = EXCLUDED.updated_at`
+
+	insertMetadataEdit = `INSERT INTO transaction_metadata_edits (tx_id, field, previous, value, operator, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	selectMetadataEdits = `SELECT field, previous, value, operator, created_at
+		FROM transaction_metadata_edits
+		WHERE tx_id = $1
+		ORDER BY id`
+)
+
+// entryMetadata are the metadata of a ledger entry as stored, i.e. with its remarks still encrypted.
+type entryMetadata struct {
+	accountID string
+	remarks   string
+	reference string
+	updatedAt sql.NullTime
+}
+
+// AmendTransactionMetadata amends the remarks and the reference of the ledger entry on behalf of the operator.
+// The ledger entry is kept as it was posted, the amendments are overlaid on it when it's read,
+// and every changed field is recorded with its previous value. Unchanged fields aren't recorded.
+func (r *PostgresRepository) AmendTransactionMetadata(ctx context.Context, txID string, request *api.AmendMetadataRequest, operator string) (*api.TransactionMetadata, error) {
+	txID = strings.TrimSpace(txID)
+
+	if !validID(txID) {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	remarks, reference := trimmedField(request.Remarks), trimmedField(request.Reference)
+
+	if (remarks != nil && len(*remarks) > maxMetadataLength) || (reference != nil && len(*reference) > maxMetadataLength) {
+		return nil, api.ErrInvalidMetadata
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.amendMetadata(ctx, tx, txID, remarks, reference, operator); err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.GetTransactionMetadata(ctx, txID)
+}
+
+func (r *PostgresRepository) amendMetadata(ctx context.Context, tx *sql.Tx, txID string, remarks, reference *string, operator string) error {
+	now := r.clock.Now()
+
+	if _, err := tx.ExecContext(ctx, lockEntryMetadata, txID); err != nil {
+		return formatUnknownError(err)
+	}
+
+	// read under the lock, so a concurrent amendment is recorded as the previous value
+	current, err := entryMetadataOf(ctx, tx, txID)
+	if err != nil {
+		return err
+	}
+
+	sealed := current.remarks
+
+	if err = r.openRemarks(ctx, dataKeys{}, &current.remarks); err != nil {
+		return err
+	}
+
+	var newRemarks, newReference *string
+
+	if remarks != nil && *remarks != current.remarks {
+		value, err := r.sealRemarks(ctx, tx, current.accountID, *remarks)
+		if err != nil {
+			return err
+		}
+
+		// the audit trail is as readable as the remarks, so both values are kept encrypted
+		if _, err = tx.ExecContext(ctx, insertMetadataEdit, txID, api.MetadataRemarks, sealed, value, operator, now); err != nil {
+			return formatUnknownError(err)
+		}
+
+		newRemarks = &value
+	}
+
+	if reference != nil && *reference != current.reference {
+		if _, err = tx.ExecContext(ctx, insertMetadataEdit, txID, api.MetadataReference, current.reference, *reference, operator, now); err != nil {
+			return formatUnknownError(err)
+		}
+
+		newReference = reference
+	}
+
+	if newRemarks == nil && newReference == nil {
+		return nil
+	}
+
+	if _, err = tx.ExecContext(ctx, upsertMetadata, txID, newRemarks, newReference, now); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// GetTransactionMetadata returns the metadata of the ledger entry, as amended, with the history of their amendments.
+func (r *PostgresRepository) GetTransactionMetadata(ctx context.Context, txID string) (*api.TransactionMetadata, error) {
+	txID = strings.TrimSpace(txID)
+
+	if !validID(txID) {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	current, err := entryMetadataOf(ctx, tx, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &api.TransactionMetadata{
+		TxID:      txID,
+		Remarks:   current.remarks,
+		Reference: current.reference,
+		Edits:     []*api.MetadataEdit{},
+	}
+
+	// the updated_at columns are timestamps without time zone, written in UTC
+	if current.updatedAt.Valid {
+		updatedAt := current.updatedAt.Time.UTC()
+		metadata.UpdatedAt = &updatedAt
+	}
+
+	rows, err := tx.QueryContext(ctx, selectMetadataEdits, txID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	remarks := []*string{&metadata.Remarks}
+
+	for rows.Next() {
+		edit := &api.MetadataEdit{}
+
+		if err = rows.Scan(&edit.Field, &edit.Previous, &edit.Value, &edit.Operator, &edit.CreatedAt); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		edit.CreatedAt = edit.CreatedAt.UTC()
+
+		if edit.Field == api.MetadataRemarks {
+			remarks = append(remarks, &edit.Previous, &edit.Value)
+		}
+
+		metadata.Edits = append(metadata.Edits, edit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, remarks...); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+func entryMetadataOf(ctx context.Context, tx *sql.Tx, txID string) (*entryMetadata, error) {
+	current := &entryMetadata{}
+
+	err := tx.QueryRowContext(ctx, selectEntryMetadata, txID).
+		Scan(&current.accountID, &current.remarks, &current.reference, &current.updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return current, nil
+}
+
+// trimmedField returns the trimmed value of an optional field, nil when it's left out.
+func trimmedField(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(*value)
+
+	return &trimmed
+}
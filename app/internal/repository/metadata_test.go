@@ -0,0 +1,115 @@
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionMetadata(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE transaction_metadata, transaction_metadata_edits, account_keys;")
+		require.NoError(t, err)
+	})
+
+	key, err := crypt.GenerateFieldKey()
+	require.NoError(t, err)
+
+	keyring, err := crypt.ParseKeyring("master", []string{"master:" + key})
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresRepository(db).WithFieldEncryption(keyring)
+
+	transactions, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "metadata_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+		Remarks:       "invoice 1OO1",
+	}, "metadata-1")
+	require.NoError(t, err)
+
+	txID := transactions[0].TxID
+
+	field := func(value string) *string { return &value }
+
+	t.Run("Amend", func(t *testing.T) {
+		metadata, err := repo.AmendTransactionMetadata(ctx, txID, &api.AmendMetadataRequest{
+			Remarks:   field(" invoice 1001 "),
+			Reference: field("INV-1001"),
+		}, "key-operator")
+		require.NoError(t, err)
+		require.Equal(t, "invoice 1001", metadata.Remarks)
+		require.Equal(t, "INV-1001", metadata.Reference)
+		require.NotNil(t, metadata.UpdatedAt)
+		require.Len(t, metadata.Edits, 2)
+		require.Equal(t, api.MetadataRemarks, metadata.Edits[0].Field)
+		require.Equal(t, "invoice 1OO1", metadata.Edits[0].Previous)
+		require.Equal(t, "invoice 1001", metadata.Edits[0].Value)
+		require.Equal(t, "key-operator", metadata.Edits[0].Operator)
+		require.Equal(t, api.MetadataReference, metadata.Edits[1].Field)
+		require.Empty(t, metadata.Edits[1].Previous)
+
+		transaction, err := repo.GetTransaction(ctx, txID)
+		require.NoError(t, err)
+		require.Equal(t, "invoice 1001", transaction.Remarks, "overlaid on the entry")
+		require.Equal(t, "INV-1001", transaction.Reference)
+	})
+
+	t.Run("Ledger unchanged", func(t *testing.T) {
+		var stored string
+
+		err := db.QueryRowContext(ctx, "SELECT description FROM transactions WHERE id = $1", txID).Scan(&stored)
+		require.NoError(t, err)
+
+		opened, err := repo.GetTransactionMetadata(ctx, txID)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(stored))
+		require.Equal(t, "invoice 1OO1", opened.Edits[0].Previous)
+
+		var amended string
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM transaction_metadata WHERE tx_id = $1", txID).Scan(&amended)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(amended), "encrypted like the entry")
+	})
+
+	t.Run("Unchanged fields", func(t *testing.T) {
+		metadata, err := repo.AmendTransactionMetadata(ctx, txID, &api.AmendMetadataRequest{Reference: field("INV-1001")}, "key-operator")
+		require.NoError(t, err)
+		require.Len(t, metadata.Edits, 2, "not recorded")
+		require.Equal(t, "invoice 1001", metadata.Remarks, "kept")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := repo.AmendTransactionMetadata(ctx, txID, &api.AmendMetadataRequest{Reference: field(strings.Repeat("x", 256))}, "key-operator")
+		require.ErrorIs(t, err, api.ErrInvalidMetadata)
+
+		_, err = repo.AmendTransactionMetadata(ctx, uuid.NewString(), &api.AmendMetadataRequest{Reference: field("INV")}, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionNotFound)
+
+		_, err = repo.GetTransactionMetadata(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrTransactionNotFound)
+	})
+
+	t.Run("Not amended", func(t *testing.T) {
+		metadata, err := repo.GetTransactionMetadata(ctx, transactions[1].TxID)
+		require.NoError(t, err)
+		require.Equal(t, "invoice 1OO1", metadata.Remarks)
+		require.Nil(t, metadata.UpdatedAt)
+		require.Empty(t, metadata.Edits)
+	})
+}
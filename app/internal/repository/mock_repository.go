@@ -6,7 +6,6 @@ import (
 	context "context"
 
 	api "github.com/devshark/wallet/api"
-
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -23,6 +22,173 @@ func (_m *MockRepository) EXPECT() *MockRepository_Expecter {
 	return &MockRepository_Expecter{mock: &_m.Mock}
 }
 
+// DeleteAlias provides a mock function with given fields: ctx, alias
+func (_m *MockRepository) DeleteAlias(ctx context.Context, alias string) error {
+	ret := _m.Called(ctx, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAlias")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, alias)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockRepository_DeleteAlias_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteAlias'
+type MockRepository_DeleteAlias_Call struct {
+	*mock.Call
+}
+
+// DeleteAlias is a helper method to define mock.On call
+//   - ctx context.Context
+//   - alias string
+func (_e *MockRepository_Expecter) DeleteAlias(ctx interface{}, alias interface{}) *MockRepository_DeleteAlias_Call {
+	return &MockRepository_DeleteAlias_Call{Call: _e.mock.On("DeleteAlias", ctx, alias)}
+}
+
+func (_c *MockRepository_DeleteAlias_Call) Run(run func(ctx context.Context, alias string)) *MockRepository_DeleteAlias_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_DeleteAlias_Call) Return(_a0 error) *MockRepository_DeleteAlias_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockRepository_DeleteAlias_Call) RunAndReturn(run func(context.Context, string) error) *MockRepository_DeleteAlias_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FilterTransactions provides a mock function with given fields: ctx, currency, accountID, filter
+func (_m *MockRepository) FilterTransactions(ctx context.Context, currency string, accountID string, filter api.TransactionFilter) ([]*api.Transaction, error) {
+	ret := _m.Called(ctx, currency, accountID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FilterTransactions")
+	}
+
+	var r0 []*api.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.TransactionFilter) ([]*api.Transaction, error)); ok {
+		return rf(ctx, currency, accountID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.TransactionFilter) []*api.Transaction); ok {
+		r0 = rf(ctx, currency, accountID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*api.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, api.TransactionFilter) error); ok {
+		r1 = rf(ctx, currency, accountID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_FilterTransactions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FilterTransactions'
+type MockRepository_FilterTransactions_Call struct {
+	*mock.Call
+}
+
+// FilterTransactions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - currency string
+//   - accountID string
+//   - filter api.TransactionFilter
+func (_e *MockRepository_Expecter) FilterTransactions(ctx interface{}, currency interface{}, accountID interface{}, filter interface{}) *MockRepository_FilterTransactions_Call {
+	return &MockRepository_FilterTransactions_Call{Call: _e.mock.On("FilterTransactions", ctx, currency, accountID, filter)}
+}
+
+func (_c *MockRepository_FilterTransactions_Call) Run(run func(ctx context.Context, currency string, accountID string, filter api.TransactionFilter)) *MockRepository_FilterTransactions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(api.TransactionFilter))
+	})
+	return _c
+}
+
+func (_c *MockRepository_FilterTransactions_Call) Return(_a0 []*api.Transaction, _a1 error) *MockRepository_FilterTransactions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_FilterTransactions_Call) RunAndReturn(run func(context.Context, string, string, api.TransactionFilter) ([]*api.Transaction, error)) *MockRepository_FilterTransactions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountAliases provides a mock function with given fields: ctx, accountID
+func (_m *MockRepository) GetAccountAliases(ctx context.Context, accountID string) ([]*api.AccountAlias, error) {
+	ret := _m.Called(ctx, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountAliases")
+	}
+
+	var r0 []*api.AccountAlias
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*api.AccountAlias, error)); ok {
+		return rf(ctx, accountID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*api.AccountAlias); ok {
+		r0 = rf(ctx, accountID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*api.AccountAlias)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetAccountAliases_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountAliases'
+type MockRepository_GetAccountAliases_Call struct {
+	*mock.Call
+}
+
+// GetAccountAliases is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID string
+func (_e *MockRepository_Expecter) GetAccountAliases(ctx interface{}, accountID interface{}) *MockRepository_GetAccountAliases_Call {
+	return &MockRepository_GetAccountAliases_Call{Call: _e.mock.On("GetAccountAliases", ctx, accountID)}
+}
+
+func (_c *MockRepository_GetAccountAliases_Call) Run(run func(ctx context.Context, accountID string)) *MockRepository_GetAccountAliases_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetAccountAliases_Call) Return(_a0 []*api.AccountAlias, _a1 error) *MockRepository_GetAccountAliases_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetAccountAliases_Call) RunAndReturn(run func(context.Context, string) ([]*api.AccountAlias, error)) *MockRepository_GetAccountAliases_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetAccountBalance provides a mock function with given fields: ctx, currency, accountID
 func (_m *MockRepository) GetAccountBalance(ctx context.Context, currency string, accountID string) (*api.Account, error) {
 	ret := _m.Called(ctx, currency, accountID)
@@ -83,6 +249,244 @@ func (_c *MockRepository_GetAccountBalance_Call) RunAndReturn(run func(context.C
 	return _c
 }
 
+// GetAccountBalances provides a mock function with given fields: ctx, accounts
+func (_m *MockRepository) GetAccountBalances(ctx context.Context, accounts []api.AccountKey) (*api.Balances, error) {
+	ret := _m.Called(ctx, accounts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountBalances")
+	}
+
+	var r0 *api.Balances
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []api.AccountKey) (*api.Balances, error)); ok {
+		return rf(ctx, accounts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []api.AccountKey) *api.Balances); ok {
+		r0 = rf(ctx, accounts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.Balances)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []api.AccountKey) error); ok {
+		r1 = rf(ctx, accounts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetAccountBalances_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountBalances'
+type MockRepository_GetAccountBalances_Call struct {
+	*mock.Call
+}
+
+// GetAccountBalances is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accounts []api.AccountKey
+func (_e *MockRepository_Expecter) GetAccountBalances(ctx interface{}, accounts interface{}) *MockRepository_GetAccountBalances_Call {
+	return &MockRepository_GetAccountBalances_Call{Call: _e.mock.On("GetAccountBalances", ctx, accounts)}
+}
+
+func (_c *MockRepository_GetAccountBalances_Call) Run(run func(ctx context.Context, accounts []api.AccountKey)) *MockRepository_GetAccountBalances_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]api.AccountKey))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetAccountBalances_Call) Return(_a0 *api.Balances, _a1 error) *MockRepository_GetAccountBalances_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetAccountBalances_Call) RunAndReturn(run func(context.Context, []api.AccountKey) (*api.Balances, error)) *MockRepository_GetAccountBalances_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountStats provides a mock function with given fields: ctx, currency, accountID
+func (_m *MockRepository) GetAccountStats(ctx context.Context, currency string, accountID string) (*api.Account, error) {
+	ret := _m.Called(ctx, currency, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountStats")
+	}
+
+	var r0 *api.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*api.Account, error)); ok {
+		return rf(ctx, currency, accountID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.Account); ok {
+		r0 = rf(ctx, currency, accountID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, currency, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetAccountStats_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountStats'
+type MockRepository_GetAccountStats_Call struct {
+	*mock.Call
+}
+
+// GetAccountStats is a helper method to define mock.On call
+//   - ctx context.Context
+//   - currency string
+//   - accountID string
+func (_e *MockRepository_Expecter) GetAccountStats(ctx interface{}, currency interface{}, accountID interface{}) *MockRepository_GetAccountStats_Call {
+	return &MockRepository_GetAccountStats_Call{Call: _e.mock.On("GetAccountStats", ctx, currency, accountID)}
+}
+
+func (_c *MockRepository_GetAccountStats_Call) Run(run func(ctx context.Context, currency string, accountID string)) *MockRepository_GetAccountStats_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetAccountStats_Call) Return(_a0 *api.Account, _a1 error) *MockRepository_GetAccountStats_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetAccountStats_Call) RunAndReturn(run func(context.Context, string, string) (*api.Account, error)) *MockRepository_GetAccountStats_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountSummary provides a mock function with given fields: ctx, accountID
+func (_m *MockRepository) GetAccountSummary(ctx context.Context, accountID string) (*api.AccountSummary, error) {
+	ret := _m.Called(ctx, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountSummary")
+	}
+
+	var r0 *api.AccountSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*api.AccountSummary, error)); ok {
+		return rf(ctx, accountID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *api.AccountSummary); ok {
+		r0 = rf(ctx, accountID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.AccountSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetAccountSummary_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountSummary'
+type MockRepository_GetAccountSummary_Call struct {
+	*mock.Call
+}
+
+// GetAccountSummary is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID string
+func (_e *MockRepository_Expecter) GetAccountSummary(ctx interface{}, accountID interface{}) *MockRepository_GetAccountSummary_Call {
+	return &MockRepository_GetAccountSummary_Call{Call: _e.mock.On("GetAccountSummary", ctx, accountID)}
+}
+
+func (_c *MockRepository_GetAccountSummary_Call) Run(run func(ctx context.Context, accountID string)) *MockRepository_GetAccountSummary_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetAccountSummary_Call) Return(_a0 *api.AccountSummary, _a1 error) *MockRepository_GetAccountSummary_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetAccountSummary_Call) RunAndReturn(run func(context.Context, string) (*api.AccountSummary, error)) *MockRepository_GetAccountSummary_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAccountTree provides a mock function with given fields: ctx, currency, accountID
+func (_m *MockRepository) GetAccountTree(ctx context.Context, currency string, accountID string) (*api.Account, error) {
+	ret := _m.Called(ctx, currency, accountID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAccountTree")
+	}
+
+	var r0 *api.Account
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*api.Account, error)); ok {
+		return rf(ctx, currency, accountID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *api.Account); ok {
+		r0 = rf(ctx, currency, accountID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.Account)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, currency, accountID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetAccountTree_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAccountTree'
+type MockRepository_GetAccountTree_Call struct {
+	*mock.Call
+}
+
+// GetAccountTree is a helper method to define mock.On call
+//   - ctx context.Context
+//   - currency string
+//   - accountID string
+func (_e *MockRepository_Expecter) GetAccountTree(ctx interface{}, currency interface{}, accountID interface{}) *MockRepository_GetAccountTree_Call {
+	return &MockRepository_GetAccountTree_Call{Call: _e.mock.On("GetAccountTree", ctx, currency, accountID)}
+}
+
+func (_c *MockRepository_GetAccountTree_Call) Run(run func(ctx context.Context, currency string, accountID string)) *MockRepository_GetAccountTree_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetAccountTree_Call) Return(_a0 *api.Account, _a1 error) *MockRepository_GetAccountTree_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetAccountTree_Call) RunAndReturn(run func(context.Context, string, string) (*api.Account, error)) *MockRepository_GetAccountTree_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetTransaction provides a mock function with given fields: ctx, txID
 func (_m *MockRepository) GetTransaction(ctx context.Context, txID string) (*api.Transaction, error) {
 	ret := _m.Called(ctx, txID)
@@ -202,6 +606,236 @@ func (_c *MockRepository_GetTransactions_Call) RunAndReturn(run func(context.Con
 	return _c
 }
 
+// GetTransactionsPage provides a mock function with given fields: ctx, currency, accountID, filter, cursor, limit
+func (_m *MockRepository) GetTransactionsPage(ctx context.Context, currency string, accountID string, filter api.TransactionFilter, cursor string, limit int) (*api.TransactionPage, error) {
+	ret := _m.Called(ctx, currency, accountID, filter, cursor, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransactionsPage")
+	}
+
+	var r0 *api.TransactionPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.TransactionFilter, string, int) (*api.TransactionPage, error)); ok {
+		return rf(ctx, currency, accountID, filter, cursor, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, api.TransactionFilter, string, int) *api.TransactionPage); ok {
+		r0 = rf(ctx, currency, accountID, filter, cursor, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.TransactionPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, api.TransactionFilter, string, int) error); ok {
+		r1 = rf(ctx, currency, accountID, filter, cursor, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_GetTransactionsPage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetTransactionsPage'
+type MockRepository_GetTransactionsPage_Call struct {
+	*mock.Call
+}
+
+// GetTransactionsPage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - currency string
+//   - accountID string
+//   - filter api.TransactionFilter
+//   - cursor string
+//   - limit int
+func (_e *MockRepository_Expecter) GetTransactionsPage(ctx interface{}, currency interface{}, accountID interface{}, filter interface{}, cursor interface{}, limit interface{}) *MockRepository_GetTransactionsPage_Call {
+	return &MockRepository_GetTransactionsPage_Call{Call: _e.mock.On("GetTransactionsPage", ctx, currency, accountID, filter, cursor, limit)}
+}
+
+func (_c *MockRepository_GetTransactionsPage_Call) Run(run func(ctx context.Context, currency string, accountID string, filter api.TransactionFilter, cursor string, limit int)) *MockRepository_GetTransactionsPage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(api.TransactionFilter), args[4].(string), args[5].(int))
+	})
+	return _c
+}
+
+func (_c *MockRepository_GetTransactionsPage_Call) Return(_a0 *api.TransactionPage, _a1 error) *MockRepository_GetTransactionsPage_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_GetTransactionsPage_Call) RunAndReturn(run func(context.Context, string, string, api.TransactionFilter, string, int) (*api.TransactionPage, error)) *MockRepository_GetTransactionsPage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RegisterAlias provides a mock function with given fields: ctx, accountID, request
+func (_m *MockRepository) RegisterAlias(ctx context.Context, accountID string, request *api.RegisterAliasRequest) (*api.AccountAlias, error) {
+	ret := _m.Called(ctx, accountID, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterAlias")
+	}
+
+	var r0 *api.AccountAlias
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *api.RegisterAliasRequest) (*api.AccountAlias, error)); ok {
+		return rf(ctx, accountID, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *api.RegisterAliasRequest) *api.AccountAlias); ok {
+		r0 = rf(ctx, accountID, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.AccountAlias)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *api.RegisterAliasRequest) error); ok {
+		r1 = rf(ctx, accountID, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_RegisterAlias_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RegisterAlias'
+type MockRepository_RegisterAlias_Call struct {
+	*mock.Call
+}
+
+// RegisterAlias is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID string
+//   - request *api.RegisterAliasRequest
+func (_e *MockRepository_Expecter) RegisterAlias(ctx interface{}, accountID interface{}, request interface{}) *MockRepository_RegisterAlias_Call {
+	return &MockRepository_RegisterAlias_Call{Call: _e.mock.On("RegisterAlias", ctx, accountID, request)}
+}
+
+func (_c *MockRepository_RegisterAlias_Call) Run(run func(ctx context.Context, accountID string, request *api.RegisterAliasRequest)) *MockRepository_RegisterAlias_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*api.RegisterAliasRequest))
+	})
+	return _c
+}
+
+func (_c *MockRepository_RegisterAlias_Call) Return(_a0 *api.AccountAlias, _a1 error) *MockRepository_RegisterAlias_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_RegisterAlias_Call) RunAndReturn(run func(context.Context, string, *api.RegisterAliasRequest) (*api.AccountAlias, error)) *MockRepository_RegisterAlias_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResolveAlias provides a mock function with given fields: ctx, alias
+func (_m *MockRepository) ResolveAlias(ctx context.Context, alias string) (*api.AccountAlias, error) {
+	ret := _m.Called(ctx, alias)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResolveAlias")
+	}
+
+	var r0 *api.AccountAlias
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*api.AccountAlias, error)); ok {
+		return rf(ctx, alias)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *api.AccountAlias); ok {
+		r0 = rf(ctx, alias)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*api.AccountAlias)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, alias)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRepository_ResolveAlias_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResolveAlias'
+type MockRepository_ResolveAlias_Call struct {
+	*mock.Call
+}
+
+// ResolveAlias is a helper method to define mock.On call
+//   - ctx context.Context
+//   - alias string
+func (_e *MockRepository_Expecter) ResolveAlias(ctx interface{}, alias interface{}) *MockRepository_ResolveAlias_Call {
+	return &MockRepository_ResolveAlias_Call{Call: _e.mock.On("ResolveAlias", ctx, alias)}
+}
+
+func (_c *MockRepository_ResolveAlias_Call) Run(run func(ctx context.Context, alias string)) *MockRepository_ResolveAlias_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_ResolveAlias_Call) Return(_a0 *api.AccountAlias, _a1 error) *MockRepository_ResolveAlias_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRepository_ResolveAlias_Call) RunAndReturn(run func(context.Context, string) (*api.AccountAlias, error)) *MockRepository_ResolveAlias_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetParentAccount provides a mock function with given fields: ctx, accountID, parentID
+func (_m *MockRepository) SetParentAccount(ctx context.Context, accountID string, parentID string) error {
+	ret := _m.Called(ctx, accountID, parentID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetParentAccount")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, accountID, parentID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockRepository_SetParentAccount_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetParentAccount'
+type MockRepository_SetParentAccount_Call struct {
+	*mock.Call
+}
+
+// SetParentAccount is a helper method to define mock.On call
+//   - ctx context.Context
+//   - accountID string
+//   - parentID string
+func (_e *MockRepository_Expecter) SetParentAccount(ctx interface{}, accountID interface{}, parentID interface{}) *MockRepository_SetParentAccount_Call {
+	return &MockRepository_SetParentAccount_Call{Call: _e.mock.On("SetParentAccount", ctx, accountID, parentID)}
+}
+
+func (_c *MockRepository_SetParentAccount_Call) Run(run func(ctx context.Context, accountID string, parentID string)) *MockRepository_SetParentAccount_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockRepository_SetParentAccount_Call) Return(_a0 error) *MockRepository_SetParentAccount_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockRepository_SetParentAccount_Call) RunAndReturn(run func(context.Context, string, string) error) *MockRepository_SetParentAccount_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Transfer provides a mock function with given fields: ctx, request, idempotencyKey
 func (_m *MockRepository) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error) {
 	ret := _m.Called(ctx, request, idempotencyKey)
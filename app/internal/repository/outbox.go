@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	insertOutboxEvent = `INSERT INTO outbox_events (id, type, version, key, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	// only inserts the event when the account is actually created, in the same statement.
+	// the parameters of the select are cast, as their types can't be inferred from the target columns
+	insertAccountWithEvent = `
+		WITH created AS (
+			INSERT INTO accounts (user_id, currency)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, currency) DO NOTHING
+			RETURNING user_id
+		)
+		INSERT INTO outbox_events (id, type, version, key, payload, created_at)
+		SELECT $3::uuid, $4::varchar, $5::int, created.user_id, $6::jsonb, $7::timestamp FROM created`
+
+	// skipping the locked events lets several workers relay concurrently without publishing the same events
+	selectPendingEvents = `SELECT id, type, version, key, payload, created_at
+		FROM outbox_events
+		WHERE published_at IS NULL
+		ORDER BY seq
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	updateEventsPublished = `UPDATE outbox_events SET published_at = $2 WHERE id = ANY($1)`
+
+	loggedEventColumns = `seq, id, type, version, key, payload, created_at, published_at`
+
+	selectEventLog = `SELECT ` + loggedEventColumns + `
+		FROM outbox_events
+		WHERE seq > $1
+		ORDER BY seq
+		LIMIT $2`
+
+	// the relay publishes the event again in its next batch
+	requeueEvent = `UPDATE outbox_events SET published_at = NULL
+		WHERE id = $1
+		RETURNING ` + loggedEventColumns
+
+	// the events not projected yet are kept for the activity feeds, and the ones not receipted yet for the receipts, if enabled
+	deletePublishedEvents = `DELETE FROM outbox_events WHERE published_at < $1
+		AND (projected_at IS NOT NULL OR NOT $2)
+		AND (receipted_at IS NOT NULL OR NOT $3)`
+)
+
+// WithOutbox writes the ledger events to the outbox, in the same transaction as the ledger entries.
+// It's only needed when the events are relayed to a broker, otherwise the outbox would grow forever.
+func (r *PostgresRepository) WithOutbox() *PostgresRepository {
+	r.outbox = true
+
+	return r
+}
+
+// newEvent wraps the event data in its envelope.
+func (r *PostgresRepository) newEvent(eventType string, version int, key string, data any, createdAt time.Time) (*api.Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	return &api.Event{
+		ID:      r.idGenerator.NewID(),
+		Type:    eventType,
+		Version: version,
+		Key:     key,
+		Time:    createdAt,
+		Data:    payload,
+	}, nil
+}
+
+// insertTransferCreated adds the transfer.created event of the double entry to the transaction.
+func (r *PostgresRepository) insertTransferCreated(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, entry *doubleEntry, idempotencyKey string) error {
+	event, err := r.newEvent(api.EventTransferCreated, api.TransferCreatedVersion, request.FromAccountID, api.TransferCreated{
+		TransferID:    idempotencyKey,
+		DebitTxID:     entry.fromTxID,
+		CreditTxID:    entry.toTxID,
+		FromAccountID: request.FromAccountID,
+		ToAccountID:   request.ToAccountID,
+		Currency:      request.Currency,
+		Amount:        request.Amount,
+		Remarks:       request.Remarks,
+		Tags:          request.Tags,
+	}, entry.createdAt)
+	if err != nil {
+		return err
+	}
+
+	// as a string, because lib/pq sends the byte slices in the binary bytea format
+	_, err = tx.ExecContext(ctx, insertOutboxEvent, event.ID, event.Type, event.Version, event.Key, string(event.Data), event.Time)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// createAccountWithEvent creates the account if it doesn't exist, with its account.created event.
+func (r *PostgresRepository) createAccountWithEvent(ctx context.Context, accountID, currency string) error {
+	createdAt := r.clock.Now()
+
+	event, err := r.newEvent(api.EventAccountCreated, api.AccountCreatedVersion, accountID, api.AccountCreated{
+		AccountID: accountID,
+		Currency:  currency,
+	}, createdAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, insertAccountWithEvent, accountID, currency, event.ID, event.Type, event.Version, string(event.Data), createdAt)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// RelayEvents hands the oldest unpublished events, at most limit, to publish in order,
+// and marks them published once publish succeeds. It returns the number of events published.
+// The events stay locked until then, so publish should not take longer than the broker's timeout.
+// If the process dies after publishing but before marking them, they are published again: the delivery is at least once.
+func (r *PostgresRepository) RelayEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []api.Event) error) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, selectPendingEvents, limit)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	events := make([]api.Event, 0, limit)
+
+	for rows.Next() {
+		var (
+			event   api.Event
+			payload []byte
+		)
+
+		if err = rows.Scan(&event.ID, &event.Type, &event.Version, &event.Key, &payload, &event.Time); err != nil {
+			_ = rows.Close()
+
+			return 0, formatUnknownError(err)
+		}
+
+		event.Data = payload
+		events = append(events, event)
+	}
+
+	if err = rows.Close(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err = publish(ctx, events); err != nil {
+		return 0, fmt.Errorf("failed to publish events: %w", err)
+	}
+
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	if _, err = tx.ExecContext(ctx, updateEventsPublished, pq.Array(ids), r.clock.Now()); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return len(events), nil
+}
+
+// PurgePublishedEvents deletes the events published before the given time, and returns how many were deleted.
+// With the activity feeds, the events are only deleted once projected too, and with the receipts once receipted.
+func (r *PostgresRepository) PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, deletePublishedEvents, before, r.activityFeed, r.receipts)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return deleted, nil
+}
+
+// GetEventLog returns the events of the outbox after the cursor, at most limit, the oldest first.
+// An empty cursor starts from the oldest event kept, the published ones are only kept for EVENTS_RETENTION.
+func (r *PostgresRepository) GetEventLog(ctx context.Context, since string, limit int) (*api.EventLog, error) {
+	var seq int64
+
+	if since != "" {
+		parsed, err := strconv.ParseInt(since, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, api.ErrInvalidCursor
+		}
+
+		seq = parsed
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectEventLog, seq, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	log := &api.EventLog{
+		Events:     []*api.LoggedEvent{},
+		NextCursor: since,
+	}
+
+	for rows.Next() {
+		event, err := scanLoggedEvent(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		log.Events = append(log.Events, event)
+		log.NextCursor = event.Cursor
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return log, nil
+}
+
+// RedeliverEvent marks the event pending again, so the relay publishes it again in its next batch,
+// i.e. out of order, after the events published since. It returns the pending event.
+func (r *PostgresRepository) RedeliverEvent(ctx context.Context, id string) (*api.LoggedEvent, error) {
+	if !validID(id) {
+		return nil, api.ErrEventNotFound
+	}
+
+	event, err := scanLoggedEvent(r.db.QueryRowContext(ctx, requeueEvent, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrEventNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return event, nil
+}
+
+func scanLoggedEvent(row rowScanner) (*api.LoggedEvent, error) {
+	event := &api.LoggedEvent{}
+
+	var (
+		seq         int64
+		payload     []byte
+		publishedAt sql.NullTime
+	)
+
+	err := row.Scan(&seq, &event.ID, &event.Type, &event.Version, &event.Key, &payload, &event.Time, &publishedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	event.Cursor = strconv.FormatInt(seq, 10)
+	event.Data = payload
+	event.Time = event.Time.UTC()
+
+	if publishedAt.Valid {
+		at := publishedAt.Time.UTC()
+		event.PublishedAt = &at
+	}
+
+	return event, nil
+}
@@ -0,0 +1,163 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE outbox_events;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).WithOutbox()
+
+	request := &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "outbox_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+		Remarks:       "deposit",
+	}
+
+	txs, err := repo.Transfer(ctx, request, "outbox-key-1")
+	require.NoError(t, err)
+
+	// the accounts already exist, so only the transfer is recorded
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "outbox_user",
+		ToAccountID:   api.CompanyAccountID,
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(40),
+	}, "outbox-key-2")
+	require.NoError(t, err)
+
+	t.Run("A failed publish leaves the events pending", func(t *testing.T) {
+		errBroker := errors.New("broker down")
+
+		published, err := repo.RelayEvents(ctx, 10, func(context.Context, []api.Event) error {
+			return errBroker
+		})
+		require.ErrorIs(t, err, errBroker)
+		require.Zero(t, published)
+	})
+
+	t.Run("Events in order", func(t *testing.T) {
+		var relayed []api.Event
+
+		published, err := repo.RelayEvents(ctx, 10, func(_ context.Context, events []api.Event) error {
+			relayed = append(relayed, events...)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 4, published)
+
+		require.Equal(t, api.EventAccountCreated, relayed[0].Type)
+		require.Equal(t, api.CompanyAccountID, relayed[0].Key)
+		require.Equal(t, api.EventAccountCreated, relayed[1].Type)
+		require.Equal(t, "outbox_user", relayed[1].Key)
+		require.Equal(t, api.EventTransferCreated, relayed[2].Type)
+		require.Equal(t, api.EventTransferCreated, relayed[3].Type)
+
+		var transfer api.TransferCreated
+		require.NoError(t, json.Unmarshal(relayed[2].Data, &transfer))
+		require.Equal(t, api.TransferCreatedVersion, relayed[2].Version)
+		require.Equal(t, "outbox-key-1", transfer.TransferID)
+		require.Equal(t, "outbox_user", transfer.ToAccountID)
+		require.True(t, request.Amount.Equal(transfer.Amount))
+		require.ElementsMatch(t, []string{txs[0].TxID, txs[1].TxID}, []string{transfer.DebitTxID, transfer.CreditTxID})
+
+		// nothing left to publish
+		published, err = repo.RelayEvents(ctx, 10, func(context.Context, []api.Event) error {
+			t.Fatal("unexpected publish")
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Zero(t, published)
+	})
+
+	t.Run("Event log", func(t *testing.T) {
+		log, err := repo.GetEventLog(ctx, "", 3)
+		require.NoError(t, err)
+		require.Len(t, log.Events, 3)
+		require.NotNil(t, log.Events[0].PublishedAt)
+		require.Equal(t, log.Events[2].Cursor, log.NextCursor)
+
+		next, err := repo.GetEventLog(ctx, log.NextCursor, 3)
+		require.NoError(t, err)
+		require.Len(t, next.Events, 1)
+		require.Equal(t, api.EventTransferCreated, next.Events[0].Type)
+
+		empty, err := repo.GetEventLog(ctx, next.NextCursor, 3)
+		require.NoError(t, err)
+		require.Empty(t, empty.Events)
+		require.Equal(t, next.NextCursor, empty.NextCursor, "polled with the same cursor")
+
+		_, err = repo.GetEventLog(ctx, "-1", 3)
+		require.ErrorIs(t, err, api.ErrInvalidCursor)
+	})
+
+	t.Run("Redeliver", func(t *testing.T) {
+		log, err := repo.GetEventLog(ctx, "", 1)
+		require.NoError(t, err)
+
+		event, err := repo.RedeliverEvent(ctx, log.Events[0].ID)
+		require.NoError(t, err)
+		require.Nil(t, event.PublishedAt)
+
+		var relayed []api.Event
+
+		published, err := repo.RelayEvents(ctx, 10, func(_ context.Context, events []api.Event) error {
+			relayed = append(relayed, events...)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, published)
+		require.Equal(t, event.ID, relayed[0].ID)
+
+		_, err = repo.RedeliverEvent(ctx, "00000000-0000-4000-8000-000000000000")
+		require.ErrorIs(t, err, api.ErrEventNotFound)
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		purged, err := repo.PurgePublishedEvents(ctx, time.Now().UTC().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, purged)
+
+		purged, err = repo.PurgePublishedEvents(ctx, time.Now().UTC().Add(time.Hour))
+		require.NoError(t, err)
+		require.EqualValues(t, 4, purged)
+	})
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		_, err := repository.NewPostgresRepository(db).Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "no_outbox_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "outbox-key-3")
+		require.NoError(t, err)
+
+		var count int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT count(1) FROM outbox_events").Scan(&count))
+		require.Zero(t, count)
+	})
+}
@@ -5,57 +5,131 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/app/internal/tagging"
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/devshark/wallet/pkg/idgen"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
 type PostgresRepository struct {
-	db     *sql.DB
-	logger *log.Logger
+	db          *sql.DB
+	logger      *slog.Logger
+	clock       clock.Clock
+	idGenerator idgen.Generator
+	outbox      bool
+	screening   api.ScreeningProvider
+	// the amounts above which the transfers wait for an approval, by currency
+	approvalThresholds map[string]decimal.Decimal
+	taxonomy           tagging.Taxonomy
+	// keyring wraps the data keys encrypting the remarks, they aren't encrypted without it
+	keyring *crypt.Keyring
+	// the deposits of each account in a sandbox, by currency, see WithSandboxQuotas
+	sandboxQuotas map[string]decimal.Decimal
+	// the outbox events are kept until they're projected to the activity feeds
+	activityFeed bool
+	// the outbox events are kept until their receipts are queued
+	receipts bool
+	// companyAccountID is the settlement account funding the deposits and receiving the withdrawals
+	companyAccountID string
+	// features switch the provisioning and account id policies, see WithFeatures
+	features features.Features
+	// hooks intercept the transfers, see WithTransferHooks
+	hooks []TransferHook
+	// planSampling is the percent of the calls whose query plans are logged, see WithQueryPlanSampling
+	planSampling float64
+	// lockStrategy is how the transfers lock their accounts, see WithLockStrategy
+	lockStrategy LockStrategy
+	// fxRates quote the cross-currency transfers, see WithFXRates
+	fxRates api.FXRateProvider
+	// rounding rounds the amounts computed by the repository, i.e. the conversions and the fees
+	rounding rounding.Policies
+	// fees charges the fees of the fee rules on the transfers, see WithFees
+	fees bool
 }
 
 const (
-	insertStatement = `INSERT INTO transactions (account_id, amount, debit_credit, description, group_id) 
-		VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	insertStatement = `INSERT INTO transactions (id, account_id, amount, debit_credit, description, group_id, created_at, tags, fx_rate) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
 
 	selectLockAccount = `SELECT id, balance
 		FROM accounts
 		WHERE user_id = $1 AND currency = $2
 		FOR NO KEY UPDATE;`
+	// the balance less the amount of the authorized holds, which a transfer can't spend
+	selectLockAccountAvailable = `SELECT id, balance - held
+		FROM accounts
+		WHERE user_id = $1 AND currency = $2
+		FOR NO KEY UPDATE;`
 	selectGroupExists    = `SELECT count(1) FROM transactions WHERE group_id = $1`
-	selectAccountBalance = `SELECT balance
+	selectAccountBalance = `SELECT balance, held
 		FROM accounts
 		WHERE user_id = $1 AND currency = $2;`
 	selectTransaction = `
-		SELECT transactions.id, 
-			accounts.user_id, accounts.currency, transactions.amount, transactions.debit_credit, 
-			accounts.balance, transactions.description, transactions.created_at 
-		FROM transactions 
+		SELECT ` + transactionColumns + `
+		FROM transactions
 		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
 		WHERE transactions.id = $1`
 
 	selectTransactions = `
-		SELECT transactions.id, 
-			accounts.user_id, accounts.currency, transactions.amount, transactions.debit_credit, 
-			accounts.balance, transactions.description, transactions.created_at 
-		FROM transactions 
+		SELECT ` + transactionColumns + `
+		FROM transactions
 		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
 		WHERE accounts.currency = $1 AND accounts.user_id = $2
 		ORDER BY transactions.created_at DESC`
 
+	// the conditions of api.TransactionFilter, its arguments from $3, see filterArgs
+	transactionFilterConditions = `transactions.tags @> $3
+			AND ($4::TIMESTAMP IS NULL OR transactions.created_at >= $4)
+			AND ($5::TIMESTAMP IS NULL OR transactions.created_at < $5)
+			AND ($6::TEXT = '' OR transactions.debit_credit::TEXT = $6)`
+
+	selectFilteredTransactions = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND ` + transactionFilterConditions + `
+		ORDER BY transactions.created_at DESC`
+
 	selectTransactionPair = `
-		SELECT transactions.id, 
-			accounts.user_id, accounts.currency, transactions.amount, transactions.debit_credit, 
-			accounts.balance, transactions.description, transactions.created_at 
-		FROM transactions 
+		SELECT ` + transactionColumns + `
+		FROM transactions
 		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
 		WHERE transactions.id in ($1, $2)
 		ORDER BY transactions.created_at DESC`
 
-	updateAccountBalance = `UPDATE accounts SET balance = balance + $1 WHERE user_id = $2 AND currency = $3 RETURNING balance;`
+	// transactionColumns are the columns of a ledger entry, in the order of scanTransaction, joined with
+	// transaction_metadata and counterpartyJoin
+	transactionColumns = `transactions.id,
+			accounts.user_id, accounts.currency, transactions.amount, transactions.debit_credit,
+			accounts.balance, COALESCE(transaction_metadata.remarks, transactions.description), transactions.created_at,
+			transactions.tags, COALESCE(transaction_metadata.reference, ''), transactions.group_id, COALESCE(counterparty.user_id, ''), transactions.fx_rate`
+
+	// counterpartyJoin joins the account of the other leg of the transfer of each ledger entry, through their group,
+	// as the counterparty of the entry
+	counterpartyJoin = `LEFT JOIN transactions other_leg ON other_leg.group_id = transactions.group_id
+			AND other_leg.debit_credit <> transactions.debit_credit
+		LEFT JOIN accounts counterparty ON counterparty.id = other_leg.account_id`
+
+	// returns the available balance, less the amount of the authorized holds
+	updateAccountBalance = `UPDATE accounts SET balance = balance + $1 WHERE user_id = $2 AND currency = $3 RETURNING balance - held;`
 
 	upsertAccount = `
 		INSERT INTO accounts (user_id, currency)
@@ -69,24 +143,63 @@ const (
 	// because it isn't simple to know the number of rows in the result, we will initiate the slice with 10 capacity
 	// we will not need this if we implement a proper pagination, but we aim to only deliver a simplified version.
 	defaultTransactionSliceCapacity = 10
-
-	// we know for certain that double-entry transactions always have exactly 2 records
-	transactionPairCapacity = 2
 )
 
 func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{
-		db:     db,
-		logger: log.Default(),
+		db:          db,
+		logger:      logging.Component(slog.Default(), "repository"),
+		clock:       clock.NewSystemClock(),
+		idGenerator: idgen.NewUUIDGenerator(),
+		taxonomy:    tagging.Any(),
+		features:    features.Disabled(),
+
+		companyAccountID: api.CompanyAccountID,
+		lockStrategy:     LockRows,
 	}
 }
 
-func (r *PostgresRepository) WithCustomLogger(logger *log.Logger) *PostgresRepository {
-	r.logger = logger
+func (r *PostgresRepository) WithCustomLogger(logger *slog.Logger) *PostgresRepository {
+	r.logger = logging.Component(logger, "repository")
+
+	return r
+}
+
+// WithClock overrides the clock used for the transaction timestamps.
+func (r *PostgresRepository) WithClock(c clock.Clock) *PostgresRepository {
+	r.clock = c
 
 	return r
 }
 
+// WithIDGenerator overrides the generator of the transaction ids. The ids must be valid UUIDs.
+func (r *PostgresRepository) WithIDGenerator(generator idgen.Generator) *PostgresRepository {
+	r.idGenerator = generator
+
+	return r
+}
+
+// WithCompanyAccount overrides the settlement account, api.CompanyAccountID by default.
+// Its balance may go negative, and it can't be a sub-account, have aliases or be erased.
+func (r *PostgresRepository) WithCompanyAccount(accountID string) *PostgresRepository {
+	r.companyAccountID = accountID
+
+	return r
+}
+
+// WithRounding rounds the amounts the repository computes, i.e. the converted amounts and the fees,
+// with the policy of their currency. They're kept at the scale of their computation otherwise.
+func (r *PostgresRepository) WithRounding(policies rounding.Policies) *PostgresRepository {
+	r.rounding = policies
+
+	return r
+}
+
+// isCompanyAccount reports whether the account is the settlement account.
+func (r *PostgresRepository) isCompanyAccount(accountID string) bool {
+	return strings.EqualFold(strings.TrimSpace(accountID), r.companyAccountID)
+}
+
 func (r *PostgresRepository) GetAccountBalance(ctx context.Context, currency, accountID string) (*api.Account, error) {
 	account := &api.Account{
 		Currency:  strings.ToUpper(strings.TrimSpace(currency)),
@@ -98,10 +211,12 @@ func (r *PostgresRepository) GetAccountBalance(ctx context.Context, currency, ac
 	}
 
 	// the last transaction for the account currency
-	err := r.db.QueryRowContext(ctx, selectAccountBalance, account.AccountID, account.Currency).Scan(&account.Balance)
+	var held decimal.Decimal
+
+	err := r.db.QueryRowContext(ctx, selectAccountBalance, account.AccountID, account.Currency).Scan(&account.Balance, &held)
 	if err != nil {
 		// if the account is the company account, the initial balance must be 0
-		if errors.Is(err, sql.ErrNoRows) && strings.EqualFold(account.AccountID, api.CompanyAccountID) {
+		if errors.Is(err, sql.ErrNoRows) && r.isCompanyAccount(account.AccountID) {
 			return &api.Account{
 				Currency:  account.Currency,
 				AccountID: account.AccountID,
@@ -112,6 +227,10 @@ func (r *PostgresRepository) GetAccountBalance(ctx context.Context, currency, ac
 		return nil, fmt.Errorf("failed to get account balance for %s: %s: %w", account.AccountID, err.Error(), api.ErrAccountNotFound)
 	}
 
+	if !held.IsZero() {
+		account.Held = &held
+	}
+
 	return account, nil
 }
 
@@ -121,11 +240,7 @@ func (r *PostgresRepository) GetTransaction(ctx context.Context, txID string) (*
 		return nil, api.ErrInvalidTxID
 	}
 
-	row := r.db.QueryRowContext(ctx, selectTransaction, txID)
-
-	tx := &api.Transaction{}
-
-	err := row.Scan(&tx.TxID, &tx.AccountID, &tx.Currency, &tx.Amount, &tx.Type, &tx.RunningBalance, &tx.Remarks, &tx.Time)
+	tx, err := scanTransaction(r.db.QueryRowContext(ctx, selectTransaction, txID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to get transaction: %s: %w", err.Error(), api.ErrTransactionNotFound)
@@ -134,6 +249,10 @@ func (r *PostgresRepository) GetTransaction(ctx context.Context, txID string) (*
 		return nil, formatUnknownError(err)
 	}
 
+	if err = r.openTransactions(ctx, tx); err != nil {
+		return nil, err
+	}
+
 	return tx, nil
 }
 
@@ -148,14 +267,61 @@ func (r *PostgresRepository) GetTransactions(ctx context.Context, currency, acco
 		return nil, formatUnknownError(err)
 	}
 
+	transactions, err := r.readTransactions(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	r.samplePlans(ctx, "GetTransactions", plannedQuery{name: "select_transactions", query: selectTransactions, args: []any{currency, accountID}})
+
+	return transactions, nil
+}
+
+// FilterTransactions returns the transactions of the account matching the filter, most recent first.
+func (r *PostgresRepository) FilterTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter) ([]*api.Transaction, error) {
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	args, err := r.filterArgs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectFilteredTransactions, append([]any{currency, accountID}, args...)...)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.readTransactions(ctx, rows)
+}
+
+// filterArgs returns the arguments of the transactionFilterConditions, once the filter is validated.
+func (r *PostgresRepository) filterArgs(filter api.TransactionFilter) ([]any, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	tags, err := r.taxonomy.Normalize(filter.Tags)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	// the times are stored in UTC, without their time zone
+	from := sql.NullTime{Time: filter.From.UTC(), Valid: !filter.From.IsZero()}
+	to := sql.NullTime{Time: filter.To.UTC(), Valid: !filter.To.IsZero()}
+
+	return []any{pq.Array(tags), from, to, string(filter.Type)}, nil
+}
+
+// scanTransactions reads the transactions of the rows, then closes them.
+func scanTransactions(rows *sql.Rows) ([]*api.Transaction, error) {
 	defer rows.Close()
 
 	transactions := make([]*api.Transaction, 0, defaultTransactionSliceCapacity)
 
 	for rows.Next() {
-		tx := &api.Transaction{}
-
-		err = rows.Scan(&tx.TxID, &tx.AccountID, &tx.Currency, &tx.Amount, &tx.Type, &tx.RunningBalance, &tx.Remarks, &tx.Time)
+		tx, err := scanTransaction(rows)
 		if err != nil {
 			return nil, formatUnknownError(err)
 		}
@@ -163,13 +329,42 @@ func (r *PostgresRepository) GetTransactions(ctx context.Context, currency, acco
 		transactions = append(transactions, tx)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, formatUnknownError(err)
 	}
 
 	return transactions, nil
 }
 
+// scanTransaction reads the columns of selectTransaction, i.e. with the amended metadata overlaid on the ledger entry.
+//
+//nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+func scanTransaction(row rowScanner) (*api.Transaction, error) {
+	tx := &api.Transaction{}
+
+	var tags pq.StringArray
+
+	var fxRate decimal.NullDecimal
+
+	err := row.Scan(&tx.TxID, &tx.AccountID, &tx.Currency, &tx.Amount, &tx.Type, &tx.RunningBalance, &tx.Remarks, &tx.Time, &tags, &tx.Reference, &tx.GroupID, &tx.Counterparty, &fxRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if fxRate.Valid {
+		tx.FXRate = &fxRate.Decimal
+	}
+
+	// created_at is a timestamp without time zone, written in UTC
+	tx.Time = tx.Time.UTC()
+
+	if len(tags) > 0 {
+		tx.Tags = tags
+	}
+
+	return tx, nil
+}
+
 type account struct {
 	id      string
 	balance decimal.Decimal
@@ -181,43 +376,84 @@ type accountPairBalance struct {
 }
 
 func (r *PostgresRepository) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error) {
-	request.Currency = strings.ToUpper(strings.TrimSpace(request.Currency))
-	request.FromAccountID = strings.TrimSpace(request.FromAccountID)
-	request.ToAccountID = strings.TrimSpace(request.ToAccountID)
+	txs, err := r.transfer(ctx, request, idempotencyKey, holds{screening: r.screening, approval: true})
+	if errors.Is(err, api.ErrUnhandledDatabaseError) {
+		// logged with the context, so the line carries the id of the request that failed
+		r.logger.ErrorContext(ctx, "failed to post the transfer", slog.String("idempotency_key", idempotencyKey),
+			slog.String("from", request.FromAccountID), slog.String("to", request.ToAccountID),
+			slog.String("currency", request.Currency), slog.Any("error", err))
+	}
 
-	var err error
+	if err == nil {
+		r.samplePlans(ctx, "Transfer", transferPlans(request, idempotencyKey, txs)...)
+	}
 
-	if err = validateCurrencyAndAccount(request.Currency, request.FromAccountID); err != nil {
+	return txs, err
+}
+
+// holds are the checks holding a transfer instead of posting it, skipped once it's been approved.
+type holds struct {
+	screening api.ScreeningProvider
+	approval  bool
+}
+
+// transfer posts the double entry, unless one of the holds keeps it for a decision.
+func (r *PostgresRepository) transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string, checks holds) (txs []*api.Transaction, err error) {
+	defer func() {
+		r.afterTransfer(ctx, request, idempotencyKey, txs, err)
+	}()
+
+	if err = r.validateTransfer(ctx, request); err != nil {
 		return nil, err
 	}
 
-	if err = validateCurrencyAndAccount(request.Currency, request.ToAccountID); err != nil {
+	// check if the tx already exists
+	var existingCount int
+	if err = r.db.QueryRowContext(ctx, selectGroupExists, idempotencyKey).Scan(&existingCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		// the ledger entries are grouped by the idempotency key
+		return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+	}
+
+	if err = r.checkSandboxQuota(ctx, request); err != nil {
 		return nil, err
 	}
 
-	if strings.EqualFold(request.FromAccountID, request.ToAccountID) {
-		return nil, api.ErrSameAccountIDs
+	if checks.screening != nil {
+		if err = r.screen(ctx, checks.screening, request, idempotencyKey); err != nil {
+			return nil, err
+		}
 	}
 
-	if request.Amount.IsZero() {
-		return nil, api.ErrInvalidAmount
+	if checks.approval {
+		if err = r.holdForApproval(ctx, request, idempotencyKey); err != nil {
+			return nil, err
+		}
 	}
 
-	if request.Amount.IsNegative() {
-		return nil, api.ErrNegativeAmount
+	// quoted once the transfer is posted rather than held, so the approved transfers are converted at the rate of the day
+	var fx *conversion
+
+	if request.ToCurrency != "" {
+		if fx, err = r.quoteConversion(ctx, request, idempotencyKey); err != nil {
+			return nil, err
+		}
 	}
 
-	// check if the tx already exists
-	var existingCount int
-	if err = r.db.QueryRowContext(ctx, selectGroupExists, idempotencyKey).Scan(&existingCount); err != nil {
-		return nil, formatUnknownError(err)
+	// charged at the rule of the day as well
+	fee, err := r.quoteFee(ctx, request, idempotencyKey)
+	if err != nil {
+		return nil, err
 	}
 
-	if existingCount > 0 {
-		return nil, api.ErrDuplicateTransaction
+	if err = r.openAccounts(ctx, request, fx); err != nil {
+		return nil, err
 	}
 
-	if err = upsertAccounts(ctx, r.db, request); err != nil {
+	if err = r.openFeesAccount(ctx, fee); err != nil {
 		return nil, err
 	}
 
@@ -227,21 +463,20 @@ func (r *PostgresRepository) Transfer(ctx context.Context, request *api.Transfer
 		return nil, formatUnknownError(err)
 	}
 
-	accountBalances, err := lockAccounts(ctx, tx, request)
-	if err != nil {
+	if err = r.beforeTransfer(ctx, tx, request, idempotencyKey); err != nil {
 		_ = tx.Rollback()
 
 		return nil, err
 	}
 
-	newTxIDFromTransfer, newTxIDToTransfer, err := createDoubleEntry(ctx, tx, request, accountBalances.from.id, accountBalances.to.id, idempotencyKey)
+	newTxIDFromTransfer, newTxIDToTransfer, err := r.postTransfer(ctx, tx, request, fx, idempotencyKey)
 	if err != nil {
 		_ = tx.Rollback()
 
 		return nil, err
 	}
 
-	if err = updateBalances(ctx, tx, request); err != nil {
+	if err = r.postFee(ctx, tx, fee, idempotencyKey); err != nil {
 		_ = tx.Rollback()
 
 		return nil, err
@@ -251,12 +486,124 @@ func (r *PostgresRepository) Transfer(ctx context.Context, request *api.Transfer
 		return nil, formatUnknownError(err)
 	}
 
-	txs, err := r.getTransactionsByIDs(ctx, newTxIDFromTransfer, newTxIDToTransfer)
+	txs, err = r.getTransactionsByIDs(ctx, newTxIDFromTransfer, newTxIDToTransfer)
 	if err != nil {
 		return nil, err
 	}
 
-	return txs, nil
+	return withFee(txs, newTxIDFromTransfer, fee), nil
+}
+
+// validateTransfer normalizes the request, and checks it can be posted, before any account is opened.
+func (r *PostgresRepository) validateTransfer(ctx context.Context, request *api.TransferRequest) (err error) {
+	request.Currency = strings.ToUpper(strings.TrimSpace(request.Currency))
+	request.FromAccountID = strings.TrimSpace(request.FromAccountID)
+	request.ToAccountID = strings.TrimSpace(request.ToAccountID)
+
+	if err = validateCurrencyAndAccount(request.Currency, request.FromAccountID); err != nil {
+		return err
+	}
+
+	if err = validateCurrencyAndAccount(request.Currency, request.ToAccountID); err != nil {
+		return err
+	}
+
+	if err = r.validateConversion(request); err != nil {
+		return err
+	}
+
+	if err = r.validateAccountIDFormat(ctx, request.FromAccountID); err != nil {
+		return err
+	}
+
+	if err = r.validateAccountIDFormat(ctx, request.ToAccountID); err != nil {
+		return err
+	}
+
+	if strings.EqualFold(request.FromAccountID, request.ToAccountID) {
+		return api.ErrSameAccountIDs
+	}
+
+	// the disputed amounts are only moved by the disputes, and the fees by their entries
+	if strings.EqualFold(request.FromAccountID, api.DisputesAccountID) || strings.EqualFold(request.ToAccountID, api.DisputesAccountID) ||
+		strings.EqualFold(request.FromAccountID, api.FeesAccountID) || strings.EqualFold(request.ToAccountID, api.FeesAccountID) {
+		return api.ErrCompanyAccount
+	}
+
+	if request.Amount.IsZero() {
+		return api.ErrInvalidAmount
+	}
+
+	if request.Amount.IsNegative() {
+		return api.ErrNegativeAmount
+	}
+
+	// checked before the NUMERIC columns, whose errors would be unhandled database errors
+	if err = api.ValidateAmount(request.Amount); err != nil {
+		return err
+	}
+
+	if request.Tags, err = r.taxonomy.Normalize(request.Tags); err != nil {
+		return err
+	}
+
+	if err = r.checkHierarchy(ctx, request); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// postDoubleEntry posts the transfer within the transaction, and returns the ids of its ledger entries.
+// The accounts must exist, see upsertAccounts.
+func (r *PostgresRepository) postDoubleEntry(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, idempotencyKey string) (string, string, error) {
+	return r.postEntries(ctx, tx, request, idempotencyKey, decimal.NullDecimal{})
+}
+
+// postEntries posts the double entry of postDoubleEntry, recording the rate of the conversion it's a leg of, if any.
+func (r *PostgresRepository) postEntries(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, idempotencyKey string, fxRate decimal.NullDecimal) (string, string, error) {
+	// only the company account may go negative, it funds the deposits
+	allowNegative := r.isCompanyAccount(request.FromAccountID)
+
+	accountBalances, err := r.lockAccounts(ctx, tx, request, allowNegative)
+	if err != nil {
+		return "", "", err
+	}
+
+	entry := &doubleEntry{
+		fromTxID:  r.idGenerator.NewID(),
+		toTxID:    r.idGenerator.NewID(),
+		createdAt: r.clock.Now(),
+		fxRate:    fxRate,
+	}
+
+	// each side of the transfer is encrypted with the key of its account
+	if entry.fromRemarks, err = r.sealRemarks(ctx, tx, request.FromAccountID, request.Remarks); err != nil {
+		return "", "", err
+	}
+
+	if entry.toRemarks, err = r.sealRemarks(ctx, tx, request.ToAccountID, request.Remarks); err != nil {
+		return "", "", err
+	}
+
+	newTxIDFromTransfer, newTxIDToTransfer, err := createDoubleEntry(ctx, tx, request, entry, accountBalances.from.id, accountBalances.to.id, idempotencyKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !r.balancesUpdated() {
+		if err = updateBalances(ctx, tx, request, allowNegative); err != nil {
+			return "", "", err
+		}
+	}
+
+	if r.outbox {
+		if err = r.insertTransferCreated(ctx, tx, request, entry, idempotencyKey); err != nil {
+			return "", "", err
+		}
+	}
+
+	return newTxIDFromTransfer, newTxIDToTransfer, nil
 }
 
 func validateCurrencyAndAccount(currency, accountID string) error {
@@ -275,8 +622,19 @@ func formatUnknownError(err error) error {
 	return fmt.Errorf("%w: %w", api.ErrUnhandledDatabaseError, err)
 }
 
+// the ids, timestamp and remarks of the ledger entries, provided by the repository instead of the database defaults.
+type doubleEntry struct {
+	fromTxID    string
+	toTxID      string
+	createdAt   time.Time
+	fromRemarks string
+	toRemarks   string
+	// fxRate is the rate of the conversion the entries are a leg of, if any
+	fxRate decimal.NullDecimal
+}
+
 // Create new double entry transactions of from and to accounts, respectively.
-func createDoubleEntry(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, fromAccountDatabaseID, toAccountDatabaseID, idempotencyKey string) (string, string, error) {
+func createDoubleEntry(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, entry *doubleEntry, fromAccountDatabaseID, toAccountDatabaseID, idempotencyKey string) (string, string, error) {
 	// Prepare the reusable statement for optimized performance of repeated queries.
 	newTxStatement, err := tx.PrepareContext(ctx, insertStatement)
 	if err != nil {
@@ -287,14 +645,14 @@ func createDoubleEntry(ctx context.Context, tx *sql.Tx, request *api.TransferReq
 
 	var newIDFromAccount string
 
-	err = newTxStatement.QueryRowContext(ctx, fromAccountDatabaseID, request.Amount, api.DEBIT, request.Remarks, idempotencyKey).Scan(&newIDFromAccount)
+	err = newTxStatement.QueryRowContext(ctx, entry.fromTxID, fromAccountDatabaseID, request.Amount, api.DEBIT, entry.fromRemarks, idempotencyKey, entry.createdAt, pq.Array(tagsOf(request.Tags)), entry.fxRate).Scan(&newIDFromAccount)
 	if err != nil {
 		return "", "", formatUnknownError(err)
 	}
 
 	var newIDToAccount string
 
-	err = newTxStatement.QueryRowContext(ctx, toAccountDatabaseID, request.Amount, api.CREDIT, request.Remarks, idempotencyKey).Scan(&newIDToAccount)
+	err = newTxStatement.QueryRowContext(ctx, entry.toTxID, toAccountDatabaseID, request.Amount, api.CREDIT, entry.toRemarks, idempotencyKey, entry.createdAt, pq.Array(tagsOf(request.Tags)), entry.fxRate).Scan(&newIDToAccount)
 	if err != nil {
 		return "", "", formatUnknownError(err)
 	}
@@ -302,8 +660,8 @@ func createDoubleEntry(ctx context.Context, tx *sql.Tx, request *api.TransferReq
 	return newIDFromAccount, newIDToAccount, nil
 }
 
-// Updates balances for both sides of the account. if it results in negative balance, return api.ErrInsufficientBalance
-func updateBalances(ctx context.Context, tx *sql.Tx, request *api.TransferRequest) error {
+// Updates balances for both sides of the account. if it results in negative available balance, return api.ErrInsufficientBalance
+func updateBalances(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, allowNegative bool) error {
 	// Prepare the reusable statement for optimized performance of repeated queries.
 	updateBalanceStatement, err := tx.PrepareContext(ctx, updateAccountBalance)
 	if err != nil {
@@ -318,7 +676,6 @@ func updateBalances(ctx context.Context, tx *sql.Tx, request *api.TransferReques
 	}
 
 	// do the check again to ensure the balance didn't go negative
-	allowNegative := strings.EqualFold(request.FromAccountID, api.CompanyAccountID)
 	if newSourceBalance.IsNegative() && !allowNegative {
 		return api.ErrInsufficientBalance // and then rollback
 	}
@@ -331,11 +688,11 @@ func updateBalances(ctx context.Context, tx *sql.Tx, request *api.TransferReques
 	return nil
 }
 
-// Pessimistic lock of both accounts. Returns the accountPairBalance{}.
+// Pessimistic lock of both accounts, see LockRows. Returns the accountPairBalance{}.
 // also returns api.ErrInsufficientBalance if the source account can't cover requested amount.
-func lockAccounts(ctx context.Context, tx *sql.Tx, request *api.TransferRequest) (*accountPairBalance, error) {
+func lockAccountRows(ctx context.Context, tx *sql.Tx, request *api.TransferRequest, allowNegative bool) (*accountPairBalance, error) {
 	// Prepare the reusable statement for optimized performance of repeated queries.
-	lockStatement, err := tx.PrepareContext(ctx, selectLockAccount)
+	lockStatement, err := tx.PrepareContext(ctx, selectLockAccountAvailable)
 	if err != nil {
 		return nil, formatUnknownError(err)
 	}
@@ -348,7 +705,6 @@ func lockAccounts(ctx context.Context, tx *sql.Tx, request *api.TransferRequest)
 	}
 
 	// we're doing the checks here as it's unnecessary to return and process elsewhere
-	allowNegative := strings.EqualFold(request.FromAccountID, api.CompanyAccountID)
 	if from.balance.LessThan(request.Amount) && !allowNegative {
 		return nil, api.ErrInsufficientBalance
 	}
@@ -367,9 +723,21 @@ func lockAccounts(ctx context.Context, tx *sql.Tx, request *api.TransferRequest)
 }
 
 // Upsert ensures the account exists before we lock them.
-func upsertAccounts(ctx context.Context, db *sql.DB, request *api.TransferRequest) error {
+func (r *PostgresRepository) upsertAccounts(ctx context.Context, request *api.TransferRequest) error {
+	if err := r.checkProvisioning(ctx, request); err != nil {
+		return err
+	}
+
+	if r.outbox {
+		if err := r.createAccountWithEvent(ctx, request.FromAccountID, request.Currency); err != nil {
+			return err
+		}
+
+		return r.createAccountWithEvent(ctx, request.ToAccountID, request.Currency)
+	}
+
 	// Prepare the reusable statement for optimized performance.
-	upsertAccountStatement, err := db.PrepareContext(ctx, upsertAccount)
+	upsertAccountStatement, err := r.db.PrepareContext(ctx, upsertAccount)
 	if err != nil {
 		return formatUnknownError(err)
 	}
@@ -390,30 +758,10 @@ func upsertAccounts(ctx context.Context, db *sql.DB, request *api.TransferReques
 }
 
 func (r *PostgresRepository) getTransactionsByIDs(ctx context.Context, txID1, txID2 string) ([]*api.Transaction, error) {
-	transactions, err := r.db.QueryContext(ctx, selectTransactionPair, txID1, txID2)
+	rows, err := r.db.QueryContext(ctx, selectTransactionPair, txID1, txID2)
 	if err != nil {
 		return nil, formatUnknownError(err)
 	}
 
-	defer transactions.Close()
-
-	// there are exactly 2 entries produced by every transfer
-	txs := make([]*api.Transaction, 0, transactionPairCapacity)
-
-	for transactions.Next() {
-		var tx api.Transaction
-
-		err = transactions.Scan(&tx.TxID, &tx.AccountID, &tx.Currency, &tx.Amount, &tx.Type, &tx.RunningBalance, &tx.Remarks, &tx.Time)
-		if err != nil {
-			return nil, formatUnknownError(err)
-		}
-
-		txs = append(txs, &tx)
-	}
-
-	if err = transactions.Err(); err != nil {
-		return nil, formatUnknownError(err)
-	}
-
-	return txs, nil
+	return r.readTransactions(ctx, rows)
 }
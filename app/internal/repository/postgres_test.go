@@ -4,10 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/devshark/wallet/api"
 	"github.com/devshark/wallet/app/internal/migration"
@@ -154,7 +155,7 @@ func TestGetAccountBalance(t *testing.T) {
 	setCleanUp(t, db)
 
 	repo := repository.NewPostgresRepository(db)
-	repo.WithCustomLogger(log.Default())
+	repo.WithCustomLogger(slog.Default())
 
 	t.Run("OK", func(t *testing.T) {
 		ctx := context.Background()
@@ -203,7 +204,7 @@ func TestGetAccountBalanceFail(t *testing.T) {
 	setCleanUp(t, db)
 
 	repo := repository.NewPostgresRepository(db)
-	repo.WithCustomLogger(log.Default())
+	repo.WithCustomLogger(slog.Default())
 
 	t.Run("Validation Failed", func(t *testing.T) {
 		ctx := context.Background()
@@ -302,7 +303,7 @@ func TestGetTransactionFail(t *testing.T) {
 	setCleanUp(t, db)
 
 	repo := repository.NewPostgresRepository(db)
-	repo.WithCustomLogger(log.Default())
+	repo.WithCustomLogger(slog.Default())
 
 	t.Run("Empty Tx ID", func(t *testing.T) {
 		ctx := context.Background()
@@ -327,7 +328,7 @@ func TestGetTransactionsFail(t *testing.T) {
 	setCleanUp(t, db)
 
 	repo := repository.NewPostgresRepository(db)
-	repo.WithCustomLogger(log.Default())
+	repo.WithCustomLogger(slog.Default())
 
 	t.Run("Validation Failed", func(t *testing.T) {
 		ctx := context.Background()
@@ -445,6 +446,24 @@ func TestTransfer(t *testing.T) {
 		txs, err := repo.Transfer(ctx, request, "some-idempotency-key")
 		require.NoError(t, err)
 		require.Len(t, txs, 2) // Expecting two transactions for a transfer
+
+		// the legs are paired by their group, and name each other's account
+		for _, tx := range txs {
+			require.Equal(t, "some-idempotency-key", tx.GroupID)
+		}
+
+		require.Equal(t, txs[1].AccountID, txs[0].Counterparty)
+		require.Equal(t, txs[0].AccountID, txs[1].Counterparty)
+
+		tx, err := repo.GetTransaction(ctx, txs[0].TxID)
+		require.NoError(t, err)
+		require.Equal(t, "some-idempotency-key", tx.GroupID)
+		require.Equal(t, txs[0].Counterparty, tx.Counterparty)
+
+		listed, err := repo.GetTransactions(ctx, "USD", "user2")
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, api.CompanyAccountID, listed[0].Counterparty)
 	})
 
 	t.Run("Refund", func(t *testing.T) {
@@ -538,6 +557,16 @@ func TestTransferFail(t *testing.T) {
 					Remarks:       "ErrNegativeAmount",
 				},
 			},
+			{
+				expectedError: api.ErrAmountOutOfRange,
+				TransferRequest: api.TransferRequest{
+					FromAccountID: api.CompanyAccountID,
+					ToAccountID:   "user2",
+					Currency:      "USD",
+					Amount:        decimal.New(1, 400),
+					Remarks:       "ErrAmountOutOfRange",
+				},
+			},
 			{
 				expectedError: api.ErrInvalidAmount,
 				TransferRequest: api.TransferRequest{
@@ -615,6 +644,10 @@ func TestTransferFail(t *testing.T) {
 		require.Error(t, err)
 		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
 		require.Nil(t, txs)
+
+		duplicate := &api.DuplicateTransactionError{}
+		require.ErrorAs(t, err, &duplicate)
+		require.Equal(t, "duplicate-idempotency-key", duplicate.GroupID, "the group of the original transfer")
 	})
 
 	t.Run("Duplicate Idempotency Key", func(t *testing.T) {
@@ -950,3 +983,48 @@ func TestDirectQueriesPOC(t *testing.T) {
 	err = tx.Commit()
 	require.NoError(t, err)
 }
+
+func TestFilterTransactions(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	repo := repository.NewPostgresRepository(db)
+	start := time.Now().Add(-time.Minute)
+
+	for i, request := range []*api.TransferRequest{
+		{FromAccountID: api.CompanyAccountID, ToAccountID: "filtered_user", Currency: "USD", Amount: decimal.NewFromInt(10)},
+		{FromAccountID: "filtered_user", ToAccountID: api.CompanyAccountID, Currency: "USD", Amount: decimal.NewFromInt(4)},
+	} {
+		_, err := repo.Transfer(ctx, request, fmt.Sprintf("filter-%d", i))
+		require.NoError(t, err)
+	}
+
+	end := time.Now().Add(time.Minute)
+
+	debits, err := repo.FilterTransactions(ctx, "USD", "filtered_user", api.TransactionFilter{Type: api.DEBIT})
+	require.NoError(t, err)
+	require.Len(t, debits, 1)
+	require.True(t, decimal.NewFromInt(4).Equal(debits[0].Amount))
+
+	credits, err := repo.FilterTransactions(ctx, "USD", "filtered_user", api.TransactionFilter{Type: api.CREDIT, From: start, To: end})
+	require.NoError(t, err)
+	require.Len(t, credits, 1)
+	require.True(t, decimal.NewFromInt(10).Equal(credits[0].Amount))
+
+	before, err := repo.FilterTransactions(ctx, "USD", "filtered_user", api.TransactionFilter{To: start})
+	require.NoError(t, err)
+	require.Empty(t, before)
+
+	page, err := repo.GetTransactionsPage(ctx, "USD", "filtered_user", api.TransactionFilter{Type: api.DEBIT, From: start}, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, page.TotalCount)
+	require.Len(t, page.Transactions, 1)
+
+	_, err = repo.FilterTransactions(ctx, "USD", "filtered_user", api.TransactionFilter{From: end, To: start})
+	require.ErrorIs(t, err, api.ErrInvalidTransactionFilter)
+
+	_, err = repo.FilterTransactions(ctx, "USD", "filtered_user", api.TransactionFilter{Type: "REFUND"})
+	require.ErrorIs(t, err, api.ErrInvalidTransactionFilter)
+}
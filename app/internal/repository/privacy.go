@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	selectAccountBalances = `SELECT user_id, currency, balance FROM accounts WHERE user_id = $1 ORDER BY currency`
+
+	// every currency of the account, the oldest first
+	selectAccountTransactions = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.user_id = $1
+		ORDER BY transactions.created_at, transactions.id`
+
+	// the key is deleted right away, so the remarks can't be read anymore
+	deleteAccountKey = `DELETE FROM account_keys WHERE account_id = $1`
+
+	erasureColumns = `id, account_id, status, requested_by, created_at, completed_at`
+
+	selectPendingErasure = `SELECT ` + erasureColumns + ` FROM erasure_requests
+		WHERE account_id = $1 AND status = 'PENDING'
+		ORDER BY created_at
+		LIMIT 1`
+
+	selectLatestErasure = `SELECT ` + erasureColumns + ` FROM erasure_requests
+		WHERE account_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	insertErasure = `INSERT INTO erasure_requests (id, account_id, status, requested_by, created_at)
+		VALUES ($1, $2, 'PENDING', $3, $4)`
+
+	// the other workers skip the erasure in progress
+	lockNextErasure = `SELECT ` + erasureColumns + ` FROM erasure_requests
+		WHERE status = 'PENDING'
+		ORDER BY created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+
+	completeErasure = `UPDATE erasure_requests SET status = 'COMPLETED', completed_at = $2 WHERE id = $1`
+)
+
+// the statements erasing the data of the account, $1, written up to the erasure request, $2.
+// The ledger entries are kept with their amounts, only their remarks are erased,
+// including the plain ones written before the encryption was enabled, and their amended remarks with their history,
+// and so are the ones of the transfers not posted yet.
+//
+//nolint:gochecknoglobals // constant
+var erasures = []string{
+	`UPDATE transactions SET description = ''
+		WHERE account_id IN (SELECT id FROM accounts WHERE user_id = $1) AND description <> '' AND created_at <= $2`,
+	`UPDATE transaction_metadata SET remarks = ''
+		WHERE tx_id IN (SELECT transactions.id FROM transactions JOIN accounts ON transactions.account_id = accounts.id
+			WHERE accounts.user_id = $1) AND remarks <> '' AND updated_at <= $2`,
+	`UPDATE transaction_metadata_edits SET previous = '', value = ''
+		WHERE tx_id IN (SELECT transactions.id FROM transactions JOIN accounts ON transactions.account_id = accounts.id
+			WHERE accounts.user_id = $1) AND field = 'remarks' AND created_at <= $2`,
+	`UPDATE transfer_reviews SET remarks = '' WHERE (from_account_id = $1 OR to_account_id = $1) AND created_at <= $2`,
+	`UPDATE pending_transfers SET remarks = '' WHERE (from_account_id = $1 OR to_account_id = $1) AND created_at <= $2`,
+	`UPDATE holds SET remarks = '' WHERE (from_account_id = $1 OR to_account_id = $1) AND created_at <= $2`,
+	`UPDATE queued_transfers SET remarks = '' WHERE (from_account_id = $1 OR to_account_id = $1) AND created_at <= $2`,
+	`UPDATE scheduled_transfers SET remarks = '' WHERE (from_account_id = $1 OR to_account_id = $1) AND created_at <= $2`,
+	`DELETE FROM account_aliases WHERE account_id = $1 AND created_at <= $2`,
+	`DELETE FROM statements WHERE account_id = $1 AND created_at <= $2`,
+	`DELETE FROM statement_subscriptions WHERE account_id = $1 AND created_at <= $2`,
+	`DELETE FROM receipts WHERE account_id = $1 AND created_at <= $2`,
+	`DELETE FROM receipt_subscriptions WHERE account_id = $1 AND created_at <= $2`,
+	`DELETE FROM account_activity WHERE account_id = $1 AND created_at <= $2`,
+}
+
+// ExportPersonalData returns everything kept about the account, with the remarks decrypted.
+func (r *PostgresRepository) ExportPersonalData(ctx context.Context, accountID string) (*api.PersonalData, error) {
+	accountID = strings.TrimSpace(accountID)
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	data := &api.PersonalData{
+		AccountID:  accountID,
+		ExportedAt: r.clock.Now(),
+		Balances:   []*api.Account{},
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectAccountBalances, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		account := &api.Account{}
+
+		if err = rows.Scan(&account.AccountID, &account.Currency, &account.Balance); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		data.Balances = append(data.Balances, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	transactions, err := r.db.QueryContext(ctx, selectAccountTransactions, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if data.Transactions, err = r.readTransactions(ctx, transactions); err != nil {
+		return nil, err
+	}
+
+	if data.Aliases, err = r.GetAccountAliases(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	if data.StatementSubscriptions, err = r.GetStatementSubscriptions(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	if data.ReceiptSubscriptions, err = r.GetReceiptSubscriptions(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	if data.Holds, err = r.getAccountHolds(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	if data.QueuedTransfers, err = r.getAccountQueuedTransfers(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	if data.ScheduledTransfers, err = r.getAccountScheduledTransfers(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	data.Erasure, err = r.GetErasure(ctx, accountID)
+	if err != nil && !errors.Is(err, api.ErrErasureNotFound) {
+		return nil, err
+	}
+
+	if len(data.Balances) == 0 && len(data.Aliases) == 0 && len(data.StatementSubscriptions) == 0 &&
+		len(data.ReceiptSubscriptions) == 0 && data.Erasure == nil {
+		return nil, api.ErrAccountNotFound
+	}
+
+	return data, nil
+}
+
+// RequestErasure crypto-shreds the remarks of the account right away, by deleting its data key,
+// and records the erasure of its other data, see ProcessErasures. The ledger amounts are kept.
+// Requesting the erasure of an account with a pending one returns the pending one.
+func (r *PostgresRepository) RequestErasure(ctx context.Context, accountID, operator string) (*api.ErasureRequest, error) {
+	accountID = strings.TrimSpace(accountID)
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	if r.isCompanyAccount(accountID) || strings.EqualFold(accountID, api.DisputesAccountID) || strings.EqualFold(accountID, api.FeesAccountID) {
+		return nil, api.ErrProtectedAccount
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	erasure, err := r.requestErasure(ctx, tx, accountID, operator)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return erasure, nil
+}
+
+func (r *PostgresRepository) requestErasure(ctx context.Context, tx *sql.Tx, accountID, operator string) (*api.ErasureRequest, error) {
+	if _, err := tx.ExecContext(ctx, deleteAccountKey, accountID); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	erasure, err := scanErasure(tx.QueryRowContext(ctx, selectPendingErasure, accountID))
+	if err == nil {
+		return erasure, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, formatUnknownError(err)
+	}
+
+	erasure = &api.ErasureRequest{
+		ID:          r.idGenerator.NewID(),
+		AccountID:   accountID,
+		Status:      api.ErasurePending,
+		RequestedBy: operator,
+		CreatedAt:   r.clock.Now(),
+	}
+
+	if _, err = tx.ExecContext(ctx, insertErasure, erasure.ID, erasure.AccountID, erasure.RequestedBy, erasure.CreatedAt); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return erasure, nil
+}
+
+// GetErasure returns the latest erasure of the account.
+func (r *PostgresRepository) GetErasure(ctx context.Context, accountID string) (*api.ErasureRequest, error) {
+	erasure, err := scanErasure(r.db.QueryRowContext(ctx, selectLatestErasure, strings.TrimSpace(accountID)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrErasureNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return erasure, nil
+}
+
+// ProcessErasures erases the data of at most limit pending erasures, the oldest first, each in its own transaction.
+// It returns the erasures completed, so their accounts are purged from the caches.
+func (r *PostgresRepository) ProcessErasures(ctx context.Context, limit int) ([]*api.ErasureRequest, error) {
+	completed := []*api.ErasureRequest{}
+
+	for len(completed) < limit {
+		erasure, err := r.processNextErasure(ctx)
+		if err != nil {
+			return completed, err
+		}
+
+		if erasure == nil {
+			break
+		}
+
+		completed = append(completed, erasure)
+	}
+
+	return completed, nil
+}
+
+// processNextErasure returns nil when there is no pending erasure left.
+func (r *PostgresRepository) processNextErasure(ctx context.Context) (*api.ErasureRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	erasure, err := scanErasure(tx.QueryRowContext(ctx, lockNextErasure))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil //nolint:nilnil // no pending erasure
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	for _, statement := range erasures {
+		if _, err = tx.ExecContext(ctx, statement, erasure.AccountID, erasure.CreatedAt); err != nil {
+			return nil, formatUnknownError(err)
+		}
+	}
+
+	completedAt := r.clock.Now()
+
+	if _, err = tx.ExecContext(ctx, completeErasure, erasure.ID, completedAt); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	erasure.Status = api.ErasureCompleted
+	erasure.CompletedAt = &completedAt
+
+	return erasure, nil
+}
+
+func scanErasure(row rowScanner) (*api.ErasureRequest, error) {
+	erasure := &api.ErasureRequest{}
+
+	var completedAt sql.NullTime
+
+	err := row.Scan(&erasure.ID, &erasure.AccountID, &erasure.Status, &erasure.RequestedBy, &erasure.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if completedAt.Valid {
+		erasure.CompletedAt = &completedAt.Time
+	}
+
+	return erasure, nil
+}
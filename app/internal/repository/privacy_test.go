@@ -0,0 +1,202 @@
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalData(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE account_keys, erasure_requests, account_aliases, holds, queued_transfers, scheduled_transfers;")
+		require.NoError(t, err)
+	})
+
+	key, err := crypt.GenerateFieldKey()
+	require.NoError(t, err)
+
+	keyring, err := crypt.ParseKeyring("master", []string{"master:" + key})
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresRepository(db).WithFieldEncryption(keyring)
+
+	transactions, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "privacy_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+		Remarks:       "rent for flat 4B",
+	}, "privacy-1")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+
+	// the legs may come in any order
+	userTxID, companyTxID := transactions[0].TxID, transactions[1].TxID
+	if transactions[1].AccountID == "privacy_user" {
+		userTxID, companyTxID = companyTxID, userTxID
+	}
+
+	hold, err := repo.PlaceHold(ctx, &api.TransferRequest{
+		FromAccountID: "privacy_user",
+		ToAccountID:   "privacy_landlord",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(1),
+		Remarks:       "deposit for flat 4B",
+	}, "privacy-hold-1")
+	require.NoError(t, err)
+	require.Equal(t, "deposit for flat 4B", hold.Remarks)
+
+	queued, err := repo.EnqueueTransfer(ctx, &api.TransferRequest{
+		FromAccountID: "privacy_user",
+		ToAccountID:   "privacy_landlord",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(1),
+		Remarks:       "utilities for flat 4B",
+	}, "privacy-queued-1")
+	require.NoError(t, err)
+	require.Equal(t, "utilities for flat 4B", queued.Remarks)
+
+	scheduled, err := repo.ScheduleTransfer(ctx, &api.ScheduleTransferRequest{
+		TransferRequest: api.TransferRequest{
+			FromAccountID: "privacy_user",
+			ToAccountID:   "privacy_landlord",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+			Remarks:       "next rent for flat 4B",
+		},
+		ExecuteAt: time.Now().Add(24 * time.Hour),
+	}, "privacy-scheduled-1")
+	require.NoError(t, err)
+	require.Equal(t, "next rent for flat 4B", scheduled.Remarks)
+
+	t.Run("Encrypted at rest", func(t *testing.T) {
+		var stored string
+
+		err := db.QueryRowContext(ctx, "SELECT description FROM transactions WHERE id = $1", userTxID).Scan(&stored)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(stored))
+		require.NotContains(t, stored, "4B")
+
+		transaction, err := repo.GetTransaction(ctx, userTxID)
+		require.NoError(t, err)
+		require.Equal(t, "rent for flat 4B", transaction.Remarks)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM holds WHERE id = $1", hold.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(stored))
+
+		held, err := repo.GetHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Equal(t, "deposit for flat 4B", held.Remarks)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM queued_transfers WHERE id = $1", queued.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(stored))
+
+		polled, err := repo.GetQueuedTransfer(ctx, queued.ID)
+		require.NoError(t, err)
+		require.Equal(t, "utilities for flat 4B", polled.Remarks)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM scheduled_transfers WHERE id = $1", scheduled.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(stored))
+
+		listed, err := repo.ListScheduledTransfers(ctx, "privacy_user", api.ScheduledTransferScheduled, 10)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		require.Equal(t, "next rent for flat 4B", listed[0].Remarks)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		data, err := repo.ExportPersonalData(ctx, "privacy_user")
+		require.NoError(t, err)
+		require.Len(t, data.Balances, 1)
+		require.Len(t, data.Transactions, 1)
+		require.Equal(t, "rent for flat 4B", data.Transactions[0].Remarks)
+		require.Len(t, data.Holds, 1)
+		require.Equal(t, "deposit for flat 4B", data.Holds[0].Remarks)
+		require.Len(t, data.QueuedTransfers, 1)
+		require.Equal(t, "utilities for flat 4B", data.QueuedTransfers[0].Remarks)
+		require.Len(t, data.ScheduledTransfers, 1)
+		require.Equal(t, "next rent for flat 4B", data.ScheduledTransfers[0].Remarks)
+		require.Nil(t, data.Erasure)
+
+		_, err = repo.ExportPersonalData(ctx, "nobody")
+		require.ErrorIs(t, err, api.ErrAccountNotFound)
+	})
+
+	t.Run("Erasure", func(t *testing.T) {
+		_, err := repo.RequestErasure(ctx, strings.ToUpper(api.CompanyAccountID), "operator")
+		require.ErrorIs(t, err, api.ErrProtectedAccount)
+
+		erasure, err := repo.RequestErasure(ctx, "privacy_user", "operator")
+		require.NoError(t, err)
+		require.Equal(t, api.ErasurePending, erasure.Status)
+
+		again, err := repo.RequestErasure(ctx, "privacy_user", "operator")
+		require.NoError(t, err)
+		require.Equal(t, erasure.ID, again.ID, "the pending erasure is returned")
+
+		transaction, err := repo.GetTransaction(ctx, userTxID)
+		require.NoError(t, err)
+		require.Empty(t, transaction.Remarks, "the key is shredded right away")
+		require.True(t, decimal.NewFromInt(10).Equal(transaction.Amount))
+
+		company, err := repo.GetTransaction(ctx, companyTxID)
+		require.NoError(t, err)
+		require.Equal(t, "rent for flat 4B", company.Remarks, "the other leg has its own key")
+
+		held, err := repo.GetHold(ctx, hold.ID)
+		require.NoError(t, err)
+		require.Empty(t, held.Remarks)
+
+		polled, err := repo.GetQueuedTransfer(ctx, queued.ID)
+		require.NoError(t, err)
+		require.Empty(t, polled.Remarks)
+
+		pending, err := repo.GetScheduledTransfer(ctx, scheduled.ID)
+		require.NoError(t, err)
+		require.Empty(t, pending.Remarks)
+
+		completed, err := repo.ProcessErasures(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, completed, 1)
+		require.Equal(t, "privacy_user", completed[0].AccountID)
+
+		erasure, err = repo.GetErasure(ctx, "privacy_user")
+		require.NoError(t, err)
+		require.Equal(t, api.ErasureCompleted, erasure.Status)
+		require.NotNil(t, erasure.CompletedAt)
+
+		var stored string
+
+		err = db.QueryRowContext(ctx, "SELECT description FROM transactions WHERE id = $1", userTxID).Scan(&stored)
+		require.NoError(t, err)
+		require.Empty(t, stored)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM holds WHERE id = $1", hold.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.Empty(t, stored)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM queued_transfers WHERE id = $1", queued.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.Empty(t, stored)
+
+		err = db.QueryRowContext(ctx, "SELECT remarks FROM scheduled_transfers WHERE id = $1", scheduled.ID).Scan(&stored)
+		require.NoError(t, err)
+		require.Empty(t, stored)
+	})
+}
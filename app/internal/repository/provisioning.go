@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/features"
+)
+
+const selectAccountExists = `SELECT count(1) FROM accounts WHERE user_id = $1 AND currency = $2`
+
+// WithProvisioningPolicy sets which accounts the transfers may open, all of them by default.
+// With the stricter policies, the accounts are opened beforehand with OpenAccount.
+func (r *PostgresRepository) WithProvisioningPolicy(policy api.ProvisioningPolicy) *PostgresRepository {
+	r.features = r.features.WithProvisioningPolicy(policy)
+
+	return r
+}
+
+// WithAccountIDPolicy restricts the ids of the accounts to the format of the deployment, i.e. UUIDs only,
+// any id up to accountid.MaxLength characters is allowed otherwise. The settlement, disputes and fees accounts are exempt.
+func (r *PostgresRepository) WithAccountIDPolicy(policy accountid.Policy) *PostgresRepository {
+	r.features = r.features.WithAccountIDPolicy(policy)
+
+	return r
+}
+
+// WithFeatures replaces the policies with the ones of the feature flags of the APIs, so the flags switch them
+// for the repository as well.
+func (r *PostgresRepository) WithFeatures(f features.Features) *PostgresRepository {
+	r.features = f
+
+	return r
+}
+
+// validateAccountIDFormat returns api.ErrInvalidAccountID when the account doesn't follow the account id policy.
+func (r *PostgresRepository) validateAccountIDFormat(ctx context.Context, accountID string) error {
+	if r.isCompanyAccount(accountID) || strings.EqualFold(accountID, api.DisputesAccountID) || strings.EqualFold(accountID, api.FeesAccountID) {
+		return nil
+	}
+
+	return r.features.ValidateAccountID(ctx, accountID) //nolint:wrapcheck // the domain errors are returned as is
+}
+
+// OpenAccount opens the account in the currency with a zero balance, whatever the provisioning policy.
+// Opening an account again is a no-op, and returns its balance.
+func (r *PostgresRepository) OpenAccount(ctx context.Context, accountID, currency string) (*api.Account, error) {
+	accountID = strings.TrimSpace(accountID)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	if err := r.validateAccountIDFormat(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	// the disputed amounts are only moved by the disputes, and the fees by their entries
+	if strings.EqualFold(accountID, api.DisputesAccountID) || strings.EqualFold(accountID, api.FeesAccountID) {
+		return nil, api.ErrCompanyAccount
+	}
+
+	if err := r.openAccount(ctx, accountID, currency); err != nil {
+		return nil, err
+	}
+
+	return r.GetAccountBalance(ctx, currency, accountID)
+}
+
+func (r *PostgresRepository) openAccount(ctx context.Context, accountID, currency string) error {
+	if r.outbox {
+		return r.createAccountWithEvent(ctx, accountID, currency)
+	}
+
+	if _, err := r.db.ExecContext(ctx, upsertAccount, accountID, currency); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// checkProvisioning rejects the transfers that would open an account the policy doesn't let them open.
+// It doesn't lock, an account opened concurrently only lets the transfer through.
+func (r *PostgresRepository) checkProvisioning(ctx context.Context, request *api.TransferRequest) error {
+	policy := r.features.ProvisioningPolicy(ctx)
+	if policy == api.ProvisionAuto {
+		return nil
+	}
+
+	// only a deposit opens the account it funds
+	deposit := policy == api.ProvisionDepositOnly && r.isCompanyAccount(request.FromAccountID)
+
+	for _, accountID := range []string{request.FromAccountID, request.ToAccountID} {
+		if r.isCompanyAccount(accountID) || (deposit && accountID == request.ToAccountID) {
+			continue
+		}
+
+		// the recipient of a conversion holds the currency it's credited in
+		currency := request.Currency
+		if accountID == request.ToAccountID {
+			currency = request.CreditCurrency()
+		}
+
+		var count int
+		if err := r.db.QueryRowContext(ctx, selectAccountExists, accountID, currency).Scan(&count); err != nil {
+			return formatUnknownError(err)
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if policy == api.ProvisionDepositOnly {
+			return api.ErrAccountRequiresDeposit
+		}
+
+		return api.ErrAccountNotProvisioned
+	}
+
+	return nil
+}
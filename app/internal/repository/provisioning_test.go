@@ -0,0 +1,104 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningPolicy(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	transfer := func(repo *repository.PostgresRepository, from, to, idempotencyKey string) error {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: from,
+			ToAccountID:   to,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, idempotencyKey)
+
+		return err
+	}
+
+	t.Run("Deposit only", func(t *testing.T) {
+		repo := repository.NewPostgresRepository(db).WithProvisioningPolicy(api.ProvisionDepositOnly)
+
+		require.NoError(t, transfer(repo, api.CompanyAccountID, "provisioned_deposit", "provisioning-1"), "opened by the deposit")
+		require.ErrorIs(t, transfer(repo, "provisioned_deposit", "provisioned_unknown", "provisioning-2"), api.ErrAccountRequiresDeposit)
+		require.ErrorIs(t, transfer(repo, "provisioned_unknown", api.CompanyAccountID, "provisioning-3"), api.ErrAccountRequiresDeposit)
+
+		_, err := repo.GetAccountBalance(ctx, "USD", "provisioned_unknown")
+		require.ErrorIs(t, err, api.ErrAccountNotFound, "not opened by the rejected transfers")
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		repo := repository.NewPostgresRepository(db).WithProvisioningPolicy(api.ProvisionStrict)
+
+		require.ErrorIs(t, transfer(repo, api.CompanyAccountID, "provisioned_strict", "provisioning-4"), api.ErrAccountNotProvisioned)
+
+		account, err := repo.OpenAccount(ctx, " provisioned_strict ", "usd")
+		require.NoError(t, err)
+		require.Equal(t, "provisioned_strict", account.AccountID)
+		require.Equal(t, "USD", account.Currency)
+		require.True(t, account.Balance.IsZero())
+
+		require.NoError(t, transfer(repo, api.CompanyAccountID, "provisioned_strict", "provisioning-5"))
+
+		account, err = repo.OpenAccount(ctx, "provisioned_strict", "USD")
+		require.NoError(t, err, "a no-op")
+		require.True(t, account.Balance.Equal(decimal.NewFromInt(10)))
+
+		_, err = repo.OpenAccount(ctx, api.DisputesAccountID, "USD")
+		require.ErrorIs(t, err, api.ErrCompanyAccount)
+	})
+
+	t.Run("Auto", func(t *testing.T) {
+		repo := repository.NewPostgresRepository(db)
+
+		require.NoError(t, transfer(repo, "provisioned_strict", "provisioned_auto", "provisioning-6"))
+	})
+}
+
+func TestAccountIDPolicy(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	policy, err := accountid.New(accountid.UUID, 0, 0)
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresRepository(db).WithAccountIDPolicy(policy)
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}, "account-id-1")
+	require.NoError(t, err, "the company account is exempt")
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "5f0c8a1e-3b7d-4c2a-9e6f-1d2b3c4d5e6f",
+		ToAccountID:   "alice@example.com",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}, "account-id-2")
+	require.ErrorIs(t, err, api.ErrInvalidAccountID)
+
+	_, err = repo.OpenAccount(ctx, "alice@example.com", "USD")
+	require.ErrorIs(t, err, api.ErrInvalidAccountID)
+
+	_, err = repo.GetAccountBalance(ctx, "USD", "alice@example.com")
+	require.ErrorIs(t, err, api.ErrAccountNotFound)
+}
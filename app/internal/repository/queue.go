@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	queuedTransferColumns = `id, idempotency_key, from_account_id, to_account_id, currency, amount,
+		COALESCE(remarks, ''), tags, status, COALESCE(error_code, ''), COALESCE(error_message, ''), created_at, processed_at`
+
+	// a retried request finds the transfer already queued with its idempotency key, so it's never queued twice
+	insertQueuedTransfer = `INSERT INTO queued_transfers
+		(id, idempotency_key, from_account_id, to_account_id, currency, amount, remarks, tags, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		ON CONFLICT (idempotency_key) DO NOTHING`
+
+	selectQueuedTransfer = `SELECT ` + queuedTransferColumns + ` FROM queued_transfers WHERE id = $1`
+
+	selectQueuedTransferByKey = `SELECT ` + queuedTransferColumns + ` FROM queued_transfers WHERE idempotency_key = $1`
+
+	// the transfers queued by or to the account
+	selectAccountQueuedTransfers = `SELECT ` + queuedTransferColumns + ` FROM queued_transfers
+		WHERE from_account_id = $1 OR to_account_id = $1
+		ORDER BY created_at, id`
+
+	// skipping the locked transfers lets several workers dequeue concurrently without posting the same transfers
+	selectQueuedTransfers = `SELECT ` + queuedTransferColumns + `
+		FROM queued_transfers
+		WHERE status = 'QUEUED'
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	updateQueuedTransfer = `UPDATE queued_transfers SET status = $2, error_code = NULLIF($3, ''), error_message = NULLIF($4, ''), processed_at = $5
+		WHERE id = $1`
+)
+
+// EnqueueTransfer validates the transfer, and queues it to be posted asynchronously by ProcessQueuedTransfers,
+// for the producers that can't wait on the locks of the accounts. The queued transfer is polled with GetQueuedTransfer.
+// Queuing it again with the same idempotency key returns the transfer already queued.
+// The remarks are encrypted with the data key of the sender until posted, see WithFieldEncryption.
+func (r *PostgresRepository) EnqueueTransfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.QueuedTransfer, error) {
+	if strings.TrimSpace(idempotencyKey) == "" {
+		return nil, api.ErrMissingIdempotencyKey
+	}
+
+	if len(idempotencyKey) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is longer than %d characters", api.ErrInvalidRequest, maxGroupIDLength)
+	}
+
+	if err := r.validateTransfer(ctx, request); err != nil {
+		return nil, err
+	}
+
+	if err := rejectConversion(request, "queued transfers"); err != nil {
+		return nil, err
+	}
+
+	var existingCount int
+	if err := r.db.QueryRowContext(ctx, selectGroupExists, idempotencyKey).Scan(&existingCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	remarks, err := r.sealRemarks(ctx, tx, request.FromAccountID, request.Remarks)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertQueuedTransfer, r.idGenerator.NewID(), idempotencyKey, request.FromAccountID, request.ToAccountID,
+		request.Currency, request.Amount, remarks, pq.Array(tagsOf(request.Tags)), api.QueuedTransferQueued, r.clock.Now())
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	queued, err := scanQueuedTransfer(tx.QueryRowContext(ctx, selectQueuedTransferByKey, idempotencyKey))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &queued.Remarks); err != nil {
+		return nil, err
+	}
+
+	return queued, nil
+}
+
+// GetQueuedTransfer returns the transfer queued to be posted asynchronously, whatever its status.
+func (r *PostgresRepository) GetQueuedTransfer(ctx context.Context, id string) (*api.QueuedTransfer, error) {
+	if !validID(id) {
+		return nil, api.ErrQueuedTransferNotFound
+	}
+
+	queued, err := scanQueuedTransfer(r.db.QueryRowContext(ctx, selectQueuedTransfer, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrQueuedTransferNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &queued.Remarks); err != nil {
+		return nil, err
+	}
+
+	return queued, nil
+}
+
+// getAccountQueuedTransfers returns the transfers queued by or to the account, the oldest first, whatever their status,
+// with their remarks decrypted.
+func (r *PostgresRepository) getAccountQueuedTransfers(ctx context.Context, accountID string) ([]*api.QueuedTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, selectAccountQueuedTransfers, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	return r.openQueuedTransfers(ctx, rows)
+}
+
+// ProcessQueuedTransfers posts the oldest queued transfers, at most limit, and returns how many were processed,
+// i.e. posted, held or failed. The transfers are posted like the ones of Transfer, one at a time, while they're locked,
+// so the workers never post the same transfer twice. A transfer failing on an unhandled database error stays queued,
+// and stops the batch, to be retried by the next one.
+func (r *PostgresRepository) ProcessQueuedTransfers(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	queued, err := r.dequeueTransfers(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+
+	for _, transfer := range queued {
+		// posted on another connection, the queue stays locked until the outcomes are recorded
+		_, transferErr := r.Transfer(ctx, transfer.Transfer(), transfer.IdempotencyKey)
+
+		status, retry := queuedTransferOutcome(transferErr)
+		if retry {
+			// the outcomes of the transfers before it are still recorded
+			if err = tx.Commit(); err != nil {
+				return 0, formatUnknownError(err)
+			}
+
+			return processed, fmt.Errorf("failed to post queued transfer %s: %w", transfer.ID, transferErr)
+		}
+
+		var code api.ErrorCode
+
+		var message string
+
+		if transferErr != nil {
+			code, message = api.CodeOf(transferErr), transferErr.Error()
+		}
+
+		// the transfers already posted are found posted by the next batch
+		if _, err = tx.ExecContext(ctx, updateQueuedTransfer, transfer.ID, status, code, message, r.clock.Now()); err != nil {
+			return 0, formatUnknownError(err)
+		}
+
+		processed++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return processed, nil
+}
+
+// dequeueTransfers locks the oldest queued transfers, with their remarks decrypted to be posted, which encrypts them again.
+func (r *PostgresRepository) dequeueTransfers(ctx context.Context, tx *sql.Tx, limit int) ([]*api.QueuedTransfer, error) {
+	rows, err := tx.QueryContext(ctx, selectQueuedTransfers, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	return r.openQueuedTransfers(ctx, rows)
+}
+
+// openQueuedTransfers scans the queued transfers of the rows, and decrypts their remarks.
+func (r *PostgresRepository) openQueuedTransfers(ctx context.Context, rows *sql.Rows) ([]*api.QueuedTransfer, error) {
+	queued := []*api.QueuedTransfer{}
+	remarks := []*string{}
+
+	for rows.Next() {
+		transfer, err := scanQueuedTransfer(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		queued = append(queued, transfer)
+		remarks = append(remarks, &transfer.Remarks)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err := r.openRemarks(ctx, dataKeys{}, remarks...); err != nil {
+		return nil, err
+	}
+
+	return queued, nil
+}
+
+// queuedTransferOutcome is the status of the queued transfer once posted with the error, or whether it's retried.
+func queuedTransferOutcome(err error) (api.QueuedTransferStatus, bool) {
+	switch {
+	case err == nil:
+		return api.QueuedTransferCompleted, false
+	case errors.Is(err, api.ErrDuplicateTransaction):
+		// posted by a worker that stopped before recording it
+		return api.QueuedTransferCompleted, false
+	case errors.Is(err, api.ErrTransferPendingApproval), errors.Is(err, api.ErrTransferUnderReview):
+		return api.QueuedTransferHeld, false
+	case errors.Is(err, api.ErrUnhandledDatabaseError),
+		errors.Is(err, api.ErrScreeningFailed),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return api.QueuedTransferQueued, true
+	default:
+		return api.QueuedTransferFailed, false
+	}
+}
+
+func scanQueuedTransfer(row rowScanner) (*api.QueuedTransfer, error) {
+	queued := &api.QueuedTransfer{}
+
+	var processedAt sql.NullTime
+
+	err := row.Scan(&queued.ID, &queued.IdempotencyKey, &queued.FromAccountID, &queued.ToAccountID, &queued.Currency, &queued.Amount,
+		&queued.Remarks, pq.Array(&queued.Tags), &queued.Status, &queued.Code, &queued.Error, &queued.CreatedAt, &processedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if processedAt.Valid {
+		queued.ProcessedAt = &processedAt.Time
+	}
+
+	return queued, nil
+}
@@ -0,0 +1,116 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferQueue(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE queued_transfers, pending_transfers;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).
+		WithApprovalThresholds(map[string]decimal.Decimal{"USD": decimal.NewFromInt(1000)})
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "queue_payer",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "queue-funding")
+	require.NoError(t, err)
+
+	enqueue := func(amount int64, idempotencyKey string) *api.QueuedTransfer {
+		t.Helper()
+
+		queued, err := repo.EnqueueTransfer(ctx, &api.TransferRequest{
+			FromAccountID: "queue_payer",
+			ToAccountID:   "queue_payee",
+			Currency:      "usd",
+			Amount:        decimal.NewFromInt(amount),
+			Remarks:       "burst",
+		}, idempotencyKey)
+		require.NoError(t, err)
+		require.Equal(t, api.QueuedTransferQueued, queued.Status)
+
+		return queued
+	}
+
+	posted := enqueue(60, "queue-1")
+	failed := enqueue(60, "queue-2")
+	held := enqueue(1500, "queue-3")
+
+	t.Run("Queued once", func(t *testing.T) {
+		retried := enqueue(60, "queue-1")
+		require.Equal(t, posted.ID, retried.ID)
+
+		_, err := repo.EnqueueTransfer(ctx, &api.TransferRequest{
+			FromAccountID: "queue_payer",
+			ToAccountID:   "queue_payee",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "queue-funding")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "the key of a posted transfer")
+	})
+
+	t.Run("Validated when queued", func(t *testing.T) {
+		_, err := repo.EnqueueTransfer(ctx, &api.TransferRequest{
+			FromAccountID: "queue_payer",
+			ToAccountID:   "queue_payer",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+		}, "queue-4")
+		require.ErrorIs(t, err, api.ErrSameAccountIDs)
+	})
+
+	t.Run("Processed in order", func(t *testing.T) {
+		processed, err := repo.ProcessQueuedTransfers(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, 3, processed)
+
+		got, err := repo.GetQueuedTransfer(ctx, posted.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.QueuedTransferCompleted, got.Status)
+		require.NotNil(t, got.ProcessedAt)
+
+		txs, err := repo.GetTransactions(ctx, "USD", "queue_payee")
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		require.Equal(t, "queue-1", txs[0].GroupID)
+
+		got, err = repo.GetQueuedTransfer(ctx, failed.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.QueuedTransferFailed, got.Status)
+		require.Equal(t, api.CodeInsufficientBalance, got.Code)
+
+		got, err = repo.GetQueuedTransfer(ctx, held.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.QueuedTransferHeld, got.Status)
+		require.Equal(t, api.CodeTransferPendingApproval, got.Code)
+
+		processed, err = repo.ProcessQueuedTransfers(ctx, 10)
+		require.NoError(t, err)
+		require.Zero(t, processed, "drained")
+	})
+
+	t.Run("Unknown transfer", func(t *testing.T) {
+		_, err := repo.GetQueuedTransfer(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrQueuedTransferNotFound)
+
+		_, err = repo.GetQueuedTransfer(ctx, "00000000-0000-0000-0000-000000000000")
+		require.ErrorIs(t, err, api.ErrQueuedTransferNotFound)
+	})
+}
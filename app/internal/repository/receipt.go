@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	// a single worker queues the receipts at a time, so an event isn't queued by two of them at once
+	lockReceiptsQueue = `SELECT pg_try_advisory_xact_lock(hashtext('receipts'))`
+
+	selectUnreceiptedEvents = `SELECT seq, id, type, version, payload, created_at
+		FROM outbox_events
+		WHERE receipted_at IS NULL
+		ORDER BY seq
+		LIMIT $1`
+
+	updateEventsReceipted = `UPDATE outbox_events SET receipted_at = $2 WHERE id = ANY($1)`
+
+	// an existing subscription is kept, and returned by the select
+	insertReceiptSubscription = `INSERT INTO receipt_subscriptions (id, account_id, destination, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT unique_receipt_subscription DO NOTHING`
+
+	selectReceiptSubscription = `SELECT id, account_id, destination, created_at
+		FROM receipt_subscriptions
+		WHERE account_id = $1 AND destination = $2`
+
+	selectReceiptSubscriptions = `SELECT id, account_id, destination, created_at
+		FROM receipt_subscriptions
+		WHERE account_id = $1
+		ORDER BY created_at, id`
+
+	deleteReceiptSubscription = `DELETE FROM receipt_subscriptions WHERE id = $1`
+
+	// the entry gets a receipt for every subscription of its account, the subscriptions created after it don't get one
+	insertReceipts = `INSERT INTO receipts (subscription_id, tx_id, transfer_id, kind, account_id, destination,
+			counterparty_id, currency, amount, next_attempt_at, created_at)
+		SELECT id, $1, $2, $3, account_id, destination, $4, $5, $6, $7, $8
+		FROM receipt_subscriptions
+		WHERE account_id = $9 AND created_at <= $8
+		ON CONFLICT ON CONSTRAINT unique_receipt DO NOTHING`
+
+	receiptColumns = `id, tx_id, transfer_id, kind, account_id, destination, counterparty_id, currency, amount,
+			status, attempts, COALESCE(last_error, ''), sent_at, created_at`
+
+	// the unsubscribed receipts are no longer sent
+	selectDueReceipts = `SELECT ` + receiptColumns + `
+		FROM receipts
+		WHERE status = 'PENDING' AND subscription_id IS NOT NULL AND next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $2`
+
+	selectAccountReceipts = `SELECT ` + receiptColumns + `
+		FROM receipts
+		WHERE account_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2`
+
+	markReceiptSent = `UPDATE receipts SET status = 'SENT', attempts = attempts + 1, last_error = NULL, sent_at = $2
+		WHERE id = $1`
+
+	// without a next attempt, the receipt has failed for good
+	markReceiptFailed = `UPDATE receipts SET attempts = attempts + 1, last_error = $2,
+			status = CASE WHEN $3::TIMESTAMP IS NULL THEN 'FAILED' ELSE 'PENDING' END,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $1`
+)
+
+// WithReceipts keeps the outbox events until their receipts are queued, see QueueReceipts.
+// The outbox must be enabled, as the receipts are queued from its events.
+func (r *PostgresRepository) WithReceipts() *PostgresRepository {
+	r.receipts = true
+
+	return r
+}
+
+// SubscribeReceipts subscribes the account to the receipts of its entries in every currency, sent to the email address.
+// Subscribing again to the same address is a no-op.
+func (r *PostgresRepository) SubscribeReceipts(ctx context.Context, request *api.SubscribeReceiptsRequest) (*api.ReceiptSubscription, error) {
+	accountID := strings.TrimSpace(request.AccountID)
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	// the settlement account takes part in every deposit and withdrawal, it never gets receipts
+	if strings.EqualFold(accountID, r.companyAccountID) {
+		return nil, api.ErrCompanyAccount
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(request.Destination))
+	if err != nil || len(address.Address) > 254 {
+		return nil, api.ErrInvalidReceiptDestination
+	}
+
+	destination := strings.ToLower(address.Address)
+
+	_, err = r.db.ExecContext(ctx, insertReceiptSubscription, r.idGenerator.NewID(), accountID, destination, r.clock.Now())
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	subscription, err := scanReceiptSubscription(r.db.QueryRowContext(ctx, selectReceiptSubscription, accountID, destination))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscription, nil
+}
+
+// GetReceiptSubscriptions returns the receipt subscriptions of the account, the oldest first.
+func (r *PostgresRepository) GetReceiptSubscriptions(ctx context.Context, accountID string) ([]*api.ReceiptSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, selectReceiptSubscriptions, strings.TrimSpace(accountID))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	subscriptions := []*api.ReceiptSubscription{}
+
+	for rows.Next() {
+		subscription, err := scanReceiptSubscription(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteReceiptSubscription unsubscribes, the receipts already queued are kept but no longer sent.
+func (r *PostgresRepository) DeleteReceiptSubscription(ctx context.Context, id string) error {
+	if !validID(id) {
+		return api.ErrReceiptSubscriptionNotFound
+	}
+
+	result, err := r.db.ExecContext(ctx, deleteReceiptSubscription, id)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deleted == 0 {
+		return api.ErrReceiptSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// GetReceipts returns the receipts of the account with their delivery status, at most limit, the latest first.
+func (r *PostgresRepository) GetReceipts(ctx context.Context, accountID string, limit int) ([]*api.ReceiptNotification, error) {
+	return r.queryReceipts(ctx, selectAccountReceipts, strings.TrimSpace(accountID), limit)
+}
+
+// QueueReceipts queues the receipts of at most limit outbox events for the subscriptions of their accounts, the oldest first,
+// and returns the number of events queued. It returns 0 while another worker is queuing.
+func (r *PostgresRepository) QueueReceipts(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	var locked bool
+	if err = tx.QueryRowContext(ctx, lockReceiptsQueue).Scan(&locked); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if !locked {
+		return 0, nil
+	}
+
+	events, _, err := pendingEvents(ctx, tx, selectUnreceiptedEvents, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	now := r.clock.Now()
+	ids := make([]string, len(events))
+
+	for i, event := range events {
+		ids[i] = event.ID
+
+		// the other events, and the later versions, don't get receipts
+		if event.Type != api.EventTransferCreated || event.Version != api.TransferCreatedVersion {
+			continue
+		}
+
+		transfer := &api.TransferCreated{}
+		if err = json.Unmarshal(event.Data, transfer); err != nil {
+			return 0, fmt.Errorf("failed to decode event %s: %w", event.ID, err)
+		}
+
+		if err = r.queueTransferReceipts(ctx, tx, transfer, event.Time, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, updateEventsReceipted, pq.Array(ids), now); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return len(events), nil
+}
+
+// queueTransferReceipts queues the receipts of both entries of the transfer, but the company account's.
+func (r *PostgresRepository) queueTransferReceipts(ctx context.Context, tx *sql.Tx, transfer *api.TransferCreated, createdAt, now time.Time) error {
+	legs := []struct {
+		txID, accountID, counterpartyID string
+		debit                           bool
+	}{
+		{transfer.DebitTxID, transfer.FromAccountID, transfer.ToAccountID, true},
+		{transfer.CreditTxID, transfer.ToAccountID, transfer.FromAccountID, false},
+	}
+
+	for _, leg := range legs {
+		if leg.accountID == r.companyAccountID {
+			continue
+		}
+
+		kind := api.NewReceiptKind(leg.debit, transfer.FromAccountID, transfer.ToAccountID, r.companyAccountID)
+
+		_, err := tx.ExecContext(ctx, insertReceipts, leg.txID, transfer.TransferID, kind, leg.counterpartyID,
+			transfer.Currency, transfer.Amount, now, createdAt, leg.accountID)
+		if err != nil {
+			return formatUnknownError(err)
+		}
+	}
+
+	return nil
+}
+
+// DueReceipts returns the pending receipts due for a delivery attempt at the time, at most limit.
+func (r *PostgresRepository) DueReceipts(ctx context.Context, at time.Time, limit int) ([]*api.ReceiptNotification, error) {
+	return r.queryReceipts(ctx, selectDueReceipts, at, limit)
+}
+
+// MarkReceiptSent records the successful delivery of the receipt.
+func (r *PostgresRepository) MarkReceiptSent(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, markReceiptSent, id, r.clock.Now()); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// MarkReceiptFailed records a failed delivery of the receipt, retried at retryAt, or failed for good if it's nil.
+func (r *PostgresRepository) MarkReceiptFailed(ctx context.Context, id, reason string, retryAt *time.Time) error {
+	if len(reason) > maxStatementError {
+		reason = reason[:maxStatementError]
+	}
+
+	if _, err := r.db.ExecContext(ctx, markReceiptFailed, id, reason, retryAt); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) queryReceipts(ctx context.Context, query string, args ...any) ([]*api.ReceiptNotification, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	receipts := []*api.ReceiptNotification{}
+
+	for rows.Next() {
+		receipt, err := scanReceipt(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		receipts = append(receipts, receipt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return receipts, nil
+}
+
+func scanReceiptSubscription(row rowScanner) (*api.ReceiptSubscription, error) {
+	subscription := &api.ReceiptSubscription{}
+
+	err := row.Scan(&subscription.ID, &subscription.AccountID, &subscription.Destination, &subscription.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	return subscription, nil
+}
+
+func scanReceipt(row rowScanner) (*api.ReceiptNotification, error) {
+	receipt := &api.ReceiptNotification{}
+
+	var sentAt sql.NullTime
+
+	err := row.Scan(&receipt.ID, &receipt.TxID, &receipt.TransferID, &receipt.Kind, &receipt.AccountID, &receipt.Destination,
+		&receipt.CounterpartyID, &receipt.Currency, &receipt.Amount, &receipt.Status, &receipt.Attempts, &receipt.LastError,
+		&sentAt, &receipt.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers
+	}
+
+	if sentAt.Valid {
+		receipt.SentAt = &sentAt.Time
+	}
+
+	return receipt, nil
+}
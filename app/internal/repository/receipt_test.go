@@ -0,0 +1,142 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceipts(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE outbox_events, receipts, receipt_subscriptions;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).WithOutbox().WithReceipts()
+
+	alice, err := repo.SubscribeReceipts(ctx, &api.SubscribeReceiptsRequest{AccountID: " receipt_alice ", Destination: "Alice <Alice@Example.com>"})
+	require.NoError(t, err)
+	require.Equal(t, "receipt_alice", alice.AccountID)
+	require.Equal(t, "alice@example.com", alice.Destination)
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		again, err := repo.SubscribeReceipts(ctx, &api.SubscribeReceiptsRequest{AccountID: "receipt_alice", Destination: "alice@example.com"})
+		require.NoError(t, err)
+		require.Equal(t, alice.ID, again.ID, "subscribing again is a no-op")
+
+		_, err = repo.SubscribeReceipts(ctx, &api.SubscribeReceiptsRequest{AccountID: "receipt_alice", Destination: "not an address"})
+		require.ErrorIs(t, err, api.ErrInvalidReceiptDestination)
+
+		_, err = repo.SubscribeReceipts(ctx, &api.SubscribeReceiptsRequest{AccountID: api.CompanyAccountID, Destination: "ops@example.com"})
+		require.ErrorIs(t, err, api.ErrCompanyAccount)
+
+		subscriptions, err := repo.GetReceiptSubscriptions(ctx, "receipt_alice")
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+
+		require.ErrorIs(t, repo.DeleteReceiptSubscription(ctx, "not-a-uuid"), api.ErrReceiptSubscriptionNotFound)
+	})
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "receipt_alice",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "receipt-key-1")
+	require.NoError(t, err)
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "receipt_alice",
+		ToAccountID:   "receipt_bob",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(30),
+	}, "receipt-key-2")
+	require.NoError(t, err)
+
+	t.Run("Unreceipted events are kept", func(t *testing.T) {
+		published, err := repo.RelayEvents(ctx, 10, func(context.Context, []api.Event) error {
+			return nil
+		})
+		require.NoError(t, err)
+		require.Positive(t, published)
+
+		purged, err := repo.PurgePublishedEvents(ctx, time.Now().UTC().Add(time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, purged)
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		queued, err := repo.QueueReceipts(ctx, 10)
+		require.NoError(t, err)
+		require.Positive(t, queued)
+
+		queued, err = repo.QueueReceipts(ctx, 10)
+		require.NoError(t, err)
+		require.Zero(t, queued)
+
+		// bob isn't subscribed, and the company account never gets receipts
+		due, err := repo.DueReceipts(ctx, time.Now().UTC().Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+
+		kinds := map[api.ReceiptKind]*api.ReceiptNotification{}
+		for _, receipt := range due {
+			kinds[receipt.Kind] = receipt
+		}
+
+		require.Contains(t, kinds, api.ReceiptDeposit)
+		require.Equal(t, "receipt-key-1", kinds[api.ReceiptDeposit].TransferID)
+		require.True(t, decimal.NewFromInt(100).Equal(kinds[api.ReceiptDeposit].Amount))
+
+		require.Contains(t, kinds, api.ReceiptTransferSent)
+		require.Equal(t, "receipt_bob", kinds[api.ReceiptTransferSent].CounterpartyID)
+		require.Equal(t, "alice@example.com", kinds[api.ReceiptTransferSent].Destination)
+	})
+
+	t.Run("Delivery", func(t *testing.T) {
+		due, err := repo.DueReceipts(ctx, time.Now().UTC().Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+
+		require.NoError(t, repo.MarkReceiptSent(ctx, due[0].ID))
+		require.NoError(t, repo.MarkReceiptFailed(ctx, due[1].ID, "mailbox full", nil))
+
+		receipts, err := repo.GetReceipts(ctx, "receipt_alice", 10)
+		require.NoError(t, err)
+		require.Len(t, receipts, 2)
+
+		statuses := map[string]*api.ReceiptNotification{}
+		for _, receipt := range receipts {
+			statuses[receipt.ID] = receipt
+		}
+
+		require.Equal(t, api.ReceiptSent, statuses[due[0].ID].Status)
+		require.NotNil(t, statuses[due[0].ID].SentAt)
+		require.Equal(t, api.ReceiptFailed, statuses[due[1].ID].Status)
+		require.Equal(t, "mailbox full", statuses[due[1].ID].LastError)
+
+		due, err = repo.DueReceipts(ctx, time.Now().UTC().Add(time.Minute), 10)
+		require.NoError(t, err)
+		require.Empty(t, due)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		require.NoError(t, repo.DeleteReceiptSubscription(ctx, alice.ID))
+		require.ErrorIs(t, repo.DeleteReceiptSubscription(ctx, alice.ID), api.ErrReceiptSubscriptionNotFound)
+
+		receipts, err := repo.GetReceipts(ctx, "receipt_alice", 10)
+		require.NoError(t, err)
+		require.Len(t, receipts, 2, "the receipts are kept")
+	})
+}
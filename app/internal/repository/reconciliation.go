@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	selectCompanyLedgerEntries = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.user_id = $1 AND accounts.currency = $2
+			AND transactions.created_at >= $3 AND transactions.created_at < $4
+		ORDER BY transactions.created_at`
+
+	insertReconciliation = `INSERT INTO reconciliations
+		(id, format, file_name, currency, period_start, period_end, matched, unmatched_statement, unmatched_ledger, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	insertReconciliationItem = `INSERT INTO reconciliation_items
+		(reconciliation_id, status, reference, debit_credit, amount, booked_at, transaction_id, remarks)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, '')::uuid, NULLIF($8, ''))`
+
+	selectReconciliation = `SELECT id, format, COALESCE(file_name, ''), currency, period_start, period_end,
+			matched, unmatched_statement, unmatched_ledger, created_at
+		FROM reconciliations
+		WHERE id = $1`
+
+	selectReconciliations = `SELECT id, format, COALESCE(file_name, ''), currency, period_start, period_end,
+			matched, unmatched_statement, unmatched_ledger, created_at
+		FROM reconciliations
+		ORDER BY created_at DESC
+		LIMIT $1`
+
+	selectReconciliationItems = `SELECT status, COALESCE(reference, ''), debit_credit, amount, booked_at,
+			COALESCE(transaction_id::text, ''), COALESCE(remarks, '')
+		FROM reconciliation_items
+		WHERE reconciliation_id = $1
+		ORDER BY id`
+)
+
+// CompanyLedgerEntries returns the ledger entries of the company account in the currency, created in [from, to), oldest first.
+func (r *PostgresRepository) CompanyLedgerEntries(ctx context.Context, currency string, from, to time.Time) ([]*api.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, selectCompanyLedgerEntries, r.companyAccountID, currency, from, to)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.readTransactions(ctx, rows)
+}
+
+// SaveReconciliation stores the report with its items, all or nothing.
+func (r *PostgresRepository) SaveReconciliation(ctx context.Context, reconciliation *api.Reconciliation) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	// a no-op once committed
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, insertReconciliation,
+		reconciliation.ID, reconciliation.Format, reconciliation.FileName, reconciliation.Currency,
+		reconciliation.PeriodStart, reconciliation.PeriodEnd,
+		reconciliation.Matched, reconciliation.UnmatchedStatement, reconciliation.UnmatchedLedger,
+		reconciliation.CreatedAt,
+	)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	// Prepare the reusable statement for optimized performance of repeated queries.
+	itemStatement, err := tx.PrepareContext(ctx, insertReconciliationItem)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer itemStatement.Close()
+
+	for _, item := range reconciliation.Items {
+		_, err = itemStatement.ExecContext(ctx, reconciliation.ID, item.Status, item.Reference, item.Type,
+			item.Amount, item.BookedAt, item.TxID, item.Remarks)
+		if err != nil {
+			return formatUnknownError(err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// GetReconciliation returns the report with its items, or api.ErrReconciliationNotFound.
+func (r *PostgresRepository) GetReconciliation(ctx context.Context, id string) (*api.Reconciliation, error) {
+	if !validID(id) {
+		return nil, api.ErrReconciliationNotFound
+	}
+
+	reconciliation, err := scanReconciliation(r.db.QueryRowContext(ctx, selectReconciliation, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrReconciliationNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectReconciliationItems, id)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	reconciliation.Items = []*api.ReconciliationItem{}
+
+	for rows.Next() {
+		item := &api.ReconciliationItem{}
+
+		err = rows.Scan(&item.Status, &item.Reference, &item.Type, &item.Amount, &item.BookedAt, &item.TxID, &item.Remarks)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		reconciliation.Items = append(reconciliation.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return reconciliation, nil
+}
+
+// ListReconciliations returns the latest reports first, without their items.
+func (r *PostgresRepository) ListReconciliations(ctx context.Context, limit int) ([]*api.Reconciliation, error) {
+	rows, err := r.db.QueryContext(ctx, selectReconciliations, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	reconciliations := []*api.Reconciliation{}
+
+	for rows.Next() {
+		reconciliation, err := scanReconciliation(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		reconciliations = append(reconciliations, reconciliation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return reconciliations, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReconciliation(row rowScanner) (*api.Reconciliation, error) {
+	reconciliation := &api.Reconciliation{}
+
+	err := row.Scan(&reconciliation.ID, &reconciliation.Format, &reconciliation.FileName, &reconciliation.Currency,
+		&reconciliation.PeriodStart, &reconciliation.PeriodEnd,
+		&reconciliation.Matched, &reconciliation.UnmatchedStatement, &reconciliation.UnmatchedLedger,
+		&reconciliation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	return reconciliation, nil
+}
@@ -0,0 +1,112 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliations(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE reconciliations CASCADE;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	txs, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "reconciled_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+		Remarks:       "DEP-1001",
+	}, "reconciliation-key")
+	require.NoError(t, err)
+
+	t.Run("Company ledger entries", func(t *testing.T) {
+		now := time.Now().UTC()
+
+		entries, err := repo.CompanyLedgerEntries(ctx, "USD", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, api.CompanyAccountID, entries[0].AccountID)
+		require.Equal(t, api.DEBIT, entries[0].Type)
+		require.Equal(t, "DEP-1001", entries[0].Remarks)
+
+		require.WithinDuration(t, now, entries[0].Time, time.Hour)
+		require.Equal(t, time.UTC, entries[0].Time.Location())
+
+		entries, err = repo.CompanyLedgerEntries(ctx, "USD", now.Add(time.Hour), now.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	report := &api.Reconciliation{
+		ID:                 "00000000-0000-4000-8000-000000000001",
+		Format:             "csv",
+		FileName:           "july.csv",
+		Currency:           "USD",
+		PeriodStart:        time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:          time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+		Matched:            1,
+		UnmatchedStatement: 1,
+		CreatedAt:          time.Date(2024, 7, 3, 9, 0, 0, 0, time.UTC),
+		Items: []*api.ReconciliationItem{
+			{
+				Status:    api.Matched,
+				Reference: "DEP-1001",
+				Type:      api.CREDIT,
+				Amount:    decimal.NewFromInt(100),
+				BookedAt:  time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+				TxID:      txs[0].TxID,
+			},
+			{
+				Status:   api.UnmatchedStatement,
+				Type:     api.CREDIT,
+				Amount:   decimal.NewFromInt(70),
+				BookedAt: time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	t.Run("Save and get", func(t *testing.T) {
+		require.NoError(t, repo.SaveReconciliation(ctx, report))
+
+		saved, err := repo.GetReconciliation(ctx, report.ID)
+		require.NoError(t, err)
+		require.Equal(t, "july.csv", saved.FileName)
+		require.Equal(t, 1, saved.UnmatchedStatement)
+		require.True(t, report.CreatedAt.Equal(saved.CreatedAt))
+		require.Len(t, saved.Items, 2)
+		require.Equal(t, txs[0].TxID, saved.Items[0].TxID)
+		require.Equal(t, "DEP-1001", saved.Items[0].Reference)
+		require.Empty(t, saved.Items[1].TxID)
+		require.True(t, decimal.NewFromInt(70).Equal(saved.Items[1].Amount))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		reports, err := repo.ListReconciliations(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, reports, 1)
+		require.Empty(t, reports[0].Items)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := repo.GetReconciliation(ctx, "00000000-0000-4000-8000-000000000002")
+		require.ErrorIs(t, err, api.ErrReconciliationNotFound)
+
+		_, err = repo.GetReconciliation(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrReconciliationNotFound)
+	})
+}
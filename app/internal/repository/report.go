@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// the debits and the credits of every account, all currencies when $2 is empty
+	selectTrialBalance = `
+		SELECT accounts.user_id, accounts.currency,
+			COALESCE(SUM(transactions.amount) FILTER (WHERE transactions.debit_credit = 'DEBIT'), 0),
+			COALESCE(SUM(transactions.amount) FILTER (WHERE transactions.debit_credit = 'CREDIT'), 0)
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE transactions.created_at < $1 AND ($2 = '' OR accounts.currency = $2)
+		GROUP BY accounts.currency, accounts.user_id
+		ORDER BY accounts.currency, accounts.user_id`
+
+	// the entries are posted in UTC, so are their days
+	selectGeneralLedger = `
+		SELECT date_trunc('day', transactions.created_at), accounts.user_id, accounts.currency,
+			COALESCE(SUM(transactions.amount) FILTER (WHERE transactions.debit_credit = 'DEBIT'), 0),
+			COALESCE(SUM(transactions.amount) FILTER (WHERE transactions.debit_credit = 'CREDIT'), 0),
+			COUNT(transactions.id)
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE transactions.created_at >= $1 AND transactions.created_at < $2 AND ($3 = '' OR accounts.currency = $3)
+		GROUP BY 1, accounts.currency, accounts.user_id
+		ORDER BY 1, accounts.currency, accounts.user_id`
+)
+
+// TrialBalance sums the debits and the credits of every account in the currency, or in all of them when it's empty,
+// over the ledger entries posted before asOf, with their totals by currency.
+func (r *PostgresRepository) TrialBalance(ctx context.Context, currency string, asOf time.Time) (*api.TrialBalance, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if len(currency) > 10 {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	report := &api.TrialBalance{
+		Currency: currency,
+		AsOf:     asOf.UTC(),
+		Totals:   []*api.TrialBalanceTotal{},
+		Accounts: []*api.TrialBalanceLine{},
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectTrialBalance, report.AsOf, currency)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	var total *api.TrialBalanceTotal
+
+	for rows.Next() {
+		line := &api.TrialBalanceLine{}
+
+		if err = rows.Scan(&line.AccountID, &line.Currency, &line.Debits, &line.Credits); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		line.Balance = line.Credits.Sub(line.Debits)
+		report.Accounts = append(report.Accounts, line)
+
+		// ordered by currency
+		if total == nil || total.Currency != line.Currency {
+			total = &api.TrialBalanceTotal{Currency: line.Currency}
+			report.Totals = append(report.Totals, total)
+		}
+
+		total.Debits = total.Debits.Add(line.Debits)
+		total.Credits = total.Credits.Add(line.Credits)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	for _, total := range report.Totals {
+		total.Balanced = total.Debits.Equal(total.Credits)
+	}
+
+	return report, nil
+}
+
+// ExportGeneralLedger renders the debits and the credits of every account by day, from from included to to excluded,
+// in the currency or in all of them when it's empty, to the writer as they're read.
+// The errors of the writer are returned as is, i.e. when the download is canceled.
+func (r *PostgresRepository) ExportGeneralLedger(ctx context.Context, currency string, from, to time.Time, w api.GeneralLedgerWriter) error {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if len(currency) > 10 {
+		return api.ErrInvalidCurrency
+	}
+
+	if from.IsZero() || !from.Before(to) {
+		return api.ErrInvalidReportPeriod
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectGeneralLedger, from.UTC(), to.UTC(), currency)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		line := &api.GeneralLedgerLine{}
+
+		if err = rows.Scan(&line.Date, &line.AccountID, &line.Currency, &line.Debits, &line.Credits, &line.Entries); err != nil {
+			return formatUnknownError(err)
+		}
+
+		line.Date = line.Date.UTC()
+
+		if err = w.Line(line); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
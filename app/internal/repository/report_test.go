@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// generalLedgerLines collects the lines of the general ledger.
+type generalLedgerLines []*api.GeneralLedgerLine
+
+func (g *generalLedgerLines) Line(line *api.GeneralLedgerLine) error {
+	*g = append(*g, line)
+
+	return nil
+}
+
+func TestReports(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	clk := wallettesting.NewFakeClock(time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC))
+
+	repo := repository.NewPostgresRepository(db).WithClock(clk)
+
+	transfer := func(from, to, currency string, amount int64, idempotencyKey string) {
+		t.Helper()
+
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: from,
+			ToAccountID:   to,
+			Currency:      currency,
+			Amount:        decimal.NewFromInt(amount),
+		}, idempotencyKey)
+		require.NoError(t, err)
+	}
+
+	transfer(api.CompanyAccountID, "report_payer", "USD", 100, "report-1")
+	transfer(api.CompanyAccountID, "report_payer", "EUR", 50, "report-2")
+	clk.Advance(time.Hour)
+	transfer("report_payer", "report_payee", "USD", 30, "report-3")
+	clk.Advance(24 * time.Hour)
+	transfer("report_payer", "report_payee", "USD", 20, "report-4")
+
+	t.Run("Trial balance", func(t *testing.T) {
+		report, err := repo.TrialBalance(ctx, "usd", time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.Equal(t, "USD", report.Currency)
+		require.Len(t, report.Accounts, 3)
+		require.Len(t, report.Totals, 1)
+		require.True(t, decimal.NewFromInt(130).Equal(report.Totals[0].Debits))
+		require.True(t, report.Totals[0].Balanced)
+
+		for _, line := range report.Accounts {
+			if line.AccountID == "report_payer" {
+				require.True(t, decimal.NewFromInt(70).Equal(line.Balance), "the last transfer is after as of")
+			}
+		}
+
+		report, err = repo.TrialBalance(ctx, "", clk.Now().Add(time.Second))
+		require.NoError(t, err)
+		require.Len(t, report.Totals, 2)
+		require.Equal(t, "EUR", report.Totals[0].Currency)
+	})
+
+	t.Run("General ledger", func(t *testing.T) {
+		lines := generalLedgerLines{}
+
+		err := repo.ExportGeneralLedger(ctx, "USD", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), &lines)
+		require.NoError(t, err)
+		require.Len(t, lines, 5, "the company, the payer and the payee the first day, the payer and the payee the second")
+
+		first := lines[0]
+		require.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), first.Date)
+
+		for _, line := range lines {
+			if line.AccountID == "report_payer" && line.Date.Day() == 1 {
+				require.True(t, decimal.NewFromInt(100).Equal(line.Credits))
+				require.True(t, decimal.NewFromInt(30).Equal(line.Debits))
+				require.Equal(t, int64(2), line.Entries)
+			}
+		}
+
+		err = repo.ExportGeneralLedger(ctx, "", clk.Now(), clk.Now(), &lines)
+		require.ErrorIs(t, err, api.ErrInvalidReportPeriod)
+	})
+}
@@ -6,9 +6,32 @@ import (
 	"github.com/devshark/wallet/api"
 )
 
-type Repository interface {
-	Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error)
+// ReadRepository reads the balances, the ledger entries and the account metadata, without changing them.
+// It can be implemented by a read replica or a caching decorator, without stubbing the writes.
+type ReadRepository interface {
 	GetAccountBalance(ctx context.Context, currency, accountID string) (*api.Account, error)
+	GetAccountBalances(ctx context.Context, accounts []api.AccountKey) (*api.Balances, error)
 	GetTransaction(ctx context.Context, txID string) (*api.Transaction, error)
 	GetTransactions(ctx context.Context, currency, accountID string) ([]*api.Transaction, error)
+	FilterTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter) ([]*api.Transaction, error)
+	GetTransactionsPage(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, limit int) (*api.TransactionPage, error)
+	GetAccountTree(ctx context.Context, currency, accountID string) (*api.Account, error)
+	GetAccountStats(ctx context.Context, currency, accountID string) (*api.Account, error)
+	GetAccountSummary(ctx context.Context, accountID string) (*api.AccountSummary, error)
+	ResolveAlias(ctx context.Context, alias string) (*api.AccountAlias, error)
+	GetAccountAliases(ctx context.Context, accountID string) ([]*api.AccountAlias, error)
+}
+
+// WriteRepository posts the transfers and changes the account metadata.
+type WriteRepository interface {
+	Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error)
+	SetParentAccount(ctx context.Context, accountID, parentID string) error
+	RegisterAlias(ctx context.Context, accountID string, request *api.RegisterAliasRequest) (*api.AccountAlias, error)
+	DeleteAlias(ctx context.Context, alias string) error
+}
+
+// Repository is the full repository, implemented by PostgresRepository.
+type Repository interface {
+	ReadRepository
+	WriteRepository
 }
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	// both entries of the transfer of the ledger entry, and whether they're themselves a reversal, or a leg of a conversion
+	selectReversedTransfer = `
+		SELECT transactions.id, transactions.group_id, accounts.user_id, accounts.currency, transactions.amount,
+			transactions.debit_credit, transactions.reversal_of IS NOT NULL, transactions.fx_rate IS NOT NULL
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE transactions.group_id = (SELECT group_id FROM transactions WHERE id = $1)`
+
+	// a dispute reversed or still holding the amount already gives it back, or may give it back, to the sender
+	selectDisputesPendingOrReversed = `SELECT COUNT(1) FROM disputes WHERE group_id = $1 AND status IN ('OPEN', 'REVERSED')`
+
+	selectGroupReversed = `SELECT EXISTS (
+		SELECT 1 FROM transactions WHERE reversal_of IN (SELECT id FROM transactions WHERE group_id = $1))`
+
+	// the unique index on reversal_of rejects a second reversal of the entry
+	updateReversalOf = `UPDATE transactions SET reversal_of = $2 WHERE id = $1`
+)
+
+// reversedTransfer is the transfer of a ledger entry, with the ids of both of its entries.
+type reversedTransfer struct {
+	groupID    string
+	request    *api.TransferRequest
+	debitTxID  string
+	creditTxID string
+}
+
+// ReverseTransaction refunds the transfer of the ledger entry on behalf of the operator: a compensating transfer returns
+// its full amount from the recipient to the sender, which the recipient must still have. Its ledger entries reference the ones
+// they compensate, the credit the reversed debit and the debit the reversed credit, so a transfer is reversed at most once.
+// The transfers of the disputes are resolved by their disputes instead, and a reversal, a conversion or a fee is never reversed,
+// nor is the fee of the reversed transfer refunded.
+// The transfer hooks intercept the compensating transfer like any other.
+func (r *PostgresRepository) ReverseTransaction(ctx context.Context, txID string, request *api.ReverseRequest, operator string) (*api.ReversalReceipt, error) {
+	reversal, groupID, txs, err := r.reverseTransaction(ctx, strings.TrimSpace(txID), request)
+	if errors.Is(err, api.ErrUnhandledDatabaseError) {
+		r.logger.ErrorContext(ctx, "failed to reverse the transaction", slog.String("tx_id", txID),
+			slog.String("operator", operator), slog.Any("error", err))
+	}
+
+	// the transfers that can't be reversed aren't posted
+	if reversal != nil {
+		r.afterTransfer(ctx, reversal, groupID, txs, err)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := api.NewTransferReceipt(reversal, groupID, txs)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	return &api.ReversalReceipt{ReversalOf: strings.TrimSpace(txID), TransferReceipt: receipt}, nil
+}
+
+func (r *PostgresRepository) reverseTransaction(ctx context.Context, txID string, request *api.ReverseRequest) (*api.TransferRequest, string, []*api.Transaction, error) {
+	if !validID(txID) {
+		return nil, "", nil, api.ErrTransactionNotFound
+	}
+
+	original, err := r.reversedTransfer(ctx, txID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var disputes int
+	if err = r.db.QueryRowContext(ctx, selectDisputesPendingOrReversed, original.groupID).Scan(&disputes); err != nil {
+		return nil, "", nil, formatUnknownError(err)
+	}
+
+	if disputes > 0 {
+		return nil, "", nil, api.ErrTransactionDisputed
+	}
+
+	groupID := api.ReversalGroupID(original.debitTxID)
+
+	var existingCount int
+	if err = r.db.QueryRowContext(ctx, selectGroupExists, groupID).Scan(&existingCount); err != nil {
+		return nil, "", nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		return nil, "", nil, api.ErrTransactionReversed
+	}
+
+	reversal := &api.TransferRequest{
+		FromAccountID: original.request.ToAccountID,
+		ToAccountID:   original.request.FromAccountID,
+		Currency:      original.request.Currency,
+		Amount:        original.request.Amount,
+		Remarks:       "reversal of " + txID,
+	}
+
+	if request != nil && strings.TrimSpace(request.Remarks) != "" {
+		reversal.Remarks = strings.TrimSpace(request.Remarks)
+	}
+
+	if err = r.upsertAccounts(ctx, reversal); err != nil {
+		return nil, "", nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", nil, formatUnknownError(err)
+	}
+
+	if err = r.beforeTransfer(ctx, tx, reversal, groupID); err != nil {
+		_ = tx.Rollback()
+
+		return reversal, groupID, nil, err
+	}
+
+	fromTxID, toTxID, err := r.postDoubleEntry(ctx, tx, reversal, groupID)
+	if err == nil {
+		err = referenceReversed(ctx, tx, fromTxID, toTxID, original)
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+
+		// the group of the reversal, posted concurrently
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return reversal, groupID, nil, api.ErrTransactionReversed
+		}
+
+		return reversal, groupID, nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return reversal, groupID, nil, formatUnknownError(err)
+	}
+
+	txs, err := r.getTransactionsByIDs(ctx, fromTxID, toTxID)
+
+	return reversal, groupID, txs, err
+}
+
+// referenceReversed references the entries of the transfer from the entries of its reversal.
+func referenceReversed(ctx context.Context, tx *sql.Tx, fromTxID, toTxID string, original *reversedTransfer) error {
+	for reversalTxID, reversedTxID := range map[string]string{toTxID: original.debitTxID, fromTxID: original.creditTxID} {
+		if _, err := tx.ExecContext(ctx, updateReversalOf, reversalTxID, reversedTxID); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				return api.ErrTransactionReversed
+			}
+
+			return formatUnknownError(err)
+		}
+	}
+
+	return nil
+}
+
+// reversedTransfer returns the transfer of the ledger entry, if it can be reversed.
+func (r *PostgresRepository) reversedTransfer(ctx context.Context, txID string) (*reversedTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, selectReversedTransfer, txID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	transfer := &reversedTransfer{request: &api.TransferRequest{}}
+
+	isReversal, isConversion := false, false
+
+	for rows.Next() {
+		var id, accountID string
+
+		var entryType api.DebitOrCreditType
+
+		var reversalOf, converted bool
+
+		err = rows.Scan(&id, &transfer.groupID, &accountID, &transfer.request.Currency, &transfer.request.Amount, &entryType, &reversalOf, &converted)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		isReversal = isReversal || reversalOf
+		isConversion = isConversion || converted
+
+		if entryType == api.DEBIT {
+			transfer.debitTxID, transfer.request.FromAccountID = id, accountID
+		} else {
+			transfer.creditTxID, transfer.request.ToAccountID = id, accountID
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if transfer.debitTxID == "" || transfer.creditTxID == "" {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	// a leg of a conversion would be reversed without the other, at a rate that has moved since
+	if isReversal || isConversion || transfer.request.FromAccountID == api.DisputesAccountID || transfer.request.ToAccountID == api.DisputesAccountID ||
+		transfer.request.ToAccountID == api.FeesAccountID {
+		return nil, api.ErrNotReversible
+	}
+
+	return transfer, nil
+}
@@ -0,0 +1,122 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransaction(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE disputes CASCADE;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	balance := func(t *testing.T, accountID string) decimal.Decimal {
+		t.Helper()
+
+		account, err := repo.GetAccountBalance(ctx, "USD", accountID)
+		require.NoError(t, err)
+
+		return account.Balance
+	}
+
+	transfer := func(from, to string, amount int64, idempotencyKey string) []*api.Transaction {
+		t.Helper()
+
+		txs, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: from,
+			ToAccountID:   to,
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(amount),
+		}, idempotencyKey)
+		require.NoError(t, err)
+
+		return txs
+	}
+
+	transfer(api.CompanyAccountID, "refund_customer", 100, "refund-deposit")
+	purchase := transfer("refund_customer", "refund_merchant", 60, "refund-purchase")
+
+	var reversal *api.ReversalReceipt
+
+	t.Run("Reverse", func(t *testing.T) {
+		var err error
+
+		// either leg of the transfer
+		reversal, err = repo.ReverseTransaction(ctx, purchase[1].TxID, &api.ReverseRequest{}, "key-operator")
+		require.NoError(t, err)
+		require.Equal(t, purchase[1].TxID, reversal.ReversalOf)
+		require.Equal(t, api.ReversalGroupID(purchase[0].TxID), reversal.GroupID)
+		require.Equal(t, "refund_merchant", reversal.Debit.AccountID)
+		require.Equal(t, "refund_customer", reversal.Credit.AccountID)
+		require.Equal(t, "reversal of "+purchase[1].TxID, reversal.Credit.Remarks)
+
+		require.True(t, decimal.NewFromInt(100).Equal(balance(t, "refund_customer")))
+		require.True(t, decimal.Zero.Equal(balance(t, "refund_merchant")))
+
+		var reversalOf string
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT reversal_of FROM transactions WHERE id = $1", reversal.Credit.TxID).Scan(&reversalOf))
+		require.Equal(t, purchase[0].TxID, reversalOf, "the credit compensates the debit")
+	})
+
+	t.Run("Reversed once", func(t *testing.T) {
+		for _, txID := range []string{purchase[0].TxID, purchase[1].TxID} {
+			_, err := repo.ReverseTransaction(ctx, txID, nil, "key-operator")
+			require.ErrorIs(t, err, api.ErrTransactionReversed)
+		}
+
+		_, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: purchase[0].TxID}, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionReversed)
+	})
+
+	t.Run("Not reversible", func(t *testing.T) {
+		_, err := repo.ReverseTransaction(ctx, reversal.Debit.TxID, nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrNotReversible, "a reversal")
+	})
+
+	t.Run("Disputed", func(t *testing.T) {
+		disputed := transfer("refund_customer", "refund_merchant", 30, "refund-disputed")
+
+		dispute, err := repo.OpenDispute(ctx, &api.OpenDisputeRequest{TxID: disputed[0].TxID}, "key-operator")
+		require.NoError(t, err)
+
+		_, err = repo.ReverseTransaction(ctx, disputed[0].TxID, nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionDisputed)
+
+		var holdTxID string
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT id FROM transactions WHERE group_id = $1 AND debit_credit = 'DEBIT'",
+			dispute.ID+":open").Scan(&holdTxID))
+
+		_, err = repo.ReverseTransaction(ctx, holdTxID, nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrNotReversible, "the hold of a dispute")
+	})
+
+	t.Run("Insufficient balance", func(t *testing.T) {
+		spent := transfer("refund_customer", "refund_merchant", 10, "refund-spent")
+		transfer("refund_merchant", "refund_supplier", 10, "refund-supplier")
+
+		_, err := repo.ReverseTransaction(ctx, spent[0].TxID, nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+	})
+
+	t.Run("Unknown transaction", func(t *testing.T) {
+		_, err := repo.ReverseTransaction(ctx, "not-a-uuid", nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionNotFound)
+
+		_, err = repo.ReverseTransaction(ctx, "00000000-0000-0000-0000-000000000000", nil, "key-operator")
+		require.ErrorIs(t, err, api.ErrTransactionNotFound)
+	})
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// SandboxQuotaPeriod is the rolling period of the sandbox deposit quotas.
+const SandboxQuotaPeriod = 24 * time.Hour
+
+// the amounts credited to the account by the company account since $3
+const selectSandboxDeposits = `
+	SELECT COALESCE(SUM(credit.amount), 0)
+	FROM transactions credit
+	JOIN accounts ON credit.account_id = accounts.id
+	JOIN transactions debit ON debit.group_id = credit.group_id AND debit.debit_credit = 'DEBIT'
+	JOIN accounts company ON debit.account_id = company.id
+	WHERE accounts.user_id = $1 AND accounts.currency = $2 AND credit.debit_credit = 'CREDIT'
+		AND company.user_id = $4 AND credit.created_at > $3`
+
+// WithSandboxQuotas caps the deposits of each account, by currency, over the SandboxQuotaPeriod.
+// The company account of a sandbox funds the deposits with fake money, so the quotas keep the partners' tests bounded.
+// The currencies without a quota aren't capped.
+func (r *PostgresRepository) WithSandboxQuotas(quotas map[string]decimal.Decimal) *PostgresRepository {
+	r.sandboxQuotas = make(map[string]decimal.Decimal, len(quotas))
+
+	for currency, quota := range quotas {
+		r.sandboxQuotas[strings.ToUpper(strings.TrimSpace(currency))] = quota
+	}
+
+	return r
+}
+
+// checkSandboxQuota returns api.ErrSandboxQuotaExceeded if the deposit would take the account above its quota.
+// The concurrent deposits of an account may exceed it slightly, which is fine for fake money.
+func (r *PostgresRepository) checkSandboxQuota(ctx context.Context, request *api.TransferRequest) error {
+	if !r.isCompanyAccount(request.FromAccountID) {
+		return nil
+	}
+
+	quota, ok := r.sandboxQuotas[request.Currency]
+	if !ok {
+		return nil
+	}
+
+	var deposited decimal.Decimal
+
+	since := r.clock.Now().Add(-SandboxQuotaPeriod)
+
+	err := r.db.QueryRowContext(ctx, selectSandboxDeposits, request.ToAccountID, request.Currency, since, r.companyAccountID).
+		Scan(&deposited)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deposited.Add(request.Amount).GreaterThan(quota) {
+		return fmt.Errorf("%w: %s of %s %s left", api.ErrSandboxQuotaExceeded,
+			decimal.Max(quota.Sub(deposited), decimal.Zero), quota, request.Currency)
+	}
+
+	return nil
+}
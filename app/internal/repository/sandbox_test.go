@@ -0,0 +1,49 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandboxQuotas(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	repo := repository.NewPostgresRepository(db).
+		WithSandboxQuotas(map[string]decimal.Decimal{"usd": decimal.NewFromInt(100)})
+
+	deposit := func(accountID, currency string, amount int64, idempotencyKey string) error {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   accountID,
+			Currency:      currency,
+			Amount:        decimal.NewFromInt(amount),
+		}, idempotencyKey)
+
+		return err
+	}
+
+	require.NoError(t, deposit("sandbox_user", "USD", 60, "sandbox-1"))
+	require.NoError(t, deposit("sandbox_user", "USD", 40, "sandbox-2"))
+	require.ErrorIs(t, deposit("sandbox_user", "USD", 1, "sandbox-3"), api.ErrSandboxQuotaExceeded)
+
+	require.NoError(t, deposit("sandbox_other", "USD", 100, "sandbox-4"), "the quota is per account")
+	require.NoError(t, deposit("sandbox_user", "EUR", 1000, "sandbox-5"), "the currencies without a quota aren't capped")
+
+	// the transfers between the accounts don't count
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: "sandbox_other",
+		ToAccountID:   "sandbox_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}, "sandbox-6")
+	require.NoError(t, err)
+}
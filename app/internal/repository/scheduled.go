@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	scheduledTransferColumns = `id, idempotency_key, from_account_id, to_account_id, currency, amount,
+		COALESCE(remarks, ''), tags, execute_at, status, COALESCE(error_code, ''), COALESCE(error_message, ''), created_at, closed_at`
+
+	// a retried request finds the transfer already scheduled with its idempotency key, so it's never scheduled twice
+	insertScheduledTransfer = `INSERT INTO scheduled_transfers
+		(id, idempotency_key, from_account_id, to_account_id, currency, amount, remarks, tags, execute_at, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11)
+		ON CONFLICT (idempotency_key) DO NOTHING`
+
+	selectScheduledTransfer = `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE id = $1`
+
+	selectScheduledTransferByKey = `SELECT ` + scheduledTransferColumns + ` FROM scheduled_transfers WHERE idempotency_key = $1`
+
+	// the transfers of the account, either sent or received
+	selectAccountScheduledTransfers = `SELECT ` + scheduledTransferColumns + `
+		FROM scheduled_transfers
+		WHERE (from_account_id = $1 OR to_account_id = $1) AND status = $2
+		ORDER BY execute_at, created_at
+		LIMIT $3`
+
+	// the transfers of the account, whatever their status
+	selectAllAccountScheduledTransfers = `SELECT ` + scheduledTransferColumns + `
+		FROM scheduled_transfers
+		WHERE from_account_id = $1 OR to_account_id = $1
+		ORDER BY created_at, id`
+
+	// skipping the locked transfers lets several workers execute the due ones concurrently without posting them twice
+	selectDueScheduledTransfers = `SELECT ` + scheduledTransferColumns + `
+		FROM scheduled_transfers
+		WHERE status = 'SCHEDULED' AND execute_at <= $1
+		ORDER BY execute_at, created_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+
+	// only a scheduled transfer can be cancelled, so it's never cancelled once executed
+	cancelScheduledTransfer = `UPDATE scheduled_transfers SET status = 'CANCELLED', closed_at = $2
+		WHERE id = $1 AND status = 'SCHEDULED'
+		RETURNING ` + scheduledTransferColumns
+
+	updateScheduledTransfer = `UPDATE scheduled_transfers SET status = $2, error_code = NULLIF($3, ''), error_message = NULLIF($4, ''), closed_at = $5
+		WHERE id = $1`
+)
+
+// ScheduleTransfer validates the transfer, and schedules it to be posted by ProcessScheduledTransfers once it's due.
+// The transfer is validated again when it's due, so it may still fail then, i.e. for an insufficient balance.
+// Scheduling it again with the same idempotency key returns the transfer already scheduled.
+// The remarks are encrypted with the data key of the sender until posted, see WithFieldEncryption.
+func (r *PostgresRepository) ScheduleTransfer(ctx context.Context, request *api.ScheduleTransferRequest, idempotencyKey string) (*api.ScheduledTransfer, error) {
+	if strings.TrimSpace(idempotencyKey) == "" {
+		return nil, api.ErrMissingIdempotencyKey
+	}
+
+	if len(idempotencyKey) > maxGroupIDLength {
+		return nil, fmt.Errorf("%w: the idempotency key is longer than %d characters", api.ErrInvalidRequest, maxGroupIDLength)
+	}
+
+	if !request.ExecuteAt.After(r.clock.Now()) {
+		return nil, fmt.Errorf("%w: execute_at must be in the future", api.ErrInvalidRequest)
+	}
+
+	if err := r.validateTransfer(ctx, &request.TransferRequest); err != nil {
+		return nil, err
+	}
+
+	if err := rejectConversion(&request.TransferRequest, "scheduled transfers"); err != nil {
+		return nil, err
+	}
+
+	var existingCount int
+	if err := r.db.QueryRowContext(ctx, selectGroupExists, idempotencyKey).Scan(&existingCount); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if existingCount > 0 {
+		return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	remarks, err := r.sealRemarks(ctx, tx, request.FromAccountID, request.Remarks)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertScheduledTransfer, r.idGenerator.NewID(), idempotencyKey, request.FromAccountID, request.ToAccountID,
+		request.Currency, request.Amount, remarks, pq.Array(tagsOf(request.Tags)), request.ExecuteAt.UTC(),
+		api.ScheduledTransferScheduled, r.clock.Now())
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	scheduled, err := scanScheduledTransfer(tx.QueryRowContext(ctx, selectScheduledTransferByKey, idempotencyKey))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &scheduled.Remarks); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// GetScheduledTransfer returns the scheduled transfer, whatever its status.
+func (r *PostgresRepository) GetScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error) {
+	if !validID(id) {
+		return nil, api.ErrScheduledTransferNotFound
+	}
+
+	scheduled, err := scanScheduledTransfer(r.db.QueryRowContext(ctx, selectScheduledTransfer, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrScheduledTransferNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &scheduled.Remarks); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// ListScheduledTransfers returns the scheduled transfers sent or received by the account in the status, the earliest due first.
+func (r *PostgresRepository) ListScheduledTransfers(ctx context.Context, accountID string, status api.ScheduledTransferStatus, limit int) ([]*api.ScheduledTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, selectAccountScheduledTransfers, accountID, status, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.openScheduledTransfers(ctx, rows)
+}
+
+// getAccountScheduledTransfers returns the transfers scheduled by or to the account, the oldest first, whatever their status.
+func (r *PostgresRepository) getAccountScheduledTransfers(ctx context.Context, accountID string) ([]*api.ScheduledTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, selectAllAccountScheduledTransfers, accountID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return r.openScheduledTransfers(ctx, rows)
+}
+
+// CancelScheduledTransfer cancels the transfer before it's due.
+// It returns api.ErrScheduledTransferClosed once the transfer was executed or cancelled.
+func (r *PostgresRepository) CancelScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error) {
+	if !validID(id) {
+		return nil, api.ErrScheduledTransferNotFound
+	}
+
+	scheduled, err := scanScheduledTransfer(r.db.QueryRowContext(ctx, cancelScheduledTransfer, id, r.clock.Now()))
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// either there's no such transfer, or it's already closed
+		if _, err = r.GetScheduledTransfer(ctx, id); err != nil {
+			return nil, err
+		}
+
+		return nil, api.ErrScheduledTransferClosed
+	case err != nil:
+		return nil, formatUnknownError(err)
+	}
+
+	if err = r.openRemarks(ctx, dataKeys{}, &scheduled.Remarks); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// ProcessScheduledTransfers posts the due transfers, at most limit, and returns how many were processed,
+// i.e. posted, held or failed. Like ProcessQueuedTransfers, the transfers are posted one at a time while they're locked,
+// and a transfer failing on an unhandled database error stays scheduled, and stops the batch, to be retried by the next one.
+func (r *PostgresRepository) ProcessScheduledTransfers(ctx context.Context, limit int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, selectDueScheduledTransfers, r.clock.Now(), limit)
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	// decrypted to be posted, which encrypts them again
+	due, err := r.openScheduledTransfers(ctx, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+
+	for _, transfer := range due {
+		// posted on another connection, the transfers stay locked until the outcomes are recorded
+		_, transferErr := r.Transfer(ctx, transfer.Transfer(), transfer.IdempotencyKey)
+
+		// the outcomes of a transfer are the same, whether it was queued or scheduled
+		outcome, retry := queuedTransferOutcome(transferErr)
+		if retry {
+			// the outcomes of the transfers before it are still recorded
+			if err = tx.Commit(); err != nil {
+				return 0, formatUnknownError(err)
+			}
+
+			return processed, fmt.Errorf("failed to post scheduled transfer %s: %w", transfer.ID, transferErr)
+		}
+
+		var code api.ErrorCode
+
+		var message string
+
+		if transferErr != nil {
+			code, message = api.CodeOf(transferErr), transferErr.Error()
+		}
+
+		if _, err = tx.ExecContext(ctx, updateScheduledTransfer, transfer.ID, scheduledTransferStatus(outcome), code, message, r.clock.Now()); err != nil {
+			return 0, formatUnknownError(err)
+		}
+
+		processed++
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return processed, nil
+}
+
+// scheduledTransferStatus is the status of the scheduled transfer posted with the outcome of a queued one,
+// which is never api.QueuedTransferQueued once it isn't retried.
+func scheduledTransferStatus(outcome api.QueuedTransferStatus) api.ScheduledTransferStatus {
+	switch outcome {
+	case api.QueuedTransferCompleted:
+		return api.ScheduledTransferCompleted
+	case api.QueuedTransferHeld:
+		return api.ScheduledTransferHeld
+	default:
+		return api.ScheduledTransferFailed
+	}
+}
+
+// openScheduledTransfers scans the scheduled transfers of the rows, and decrypts their remarks.
+func (r *PostgresRepository) openScheduledTransfers(ctx context.Context, rows *sql.Rows) ([]*api.ScheduledTransfer, error) {
+	defer rows.Close()
+
+	scheduled := []*api.ScheduledTransfer{}
+	remarks := []*string{}
+
+	for rows.Next() {
+		transfer, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		scheduled = append(scheduled, transfer)
+		remarks = append(remarks, &transfer.Remarks)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err := r.openRemarks(ctx, dataKeys{}, remarks...); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+func scanScheduledTransfer(row rowScanner) (*api.ScheduledTransfer, error) {
+	scheduled := &api.ScheduledTransfer{}
+
+	var closedAt sql.NullTime
+
+	err := row.Scan(&scheduled.ID, &scheduled.IdempotencyKey, &scheduled.FromAccountID, &scheduled.ToAccountID, &scheduled.Currency,
+		&scheduled.Amount, &scheduled.Remarks, pq.Array(&scheduled.Tags), &scheduled.ExecuteAt, &scheduled.Status, &scheduled.Code,
+		&scheduled.Error, &scheduled.CreatedAt, &closedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if closedAt.Valid {
+		scheduled.ClosedAt = &closedAt.Time
+	}
+
+	return scheduled, nil
+}
@@ -0,0 +1,138 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTransfers(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE scheduled_transfers;")
+		require.NoError(t, err)
+	})
+
+	clk := wallettesting.NewFakeClock(time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC))
+
+	repo := repository.NewPostgresRepository(db).WithClock(clk)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "scheduled_tenant",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "scheduled-funding")
+	require.NoError(t, err)
+
+	schedule := func(amount int64, executeIn time.Duration, idempotencyKey string) (*api.ScheduledTransfer, error) {
+		return repo.ScheduleTransfer(ctx, &api.ScheduleTransferRequest{
+			TransferRequest: api.TransferRequest{
+				FromAccountID: "scheduled_tenant",
+				ToAccountID:   "scheduled_landlord",
+				Currency:      "usd",
+				Amount:        decimal.NewFromInt(amount),
+				Remarks:       "rent",
+			},
+			ExecuteAt: clk.Now().Add(executeIn),
+		}, idempotencyKey)
+	}
+
+	rent, err := schedule(60, time.Hour, "scheduled-1")
+	require.NoError(t, err)
+	require.Equal(t, api.ScheduledTransferScheduled, rent.Status)
+	require.Equal(t, "USD", rent.Currency)
+
+	overdraft, err := schedule(60, 2*time.Hour, "scheduled-2")
+	require.NoError(t, err)
+
+	cancelled, err := schedule(10, time.Hour, "scheduled-3")
+	require.NoError(t, err)
+
+	later, err := schedule(10, 24*time.Hour, "scheduled-4")
+	require.NoError(t, err)
+
+	t.Run("Scheduled once", func(t *testing.T) {
+		retried, err := schedule(60, time.Hour, "scheduled-1")
+		require.NoError(t, err)
+		require.Equal(t, rent.ID, retried.ID)
+
+		_, err = schedule(1, time.Hour, "scheduled-funding")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction, "the key of a posted transfer")
+	})
+
+	t.Run("In the future", func(t *testing.T) {
+		_, err := schedule(1, 0, "scheduled-5")
+		require.ErrorIs(t, err, api.ErrInvalidRequest)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		got, err := repo.CancelScheduledTransfer(ctx, cancelled.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.ScheduledTransferCancelled, got.Status)
+		require.NotNil(t, got.ClosedAt)
+
+		_, err = repo.CancelScheduledTransfer(ctx, cancelled.ID)
+		require.ErrorIs(t, err, api.ErrScheduledTransferClosed)
+	})
+
+	t.Run("Listed by account", func(t *testing.T) {
+		list, err := repo.ListScheduledTransfers(ctx, "scheduled_landlord", api.ScheduledTransferScheduled, 10)
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		require.Equal(t, rent.ID, list[0].ID, "the earliest due first")
+		require.Equal(t, later.ID, list[2].ID)
+	})
+
+	t.Run("Executed once due", func(t *testing.T) {
+		processed, err := repo.ProcessScheduledTransfers(ctx, 10)
+		require.NoError(t, err)
+		require.Zero(t, processed, "none is due")
+
+		clk.Advance(3 * time.Hour)
+
+		processed, err = repo.ProcessScheduledTransfers(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, 2, processed)
+
+		got, err := repo.GetScheduledTransfer(ctx, rent.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.ScheduledTransferCompleted, got.Status)
+
+		txs, err := repo.GetTransactions(ctx, "USD", "scheduled_landlord")
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		require.Equal(t, "scheduled-1", txs[0].GroupID)
+
+		got, err = repo.GetScheduledTransfer(ctx, overdraft.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.ScheduledTransferFailed, got.Status)
+		require.Equal(t, api.CodeInsufficientBalance, got.Code)
+
+		got, err = repo.GetScheduledTransfer(ctx, later.ID)
+		require.NoError(t, err)
+		require.Equal(t, api.ScheduledTransferScheduled, got.Status)
+
+		_, err = repo.CancelScheduledTransfer(ctx, rent.ID)
+		require.ErrorIs(t, err, api.ErrScheduledTransferClosed)
+	})
+
+	t.Run("Unknown transfer", func(t *testing.T) {
+		_, err := repo.GetScheduledTransfer(ctx, "not-a-uuid")
+		require.ErrorIs(t, err, api.ErrScheduledTransferNotFound)
+
+		_, err = repo.CancelScheduledTransfer(ctx, "00000000-0000-0000-0000-000000000000")
+		require.ErrorIs(t, err, api.ErrScheduledTransferNotFound)
+	})
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/devshark/wallet/api"
+	"github.com/lib/pq"
+)
+
+const (
+	selectReviewStatus = `SELECT status FROM transfer_reviews WHERE idempotency_key = $1`
+
+	// a retried transfer finds its review by the idempotency key, so it's never held twice
+	insertTransferReview = `INSERT INTO transfer_reviews
+		(idempotency_key, from_account_id, to_account_id, currency, amount, remarks, reason, status, created_at, tags, to_currency)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10, NULLIF($11, ''))
+		ON CONFLICT (idempotency_key) DO NOTHING`
+
+	selectTransferReview = `SELECT idempotency_key, from_account_id, to_account_id, currency, amount,
+			COALESCE(remarks, ''), tags, reason, status, created_at, decided_at, COALESCE(to_currency, '')
+		FROM transfer_reviews
+		WHERE idempotency_key = $1`
+
+	selectTransferReviews = `SELECT idempotency_key, from_account_id, to_account_id, currency, amount,
+			COALESCE(remarks, ''), tags, reason, status, created_at, decided_at, COALESCE(to_currency, '')
+		FROM transfer_reviews
+		WHERE status = $1
+		ORDER BY created_at
+		LIMIT $2`
+
+	// only a pending review can be decided, so two reviewers can't both decide it
+	decideTransferReview = `UPDATE transfer_reviews SET status = $2, decided_at = $3
+		WHERE idempotency_key = $1 AND status = 'PENDING'
+		RETURNING from_account_id, to_account_id, currency, amount, COALESCE(remarks, ''), tags, COALESCE(to_currency, '')`
+
+	reopenTransferReview = `UPDATE transfer_reviews SET status = 'PENDING', decided_at = NULL WHERE idempotency_key = $1`
+)
+
+// WithScreening screens the transfers with the provider before posting them.
+// The flagged transfers are held for a review instead, see ApproveTransferReview and RejectTransferReview.
+func (r *PostgresRepository) WithScreening(provider api.ScreeningProvider) *PostgresRepository {
+	r.screening = provider
+
+	return r
+}
+
+// screen returns api.ErrTransferUnderReview if the transfer is flagged, or was already flagged and is still pending,
+// and api.ErrTransferRejected if it was rejected.
+func (r *PostgresRepository) screen(ctx context.Context, screening api.ScreeningProvider, request *api.TransferRequest, idempotencyKey string) error {
+	var status api.ReviewStatus
+
+	err := r.db.QueryRowContext(ctx, selectReviewStatus, idempotencyKey).Scan(&status)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return formatUnknownError(err)
+	case status == api.ReviewRejected:
+		return api.ErrTransferRejected
+	default:
+		// an approved transfer is being posted, the retry finds it posted once it's done
+		return api.ErrTransferUnderReview
+	}
+
+	result, err := screening.Screen(ctx, request)
+	if err != nil {
+		return fmt.Errorf("%w: %w", api.ErrScreeningFailed, err)
+	}
+
+	if !result.Flagged {
+		return nil
+	}
+
+	_, err = r.db.ExecContext(ctx, insertTransferReview, idempotencyKey, request.FromAccountID, request.ToAccountID,
+		request.Currency, request.Amount, request.Remarks, result.Reason, api.ReviewPending, r.clock.Now(), pq.Array(tagsOf(request.Tags)), request.ToCurrency)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	return api.ErrTransferUnderReview
+}
+
+// GetTransferReview returns the review of the transfer with the idempotency key.
+func (r *PostgresRepository) GetTransferReview(ctx context.Context, idempotencyKey string) (*api.TransferReview, error) {
+	review, err := scanTransferReview(r.db.QueryRowContext(ctx, selectTransferReview, idempotencyKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrReviewNotFound
+	}
+
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return review, nil
+}
+
+// ListTransferReviews returns the reviews in the status, at most limit, the oldest first.
+func (r *PostgresRepository) ListTransferReviews(ctx context.Context, status api.ReviewStatus, limit int) ([]*api.TransferReview, error) {
+	rows, err := r.db.QueryContext(ctx, selectTransferReviews, status, limit)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	reviews := []*api.TransferReview{}
+
+	for rows.Next() {
+		review, err := scanTransferReview(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return reviews, nil
+}
+
+// ApproveTransferReview posts the held transfer, without screening it again, and returns its ledger entries.
+// If the transfer fails, i.e. the balance is now insufficient, the review stays pending.
+// A transfer above the approval threshold is then held for its approval, see ApprovePendingTransfer.
+func (r *PostgresRepository) ApproveTransferReview(ctx context.Context, idempotencyKey string) ([]*api.Transaction, error) {
+	request, err := r.decideTransferReview(ctx, idempotencyKey, api.ReviewApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := r.transfer(ctx, request, idempotencyKey, holds{approval: true})
+	if errors.Is(err, api.ErrTransferPendingApproval) {
+		// the review is done, the transfer now waits for its approval
+		return nil, err
+	}
+
+	if err != nil {
+		if _, reopenErr := r.db.ExecContext(ctx, reopenTransferReview, idempotencyKey); reopenErr != nil {
+			return nil, errors.Join(err, formatUnknownError(reopenErr))
+		}
+
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// RejectTransferReview rejects the held transfer for good, its retries fail with api.ErrTransferRejected.
+func (r *PostgresRepository) RejectTransferReview(ctx context.Context, idempotencyKey string) error {
+	_, err := r.decideTransferReview(ctx, idempotencyKey, api.ReviewRejected)
+
+	return err
+}
+
+// decideTransferReview moves the pending review to the status, and returns its transfer.
+func (r *PostgresRepository) decideTransferReview(ctx context.Context, idempotencyKey string, status api.ReviewStatus) (*api.TransferRequest, error) {
+	request := &api.TransferRequest{}
+
+	err := r.db.QueryRowContext(ctx, decideTransferReview, idempotencyKey, status, r.clock.Now()).
+		Scan(&request.FromAccountID, &request.ToAccountID, &request.Currency, &request.Amount, &request.Remarks, pq.Array(&request.Tags), &request.ToCurrency)
+	if err == nil {
+		return request, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, formatUnknownError(err)
+	}
+
+	// either there's no such review, or it's no longer pending
+	if _, err = r.GetTransferReview(ctx, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return nil, api.ErrReviewDecided
+}
+
+func scanTransferReview(row rowScanner) (*api.TransferReview, error) {
+	review := &api.TransferReview{}
+
+	var decidedAt sql.NullTime
+
+	err := row.Scan(&review.IdempotencyKey, &review.FromAccountID, &review.ToAccountID, &review.Currency, &review.Amount,
+		&review.Remarks, pq.Array(&review.Tags), &review.Reason, &review.Status, &review.CreatedAt, &decidedAt, &review.ToCurrency)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if decidedAt.Valid {
+		review.DecidedAt = &decidedAt.Time
+	}
+
+	return review, nil
+}
@@ -0,0 +1,147 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/screening"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+type failingScreening struct{}
+
+func (failingScreening) Screen(context.Context, *api.TransferRequest) (api.ScreeningResult, error) {
+	return api.ScreeningResult{}, errors.New("provider unreachable")
+}
+
+func TestTransferScreening(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE transfer_reviews;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db).
+		WithScreening(screening.NewDenylist([]string{"sanctioned"}, []string{"embargo"}))
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "screened_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(100),
+	}, "screening-deposit")
+	require.NoError(t, err)
+
+	flagged := func() *api.TransferRequest {
+		return &api.TransferRequest{
+			FromAccountID: "screened_user",
+			ToAccountID:   "sanctioned",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(40),
+		}
+	}
+
+	t.Run("Held for review", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, flagged(), "screening-held")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview)
+
+		// the retries are still pending, without another review
+		_, err = repo.Transfer(ctx, flagged(), "screening-held")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview)
+
+		reviews, err := repo.ListTransferReviews(ctx, api.ReviewPending, 10)
+		require.NoError(t, err)
+		require.Len(t, reviews, 1)
+		require.Equal(t, "screening-held", reviews[0].IdempotencyKey)
+		require.Contains(t, reviews[0].Reason, "sanctioned")
+		require.Nil(t, reviews[0].DecidedAt)
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "screened_user")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(100).Equal(account.Balance))
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		txs, err := repo.ApproveTransferReview(ctx, "screening-held")
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+
+		account, err := repo.GetAccountBalance(ctx, "USD", "screened_user")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(60).Equal(account.Balance))
+
+		review, err := repo.GetTransferReview(ctx, "screening-held")
+		require.NoError(t, err)
+		require.Equal(t, api.ReviewApproved, review.Status)
+		require.NotNil(t, review.DecidedAt)
+
+		_, err = repo.ApproveTransferReview(ctx, "screening-held")
+		require.ErrorIs(t, err, api.ErrReviewDecided)
+
+		_, err = repo.Transfer(ctx, flagged(), "screening-held")
+		require.ErrorIs(t, err, api.ErrDuplicateTransaction)
+	})
+
+	t.Run("Approve with insufficient balance", func(t *testing.T) {
+		request := flagged()
+		request.Amount = decimal.NewFromInt(1000)
+
+		_, err := repo.Transfer(ctx, request, "screening-too-much")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview)
+
+		_, err = repo.ApproveTransferReview(ctx, "screening-too-much")
+		require.ErrorIs(t, err, api.ErrInsufficientBalance)
+
+		review, err := repo.GetTransferReview(ctx, "screening-too-much")
+		require.NoError(t, err)
+		require.Equal(t, api.ReviewPending, review.Status)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		request := flagged()
+		request.ToAccountID = "another_user"
+		request.Remarks = "Embargo goods"
+
+		_, err := repo.Transfer(ctx, request, "screening-rejected")
+		require.ErrorIs(t, err, api.ErrTransferUnderReview)
+
+		require.NoError(t, repo.RejectTransferReview(ctx, "screening-rejected"))
+
+		_, err = repo.Transfer(ctx, request, "screening-rejected")
+		require.ErrorIs(t, err, api.ErrTransferRejected)
+
+		require.ErrorIs(t, repo.RejectTransferReview(ctx, "screening-rejected"), api.ErrReviewDecided)
+		require.ErrorIs(t, repo.RejectTransferReview(ctx, "unknown"), api.ErrReviewNotFound)
+	})
+
+	t.Run("Clear transfers are posted", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "screened_user",
+			ToAccountID:   "another_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, "screening-clear")
+		require.NoError(t, err)
+	})
+
+	t.Run("Failing provider", func(t *testing.T) {
+		repo := repository.NewPostgresRepository(db).WithScreening(failingScreening{})
+
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: "screened_user",
+			ToAccountID:   "another_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, "screening-failed")
+		require.ErrorIs(t, err, api.ErrScreeningFailed)
+	})
+}
@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+// maxStatementError bounds the last delivery error kept on a statement.
+const maxStatementError = 1024
+
+const (
+	// an existing subscription is kept, and returned by the select
+	insertSubscription = `INSERT INTO statement_subscriptions (id, account_id, currency, channel, destination, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT ON CONSTRAINT unique_statement_subscription DO NOTHING`
+
+	selectSubscription = `SELECT id, account_id, currency, channel, destination, created_at
+		FROM statement_subscriptions
+		WHERE account_id = $1 AND currency = $2 AND channel = $3 AND destination = $4`
+
+	selectSubscriptions = `SELECT id, account_id, currency, channel, destination, created_at
+		FROM statement_subscriptions
+		WHERE account_id = $1
+		ORDER BY created_at, id`
+
+	deleteSubscription = `DELETE FROM statement_subscriptions WHERE id = $1`
+
+	// the balance of the subscribed account before a time, as the sum of its entries,
+	// so the balances of a closed period don't change
+	sumOfEntriesBefore = `COALESCE((
+			SELECT SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END)
+			FROM transactions
+			JOIN accounts ON transactions.account_id = accounts.id
+			WHERE accounts.user_id = statement_subscriptions.account_id
+				AND accounts.currency = statement_subscriptions.currency
+				AND transactions.created_at < `
+
+	// the subscriptions created after the period don't get its statement
+	insertStatements = `INSERT INTO statements (subscription_id, account_id, currency, channel, destination,
+			period_start, period_end, opening_balance, closing_balance, next_attempt_at, created_at)
+		SELECT id, account_id, currency, channel, destination, $1, $2,
+			` + sumOfEntriesBefore + `$1), 0),
+			` + sumOfEntriesBefore + `$2), 0),
+			$3, $3
+		FROM statement_subscriptions
+		WHERE created_at < $2
+		ON CONFLICT ON CONSTRAINT unique_statement_period DO NOTHING`
+
+	statementColumns = `id, account_id, currency, channel, destination, period_start, period_end,
+			opening_balance, closing_balance, status, attempts, COALESCE(last_error, ''), delivered_at, created_at`
+
+	// the unsubscribed statements are no longer delivered
+	selectDueStatements = `SELECT ` + statementColumns + `
+		FROM statements
+		WHERE status = 'PENDING' AND subscription_id IS NOT NULL AND next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $2`
+
+	selectAccountStatements = `SELECT ` + statementColumns + `
+		FROM statements
+		WHERE account_id = $1
+		ORDER BY period_start DESC, created_at DESC
+		LIMIT $2`
+
+	selectStatementTransactions = `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		LEFT JOIN transaction_metadata ON transaction_metadata.tx_id = transactions.id
+		` + counterpartyJoin + `
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND transactions.created_at >= $3 AND transactions.created_at < $4
+		ORDER BY transactions.created_at, transactions.id`
+
+	// the balance of the account before the downloaded period, as the sum of its entries
+	selectBalanceBefore = `SELECT COALESCE(SUM(CASE WHEN transactions.debit_credit = 'CREDIT' THEN transactions.amount ELSE -transactions.amount END), 0)
+		FROM transactions
+		JOIN accounts ON transactions.account_id = accounts.id
+		WHERE accounts.currency = $1 AND accounts.user_id = $2 AND transactions.created_at < $3`
+
+	markStatementDelivered = `UPDATE statements SET status = 'DELIVERED', attempts = attempts + 1, last_error = NULL, delivered_at = $2
+		WHERE id = $1`
+
+	// without a next attempt, the statement has failed for good
+	markStatementFailed = `UPDATE statements SET attempts = attempts + 1, last_error = $2,
+			status = CASE WHEN $3::TIMESTAMP IS NULL THEN 'FAILED' ELSE 'PENDING' END,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE id = $1`
+)
+
+// SubscribeStatements subscribes the account to its monthly statements in the currency, delivered to the destination.
+// Subscribing again to the same destination is a no-op.
+func (r *PostgresRepository) SubscribeStatements(ctx context.Context, request *api.SubscribeStatementsRequest) (*api.StatementSubscription, error) {
+	accountID := strings.TrimSpace(request.AccountID)
+	if accountID == "" || len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(request.Currency))
+	if currency == "" || len(currency) > 10 {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	channel := api.StatementChannel(strings.ToUpper(strings.TrimSpace(string(request.Channel))))
+
+	destination, err := statementDestination(channel, request.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, insertSubscription, r.idGenerator.NewID(), accountID, currency, channel, destination, r.clock.Now())
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	subscription, err := scanSubscription(r.db.QueryRowContext(ctx, selectSubscription, accountID, currency, channel, destination))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscription, nil
+}
+
+// GetStatementSubscriptions returns the statement subscriptions of the account, the oldest first.
+func (r *PostgresRepository) GetStatementSubscriptions(ctx context.Context, accountID string) ([]*api.StatementSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, selectSubscriptions, strings.TrimSpace(accountID))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	subscriptions := []*api.StatementSubscription{}
+
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteStatementSubscription unsubscribes, the statements already generated are kept but no longer delivered.
+func (r *PostgresRepository) DeleteStatementSubscription(ctx context.Context, id string) error {
+	if !validID(id) {
+		return api.ErrSubscriptionNotFound
+	}
+
+	result, err := r.db.ExecContext(ctx, deleteSubscription, id)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deleted == 0 {
+		return api.ErrSubscriptionNotFound
+	}
+
+	return nil
+}
+
+// GetStatements returns the statements of the account with their delivery status, at most limit, the latest period first.
+func (r *PostgresRepository) GetStatements(ctx context.Context, accountID string, limit int) ([]*api.Statement, error) {
+	return r.queryStatements(ctx, selectAccountStatements, strings.TrimSpace(accountID), limit)
+}
+
+// ScheduleStatements generates the statement of the period, from start included to end excluded, of every subscription.
+// The period is only generated once per subscription, so it can be scheduled repeatedly. It returns the number generated.
+func (r *PostgresRepository) ScheduleStatements(ctx context.Context, start, end time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, insertStatements, start, end, r.clock.Now())
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	scheduled, err := result.RowsAffected()
+	if err != nil {
+		return 0, formatUnknownError(err)
+	}
+
+	return scheduled, nil
+}
+
+// DueStatements returns the pending statements due for a delivery attempt at the time, at most limit,
+// with the entries of their period.
+func (r *PostgresRepository) DueStatements(ctx context.Context, at time.Time, limit int) ([]*api.Statement, error) {
+	statements, err := r.queryStatements(ctx, selectDueStatements, at, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range statements {
+		if err = r.withStatementTransactions(ctx, statement); err != nil {
+			return nil, err
+		}
+	}
+
+	return statements, nil
+}
+
+// MarkStatementDelivered records the successful delivery of the statement.
+func (r *PostgresRepository) MarkStatementDelivered(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, markStatementDelivered, id, r.clock.Now()); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+// MarkStatementFailed records a failed delivery of the statement, retried at retryAt, or failed for good if it's nil.
+func (r *PostgresRepository) MarkStatementFailed(ctx context.Context, id, reason string, retryAt *time.Time) error {
+	if len(reason) > maxStatementError {
+		reason = reason[:maxStatementError]
+	}
+
+	if _, err := r.db.ExecContext(ctx, markStatementFailed, id, reason, retryAt); err != nil {
+		return formatUnknownError(err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) queryStatements(ctx context.Context, query string, args ...any) ([]*api.Statement, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	statements := []*api.Statement{}
+
+	for rows.Next() {
+		statement, err := scanStatement(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		statements = append(statements, statement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return statements, nil
+}
+
+// withStatementTransactions sets the entries of the statement's period, with their running balance from the opening balance.
+func (r *PostgresRepository) withStatementTransactions(ctx context.Context, statement *api.Statement) error {
+	rows, err := r.db.QueryContext(ctx, selectStatementTransactions, statement.Currency, statement.AccountID, statement.PeriodStart, statement.PeriodEnd)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	transactions, err := r.readTransactions(ctx, rows)
+	if err != nil {
+		return err
+	}
+
+	balance := statement.OpeningBalance
+
+	for _, transaction := range transactions {
+		if transaction.Type == api.CREDIT {
+			balance = balance.Add(transaction.Amount)
+		} else {
+			balance = balance.Sub(transaction.Amount)
+		}
+
+		transaction.RunningBalance = balance
+	}
+
+	statement.Transactions = transactions
+
+	return nil
+}
+
+// DownloadStatement renders the statement of the account in the currency, from from included to to excluded,
+// to the writer as its entries are read, so the statements of any period aren't held in memory.
+// The entries and the balances are read from the same snapshot of the ledger.
+// The errors of the writer are returned as is, i.e. when the download is canceled.
+func (r *PostgresRepository) DownloadStatement(ctx context.Context, accountID, currency string, from, to time.Time, w api.StatementWriter) error {
+	accountID = strings.TrimSpace(accountID)
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return err
+	}
+
+	if from.IsZero() || !from.Before(to) {
+		return api.ErrInvalidStatementPeriod
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	// read-only, there is nothing to commit
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	statement := &api.Statement{
+		AccountID:   accountID,
+		Currency:    currency,
+		PeriodStart: from.UTC(),
+		PeriodEnd:   to.UTC(),
+		CreatedAt:   r.clock.Now(),
+	}
+
+	if err = tx.QueryRowContext(ctx, selectBalanceBefore, currency, accountID, statement.PeriodStart).Scan(&statement.OpeningBalance); err != nil {
+		return formatUnknownError(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, selectStatementTransactions, currency, accountID, statement.PeriodStart, statement.PeriodEnd)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	if err = w.Begin(statement); err != nil {
+		return err
+	}
+
+	balance := statement.OpeningBalance
+	// the entries of an account are encrypted with the same keys, loaded once
+	keys := dataKeys{}
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return formatUnknownError(err)
+		}
+
+		if err = r.openRemarks(ctx, keys, &transaction.Remarks); err != nil {
+			return err
+		}
+
+		if transaction.Type == api.CREDIT {
+			balance = balance.Add(transaction.Amount)
+		} else {
+			balance = balance.Sub(transaction.Amount)
+		}
+
+		transaction.RunningBalance = balance
+
+		if err = w.Entry(transaction); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return formatUnknownError(err)
+	}
+
+	statement.ClosingBalance = balance
+
+	return w.End(statement)
+}
+
+// statementDestination validates the destination of the channel, and returns it normalized.
+func statementDestination(channel api.StatementChannel, destination string) (string, error) {
+	destination = strings.TrimSpace(destination)
+
+	switch channel {
+	case api.StatementWebhook:
+		parsed, err := url.Parse(destination)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || len(destination) > 2048 {
+			return "", api.ErrInvalidStatementChannel
+		}
+
+		return parsed.String(), nil
+	case api.StatementEmail:
+		address, err := mail.ParseAddress(destination)
+		if err != nil || len(address.Address) > 254 {
+			return "", api.ErrInvalidStatementChannel
+		}
+
+		return strings.ToLower(address.Address), nil
+	default:
+		return "", api.ErrInvalidStatementChannel
+	}
+}
+
+func scanSubscription(row rowScanner) (*api.StatementSubscription, error) {
+	subscription := &api.StatementSubscription{}
+
+	err := row.Scan(&subscription.ID, &subscription.AccountID, &subscription.Currency, &subscription.Channel,
+		&subscription.Destination, &subscription.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	return subscription, nil
+}
+
+func scanStatement(row rowScanner) (*api.Statement, error) {
+	statement := &api.Statement{}
+
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(&statement.ID, &statement.AccountID, &statement.Currency, &statement.Channel, &statement.Destination,
+		&statement.PeriodStart, &statement.PeriodEnd, &statement.OpeningBalance, &statement.ClosingBalance,
+		&statement.Status, &statement.Attempts, &statement.LastError, &deliveredAt, &statement.CreatedAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	if deliveredAt.Valid {
+		statement.DeliveredAt = &deliveredAt.Time
+	}
+
+	return statement, nil
+}
@@ -0,0 +1,137 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatements(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE statements, statement_subscriptions;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	_, err := repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "statement_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(10),
+	}, "statements-1")
+	require.NoError(t, err)
+
+	start := time.Now().UTC()
+	end := start.Add(time.Hour)
+
+	_, err = repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: api.CompanyAccountID,
+		ToAccountID:   "statement_user",
+		Currency:      "USD",
+		Amount:        decimal.NewFromInt(5),
+	}, "statements-2")
+	require.NoError(t, err)
+
+	t.Run("Subscribe", func(t *testing.T) {
+		_, err := repo.SubscribeStatements(ctx, &api.SubscribeStatementsRequest{
+			AccountID: "statement_user", Currency: "USD", Channel: api.StatementWebhook, Destination: "not a url",
+		})
+		require.ErrorIs(t, err, api.ErrInvalidStatementChannel)
+
+		subscription, err := repo.SubscribeStatements(ctx, &api.SubscribeStatementsRequest{
+			AccountID: "statement_user", Currency: "USD", Channel: api.StatementEmail, Destination: "jane@example.com",
+		})
+		require.NoError(t, err)
+
+		subscriptions, err := repo.GetStatementSubscriptions(ctx, "statement_user")
+		require.NoError(t, err)
+		require.Equal(t, []*api.StatementSubscription{subscription}, subscriptions)
+	})
+
+	t.Run("Schedule once", func(t *testing.T) {
+		scheduled, err := repo.ScheduleStatements(ctx, start, end)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, scheduled)
+
+		scheduled, err = repo.ScheduleStatements(ctx, start, end)
+		require.NoError(t, err)
+		require.Zero(t, scheduled, "the period is only scheduled once")
+	})
+
+	t.Run("Deliver", func(t *testing.T) {
+		due, err := repo.DueStatements(ctx, end, 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		require.True(t, decimal.NewFromInt(10).Equal(due[0].OpeningBalance))
+		require.True(t, decimal.NewFromInt(15).Equal(due[0].ClosingBalance))
+		require.Len(t, due[0].Transactions, 1)
+
+		retryAt := end.Add(time.Minute)
+		require.NoError(t, repo.MarkStatementFailed(ctx, due[0].ID, "mailbox full", &retryAt))
+
+		due, err = repo.DueStatements(ctx, end, 10)
+		require.NoError(t, err)
+		require.Empty(t, due, "waiting for the retry")
+
+		due, err = repo.DueStatements(ctx, retryAt, 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		require.Equal(t, 1, due[0].Attempts)
+		require.NoError(t, repo.MarkStatementDelivered(ctx, due[0].ID))
+
+		statements, err := repo.GetStatements(ctx, "statement_user", 10)
+		require.NoError(t, err)
+		require.Len(t, statements, 1)
+		require.Equal(t, api.StatementDelivered, statements[0].Status)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		download := &recordedStatement{}
+		require.NoError(t, repo.DownloadStatement(ctx, "statement_user", "usd", start, end, download))
+		require.True(t, download.ended)
+		require.True(t, decimal.NewFromInt(10).Equal(download.statement.OpeningBalance))
+		require.True(t, decimal.NewFromInt(15).Equal(download.statement.ClosingBalance))
+		require.Len(t, download.transactions, 1)
+		require.True(t, decimal.NewFromInt(15).Equal(download.transactions[0].RunningBalance))
+
+		err := repo.DownloadStatement(ctx, "statement_user", "USD", end, start, &recordedStatement{})
+		require.ErrorIs(t, err, api.ErrInvalidStatementPeriod)
+	})
+}
+
+// recordedStatement keeps the downloaded statement.
+type recordedStatement struct {
+	statement    *api.Statement
+	transactions []*api.Transaction
+	ended        bool
+}
+
+func (s *recordedStatement) Begin(statement *api.Statement) error {
+	s.statement = statement
+
+	return nil
+}
+
+func (s *recordedStatement) Entry(transaction *api.Transaction) error {
+	s.transactions = append(s.transactions, transaction)
+
+	return nil
+}
+
+func (s *recordedStatement) End(*api.Statement) error {
+	s.ended = true
+
+	return nil
+}
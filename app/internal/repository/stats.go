@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// aggregated over the index of the ledger entries by account, without reading them
+const selectAccountStats = `SELECT accounts.balance, COUNT(transactions.id), MIN(transactions.created_at), MAX(transactions.created_at)
+		FROM accounts
+		LEFT JOIN transactions ON transactions.account_id = accounts.id
+		WHERE accounts.user_id = $1 AND accounts.currency = $2
+		GROUP BY accounts.id`
+
+// GetAccountStats returns the balance of the account with the number of its ledger entries in the currency,
+// and the times of the first and the last of them. The company account has no entries before its first transfer.
+func (r *PostgresRepository) GetAccountStats(ctx context.Context, currency, accountID string) (*api.Account, error) {
+	account := &api.Account{
+		Currency:  strings.ToUpper(strings.TrimSpace(currency)),
+		AccountID: strings.TrimSpace(accountID),
+	}
+
+	if err := validateCurrencyAndAccount(currency, accountID); err != nil {
+		return nil, err
+	}
+
+	var (
+		count       int64
+		first, last sql.NullTime
+	)
+
+	err := r.db.QueryRowContext(ctx, selectAccountStats, account.AccountID, account.Currency).Scan(&account.Balance, &count, &first, &last)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && r.isCompanyAccount(account.AccountID):
+		account.Balance = decimal.Zero
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to get account stats for %s: %w", account.AccountID, api.ErrAccountNotFound)
+	case err != nil:
+		return nil, formatUnknownError(err)
+	}
+
+	account.TransactionCount = &count
+
+	if first.Valid {
+		firstActivityAt := first.Time.UTC()
+		account.FirstActivityAt = &firstActivityAt
+	}
+
+	if last.Valid {
+		lastActivityAt := last.Time.UTC()
+		account.LastActivityAt = &lastActivityAt
+	}
+
+	return account, nil
+}
@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountStats(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+	clk := wallettesting.NewFakeClock(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	repo := repository.NewPostgresRepository(db).WithClock(clk)
+
+	for _, idempotencyKey := range []string{"stats-1", "stats-2", "stats-3"} {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "stats_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, idempotencyKey)
+		require.NoError(t, err)
+
+		clk.Advance(time.Hour)
+	}
+
+	account, err := repo.GetAccountStats(ctx, "usd", " stats_user ")
+	require.NoError(t, err)
+	require.Equal(t, "stats_user", account.AccountID)
+	require.True(t, account.Balance.Equal(decimal.NewFromInt(30)))
+	require.Equal(t, int64(3), *account.TransactionCount)
+	require.Equal(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), *account.FirstActivityAt)
+	require.Equal(t, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), *account.LastActivityAt)
+
+	_, err = repo.GetAccountStats(ctx, "EUR", "stats_user")
+	require.ErrorIs(t, err, api.ErrAccountNotFound)
+
+	account, err = repo.GetAccountStats(ctx, "JPY", api.CompanyAccountID)
+	require.NoError(t, err, "before its first transfer")
+	require.Zero(t, *account.TransactionCount)
+	require.Nil(t, account.FirstActivityAt)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "github.com/devshark/wallet/app/internal/tagging"
+
+// WithTaxonomy restricts the tags of the transfers to the taxonomy, any well-formed tag is allowed otherwise.
+func (r *PostgresRepository) WithTaxonomy(taxonomy tagging.Taxonomy) *PostgresRepository {
+	r.taxonomy = taxonomy
+
+	return r
+}
+
+// tagsOf returns the tags, never nil since the tags columns aren't nullable.
+func tagsOf(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
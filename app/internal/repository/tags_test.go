@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/tagging"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionTags(t *testing.T) {
+	db := setupTestDB(t)
+
+	setCleanUp(t, db)
+
+	ctx := context.Background()
+
+	repo := repository.NewPostgresRepository(db).
+		WithTaxonomy(tagging.New([]string{"food:groceries", "travel", "payroll"}))
+
+	for i, tags := range [][]string{{"payroll"}, {"Travel", "food:groceries", "travel"}, {"travel"}} {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "tagged_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(int64(10 * (i + 1))),
+			Tags:          tags,
+		}, fmt.Sprintf("tags-%d", i))
+		require.NoError(t, err)
+	}
+
+	t.Run("Stored normalized", func(t *testing.T) {
+		transactions, err := repo.GetTransactions(ctx, "USD", "tagged_user")
+		require.NoError(t, err)
+		require.Len(t, transactions, 3)
+		require.Equal(t, []string{"food:groceries", "travel"}, transactions[1].Tags)
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		transactions, err := repo.FilterTransactions(ctx, "USD", "tagged_user", api.TransactionFilter{Tags: []string{"travel"}})
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+
+		transactions, err = repo.FilterTransactions(ctx, "USD", "tagged_user", api.TransactionFilter{Tags: []string{"travel", "food:groceries"}})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1, "the transactions have all of the tags")
+
+		transactions, err = repo.FilterTransactions(ctx, "USD", api.CompanyAccountID, api.TransactionFilter{Tags: []string{"payroll"}})
+		require.NoError(t, err)
+		require.Len(t, transactions, 1, "both entries of the transfer are tagged")
+	})
+
+	t.Run("Outside of the taxonomy", func(t *testing.T) {
+		_, err := repo.Transfer(ctx, &api.TransferRequest{
+			FromAccountID: api.CompanyAccountID,
+			ToAccountID:   "tagged_user",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(1),
+			Tags:          []string{"gambling"},
+		}, "tags-gambling")
+		require.ErrorIs(t, err, api.ErrInvalidTag)
+
+		_, err = repo.FilterTransactions(ctx, "USD", "tagged_user", api.TransactionFilter{Tags: []string{"not a tag"}})
+		require.ErrorIs(t, err, api.ErrInvalidTag)
+	})
+}
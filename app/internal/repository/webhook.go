@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/webhook"
+)
+
+const (
+	// an existing subscription is kept, and returned by the select
+	insertWebhook = `INSERT INTO webhook_subscriptions (id, url, account_id, currency, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ON CONSTRAINT unique_webhook_subscription DO NOTHING`
+
+	selectWebhook = `SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE url = $1 AND account_id = $2 AND currency = $3`
+
+	// every subscription without an account filter
+	selectWebhooks = `SELECT ` + webhookColumns + `
+		FROM webhook_subscriptions
+		WHERE $1 = '' OR account_id = $1
+		ORDER BY created_at, id`
+
+	webhookColumns = `id, url, account_id, currency, created_at,
+			COALESCE(secret, ''), COALESCE(previous_secret, ''), previous_secret_expires_at`
+
+	deleteWebhook = `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	// the secret being replaced is the previous one, NULL for the secret of the server
+	rotateWebhookSecret = `UPDATE webhook_subscriptions
+		SET previous_secret = secret, secret = $2, previous_secret_expires_at = $3
+		WHERE id = $1`
+
+	insertWebhookRotation = `INSERT INTO webhook_secret_rotations (subscription_id, operator, rotated_at, previous_expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+
+	selectWebhookExists = `SELECT count(1) FROM webhook_subscriptions WHERE id = $1`
+
+	selectWebhookRotations = `SELECT id, subscription_id, operator, rotated_at, previous_expires_at
+		FROM webhook_secret_rotations
+		WHERE subscription_id = $1
+		ORDER BY id`
+)
+
+// SubscribeWebhook subscribes the url to the ledger events, of every account or only those of the account in the request.
+// Subscribing again with the same scope is a no-op.
+func (r *PostgresRepository) SubscribeWebhook(ctx context.Context, request *api.SubscribeWebhookRequest) (*api.WebhookSubscription, error) {
+	accountID := strings.TrimSpace(request.AccountID)
+	if len(accountID) > 255 {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(request.Currency))
+	if len(currency) > 10 {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	destination := strings.TrimSpace(request.URL)
+
+	parsed, err := url.Parse(destination)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || len(destination) > 2048 {
+		return nil, api.ErrInvalidWebhook
+	}
+
+	destination = parsed.String()
+
+	_, err = r.db.ExecContext(ctx, insertWebhook, r.idGenerator.NewID(), destination, accountID, currency, r.clock.Now())
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	subscription, err := scanWebhook(r.db.QueryRowContext(ctx, selectWebhook, destination, accountID, currency))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscription, nil
+}
+
+// GetWebhookSubscriptions returns the webhook subscriptions of the account, or all of them without an account,
+// the oldest first.
+func (r *PostgresRepository) GetWebhookSubscriptions(ctx context.Context, accountID string) ([]*api.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, selectWebhooks, strings.TrimSpace(accountID))
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	subscriptions := []*api.WebhookSubscription{}
+
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription unsubscribes, the events not delivered yet are no longer posted to it.
+func (r *PostgresRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	if !validID(id) {
+		return api.ErrWebhookNotFound
+	}
+
+	result, err := r.db.ExecContext(ctx, deleteWebhook, id)
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return formatUnknownError(err)
+	}
+
+	if deleted == 0 {
+		return api.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// RotateWebhookSecret replaces the signing secret of the subscription with a new one, returned once.
+// The previous secret keeps signing the deliveries for the grace period of the request, and the rotation is recorded
+// with the operator. Rotating again within the grace period replaces the previous secret.
+func (r *PostgresRepository) RotateWebhookSecret(ctx context.Context, id string, request *api.RotateWebhookSecretRequest, operator string) (*api.WebhookSecretRotation, error) {
+	gracePeriod := api.DefaultWebhookGracePeriod
+
+	if request.GracePeriodSeconds != nil {
+		gracePeriod = time.Duration(*request.GracePeriodSeconds) * time.Second
+
+		if *request.GracePeriodSeconds < 0 || gracePeriod > api.MaxWebhookGracePeriod {
+			return nil, api.ErrInvalidGracePeriod
+		}
+	}
+
+	if !validID(id) {
+		return nil, api.ErrWebhookNotFound
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already wrapped
+	}
+
+	rotation := &api.WebhookSecretRotation{
+		SubscriptionID: id,
+		Secret:         secret,
+		Operator:       operator,
+		RotatedAt:      r.clock.Now().UTC(),
+	}
+
+	// without a grace period, the previous secret is revoked right away
+	if gracePeriod > 0 {
+		expiresAt := rotation.RotatedAt.Add(gracePeriod)
+		rotation.PreviousExpiresAt = &expiresAt
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, rotateWebhookSecret, id, secret, rotation.PreviousExpiresAt)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	rotated, err := result.RowsAffected()
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if rotated == 0 {
+		return nil, api.ErrWebhookNotFound
+	}
+
+	err = tx.QueryRowContext(ctx, insertWebhookRotation, id, operator, rotation.RotatedAt, rotation.PreviousExpiresAt).Scan(&rotation.ID)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return rotation, nil
+}
+
+// GetWebhookSecretRotations returns the rotations of the secret of the subscription, the oldest first, without the secrets.
+func (r *PostgresRepository) GetWebhookSecretRotations(ctx context.Context, id string) ([]*api.WebhookSecretRotation, error) {
+	if !validID(id) {
+		return nil, api.ErrWebhookNotFound
+	}
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, selectWebhookExists, id).Scan(&count); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	if count == 0 {
+		return nil, api.ErrWebhookNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectWebhookRotations, id)
+	if err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	defer rows.Close()
+
+	rotations := []*api.WebhookSecretRotation{}
+
+	for rows.Next() {
+		var (
+			rotation  api.WebhookSecretRotation
+			expiresAt sql.NullTime
+		)
+
+		if err = rows.Scan(&rotation.ID, &rotation.SubscriptionID, &rotation.Operator, &rotation.RotatedAt, &expiresAt); err != nil {
+			return nil, formatUnknownError(err)
+		}
+
+		rotation.RotatedAt = rotation.RotatedAt.UTC()
+
+		if expiresAt.Valid {
+			previousExpiresAt := expiresAt.Time.UTC()
+			rotation.PreviousExpiresAt = &previousExpiresAt
+		}
+
+		rotations = append(rotations, &rotation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, formatUnknownError(err)
+	}
+
+	return rotations, nil
+}
+
+func scanWebhook(row rowScanner) (*api.WebhookSubscription, error) {
+	var (
+		subscription = &api.WebhookSubscription{}
+		expiresAt    sql.NullTime
+	)
+
+	err := row.Scan(&subscription.ID, &subscription.URL, &subscription.AccountID, &subscription.Currency, &subscription.CreatedAt,
+		&subscription.Secret, &subscription.PreviousSecret, &expiresAt)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the callers, which check for sql.ErrNoRows
+	}
+
+	subscription.CreatedAt = subscription.CreatedAt.UTC()
+
+	if expiresAt.Valid {
+		previousExpiresAt := expiresAt.Time.UTC()
+		subscription.PreviousSecretExpiresAt = &previousExpiresAt
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,132 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/devshark/wallet/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	db := setupTestDB(t)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE webhook_subscriptions CASCADE;")
+		require.NoError(t, err)
+	})
+
+	repo := repository.NewPostgresRepository(db)
+
+	var global, scoped *api.WebhookSubscription
+
+	t.Run("Subscribe", func(t *testing.T) {
+		_, err := repo.SubscribeWebhook(ctx, &api.SubscribeWebhookRequest{URL: "ftp://example.com"})
+		require.ErrorIs(t, err, api.ErrInvalidWebhook)
+
+		global, err = repo.SubscribeWebhook(ctx, &api.SubscribeWebhookRequest{URL: "https://example.com/events"})
+		require.NoError(t, err)
+		require.Empty(t, global.AccountID)
+
+		scoped, err = repo.SubscribeWebhook(ctx, &api.SubscribeWebhookRequest{
+			URL: " https://merchant.example.com/events ", AccountID: "merchant", Currency: "usd",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "merchant", scoped.AccountID)
+		require.Equal(t, "USD", scoped.Currency)
+
+		again, err := repo.SubscribeWebhook(ctx, &api.SubscribeWebhookRequest{
+			URL: "https://merchant.example.com/events", AccountID: "merchant", Currency: "USD",
+		})
+		require.NoError(t, err)
+		require.Equal(t, scoped.ID, again.ID, "a no-op")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		subscriptions, err := repo.GetWebhookSubscriptions(ctx, "")
+		require.NoError(t, err)
+		require.Equal(t, []*api.WebhookSubscription{global, scoped}, subscriptions)
+
+		subscriptions, err = repo.GetWebhookSubscriptions(ctx, "merchant")
+		require.NoError(t, err)
+		require.Equal(t, []*api.WebhookSubscription{scoped}, subscriptions)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repo.DeleteWebhookSubscription(ctx, scoped.ID))
+		require.ErrorIs(t, repo.DeleteWebhookSubscription(ctx, scoped.ID), api.ErrWebhookNotFound)
+		require.ErrorIs(t, repo.DeleteWebhookSubscription(ctx, "unknown"), api.ErrWebhookNotFound)
+	})
+}
+
+func TestWebhookSecretRotation(t *testing.T) {
+	db := setupTestDB(t)
+
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, "TRUNCATE TABLE webhook_subscriptions CASCADE;")
+		require.NoError(t, err)
+	})
+
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := repository.NewPostgresRepository(db).WithClock(wallettesting.NewFakeClock(now))
+
+	subscription, err := repo.SubscribeWebhook(ctx, &api.SubscribeWebhookRequest{URL: "https://partner.example.com/events"})
+	require.NoError(t, err)
+	require.Nil(t, subscription.SigningSecrets(now), "signed with the secret of the server")
+
+	first, err := repo.RotateWebhookSecret(ctx, subscription.ID, &api.RotateWebhookSecretRequest{}, "ops")
+	require.NoError(t, err)
+	require.True(t, len(first.Secret) > len(webhook.SecretPrefix))
+	require.Equal(t, now.Add(api.DefaultWebhookGracePeriod), *first.PreviousExpiresAt)
+
+	subscriptions, err := repo.GetWebhookSubscriptions(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, [][]byte{[]byte(first.Secret), nil}, subscriptions[0].SigningSecrets(now), "and the secret of the server for the grace period")
+	require.Equal(t, [][]byte{[]byte(first.Secret)}, subscriptions[0].SigningSecrets(now.Add(api.DefaultWebhookGracePeriod)))
+
+	hour := int64(time.Hour.Seconds())
+
+	second, err := repo.RotateWebhookSecret(ctx, subscription.ID, &api.RotateWebhookSecretRequest{GracePeriodSeconds: &hour}, "ops")
+	require.NoError(t, err)
+	require.NotEqual(t, first.Secret, second.Secret)
+
+	subscriptions, err = repo.GetWebhookSubscriptions(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte(second.Secret), []byte(first.Secret)}, subscriptions[0].SigningSecrets(now))
+
+	revoke := int64(0)
+
+	third, err := repo.RotateWebhookSecret(ctx, subscription.ID, &api.RotateWebhookSecretRequest{GracePeriodSeconds: &revoke}, "security")
+	require.NoError(t, err)
+	require.Nil(t, third.PreviousExpiresAt)
+
+	subscriptions, err = repo.GetWebhookSubscriptions(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte(third.Secret)}, subscriptions[0].SigningSecrets(now), "the leaked secret is revoked right away")
+
+	tooLong := int64((api.MaxWebhookGracePeriod + time.Second).Seconds())
+
+	_, err = repo.RotateWebhookSecret(ctx, subscription.ID, &api.RotateWebhookSecretRequest{GracePeriodSeconds: &tooLong}, "ops")
+	require.ErrorIs(t, err, api.ErrInvalidGracePeriod)
+
+	_, err = repo.RotateWebhookSecret(ctx, "00000000-0000-4000-8000-000000000000", &api.RotateWebhookSecretRequest{}, "ops")
+	require.ErrorIs(t, err, api.ErrWebhookNotFound)
+
+	rotations, err := repo.GetWebhookSecretRotations(ctx, subscription.ID)
+	require.NoError(t, err)
+	require.Len(t, rotations, 3)
+	require.Equal(t, "security", rotations[2].Operator)
+	require.Empty(t, rotations[0].Secret, "the secrets aren't kept in the audit trail")
+
+	_, err = repo.GetWebhookSecretRotations(ctx, "00000000-0000-4000-8000-000000000000")
+	require.ErrorIs(t, err, api.ErrWebhookNotFound)
+}
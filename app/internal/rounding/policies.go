@@ -0,0 +1,109 @@
+// Package rounding applies the rounding policies of the currencies to the amounts,
+// so the fees, the conversions and the responses round the same way.
+package rounding
+
+import (
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/shopspring/decimal"
+)
+
+// AnyCurrency is the key of the policy applied to the currencies without their own.
+const AnyCurrency = "*"
+
+// Policies are the rounding policies by currency. The currencies without a policy aren't rounded.
+type Policies struct {
+	policies map[string]api.RoundingPolicy
+}
+
+// New keys the policies by currency, case-insensitively, AnyCurrency being the fallback.
+func New(policies map[string]api.RoundingPolicy) Policies {
+	byCurrency := make(map[string]api.RoundingPolicy, len(policies))
+
+	for currency, policy := range policies {
+		byCurrency[strings.ToUpper(currency)] = policy
+	}
+
+	return Policies{
+		policies: byCurrency,
+	}
+}
+
+// None returns the policies rounding nothing.
+func None() Policies {
+	return New(nil)
+}
+
+// For returns the policy of the currency, false if its amounts aren't rounded.
+func (p Policies) For(currency string) (api.RoundingPolicy, bool) {
+	if policy, ok := p.policies[strings.ToUpper(currency)]; ok {
+		return policy, true
+	}
+
+	policy, ok := p.policies[AnyCurrency]
+
+	return policy, ok
+}
+
+// Round rounds the amount with the policy of the currency, or returns it as is.
+func (p Policies) Round(currency string, amount decimal.Decimal) decimal.Decimal {
+	if policy, ok := p.For(currency); ok {
+		return policy.Round(amount)
+	}
+
+	return amount
+}
+
+// Account rounds the balances of the account and its sub-accounts, and sets the policy applied.
+func (p Policies) Account(account *api.Account) {
+	policy, ok := p.For(account.Currency)
+	if !ok {
+		return
+	}
+
+	account.Balance = policy.Round(account.Balance)
+	account.Rounding = &policy
+
+	if account.TotalBalance != nil {
+		total := policy.Round(*account.TotalBalance)
+		account.TotalBalance = &total
+	}
+
+	if account.Held != nil {
+		held := policy.Round(*account.Held)
+		account.Held = &held
+	}
+
+	for _, child := range account.Children {
+		p.Account(child)
+	}
+}
+
+// Transactions rounds the amounts and the running balances of the transactions, and sets the policy applied.
+func (p Policies) Transactions(transactions ...*api.Transaction) {
+	for _, transaction := range transactions {
+		policy, ok := p.For(transaction.Currency)
+		if !ok {
+			continue
+		}
+
+		transaction.Amount = policy.Round(transaction.Amount)
+		transaction.RunningBalance = policy.Round(transaction.RunningBalance)
+		transaction.Rounding = &policy
+	}
+}
+
+// Activities rounds the amounts and the running balances of the activities, and sets the policy applied.
+func (p Policies) Activities(activities ...*api.AccountActivity) {
+	for _, activity := range activities {
+		policy, ok := p.For(activity.Currency)
+		if !ok {
+			continue
+		}
+
+		activity.Amount = policy.Round(activity.Amount)
+		activity.RunningBalance = policy.Round(activity.RunningBalance)
+		activity.Rounding = &policy
+	}
+}
@@ -0,0 +1,74 @@
+package rounding_test
+
+import (
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicies(t *testing.T) {
+	policies := rounding.New(map[string]api.RoundingPolicy{
+		"usd":                {Mode: api.RoundHalfEven, Scale: 2},
+		"JPY":                {Mode: api.RoundHalfUp, Scale: 0},
+		rounding.AnyCurrency: {Mode: api.RoundHalfUp, Scale: 4},
+	})
+
+	for _, tc := range []struct {
+		currency string
+		amount   string
+		expected string
+	}{
+		{"USD", "10.125", "10.12"},
+		{"USD", "10.135", "10.14"},
+		{"USD", "-10.125", "-10.12"},
+		{"JPY", "100.5", "101"},
+		{"JPY", "-100.5", "-101"},
+		{"EUR", "1.00005", "1.0001"},
+	} {
+		t.Run(tc.currency+" "+tc.amount, func(t *testing.T) {
+			rounded := policies.Round(tc.currency, decimal.RequireFromString(tc.amount))
+			require.Equal(t, tc.expected, rounded.String())
+		})
+	}
+
+	t.Run("Responses", func(t *testing.T) {
+		total := decimal.RequireFromString("3.335")
+		account := &api.Account{
+			AccountID:    "merchant1",
+			Currency:     "USD",
+			Balance:      decimal.RequireFromString("1.005"),
+			TotalBalance: &total,
+			Children:     []*api.Account{{AccountID: "merchant1:eu", Currency: "USD", Balance: decimal.RequireFromString("2.33")}},
+		}
+
+		policies.Account(account)
+		require.Equal(t, "1", account.Balance.String())
+		require.Equal(t, "3.34", account.TotalBalance.String())
+		require.Equal(t, &api.RoundingPolicy{Mode: api.RoundHalfEven, Scale: 2}, account.Children[0].Rounding)
+
+		transaction := &api.Transaction{Currency: "JPY", Amount: decimal.RequireFromString("99.5"), RunningBalance: decimal.RequireFromString("0.4")}
+
+		policies.Transactions(transaction)
+		require.Equal(t, "100", transaction.Amount.String())
+		require.Equal(t, "0", transaction.RunningBalance.String())
+		require.Equal(t, api.RoundHalfUp, transaction.Rounding.Mode)
+
+		activity := &api.AccountActivity{Currency: "USD", Amount: decimal.RequireFromString("0.125"), RunningBalance: decimal.RequireFromString("7.335")}
+
+		policies.Activities(activity)
+		require.Equal(t, "0.12", activity.Amount.String())
+		require.Equal(t, "7.34", activity.RunningBalance.String())
+		require.Equal(t, api.RoundHalfEven, activity.Rounding.Mode)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		transaction := &api.Transaction{Currency: "USD", Amount: decimal.RequireFromString("1.005")}
+
+		rounding.None().Transactions(transaction)
+		require.Equal(t, "1.005", transaction.Amount.String())
+		require.Nil(t, transaction.Rounding)
+	})
+}
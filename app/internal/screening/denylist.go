@@ -0,0 +1,68 @@
+// Package screening provides the default screening of the transfers, against a static denylist.
+package screening
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// Denylist flags the transfers from or to a listed account, or whose remarks contain a listed term.
+// The accounts and the terms are matched case-insensitively.
+type Denylist struct {
+	accounts map[string]struct{}
+	terms    []string
+}
+
+// NewDenylist lists the account ids and the remarks terms to flag, ignoring the blanks.
+func NewDenylist(accountIDs, terms []string) *Denylist {
+	denylist := &Denylist{
+		accounts: make(map[string]struct{}, len(accountIDs)),
+		terms:    make([]string, 0, len(terms)),
+	}
+
+	for _, accountID := range accountIDs {
+		if accountID = strings.TrimSpace(accountID); accountID != "" {
+			denylist.accounts[strings.ToLower(accountID)] = struct{}{}
+		}
+	}
+
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			denylist.terms = append(denylist.terms, strings.ToLower(term))
+		}
+	}
+
+	return denylist
+}
+
+// Empty reports whether the denylist can't flag any transfer.
+func (d *Denylist) Empty() bool {
+	return len(d.accounts) == 0 && len(d.terms) == 0
+}
+
+func (d *Denylist) Screen(_ context.Context, request *api.TransferRequest) (api.ScreeningResult, error) {
+	for _, accountID := range []string{request.FromAccountID, request.ToAccountID} {
+		if _, listed := d.accounts[strings.ToLower(accountID)]; listed {
+			return api.ScreeningResult{
+				Flagged: true,
+				Reason:  fmt.Sprintf("denylisted account %s", accountID),
+			}, nil
+		}
+	}
+
+	remarks := strings.ToLower(request.Remarks)
+
+	for _, term := range d.terms {
+		if strings.Contains(remarks, term) {
+			return api.ScreeningResult{
+				Flagged: true,
+				Reason:  fmt.Sprintf("denylisted term %q in the remarks", term),
+			}, nil
+		}
+	}
+
+	return api.ScreeningResult{}, nil
+}
@@ -0,0 +1,40 @@
+package screening_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/screening"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenylist(t *testing.T) {
+	ctx := context.Background()
+
+	denylist := screening.NewDenylist([]string{" Sanctioned1 ", ""}, []string{"Crimea", " "})
+	require.False(t, denylist.Empty())
+
+	for name, request := range map[string]*api.TransferRequest{
+		"Sender":    {FromAccountID: "sanctioned1", ToAccountID: "user1"},
+		"Recipient": {FromAccountID: "user1", ToAccountID: "SANCTIONED1"},
+		"Remarks":   {FromAccountID: "user1", ToAccountID: "user2", Remarks: "invoice for CRIMEA shipment"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := denylist.Screen(ctx, request)
+			require.NoError(t, err)
+			require.True(t, result.Flagged)
+			require.NotEmpty(t, result.Reason)
+		})
+	}
+
+	t.Run("Clear", func(t *testing.T) {
+		result, err := denylist.Screen(ctx, &api.TransferRequest{FromAccountID: "user1", ToAccountID: "sanctioned10", Remarks: "rent"})
+		require.NoError(t, err)
+		require.Equal(t, api.ScreeningResult{}, result)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		require.True(t, screening.NewDenylist(nil, []string{""}).Empty())
+	})
+}
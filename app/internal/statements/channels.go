@@ -0,0 +1,119 @@
+package statements
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/webhook"
+)
+
+// Webhook posts the statements as JSON to their destination URL, signed with the webhook secret.
+type Webhook struct {
+	sender *webhook.Sender
+}
+
+func NewWebhook(sender *webhook.Sender) *Webhook {
+	return &Webhook{sender: sender}
+}
+
+func (w *Webhook) Deliver(ctx context.Context, statement *api.Statement) error {
+	return w.sender.Post(ctx, statement.Destination, statement) //nolint:wrapcheck // the sender's errors name the destination
+}
+
+//nolint:gochecknoglobals // stateless
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+// SendMailFunc is the signature of smtp.SendMail.
+type SendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// Email mails the statements as plain text to their destination address through the SMTP server.
+type Email struct {
+	address  string
+	from     string
+	auth     smtp.Auth
+	sendMail SendMailFunc
+}
+
+// NewEmail sends through the SMTP server at address, i.e. smtp.example.com:587, from the sender address.
+// The connection is upgraded to TLS when the server supports it.
+func NewEmail(address, from string) *Email {
+	return &Email{
+		address:  address,
+		from:     from,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// WithAuth authenticates to the SMTP server, which smtp.PlainAuth only allows over TLS or to localhost.
+func (e *Email) WithAuth(username, password string) *Email {
+	host, _, _ := strings.Cut(e.address, ":")
+	e.auth = smtp.PlainAuth("", username, password, host)
+
+	return e
+}
+
+// WithSendMail overrides how the messages are sent, i.e. in the tests.
+func (e *Email) WithSendMail(sendMail SendMailFunc) *Email {
+	e.sendMail = sendMail
+
+	return e
+}
+
+func (e *Email) Deliver(_ context.Context, statement *api.Statement) error {
+	err := e.sendMail(e.address, e.auth, e.from, []string{statement.Destination}, e.message(statement))
+	if err != nil {
+		return fmt.Errorf("failed to mail the statement: %w", err)
+	}
+
+	return nil
+}
+
+// message renders the statement as a plain text email.
+func (e *Email) message(statement *api.Statement) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "From: %s\r\n", e.from)
+	fmt.Fprintf(buf, "To: %s\r\n", statement.Destination)
+	// the account ids are free-form, they can't inject headers
+	fmt.Fprintf(buf, "Subject: Statement of %s in %s for %s\r\n", headerValue.Replace(statement.AccountID), statement.Currency, statement.PeriodStart.Format("January 2006"))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(buf, "\r\n")
+
+	fmt.Fprintf(buf, "Account: %s\r\n", statement.AccountID)
+	fmt.Fprintf(buf, "Currency: %s\r\n", statement.Currency)
+	fmt.Fprintf(buf, "Period: %s to %s\r\n", statement.PeriodStart.Format("2006-01-02"), statement.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+	fmt.Fprintf(buf, "Opening balance: %s\r\n\r\n", statement.OpeningBalance)
+
+	table := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Date\tType\tAmount\tBalance\tCounterparty\tRemarks\r\n")
+
+	for _, transaction := range statement.Transactions {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\r\n", transaction.Time.UTC().Format(time.DateTime), transaction.Type, transaction.Amount,
+			transaction.RunningBalance, counterparty(transaction), transaction.Remarks)
+	}
+
+	_ = table.Flush()
+
+	fmt.Fprintf(buf, "\r\nClosing balance: %s\r\n", statement.ClosingBalance)
+
+	return buf.Bytes()
+}
+
+// counterparty is where the money of the entry came from, or went to.
+func counterparty(transaction *api.Transaction) string {
+	switch {
+	case transaction.Counterparty == "":
+		return ""
+	case transaction.Type == api.CREDIT:
+		return "from " + transaction.Counterparty
+	default:
+		return "to " + transaction.Counterparty
+	}
+}
@@ -0,0 +1,273 @@
+package statements
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// the PDF pages are A4 portrait, in points
+	pageWidth  = 595
+	pageHeight = 842
+	pageMargin = 40
+	// the text is set in Courier, whose glyphs are 0.6 em wide, so the columns align
+	fontSize     = 9
+	lineHeight   = 11
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+	lineLength   = (pageWidth - 2*pageMargin) * 10 / (fontSize * 6)
+)
+
+//nolint:gochecknoglobals // constant
+var csvHeader = []string{"time", "tx_id", "type", "amount", "running_balance", "counterparty", "reference", "remarks"}
+
+// NewWriter renders a downloaded statement in the format to w, as its entries are read.
+// Only the current page of a PDF is held in memory.
+func NewWriter(format api.StatementFormat, w io.Writer) (api.StatementWriter, error) {
+	switch format {
+	case api.StatementCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case api.StatementPDF:
+		return &pdfWriter{w: &countingWriter{w: w}, offsets: map[int]int64{}, next: pdfFirstPage}, nil
+	default:
+		return nil, api.ErrInvalidStatementFormat
+	}
+}
+
+// ContentType returns the media type of the format.
+func ContentType(format api.StatementFormat) string {
+	if format == api.StatementPDF {
+		return "application/pdf"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// csvWriter writes a header row, then a row per entry.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Begin(*api.Statement) error {
+	return c.write(csvHeader)
+}
+
+func (c *csvWriter) Entry(transaction *api.Transaction) error {
+	return c.write([]string{
+		transaction.Time.UTC().Format(time.RFC3339),
+		transaction.TxID,
+		string(transaction.Type),
+		transaction.Amount.String(),
+		transaction.RunningBalance.String(),
+		transaction.Counterparty,
+		transaction.Reference,
+		transaction.Remarks,
+	})
+}
+
+func (c *csvWriter) End(*api.Statement) error {
+	c.w.Flush()
+
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	return nil
+}
+
+func (c *csvWriter) write(record []string) error {
+	if err := c.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	return nil
+}
+
+// the objects of the PDF known upfront, the pages tree is written last, once its pages are known
+const (
+	pdfCatalog = iota + 1
+	pdfPages
+	pdfFont
+	pdfFirstPage
+)
+
+// pdfWriter writes a page of text lines at a time, and the cross-reference table of the objects at the end.
+type pdfWriter struct {
+	w *countingWriter
+	// the byte offsets of the objects written, by object number
+	offsets map[int]int64
+	next    int
+	pages   []int
+	lines   []string
+}
+
+func (p *pdfWriter) Begin(statement *api.Statement) error {
+	if _, err := io.WriteString(p.w, "%PDF-1.4\n"); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	if err := p.object(pdfCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages)); err != nil {
+		return err
+	}
+
+	if err := p.object(pdfFont, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>"); err != nil {
+		return err
+	}
+
+	return p.print(
+		fmt.Sprintf("Statement of %s in %s", statement.AccountID, statement.Currency),
+		fmt.Sprintf("From %s to %s", statement.PeriodStart.UTC().Format(time.RFC3339), statement.PeriodEnd.UTC().Format(time.RFC3339)),
+		"Opening balance: "+statement.OpeningBalance.String(),
+		"",
+		fmt.Sprintf("%-20s %-6s %16s %16s  %s", "Time", "Type", "Amount", "Balance", "Counterparty / Remarks"),
+	)
+}
+
+func (p *pdfWriter) Entry(transaction *api.Transaction) error {
+	details := transaction.Counterparty
+	if transaction.Remarks != "" {
+		details = strings.TrimPrefix(details+" / "+transaction.Remarks, " / ")
+	}
+
+	return p.print(fmt.Sprintf("%-20s %-6s %16s %16s  %s", transaction.Time.UTC().Format(time.RFC3339),
+		transaction.Type, transaction.Amount.String(), transaction.RunningBalance.String(), details))
+}
+
+func (p *pdfWriter) End(statement *api.Statement) error {
+	if err := p.print("", "Closing balance: "+statement.ClosingBalance.String()); err != nil {
+		return err
+	}
+
+	if err := p.flushPage(); err != nil {
+		return err
+	}
+
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+
+	if err := p.object(pdfPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages))); err != nil {
+		return err
+	}
+
+	xref := p.w.written
+
+	var trailer strings.Builder
+
+	fmt.Fprintf(&trailer, "xref\n0 %d\n0000000000 65535 f \n", p.next)
+
+	for id := 1; id < p.next; id++ {
+		fmt.Fprintf(&trailer, "%010d 00000 n \n", p.offsets[id])
+	}
+
+	fmt.Fprintf(&trailer, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.next, pdfCatalog, xref)
+
+	if _, err := io.WriteString(p.w, trailer.String()); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	return nil
+}
+
+// print adds the lines to the current page, writing it once full.
+func (p *pdfWriter) print(lines ...string) error {
+	for _, line := range lines {
+		if len(p.lines) == linesPerPage {
+			if err := p.flushPage(); err != nil {
+				return err
+			}
+		}
+
+		p.lines = append(p.lines, pdfText(line))
+	}
+
+	return nil
+}
+
+// flushPage writes the content stream and the page object of the current page.
+func (p *pdfWriter) flushPage() error {
+	var content strings.Builder
+
+	fmt.Fprintf(&content, "BT /F1 %d Tf %d TL %d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin-fontSize)
+
+	for _, line := range p.lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+	}
+
+	content.WriteString("ET")
+
+	contentID, pageID := p.next, p.next+1
+	p.next += 2
+
+	err := p.object(contentID, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	if err != nil {
+		return err
+	}
+
+	err = p.object(pageID, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPages, pageWidth, pageHeight, pdfFont, contentID))
+	if err != nil {
+		return err
+	}
+
+	p.pages = append(p.pages, pageID)
+	p.lines = p.lines[:0]
+
+	return nil
+}
+
+func (p *pdfWriter) object(id int, body string) error {
+	p.offsets[id] = p.w.written
+
+	if _, err := fmt.Fprintf(p.w, "%d 0 obj\n%s\nendobj\n", id, body); err != nil {
+		return fmt.Errorf("failed to write statement: %w", err)
+	}
+
+	return nil
+}
+
+// pdfText escapes the line for a PDF string, truncated to the width of the page.
+// Courier only has the Latin glyphs, so the others are replaced.
+func pdfText(line string) string {
+	var text strings.Builder
+
+	length := 0
+
+	for _, r := range line {
+		if length == lineLength {
+			break
+		}
+
+		length++
+
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			text.WriteRune('\\')
+			text.WriteRune(r)
+		case r < ' ' || r > '~':
+			text.WriteRune('?')
+		default:
+			text.WriteRune(r)
+		}
+	}
+
+	return text.String()
+}
+
+// countingWriter counts the bytes written, for the offsets of the PDF objects.
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.written += int64(n)
+
+	return n, err //nolint:wrapcheck // wrapped by the callers
+}
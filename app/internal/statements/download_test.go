@@ -0,0 +1,105 @@
+package statements_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// writeStatement renders the statement with its transactions.
+func writeStatement(t *testing.T, format api.StatementFormat, statement *api.Statement) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	writer, err := statements.NewWriter(format, &out)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Begin(statement))
+
+	for _, transaction := range statement.Transactions {
+		require.NoError(t, writer.Entry(transaction))
+	}
+
+	require.NoError(t, writer.End(statement))
+
+	return out.Bytes()
+}
+
+func TestWriter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	statement := &api.Statement{
+		AccountID:      "alice",
+		Currency:       "USD",
+		PeriodStart:    start,
+		PeriodEnd:      start.AddDate(0, 1, 0),
+		OpeningBalance: decimal.NewFromInt(100),
+		ClosingBalance: decimal.NewFromInt(70),
+	}
+
+	for i := range 100 {
+		statement.Transactions = append(statement.Transactions, &api.Transaction{
+			TxID:           fmt.Sprintf("tx%d", i),
+			Type:           api.DEBIT,
+			Amount:         decimal.NewFromFloat(0.3),
+			RunningBalance: decimal.NewFromInt(100).Sub(decimal.NewFromFloat(0.3).Mul(decimal.NewFromInt(int64(i + 1)))),
+			Counterparty:   "bob",
+			Remarks:        "lunch (shared), café",
+			Time:           start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		records, err := csv.NewReader(bytes.NewReader(writeStatement(t, api.StatementCSV, statement))).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 101)
+		require.Equal(t, "running_balance", records[0][4])
+		require.Equal(t, []string{"2024-01-01T00:00:00Z", "tx0", "DEBIT", "0.3", "99.7", "bob", "", "lunch (shared), café"}, records[1])
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		document := writeStatement(t, api.StatementPDF, statement)
+
+		require.True(t, bytes.HasPrefix(document, []byte("%PDF-1.4\n")))
+		require.True(t, bytes.HasSuffix(document, []byte("%%EOF\n")))
+		require.Contains(t, string(document), "/Count 2", "the entries overflow the first page")
+		require.Contains(t, string(document), `bob / lunch \(shared\), caf?`)
+		require.Contains(t, string(document), "Closing balance: 70")
+
+		// every object is at its offset in the cross-reference table
+		startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(document)
+		require.NotNil(t, startxref)
+
+		offset, err := strconv.Atoi(string(startxref[1]))
+		require.NoError(t, err)
+
+		entries := strings.Split(string(document[offset:]), "\n")
+		require.Equal(t, "xref", entries[0])
+
+		for id, entry := range entries[3:] {
+			if strings.HasPrefix(entry, "trailer") {
+				break
+			}
+
+			position, err := strconv.Atoi(entry[:10])
+			require.NoError(t, err)
+			require.True(t, bytes.HasPrefix(document[position:], []byte(fmt.Sprintf("%d 0 obj\n", id+1))))
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		_, err := statements.NewWriter("xlsx", &bytes.Buffer{})
+		require.ErrorIs(t, err, api.ErrInvalidStatementFormat)
+	})
+}
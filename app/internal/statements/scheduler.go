@@ -0,0 +1,176 @@
+// Package statements generates the monthly statements of the subscribed accounts, and delivers them
+// through their channel, retrying the failed deliveries with a backoff.
+package statements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/clock"
+)
+
+var ErrChannelNotConfigured = errors.New("statement channel not configured")
+
+const (
+	defaultBatchSize   = 100
+	DefaultMaxAttempts = 5
+
+	// the retries back off from a minute, doubling up to a day
+	initialBackoff = time.Minute
+	maxBackoff     = 24 * time.Hour
+)
+
+// Store is implemented by repository.PostgresRepository.
+type Store interface {
+	ScheduleStatements(ctx context.Context, start, end time.Time) (int64, error)
+	DueStatements(ctx context.Context, at time.Time, limit int) ([]*api.Statement, error)
+	MarkStatementDelivered(ctx context.Context, id string) error
+	MarkStatementFailed(ctx context.Context, id, reason string, retryAt *time.Time) error
+}
+
+// Channel delivers the statements to their destination.
+type Channel interface {
+	Deliver(ctx context.Context, statement *api.Statement) error
+}
+
+// Scheduler generates the statements of the previous month, then delivers the due ones.
+type Scheduler struct {
+	store       Store
+	channels    map[api.StatementChannel]Channel
+	batchSize   int
+	maxAttempts int
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+func NewScheduler(store Store) *Scheduler {
+	return &Scheduler{
+		store:       store,
+		channels:    map[api.StatementChannel]Channel{},
+		batchSize:   defaultBatchSize,
+		maxAttempts: DefaultMaxAttempts,
+		clock:       clock.NewSystemClock(),
+		logger:      slog.Default(),
+	}
+}
+
+// WithChannel delivers the statements of the kind through the channel.
+// The statements of a kind without a channel fail, as the subscription can't be honored.
+func (s *Scheduler) WithChannel(kind api.StatementChannel, channel Channel) *Scheduler {
+	s.channels[kind] = channel
+
+	return s
+}
+
+// WithMaxAttempts sets the number of delivery attempts before a statement fails for good.
+func (s *Scheduler) WithMaxAttempts(attempts int) *Scheduler {
+	s.maxAttempts = attempts
+
+	return s
+}
+
+// WithClock overrides the clock deciding the period and the retries.
+func (s *Scheduler) WithClock(c clock.Clock) *Scheduler {
+	s.clock = c
+
+	return s
+}
+
+func (s *Scheduler) WithLogger(logger *slog.Logger) *Scheduler {
+	s.logger = logger
+
+	return s
+}
+
+// Run generates the statements of the previous month, if not already, then attempts the due deliveries.
+// A failed delivery is retried by a later run, the other statements are still delivered.
+func (s *Scheduler) Run(ctx context.Context) error {
+	now := s.clock.Now()
+	start, end := PreviousMonth(now)
+
+	scheduled, err := s.store.ScheduleStatements(ctx, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to schedule statements: %w", err)
+	}
+
+	if scheduled > 0 {
+		s.logger.InfoContext(ctx, "statements scheduled", slog.Int64("count", scheduled), slog.Time("period_start", start))
+	}
+
+	due, err := s.store.DueStatements(ctx, now, s.batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to get due statements: %w", err)
+	}
+
+	for _, statement := range due {
+		if err = s.deliver(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deliver attempts the delivery of the statement, and records the outcome.
+// It only fails if the outcome can't be recorded.
+func (s *Scheduler) deliver(ctx context.Context, statement *api.Statement) error {
+	logger := s.logger.With(slog.String("statement_id", statement.ID), slog.String("channel", string(statement.Channel)))
+
+	deliveryErr := ErrChannelNotConfigured
+	if channel, ok := s.channels[statement.Channel]; ok {
+		deliveryErr = channel.Deliver(ctx, statement)
+	}
+
+	if deliveryErr == nil {
+		if err := s.store.MarkStatementDelivered(ctx, statement.ID); err != nil {
+			return fmt.Errorf("failed to mark statement %s delivered: %w", statement.ID, err)
+		}
+
+		logger.InfoContext(ctx, "statement delivered")
+
+		return nil
+	}
+
+	attempts := statement.Attempts + 1
+
+	var retryAt *time.Time
+	if attempts < s.maxAttempts {
+		next := s.clock.Now().Add(Backoff(attempts))
+		retryAt = &next
+	}
+
+	if err := s.store.MarkStatementFailed(ctx, statement.ID, deliveryErr.Error(), retryAt); err != nil {
+		return fmt.Errorf("failed to mark statement %s failed: %w", statement.ID, err)
+	}
+
+	logger.WarnContext(ctx, "statement delivery failed",
+		slog.Any("error", deliveryErr),
+		slog.Int("attempts", attempts),
+		slog.Bool("retried", retryAt != nil),
+	)
+
+	return nil
+}
+
+// PreviousMonth returns the calendar month before the time, in UTC, from its first day included to the next month excluded.
+func PreviousMonth(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return end.AddDate(0, -1, 0), end
+}
+
+// Backoff returns the wait before the next attempt after the failed attempts.
+func Backoff(attempts int) time.Duration {
+	backoff := initialBackoff
+
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxBackoff)
+}
@@ -0,0 +1,153 @@
+package statements_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/statements"
+	"github.com/devshark/wallet/pkg/logging"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/devshark/wallet/pkg/webhook"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps the outcomes of the deliveries.
+type memoryStore struct {
+	periods   [][2]time.Time
+	due       []*api.Statement
+	delivered []string
+	failed    map[string]*time.Time
+}
+
+func (s *memoryStore) ScheduleStatements(_ context.Context, start, end time.Time) (int64, error) {
+	s.periods = append(s.periods, [2]time.Time{start, end})
+
+	return int64(len(s.due)), nil
+}
+
+func (s *memoryStore) DueStatements(context.Context, time.Time, int) ([]*api.Statement, error) {
+	return s.due, nil
+}
+
+func (s *memoryStore) MarkStatementDelivered(_ context.Context, id string) error {
+	s.delivered = append(s.delivered, id)
+
+	return nil
+}
+
+func (s *memoryStore) MarkStatementFailed(_ context.Context, id, _ string, retryAt *time.Time) error {
+	s.failed[id] = retryAt
+
+	return nil
+}
+
+type channelFunc func(ctx context.Context, statement *api.Statement) error
+
+func (f channelFunc) Deliver(ctx context.Context, statement *api.Statement) error {
+	return f(ctx, statement)
+}
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2024, 7, 3, 10, 0, 0, 0, time.UTC)
+
+	store := &memoryStore{
+		due: []*api.Statement{
+			{ID: "delivered", Channel: api.StatementWebhook},
+			{ID: "retried", Channel: api.StatementEmail, Attempts: 1},
+			{ID: "exhausted", Channel: api.StatementEmail, Attempts: 4},
+			{ID: "unconfigured", Channel: "SMS"},
+		},
+		failed: map[string]*time.Time{},
+	}
+
+	scheduler := statements.NewScheduler(store).
+		WithChannel(api.StatementWebhook, channelFunc(func(context.Context, *api.Statement) error { return nil })).
+		WithChannel(api.StatementEmail, channelFunc(func(context.Context, *api.Statement) error { return errors.New("mailbox full") })).
+		WithMaxAttempts(5).
+		WithClock(wallettesting.NewFakeClock(now)).
+		WithLogger(logging.Discard())
+
+	require.NoError(t, scheduler.Run(context.Background()))
+
+	require.Equal(t, [][2]time.Time{{
+		time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+	}}, store.periods, "the previous month is scheduled")
+
+	require.Equal(t, []string{"delivered"}, store.delivered)
+
+	require.NotNil(t, store.failed["retried"])
+	require.Equal(t, now.Add(2*time.Minute), *store.failed["retried"], "the second attempt backs off twice as long")
+	require.Nil(t, store.failed["exhausted"], "failed for good")
+	require.Contains(t, store.failed, "unconfigured")
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, time.Minute, statements.Backoff(1))
+	require.Equal(t, 4*time.Minute, statements.Backoff(3))
+	require.Equal(t, 24*time.Hour, statements.Backoff(20))
+}
+
+func TestChannels(t *testing.T) {
+	statement := &api.Statement{
+		ID:             "statement1",
+		AccountID:      "user1\r\nBcc: spam@example.com",
+		Currency:       "USD",
+		PeriodStart:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:      time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: decimal.NewFromInt(10),
+		ClosingBalance: decimal.NewFromInt(15),
+		Transactions: []*api.Transaction{
+			{TxID: "tx1", Type: api.CREDIT, Amount: decimal.NewFromInt(5), RunningBalance: decimal.NewFromInt(15), Remarks: "salary", Counterparty: "employer"},
+		},
+	}
+
+	t.Run("Webhook", func(t *testing.T) {
+		secret := []byte("whsec")
+		received := make(chan error, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, time.Now(), time.Minute)
+		}))
+		t.Cleanup(server.Close)
+
+		statement := *statement
+		statement.Destination = server.URL
+
+		require.NoError(t, statements.NewWebhook(webhook.NewSender(secret)).Deliver(context.Background(), &statement))
+		require.NoError(t, <-received)
+	})
+
+	t.Run("Email", func(t *testing.T) {
+		var message []byte
+
+		email := statements.NewEmail("smtp.example.com:587", "statements@example.com").
+			WithSendMail(func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+				require.Equal(t, "smtp.example.com:587", addr)
+				require.Equal(t, "statements@example.com", from)
+				require.Equal(t, []string{"jane@example.com"}, to)
+				message = msg
+
+				return nil
+			})
+
+		statement := *statement
+		statement.Destination = "jane@example.com"
+
+		require.NoError(t, email.Deliver(context.Background(), &statement))
+		require.Contains(t, string(message), "Subject: Statement of user1Bcc: spam@example.com in USD for June 2024\r\n")
+		require.Contains(t, string(message), "Period: 2024-06-01 to 2024-06-30")
+		require.Contains(t, string(message), "salary")
+		require.Contains(t, string(message), "from employer")
+		require.Contains(t, string(message), "Closing balance: 15")
+	})
+}
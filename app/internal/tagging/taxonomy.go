@@ -0,0 +1,85 @@
+// Package tagging validates the tags of the transfers against the configured taxonomy.
+package tagging
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// MaxTags is the maximum number of tags of a transfer.
+const MaxTags = 10
+
+//nolint:gochecknoglobals // compiled once
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,63}$`)
+
+// Taxonomy is the set of the allowed tags. The empty taxonomy allows any well-formed tag,
+// i.e. lowercase letters, digits and . _ : - up to 64 characters, where : separates the levels of a category like food:groceries.
+type Taxonomy struct {
+	tags map[string]struct{}
+}
+
+// New lists the allowed tags, case-insensitively, ignoring the blanks.
+func New(tags []string) Taxonomy {
+	taxonomy := Taxonomy{
+		tags: make(map[string]struct{}, len(tags)),
+	}
+
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			taxonomy.tags[tag] = struct{}{}
+		}
+	}
+
+	return taxonomy
+}
+
+// Any returns the taxonomy allowing any well-formed tag.
+func Any() Taxonomy {
+	return New(nil)
+}
+
+// Tags returns the allowed tags sorted, empty if any well-formed tag is allowed.
+func (t Taxonomy) Tags() []string {
+	tags := make([]string, 0, len(t.tags))
+
+	for tag := range t.tags {
+		tags = append(tags, tag)
+	}
+
+	slices.Sort(tags)
+
+	return tags
+}
+
+// Normalize lowercases, sorts and deduplicates the tags, and returns api.ErrInvalidTag
+// if one of them is malformed or outside of the taxonomy, or if there are more than MaxTags.
+func (t Taxonomy) Normalize(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%w: %q", api.ErrInvalidTag, tag)
+		}
+
+		if _, ok := t.tags[tag]; len(t.tags) > 0 && !ok {
+			return nil, fmt.Errorf("%w: %s is not in the taxonomy", api.ErrInvalidTag, tag)
+		}
+
+		normalized = append(normalized, tag)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags", api.ErrInvalidTag, MaxTags)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,51 @@
+package tagging_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/tagging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaxonomy(t *testing.T) {
+	taxonomy := tagging.New([]string{" Food:Groceries ", "travel", "rent", ""})
+	require.Equal(t, []string{"food:groceries", "rent", "travel"}, taxonomy.Tags())
+
+	t.Run("Normalize", func(t *testing.T) {
+		tags, err := taxonomy.Normalize([]string{"Travel", " rent", "travel"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rent", "travel"}, tags)
+
+		tags, err = taxonomy.Normalize(nil)
+		require.NoError(t, err)
+		require.Empty(t, tags)
+	})
+
+	t.Run("Outside of the taxonomy", func(t *testing.T) {
+		_, err := taxonomy.Normalize([]string{"travel", "gambling"})
+		require.ErrorIs(t, err, api.ErrInvalidTag)
+	})
+
+	t.Run("Any", func(t *testing.T) {
+		tags, err := tagging.Any().Normalize([]string{"Gambling", "food:groceries"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"food:groceries", "gambling"}, tags)
+
+		for _, tag := range []string{"", "-food", "food groceries", "food/groceries"} {
+			_, err = tagging.Any().Normalize([]string{tag})
+			require.ErrorIs(t, err, api.ErrInvalidTag, tag)
+		}
+	})
+
+	t.Run("Too many", func(t *testing.T) {
+		tags := make([]string, 0, tagging.MaxTags+1)
+		for i := range tagging.MaxTags + 1 {
+			tags = append(tags, fmt.Sprintf("tag%d", i))
+		}
+
+		_, err := tagging.Any().Normalize(tags)
+		require.ErrorIs(t, err, api.ErrInvalidTag)
+	})
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+const defaultActivityBatchSize = 100
+
+// ActivityProjector is implemented by repository.PostgresRepository.
+type ActivityProjector interface {
+	ProjectActivity(ctx context.Context, limit int) (int, error)
+}
+
+// ActivityProjection keeps the activity feeds of the accounts up to date with the outbox events.
+type ActivityProjection struct {
+	projector ActivityProjector
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewActivityProjection(projector ActivityProjector) *ActivityProjection {
+	return &ActivityProjection{
+		projector: projector,
+		batchSize: defaultActivityBatchSize,
+		logger:    slog.Default(),
+	}
+}
+
+func (p *ActivityProjection) WithLogger(logger *slog.Logger) *ActivityProjection {
+	p.logger = logger
+
+	return p
+}
+
+// Run projects the pending events in batches, until there is none left.
+func (p *ActivityProjection) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		projected, err := p.projector.ProjectActivity(ctx, p.batchSize)
+		total += projected
+
+		if err != nil {
+			return fmt.Errorf("failed to project activity: %w", err)
+		}
+
+		if projected < p.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		p.logger.DebugContext(ctx, "activity projected", slog.Int("events", total))
+	}
+
+	return nil
+}
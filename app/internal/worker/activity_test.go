@@ -0,0 +1,46 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type projectorFunc func(ctx context.Context, limit int) (int, error)
+
+func (f projectorFunc) ProjectActivity(ctx context.Context, limit int) (int, error) {
+	return f(ctx, limit)
+}
+
+func TestActivityProjection(t *testing.T) {
+	t.Run("Until drained", func(t *testing.T) {
+		pending := 250
+		calls := 0
+
+		projection := worker.NewActivityProjection(projectorFunc(func(_ context.Context, limit int) (int, error) {
+			calls++
+			projected := min(limit, pending)
+			pending -= projected
+
+			return projected, nil
+		})).WithLogger(logging.Discard())
+
+		require.NoError(t, projection.Run(context.Background()))
+		require.Zero(t, pending)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Projector failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+
+		projection := worker.NewActivityProjection(projectorFunc(func(context.Context, int) (int, error) {
+			return 0, errDB
+		}))
+
+		require.ErrorIs(t, projection.Run(context.Background()), errDB)
+	})
+}
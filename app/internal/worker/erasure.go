@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/devshark/wallet/api"
+)
+
+const defaultErasureBatchSize = 10
+
+// ErasureProcessor is implemented by repository.PostgresRepository.
+type ErasureProcessor interface {
+	ProcessErasures(ctx context.Context, limit int) ([]*api.ErasureRequest, error)
+}
+
+// CachePurge deletes the cached responses of the accounts, i.e. rest.InvalidateTransactions, and returns how many were deleted.
+type CachePurge func(ctx context.Context, accountIDs ...string) (int64, error)
+
+// Erasures removes the personal data of the accounts whose erasure was requested.
+// Their remarks are already unreadable, their data keys being deleted by the request.
+type Erasures struct {
+	processor ErasureProcessor
+	batchSize int
+	logger    *slog.Logger
+	purge     CachePurge
+}
+
+func NewErasures(processor ErasureProcessor) *Erasures {
+	return &Erasures{
+		processor: processor,
+		batchSize: defaultErasureBatchSize,
+		logger:    slog.Default(),
+	}
+}
+
+func (e *Erasures) WithLogger(logger *slog.Logger) *Erasures {
+	e.logger = logger
+
+	return e
+}
+
+// WithCachePurge purges the cached responses of the accounts once erased, which still carry their plain remarks.
+func (e *Erasures) WithCachePurge(purge CachePurge) *Erasures {
+	e.purge = purge
+
+	return e
+}
+
+// Run completes the pending erasures in batches, until there is none left.
+func (e *Erasures) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		completed, err := e.processor.ProcessErasures(ctx, e.batchSize)
+		total += len(completed)
+
+		e.purgeCache(ctx, completed)
+
+		if err != nil {
+			return fmt.Errorf("failed to process erasures: %w", err)
+		}
+
+		if len(completed) < e.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		e.logger.InfoContext(ctx, "erasures completed", slog.Int("count", total))
+	}
+
+	return nil
+}
+
+// purgeCache purges the cached responses of the erased accounts. The erasures are completed whether it fails or not,
+// the responses left expire with the cache.
+func (e *Erasures) purgeCache(ctx context.Context, completed []*api.ErasureRequest) {
+	if e.purge == nil || len(completed) == 0 {
+		return
+	}
+
+	accountIDs := make([]string, 0, len(completed))
+	for _, erasure := range completed {
+		accountIDs = append(accountIDs, erasure.AccountID)
+	}
+
+	deleted, err := e.purge(ctx, accountIDs...)
+	if err != nil {
+		e.logger.ErrorContext(ctx, "failed to purge the cache of the erased accounts", slog.Any("account_ids", accountIDs),
+			slog.Any("error", err))
+
+		return
+	}
+
+	e.logger.InfoContext(ctx, "cache of the erased accounts purged", slog.Int("accounts", len(accountIDs)), slog.Int64("deleted", deleted))
+}
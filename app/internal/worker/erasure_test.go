@@ -0,0 +1,86 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type erasureFunc func(ctx context.Context, limit int) ([]*api.ErasureRequest, error)
+
+func (f erasureFunc) ProcessErasures(ctx context.Context, limit int) ([]*api.ErasureRequest, error) {
+	return f(ctx, limit)
+}
+
+// erasedAccounts completes the erasures of user0, user1... in batches.
+func erasedAccounts(pending int, calls *int) erasureFunc {
+	erased := 0
+
+	return func(_ context.Context, limit int) ([]*api.ErasureRequest, error) {
+		*calls++
+
+		completed := []*api.ErasureRequest{}
+		for ; erased < pending && len(completed) < limit; erased++ {
+			completed = append(completed, &api.ErasureRequest{AccountID: fmt.Sprintf("user%d", erased), Status: api.ErasureCompleted})
+		}
+
+		return completed, nil
+	}
+}
+
+func TestErasures(t *testing.T) {
+	t.Run("Until drained", func(t *testing.T) {
+		calls := 0
+
+		erasures := worker.NewErasures(erasedAccounts(25, &calls)).WithLogger(logging.Discard())
+
+		require.NoError(t, erasures.Run(context.Background()))
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Cache purged", func(t *testing.T) {
+		calls := 0
+		purged := []string{}
+
+		erasures := worker.NewErasures(erasedAccounts(12, &calls)).
+			WithLogger(logging.Discard()).
+			WithCachePurge(func(_ context.Context, accountIDs ...string) (int64, error) {
+				purged = append(purged, accountIDs...)
+
+				return int64(len(accountIDs)), nil
+			})
+
+		require.NoError(t, erasures.Run(context.Background()))
+		require.Len(t, purged, 12)
+		require.Equal(t, "user0", purged[0])
+		require.Equal(t, "user11", purged[11])
+	})
+
+	t.Run("Cache purge failure", func(t *testing.T) {
+		calls := 0
+
+		erasures := worker.NewErasures(erasedAccounts(1, &calls)).
+			WithLogger(logging.Discard()).
+			WithCachePurge(func(context.Context, ...string) (int64, error) {
+				return 0, errors.New("redis down")
+			})
+
+		require.NoError(t, erasures.Run(context.Background()), "the erasures are completed, the cache expires")
+	})
+
+	t.Run("Processor failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+
+		erasures := worker.NewErasures(erasureFunc(func(context.Context, int) ([]*api.ErasureRequest, error) {
+			return nil, errDB
+		}))
+
+		require.ErrorIs(t, erasures.Run(context.Background()), errDB)
+	})
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/devshark/wallet/api"
+)
+
+var ErrLedgerDiscrepancy = errors.New("ledger discrepancy")
+
+// LedgerVerifier is implemented by repository.PostgresRepository.
+type LedgerVerifier interface {
+	VerifyLedger(ctx context.Context) ([]*api.LedgerDiscrepancy, error)
+}
+
+// LedgerCheck reconciles the account balances against their ledger entries.
+// The discrepancies are only reported, fixing them requires a human decision.
+type LedgerCheck struct {
+	verifier LedgerVerifier
+	logger   *slog.Logger
+}
+
+func NewLedgerCheck(verifier LedgerVerifier) *LedgerCheck {
+	return &LedgerCheck{
+		verifier: verifier,
+		logger:   slog.Default(),
+	}
+}
+
+func (c *LedgerCheck) WithLogger(logger *slog.Logger) *LedgerCheck {
+	c.logger = logger
+
+	return c
+}
+
+// Run verifies the ledger once, logging every discrepancy found.
+func (c *LedgerCheck) Run(ctx context.Context) error {
+	discrepancies, err := c.verifier.VerifyLedger(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify ledger: %w", err)
+	}
+
+	for _, d := range discrepancies {
+		c.logger.ErrorContext(ctx, "ledger discrepancy",
+			slog.String("account_id", d.AccountID),
+			slog.String("currency", d.Currency),
+			slog.String("balance", d.Balance.String()),
+			slog.String("ledger_balance", d.LedgerBalance.String()),
+		)
+	}
+
+	if len(discrepancies) > 0 {
+		return fmt.Errorf("%w: %d accounts", ErrLedgerDiscrepancy, len(discrepancies))
+	}
+
+	c.logger.DebugContext(ctx, "ledger verified")
+
+	return nil
+}
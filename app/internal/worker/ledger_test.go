@@ -0,0 +1,58 @@
+package worker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+type verifierFunc func(ctx context.Context) ([]*api.LedgerDiscrepancy, error)
+
+func (f verifierFunc) VerifyLedger(ctx context.Context) ([]*api.LedgerDiscrepancy, error) {
+	return f(ctx)
+}
+
+func TestLedgerCheck(t *testing.T) {
+	t.Run("Consistent ledger", func(t *testing.T) {
+		check := worker.NewLedgerCheck(verifierFunc(func(context.Context) ([]*api.LedgerDiscrepancy, error) {
+			return []*api.LedgerDiscrepancy{}, nil
+		}))
+
+		require.NoError(t, check.Run(context.Background()))
+	})
+
+	t.Run("Discrepancies are logged", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		check := worker.NewLedgerCheck(verifierFunc(func(context.Context) ([]*api.LedgerDiscrepancy, error) {
+			return []*api.LedgerDiscrepancy{{
+				AccountID:     "user1",
+				Currency:      "USD",
+				Balance:       decimal.NewFromInt(101),
+				LedgerBalance: decimal.NewFromInt(100),
+			}}, nil
+		})).WithLogger(slog.New(slog.NewTextHandler(buf, nil)))
+
+		err := check.Run(context.Background())
+		require.ErrorIs(t, err, worker.ErrLedgerDiscrepancy)
+		require.Contains(t, buf.String(), "account_id=user1")
+		require.Contains(t, buf.String(), "ledger_balance=100")
+	})
+
+	t.Run("Verifier failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+
+		check := worker.NewLedgerCheck(verifierFunc(func(context.Context) ([]*api.LedgerDiscrepancy, error) {
+			return nil, errDB
+		}))
+
+		require.ErrorIs(t, check.Run(context.Background()), errDB)
+	})
+}
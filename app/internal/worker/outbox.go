@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/events"
+)
+
+const (
+	defaultOutboxBatchSize = 100
+	defaultOutboxRetention = 7 * 24 * time.Hour
+)
+
+// OutboxStore is implemented by repository.PostgresRepository.
+type OutboxStore interface {
+	RelayEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []api.Event) error) (int, error)
+	PurgePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxRelay publishes the events written to the outbox by the ledger transactions.
+// An event is only marked published once the broker has acknowledged it, so the consumers
+// may receive an event twice but never miss one, and must deduplicate by the event id.
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher events.Publisher
+	batchSize int
+	retention time.Duration
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+func NewOutboxRelay(store OutboxStore, publisher events.Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		store:     store,
+		publisher: publisher,
+		batchSize: defaultOutboxBatchSize,
+		retention: defaultOutboxRetention,
+		clock:     clock.NewSystemClock(),
+		logger:    slog.Default(),
+	}
+}
+
+// WithBatchSize sets the maximum number of events published at once.
+func (r *OutboxRelay) WithBatchSize(size int) *OutboxRelay {
+	r.batchSize = size
+
+	return r
+}
+
+// WithRetention sets how long the published events are kept, i.e. to replay them. Zero keeps them forever.
+func (r *OutboxRelay) WithRetention(retention time.Duration) *OutboxRelay {
+	r.retention = retention
+
+	return r
+}
+
+// WithClock overrides the clock used to expire the published events.
+func (r *OutboxRelay) WithClock(c clock.Clock) *OutboxRelay {
+	r.clock = c
+
+	return r
+}
+
+func (r *OutboxRelay) WithLogger(logger *slog.Logger) *OutboxRelay {
+	r.logger = logger
+
+	return r
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, batch []api.Event) error {
+	return r.publisher.Publish(ctx, batch...)
+}
+
+// Run publishes the pending events in batches until the outbox is drained, then purges the expired ones.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		published, err := r.store.RelayEvents(ctx, r.batchSize, r.publish)
+		if err != nil {
+			return fmt.Errorf("failed to relay events after %d published: %w", total, err)
+		}
+
+		total += published
+
+		// a partial batch means the outbox is drained, the rest is left for the next run
+		if published < r.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		r.logger.DebugContext(ctx, "events published", slog.Int("count", total))
+	}
+
+	if r.retention <= 0 {
+		return nil
+	}
+
+	purged, err := r.store.PurgePublishedEvents(ctx, r.clock.Now().Add(-r.retention))
+	if err != nil {
+		return fmt.Errorf("failed to purge published events: %w", err)
+	}
+
+	if purged > 0 {
+		r.logger.DebugContext(ctx, "published events purged", slog.Int64("count", purged))
+	}
+
+	return nil
+}
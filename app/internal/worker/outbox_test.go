@@ -0,0 +1,113 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/worker"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox hands out its pending events in batches, like the repository.
+type fakeOutbox struct {
+	pending   []api.Event
+	published []api.Event
+	purgedAt  time.Time
+	purges    int
+}
+
+func (o *fakeOutbox) RelayEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []api.Event) error) (int, error) {
+	batch := o.pending[:min(limit, len(o.pending))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+
+	o.published = append(o.published, batch...)
+	o.pending = o.pending[len(batch):]
+
+	return len(batch), nil
+}
+
+func (o *fakeOutbox) PurgePublishedEvents(_ context.Context, before time.Time) (int64, error) {
+	o.purgedAt = before
+	o.purges++
+
+	return 0, nil
+}
+
+type fakePublisher struct {
+	batches [][]api.Event
+	err     error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, events ...api.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.batches = append(p.batches, events)
+
+	return nil
+}
+
+func (p *fakePublisher) Close() error {
+	return nil
+}
+
+func pendingEvents(n int) []api.Event {
+	events := make([]api.Event, n)
+	for i := range events {
+		events[i] = api.Event{ID: string(rune('a' + i)), Type: api.EventTransferCreated, Version: api.TransferCreatedVersion}
+	}
+
+	return events
+}
+
+func TestOutboxRelay(t *testing.T) {
+	now := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Drains the outbox in batches", func(t *testing.T) {
+		outbox := &fakeOutbox{pending: pendingEvents(5)}
+		publisher := &fakePublisher{}
+
+		relay := worker.NewOutboxRelay(outbox, publisher).
+			WithBatchSize(2).
+			WithRetention(time.Hour).
+			WithClock(wallettesting.NewFakeClock(now))
+
+		require.NoError(t, relay.Run(context.Background()))
+		require.Empty(t, outbox.pending)
+		require.Len(t, publisher.batches, 3)
+		require.Equal(t, "a", publisher.batches[0][0].ID)
+		require.Equal(t, "e", publisher.batches[2][0].ID)
+		require.Equal(t, now.Add(-time.Hour), outbox.purgedAt)
+	})
+
+	t.Run("Published events kept", func(t *testing.T) {
+		outbox := &fakeOutbox{}
+
+		relay := worker.NewOutboxRelay(outbox, &fakePublisher{}).WithRetention(0)
+
+		require.NoError(t, relay.Run(context.Background()))
+		require.Zero(t, outbox.purges)
+	})
+
+	t.Run("Broker failure leaves the events pending", func(t *testing.T) {
+		errBroker := errors.New("broker down")
+		outbox := &fakeOutbox{pending: pendingEvents(3)}
+
+		relay := worker.NewOutboxRelay(outbox, &fakePublisher{err: errBroker})
+
+		require.ErrorIs(t, relay.Run(context.Background()), errBroker)
+		require.Len(t, outbox.pending, 3)
+		require.Zero(t, outbox.purges)
+	})
+}
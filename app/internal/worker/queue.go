@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+const defaultTransferQueueBatchSize = 100
+
+// TransferQueueProcessor is implemented by repository.PostgresRepository.
+type TransferQueueProcessor interface {
+	ProcessQueuedTransfers(ctx context.Context, limit int) (int, error)
+}
+
+// TransferQueue posts the transfers queued by the producers that can't wait on the locks of the accounts.
+// The workers dequeue concurrently, each transfer being locked by the one posting it.
+type TransferQueue struct {
+	processor TransferQueueProcessor
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewTransferQueue(processor TransferQueueProcessor) *TransferQueue {
+	return &TransferQueue{
+		processor: processor,
+		batchSize: defaultTransferQueueBatchSize,
+		logger:    slog.Default(),
+	}
+}
+
+// WithBatchSize sets the maximum number of transfers dequeued at once, and locked until they're all posted.
+func (q *TransferQueue) WithBatchSize(size int) *TransferQueue {
+	q.batchSize = size
+
+	return q
+}
+
+func (q *TransferQueue) WithLogger(logger *slog.Logger) *TransferQueue {
+	q.logger = logger
+
+	return q
+}
+
+// Run posts the queued transfers in batches, until there is none left.
+func (q *TransferQueue) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		processed, err := q.processor.ProcessQueuedTransfers(ctx, q.batchSize)
+		total += processed
+
+		if err != nil {
+			return fmt.Errorf("failed to process queued transfers after %d processed: %w", total, err)
+		}
+
+		if processed < q.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		q.logger.DebugContext(ctx, "queued transfers processed", slog.Int("count", total))
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type transferQueueFunc func(ctx context.Context, limit int) (int, error)
+
+func (f transferQueueFunc) ProcessQueuedTransfers(ctx context.Context, limit int) (int, error) {
+	return f(ctx, limit)
+}
+
+func TestTransferQueue(t *testing.T) {
+	t.Run("Until drained", func(t *testing.T) {
+		queued := 25
+		calls := 0
+
+		queue := worker.NewTransferQueue(transferQueueFunc(func(_ context.Context, limit int) (int, error) {
+			calls++
+			processed := min(limit, queued)
+			queued -= processed
+
+			return processed, nil
+		})).WithBatchSize(10).WithLogger(logging.Discard())
+
+		require.NoError(t, queue.Run(context.Background()))
+		require.Zero(t, queued)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Processor failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+		calls := 0
+
+		queue := worker.NewTransferQueue(transferQueueFunc(func(_ context.Context, limit int) (int, error) {
+			calls++
+
+			// the transfers before the failing one are still processed
+			return limit - 1, errDB
+		})).WithBatchSize(10).WithLogger(logging.Discard())
+
+		err := queue.Run(context.Background())
+		require.ErrorIs(t, err, errDB)
+		require.ErrorContains(t, err, "after 9 processed")
+		require.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+const defaultScheduledTransfersBatchSize = 100
+
+// ScheduledTransfersProcessor is implemented by repository.PostgresRepository.
+type ScheduledTransfersProcessor interface {
+	ProcessScheduledTransfers(ctx context.Context, limit int) (int, error)
+}
+
+// ScheduledTransfers posts the scheduled transfers once they're due.
+// The workers execute them concurrently, each transfer being locked by the one posting it.
+type ScheduledTransfers struct {
+	processor ScheduledTransfersProcessor
+	batchSize int
+	logger    *slog.Logger
+}
+
+func NewScheduledTransfers(processor ScheduledTransfersProcessor) *ScheduledTransfers {
+	return &ScheduledTransfers{
+		processor: processor,
+		batchSize: defaultScheduledTransfersBatchSize,
+		logger:    slog.Default(),
+	}
+}
+
+// WithBatchSize sets the maximum number of due transfers executed at once, and locked until they're all posted.
+func (s *ScheduledTransfers) WithBatchSize(size int) *ScheduledTransfers {
+	s.batchSize = size
+
+	return s
+}
+
+func (s *ScheduledTransfers) WithLogger(logger *slog.Logger) *ScheduledTransfers {
+	s.logger = logger
+
+	return s
+}
+
+// Run posts the due transfers in batches, until there is none left.
+func (s *ScheduledTransfers) Run(ctx context.Context) error {
+	total := 0
+
+	for {
+		processed, err := s.processor.ProcessScheduledTransfers(ctx, s.batchSize)
+		total += processed
+
+		if err != nil {
+			return fmt.Errorf("failed to process scheduled transfers after %d processed: %w", total, err)
+		}
+
+		if processed < s.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.logger.InfoContext(ctx, "scheduled transfers executed", slog.Int("count", total))
+	}
+
+	return nil
+}
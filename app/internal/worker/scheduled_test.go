@@ -0,0 +1,48 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devshark/wallet/app/internal/worker"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type scheduledTransfersFunc func(ctx context.Context, limit int) (int, error)
+
+func (f scheduledTransfersFunc) ProcessScheduledTransfers(ctx context.Context, limit int) (int, error) {
+	return f(ctx, limit)
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	t.Run("Until none is due", func(t *testing.T) {
+		due := 12
+		calls := 0
+
+		scheduled := worker.NewScheduledTransfers(scheduledTransfersFunc(func(_ context.Context, limit int) (int, error) {
+			calls++
+			processed := min(limit, due)
+			due -= processed
+
+			return processed, nil
+		})).WithBatchSize(5).WithLogger(logging.Discard())
+
+		require.NoError(t, scheduled.Run(context.Background()))
+		require.Zero(t, due)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Processor failure", func(t *testing.T) {
+		errDB := errors.New("db down")
+
+		scheduled := worker.NewScheduledTransfers(scheduledTransfersFunc(func(_ context.Context, _ int) (int, error) {
+			return 2, errDB
+		})).WithBatchSize(5).WithLogger(logging.Discard())
+
+		err := scheduled.Run(context.Background())
+		require.ErrorIs(t, err, errDB)
+		require.ErrorContains(t, err, "after 2 processed")
+	})
+}
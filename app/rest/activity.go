@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/devshark/wallet/api"
+)
+
+// ActivityFeed is implemented by repository.PostgresRepository.
+type ActivityFeed interface {
+	GetAccountActivity(ctx context.Context, accountID, currency string, limit int) ([]*api.AccountActivity, error)
+}
+
+// WithActivityFeed serves the activity feeds of the accounts, projected from the outbox by the worker.
+func (r *APIServer) WithActivityFeed(feed ActivityFeed) *APIServer {
+	r.activity = feed
+
+	return r
+}
+
+func (r *APIServer) registerActivityEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.activity == nil {
+		return
+	}
+
+	// not cached, the projection keeps adding to the feeds
+	mux.HandleFunc("GET /account/{accountId}/{currency}/activity", handler.HandleGetAccountActivity)
+}
+
+// HandleGetAccountActivity responds with the latest activity of the account in the currency, the latest first.
+// The feed lags behind the ledger by the projection interval.
+func (h *Handlers) HandleGetAccountActivity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	accountID := r.PathValue("accountId")
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	activities, err := h.activity.GetAccountActivity(ctx, accountID, r.PathValue("currency"), limit)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidAccountID), errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to get account activity", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.rounding.Activities(activities...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(activities)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// HandleRegisterAlias maps the external identifier in the request to the account.
+func (h *Handlers) HandleRegisterAlias(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.PathValue("accountId"))
+
+	request := &api.RegisterAliasRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	if err = h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	alias, err := h.repo.RegisterAlias(ctx, accountID, request)
+
+	switch {
+	case errors.Is(err, api.ErrAliasTaken):
+		h.HandleError(w, http.StatusConflict, err)
+
+		return
+	case errors.Is(err, api.ErrInvalidAlias),
+		errors.Is(err, api.ErrCompanyAccount),
+		errors.Is(err, api.ErrInvalidAccountID):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to register alias", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(alias)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetAccountAliases lists the aliases registered to the account.
+func (h *Handlers) HandleGetAccountAliases(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.PathValue("accountId"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidAccountID)
+
+		return
+	}
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	aliases, err := h.repo.GetAccountAliases(ctx, accountID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get account aliases", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(aliases))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(aliases)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleResolveAlias returns the account the alias is registered to.
+func (h *Handlers) HandleResolveAlias(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	alias, err := h.repo.ResolveAlias(ctx, r.PathValue("alias"))
+	if errors.Is(err, api.ErrAliasNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to resolve alias", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(alias)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleDeleteAlias unregisters the alias.
+func (h *Handlers) HandleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.repo.DeleteAlias(ctx, r.PathValue("alias"))
+	if errors.Is(err, api.ErrAliasNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to delete alias", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resolveAccount sets the account id from the alias of the request, if any, and responds if it can't.
+func (h *Handlers) resolveAccount(w http.ResponseWriter, r *http.Request, accountID *string, alias string) bool {
+	ctx := r.Context()
+
+	err := h.resolveAlias(ctx, accountID, alias)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, api.ErrInvalidRequest):
+		h.HandleError(w, http.StatusBadRequest, err)
+	case errors.Is(err, api.ErrAliasNotFound):
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
+	default:
+		h.logger.ErrorContext(ctx, "failed to resolve alias", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrTransferFailed)
+	}
+
+	return false
+}
+
+// resolveAlias replaces the account id with the one of the alias, if any.
+// The request can't have both the account id and its alias.
+func (h *Handlers) resolveAlias(ctx context.Context, accountID *string, alias string) error {
+	if strings.TrimSpace(alias) == "" {
+		return nil
+	}
+
+	if strings.TrimSpace(*accountID) != "" {
+		return api.ErrInvalidRequest
+	}
+
+	resolved, err := h.repo.ResolveAlias(ctx, alias)
+	if err != nil {
+		return err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	*accountID = resolved.AccountID
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// PendingTransfers is implemented by repository.PostgresRepository.
+type PendingTransfers interface {
+	GetPendingTransfer(ctx context.Context, id string) (*api.PendingTransfer, error)
+	ListPendingTransfers(ctx context.Context, status api.ReviewStatus, limit int) ([]*api.PendingTransfer, error)
+	ApprovePendingTransfer(ctx context.Context, id, operator string) ([]*api.Transaction, error)
+	RejectPendingTransfer(ctx context.Context, id, operator string) error
+}
+
+// WithPendingTransfers serves the transfers held for their approval under /admin/transfers.
+// The operators are identified by their admin key, which is recorded with the decision.
+func (r *APIServer) WithPendingTransfers(auth middlewares.Middleware, pending PendingTransfers) *APIServer {
+	r.adminAuth = auth
+	r.pending = pending
+
+	return r
+}
+
+func (r *APIServer) registerApprovalEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.pending == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/transfers", r.adminAuth(handler.HandleListPendingTransfers))
+	mux.HandleFunc("GET /admin/transfers/{id}", r.adminAuth(handler.HandleGetPendingTransfer))
+	mux.HandleFunc("POST /admin/transfers/{id}/approve", r.adminAuth(handler.HandleApprovePendingTransfer))
+	mux.HandleFunc("POST /admin/transfers/{id}/reject", r.adminAuth(handler.HandleRejectPendingTransfer))
+}
+
+// HandleListPendingTransfers responds with the held transfers in the status parameter, the pending ones by default, the oldest first.
+func (h *Handlers) HandleListPendingTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := api.ReviewPending
+
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = api.ReviewStatus(strings.ToUpper(value))
+
+		if status != api.ReviewPending && status != api.ReviewApproved && status != api.ReviewRejected {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	transfers, err := h.pending.ListPendingTransfers(ctx, status, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list pending transfers", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(transfers)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetPendingTransfer responds with the held transfer.
+func (h *Handlers) HandleGetPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	transfer, err := h.pending.GetPendingTransfer(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrPendingTransferNotFound) {
+		h.HandleError(w, http.StatusNotFound, api.ErrPendingTransferNotFound)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get pending transfer", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(transfer)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleApprovePendingTransfer posts the held transfer, and responds with both of its ledger entries.
+func (h *Handlers) HandleApprovePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	tx, err := h.pending.ApprovePendingTransfer(ctx, r.PathValue("id"), operator)
+	if h.handleApprovalError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "pending transfer approved", slog.String("id", r.PathValue("id")), slog.String("operator", operator))
+
+	h.rounding.Transactions(tx...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(tx)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleRejectPendingTransfer rejects the held transfer, and responds with the decided transfer.
+func (h *Handlers) HandleRejectPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	operator := middlewares.Operator(ctx)
+
+	if h.handleApprovalError(w, h.pending.RejectPendingTransfer(ctx, id, operator)) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "pending transfer rejected", slog.String("id", id), slog.String("operator", operator))
+
+	transfer, err := h.pending.GetPendingTransfer(ctx, id)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get pending transfer", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(transfer)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleApprovalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, api.ErrPendingTransferNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return true
+	case errors.Is(err, api.ErrPendingTransferDecided):
+		h.HandleError(w, http.StatusConflict, err)
+
+		return true
+	default:
+		// the approval posts the transfer, which fails like any other transfer
+		return h.HandleTransferError(w, err)
+	}
+}
@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+)
+
+// TransferBatches is implemented by repository.PostgresRepository.
+type TransferBatches interface {
+	TransferBatch(ctx context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error)
+}
+
+// WithTransferBatches serves POST /transfers/batch, posting many transfers at once, i.e. a payroll,
+// either all of them or none.
+func (r *APIServer) WithTransferBatches(batches TransferBatches) *APIServer {
+	r.batches = batches
+
+	return r
+}
+
+func (r *APIServer) registerTransferBatchEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.batches == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /transfers/batch", handler.HandleTransferBatch)
+}
+
+// HandleTransferBatch posts the transfers of the batch atomically, with a single idempotency key.
+// Each transfer is validated like the ones of HandleTransfer, and the error of the one failing the batch carries its index.
+func (h *Handlers) HandleTransferBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.TransferBatchRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	// idempotency key is required
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if idempotencyKey == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrMissingIdempotencyKey)
+
+		return
+	}
+
+	if len(request.Transfers) == 0 || len(request.Transfers) > api.MaxBatchTransfers {
+		h.HandleError(w, http.StatusBadRequest, fmt.Errorf("%w: from 1 to %d transfers", api.ErrInvalidBatch, api.MaxBatchTransfers))
+
+		return
+	}
+
+	payloads := make([]*api.TransferRequest, len(request.Transfers))
+
+	for i := range request.Transfers {
+		if payloads[i], err = h.transferPayload(ctx, &request.Transfers[i]); err != nil {
+			h.HandleTransferError(w, &api.BatchTransferError{Index: i, Err: err})
+
+			return
+		}
+	}
+
+	receipt, err := h.batches.TransferBatch(ctx, payloads, idempotencyKey)
+	h.recordIdempotencyConflict(r, func() string { return api.BatchFingerprint(payloads) }, idempotencyKey, err)
+
+	handled := h.HandleTransferError(w, err)
+	if handled {
+		return
+	}
+
+	for _, transfer := range receipt.Transfers {
+		h.rounding.Transactions(transfer.Debit, transfer.Credit)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(receipt)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// cachedTransactions matches the cache keys of GET /transactions/{txId}, the only responses cached.
+const cachedTransactions = "/transactions/*"
+
+// WithCacheInvalidation lets the support staff delete the responses of WithCacheMiddleware under /admin/cache/invalidate
+// with the admin keys, i.e. a stale transaction, without waiting for them to expire.
+func (r *APIServer) WithCacheInvalidation(auth middlewares.Middleware, client middlewares.ScannerAndDeleter) *APIServer {
+	r.adminAuth = auth
+	r.cache = client
+
+	return r
+}
+
+func (r *APIServer) registerCacheEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.cache == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/cache/invalidate", r.adminAuth(handler.HandleInvalidateCache))
+}
+
+// HandleInvalidateCache deletes the cached responses whose request URI matches any of the path patterns,
+// and the cached transactions of the accounts, then responds with how many were deleted.
+func (h *Handlers) HandleInvalidateCache(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.InvalidateCacheRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	if !validCachePatterns(request) {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidCachePattern)
+
+		return
+	}
+
+	invalidation := &api.CacheInvalidation{}
+
+	for _, pattern := range request.Paths {
+		deleted, err := middlewares.InvalidateCache(ctx, h.cache, pattern, nil)
+		invalidation.Deleted += deleted
+
+		if err != nil {
+			h.failedCacheInvalidation(w, r, invalidation, err)
+
+			return
+		}
+	}
+
+	if len(request.AccountIDs) > 0 {
+		deleted, err := InvalidateTransactions(ctx, h.cache, request.AccountIDs...)
+		invalidation.Deleted += deleted
+
+		if err != nil {
+			h.failedCacheInvalidation(w, r, invalidation, err)
+
+			return
+		}
+	}
+
+	h.logger.InfoContext(ctx, "cache invalidated", slog.Any("paths", request.Paths), slog.Any("account_ids", request.AccountIDs),
+		slog.Int64("deleted", invalidation.Deleted), slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(invalidation)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// failedCacheInvalidation logs the responses deleted before the error, the invalidation can be sent again.
+func (h *Handlers) failedCacheInvalidation(w http.ResponseWriter, r *http.Request, invalidation *api.CacheInvalidation, err error) {
+	h.logger.ErrorContext(r.Context(), "failed to invalidate the cache", slog.Int64("deleted", invalidation.Deleted), slog.Any("error", err))
+	h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+}
+
+// validCachePatterns requires a pattern at least, and the path patterns to start with a slash like the cache keys,
+// so they can't match the other keys of the Redis, i.e. the idempotency reservations.
+func validCachePatterns(request *api.InvalidateCacheRequest) bool {
+	if len(request.Paths) == 0 && len(request.AccountIDs) == 0 {
+		return false
+	}
+
+	for _, pattern := range request.Paths {
+		if !strings.HasPrefix(pattern, "/") {
+			return false
+		}
+	}
+
+	for _, accountID := range request.AccountIDs {
+		if strings.TrimSpace(accountID) == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// InvalidateTransactions deletes the cached transactions of the accounts, i.e. once they're erased,
+// and returns how many were deleted.
+func InvalidateTransactions(ctx context.Context, client middlewares.ScannerAndDeleter, accountIDs ...string) (int64, error) {
+	return middlewares.InvalidateCache(ctx, client, cachedTransactions, ofAccounts(accountIDs)) //nolint:wrapcheck // the error names the cache
+}
+
+// invalidateTransaction deletes the cached responses of GET /transactions/{txId}, with or without a query string.
+// It doesn't fail without WithCacheInvalidation, the responses then expire with the cache.
+func (h *Handlers) invalidateTransaction(ctx context.Context, txID string) {
+	if h.cache == nil {
+		return
+	}
+
+	// the id is escaped, so it only matches itself
+	key := "/transactions/" + globEscaper.Replace(txID)
+
+	for _, pattern := range []string{key, key + "\\?*"} {
+		if _, err := middlewares.InvalidateCache(ctx, h.cache, pattern, nil); err != nil {
+			h.logger.ErrorContext(ctx, "failed to invalidate the cached transaction", slog.String("tx_id", txID), slog.Any("error", err))
+
+			return
+		}
+	}
+}
+
+// globEscaper escapes the special characters of the Redis glob patterns.
+//
+//nolint:gochecknoglobals // constant
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// ofAccounts matches the cached transactions of the accounts.
+func ofAccounts(accountIDs []string) func(payload []byte) bool {
+	accounts := make(map[string]bool, len(accountIDs))
+	for _, accountID := range accountIDs {
+		accounts[accountID] = true
+	}
+
+	return func(payload []byte) bool {
+		var transaction api.Transaction
+		if err := json.Unmarshal(payload, &transaction); err != nil {
+			return false
+		}
+
+		return accounts[transaction.AccountID]
+	}
+}
@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// WithDebugEndpoints serves the pprof profiles under /debug/pprof/ and the expvar variables under /debug/vars.
+// They expose the internals of the process, so they're only served behind the given authentication.
+func (r *APIServer) WithDebugEndpoints(auth middlewares.Middleware) *APIServer {
+	r.debugAuth = auth
+
+	return r
+}
+
+func (r *APIServer) registerDebugEndpoints(mux *http.ServeMux) {
+	if r.debugAuth == nil {
+		return
+	}
+
+	// registered explicitly, because importing net/http/pprof only registers them on the default mux
+	mux.HandleFunc("GET /debug/pprof/", r.debugAuth(pprof.Index))
+	mux.HandleFunc("GET /debug/pprof/cmdline", r.debugAuth(pprof.Cmdline))
+	mux.HandleFunc("GET /debug/pprof/profile", r.debugAuth(pprof.Profile))
+	mux.HandleFunc("GET /debug/pprof/symbol", r.debugAuth(pprof.Symbol))
+	mux.HandleFunc("POST /debug/pprof/symbol", r.debugAuth(pprof.Symbol))
+	mux.HandleFunc("GET /debug/pprof/trace", r.debugAuth(pprof.Trace))
+	mux.HandleFunc("GET /debug/vars", r.debugAuth(expvar.Handler().ServeHTTP))
+}
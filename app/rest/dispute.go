@@ -0,0 +1,237 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Disputes is implemented by repository.PostgresRepository.
+type Disputes interface {
+	OpenDispute(ctx context.Context, request *api.OpenDisputeRequest, operator string) (*api.Dispute, error)
+	GetDispute(ctx context.Context, id string) (*api.Dispute, error)
+	ListDisputes(ctx context.Context, status api.DisputeStatus, limit int) ([]*api.Dispute, error)
+	ReverseDispute(ctx context.Context, id, operator string) (*api.Dispute, error)
+	ReleaseDispute(ctx context.Context, id, operator string) (*api.Dispute, error)
+	GetTransactionDisputes(ctx context.Context, txID string) ([]*api.Dispute, error)
+}
+
+// WithDisputes serves the disputes under /admin/disputes, and their history by transaction under /admin/transactions.
+// The operators are identified by their admin key, which is recorded with every status change.
+func (r *APIServer) WithDisputes(auth middlewares.Middleware, disputes Disputes) *APIServer {
+	r.adminAuth = auth
+	r.disputes = disputes
+
+	return r
+}
+
+func (r *APIServer) registerDisputeEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.disputes == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/disputes", r.adminAuth(handler.HandleOpenDispute))
+	mux.HandleFunc("GET /admin/disputes", r.adminAuth(handler.HandleListDisputes))
+	mux.HandleFunc("GET /admin/disputes/{id}", r.adminAuth(handler.HandleGetDispute))
+	mux.HandleFunc("POST /admin/disputes/{id}/reverse", r.adminAuth(handler.HandleReverseDispute))
+	mux.HandleFunc("POST /admin/disputes/{id}/release", r.adminAuth(handler.HandleReleaseDispute))
+	mux.HandleFunc("GET /admin/transactions/{txId}/disputes", r.adminAuth(handler.HandleGetTransactionDisputes))
+}
+
+// HandleOpenDispute disputes the transfer of the ledger entry in the request, and holds the disputed amount.
+func (h *Handlers) HandleOpenDispute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	request := &api.OpenDisputeRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || strings.TrimSpace(request.TxID) == "" || request.Amount.IsNegative() {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	// compared with the amount of the transfer, which an extreme exponent would take forever to
+	if err = api.ValidateAmount(request.Amount); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	dispute, err := h.disputes.OpenDispute(ctx, request, operator)
+	if h.handleDisputeError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "dispute opened", slog.String("id", dispute.ID), slog.String("operator", operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(dispute)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleListDisputes responds with the disputes in the status parameter, the open ones by default, the oldest first.
+func (h *Handlers) HandleListDisputes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := api.DisputeOpen
+
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = api.DisputeStatus(strings.ToUpper(value))
+
+		if status != api.DisputeOpen && status != api.DisputeReversed && status != api.DisputeReleased {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	disputes, err := h.disputes.ListDisputes(ctx, status, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list disputes", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(disputes)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetDispute responds with the dispute and its status history.
+func (h *Handlers) HandleGetDispute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dispute, err := h.disputes.GetDispute(ctx, r.PathValue("id"))
+	if h.handleDisputeError(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(dispute)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleReverseDispute returns the held amount to the sender, and responds with the resolved dispute.
+func (h *Handlers) HandleReverseDispute(w http.ResponseWriter, r *http.Request) {
+	h.handleResolveDispute(w, r, h.disputes.ReverseDispute)
+}
+
+// HandleReleaseDispute returns the held amount to the recipient, and responds with the resolved dispute.
+func (h *Handlers) HandleReleaseDispute(w http.ResponseWriter, r *http.Request) {
+	h.handleResolveDispute(w, r, h.disputes.ReleaseDispute)
+}
+
+func (h *Handlers) handleResolveDispute(w http.ResponseWriter, r *http.Request, resolve func(ctx context.Context, id, operator string) (*api.Dispute, error)) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	dispute, err := resolve(ctx, r.PathValue("id"), operator)
+	if h.handleDisputeError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "dispute resolved", slog.String("id", dispute.ID),
+		slog.String("status", string(dispute.Status)), slog.String("operator", operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(dispute)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetTransactionDisputes responds with the disputes of the transfer of the ledger entry, with their status history.
+func (h *Handlers) HandleGetTransactionDisputes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	disputes, err := h.disputes.GetTransactionDisputes(ctx, r.PathValue("txId"))
+	if h.handleDisputeError(w, err) {
+		return
+	}
+
+	setTotalCount(w, len(disputes))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(disputes)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleDisputeError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, api.ErrDisputeNotFound),
+		errors.Is(err, api.ErrTransactionNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return true
+	case errors.Is(err, api.ErrDisputeOpen),
+		errors.Is(err, api.ErrDisputeResolved):
+		h.HandleError(w, http.StatusConflict, err)
+
+		return true
+	case errors.Is(err, api.ErrDisputedAmount),
+		errors.Is(err, api.ErrDisputedDispute),
+		errors.Is(err, api.ErrNotDisputable):
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
+
+		return true
+	default:
+		// the disputes post ledger entries, which fail like any other transfer
+		return h.HandleTransferError(w, err)
+	}
+}
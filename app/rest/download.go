@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/statements"
+)
+
+// StatementDownloads is implemented by repository.PostgresRepository.
+type StatementDownloads interface {
+	DownloadStatement(ctx context.Context, accountID, currency string, from, to time.Time, w api.StatementWriter) error
+}
+
+// WithStatementDownloads serves the statements of any period under /statements/{accountId}/{currency},
+// as CSV or PDF, streamed as they're read from the ledger.
+func (r *APIServer) WithStatementDownloads(downloads StatementDownloads) *APIServer {
+	r.downloads = downloads
+
+	return r
+}
+
+func (r *APIServer) registerStatementDownloadEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.downloads == nil {
+		return
+	}
+
+	// not cached, as the statements of the current period change
+	mux.HandleFunc("GET /statements/{accountId}/{currency}", handler.HandleDownloadStatement)
+}
+
+// HandleDownloadStatement streams the statement of the account in the currency, from the from parameter included
+// to the to parameter excluded, both RFC 3339 times or dates, in the format parameter, csv by default.
+func (h *Handlers) HandleDownloadStatement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	format := api.StatementFormat(strings.ToLower(strings.TrimSpace(query.Get("format"))))
+	if format == "" {
+		format = api.StatementCSV
+	}
+
+	from, fromErr := parseStatementTime(query.Get("from"))
+	to, toErr := parseStatementTime(query.Get("to"))
+
+	if fromErr != nil || toErr != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidStatementPeriod)
+
+		return
+	}
+
+	writer, err := statements.NewWriter(format, w)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	accountID, currency := r.PathValue("accountId"), r.PathValue("currency")
+
+	if err = h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	// the headers are only sent with the first entries, so the errors before can still be answered
+	download := &statementDownload{StatementWriter: writer, begin: func(statement *api.Statement) {
+		filename := fmt.Sprintf("statement-%s-%s-%s.%s", statement.AccountID, statement.Currency,
+			statement.PeriodStart.Format(time.DateOnly), format)
+
+		w.Header().Set("Content-Type", statements.ContentType(format))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.WriteHeader(http.StatusOK)
+	}}
+
+	err = h.downloads.DownloadStatement(ctx, accountID, currency, from, to, download)
+
+	switch {
+	case err == nil:
+		return
+	case download.begun:
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "failed to download statement", slog.String("account_id", accountID), slog.Any("error", err))
+	case errors.Is(err, api.ErrInvalidStatementPeriod),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+	default:
+		h.logger.ErrorContext(ctx, "failed to download statement", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+	}
+}
+
+// statementDownload sends the headers of the response once the statement begins.
+type statementDownload struct {
+	api.StatementWriter
+	begin func(statement *api.Statement)
+	begun bool
+}
+
+func (d *statementDownload) Begin(statement *api.Statement) error {
+	d.begin(statement)
+	d.begun = true
+
+	return d.StatementWriter.Begin(statement) //nolint:wrapcheck // returned as is by the repository
+}
+
+// parseStatementTime parses an RFC 3339 time, or a date at midnight in UTC.
+func parseStatementTime(value string) (time.Time, error) {
+	parsed, err := parseDateOrTime(value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %w", api.ErrInvalidStatementPeriod, err)
+	}
+
+	return parsed, nil
+}
+
+// parseDateOrTime parses an RFC 3339 time, or a date at midnight in the location.
+func parseDateOrTime(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if date, err := time.ParseInLocation(time.DateOnly, value, location); err == nil {
+		return date, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+
+	return parsed, nil
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/devshark/wallet/api"
@@ -11,48 +12,111 @@ import (
 // HandleError writes an error response with the given code and error message to the given response writer.
 // It is used to handle errors that occur during request processing.
 func (h *Handlers) HandleError(w http.ResponseWriter, code int, err error) {
+	response := api.ErrorResponse{
+		ErrorCode: code,
+		Code:      api.CodeOf(err),
+		Message:   err.Error(),
+	}
+
+	// the clients fetch the original transfer instead of retrying
+	if duplicate := (&api.DuplicateTransactionError{}); errors.As(err, &duplicate) {
+		response.GroupID = duplicate.GroupID
+	}
+
+	// the clients fix the transfer failing the batch, and retry the whole batch
+	if failed := (&api.BatchTransferError{}); errors.As(err, &failed) {
+		response.Index = &failed.Index
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
-	errEncode := json.NewEncoder(w).Encode(api.ErrorResponse{
-		ErrorCode: code,
-		Message:   err.Error(),
-	})
+	errEncode := json.NewEncoder(w).Encode(response)
 	if errEncode != nil {
 		// we can't respond with an error payload anymore, because the headers have already been sent
 		// headers must be written before the content, so if writing the content fails, we can't go back
 		// just log it
-		h.logger.Printf("encoding error: %v", err)
+		h.logger.Error("encoding error", slog.Any("error", errEncode))
 	}
 }
 
 // returns true if response is handled, false otherwise ie no errors
 func (h *Handlers) HandleTransferError(w http.ResponseWriter, err error) bool {
-	switch {
-	case errors.Is(err, api.ErrInsufficientBalance):
-		fallthrough
-	case errors.Is(err, api.ErrDuplicateTransaction):
-		h.HandleError(w, http.StatusUnprocessableEntity, err)
-
-		return true
-	case errors.Is(err, api.ErrSameAccountIDs):
-		fallthrough
-	case errors.Is(err, api.ErrInvalidAmount):
-		fallthrough
-	case errors.Is(err, api.ErrInvalidAccountID):
-		fallthrough
-	case errors.Is(err, api.ErrInvalidCurrency):
-		fallthrough
-	case errors.Is(err, api.ErrInvalidRequest):
-		h.HandleError(w, http.StatusBadRequest, err)
-
-		return true
-	case errors.Is(err, nil):
+	if err == nil {
 		return false
-	default:
-		h.logger.Printf("failed to transfer: %v\n", err)
+	}
+
+	status, known := transferStatus(err)
+	if !known {
+		h.logger.Error("failed to transfer", slog.Any("error", err))
 		h.HandleError(w, http.StatusInternalServerError, api.ErrTransferFailed)
 
 		return true
 	}
+
+	if override, ok := h.transferStatuses[api.CodeOf(err)]; ok {
+		status = override
+	}
+
+	h.HandleError(w, status, err)
+
+	return true
+}
+
+// transferStatus is the default HTTP status of a failed transfer, see WithTransferStatuses. It's false for the unknown errors.
+func transferStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, api.ErrTransferUnderReview),
+		errors.Is(err, api.ErrTransferPendingApproval):
+		// held by the screening or the approval threshold, it's posted once approved
+		return http.StatusAccepted, true
+	case errors.Is(err, api.ErrTransferRejected):
+		return http.StatusForbidden, true
+	case errors.Is(err, api.ErrHoldNotFound),
+		errors.Is(err, api.ErrQueuedTransferNotFound),
+		errors.Is(err, api.ErrScheduledTransferNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, api.ErrHoldClosed):
+		// already captured or released
+		return http.StatusConflict, true
+	case errors.Is(err, api.ErrScheduledTransferClosed):
+		// already executed or cancelled
+		return http.StatusConflict, true
+	case errors.Is(err, api.ErrDuplicateTransaction):
+		// already posted with the idempotency key, the response carries its group id
+		return http.StatusConflict, true
+	case errors.Is(err, api.ErrTransferInProgress):
+		// reserved by another request, i.e. in another region, the client retries once it's done
+		return http.StatusConflict, true
+	case errors.Is(err, api.ErrSandboxQuotaExceeded):
+		// the fake money of the sandbox is replenished over the quota period
+		return http.StatusTooManyRequests, true
+	case errors.Is(err, api.ErrFXRateUnavailable):
+		// the provider of the rates can't be reached, the client retries later
+		return http.StatusServiceUnavailable, true
+	case errors.Is(err, api.ErrInsufficientBalance),
+		errors.Is(err, api.ErrOutsideHierarchy),
+		errors.Is(err, api.ErrAccountNotProvisioned),
+		errors.Is(err, api.ErrAccountRequiresDeposit),
+		errors.Is(err, api.ErrBatchTransferHeld),
+		errors.Is(err, api.ErrHoldRequiresApproval),
+		errors.Is(err, api.ErrUnsupportedCurrencyPair),
+		errors.Is(err, api.ErrAccountNotFound),
+		errors.Is(err, api.ErrAliasNotFound):
+		return http.StatusUnprocessableEntity, true
+	case errors.Is(err, api.ErrSameAccountIDs),
+		errors.Is(err, api.ErrCompanyAccount),
+		errors.Is(err, api.ErrInvalidAmount),
+		errors.Is(err, api.ErrAmountOutOfRange),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency),
+		errors.Is(err, api.ErrInvalidTag),
+		errors.Is(err, api.ErrInvalidRemarks),
+		errors.Is(err, api.ErrInvalidTransactionFilter),
+		errors.Is(err, api.ErrInvalidBatch),
+		errors.Is(err, api.ErrInvalidRequest):
+		return http.StatusBadRequest, true
+	default:
+		return 0, false
+	}
 }
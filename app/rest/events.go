@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// EventLog is implemented by repository.PostgresRepository.
+type EventLog interface {
+	GetEventLog(ctx context.Context, since string, limit int) (*api.EventLog, error)
+	RedeliverEvent(ctx context.Context, id string) (*api.LoggedEvent, error)
+}
+
+// WithEventLog serves the events of the outbox under /admin/events, so the consumers that missed some can backfill,
+// and the operators can have an event delivered again.
+func (r *APIServer) WithEventLog(auth middlewares.Middleware, eventLog EventLog) *APIServer {
+	r.adminAuth = auth
+	r.eventLog = eventLog
+
+	return r
+}
+
+func (r *APIServer) registerEventLogEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.eventLog == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/events", r.adminAuth(handler.HandleGetEventLog))
+	mux.HandleFunc("POST /admin/events/{id}/redeliver", r.adminAuth(handler.HandleRedeliverEvent))
+}
+
+// HandleGetEventLog responds with the events after the since cursor, the oldest first, and the cursor of the next page.
+func (h *Handlers) HandleGetEventLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	log, err := h.eventLog.GetEventLog(ctx, r.URL.Query().Get("since"), limit)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidCursor):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to get the event log", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setNextCursor(w, log.NextCursor)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(log)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleRedeliverEvent has the event published again by the relay, and responds with the pending event.
+func (h *Handlers) HandleRedeliverEvent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	event, err := h.eventLog.RedeliverEvent(ctx, r.PathValue("id"))
+
+	switch {
+	case errors.Is(err, api.ErrEventNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to redeliver the event", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "event redelivery requested", slog.String("id", event.ID),
+		slog.String("operator", middlewares.Operator(ctx)))
+
+	// delivered by the worker
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	err = json.NewEncoder(w).Encode(event)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// FeeRules is implemented by repository.PostgresRepository.
+type FeeRules interface {
+	SetFeeRule(ctx context.Context, operation api.FeeOperation, currency string, request *api.SetFeeRuleRequest, operator string) (*api.FeeRule, error)
+	GetFeeRules(ctx context.Context) ([]*api.FeeRule, error)
+	DeleteFeeRule(ctx context.Context, operation api.FeeOperation, currency string) error
+}
+
+// WithFeeRules serves the fee rules of the transfers and withdrawals under /admin/fees, set by the operators.
+func (r *APIServer) WithFeeRules(auth middlewares.Middleware, rules FeeRules) *APIServer {
+	r.adminAuth = auth
+	r.feeRules = rules
+
+	return r
+}
+
+func (r *APIServer) registerFeeRuleEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.feeRules == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/fees", r.adminAuth(handler.HandleGetFeeRules))
+	mux.HandleFunc("PUT /admin/fees/{operation}/{currency}", r.adminAuth(handler.HandleSetFeeRule))
+	mux.HandleFunc("DELETE /admin/fees/{operation}/{currency}", r.adminAuth(handler.HandleDeleteFeeRule))
+}
+
+// HandleSetFeeRule sets the fee of the operation in the currency of the path, and responds with the rule.
+func (h *Handlers) HandleSetFeeRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	request := &api.SetFeeRuleRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	rule, err := h.feeRules.SetFeeRule(ctx, api.FeeOperation(r.PathValue("operation")), r.PathValue("currency"), request, operator)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidFeeRule),
+		errors.Is(err, api.ErrInvalidCurrency),
+		errors.Is(err, api.ErrAmountOutOfRange):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to set fee rule", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "fee rule set", slog.String("operation", string(rule.Operation)),
+		slog.String("currency", rule.Currency), slog.String("operator", operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(rule)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetFeeRules responds with the fee rules, by operation then currency.
+func (h *Handlers) HandleGetFeeRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rules, err := h.feeRules.GetFeeRules(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get fee rules", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(rules))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(rules)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleDeleteFeeRule stops charging the fee of the operation in the currency of the path.
+func (h *Handlers) HandleDeleteFeeRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.feeRules.DeleteFeeRule(ctx, api.FeeOperation(r.PathValue("operation")), r.PathValue("currency"))
+	if errors.Is(err, api.ErrFeeRuleNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to delete fee rule", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "fee rule deleted", slog.String("operation", r.PathValue("operation")),
+		slog.String("currency", r.PathValue("currency")), slog.String("operator", middlewares.Operator(ctx)))
+
+	w.WriteHeader(http.StatusNoContent)
+}
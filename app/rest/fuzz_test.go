@@ -0,0 +1,155 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// the payloads seeding the fuzzing of the transfers, the deposits and the withdrawals
+var fuzzPayloads = []string{
+	`{"from_account_id":"user1","to_account_id":"user2","account_id":"user1","currency":"USD","amount":"10.50"}`,
+	`{"from_account_id":" user1 ","to_account_id":"USER1","account_id":" ","currency":" ","amount":1}`,
+	`{"from_account_id":"user1","to_account_id":"company","account_id":"COMPANY","currency":"USD","amount":"-0.01"}`,
+	`{"from_account_id":"user1","to_account_id":"user2","account_id":"user1","currency":"USD","amount":"1e-20"}`,
+	`{"from_account_id":"user1","to_account_id":"user2","account_id":"user1","currency":"USD","amount":"123456789012345678901234567890.123456789"}`,
+	`{"from_alias":"alice","to_alias":"bob","account_alias":"alice","currency":"USD","amount":"5"}`,
+	`{"from_account_id":"user1","to_account_id":"user2","account_id":"user1","currency":"USD","amount":"1e2147483647"}`,
+	`{"from_account_id":"user1","to_account_id":"user2","account_id":"user1","currency":"USD","amount":1.5E-19}`,
+	`{"currency":"USD","amount":null}`,
+	`{"amount":"NaN"}`,
+	`[]`,
+	`:nul'`,
+}
+
+// fuzzRepository answers the transfers with both of their legs, and fails the test if the handler lets through
+// a payload the validation should have rejected.
+func fuzzRepository(t *testing.T) *repository.MockRepository {
+	t.Helper()
+
+	repo := repository.NewMockRepository(t)
+
+	repo.EXPECT().ResolveAlias(mock.Anything, mock.Anything).Return(nil, api.ErrAliasNotFound).Maybe()
+	repo.EXPECT().Transfer(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, request *api.TransferRequest, idempotencyKey string) ([]*api.Transaction, error) {
+			requireValidTransfer(t, request)
+
+			return []*api.Transaction{
+				{TxID: "tx1", AccountID: request.FromAccountID, Currency: request.Currency, Type: api.DEBIT, Amount: request.Amount, GroupID: idempotencyKey},
+				{TxID: "tx2", AccountID: request.ToAccountID, Currency: request.Currency, Type: api.CREDIT, Amount: request.Amount, GroupID: idempotencyKey},
+			}, nil
+		}).Maybe()
+
+	return repo
+}
+
+func requireValidTransfer(t *testing.T, request *api.TransferRequest) {
+	t.Helper()
+
+	require.True(t, request.Amount.IsPositive(), "amount %s", request.Amount)
+	require.NoError(t, api.ValidateAmount(request.Amount))
+	require.NotEmpty(t, request.Currency)
+	require.Equal(t, strings.TrimSpace(request.Currency), request.Currency)
+
+	for _, accountID := range []string{request.FromAccountID, request.ToAccountID} {
+		require.NotEmpty(t, accountID)
+		require.Equal(t, strings.TrimSpace(accountID), accountID)
+	}
+
+	require.False(t, strings.EqualFold(request.FromAccountID, request.ToAccountID), "transfer from %s to itself", request.FromAccountID)
+	require.False(t, strings.EqualFold(request.FromAccountID, api.CompanyAccountID) && strings.EqualFold(request.ToAccountID, api.CompanyAccountID))
+}
+
+// fuzzPost posts the payload to the handler, which must answer it with a created receipt or a client error.
+func fuzzPost(t *testing.T, handler http.HandlerFunc, path string, payload []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(rest.IdempotencyKeyHeader, "fuzz-key")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Contains(t, []int{http.StatusCreated, http.StatusBadRequest, http.StatusUnprocessableEntity}, rr.Code, rr.Body.String())
+}
+
+func FuzzHandleDeposit(f *testing.F) {
+	for _, payload := range fuzzPayloads {
+		f.Add([]byte(payload))
+	}
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		handlers := rest.NewRestHandlers(fuzzRepository(t))
+
+		fuzzPost(t, handlers.HandleDeposit, "/deposit", payload)
+	})
+}
+
+func FuzzHandleWithdrawal(f *testing.F) {
+	for _, payload := range fuzzPayloads {
+		f.Add([]byte(payload))
+	}
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		handlers := rest.NewRestHandlers(fuzzRepository(t))
+
+		fuzzPost(t, handlers.HandleWithdrawal, "/withdraw", payload)
+	})
+}
+
+func FuzzHandleTransfer(f *testing.F) {
+	for _, payload := range fuzzPayloads {
+		f.Add([]byte(payload))
+	}
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		handlers := rest.NewRestHandlers(fuzzRepository(t))
+
+		fuzzPost(t, handlers.HandleTransfer, "/transfer", payload)
+	})
+}
+
+func FuzzGetAccountBalance(f *testing.F) {
+	f.Add("user1", "USD", "")
+	f.Add("", "", "")
+	f.Add(" ", "usd", "children")
+	f.Add("user1", "USD", "stats")
+	f.Add("user%2F1", "U\x00SD", "unknown")
+
+	f.Fuzz(func(t *testing.T, accountID, currency, include string) {
+		repo := repository.NewMockRepository(t)
+
+		account := func(_ context.Context, currency, accountID string) (*api.Account, error) {
+			require.NotEmpty(t, currency)
+			require.NotEmpty(t, accountID)
+
+			return &api.Account{AccountID: accountID, Currency: currency}, nil
+		}
+
+		repo.EXPECT().GetAccountBalance(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(account).Maybe()
+		repo.EXPECT().GetAccountTree(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(account).Maybe()
+		repo.EXPECT().GetAccountStats(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(account).Maybe()
+
+		handlers := rest.NewRestHandlers(repo)
+
+		req := httptest.NewRequest(http.MethodGet, "/account/x/y", nil)
+		req.SetPathValue("accountId", accountID)
+		req.SetPathValue("currency", currency)
+		req.URL.RawQuery = "include=" + include
+
+		rr := httptest.NewRecorder()
+		handlers.GetAccountBalance(rr, req)
+
+		require.Contains(t, []int{http.StatusOK, http.StatusBadRequest}, rr.Code, rr.Body.String())
+	})
+}
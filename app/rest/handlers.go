@@ -1,20 +1,127 @@
 package rest
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"strings"
 
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/remarks"
 	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/pkg/middlewares"
 )
 
 type Handlers struct {
-	repo    repository.Repository
-	logger  *log.Logger
-	pingers []Pinger
+	repo            repository.Repository
+	logger          *slog.Logger
+	pingers         []Pinger
+	degradedPingers []degradedPinger
+	features        features.Features
+	reconciler      Reconciler
+	reviews         TransferReviews
+	pending         PendingTransfers
+	disputes        Disputes
+	metadata        Metadata
+	reversals       Reversals
+	reports         Reports
+	statements      Statements
+	receipts        Receipts
+	webhooks        Webhooks
+	eventLog        EventLog
+	personalData    PersonalData
+	activity        ActivityFeed
+	provisioning    AccountProvisioning
+	rebuilder       BalanceRebuilder
+	readOnly        ReadOnlyMode
+	conflicts       IdempotencyConflicts
+	downloads       StatementDownloads
+	subscriptions   *subscriptions
+	streams         TransactionStreams
+	batches         TransferBatches
+	holds           Holds
+	queue           TransferQueue
+	scheduled       ScheduledTransfers
+	feeRules        FeeRules
+	cache           middlewares.ScannerAndDeleter
+	rounding        rounding.Policies
+	// remarks is the length and the content of the remarks of the transfers
+	remarks remarks.Policy
+	// transferStatuses override the HTTP status of the failed transfers, by error code
+	transferStatuses map[api.ErrorCode]int
+	// companyAccountID funds the deposits and receives the withdrawals, the transfers can't use it
+	companyAccountID string
 }
 
 func NewRestHandlers(repo repository.Repository) *Handlers {
 	return &Handlers{
-		repo:   repo,
-		logger: log.Default(),
+		repo:     repo,
+		logger:   slog.Default(),
+		features: features.Disabled(),
+		rounding: rounding.None(),
+
+		companyAccountID: api.CompanyAccountID,
+	}
+}
+
+// WithFeatures sets the feature flags gating the risky behaviors.
+func (h *Handlers) WithFeatures(f features.Features) *Handlers {
+	h.features = f
+
+	return h
+}
+
+// WithRounding sets the rounding policies of the amounts in the responses.
+func (h *Handlers) WithRounding(policies rounding.Policies) *Handlers {
+	h.rounding = policies
+
+	return h
+}
+
+// WithTransferStatuses overrides the HTTP status of the failed transfers by error code,
+// i.e. DUPLICATE_TRANSACTION to 422 for the clients written before it was answered with 409.
+func (h *Handlers) WithTransferStatuses(statuses map[api.ErrorCode]int) *Handlers {
+	h.transferStatuses = statuses
+
+	return h
+}
+
+// WithRemarksPolicy limits the length and screens the content of the remarks of the transfers, and of their amendments.
+// The remarks are limited to remarks.MaxLength characters by default.
+func (h *Handlers) WithRemarksPolicy(policy remarks.Policy) *Handlers {
+	h.remarks = policy
+
+	return h
+}
+
+// WithCompanyAccount overrides the settlement account of the deposits and withdrawals, api.CompanyAccountID by default.
+func (h *Handlers) WithCompanyAccount(accountID string) *Handlers {
+	h.companyAccountID = accountID
+
+	return h
+}
+
+// isCompanyAccount reports whether the account of the request is the settlement account.
+func (h *Handlers) isCompanyAccount(accountID string) bool {
+	return strings.EqualFold(strings.TrimSpace(accountID), h.companyAccountID)
+}
+
+// validateAccountIDs returns api.ErrInvalidAccountID, explaining the format, when an account id doesn't follow the
+// account id policy of the deployment. The settlement, disputes and fees accounts are exempt.
+func (h *Handlers) validateAccountIDs(ctx context.Context, accountIDs ...string) error {
+	for _, accountID := range accountIDs {
+		accountID = strings.TrimSpace(accountID)
+
+		if h.isCompanyAccount(accountID) || strings.EqualFold(accountID, api.DisputesAccountID) ||
+			strings.EqualFold(accountID, api.FeesAccountID) {
+			continue
+		}
+
+		if err := h.features.ValidateAccountID(ctx, accountID); err != nil {
+			return err //nolint:wrapcheck // the domain errors are returned as is
+		}
 	}
+
+	return nil
 }
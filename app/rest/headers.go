@@ -0,0 +1,32 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+// The headers must be set before the status is written, so the handlers call these right before WriteHeader.
+
+// setTotalCount sets the api.TotalCountHeader of a list, across its pages when it's read by page.
+func setTotalCount(w http.ResponseWriter, total int) {
+	w.Header().Set(api.TotalCountHeader, strconv.Itoa(total))
+}
+
+// setNextCursor sets the api.NextCursorHeader of a page read by cursor, unless there's no cursor to resume from.
+func setNextCursor(w http.ResponseWriter, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	w.Header().Set(api.NextCursorHeader, cursor)
+}
+
+// setRateLimit sets the limit of the window, the requests left in it, and when it resets.
+func setRateLimit(w http.ResponseWriter, limit, remaining int64, reset time.Time) {
+	w.Header().Set(api.RateLimitLimitHeader, strconv.FormatInt(limit, 10))
+	w.Header().Set(api.RateLimitRemainingHeader, strconv.FormatInt(max(remaining, 0), 10))
+	w.Header().Set(api.RateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+}
@@ -2,23 +2,65 @@ package rest
 
 import (
 	"context"
+	"encoding/json"
+	"expvar"
+	"log/slog"
 	"net/http"
+	"sync"
+
+	"github.com/devshark/wallet/api"
 )
 
 type Pinger func(ctx context.Context) error
 
+// degradedPinger checks a dependency the service can do without, named in the readiness and the gauge.
+type degradedPinger struct {
+	name string
+	ping Pinger
+}
+
+//nolint:gochecknoglobals // expvar panics when a name is published twice
+var (
+	degradedGaugeOnce sync.Once
+	degradedGauge     *expvar.Map
+)
+
+// degradedDependencies is the degraded expvar variable, served under /debug/vars:
+// 1 for each dependency down at the last readiness check, 0 once it's back.
+func degradedDependencies() *expvar.Map {
+	degradedGaugeOnce.Do(func() {
+		degradedGauge = expvar.NewMap("degraded")
+	})
+
+	return degradedGauge
+}
+
 func (r *APIServer) AddPinger(p Pinger) *APIServer {
 	r.pingers = append(r.pingers, p)
 
 	return r
 }
 
+// AddDegradedPinger checks a dependency the service can do without, i.e. the cache, in GET /readyz.
+// When it's down the service is reported degraded, but still ready, and /health ignores it.
+func (r *APIServer) AddDegradedPinger(name string, p Pinger) *APIServer {
+	r.degradedPingers = append(r.degradedPingers, degradedPinger{name: name, ping: p})
+
+	return r
+}
+
 func (h *Handlers) AddPinger(p Pinger) *Handlers {
 	h.pingers = append(h.pingers, p)
 
 	return h
 }
 
+func (h *Handlers) AddDegradedPinger(name string, p Pinger) *Handlers {
+	h.degradedPingers = append(h.degradedPingers, degradedPinger{name: name, ping: p})
+
+	return h
+}
+
 func (h *Handlers) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -33,3 +75,53 @@ func (h *Handlers) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
 }
+
+// HandleReadiness fails like the health check when a required dependency is down, and responds with
+// the degraded status, still 200, when only the dependencies the service can do without are down.
+func (h *Handlers) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	for _, pinger := range h.pingers {
+		if err := pinger(ctx); err != nil {
+			h.HandleError(w, http.StatusServiceUnavailable, err)
+
+			return
+		}
+	}
+
+	readiness := &api.Readiness{Status: api.ReadinessReady}
+	gauge := degradedDependencies()
+
+	for _, pinger := range h.degradedPingers {
+		if err := pinger.ping(ctx); err != nil {
+			h.logger.WarnContext(ctx, "dependency degraded", slog.String("dependency", pinger.name), slog.Any("error", err))
+
+			readiness.Status = api.ReadinessDegraded
+			readiness.Degraded = append(readiness.Degraded, pinger.name)
+
+			gauge.Set(pinger.name, intVar(1))
+
+			continue
+		}
+
+		gauge.Set(pinger.name, intVar(0))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(readiness)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+func intVar(value int64) *expvar.Int {
+	v := &expvar.Int{}
+	v.Set(value)
+
+	return v
+}
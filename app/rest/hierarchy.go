@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// HandleSetParent makes the account a sub-account of the parent account in the request.
+func (h *Handlers) HandleSetParent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.PathValue("accountId"))
+
+	request := &api.SetParentRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	parentID := strings.TrimSpace(request.ParentAccountID)
+
+	if accountID == "" || parentID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	if err = h.validateAccountIDs(ctx, accountID, parentID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = h.repo.SetParentAccount(ctx, accountID, parentID)
+
+	switch {
+	case errors.Is(err, api.ErrParentAlreadySet),
+		errors.Is(err, api.ErrHierarchyCycle):
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
+
+		return
+	case errors.Is(err, api.ErrSameAccountIDs),
+		errors.Is(err, api.ErrCompanyAccount),
+		errors.Is(err, api.ErrInvalidAccountID):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to set parent account", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(&api.AccountParent{
+		AccountID:       accountID,
+		ParentAccountID: parentID,
+	})
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,129 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+)
+
+// Holds is implemented by repository.PostgresRepository.
+type Holds interface {
+	PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error)
+	GetHold(ctx context.Context, id string) (*api.Hold, error)
+	CaptureHold(ctx context.Context, id string) (*api.TransferReceipt, error)
+	ReleaseHold(ctx context.Context, id string) (*api.Hold, error)
+}
+
+// WithHolds serves the authorize then capture flow under /holds: a hold reserves the amount of a transfer on the sender,
+// i.e. a card authorization, until it's captured as the transfer or released.
+func (r *APIServer) WithHolds(holds Holds) *APIServer {
+	r.holds = holds
+
+	return r
+}
+
+func (r *APIServer) registerHoldEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.holds == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /holds", handler.HandlePlaceHold)
+	mux.HandleFunc("GET /holds/{id}", handler.HandleGetHold)
+	mux.HandleFunc("POST /holds/{id}/capture", handler.HandleCaptureHold)
+	mux.HandleFunc("POST /holds/{id}/release", handler.HandleReleaseHold)
+}
+
+// HandlePlaceHold reserves the amount of the transfer on the sender, and responds with the authorized hold.
+// The transfer is validated like the ones of HandleTransfer, and the idempotency key is its group once captured.
+func (h *Handlers) HandlePlaceHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.TransferRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	// idempotency key is required
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if idempotencyKey == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrMissingIdempotencyKey)
+
+		return
+	}
+
+	payload, err := h.transferPayload(ctx, request)
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	hold, err := h.holds.PlaceHold(ctx, payload, idempotencyKey)
+	h.recordIdempotencyConflict(r, payload.Fingerprint, idempotencyKey, err)
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.writeHold(ctx, w, http.StatusCreated, hold)
+}
+
+// HandleGetHold responds with the hold, whatever its status.
+func (h *Handlers) HandleGetHold(w http.ResponseWriter, r *http.Request) {
+	hold, err := h.holds.GetHold(r.Context(), r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.writeHold(r.Context(), w, http.StatusOK, hold)
+}
+
+// HandleCaptureHold posts the transfer of the authorized hold, and responds with its receipt, like HandleTransfer.
+func (h *Handlers) HandleCaptureHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	receipt, err := h.holds.CaptureHold(ctx, r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.rounding.Transactions(receipt.Debit, receipt.Credit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(receipt)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleReleaseHold cancels the authorized hold, and responds with the released hold.
+func (h *Handlers) HandleReleaseHold(w http.ResponseWriter, r *http.Request) {
+	hold, err := h.holds.ReleaseHold(r.Context(), r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.writeHold(r.Context(), w, http.StatusOK, hold)
+}
+
+func (h *Handlers) writeHold(ctx context.Context, w http.ResponseWriter, status int, hold *api.Hold) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(hold)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
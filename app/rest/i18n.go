@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/i18n"
+	"golang.org/x/text/language"
+)
+
+// WithErrorMessages translates the messages of the error responses from the catalog, in the language negotiated with
+// the Accept-Language header of the clients. The errors keep their code, and their message in i18n.Source otherwise.
+func (r *APIServer) WithErrorMessages(catalog *i18n.Catalog) *APIServer {
+	r.errorMessages = catalog
+
+	return r
+}
+
+// localizeErrors rewrites the messages of the api.ErrorResponse once they're written, as the handlers don't negotiate.
+func localizeErrors(catalog *i18n.Catalog, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		tag := catalog.Negotiate(strings.Join(req.Header.Values("Accept-Language"), ","))
+		if tag == i18n.Source {
+			next.ServeHTTP(w, req)
+
+			return
+		}
+
+		writer := &localizedErrorWriter{ResponseWriter: w, catalog: catalog, tag: tag}
+		next.ServeHTTP(writer, req)
+		writer.finish()
+	})
+}
+
+// localizedErrorWriter buffers the JSON responses that may be errors to translate them, and passes the others through.
+type localizedErrorWriter struct {
+	http.ResponseWriter
+	catalog     *i18n.Catalog
+	tag         language.Tag
+	status      int
+	wroteHeader bool
+	// buffer holds the response that may be an error, nil for the ones passed through
+	buffer *bytes.Buffer
+}
+
+func (w *localizedErrorWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = status
+
+	// the held transfers are answered with 202 and an error
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "application/json" && (status >= http.StatusBadRequest || status == http.StatusAccepted) {
+		w.buffer = &bytes.Buffer{}
+
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *localizedErrorWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffer != nil {
+		return w.buffer.Write(b) //nolint:wrapcheck // never fails
+	}
+
+	return w.ResponseWriter.Write(b) //nolint:wrapcheck // as the handler would get it
+}
+
+// Flush holds the buffered responses until they're complete, and flushes the others.
+func (w *localizedErrorWriter) Flush() {
+	if w.buffer != nil {
+		return
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the deadlines of the connection.
+func (w *localizedErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish sends the buffered response with the translated message, or as is if it isn't an error of a translated code.
+func (w *localizedErrorWriter) finish() {
+	if w.buffer == nil {
+		return
+	}
+
+	body := w.buffer.Bytes()
+
+	if localized, ok := w.localize(body); ok {
+		body = localized
+
+		w.Header().Set("Content-Language", w.tag.String())
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	// the client is gone when it fails
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// localize translates the message of the error response, which has none of the fields of the other responses.
+func (w *localizedErrorWriter) localize(body []byte) ([]byte, bool) {
+	response := api.ErrorResponse{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&response); err != nil || response.Code == "" {
+		return nil, false
+	}
+
+	message, ok := w.catalog.Message(w.tag, response.Code)
+	if !ok {
+		return nil, false
+	}
+
+	response.Message = message
+
+	localized := &bytes.Buffer{}
+
+	if err := json.NewEncoder(localized).Encode(response); err != nil {
+		return nil, false
+	}
+
+	return localized.Bytes(), true
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// IdempotencyConflicts is implemented by repository.PostgresRepository.
+type IdempotencyConflicts interface {
+	RecordIdempotencyConflict(ctx context.Context, conflict *api.IdempotencyConflict) error
+	GetIdempotencyConflicts(ctx context.Context, idempotencyKey string, limit int) ([]*api.IdempotencyConflict, error)
+}
+
+// WithIdempotencyConflicts records the transfers rejected because their idempotency key was already used,
+// with the fingerprint of the request and its source, and serves them under /admin/idempotency-conflicts.
+func (r *APIServer) WithIdempotencyConflicts(auth middlewares.Middleware, conflicts IdempotencyConflicts) *APIServer {
+	r.adminAuth = auth
+	r.conflicts = conflicts
+
+	return r
+}
+
+func (r *APIServer) registerIdempotencyConflictEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.conflicts == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/idempotency-conflicts", r.adminAuth(handler.HandleGetIdempotencyConflicts))
+}
+
+// recordIdempotencyConflict records the transfer, the batch or the hold if it's been rejected for its idempotency key,
+// with the fingerprint of its request. The rejection is answered whether it's recorded or not.
+func (h *Handlers) recordIdempotencyConflict(r *http.Request, fingerprint func() string, idempotencyKey string, err error) {
+	if h.conflicts == nil ||
+		!errors.Is(err, api.ErrDuplicateTransaction) && !errors.Is(err, api.ErrTransferInProgress) {
+		return
+	}
+
+	// the client may be gone, the conflict is recorded regardless
+	ctx := context.WithoutCancel(r.Context())
+
+	conflict := &api.IdempotencyConflict{
+		IdempotencyKey: idempotencyKey,
+		Code:           api.CodeOf(err),
+		Fingerprint:    fingerprint(),
+		ClientIP:       clientIP(r),
+		APIKeyID:       middlewares.ClientKeyID(r),
+		RequestID:      middlewares.RequestID(ctx),
+	}
+
+	if errRecord := h.conflicts.RecordIdempotencyConflict(ctx, conflict); errRecord != nil {
+		h.logger.WarnContext(ctx, "failed to record idempotency conflict", slog.String("idempotency_key", idempotencyKey),
+			slog.Any("error", errRecord))
+	}
+}
+
+// HandleGetIdempotencyConflicts responds with the conflicts of the idempotency_key parameter, or of every key, the latest first.
+func (h *Handlers) HandleGetIdempotencyConflicts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	conflicts, err := h.conflicts.GetIdempotencyConflicts(ctx, strings.TrimSpace(r.URL.Query().Get("idempotency_key")), limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get idempotency conflicts", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(conflicts)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// BalanceRebuilder is implemented by repository.PostgresRepository.
+type BalanceRebuilder interface {
+	RebuildBalance(ctx context.Context, accountID, currency string) (*api.BalanceRebuild, error)
+}
+
+// WithBalanceRebuilds serves the rebuild of the balances from the ledger under /admin/accounts, to recover from the drifts
+// reported by the ledger check.
+func (r *APIServer) WithBalanceRebuilds(auth middlewares.Middleware, rebuilder BalanceRebuilder) *APIServer {
+	r.adminAuth = auth
+	r.rebuilder = rebuilder
+
+	return r
+}
+
+func (r *APIServer) registerBalanceRebuildEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.rebuilder == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/accounts/{accountId}/{currency}/rebuild-balance", r.adminAuth(handler.HandleRebuildBalance))
+}
+
+// HandleRebuildBalance recomputes the balance of the account in the currency from its ledger entries,
+// and responds with the previous and the rebuilt balances.
+func (h *Handlers) HandleRebuildBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rebuild, err := h.rebuilder.RebuildBalance(ctx, r.PathValue("accountId"), r.PathValue("currency"))
+
+	switch {
+	case errors.Is(err, api.ErrInvalidAccountID), errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case errors.Is(err, api.ErrAccountNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to rebuild balance", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	// a correction rewrites a balance outside of the ledger, it's kept in the logs with the operator
+	logger := h.logger.InfoContext
+	if !rebuild.Drift.IsZero() {
+		logger = h.logger.WarnContext
+	}
+
+	logger(ctx, "balance rebuilt", slog.String("account_id", rebuild.AccountID), slog.String("currency", rebuild.Currency),
+		slog.String("previous_balance", rebuild.PreviousBalance.String()), slog.String("balance", rebuild.Balance.String()),
+		slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(rebuild)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
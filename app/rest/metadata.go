@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Metadata is implemented by repository.PostgresRepository.
+type Metadata interface {
+	AmendTransactionMetadata(ctx context.Context, txID string, request *api.AmendMetadataRequest, operator string) (*api.TransactionMetadata, error)
+	GetTransactionMetadata(ctx context.Context, txID string) (*api.TransactionMetadata, error)
+}
+
+// WithTransactionMetadata serves the amendments of the remarks and the reference of the ledger entries
+// under /transactions/{txId}/metadata. The operators are identified by their admin key, which is recorded with every edit.
+func (r *APIServer) WithTransactionMetadata(auth middlewares.Middleware, metadata Metadata) *APIServer {
+	r.adminAuth = auth
+	r.metadata = metadata
+
+	return r
+}
+
+func (r *APIServer) registerMetadataEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.metadata == nil {
+		return
+	}
+
+	// not cached, the edits must be read back right away
+	mux.HandleFunc("GET /transactions/{txId}/metadata", r.adminAuth(handler.HandleGetTransactionMetadata))
+	mux.HandleFunc("PATCH /transactions/{txId}/metadata", r.adminAuth(handler.HandleAmendTransactionMetadata))
+}
+
+// HandleAmendTransactionMetadata amends the fields in the request, invalidates the cached ledger entry,
+// and responds with the metadata and their edits.
+func (h *Handlers) HandleAmendTransactionMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	request := &api.AmendMetadataRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || (request.Remarks == nil && request.Reference == nil) {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	if request.Remarks != nil {
+		if err = h.remarks.Validate(strings.TrimSpace(*request.Remarks)); err != nil {
+			h.HandleError(w, http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	metadata, err := h.metadata.AmendTransactionMetadata(ctx, r.PathValue("txId"), request, operator)
+	if h.handleMetadataError(ctx, w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "transaction metadata amended", slog.String("tx_id", metadata.TxID), slog.String("operator", operator))
+
+	// the cached entry would serve the remarks before the amendment
+	h.invalidateTransaction(ctx, metadata.TxID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(metadata)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetTransactionMetadata responds with the metadata of the ledger entry and the history of their edits.
+func (h *Handlers) HandleGetTransactionMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	metadata, err := h.metadata.GetTransactionMetadata(ctx, r.PathValue("txId"))
+	if h.handleMetadataError(ctx, w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(metadata)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleMetadataError(ctx context.Context, w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, api.ErrTransactionNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return true
+	case errors.Is(err, api.ErrInvalidMetadata):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return true
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to read or amend transaction metadata", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return true
+	default:
+		return false
+	}
+}
@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -32,34 +34,64 @@ func (h *Handlers) HandleDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the fields are validated as they're posted, without their surrounding spaces
+	request.ToAccountID = strings.TrimSpace(request.ToAccountID)
+	request.Currency = strings.TrimSpace(request.Currency)
+
+	if !h.resolveAccount(w, r, &request.ToAccountID, request.AccountAlias) {
+		return
+	}
+
 	if request.ToAccountID == "" || request.Currency == "" || request.Amount.IsZero() || request.Amount.IsNegative() {
 		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
 
 		return
 	}
 
-	if strings.EqualFold(request.ToAccountID, api.CompanyAccountID) {
+	if err = api.ValidateAmount(request.Amount); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if h.isCompanyAccount(request.ToAccountID) {
 		h.HandleError(w, http.StatusBadRequest, api.ErrCompanyAccount)
 
 		return
 	}
 
+	if err = h.validateAccountIDs(ctx, request.ToAccountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
 	payload := &api.TransferRequest{
-		FromAccountID: api.CompanyAccountID,
+		FromAccountID: h.companyAccountID,
 		ToAccountID:   strings.TrimSpace(request.ToAccountID),
 		Currency:      strings.TrimSpace(request.Currency),
 		Amount:        request.Amount,
 		Remarks:       strings.TrimSpace(request.Remarks),
+		Tags:          request.Tags,
+	}
+
+	if err = h.remarks.Validate(payload.Remarks); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
 	}
 
 	// create double entry transaction, returns both transaction result
 	tx, err := h.repo.Transfer(ctx, payload, idempotencyKey)
+	h.recordIdempotencyConflict(r, payload.Fingerprint, idempotencyKey, err)
 
 	handled := h.HandleTransferError(w, err)
 	if handled {
 		return
 	}
 
+	h.rounding.Transactions(tx...)
+
 	// return the transaction receipt containing the relevant transfer details
 	for _, t := range tx {
 		if strings.EqualFold(string(t.Type), string(api.CREDIT)) {
@@ -71,7 +103,7 @@ func (h *Handlers) HandleDeposit(w http.ResponseWriter, r *http.Request) {
 				// we can't respond with an error payload anymore, because the headers have already been sent
 				// headers must be written before the content, so if writing the content fails, we can't go back
 				// just log it
-				h.logger.Printf("encoding error: %v", err)
+				h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 			}
 
 			return
@@ -102,34 +134,64 @@ func (h *Handlers) HandleWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the fields are validated as they're posted, without their surrounding spaces
+	request.FromAccountID = strings.TrimSpace(request.FromAccountID)
+	request.Currency = strings.TrimSpace(request.Currency)
+
+	if !h.resolveAccount(w, r, &request.FromAccountID, request.AccountAlias) {
+		return
+	}
+
 	if request.FromAccountID == "" || request.Currency == "" || request.Amount.IsZero() || request.Amount.IsNegative() {
 		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
 
 		return
 	}
 
-	if strings.EqualFold(request.FromAccountID, api.CompanyAccountID) {
+	if err = api.ValidateAmount(request.Amount); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if h.isCompanyAccount(request.FromAccountID) {
 		h.HandleError(w, http.StatusBadRequest, api.ErrCompanyAccount)
 
 		return
 	}
 
+	if err = h.validateAccountIDs(ctx, request.FromAccountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
 	payload := &api.TransferRequest{
 		FromAccountID: strings.TrimSpace(request.FromAccountID),
-		ToAccountID:   api.CompanyAccountID,
+		ToAccountID:   h.companyAccountID,
 		Currency:      strings.TrimSpace(request.Currency),
 		Amount:        request.Amount,
 		Remarks:       strings.TrimSpace(request.Remarks),
+		Tags:          request.Tags,
+	}
+
+	if err = h.remarks.Validate(payload.Remarks); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
 	}
 
 	// create double entry transaction, returns both transaction result
 	tx, err := h.repo.Transfer(ctx, payload, idempotencyKey)
+	h.recordIdempotencyConflict(r, payload.Fingerprint, idempotencyKey, err)
 
 	handled := h.HandleTransferError(w, err)
 	if handled {
 		return
 	}
 
+	h.rounding.Transactions(tx...)
+
 	// return the transaction receipt containing the relevant transfer details
 	for _, t := range tx {
 		if strings.EqualFold(string(t.Type), string(api.DEBIT)) {
@@ -141,7 +203,7 @@ func (h *Handlers) HandleWithdrawal(w http.ResponseWriter, r *http.Request) {
 				// we can't respond with an error payload anymore, because the headers have already been sent
 				// headers must be written before the content, so if writing the content fails, we can't go back
 				// just log it
-				h.logger.Printf("encoding error: %v", err)
+				h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 			}
 
 			return
@@ -173,50 +235,100 @@ func (h *Handlers) HandleTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if request.FromAccountID == "" || request.ToAccountID == "" || request.Currency == "" || request.Amount.IsZero() || request.Amount.IsNegative() {
-		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
-
+	payload, err := h.transferPayload(ctx, request)
+	if h.HandleTransferError(w, err) {
 		return
 	}
 
-	if strings.EqualFold(request.FromAccountID, request.ToAccountID) {
-		h.HandleError(w, http.StatusBadRequest, api.ErrSameAccountIDs)
+	// posted later by the worker, bypassing the reservations of the idempotency keys
+	if h.prefersAsync(r) {
+		h.enqueueTransfer(w, r, payload, idempotencyKey)
 
 		return
 	}
 
-	if strings.EqualFold(request.FromAccountID, api.CompanyAccountID) || strings.EqualFold(request.ToAccountID, api.CompanyAccountID) {
-		h.HandleError(w, http.StatusBadRequest, api.ErrCompanyAccount)
+	// create double entry transaction, returns both transaction result
+	tx, err := h.repo.Transfer(ctx, payload, idempotencyKey)
+	h.recordIdempotencyConflict(r, payload.Fingerprint, idempotencyKey, err)
 
+	handled := h.HandleTransferError(w, err)
+	if handled {
 		return
 	}
 
-	// makes sure we compose and pass only the sanitized payload
-	payload := &api.TransferRequest{
-		FromAccountID: strings.TrimSpace(request.FromAccountID),
-		ToAccountID:   strings.TrimSpace(request.ToAccountID),
-		Currency:      strings.TrimSpace(request.Currency),
-		Amount:        request.Amount,
-		Remarks:       strings.TrimSpace(request.Remarks),
-	}
+	h.rounding.Transactions(tx...)
 
-	// create double entry transaction, returns both transaction result
-	tx, err := h.repo.Transfer(ctx, payload, idempotencyKey)
+	// return the transfer receipt pairing both legs of the double entry
+	receipt, err := api.NewTransferReceipt(payload, idempotencyKey, tx)
+	if err != nil {
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
 
-	handled := h.HandleTransferError(w, err)
-	if handled {
 		return
 	}
 
-	// return the transaction receipt containing the relevant transfer details
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 
-	err = json.NewEncoder(w).Encode(tx)
+	err = json.NewEncoder(w).Encode(receipt)
 	if err != nil {
 		// we can't respond with an error payload anymore, because the headers have already been sent
 		// headers must be written before the content, so if writing the content fails, we can't go back
 		// just log it
-		h.logger.Printf("encoding error: %v", err)
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// transferPayload validates a transfer, posted alone or in a batch, held or scheduled, and returns its sanitized payload.
+// Its errors are responded by HandleTransferError.
+func (h *Handlers) transferPayload(ctx context.Context, request *api.TransferRequest) (*api.TransferRequest, error) {
+	// the fields are validated as they're posted, without their surrounding spaces
+	request.FromAccountID = strings.TrimSpace(request.FromAccountID)
+	request.ToAccountID = strings.TrimSpace(request.ToAccountID)
+	request.Currency = strings.TrimSpace(request.Currency)
+	request.ToCurrency = strings.TrimSpace(request.ToCurrency)
+
+	if err := h.resolveAlias(ctx, &request.FromAccountID, request.FromAlias); err != nil {
+		return nil, err
 	}
+
+	if err := h.resolveAlias(ctx, &request.ToAccountID, request.ToAlias); err != nil {
+		return nil, err
+	}
+
+	if request.FromAccountID == "" || request.ToAccountID == "" || request.Currency == "" || request.Amount.IsZero() || request.Amount.IsNegative() {
+		return nil, api.ErrInvalidRequest
+	}
+
+	if err := api.ValidateAmount(request.Amount); err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	if strings.EqualFold(request.FromAccountID, request.ToAccountID) {
+		return nil, api.ErrSameAccountIDs
+	}
+
+	if h.isCompanyAccount(request.FromAccountID) || h.isCompanyAccount(request.ToAccountID) {
+		return nil, api.ErrCompanyAccount
+	}
+
+	// makes sure we compose and pass only the sanitized payload
+	payload := &api.TransferRequest{
+		FromAccountID: request.FromAccountID,
+		ToAccountID:   request.ToAccountID,
+		Currency:      request.Currency,
+		ToCurrency:    request.ToCurrency,
+		Amount:        request.Amount,
+		Remarks:       strings.TrimSpace(request.Remarks),
+		Tags:          request.Tags,
+	}
+
+	if err := h.validateAccountIDs(ctx, payload.FromAccountID, payload.ToAccountID); err != nil {
+		return nil, err
+	}
+
+	if err := h.remarks.Validate(payload.Remarks); err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are returned as is
+	}
+
+	return payload, nil
 }
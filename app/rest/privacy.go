@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// PersonalData is implemented by repository.PostgresRepository.
+type PersonalData interface {
+	ExportPersonalData(ctx context.Context, accountID string) (*api.PersonalData, error)
+	RequestErasure(ctx context.Context, accountID, operator string) (*api.ErasureRequest, error)
+	GetErasure(ctx context.Context, accountID string) (*api.ErasureRequest, error)
+}
+
+// WithPersonalData serves the data subject requests under /admin/accounts, i.e. the export of the personal data
+// of an account and its erasure. The operators are identified by their admin key, which is recorded with the erasure.
+func (r *APIServer) WithPersonalData(auth middlewares.Middleware, personalData PersonalData) *APIServer {
+	r.adminAuth = auth
+	r.personalData = personalData
+
+	return r
+}
+
+func (r *APIServer) registerPersonalDataEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.personalData == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/accounts/{accountId}/personal-data", r.adminAuth(handler.HandleExportPersonalData))
+	mux.HandleFunc("POST /admin/accounts/{accountId}/erasure", r.adminAuth(handler.HandleRequestErasure))
+	mux.HandleFunc("GET /admin/accounts/{accountId}/erasure", r.adminAuth(handler.HandleGetErasure))
+}
+
+// HandleExportPersonalData responds with everything kept about the account, with the remarks decrypted.
+func (h *Handlers) HandleExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	data, err := h.personalData.ExportPersonalData(ctx, r.PathValue("accountId"))
+
+	switch {
+	case errors.Is(err, api.ErrInvalidAccountID):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case errors.Is(err, api.ErrAccountNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to export personal data", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "personal data exported", slog.String("account_id", data.AccountID),
+		slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(data)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleRequestErasure shreds the data key of the account, schedules the erasure of its other data,
+// and purges its cached transactions with WithCacheInvalidation.
+func (h *Handlers) HandleRequestErasure(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	erasure, err := h.personalData.RequestErasure(ctx, r.PathValue("accountId"), operator)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidAccountID):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case errors.Is(err, api.ErrProtectedAccount):
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to request erasure", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "erasure requested", slog.String("id", erasure.ID),
+		slog.String("account_id", erasure.AccountID), slog.String("operator", operator))
+
+	// the cached transactions carry the remarks decrypted before the data key was shredded
+	if h.cache != nil {
+		if _, err = InvalidateTransactions(ctx, h.cache, erasure.AccountID); err != nil {
+			// purged again by the worker once the erasure completes
+			h.logger.ErrorContext(ctx, "failed to purge the cache of the erased account", slog.String("account_id", erasure.AccountID),
+				slog.Any("error", err))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	err = json.NewEncoder(w).Encode(erasure)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetErasure responds with the latest erasure of the account and its status.
+func (h *Handlers) HandleGetErasure(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	erasure, err := h.personalData.GetErasure(ctx, r.PathValue("accountId"))
+	if errors.Is(err, api.ErrErasureNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get erasure", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(erasure)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
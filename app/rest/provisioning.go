@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// AccountProvisioning is implemented by repository.PostgresRepository.
+type AccountProvisioning interface {
+	OpenAccount(ctx context.Context, accountID, currency string) (*api.Account, error)
+}
+
+// WithAccountProvisioning serves the opening of the accounts under /admin/accounts, for the provisioning policies
+// that don't let the transfers open them.
+func (r *APIServer) WithAccountProvisioning(auth middlewares.Middleware, provisioning AccountProvisioning) *APIServer {
+	r.adminAuth = auth
+	r.provisioning = provisioning
+
+	return r
+}
+
+func (r *APIServer) registerProvisioningEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.provisioning == nil {
+		return
+	}
+
+	mux.HandleFunc("PUT /admin/accounts/{accountId}/{currency}", r.adminAuth(handler.HandleOpenAccount))
+}
+
+// HandleOpenAccount opens the account in the currency, and responds with its balance. Opening it again is a no-op.
+func (h *Handlers) HandleOpenAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := r.PathValue("accountId")
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	account, err := h.provisioning.OpenAccount(ctx, accountID, r.PathValue("currency"))
+
+	switch {
+	case errors.Is(err, api.ErrCompanyAccount),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to open account", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(account)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// PreferHeader with respondAsync queues the transfer instead of posting it, see WithTransferQueue.
+	PreferHeader = "Prefer"
+	respondAsync = "respond-async"
+)
+
+// TransferQueue is implemented by repository.PostgresRepository.
+type TransferQueue interface {
+	EnqueueTransfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.QueuedTransfer, error)
+	GetQueuedTransfer(ctx context.Context, id string) (*api.QueuedTransfer, error)
+}
+
+// WithTransferQueue lets POST /transfer queue the transfer with Prefer: respond-async, for the bursty producers that can't
+// wait on the locks of the accounts. It's answered with 202 Accepted and the queued transfer, whose status is polled
+// under GET /transfers/{id}/status until a worker has posted it.
+func (r *APIServer) WithTransferQueue(queue TransferQueue) *APIServer {
+	r.queue = queue
+
+	return r
+}
+
+func (r *APIServer) registerTransferQueueEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.queue == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /transfers/{id}/status", handler.HandleGetQueuedTransfer)
+}
+
+// prefersAsync reports whether the request prefers to be answered before it's processed, see RFC 7240.
+func (h *Handlers) prefersAsync(r *http.Request) bool {
+	if h.queue == nil {
+		return false
+	}
+
+	for _, header := range r.Header.Values(PreferHeader) {
+		for _, preference := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(preference), respondAsync) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// enqueueTransfer queues the validated transfer, and responds with the queued transfer.
+func (h *Handlers) enqueueTransfer(w http.ResponseWriter, r *http.Request, payload *api.TransferRequest, idempotencyKey string) {
+	ctx := r.Context()
+
+	queued, err := h.queue.EnqueueTransfer(ctx, payload, idempotencyKey)
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	w.Header().Set("Preference-Applied", respondAsync)
+	w.Header().Set("Location", "/transfers/"+queued.ID+"/status")
+
+	h.writeQueuedTransfer(ctx, w, http.StatusAccepted, queued)
+}
+
+// HandleGetQueuedTransfer responds with the status of the queued transfer, and its error once failed or held.
+func (h *Handlers) HandleGetQueuedTransfer(w http.ResponseWriter, r *http.Request) {
+	queued, err := h.queue.GetQueuedTransfer(r.Context(), r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.writeQueuedTransfer(r.Context(), w, http.StatusOK, queued)
+}
+
+func (h *Handlers) writeQueuedTransfer(ctx context.Context, w http.ResponseWriter, status int, queued *api.QueuedTransfer) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(queued)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitKeyPrefix namespaces the counters of the windows in Redis.
+const rateLimitKeyPrefix = "ratelimit:"
+
+// RateCounter is implemented by the Redis client.
+type RateCounter interface {
+	Incr(ctx context.Context, key string) *redis.IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+}
+
+// WithRateLimit allows every client, by IP, up to limit requests in each window, and answers the others with a 429.
+// The counters are kept in Redis, so the limit is shared by the replicas. The responses carry the rate limit headers,
+// see api.RateLimitRemainingHeader. The probes aren't limited, and the requests are let through when Redis is down.
+func (r *APIServer) WithRateLimit(counter RateCounter, limit int64, window time.Duration) *APIServer {
+	r.rateLimit = &rateLimit{counter: counter, limit: limit, window: window}
+
+	return r
+}
+
+type rateLimit struct {
+	counter RateCounter
+	limit   int64
+	window  time.Duration
+}
+
+// rateLimited counts the request in the fixed window of its client, before serving it.
+func (h *Handlers) rateLimited(limit *rateLimit, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the orchestrators must always reach the probes
+		if r.URL.Path == "/health" || r.URL.Path == "/readyz" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		ctx := r.Context()
+		start := time.Now().Truncate(limit.window)
+		reset := start.Add(limit.window)
+
+		count, err := limit.count(ctx, rateLimitKeyPrefix+clientIP(r)+":"+strconv.FormatInt(start.Unix(), 10))
+		if err != nil {
+			// a Redis outage shouldn't take the API down with it
+			h.logger.WarnContext(ctx, "failed to count the request, not rate limited", slog.Any("error", err))
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		setRateLimit(w, limit.limit, limit.limit-count, reset)
+
+		if count > limit.limit {
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+			h.HandleError(w, http.StatusTooManyRequests, api.ErrRateLimited)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// count increments the counter of the window, which expires with it.
+func (l *rateLimit) count(ctx context.Context, key string) (int64, error) {
+	count, err := l.counter.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+
+	// the first request of the window sets its expiry
+	if count == 1 {
+		if err := l.counter.Expire(ctx, key, l.window).Err(); err != nil {
+			return 0, fmt.Errorf("failed to expire %s: %w", key, err)
+		}
+	}
+
+	return count, nil
+}
+
+// clientIP is the address of the peer, i.e. the proxy in front of the server, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
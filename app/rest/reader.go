@@ -3,11 +3,51 @@ package rest
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/devshark/wallet/api"
 )
 
+const (
+	// includeChildren adds the sub-accounts to the account balance.
+	includeChildren = "children"
+	// includeStats adds the number of transactions and the first and last activity to the account balance.
+	includeStats = "stats"
+
+	// the page size of the transactions read by cursor, when the limit isn't set
+	defaultTransactionsLimit = 50
+	maxTransactionsLimit     = 500
+)
+
+// timeZone is the location of the ?tz= parameter, an IANA name like Asia/Manila, to render the times in.
+// The times are in UTC without it.
+func timeZone(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", api.ErrInvalidTimeZone, name)
+	}
+
+	return location, nil
+}
+
+// inTimeZone renders the times of the transactions in the location.
+func inTimeZone(location *time.Location, transactions ...*api.Transaction) {
+	for _, tx := range transactions {
+		tx.Time = tx.Time.In(location)
+	}
+}
+
 func (h *Handlers) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -26,7 +66,31 @@ func (h *Handlers) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	account, err := h.repo.GetAccountBalance(ctx, currency, accountID)
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	var (
+		account *api.Account
+		err     error
+	)
+
+	// the roll-up of the sub-accounts, i.e. a merchant's sub-balances
+	switch r.URL.Query().Get("include") {
+	case "":
+		account, err = h.repo.GetAccountBalance(ctx, currency, accountID)
+	case includeChildren:
+		account, err = h.repo.GetAccountTree(ctx, currency, accountID)
+	case includeStats:
+		account, err = h.repo.GetAccountStats(ctx, currency, accountID)
+	default:
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
 	if errors.Is(err, api.ErrAccountNotFound) {
 		h.HandleError(w, http.StatusNotFound, api.ErrAccountNotFound)
 
@@ -34,12 +98,14 @@ func (h *Handlers) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.logger.Printf("failed to get account balance: %v\n", err)
+		h.logger.ErrorContext(ctx, "failed to get account balance", slog.Any("error", err))
 		h.HandleError(w, http.StatusInternalServerError, api.ErrFailedToGetTransaction)
 
 		return
 	}
 
+	h.rounding.Account(account)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -48,7 +114,98 @@ func (h *Handlers) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
 		// we can't respond with an error payload anymore, because the headers have already been sent
 		// headers must be written before the content, so if writing the content fails, we can't go back
 		// just log it
-		h.logger.Printf("encoding error: %v", err)
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// GetAccountSummary responds with the balances of the account in every currency it holds, by currency,
+// so the clients don't request them one currency at a time.
+func (h *Handlers) GetAccountSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := r.PathValue("accountId")
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	summary, err := h.repo.GetAccountSummary(ctx, accountID)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidAccountID):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case errors.Is(err, api.ErrAccountNotFound):
+		h.HandleError(w, http.StatusNotFound, api.ErrAccountNotFound)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to get account summary", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	for _, account := range summary.Balances {
+		h.rounding.Account(account)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(summary)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleQueryBalances responds with the balances of the accounts in the query, read in one round trip,
+// and lists the accounts that don't exist.
+func (h *Handlers) HandleQueryBalances(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	query := &api.BalancesQuery{}
+
+	err := json.NewDecoder(r.Body).Decode(query)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	balances, err := h.repo.GetAccountBalances(ctx, query.Accounts)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidRequest), errors.Is(err, api.ErrInvalidAccountID), errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to get account balances", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	for _, account := range balances.Balances {
+		h.rounding.Account(account)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(balances)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 	}
 }
 
@@ -69,20 +226,83 @@ func (h *Handlers) GetTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	transactions, err := h.repo.GetTransactions(ctx, currency, accountID)
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	location, err := timeZone(r)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter, err := transactionFilter(query, location)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	var (
+		transactions []*api.Transaction
+		page         *api.TransactionPage
+		total        int
+	)
+
+	// ?limit=50&cursor=... reads a page, the list is returned whole without either
+	switch {
+	case query.Has("limit") || query.Has("cursor"):
+		limit, ok := transactionsLimit(query.Get("limit"))
+		if !ok {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		page, err = h.repo.GetTransactionsPage(ctx, currency, accountID, filter, query.Get("cursor"), limit)
+		if page != nil {
+			transactions, total = page.Transactions, page.TotalCount
+		}
+	case !filter.IsZero():
+		transactions, err = h.repo.FilterTransactions(ctx, currency, accountID, filter)
+	default:
+		transactions, err = h.repo.GetTransactions(ctx, currency, accountID)
+	}
+
 	if errors.Is(err, api.ErrTransactionNotFound) {
 		h.HandleError(w, http.StatusNotFound, api.ErrTransactionNotFound)
 
 		return
 	}
 
+	if errors.Is(err, api.ErrInvalidTag) || errors.Is(err, api.ErrInvalidTransactionFilter) || errors.Is(err, api.ErrInvalidCursor) {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
 	if err != nil {
-		h.logger.Printf("failed to get transactions: %v\n", err)
+		h.logger.ErrorContext(ctx, "failed to get transactions", slog.Any("error", err))
 		h.HandleError(w, http.StatusInternalServerError, api.ErrFailedToGetTransaction)
 
 		return
 	}
 
+	h.rounding.Transactions(transactions...)
+	inTimeZone(location, transactions...)
+
+	if page != nil {
+		setTotalCount(w, total)
+		setNextCursor(w, page.NextCursor)
+	} else {
+		setTotalCount(w, len(transactions))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -97,10 +317,50 @@ func (h *Handlers) GetTransactions(w http.ResponseWriter, r *http.Request) {
 		// we can't respond with an error payload anymore, because the headers have already been sent
 		// headers must be written before the content, so if writing the content fails, we can't go back
 		// just log it
-		h.logger.Printf("encoding error: %v", err)
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 	}
 }
 
+// transactionFilter parses the filter of the transactions: ?tag=food&tag=travel keeps the transactions with all of the tags,
+// ?from= and ?to= the ones posted from from included to to excluded, RFC 3339 times or dates at midnight in the time zone,
+// and ?type=DEBIT or CREDIT the entries of that type.
+func transactionFilter(query url.Values, location *time.Location) (api.TransactionFilter, error) {
+	filter := api.TransactionFilter{
+		Tags: query["tag"],
+		Type: api.DebitOrCreditType(strings.ToUpper(strings.TrimSpace(query.Get("type")))),
+	}
+
+	var err error
+
+	if value := query.Get("from"); value != "" {
+		if filter.From, err = parseDateOrTime(value, location); err != nil {
+			return filter, fmt.Errorf("%w: from: %w", api.ErrInvalidTransactionFilter, err)
+		}
+	}
+
+	if value := query.Get("to"); value != "" {
+		if filter.To, err = parseDateOrTime(value, location); err != nil {
+			return filter, fmt.Errorf("%w: to: %w", api.ErrInvalidTransactionFilter, err)
+		}
+	}
+
+	return filter, filter.Validate() //nolint:wrapcheck // the domain errors are answered as is
+}
+
+// transactionsLimit parses the page size of the transactions, the default one when empty.
+func transactionsLimit(value string) (int, bool) {
+	if value == "" {
+		return defaultTransactionsLimit, true
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxTransactionsLimit {
+		return 0, false
+	}
+
+	return limit, true
+}
+
 func (h *Handlers) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	txID := r.PathValue("txId")
@@ -111,6 +371,13 @@ func (h *Handlers) GetTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	location, err := timeZone(r)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
 	tx, err := h.repo.GetTransaction(ctx, txID)
 	if errors.Is(err, api.ErrTransactionNotFound) {
 		h.HandleError(w, http.StatusNotFound, api.ErrTransactionNotFound)
@@ -119,12 +386,15 @@ func (h *Handlers) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err != nil {
-		h.logger.Printf("failed to get transaction: %v\n", err)
+		h.logger.ErrorContext(ctx, "failed to get transaction", slog.Any("error", err))
 		h.HandleError(w, http.StatusInternalServerError, api.ErrFailedToGetTransaction)
 
 		return
 	}
 
+	h.rounding.Transactions(tx)
+	inTimeZone(location, tx)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -133,6 +403,6 @@ func (h *Handlers) GetTransaction(w http.ResponseWriter, r *http.Request) {
 		// we can't respond with an error payload anymore, because the headers have already been sent
 		// headers must be written before the content, so if writing the content fails, we can't go back
 		// just log it
-		h.logger.Printf("encoding error: %v", err)
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 	}
 }
@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// ReadOnlyMode is implemented by readonly.Mode.
+type ReadOnlyMode interface {
+	ReadOnly(ctx context.Context) bool
+	Status(ctx context.Context) (*api.ReadOnlyStatus, error)
+	Set(ctx context.Context, enabled bool) (*api.ReadOnlyStatus, error)
+}
+
+// the POST endpoints that only read, served in read-only too, along with the switch itself
+//
+//nolint:gochecknoglobals // constant
+var readOnlyAllowed = map[string]bool{
+	"/balances/query":  true,
+	"/graphql":         true,
+	"/admin/read-only": true,
+}
+
+// WithReadOnly rejects the mutations with a 503 while the mode is read-only, i.e. during a failover,
+// the reads being served as usual. With the admin keys, the operators switch it under /admin/read-only.
+func (r *APIServer) WithReadOnly(mode ReadOnlyMode) *APIServer {
+	r.readOnly = mode
+
+	return r
+}
+
+func (r *APIServer) registerReadOnlyEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.readOnly == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/read-only", r.adminAuth(handler.HandleGetReadOnly))
+	mux.HandleFunc("PUT /admin/read-only", r.adminAuth(handler.HandleSetReadOnly))
+}
+
+// readOnlyGuard rejects the mutations while the wallet is read-only.
+func (h *Handlers) readOnlyGuard(mode ReadOnlyMode, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if readOnlyAllowed[r.URL.Path] || !mode.ReadOnly(r.Context()) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		h.HandleError(w, http.StatusServiceUnavailable, api.ErrReadOnly)
+	})
+}
+
+// HandleGetReadOnly responds with whether the wallet is read-only.
+func (h *Handlers) HandleGetReadOnly(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status, err := h.readOnly.Status(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get the read-only status", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.writeReadOnlyStatus(ctx, w, status)
+}
+
+// HandleSetReadOnly switches the wallet to read-only, or back to writable, for every server.
+func (h *Handlers) HandleSetReadOnly(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.SetReadOnlyRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	status, err := h.readOnly.Set(ctx, request.Enabled)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to switch the read-only mode", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.WarnContext(ctx, "read-only mode switched", slog.Bool("enabled", status.Enabled), slog.Bool("forced", status.Forced),
+		slog.String("operator", middlewares.Operator(ctx)))
+
+	h.writeReadOnlyStatus(ctx, w, status)
+}
+
+func (h *Handlers) writeReadOnlyStatus(ctx context.Context, w http.ResponseWriter, status *api.ReadOnlyStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(status)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Receipts is implemented by repository.PostgresRepository.
+type Receipts interface {
+	SubscribeReceipts(ctx context.Context, request *api.SubscribeReceiptsRequest) (*api.ReceiptSubscription, error)
+	GetReceiptSubscriptions(ctx context.Context, accountID string) ([]*api.ReceiptSubscription, error)
+	DeleteReceiptSubscription(ctx context.Context, id string) error
+	GetReceipts(ctx context.Context, accountID string, limit int) ([]*api.ReceiptNotification, error)
+}
+
+// WithReceipts serves the receipt subscriptions and the delivery status of the receipts under /admin/receipts.
+// The receipts are sent by the worker, so only the operators subscribe the accounts.
+func (r *APIServer) WithReceipts(auth middlewares.Middleware, receipts Receipts) *APIServer {
+	r.adminAuth = auth
+	r.receipts = receipts
+
+	return r
+}
+
+func (r *APIServer) registerReceiptEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.receipts == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/receipts/subscriptions", r.adminAuth(handler.HandleSubscribeReceipts))
+	mux.HandleFunc("GET /admin/receipts/subscriptions", r.adminAuth(handler.HandleGetReceiptSubscriptions))
+	mux.HandleFunc("DELETE /admin/receipts/subscriptions/{id}", r.adminAuth(handler.HandleDeleteReceiptSubscription))
+	mux.HandleFunc("GET /admin/receipts", r.adminAuth(handler.HandleGetReceipts))
+}
+
+// HandleSubscribeReceipts subscribes the account in the request to the receipts of its entries.
+func (h *Handlers) HandleSubscribeReceipts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.SubscribeReceiptsRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	subscription, err := h.receipts.SubscribeReceipts(ctx, request)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidReceiptDestination),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrCompanyAccount):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to subscribe receipts", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "receipts subscribed", slog.String("id", subscription.ID),
+		slog.String("account_id", subscription.AccountID), slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(subscription)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetReceiptSubscriptions responds with the receipt subscriptions of the account_id parameter.
+func (h *Handlers) HandleGetReceiptSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.URL.Query().Get("account_id"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidAccountID)
+
+		return
+	}
+
+	subscriptions, err := h.receipts.GetReceiptSubscriptions(ctx, accountID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get receipt subscriptions", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(subscriptions))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(subscriptions)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleDeleteReceiptSubscription unsubscribes, the receipts already queued aren't sent anymore.
+func (h *Handlers) HandleDeleteReceiptSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.receipts.DeleteReceiptSubscription(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrReceiptSubscriptionNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to delete receipt subscription", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetReceipts responds with the receipts of the account_id parameter and their delivery status, the latest first.
+func (h *Handlers) HandleGetReceipts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.URL.Query().Get("account_id"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidAccountID)
+
+		return
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	receipts, err := h.receipts.GetReceipts(ctx, accountID, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get receipts", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(receipts)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/reconciliation"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+const (
+	// the statements are uploaded as the raw request body
+	maxStatementBytes = 10 << 20
+
+	defaultReconciliationsLimit = 20
+	maxReconciliationsLimit     = 100
+)
+
+// Reconciler is implemented by reconciliation.Reconciler.
+type Reconciler interface {
+	Reconcile(ctx context.Context, format reconciliation.Format, fileName string, statement io.Reader) (*api.Reconciliation, error)
+	Get(ctx context.Context, id string) (*api.Reconciliation, error)
+	List(ctx context.Context, limit int) ([]*api.Reconciliation, error)
+}
+
+// WithReconciliations serves the reconciliation of the external statements under /admin/reconciliations.
+// They reveal the company account's ledger, so they're only served behind the given authentication.
+func (r *APIServer) WithReconciliations(auth middlewares.Middleware, reconciler Reconciler) *APIServer {
+	r.adminAuth = auth
+	r.reconciler = reconciler
+
+	return r
+}
+
+func (r *APIServer) registerAdminEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.reconciler == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/reconciliations", r.adminAuth(handler.HandleCreateReconciliation))
+	mux.HandleFunc("GET /admin/reconciliations", r.adminAuth(handler.HandleListReconciliations))
+	mux.HandleFunc("GET /admin/reconciliations/{id}", r.adminAuth(handler.HandleGetReconciliation))
+}
+
+// HandleCreateReconciliation reconciles the statement uploaded as the request body.
+// The format is given by the format query parameter, or guessed from the content type,
+// and the optional file_name query parameter is kept with the report.
+func (h *Handlers) HandleCreateReconciliation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	format, err := statementFormat(r)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxStatementBytes)
+
+	report, err := h.reconciler.Reconcile(ctx, format, r.URL.Query().Get("file_name"), body)
+
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		h.HandleError(w, http.StatusRequestEntityTooLarge, api.ErrInvalidStatement)
+
+		return
+	case errors.Is(err, api.ErrInvalidStatement):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to reconcile statement", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrReconciliationFailed)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleListReconciliations responds with the latest reports, without their items.
+func (h *Handlers) HandleListReconciliations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultReconciliationsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReconciliationsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	reports, err := h.reconciler.List(ctx, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list reconciliations", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(reports)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetReconciliation responds with a report and its items.
+func (h *Handlers) HandleGetReconciliation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	report, err := h.reconciler.Get(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrReconciliationNotFound) {
+		h.HandleError(w, http.StatusNotFound, api.ErrReconciliationNotFound)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get reconciliation", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+func statementFormat(r *http.Request) (reconciliation.Format, error) {
+	if value := r.URL.Query().Get("format"); value != "" {
+		return reconciliation.ParseFormat(value) //nolint:wrapcheck // a client error
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "text/csv":
+		return reconciliation.FormatCSV, nil
+	case "application/xml", "text/xml":
+		return reconciliation.FormatCAMT053, nil
+	default:
+		return reconciliation.ParseFormat(mediaType) //nolint:wrapcheck // a client error
+	}
+}
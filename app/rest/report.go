@@ -0,0 +1,217 @@
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+const reportCSV = "csv"
+
+//nolint:gochecknoglobals // constant
+var generalLedgerHeader = []string{"date", "account_id", "currency", "debits", "credits", "entries"}
+
+// Reports is implemented by repository.PostgresRepository.
+type Reports interface {
+	TrialBalance(ctx context.Context, currency string, asOf time.Time) (*api.TrialBalance, error)
+	ExportGeneralLedger(ctx context.Context, currency string, from, to time.Time, w api.GeneralLedgerWriter) error
+}
+
+// WithReports serves the accounting reports of the ledger under /admin/reports: the trial balance,
+// and the general ledger by day, streamed as CSV.
+func (r *APIServer) WithReports(auth middlewares.Middleware, reports Reports) *APIServer {
+	r.adminAuth = auth
+	r.reports = reports
+
+	return r
+}
+
+func (r *APIServer) registerReportEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.reports == nil {
+		return
+	}
+
+	// not cached, the current period changes
+	mux.HandleFunc("GET /admin/reports/trial-balance", r.adminAuth(handler.HandleTrialBalance))
+	mux.HandleFunc("GET /admin/reports/general-ledger", r.adminAuth(handler.HandleExportGeneralLedger))
+}
+
+// HandleTrialBalance responds with the debits and the credits of every account in the currency parameter,
+// or in all of them, over the ledger entries posted before the as_of parameter, now by default.
+// It's JSON, or CSV with the format parameter.
+func (h *Handlers) HandleTrialBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	format := strings.ToLower(strings.TrimSpace(query.Get("format")))
+	if format != "" && format != "json" && format != reportCSV {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	asOf := time.Now()
+
+	if value := query.Get("as_of"); value != "" {
+		parsed, err := parseDateOrTime(value, time.UTC)
+		if err != nil {
+			h.HandleError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", api.ErrInvalidReportPeriod, err))
+
+			return
+		}
+
+		asOf = parsed
+	}
+
+	report, err := h.reports.TrialBalance(ctx, query.Get("currency"), asOf)
+	if h.handleReportError(ctx, w, err) {
+		return
+	}
+
+	if format == reportCSV {
+		h.writeTrialBalanceCSV(ctx, w, report)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+func (h *Handlers) writeTrialBalanceCSV(ctx context.Context, w http.ResponseWriter, report *api.TrialBalance) {
+	setCSVAttachment(w, fmt.Sprintf("trial-balance-%s.csv", report.AsOf.Format(time.DateOnly)))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+
+	_ = writer.Write([]string{"account_id", "currency", "debits", "credits", "balance"})
+
+	for _, line := range report.Accounts {
+		_ = writer.Write([]string{line.AccountID, line.Currency, line.Debits.String(), line.Credits.String(), line.Balance.String()})
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		// the headers have already been sent, just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleExportGeneralLedger streams the debits and the credits of every account by day as CSV, in the currency parameter
+// or in all of them, from the from parameter included to the to parameter excluded, both RFC 3339 times or dates.
+func (h *Handlers) HandleExportGeneralLedger(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	from, fromErr := parseDateOrTime(query.Get("from"), time.UTC)
+	to, toErr := parseDateOrTime(query.Get("to"), time.UTC)
+
+	if fromErr != nil || toErr != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidReportPeriod)
+
+		return
+	}
+
+	// the headers are only sent with the first line, so the errors before can still be answered
+	export := &generalLedgerCSV{begin: func() *csv.Writer {
+		setCSVAttachment(w, fmt.Sprintf("general-ledger-%s-%s.csv", from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly)))
+		w.WriteHeader(http.StatusOK)
+
+		return csv.NewWriter(w)
+	}}
+
+	err := h.reports.ExportGeneralLedger(ctx, query.Get("currency"), from, to, export)
+	if err == nil {
+		err = export.End()
+	}
+
+	switch {
+	case err == nil:
+		return
+	case export.writer != nil:
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "failed to export general ledger", slog.Any("error", err))
+	default:
+		h.handleReportError(ctx, w, err)
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleReportError(ctx context.Context, w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, api.ErrInvalidReportPeriod),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+	default:
+		h.logger.ErrorContext(ctx, "failed to generate report", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+	}
+
+	return true
+}
+
+// generalLedgerCSV writes the lines of the general ledger as CSV, after the header row.
+type generalLedgerCSV struct {
+	begin  func() *csv.Writer
+	writer *csv.Writer
+}
+
+func (g *generalLedgerCSV) Line(line *api.GeneralLedgerLine) error {
+	if g.writer == nil {
+		g.writer = g.begin()
+
+		if err := g.writer.Write(generalLedgerHeader); err != nil {
+			return fmt.Errorf("failed to write general ledger: %w", err)
+		}
+	}
+
+	err := g.writer.Write([]string{line.Date.Format(time.DateOnly), line.AccountID, line.Currency,
+		line.Debits.String(), line.Credits.String(), strconv.FormatInt(line.Entries, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to write general ledger: %w", err)
+	}
+
+	return nil
+}
+
+// End flushes the lines, or writes the header row alone if there was none.
+func (g *generalLedgerCSV) End() error {
+	if g.writer == nil {
+		g.writer = g.begin()
+
+		_ = g.writer.Write(generalLedgerHeader)
+	}
+
+	g.writer.Flush()
+
+	return g.writer.Error() //nolint:wrapcheck // logged by the handler
+}
+
+func setCSVAttachment(w http.ResponseWriter, filename string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
@@ -2,16 +2,27 @@ package rest_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/remarks"
 	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
 	"github.com/devshark/wallet/app/rest"
+	"github.com/devshark/wallet/pkg/buildinfo"
+	"github.com/devshark/wallet/pkg/logging"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -91,6 +102,70 @@ func TestHandleHealthCheck(t *testing.T) {
 	})
 }
 
+func TestHandleReadiness(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	serve := func(handlers *rest.Handlers) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleReadiness).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		return rr
+	}
+
+	t.Run("Ready", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t)).
+			AddDegradedPinger("redis", func(context.Context) error { return nil })
+
+		rr := serve(handlers)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.JSONEq(t, `{"status":"ready"}`, rr.Body.String())
+		require.Equal(t, "0", expvar.Get("degraded").(*expvar.Map).Get("redis").String())
+	})
+
+	t.Run("Degraded", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t)).
+			AddPinger(func(context.Context) error { return nil }).
+			AddDegradedPinger("redis", func(context.Context) error { return errors.New("connection refused") })
+
+		rr := serve(handlers)
+		require.Equal(t, http.StatusOK, rr.Code, "still ready")
+		require.JSONEq(t, `{"status":"degraded","degraded":["redis"]}`, rr.Body.String())
+		require.Equal(t, "1", expvar.Get("degraded").(*expvar.Map).Get("redis").String())
+
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleHealthCheck).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+		require.Equal(t, http.StatusOK, rr.Code, "ignored by the health check")
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t)).
+			AddPinger(func(context.Context) error { return errors.New("db down") }).
+			AddDegradedPinger("redis", func(context.Context) error { return nil })
+
+		require.Equal(t, http.StatusServiceUnavailable, serve(handlers).Code)
+	})
+}
+
+func TestHandleVersion(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	handlers := rest.NewRestHandlers(repository.NewMockRepository(t))
+
+	req, err := http.NewRequest(http.MethodGet, "/version", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.HandleVersion).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response buildinfo.Info
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.Equal(t, buildinfo.Get(), response)
+}
+
 func TestGetAccountBalance(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -115,18 +190,116 @@ func TestGetAccountBalance(t *testing.T) {
 
 		handler.ServeHTTP(rr, req)
 
-		require.Equal(t, http.StatusOK, rr.Code)
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/account_balance.golden.json")
 
-		var response api.Account
-		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Include children", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		childTotal := decimal.NewFromInt(30)
+		total := decimal.NewFromInt(130)
+
+		mockRepo.EXPECT().GetAccountTree(mock.Anything, "USD", "merchant1").Return(&api.Account{
+			AccountID: "merchant1",
+			Currency:  "USD",
+			Balance:   decimal.NewFromInt(100),
+			Children: []*api.Account{{
+				AccountID:    "merchant1-shop",
+				Currency:     "USD",
+				Balance:      decimal.NewFromInt(30),
+				TotalBalance: &childTotal,
+			}},
+			TotalBalance: &total,
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?include=children", nil)
 		require.NoError(t, err)
-		require.Equal(t, mockAccount.AccountID, response.AccountID)
-		require.Equal(t, mockAccount.Currency, response.Currency)
-		require.True(t, mockAccount.Balance.Equal(response.Balance))
+		req.SetPathValue("accountId", "merchant1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetAccountBalance)
+
+		handler.ServeHTTP(rr, req)
+
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/account_tree.golden.json")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Include stats", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		count := int64(12)
+		first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		last := time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC)
+
+		mockRepo.EXPECT().GetAccountStats(mock.Anything, "USD", "user1").Return(&api.Account{
+			AccountID:        "user1",
+			Currency:         "USD",
+			Balance:          decimal.NewFromInt(100),
+			TransactionCount: &count,
+			FirstActivityAt:  &first,
+			LastActivityAt:   &last,
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?include=stats", nil)
+		require.NoError(t, err)
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetAccountBalance)
+
+		handler.ServeHTTP(rr, req)
+
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/account_stats.golden.json")
 
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Rounded", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo).
+			WithRounding(rounding.New(map[string]api.RoundingPolicy{"USD": {Mode: api.RoundHalfEven, Scale: 2}}))
+
+		mockRepo.EXPECT().GetAccountBalance(mock.Anything, "USD", "user1").Return(&api.Account{
+			AccountID: "user1",
+			Currency:  "USD",
+			Balance:   decimal.RequireFromString("100.125"),
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetAccountBalance)
+
+		handler.ServeHTTP(rr, req)
+
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/account_balance_rounded.golden.json")
+	})
+
+	t.Run("Unknown include", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t))
+
+		req, err := http.NewRequest(http.MethodGet, "/?include=parents", nil)
+		require.NoError(t, err)
+		req.SetPathValue("accountId", "merchant1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.GetAccountBalance).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := repository.NewMockRepository(t)
 		handlers := rest.NewRestHandlers(mockRepo)
@@ -215,6 +388,117 @@ func TestGetAccountBalance(t *testing.T) {
 	})
 }
 
+func TestQueryBalances(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		query := []api.AccountKey{{AccountID: "user1", Currency: "USD"}, {AccountID: "user2", Currency: "EUR"}}
+
+		mockRepo.EXPECT().GetAccountBalances(mock.Anything, query).Return(&api.Balances{
+			Balances: []*api.Account{{AccountID: "user1", Currency: "USD", Balance: decimal.NewFromInt(100)}},
+			NotFound: []api.AccountKey{{AccountID: "user2", Currency: "EUR"}},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/balances/query",
+			strings.NewReader(`{"accounts":[{"account":"user1","currency":"USD"},{"account":"user2","currency":"EUR"}]}`))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleQueryBalances).ServeHTTP(rr, req)
+
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/balances.golden.json")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetAccountBalances(mock.Anything, mock.Anything).Return(nil, api.ErrInvalidCurrency)
+
+		for _, body := range []string{`{"accounts":[{"account":"user1"}]}`, `not json`} {
+			req, err := http.NewRequest(http.MethodPost, "/balances/query", strings.NewReader(body))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.HandleQueryBalances).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code, body)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetAccountBalances(mock.Anything, mock.Anything).Return(nil, api.ErrUnhandledDatabaseError)
+
+		req, err := http.NewRequest(http.MethodPost, "/balances/query", strings.NewReader(`{"accounts":[{"account":"user1","currency":"USD"}]}`))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleQueryBalances).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestGetAccountSummary(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetAccountSummary(mock.Anything, "user1").Return(&api.AccountSummary{
+			AccountID: "user1",
+			Balances: []*api.Account{
+				{AccountID: "user1", Currency: "EUR", Balance: decimal.NewFromInt(20)},
+				{AccountID: "user1", Currency: "USD", Balance: decimal.NewFromInt(100)},
+			},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/account/user1", nil)
+		require.NoError(t, err)
+		req.SetPathValue("accountId", "user1")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.GetAccountSummary).ServeHTTP(rr, req)
+
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/account_summary.golden.json")
+	})
+
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"Invalid", api.ErrInvalidAccountID, http.StatusBadRequest},
+		{"Not found", api.ErrAccountNotFound, http.StatusNotFound},
+		{"Failed", api.ErrUnhandledDatabaseError, http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := repository.NewMockRepository(t)
+			handlers := rest.NewRestHandlers(mockRepo)
+
+			mockRepo.EXPECT().GetAccountSummary(mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			req, err := http.NewRequest(http.MethodGet, "/account/user1", nil)
+			require.NoError(t, err)
+			req.SetPathValue("accountId", "user1")
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.GetAccountSummary).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+		})
+	}
+}
+
 func TestGetTransactions(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -223,8 +507,8 @@ func TestGetTransactions(t *testing.T) {
 		handlers := rest.NewRestHandlers(mockRepo)
 
 		mockTxs := []*api.Transaction{
-			{TxID: "tx1", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(50.00)},
-			{TxID: "tx2", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(25.00)},
+			{TxID: "tx1", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(50.00), GroupID: "deposit-1"},
+			{TxID: "tx2", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(25.00), GroupID: "deposit-2"},
 		}
 		mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(mockTxs, nil)
 
@@ -239,25 +523,50 @@ func TestGetTransactions(t *testing.T) {
 
 		handler.ServeHTTP(rr, req)
 
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/transactions.golden.json")
+		require.Equal(t, "2", rr.Header().Get(api.TotalCountHeader))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(nil, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
 		require.Equal(t, http.StatusOK, rr.Code)
 
 		var response []*api.Transaction
 		err = json.Unmarshal(rr.Body.Bytes(), &response)
 		require.NoError(t, err)
-		require.Len(t, response, 2)
-		require.Equal(t, mockTxs[0].TxID, response[0].TxID)
-		require.Equal(t, mockTxs[1].TxID, response[1].TxID)
+		require.Len(t, response, 0)
+		require.Equal(t, "0", rr.Header().Get(api.TotalCountHeader))
 
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("Empty", func(t *testing.T) {
+	t.Run("Tags", func(t *testing.T) {
 		mockRepo := repository.NewMockRepository(t)
 		handlers := rest.NewRestHandlers(mockRepo)
 
-		mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(nil, nil)
+		mockTxs := []*api.Transaction{
+			{TxID: "tx1", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(50.00), Tags: []string{"food", "travel"}},
+		}
+		mockRepo.EXPECT().FilterTransactions(mock.Anything, "USD", "user1", api.TransactionFilter{Tags: []string{"food", "travel"}}).Return(mockTxs, nil)
 
-		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		req, err := http.NewRequest(http.MethodGet, "/?tag=food&tag=travel", nil)
 		require.NoError(t, err)
 
 		req.SetPathValue("accountId", "user1")
@@ -273,11 +582,204 @@ func TestGetTransactions(t *testing.T) {
 		var response []*api.Transaction
 		err = json.Unmarshal(rr.Body.Bytes(), &response)
 		require.NoError(t, err)
-		require.Len(t, response, 0)
+		require.Len(t, response, 1)
+		require.Equal(t, []string{"food", "travel"}, response[0].Tags)
 
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Period and type", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		manila, err := time.LoadLocation("Asia/Manila")
+		require.NoError(t, err)
+
+		mockRepo.EXPECT().FilterTransactions(mock.Anything, "USD", "user1", mock.MatchedBy(func(filter api.TransactionFilter) bool {
+			// the dates are midnight in the time zone of the request
+			return filter.Type == api.DEBIT && filter.Tags == nil &&
+				filter.From.Equal(time.Date(2024, 7, 1, 0, 0, 0, 0, manila)) &&
+				filter.To.Equal(time.Date(2024, 7, 2, 12, 0, 0, 0, time.UTC))
+		})).Return([]*api.Transaction{{TxID: "tx1", Type: api.DEBIT}}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?from=2024-07-01&to=2024-07-02T12:00:00Z&type=debit&tz=Asia/Manila", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"tx1"`)
+	})
+
+	t.Run("Invalid filter", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		for _, query := range []string{"type=REFUND", "from=yesterday", "to=2024-13-01", "from=2024-07-02&to=2024-07-01", "from=2024-07-01&to=2024-07-01"} {
+			req, err := http.NewRequest(http.MethodGet, "/?"+query, nil)
+			require.NoError(t, err)
+
+			req.SetPathValue("accountId", "user1")
+			req.SetPathValue("currency", "USD")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(handlers.GetTransactions)
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code, query)
+			require.Contains(t, rr.Body.String(), string(api.CodeInvalidTransactionFilter), query)
+		}
+	})
+
+	t.Run("Page", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		page := &api.TransactionPage{
+			Transactions: []*api.Transaction{{TxID: "tx2", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(50.00)}},
+			TotalCount:   3,
+			NextCursor:   "next",
+		}
+		mockRepo.EXPECT().GetTransactionsPage(mock.Anything, "USD", "user1", api.TransactionFilter{Tags: []string{"food"}}, "previous", 1).Return(page, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?limit=1&cursor=previous&tag=food", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "3", rr.Header().Get(api.TotalCountHeader))
+		require.Equal(t, "next", rr.Header().Get(api.NextCursorHeader))
+
+		var response []*api.Transaction
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Len(t, response, 1)
+		require.Equal(t, "tx2", response[0].TxID)
+	})
+
+	t.Run("First page by default", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetTransactionsPage(mock.Anything, "USD", "user1", api.TransactionFilter{}, "", 50).
+			Return(&api.TransactionPage{TotalCount: 0}, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?limit=", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "[]", rr.Body.String())
+		require.Equal(t, "0", rr.Header().Get(api.TotalCountHeader))
+		require.Empty(t, rr.Header().Get(api.NextCursorHeader), "the last page has no next cursor")
+	})
+
+	t.Run("Invalid page", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().GetTransactionsPage(mock.Anything, "USD", "user1", api.TransactionFilter{}, "garbage", 50).Return(nil, api.ErrInvalidCursor)
+
+		for _, query := range []string{"limit=0", "limit=501", "limit=ten", "cursor=garbage"} {
+			req, err := http.NewRequest(http.MethodGet, "/?"+query, nil)
+			require.NoError(t, err)
+
+			req.SetPathValue("accountId", "user1")
+			req.SetPathValue("currency", "USD")
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(handlers.GetTransactions)
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("Time zone", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockTxs := []*api.Transaction{
+			{TxID: "tx1", AccountID: "user1", Currency: "USD", Amount: decimal.NewFromFloat(50.00), Time: time.Date(2024, 7, 1, 20, 0, 0, 0, time.UTC)},
+		}
+		mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return(mockTxs, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/?tz=Asia/Manila", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"time":"2024-07-02T04:00:00+08:00"`)
+	})
+
+	t.Run("Invalid time zone", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		req, err := http.NewRequest(http.MethodGet, "/?tz=Mars/Olympus", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), string(api.CodeInvalidTimeZone))
+	})
+
+	t.Run("Invalid tag", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().FilterTransactions(mock.Anything, "USD", "user1", api.TransactionFilter{Tags: []string{"no spaces"}}).Return(nil, api.ErrInvalidTag)
+
+		req, err := http.NewRequest(http.MethodGet, "/?tag=no+spaces", nil)
+		require.NoError(t, err)
+
+		req.SetPathValue("accountId", "user1")
+		req.SetPathValue("currency", "USD")
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(handlers.GetTransactions)
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
 	t.Run("Not Found", func(t *testing.T) {
 		mockRepo := repository.NewMockRepository(t)
 		handlers := rest.NewRestHandlers(mockRepo)
@@ -372,10 +874,12 @@ func TestGetTransaction(t *testing.T) {
 		handlers := rest.NewRestHandlers(mockRepo)
 
 		mockTx := &api.Transaction{
-			TxID:      "tx1",
-			AccountID: "user1",
-			Currency:  "USD",
-			Amount:    decimal.NewFromFloat(50.00),
+			TxID:         "tx1",
+			AccountID:    "user1",
+			Currency:     "USD",
+			Amount:       decimal.NewFromFloat(50.00),
+			GroupID:      "deposit-1",
+			Counterparty: api.CompanyAccountID,
 		}
 		mockRepo.EXPECT().GetTransaction(mock.Anything, "tx1").Return(mockTx, nil)
 
@@ -389,15 +893,7 @@ func TestGetTransaction(t *testing.T) {
 
 		handler.ServeHTTP(rr, req)
 
-		require.Equal(t, http.StatusOK, rr.Code)
-
-		var response api.Transaction
-		err = json.Unmarshal(rr.Body.Bytes(), &response)
-		require.NoError(t, err)
-		require.Equal(t, mockTx.TxID, response.TxID)
-		require.Equal(t, mockTx.AccountID, response.AccountID)
-		require.Equal(t, mockTx.Currency, response.Currency)
-		require.True(t, mockTx.Amount.Equal(response.Amount))
+		wallettesting.AssertGoldenResponse(t, rr, http.StatusOK, "testdata/transaction.golden.json")
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -648,6 +1144,40 @@ func TestHandleDeposit(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, response.ErrorCode)
 	})
 
+	t.Run("Configured company account", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo).WithCompanyAccount("settlement")
+
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.MatchedBy(func(request *api.TransferRequest) bool {
+			return request.FromAccountID == "settlement" && request.ToAccountID == api.CompanyAccountID
+		}), "test-key").Return([]*api.Transaction{
+			{TxID: "tx1", AccountID: "settlement", Type: api.DEBIT, Currency: "USD", Amount: decimal.NewFromFloat(100.00)},
+			{TxID: "tx2", AccountID: api.CompanyAccountID, Type: api.CREDIT, Currency: "USD", Amount: decimal.NewFromFloat(100.00)},
+		}, nil)
+
+		// the former company account is a regular account
+		body, _ := json.Marshal(&api.DepositRequest{ToAccountID: api.CompanyAccountID, Currency: "USD", Amount: decimal.NewFromFloat(100.00)})
+		req, err := http.NewRequest(http.MethodPost, "/deposit", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Idempotency-Key", "test-key")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleDeposit).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		body, _ = json.Marshal(&api.DepositRequest{ToAccountID: "Settlement", Currency: "USD", Amount: decimal.NewFromFloat(100.00)})
+		req, err = http.NewRequest(http.MethodPost, "/deposit", bytes.NewBuffer(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Idempotency-Key", "test-key")
+
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleDeposit).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), api.ErrCompanyAccount.Error())
+	})
+
 	t.Run("Repo failed", func(t *testing.T) {
 		mockRepo := repository.NewMockRepository(t)
 		handlers := rest.NewRestHandlers(mockRepo)
@@ -703,7 +1233,11 @@ func TestHandleDeposit(t *testing.T) {
 			{errorMessage: api.ErrInvalidRequest, errorCode: http.StatusBadRequest},
 
 			{errorMessage: api.ErrInsufficientBalance, errorCode: http.StatusUnprocessableEntity},
-			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusUnprocessableEntity},
+			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusConflict},
+			{errorMessage: api.ErrAccountNotProvisioned, errorCode: http.StatusUnprocessableEntity},
+			{errorMessage: api.ErrAccountRequiresDeposit, errorCode: http.StatusUnprocessableEntity},
+
+			{errorMessage: api.ErrSandboxQuotaExceeded, errorCode: http.StatusTooManyRequests},
 
 			{errorMessage: api.ErrTransferFailed, errorCode: http.StatusInternalServerError},
 		}
@@ -1040,7 +1574,7 @@ func TestHandleWithdrawal(t *testing.T) {
 			{errorMessage: api.ErrInvalidRequest, errorCode: http.StatusBadRequest},
 
 			{errorMessage: api.ErrInsufficientBalance, errorCode: http.StatusUnprocessableEntity},
-			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusUnprocessableEntity},
+			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusConflict},
 
 			{errorMessage: api.ErrTransferFailed, errorCode: http.StatusInternalServerError},
 		}
@@ -1132,8 +1666,8 @@ func TestHandleTransferOK(t *testing.T) {
 		Amount:        decimal.NewFromFloat(75.00),
 	}
 	mockTxs := []*api.Transaction{
-		{TxID: "tx1", AccountID: "user1", Currency: "USD", Type: api.DEBIT, Amount: decimal.NewFromFloat(-75.00)},
-		{TxID: "tx2", AccountID: "user2", Currency: "USD", Type: api.CREDIT, Amount: decimal.NewFromFloat(75.00)},
+		{TxID: "tx1", AccountID: "user1", Currency: "USD", Type: api.DEBIT, Amount: decimal.NewFromFloat(-75.00), GroupID: "test-key", Counterparty: "user2"},
+		{TxID: "tx2", AccountID: "user2", Currency: "USD", Type: api.CREDIT, Amount: decimal.NewFromFloat(75.00), GroupID: "test-key", Counterparty: "user1"},
 	}
 	mockRepo.EXPECT().Transfer(mock.Anything, mock.AnythingOfType("*api.TransferRequest"), mock.AnythingOfType("string")).Return(mockTxs, nil)
 
@@ -1151,14 +1685,7 @@ func TestHandleTransferOK(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	require.Equal(t, http.StatusCreated, rr.Code)
-
-	var response []*api.Transaction
-	err = json.Unmarshal(rr.Body.Bytes(), &response)
-	require.NoError(t, err)
-	require.Len(t, response, 2)
-	require.Equal(t, mockTxs[0].TxID, response[0].TxID)
-	require.Equal(t, mockTxs[1].TxID, response[1].TxID)
+	wallettesting.AssertGoldenResponse(t, rr, http.StatusCreated, "testdata/transfer.golden.json")
 
 	mockRepo.AssertExpectations(t)
 }
@@ -1319,6 +1846,29 @@ func TestHandleTransferInputValidations(t *testing.T) {
 func TestHandleTransfer(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
+	t.Run("Amount out of range", func(t *testing.T) {
+		// never reaching the repository, nor the NUMERIC columns
+		handlers := rest.NewRestHandlers(nil)
+
+		for _, amount := range []string{"1e400", "1e2147483647", "0.0000000000000000001", "123456789012345678901234567890123456789"} {
+			body := `{"from_account_id":"user1","to_account_id":"user2","currency":"USD","amount":"` + amount + `"}`
+
+			req, err := http.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Idempotency-Key", "test-key")
+
+			rr := httptest.NewRecorder()
+			handlers.HandleTransfer(rr, req)
+
+			require.Equal(t, http.StatusBadRequest, rr.Code, amount)
+
+			var response api.ErrorResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			require.Equal(t, api.ErrAmountOutOfRange.Error(), response.Message)
+		}
+	})
+
 	t.Run("Repo failed", func(t *testing.T) {
 		mockRepo := repository.NewMockRepository(t)
 		handlers := rest.NewRestHandlers(mockRepo)
@@ -1376,7 +1926,12 @@ func TestHandleTransfer(t *testing.T) {
 			{errorMessage: api.ErrInvalidRequest, errorCode: http.StatusBadRequest},
 
 			{errorMessage: api.ErrInsufficientBalance, errorCode: http.StatusUnprocessableEntity},
-			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusUnprocessableEntity},
+			{errorMessage: api.ErrDuplicateTransaction, errorCode: http.StatusConflict},
+
+			{errorMessage: api.ErrTransferUnderReview, errorCode: http.StatusAccepted},
+			{errorMessage: api.ErrTransferPendingApproval, errorCode: http.StatusAccepted},
+			{errorMessage: api.ErrTransferRejected, errorCode: http.StatusForbidden},
+			{errorMessage: api.ErrTransferInProgress, errorCode: http.StatusConflict},
 
 			{errorMessage: api.ErrTransferFailed, errorCode: http.StatusInternalServerError},
 		}
@@ -1409,3 +1964,446 @@ func TestHandleTransfer(t *testing.T) {
 		}
 	})
 }
+
+func TestHandleTransferDuplicate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	transfer := func(handlers *rest.Handlers) (*httptest.ResponseRecorder, api.ErrorResponse) {
+		body, _ := json.Marshal(&api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user2",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(100),
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewBuffer(body))
+		req.Header.Set("X-Idempotency-Key", "order-42")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleTransfer).ServeHTTP(rr, req)
+
+		var response api.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+		return rr, response
+	}
+
+	t.Run("Conflict with the original group", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "order-42").
+			Return(nil, &api.DuplicateTransactionError{GroupID: "order-42"}).Once()
+
+		rr, response := transfer(rest.NewRestHandlers(mockRepo))
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Equal(t, api.CodeDuplicateTransaction, response.Code)
+		require.Equal(t, "order-42", response.GroupID)
+	})
+
+	t.Run("Overridden status", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "order-42").
+			Return(nil, &api.DuplicateTransactionError{GroupID: "order-42"}).Once()
+
+		handlers := rest.NewRestHandlers(mockRepo).
+			WithTransferStatuses(map[api.ErrorCode]int{api.CodeDuplicateTransaction: http.StatusUnprocessableEntity})
+
+		rr, response := transfer(handlers)
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.Equal(t, http.StatusUnprocessableEntity, response.ErrorCode)
+		require.Equal(t, "order-42", response.GroupID)
+	})
+}
+
+func TestHandleTransferRemarks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	policy, err := remarks.New(20, remarks.PII{})
+	require.NoError(t, err)
+
+	for _, text := range []string{strings.Repeat("a", 21), "card 4111 1111 1111 1111"} {
+		body, _ := json.Marshal(&api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user2",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(100),
+			Remarks:       text,
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewBuffer(body))
+		req.Header.Set("X-Idempotency-Key", "order-42")
+
+		// rejected before the repository is called
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(rest.NewRestHandlers(repository.NewMockRepository(t)).WithRemarksPolicy(policy).HandleTransfer).ServeHTTP(rr, req)
+
+		var response api.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, http.StatusBadRequest, rr.Code, text)
+		require.Equal(t, api.CodeInvalidRemarks, response.Code, text)
+	}
+}
+
+func TestHandleTransferConversion(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for _, test := range []struct {
+		err    error
+		status int
+		code   api.ErrorCode
+	}{
+		{err: api.ErrUnsupportedCurrencyPair, status: http.StatusUnprocessableEntity, code: api.CodeUnsupportedCurrencyPair},
+		{err: api.ErrFXRateUnavailable, status: http.StatusServiceUnavailable, code: api.CodeFXRateUnavailable},
+	} {
+		mockRepo := repository.NewMockRepository(t)
+
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.MatchedBy(func(request *api.TransferRequest) bool {
+			return request.Currency == "USD" && request.ToCurrency == "EUR"
+		}), "order-42").Return(nil, test.err)
+
+		body, _ := json.Marshal(&api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user2",
+			Currency:      "USD",
+			ToCurrency:    "EUR",
+			Amount:        decimal.NewFromInt(100),
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewBuffer(body))
+		req.Header.Set("X-Idempotency-Key", "order-42")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(rest.NewRestHandlers(mockRepo).HandleTransfer).ServeHTTP(rr, req)
+
+		var response api.ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, test.status, rr.Code, test.err)
+		require.Equal(t, test.code, response.Code, test.err)
+	}
+}
+
+func TestHandleTransferFeatureFlags(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	newRequest := func(t *testing.T, transferRequest *api.TransferRequest) *http.Request {
+		t.Helper()
+
+		body, err := json.Marshal(transferRequest)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/transfer", bytes.NewBuffer(body))
+		require.NoError(t, err)
+
+		req.Header.Set("X-Idempotency-Key", "test-key")
+
+		return req
+	}
+
+	transferRequest := &api.TransferRequest{
+		FromAccountID: "user1",
+		ToAccountID:   "user2",
+		Currency:      "USD",
+		Amount:        decimal.NewFromFloat(75.00),
+	}
+
+	t.Run("Strict account creation rejects unknown recipient", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo).
+			WithFeatures(features.Disabled().WithProvisioningPolicy(api.ProvisionDepositOnly))
+
+		// the repository enforces the policy, the handler doesn't look the recipient up on its own
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.AnythingOfType("*api.TransferRequest"), "test-key").
+			Return(nil, api.ErrAccountRequiresDeposit)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleTransfer).ServeHTTP(rr, newRequest(t, transferRequest))
+
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.JSONEq(t, `{"error_code":422,"code":"ACCOUNT_REQUIRES_DEPOSIT","message":"the account can only be opened by a deposit"}`,
+			rr.Body.String())
+	})
+
+	t.Run("Strict account ids", func(t *testing.T) {
+		policy, err := accountid.New(`user[0-9]+`, 0, 0)
+		require.NoError(t, err)
+
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo).
+			WithFeatures(features.Disabled().WithAccountIDPolicy(policy))
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleTransfer).ServeHTTP(rr, newRequest(t, &api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user/2",
+			Currency:      "USD",
+			Amount:        decimal.NewFromFloat(75.00),
+		}))
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.JSONEq(t, `{"error_code":400,"code":"INVALID_ACCOUNT_ID","message":"invalid account id: \"user/2\" must match ^(?:user[0-9]+)$"}`,
+			rr.Body.String())
+
+		// the reads are rejected the same way, the account can't exist
+		req := httptest.NewRequest(http.MethodGet, "/account/user%2F2/USD", nil)
+		req.SetPathValue("accountId", "user/2")
+		req.SetPathValue("currency", "USD")
+
+		rr = httptest.NewRecorder()
+		http.HandlerFunc(handlers.GetAccountBalance).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "must match")
+	})
+}
+
+// transferStub is a repository answering the transfers instantly, so the benchmarks only measure the handler.
+type transferStub struct {
+	repository.Repository
+}
+
+func (transferStub) Transfer(_ context.Context, request *api.TransferRequest, _ string) ([]*api.Transaction, error) {
+	return []*api.Transaction{
+		{TxID: "tx1", AccountID: request.FromAccountID, Currency: request.Currency, Type: api.DEBIT, Amount: request.Amount.Neg()},
+		{TxID: "tx2", AccountID: request.ToAccountID, Currency: request.Currency, Type: api.CREDIT, Amount: request.Amount},
+	}, nil
+}
+
+func BenchmarkHandleTransfer(b *testing.B) {
+	handler := rest.NewAPIServer(transferStub{}).
+		WithCustomLogger(logging.Discard()).
+		HTTPServer(8080, time.Second, time.Second).
+		Handler
+
+	body, err := json.Marshal(&api.TransferRequest{
+		FromAccountID: "user1",
+		ToAccountID:   "user2",
+		Currency:      "USD",
+		Amount:        decimal.NewFromFloat(75.00),
+	})
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req := httptest.NewRequest(http.MethodPost, "/transfer", bytes.NewReader(body))
+			req.Header.Set(rest.IdempotencyKeyHeader, "test-key")
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusCreated {
+				b.Fatalf("unexpected status %d", rr.Code)
+			}
+		}
+	})
+}
+
+func TestHandleSetParent(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	newRequest := func(t *testing.T, accountID, body string) *http.Request {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+		require.NoError(t, err)
+		req.SetPathValue("accountId", accountID)
+
+		return req
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().SetParentAccount(mock.Anything, "merchant1-shop", "merchant1").Return(nil)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleSetParent).ServeHTTP(rr, newRequest(t, "merchant1-shop", `{"parent_account_id":" merchant1 "}`))
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response api.AccountParent
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, api.AccountParent{AccountID: "merchant1-shop", ParentAccountID: "merchant1"}, response)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t))
+
+		for _, body := range []string{`{}`, `{"parent_account_id":""}`, `not json`} {
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.HandleSetParent).ServeHTTP(rr, newRequest(t, "merchant1-shop", body))
+
+			require.Equal(t, http.StatusBadRequest, rr.Code, body)
+		}
+	})
+
+	errorCases := []struct {
+		err          error
+		expectedCode int
+	}{
+		{api.ErrParentAlreadySet, http.StatusUnprocessableEntity},
+		{api.ErrHierarchyCycle, http.StatusUnprocessableEntity},
+		{api.ErrCompanyAccount, http.StatusBadRequest},
+		{api.ErrSameAccountIDs, http.StatusBadRequest},
+		{errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			mockRepo := repository.NewMockRepository(t)
+			handlers := rest.NewRestHandlers(mockRepo)
+
+			mockRepo.EXPECT().SetParentAccount(mock.Anything, "merchant1-shop", "merchant1").Return(tc.err)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.HandleSetParent).ServeHTTP(rr, newRequest(t, "merchant1-shop", `{"parent_account_id":"merchant1"}`))
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestHandleAliases(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	registered := &api.AccountAlias{Alias: "jane@example.com", Kind: api.AliasEmail, AccountID: "user1"}
+
+	t.Run("Register", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().RegisterAlias(mock.Anything, "user1", &api.RegisterAliasRequest{Alias: "Jane@Example.com", Kind: api.AliasEmail}).Return(registered, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"alias":"Jane@Example.com","kind":"EMAIL"}`))
+		require.NoError(t, err)
+		req.SetPathValue("accountId", "user1")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleRegisterAlias).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var response api.AccountAlias
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Equal(t, "user1", response.AccountID)
+	})
+
+	registerErrors := []struct {
+		err          error
+		expectedCode int
+	}{
+		{api.ErrAliasTaken, http.StatusConflict},
+		{api.ErrInvalidAlias, http.StatusBadRequest},
+		{api.ErrCompanyAccount, http.StatusBadRequest},
+		{errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range registerErrors {
+		t.Run("Register "+tc.err.Error(), func(t *testing.T) {
+			mockRepo := repository.NewMockRepository(t)
+			handlers := rest.NewRestHandlers(mockRepo)
+
+			mockRepo.EXPECT().RegisterAlias(mock.Anything, "user1", mock.Anything).Return(nil, tc.err)
+
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(`{"alias":"12345"}`))
+			require.NoError(t, err)
+			req.SetPathValue("accountId", "user1")
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.HandleRegisterAlias).ServeHTTP(rr, req)
+
+			require.Equal(t, tc.expectedCode, rr.Code)
+		})
+	}
+
+	t.Run("Resolve", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().ResolveAlias(mock.Anything, "jane@example.com").Return(registered, nil)
+		mockRepo.EXPECT().ResolveAlias(mock.Anything, "unknown").Return(nil, api.ErrAliasNotFound)
+
+		for alias, expectedCode := range map[string]int{"jane@example.com": http.StatusOK, "unknown": http.StatusNotFound} {
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			req.SetPathValue("alias", alias)
+
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(handlers.HandleResolveAlias).ServeHTTP(rr, req)
+
+			require.Equal(t, expectedCode, rr.Code, alias)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().DeleteAlias(mock.Anything, "jane@example.com").Return(nil)
+
+		req, err := http.NewRequest(http.MethodDelete, "/", nil)
+		require.NoError(t, err)
+		req.SetPathValue("alias", "jane@example.com")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleDeleteAlias).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+	})
+
+	t.Run("Transfer to an alias", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().ResolveAlias(mock.Anything, "jane@example.com").Return(registered, nil)
+		mockRepo.EXPECT().Transfer(mock.Anything, &api.TransferRequest{
+			FromAccountID: "user2",
+			ToAccountID:   "user1",
+			Currency:      "USD",
+			Amount:        decimal.NewFromInt(10),
+		}, "alias-key").Return([]*api.Transaction{
+			{TxID: "tx1", AccountID: "user2", Type: api.DEBIT},
+			{TxID: "tx2", AccountID: "user1", Type: api.CREDIT},
+		}, nil)
+
+		req, err := http.NewRequest(http.MethodPost, "/transfer", strings.NewReader(`{"from_account_id":"user2","to_alias":"jane@example.com","currency":"USD","amount":"10"}`))
+		require.NoError(t, err)
+		req.Header.Set(rest.IdempotencyKeyHeader, "alias-key")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleTransfer).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("Deposit to an unknown alias", func(t *testing.T) {
+		mockRepo := repository.NewMockRepository(t)
+		handlers := rest.NewRestHandlers(mockRepo)
+
+		mockRepo.EXPECT().ResolveAlias(mock.Anything, "unknown").Return(nil, api.ErrAliasNotFound)
+
+		req, err := http.NewRequest(http.MethodPost, "/deposit", strings.NewReader(`{"account_alias":"unknown","currency":"USD","amount":"10"}`))
+		require.NoError(t, err)
+		req.Header.Set(rest.IdempotencyKeyHeader, "alias-key")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleDeposit).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("Both the account and its alias", func(t *testing.T) {
+		handlers := rest.NewRestHandlers(repository.NewMockRepository(t))
+
+		req, err := http.NewRequest(http.MethodPost, "/withdraw", strings.NewReader(`{"account_id":"user1","account_alias":"jane@example.com","currency":"USD","amount":"10"}`))
+		require.NoError(t, err)
+		req.Header.Set(rest.IdempotencyKeyHeader, "alias-key")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.HandleWithdrawal).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Reversals is implemented by repository.PostgresRepository.
+type Reversals interface {
+	ReverseTransaction(ctx context.Context, txID string, request *api.ReverseRequest, operator string) (*api.ReversalReceipt, error)
+}
+
+// WithReversals serves the refunds of the transfers under /transactions/{txId}/reverse.
+// The operators are identified by their admin key, which is logged with every reversal.
+func (r *APIServer) WithReversals(auth middlewares.Middleware, reversals Reversals) *APIServer {
+	r.adminAuth = auth
+	r.reversals = reversals
+
+	return r
+}
+
+func (r *APIServer) registerReversalEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.reversals == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /transactions/{txId}/reverse", r.adminAuth(handler.HandleReverseTransaction))
+}
+
+// HandleReverseTransaction posts the compensating transfer of the transfer of the ledger entry,
+// and responds with its receipt. The body, with the remarks of the reversal, is optional.
+func (h *Handlers) HandleReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	request := &api.ReverseRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	if err = h.remarks.Validate(strings.TrimSpace(request.Remarks)); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	receipt, err := h.reversals.ReverseTransaction(ctx, r.PathValue("txId"), request, operator)
+	if h.handleReversalError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "transaction reversed", slog.String("tx_id", receipt.ReversalOf),
+		slog.String("group_id", receipt.GroupID), slog.String("operator", operator))
+
+	h.rounding.Transactions(receipt.Debit, receipt.Credit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(receipt)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleReversalError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, api.ErrTransactionNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return true
+	case errors.Is(err, api.ErrTransactionReversed),
+		errors.Is(err, api.ErrTransactionDisputed):
+		h.HandleError(w, http.StatusConflict, err)
+
+		return true
+	case errors.Is(err, api.ErrNotReversible):
+		h.HandleError(w, http.StatusUnprocessableEntity, err)
+
+		return true
+	default:
+		// the reversals post ledger entries, which fail like any other transfer
+		return h.HandleTransferError(w, err)
+	}
+}
@@ -2,13 +2,22 @@ package rest
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/gql"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/i18n"
+	"github.com/devshark/wallet/app/internal/remarks"
 	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/pkg/idgen"
+	"github.com/devshark/wallet/pkg/logging"
 	"github.com/devshark/wallet/pkg/middlewares"
-	"github.com/go-redis/redis/v8"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 const (
@@ -19,50 +28,230 @@ const (
 )
 
 type APIServer struct {
-	repo        repository.Repository
-	middlewares []middlewares.Middleware
-	logger      *log.Logger
-	pingers     []Pinger
+	repo             repository.Repository
+	middlewares      []middlewares.Middleware
+	logger           *slog.Logger
+	pingers          []Pinger
+	degradedPingers  []degradedPinger
+	debugAuth        middlewares.Middleware
+	adminAuth        middlewares.Middleware
+	reconciler       Reconciler
+	reviews          TransferReviews
+	pending          PendingTransfers
+	disputes         Disputes
+	metadata         Metadata
+	reversals        Reversals
+	reports          Reports
+	statements       Statements
+	receipts         Receipts
+	webhooks         Webhooks
+	eventLog         EventLog
+	personalData     PersonalData
+	activity         ActivityFeed
+	provisioning     AccountProvisioning
+	rebuilder        BalanceRebuilder
+	readOnly         ReadOnlyMode
+	conflicts        IdempotencyConflicts
+	downloads        StatementDownloads
+	subscriptions    *subscriptions
+	streams          TransactionStreams
+	batches          TransferBatches
+	holds            Holds
+	queue            TransferQueue
+	scheduled        ScheduledTransfers
+	feeRules         FeeRules
+	cache            middlewares.ScannerAndDeleter
+	features         features.Features
+	rounding         rounding.Policies
+	remarks          remarks.Policy
+	transferStatuses map[api.ErrorCode]int
+	rateLimit        *rateLimit
+	sandbox          bool
+	errorMessages    *i18n.Catalog
+	// the connections of HTTPServer, see WithConnectionTimeouts and WithH2C
+	readHeaderTimeout time.Duration
+	idleTimeout       time.Duration
+	h2c               bool
+	// companyAccountID is the settlement account, see WithCompanyAccount
+	companyAccountID string
 }
 
 func NewAPIServer(repo repository.Repository) *APIServer {
 	return &APIServer{
 		repo:        repo,
 		pingers:     []Pinger{},
-		logger:      log.Default(),
+		logger:      slog.Default(),
+		features:    features.Disabled(),
+		rounding:    rounding.None(),
 		middlewares: make([]middlewares.Middleware, 0, middlewaresInitialCapacity),
+
+		companyAccountID: api.CompanyAccountID,
 	}
 }
 
-func (r *APIServer) WithCacheMiddleware(redisClient *redis.Client, redisExpiration time.Duration) *APIServer {
+func (r *APIServer) WithCacheMiddleware(redisClient middlewares.GetterAndSetter, redisExpiration time.Duration) *APIServer {
 	// only caches GET requests
-	cacheMiddleware := middlewares.NewRedisCacheMiddleware(redisClient, redisExpiration)
+	cacheMiddleware := middlewares.NewRedisCacheMiddleware(redisClient,
+		middlewares.WithCacheTTL(redisExpiration),
+		middlewares.WithCacheLogger(logging.Component(r.logger, "cache")))
 	r.middlewares = append(r.middlewares, cacheMiddleware)
 
 	return r
 }
 
-func (r *APIServer) WithCustomLogger(logger *log.Logger) *APIServer {
+// WithCustomLogger sets the structured logger used by the handlers, tagged with the rest component.
+func (r *APIServer) WithCustomLogger(logger *slog.Logger) *APIServer {
 	r.logger = logger
 
 	return r
 }
 
+// WithFeatures sets the feature flags gating the risky behaviors of the handlers.
+func (r *APIServer) WithFeatures(f features.Features) *APIServer {
+	r.features = f
+
+	return r
+}
+
+// WithRounding sets the rounding policies of the amounts in the responses, by currency.
+func (r *APIServer) WithRounding(policies rounding.Policies) *APIServer {
+	r.rounding = policies
+
+	return r
+}
+
+// WithRemarksPolicy limits the length and screens the content of the remarks, see Handlers.WithRemarksPolicy.
+func (r *APIServer) WithRemarksPolicy(policy remarks.Policy) *APIServer {
+	r.remarks = policy
+
+	return r
+}
+
+// WithTransferStatuses overrides the HTTP status of the failed transfers by error code, see Handlers.WithTransferStatuses.
+func (r *APIServer) WithTransferStatuses(statuses map[api.ErrorCode]int) *APIServer {
+	r.transferStatuses = statuses
+
+	return r
+}
+
+// WithCompanyAccount overrides the settlement account of the deposits and withdrawals, api.CompanyAccountID by default.
+// It must be the company account of the repository.
+func (r *APIServer) WithCompanyAccount(accountID string) *APIServer {
+	r.companyAccountID = accountID
+
+	return r
+}
+
+// WithConnectionTimeouts bounds the time to read the headers of a request, and to keep an idle connection open,
+// which both default to the read timeout of HTTPServer.
+func (r *APIServer) WithConnectionTimeouts(readHeaderTimeout, idleTimeout time.Duration) *APIServer {
+	r.readHeaderTimeout = readHeaderTimeout
+	r.idleTimeout = idleTimeout
+
+	return r
+}
+
+// WithH2C serves HTTP/2 without TLS from HTTPServer, next to HTTP/1.1, i.e. behind a proxy terminating TLS.
+// Over TLS, HTTP/2 is always negotiated, so it's not needed.
+func (r *APIServer) WithH2C() *APIServer {
+	r.h2c = true
+
+	return r
+}
+
+// HTTPServer returns the server listening on the port, serving the Handler.
 func (r *APIServer) HTTPServer(port int64, httpReadTimeout, httpWriteTimeout time.Duration) *http.Server {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           r.Handler(),
+		ReadTimeout:       httpReadTimeout,
+		WriteTimeout:      httpWriteTimeout,
+		ReadHeaderTimeout: r.readHeaderTimeout,
+		IdleTimeout:       r.idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	if r.subscriptions != nil {
+		server.RegisterOnShutdown(r.subscriptions.shutdown)
+	}
+
+	if r.h2c {
+		http2Server := &http2.Server{IdleTimeout: r.idleTimeout}
+
+		// registers the h2c connections with the server, so its shutdown sends them a GOAWAY
+		// and their in-flight requests complete, without accepting new streams
+		if err := http2.ConfigureServer(server, http2Server); err != nil {
+			// there's no TLS config yet, so it can only fail on a programming error
+			panic(err)
+		}
+
+		server.Handler = h2c.NewHandler(server.Handler, http2Server)
+	}
+
+	return server
+}
+
+// Handler returns the configured routes, without a server, so the API can be mounted in the router of another service,
+// i.e. with http.StripPrefix, or served by a custom server, like h2c or a unix socket.
+// The timeouts and the header limit of HTTPServer are then up to that server.
+func (r *APIServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	handler := &Handlers{
-		repo:    r.repo,
-		logger:  r.logger,
-		pingers: r.pingers,
+		repo:             r.repo,
+		logger:           logging.Component(r.logger, "rest"),
+		pingers:          r.pingers,
+		degradedPingers:  r.degradedPingers,
+		features:         r.features,
+		reconciler:       r.reconciler,
+		reviews:          r.reviews,
+		pending:          r.pending,
+		disputes:         r.disputes,
+		metadata:         r.metadata,
+		reversals:        r.reversals,
+		reports:          r.reports,
+		statements:       r.statements,
+		receipts:         r.receipts,
+		webhooks:         r.webhooks,
+		eventLog:         r.eventLog,
+		personalData:     r.personalData,
+		activity:         r.activity,
+		provisioning:     r.provisioning,
+		rebuilder:        r.rebuilder,
+		readOnly:         r.readOnly,
+		conflicts:        r.conflicts,
+		downloads:        r.downloads,
+		subscriptions:    r.subscriptions,
+		streams:          r.streams,
+		batches:          r.batches,
+		holds:            r.holds,
+		queue:            r.queue,
+		scheduled:        r.scheduled,
+		feeRules:         r.feeRules,
+		cache:            r.cache,
+		rounding:         r.rounding,
+		remarks:          r.remarks,
+		transferStatuses: r.transferStatuses,
+
+		companyAccountID: r.companyAccountID,
 	}
 
 	middlewareChain := middlewares.MiddlewareChain(r.middlewares...)
 
+	// the schema is static, so it can only fail on a programming error
+	graphqlHandler, err := gql.NewHandler(r.repo, r.logger)
+	if err != nil {
+		panic(err)
+	}
+
 	// pointless to cache health check
 	mux.HandleFunc("GET /health", handler.HandleHealthCheck)
+	mux.HandleFunc("GET /readyz", handler.HandleReadiness)
+	mux.HandleFunc("GET /version", handler.HandleVersion)
 	// don't cache account balance, as it may change frequently
 	mux.HandleFunc("GET /account/{accountId}/{currency}", (handler.GetAccountBalance))
+	mux.HandleFunc("GET /account/{accountId}", handler.GetAccountSummary)
+	mux.HandleFunc("POST /balances/query", handler.HandleQueryBalances)
 	// only cache transactions, as they are fixed
 	mux.HandleFunc("GET /transactions/{accountId}/{currency}", (handler.GetTransactions))
 	mux.HandleFunc("GET /transactions/{txId}", middlewareChain(handler.GetTransaction))
@@ -71,13 +260,63 @@ func (r *APIServer) HTTPServer(port int64, httpReadTimeout, httpWriteTimeout tim
 	mux.HandleFunc("POST /deposit", (handler.HandleDeposit))
 	mux.HandleFunc("POST /withdraw", (handler.HandleWithdrawal))
 	mux.HandleFunc("POST /transfer", (handler.HandleTransfer))
+	mux.HandleFunc("PUT /account/{accountId}/parent", handler.HandleSetParent)
+	mux.HandleFunc("POST /account/{accountId}/aliases", handler.HandleRegisterAlias)
+	mux.HandleFunc("GET /account/{accountId}/aliases", handler.HandleGetAccountAliases)
+	mux.HandleFunc("GET /aliases/{alias}", handler.HandleResolveAlias)
+	mux.HandleFunc("DELETE /aliases/{alias}", handler.HandleDeleteAlias)
 
-	return &http.Server{
-		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           mux,
-		ReadTimeout:       httpReadTimeout,
-		WriteTimeout:      httpWriteTimeout,
-		ReadHeaderTimeout: 0,
-		MaxHeaderBytes:    maxHeaderBytes,
+	// read-only queries, not cached as the balances may change
+	mux.Handle("/graphql", graphqlHandler)
+
+	r.registerDebugEndpoints(mux)
+	r.registerAdminEndpoints(mux, handler)
+	r.registerReviewEndpoints(mux, handler)
+	r.registerApprovalEndpoints(mux, handler)
+	r.registerDisputeEndpoints(mux, handler)
+	r.registerMetadataEndpoints(mux, handler)
+	r.registerReversalEndpoints(mux, handler)
+	r.registerReportEndpoints(mux, handler)
+	r.registerStatementEndpoints(mux, handler)
+	r.registerReceiptEndpoints(mux, handler)
+	r.registerWebhookEndpoints(mux, handler)
+	r.registerEventLogEndpoints(mux, handler)
+	r.registerPersonalDataEndpoints(mux, handler)
+	r.registerActivityEndpoints(mux, handler)
+	r.registerProvisioningEndpoints(mux, handler)
+	r.registerBalanceRebuildEndpoints(mux, handler)
+	r.registerReadOnlyEndpoints(mux, handler)
+	r.registerIdempotencyConflictEndpoints(mux, handler)
+	r.registerStatementDownloadEndpoints(mux, handler)
+	r.registerSubscriptionEndpoints(mux, handler)
+	r.registerTransactionStreamEndpoints(mux, handler)
+	r.registerTransferBatchEndpoints(mux, handler)
+	r.registerHoldEndpoints(mux, handler)
+	r.registerTransferQueueEndpoints(mux, handler)
+	r.registerScheduledTransferEndpoints(mux, handler)
+	r.registerFeeRuleEndpoints(mux, handler)
+	r.registerCacheEndpoints(mux, handler)
+
+	var root http.Handler = mux
+	if r.readOnly != nil {
+		root = handler.readOnlyGuard(r.readOnly, root)
 	}
+
+	if r.rateLimit != nil {
+		root = handler.rateLimited(r.rateLimit, root)
+	}
+
+	if r.sandbox {
+		root = markSandbox(root)
+	}
+
+	// the errors of the guards above are translated and negotiated too
+	if r.errorMessages != nil {
+		root = localizeErrors(r.errorMessages, root)
+	}
+
+	root = middlewares.NewMessagePack()(root.ServeHTTP)
+
+	// outermost, so every line logged for the request carries its id
+	return middlewares.NewRequestID(idgen.NewUUIDGenerator())(root.ServeHTTP)
 }
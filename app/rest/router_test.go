@@ -1,18 +1,40 @@
 package rest
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/i18n"
+	"github.com/devshark/wallet/app/internal/reconciliation"
 	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/devshark/wallet/pkg/msgpack"
 	"github.com/go-redis/redis/v8"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"golang.org/x/net/http2"
 )
 
 func TestNewApiServer(t *testing.T) {
@@ -66,7 +88,7 @@ func TestWithCustomLogger(t *testing.T) {
 	mockRepo := &repository.MockRepository{}
 	server := NewAPIServer(mockRepo)
 
-	customLogger := log.New(os.Stdout, "TEST: ", log.Ldate|log.Ltime|log.Lshortfile)
+	customLogger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	updatedServer := server.WithCustomLogger(customLogger)
 
 	require.Equal(t, server, updatedServer)
@@ -90,13 +112,3351 @@ func TestHttpServer(t *testing.T) {
 	require.Equal(t, readTimeout, httpServer.ReadTimeout)
 	require.Equal(t, writeTimeout, httpServer.WriteTimeout)
 
-	// Test that routes are set up correctly
-	mux, ok := httpServer.Handler.(*http.ServeMux)
-	require.True(t, ok)
+	// Test that routes are set up correctly, behind the request ids
+	rec := httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
 
-	// You can test individual routes if needed, for example:
-	healthHandler, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, "/health", nil))
-	require.NotNil(t, healthHandler)
-	require.NotNil(t, pattern)
-	require.NotEmpty(t, pattern)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, rec.Header().Get(middlewares.RequestIDHeader))
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	req.Header.Set(middlewares.RequestIDHeader, "edge-42")
+
+	rec = httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, req)
+
+	require.Equal(t, "edge-42", rec.Header().Get(middlewares.RequestIDHeader))
+}
+
+func TestH2C(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithConnectionTimeouts(time.Second, time.Minute).
+		WithH2C().
+		HTTPServer(8080, time.Second, time.Second)
+
+	require.Equal(t, time.Second, httpServer.ReadHeaderTimeout)
+	require.Equal(t, time.Minute, httpServer.IdleTimeout)
+
+	server := httptest.NewServer(httpServer.Handler)
+	defer server.Close()
+
+	// HTTP/2 with prior knowledge, without TLS
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	defer transport.CloseIdleConnections()
+
+	res, err := (&http.Client{Transport: transport}).Get(server.URL + "/version")
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, 2, res.ProtoMajor)
+
+	// HTTP/1.1 is still served
+	res, err = server.Client().Get(server.URL + "/version")
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	require.Equal(t, 1, res.ProtoMajor)
+}
+
+func TestHandler(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	outer := http.NewServeMux()
+	outer.Handle("/wallet/", http.StripPrefix("/wallet", NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		Handler()))
+
+	rec := httptest.NewRecorder()
+	outer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/wallet/version", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code, "mounted in another router")
+	require.NotEmpty(t, rec.Header().Get(middlewares.RequestIDHeader))
+}
+
+func TestSandbox(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithSandbox().
+		HTTPServer(8080, time.Second, time.Second)
+
+	rec := httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "true", rec.Header().Get(api.SandboxHeader))
+
+	rec = httptest.NewRecorder()
+	httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "true", rec.Header().Get(api.SandboxHeader), "the errors are marked too")
+}
+
+func TestErrorMessages(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithFeeRules(middlewares.NewAPIKeyAuth([]string{hash}), &stubFeeRules{rules: map[string]*api.FeeRule{}}).
+		WithErrorMessages(i18n.DefaultCatalog()).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request, acceptLanguage string) *httptest.ResponseRecorder {
+		req.Header.Set("Accept-Language", acceptLanguage)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) *api.ErrorResponse {
+		t.Helper()
+
+		response := &api.ErrorResponse{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(response))
+
+		return response
+	}
+
+	t.Run("Translated", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader("{")), "es-MX,es;q=0.9,en;q=0.8")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, "es", rec.Header().Get("Content-Language"))
+		require.Contains(t, rec.Header().Values("Vary"), "Accept-Language")
+
+		response := decode(t, rec)
+		require.Equal(t, http.StatusBadRequest, response.ErrorCode)
+		require.Equal(t, api.CodeInvalidRequest, response.Code)
+		require.Equal(t, "La solicitud no es válida", response.Message)
+	})
+
+	t.Run("Guards translated", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/fees", nil), "fr")
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		require.Equal(t, "Non autorisé", decode(t, rec).Message)
+	})
+
+	t.Run("English", func(t *testing.T) {
+		for _, acceptLanguage := range []string{"", "en-GB", "de, ja;q=0.5", "not a language;q=x"} {
+			rec := serve(httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader("{")), acceptLanguage)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Empty(t, rec.Header().Get("Content-Language"), acceptLanguage)
+			require.Equal(t, api.ErrInvalidRequest.Error(), decode(t, rec).Message, acceptLanguage)
+		}
+	})
+
+	t.Run("Successes untouched", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/fees", nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := serve(req, "es")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Header().Get("Content-Language"))
+		require.JSONEq(t, "[]", rec.Body.String())
+	})
+
+	t.Run("Translated in MessagePack", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader("{"))
+		req.Header.Set("Accept", msgpack.ContentType)
+
+		rec := serve(req, "fr-CA")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Equal(t, msgpack.ContentType, rec.Header().Get("Content-Type"))
+
+		var response api.ErrorResponse
+		require.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &response))
+		require.Equal(t, "La requête n'est pas valide", response.Message)
+	})
+}
+
+// memoryCounter counts in memory, failing while err is set.
+type memoryCounter struct {
+	counts map[string]int64
+	err    error
+}
+
+func (c *memoryCounter) Incr(ctx context.Context, key string) *redis.IntCmd {
+	if c.err != nil {
+		return redis.NewIntResult(0, c.err)
+	}
+
+	c.counts[key]++
+
+	return redis.NewIntResult(c.counts[key], nil)
+}
+
+func (c *memoryCounter) Expire(context.Context, string, time.Duration) *redis.BoolCmd {
+	return redis.NewBoolResult(true, c.err)
+}
+
+func TestRateLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	counter := &memoryCounter{counts: map[string]int64{}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithRateLimit(counter, 2, time.Hour).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := serve("/version", "192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "2", rec.Header().Get(api.RateLimitLimitHeader))
+	require.Equal(t, "1", rec.Header().Get(api.RateLimitRemainingHeader))
+
+	reset, err := strconv.ParseInt(rec.Header().Get(api.RateLimitResetHeader), 10, 64)
+	require.NoError(t, err)
+	require.Greater(t, reset, time.Now().Unix())
+
+	rec = serve("/version", "192.0.2.1:5678")
+	require.Equal(t, http.StatusOK, rec.Code, "the same client on another port")
+	require.Equal(t, "0", rec.Header().Get(api.RateLimitRemainingHeader))
+
+	rec = serve("/version", "192.0.2.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "0", rec.Header().Get(api.RateLimitRemainingHeader))
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), string(api.CodeRateLimited))
+
+	rec = serve("/version", "192.0.2.2:1234")
+	require.Equal(t, http.StatusOK, rec.Code, "another client")
+
+	rec = serve("/health", "192.0.2.1:1234")
+	require.NotEqual(t, http.StatusTooManyRequests, rec.Code, "the probes aren't limited")
+	require.Empty(t, rec.Header().Get(api.RateLimitRemainingHeader))
+
+	counter.err = redis.ErrClosed
+
+	rec = serve("/version", "192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, rec.Code, "let through while Redis is down")
+	require.Empty(t, rec.Header().Get(api.RateLimitRemainingHeader))
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		httpServer := NewAPIServer(&repository.MockRepository{}).HTTPServer(8080, time.Second, time.Second)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithDebugEndpoints(middlewares.NewAPIKeyAuth([]string{hash})).
+		HTTPServer(8080, time.Second, time.Second)
+
+	for _, path := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/cmdline"} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", "Bearer "+key)
+
+			rec = httptest.NewRecorder()
+			httpServer.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+// stubReconciler reconciles every statement into the same report.
+type stubReconciler struct {
+	format reconciliation.Format
+	err    error
+}
+
+func (s *stubReconciler) Reconcile(_ context.Context, format reconciliation.Format, fileName string, statement io.Reader) (*api.Reconciliation, error) {
+	s.format = format
+
+	if _, err := io.ReadAll(statement); err != nil {
+		return nil, err
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &api.Reconciliation{ID: "00000000-0000-4000-8000-000000000001", FileName: fileName, Matched: 1}, nil
+}
+
+func (s *stubReconciler) Get(_ context.Context, id string) (*api.Reconciliation, error) {
+	if id != "00000000-0000-4000-8000-000000000001" {
+		return nil, api.ErrReconciliationNotFound
+	}
+
+	return &api.Reconciliation{ID: id, Matched: 1, Items: []*api.ReconciliationItem{{Status: api.Matched}}}, nil
+}
+
+func (s *stubReconciler) List(context.Context, int) ([]*api.Reconciliation, error) {
+	return []*api.Reconciliation{{ID: "00000000-0000-4000-8000-000000000001"}}, nil
+}
+
+func TestReconciliationEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	reconciler := &stubReconciler{}
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithReconciliations(middlewares.NewAPIKeyAuth([]string{hash}), reconciler).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reconciliations", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reconciliations?file_name=july.csv", strings.NewReader("date,amount,currency\n"))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+
+		rec := serve(req)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, reconciliation.FormatCSV, reconciler.format)
+
+		report := &api.Reconciliation{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(report))
+		require.Equal(t, "july.csv", report.FileName)
+	})
+
+	t.Run("Format parameter", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/reconciliations?format=camt053", strings.NewReader("<Document/>")))
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, reconciliation.FormatCAMT053, reconciler.format)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/reconciliations", strings.NewReader("{}")))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Invalid statement", func(t *testing.T) {
+		reconciler := &stubReconciler{err: fmt.Errorf("%w: line 2", api.ErrInvalidStatement)}
+		httpServer := NewAPIServer(&repository.MockRepository{}).
+			WithReconciliations(middlewares.NewAPIKeyAuth([]string{hash}), reconciler).
+			HTTPServer(8080, time.Second, time.Second)
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/reconciliations?format=csv", strings.NewReader("date\n"))
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), "line 2")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/reconciliations?limit=5", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		reports := []*api.Reconciliation{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&reports))
+		require.Len(t, reports, 1)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/reconciliations?limit=1000", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/reconciliations/00000000-0000-4000-8000-000000000001", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		report := &api.Reconciliation{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(report))
+		require.Len(t, report.Items, 1)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/reconciliations/unknown", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubReviews holds a single pending transfer, with the key "held".
+type stubReviews struct {
+	review     *api.TransferReview
+	approveErr error
+	lastLimit  int
+	lastStatus api.ReviewStatus
+}
+
+func (s *stubReviews) GetTransferReview(_ context.Context, key string) (*api.TransferReview, error) {
+	if key != s.review.IdempotencyKey {
+		return nil, api.ErrReviewNotFound
+	}
+
+	return s.review, nil
+}
+
+func (s *stubReviews) ListTransferReviews(_ context.Context, status api.ReviewStatus, limit int) ([]*api.TransferReview, error) {
+	s.lastStatus = status
+	s.lastLimit = limit
+
+	return []*api.TransferReview{s.review}, nil
+}
+
+func (s *stubReviews) ApproveTransferReview(ctx context.Context, key string) ([]*api.Transaction, error) {
+	if err := s.decide(key, api.ReviewApproved); err != nil {
+		return nil, err
+	}
+
+	if s.approveErr != nil {
+		s.review.Status = api.ReviewPending
+
+		return nil, s.approveErr
+	}
+
+	return []*api.Transaction{{TxID: "00000000-0000-4000-8000-000000000001", Type: api.DEBIT}, {TxID: "00000000-0000-4000-8000-000000000002", Type: api.CREDIT}}, nil
+}
+
+func (s *stubReviews) RejectTransferReview(_ context.Context, key string) error {
+	return s.decide(key, api.ReviewRejected)
+}
+
+func (s *stubReviews) decide(key string, status api.ReviewStatus) error {
+	if key != s.review.IdempotencyKey {
+		return api.ErrReviewNotFound
+	}
+
+	if s.review.Status != api.ReviewPending {
+		return api.ErrReviewDecided
+	}
+
+	s.review.Status = status
+
+	return nil
+}
+
+func TestReviewEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	newServer := func(reviews *stubReviews) *http.Server {
+		return NewAPIServer(&repository.MockRepository{}).
+			WithCustomLogger(logging.Discard()).
+			WithTransferReviews(middlewares.NewAPIKeyAuth([]string{hash}), reviews).
+			HTTPServer(8080, time.Second, time.Second)
+	}
+
+	serve := func(httpServer *http.Server, req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	newReviews := func() *stubReviews {
+		return &stubReviews{review: &api.TransferReview{IdempotencyKey: "held", Status: api.ReviewPending, Reason: "denylisted account sanctioned1"}}
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newServer(newReviews()).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reviews", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		reviews := newReviews()
+		httpServer := newServer(reviews)
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, api.ReviewPending, reviews.lastStatus)
+		require.Equal(t, defaultReviewsLimit, reviews.lastLimit)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews?status=rejected&limit=5", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, api.ReviewRejected, reviews.lastStatus)
+		require.Equal(t, 5, reviews.lastLimit)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews?status=unknown", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews?limit=0", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		httpServer := newServer(newReviews())
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews/held", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		review := &api.TransferReview{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(review))
+		require.Equal(t, "denylisted account sanctioned1", review.Reason)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/reviews/unknown", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		httpServer := newServer(newReviews())
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/reviews/held/approve", nil))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		txs := []*api.Transaction{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&txs))
+		require.Len(t, txs, 2)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/reviews/held/approve", nil))
+		require.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/reviews/unknown/approve", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Approve with insufficient balance", func(t *testing.T) {
+		reviews := newReviews()
+		reviews.approveErr = api.ErrInsufficientBalance
+
+		rec := serve(newServer(reviews), httptest.NewRequest(http.MethodPost, "/admin/reviews/held/approve", nil))
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		require.Equal(t, api.ReviewPending, reviews.review.Status)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		httpServer := newServer(newReviews())
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/reviews/held/reject", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		review := &api.TransferReview{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(review))
+		require.Equal(t, api.ReviewRejected, review.Status)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/reviews/held/approve", nil))
+		require.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+// stubPendingTransfers holds a single transfer above the approval threshold.
+type stubPendingTransfers struct {
+	transfer *api.PendingTransfer
+}
+
+func (s *stubPendingTransfers) GetPendingTransfer(_ context.Context, id string) (*api.PendingTransfer, error) {
+	if id != s.transfer.ID {
+		return nil, api.ErrPendingTransferNotFound
+	}
+
+	return s.transfer, nil
+}
+
+func (s *stubPendingTransfers) ListPendingTransfers(context.Context, api.ReviewStatus, int) ([]*api.PendingTransfer, error) {
+	return []*api.PendingTransfer{s.transfer}, nil
+}
+
+func (s *stubPendingTransfers) ApprovePendingTransfer(_ context.Context, id, operator string) ([]*api.Transaction, error) {
+	if err := s.decide(id, operator, api.ReviewApproved); err != nil {
+		return nil, err
+	}
+
+	return []*api.Transaction{{TxID: "00000000-0000-4000-8000-000000000001", Type: api.DEBIT}, {TxID: "00000000-0000-4000-8000-000000000002", Type: api.CREDIT}}, nil
+}
+
+func (s *stubPendingTransfers) RejectPendingTransfer(_ context.Context, id, operator string) error {
+	return s.decide(id, operator, api.ReviewRejected)
+}
+
+func (s *stubPendingTransfers) decide(id, operator string, status api.ReviewStatus) error {
+	if id != s.transfer.ID {
+		return api.ErrPendingTransferNotFound
+	}
+
+	if s.transfer.Status != api.ReviewPending {
+		return api.ErrPendingTransferDecided
+	}
+
+	s.transfer.Status = status
+	s.transfer.DecidedBy = operator
+
+	return nil
+}
+
+func TestApprovalEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	const id = "00000000-0000-4000-8000-000000000009"
+
+	newServer := func() (*http.Server, *stubPendingTransfers) {
+		pending := &stubPendingTransfers{transfer: &api.PendingTransfer{ID: id, IdempotencyKey: "large", Status: api.ReviewPending}}
+
+		return NewAPIServer(&repository.MockRepository{}).
+			WithCustomLogger(logging.Discard()).
+			WithPendingTransfers(middlewares.NewAPIKeyAuth([]string{hash}), pending).
+			HTTPServer(8080, time.Second, time.Second), pending
+	}
+
+	serve := func(httpServer *http.Server, req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		httpServer, pending := newServer()
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/transfers/"+id+"/approve", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Equal(t, api.ReviewPending, pending.transfer.Status)
+	})
+
+	t.Run("List and get", func(t *testing.T) {
+		httpServer, _ := newServer()
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/transfers?status=pending", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		transfers := []*api.PendingTransfer{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&transfers))
+		require.Len(t, transfers, 1)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/transfers?status=held", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/transfers/"+id, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodGet, "/admin/transfers/unknown", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		httpServer, pending := newServer()
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/transfers/"+id+"/approve", nil))
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, middlewares.OperatorID(hash), pending.transfer.DecidedBy)
+
+		rec = serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/transfers/"+id+"/reject", nil))
+		require.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		httpServer, _ := newServer()
+
+		rec := serve(httpServer, httptest.NewRequest(http.MethodPost, "/admin/transfers/"+id+"/reject", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		transfer := &api.PendingTransfer{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(transfer))
+		require.Equal(t, api.ReviewRejected, transfer.Status)
+		require.Equal(t, middlewares.OperatorID(hash), transfer.DecidedBy)
+	})
+}
+
+// stubDisputes holds a single dispute of a single transfer.
+type stubDisputes struct {
+	txID    string
+	dispute *api.Dispute
+}
+
+func (s *stubDisputes) OpenDispute(_ context.Context, request *api.OpenDisputeRequest, operator string) (*api.Dispute, error) {
+	if request.TxID != s.txID {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	if s.dispute != nil && s.dispute.Status == api.DisputeOpen {
+		return nil, api.ErrDisputeOpen
+	}
+
+	s.dispute = &api.Dispute{
+		ID:      "00000000-0000-4000-8000-000000000010",
+		TxID:    request.TxID,
+		Amount:  request.Amount,
+		Status:  api.DisputeOpen,
+		History: []*api.DisputeEvent{{Status: api.DisputeOpen, Operator: operator}},
+	}
+
+	return s.dispute, nil
+}
+
+func (s *stubDisputes) GetDispute(_ context.Context, id string) (*api.Dispute, error) {
+	if s.dispute == nil || id != s.dispute.ID {
+		return nil, api.ErrDisputeNotFound
+	}
+
+	return s.dispute, nil
+}
+
+func (s *stubDisputes) ListDisputes(context.Context, api.DisputeStatus, int) ([]*api.Dispute, error) {
+	if s.dispute == nil {
+		return []*api.Dispute{}, nil
+	}
+
+	return []*api.Dispute{s.dispute}, nil
+}
+
+func (s *stubDisputes) ReverseDispute(ctx context.Context, id, operator string) (*api.Dispute, error) {
+	return s.resolve(ctx, id, operator, api.DisputeReversed)
+}
+
+func (s *stubDisputes) ReleaseDispute(ctx context.Context, id, operator string) (*api.Dispute, error) {
+	return s.resolve(ctx, id, operator, api.DisputeReleased)
+}
+
+func (s *stubDisputes) resolve(ctx context.Context, id, operator string, status api.DisputeStatus) (*api.Dispute, error) {
+	dispute, err := s.GetDispute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if dispute.Status != api.DisputeOpen {
+		return nil, api.ErrDisputeResolved
+	}
+
+	dispute.Status = status
+	dispute.History = append(dispute.History, &api.DisputeEvent{Status: status, Operator: operator})
+
+	return dispute, nil
+}
+
+func (s *stubDisputes) GetTransactionDisputes(ctx context.Context, txID string) ([]*api.Dispute, error) {
+	if txID != s.txID {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	return s.ListDisputes(ctx, api.DisputeOpen, 1)
+}
+
+func TestDisputeEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	const txID = "00000000-0000-4000-8000-000000000001"
+
+	disputes := &stubDisputes{txID: txID}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithDisputes(middlewares.NewAPIKeyAuth([]string{hash}), disputes).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(`{"tx_id":"`+txID+`"}`)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Nil(t, disputes.dispute)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{`{`, `{}`, `{"tx_id":"` + txID + `","amount":"-1"}`} {
+			rec := serve(httptest.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(body)))
+			require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(`{"tx_id":"unknown"}`)))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/disputes?status=closed", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(`{"tx_id":"`+txID+`","amount":"10","reason":"not received"}`)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		dispute := &api.Dispute{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(dispute))
+		require.Equal(t, api.DisputeOpen, dispute.Status)
+		require.Equal(t, "10", dispute.Amount.String())
+		require.Equal(t, middlewares.OperatorID(hash), dispute.History[0].Operator)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/disputes", strings.NewReader(`{"tx_id":"`+txID+`"}`)))
+		require.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/disputes", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/disputes/"+dispute.ID, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/disputes/"+disputes.dispute.ID+"/reverse", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/disputes/"+disputes.dispute.ID+"/release", nil))
+		require.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/disputes/unknown/release", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("History by transaction", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/transactions/"+txID+"/disputes", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		history := []*api.Dispute{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&history))
+		require.Len(t, history, 1)
+		require.Equal(t, api.DisputeReversed, history[0].Status)
+		require.Len(t, history[0].History, 2)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/transactions/unknown/disputes", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubMetadata amends the metadata of a single ledger entry.
+type stubMetadata struct {
+	txID     string
+	metadata *api.TransactionMetadata
+}
+
+func (s *stubMetadata) AmendTransactionMetadata(ctx context.Context, txID string, request *api.AmendMetadataRequest, operator string) (*api.TransactionMetadata, error) {
+	metadata, err := s.GetTransactionMetadata(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Reference != nil {
+		if len(*request.Reference) > 255 {
+			return nil, api.ErrInvalidMetadata
+		}
+
+		metadata.Edits = append(metadata.Edits, &api.MetadataEdit{
+			Field: api.MetadataReference, Previous: metadata.Reference, Value: *request.Reference, Operator: operator,
+		})
+		metadata.Reference = *request.Reference
+	}
+
+	return metadata, nil
+}
+
+func (s *stubMetadata) GetTransactionMetadata(_ context.Context, txID string) (*api.TransactionMetadata, error) {
+	if txID != s.txID {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	if s.metadata == nil {
+		s.metadata = &api.TransactionMetadata{TxID: txID, Edits: []*api.MetadataEdit{}}
+	}
+
+	return s.metadata, nil
+}
+
+func TestTransactionMetadataEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	const txID = "00000000-0000-4000-8000-000000000001"
+
+	metadata := &stubMetadata{txID: txID}
+
+	cache := &stubCache{entries: map[string]string{
+		"/transactions/" + txID:             `{"tx_id":"` + txID + `"}`,
+		"/transactions/" + txID + "?tz=UTC": `{"tx_id":"` + txID + `"}`,
+		"/transactions/" + txID + "0":       `{"tx_id":"` + txID + `0"}`,
+	}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithTransactionMetadata(middlewares.NewAPIKeyAuth([]string{hash}), metadata).
+		WithCacheInvalidation(middlewares.NewAPIKeyAuth([]string{hash}), cache).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/transactions/"+txID+"/metadata", strings.NewReader(`{"reference":"INV-1"}`)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Nil(t, metadata.metadata)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{`{`, `{}`} {
+			rec := serve(httptest.NewRequest(http.MethodPatch, "/transactions/"+txID+"/metadata", strings.NewReader(body)))
+			require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+
+		rec := serve(httptest.NewRequest(http.MethodPatch, "/transactions/"+txID+"/metadata", strings.NewReader(`{"reference":"`+strings.Repeat("x", 256)+`"}`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidMetadata))
+
+		rec = serve(httptest.NewRequest(http.MethodPatch, "/transactions/unknown/metadata", strings.NewReader(`{"reference":"INV-1"}`)))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Amend", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPatch, "/transactions/"+txID+"/metadata", strings.NewReader(`{"reference":"INV-1"}`)))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		amended := &api.TransactionMetadata{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(amended))
+		require.Equal(t, "INV-1", amended.Reference)
+		require.Len(t, amended.Edits, 1)
+		require.Equal(t, middlewares.OperatorID(hash), amended.Edits[0].Operator)
+		require.Equal(t, map[string]string{
+			"/transactions/" + txID + "0": `{"tx_id":"` + txID + `0"}`,
+		}, cache.entries, "the cached entry is invalidated, with its query strings")
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/transactions/"+txID+"/metadata", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "INV-1")
+	})
+}
+
+// stubReversals reverses a single ledger entry, once.
+type stubReversals struct {
+	txID     string
+	reversed *api.ReversalReceipt
+}
+
+func (s *stubReversals) ReverseTransaction(_ context.Context, txID string, request *api.ReverseRequest, operator string) (*api.ReversalReceipt, error) {
+	if txID != s.txID {
+		return nil, api.ErrTransactionNotFound
+	}
+
+	if s.reversed != nil {
+		return nil, api.ErrTransactionReversed
+	}
+
+	transfer := &api.TransferRequest{FromAccountID: "merchant", ToAccountID: "payer", Currency: "USD", Amount: decimal.NewFromInt(10), Remarks: request.Remarks}
+
+	s.reversed = &api.ReversalReceipt{
+		ReversalOf: txID,
+		TransferReceipt: &api.TransferReceipt{
+			GroupID: api.ReversalGroupID(txID),
+			Request: *transfer,
+			Debit:   &api.Transaction{AccountID: "merchant", Type: api.DEBIT, Amount: transfer.Amount, Remarks: operator},
+			Credit:  &api.Transaction{AccountID: "payer", Type: api.CREDIT, Amount: transfer.Amount, Remarks: operator},
+		},
+	}
+
+	return s.reversed, nil
+}
+
+func TestReversalEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	const txID = "00000000-0000-4000-8000-000000000001"
+
+	reversals := &stubReversals{txID: txID}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithReversals(middlewares.NewAPIKeyAuth([]string{hash}), reversals).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/transactions/"+txID+"/reverse", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Nil(t, reversals.reversed)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/transactions/"+txID+"/reverse", strings.NewReader(`{`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/transactions/unknown/reverse", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/transactions/"+txID+"/reverse", strings.NewReader(`{"remarks":"refund"}`)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		receipt := &api.ReversalReceipt{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(receipt))
+		require.Equal(t, txID, receipt.ReversalOf)
+		require.Equal(t, api.ReversalGroupID(txID), receipt.GroupID)
+		require.Equal(t, "refund", receipt.Request.Remarks)
+		require.Equal(t, middlewares.OperatorID(hash), receipt.Credit.Remarks)
+	})
+
+	t.Run("Reversed once", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/transactions/"+txID+"/reverse", nil))
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeTransactionReversed))
+	})
+}
+
+// stubStatements holds the subscriptions, validating only the channel.
+type stubStatements struct {
+	subscriptions []*api.StatementSubscription
+}
+
+func (s *stubStatements) SubscribeStatements(_ context.Context, request *api.SubscribeStatementsRequest) (*api.StatementSubscription, error) {
+	if request.Channel != api.StatementWebhook && request.Channel != api.StatementEmail {
+		return nil, api.ErrInvalidStatementChannel
+	}
+
+	subscription := &api.StatementSubscription{
+		ID:          "00000000-0000-4000-8000-000000000020",
+		AccountID:   request.AccountID,
+		Currency:    request.Currency,
+		Channel:     request.Channel,
+		Destination: request.Destination,
+	}
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return subscription, nil
+}
+
+func (s *stubStatements) GetStatementSubscriptions(context.Context, string) ([]*api.StatementSubscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *stubStatements) DeleteStatementSubscription(_ context.Context, id string) error {
+	for i, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return api.ErrSubscriptionNotFound
+}
+
+func (s *stubStatements) GetStatements(_ context.Context, accountID string, _ int) ([]*api.Statement, error) {
+	return []*api.Statement{{ID: "statement1", AccountID: accountID, Status: api.StatementDelivered}}, nil
+}
+
+func TestStatementEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	statements := &stubStatements{}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithStatements(middlewares.NewAPIKeyAuth([]string{hash}), statements).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	subscribe := `{"account_id":"user1","currency":"USD","channel":"WEBHOOK","destination":"https://partner.example.com/statements"}`
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/statements/subscriptions", strings.NewReader(subscribe)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, statements.subscriptions)
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/statements/subscriptions", strings.NewReader(subscribe)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/statements/subscriptions", strings.NewReader(`{"account_id":"user1","channel":"SMS"}`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/statements/subscriptions?account_id=user1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		subscriptions := []*api.StatementSubscription{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&subscriptions))
+		require.Len(t, subscriptions, 1)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/statements/subscriptions", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Statements", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/statements?account_id=user1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/statements?account_id=user1&limit=0", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodDelete, "/admin/statements/subscriptions/00000000-0000-4000-8000-000000000020", nil))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodDelete, "/admin/statements/subscriptions/00000000-0000-4000-8000-000000000020", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubReceipts holds the receipt subscriptions, validating only the destination.
+type stubReceipts struct {
+	subscriptions []*api.ReceiptSubscription
+}
+
+func (s *stubReceipts) SubscribeReceipts(_ context.Context, request *api.SubscribeReceiptsRequest) (*api.ReceiptSubscription, error) {
+	if !strings.Contains(request.Destination, "@") {
+		return nil, api.ErrInvalidReceiptDestination
+	}
+
+	subscription := &api.ReceiptSubscription{
+		ID:          "00000000-0000-4000-8000-000000000040",
+		AccountID:   request.AccountID,
+		Destination: request.Destination,
+	}
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return subscription, nil
+}
+
+func (s *stubReceipts) GetReceiptSubscriptions(context.Context, string) ([]*api.ReceiptSubscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *stubReceipts) DeleteReceiptSubscription(_ context.Context, id string) error {
+	for i, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return api.ErrReceiptSubscriptionNotFound
+}
+
+func (s *stubReceipts) GetReceipts(_ context.Context, accountID string, _ int) ([]*api.ReceiptNotification, error) {
+	return []*api.ReceiptNotification{{ID: "receipt1", AccountID: accountID, Kind: api.ReceiptDeposit, Status: api.ReceiptSent}}, nil
+}
+
+func TestReceiptEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	receipts := &stubReceipts{}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithReceipts(middlewares.NewAPIKeyAuth([]string{hash}), receipts).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	subscribe := `{"account_id":"user1","destination":"user1@example.com"}`
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/receipts/subscriptions", strings.NewReader(subscribe)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, receipts.subscriptions)
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/receipts/subscriptions", strings.NewReader(subscribe)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/receipts/subscriptions", strings.NewReader(`{"account_id":"user1","destination":"nowhere"}`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidReceiptDestination))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/receipts/subscriptions?account_id=user1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		subscriptions := []*api.ReceiptSubscription{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&subscriptions))
+		require.Len(t, subscriptions, 1)
+	})
+
+	t.Run("Receipts", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/receipts?account_id=user1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.ReceiptSent))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/receipts", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodDelete, "/admin/receipts/subscriptions/00000000-0000-4000-8000-000000000040", nil))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodDelete, "/admin/receipts/subscriptions/00000000-0000-4000-8000-000000000040", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubWebhooks holds the webhook subscriptions and the rotations of their secrets, validating only the url.
+type stubWebhooks struct {
+	subscriptions []*api.WebhookSubscription
+	rotations     []*api.WebhookSecretRotation
+}
+
+func (s *stubWebhooks) SubscribeWebhook(_ context.Context, request *api.SubscribeWebhookRequest) (*api.WebhookSubscription, error) {
+	if !strings.HasPrefix(request.URL, "https://") {
+		return nil, api.ErrInvalidWebhook
+	}
+
+	subscription := &api.WebhookSubscription{
+		ID:        "00000000-0000-4000-8000-000000000030",
+		URL:       request.URL,
+		AccountID: request.AccountID,
+		Currency:  request.Currency,
+	}
+
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return subscription, nil
+}
+
+func (s *stubWebhooks) GetWebhookSubscriptions(_ context.Context, accountID string) ([]*api.WebhookSubscription, error) {
+	subscriptions := []*api.WebhookSubscription{}
+
+	for _, subscription := range s.subscriptions {
+		if accountID == "" || subscription.AccountID == accountID {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (s *stubWebhooks) DeleteWebhookSubscription(_ context.Context, id string) error {
+	for i, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return api.ErrWebhookNotFound
+}
+
+func (s *stubWebhooks) RotateWebhookSecret(_ context.Context, id string, request *api.RotateWebhookSecretRequest, operator string) (*api.WebhookSecretRotation, error) {
+	if request.GracePeriodSeconds != nil && *request.GracePeriodSeconds < 0 {
+		return nil, api.ErrInvalidGracePeriod
+	}
+
+	for _, subscription := range s.subscriptions {
+		if subscription.ID == id {
+			rotation := &api.WebhookSecretRotation{ID: int64(len(s.rotations) + 1), SubscriptionID: id, Operator: operator}
+			s.rotations = append(s.rotations, rotation)
+
+			return &api.WebhookSecretRotation{ID: rotation.ID, SubscriptionID: id, Operator: operator, Secret: "whsec_new"}, nil
+		}
+	}
+
+	return nil, api.ErrWebhookNotFound
+}
+
+func (s *stubWebhooks) GetWebhookSecretRotations(_ context.Context, id string) ([]*api.WebhookSecretRotation, error) {
+	rotations := []*api.WebhookSecretRotation{}
+
+	for _, rotation := range s.rotations {
+		if rotation.SubscriptionID == id {
+			rotations = append(rotations, rotation)
+		}
+	}
+
+	return rotations, nil
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	webhooks := &stubWebhooks{}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithWebhooks(middlewares.NewAPIKeyAuth([]string{hash}), webhooks).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	subscribe := `{"url":"https://merchant.example.com/events","account_id":"merchant","currency":"USD"}`
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(subscribe)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, webhooks.subscriptions)
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(subscribe)))
+		require.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(`{"url":"ftp://example.com"}`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidWebhook))
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(`{"url":"https://example.com/events"}`)))
+		require.Equal(t, http.StatusCreated, rec.Code, "global")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/webhooks?account_id=merchant", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		subscriptions := []*api.WebhookSubscription{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&subscriptions))
+		require.Len(t, subscriptions, 1)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "2", rec.Header().Get(api.TotalCountHeader))
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&subscriptions))
+		require.Len(t, subscriptions, 2)
+	})
+
+	t.Run("Rotate secret", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks/00000000-0000-4000-8000-000000000030/secret", nil))
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+		rotation := &api.WebhookSecretRotation{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(rotation))
+		require.Equal(t, "whsec_new", rotation.Secret)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks/00000000-0000-4000-8000-000000000030/secret",
+			strings.NewReader(`{"grace_period_seconds":-1}`)))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidGracePeriod))
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/webhooks/unknown/secret", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/webhooks/00000000-0000-4000-8000-000000000030/rotations", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "1", rec.Header().Get(api.TotalCountHeader))
+		require.NotContains(t, rec.Body.String(), "whsec_new", "the secret is only shown once")
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodDelete, "/admin/webhooks/00000000-0000-4000-8000-000000000030", nil))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodDelete, "/admin/webhooks/unknown", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubEventLog holds the events, their cursor is their position.
+type stubEventLog struct {
+	events []*api.LoggedEvent
+}
+
+func (s *stubEventLog) GetEventLog(_ context.Context, since string, limit int) (*api.EventLog, error) {
+	start := 0
+
+	if since != "" {
+		parsed, err := strconv.Atoi(since)
+		if err != nil {
+			return nil, api.ErrInvalidCursor
+		}
+
+		start = parsed
+	}
+
+	log := &api.EventLog{Events: []*api.LoggedEvent{}, NextCursor: since}
+
+	for _, event := range s.events[min(start, len(s.events)):min(start+limit, len(s.events))] {
+		log.Events = append(log.Events, event)
+		log.NextCursor = event.Cursor
+	}
+
+	return log, nil
+}
+
+func (s *stubEventLog) RedeliverEvent(_ context.Context, id string) (*api.LoggedEvent, error) {
+	for _, event := range s.events {
+		if event.ID == id {
+			event.PublishedAt = nil
+
+			return event, nil
+		}
+	}
+
+	return nil, api.ErrEventNotFound
+}
+
+func TestEventLogEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	publishedAt := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	eventLog := &stubEventLog{events: []*api.LoggedEvent{
+		{Event: api.Event{ID: "00000000-0000-4000-8000-000000000041", Type: api.EventAccountCreated}, Cursor: "1", PublishedAt: &publishedAt},
+		{Event: api.Event{ID: "00000000-0000-4000-8000-000000000042", Type: api.EventTransferCreated}, Cursor: "2", PublishedAt: &publishedAt},
+	}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithEventLog(middlewares.NewAPIKeyAuth([]string{hash}), eventLog).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/events", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Backfill", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/events?limit=1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		log := &api.EventLog{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(log))
+		require.Len(t, log.Events, 1)
+		require.Equal(t, "1", log.NextCursor)
+		require.Equal(t, log.NextCursor, rec.Header().Get(api.NextCursorHeader))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/events?since="+log.NextCursor, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(log))
+		require.Len(t, log.Events, 1)
+		require.Equal(t, api.EventTransferCreated, log.Events[0].Type)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/events?since=invalid", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidCursor))
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/events?limit=0", nil))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Redeliver", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/events/00000000-0000-4000-8000-000000000042/redeliver", nil))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		require.Nil(t, eventLog.events[1].PublishedAt)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/events/unknown/redeliver", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// stubPersonalData holds the erasures, the company account is protected.
+type stubPersonalData struct {
+	erasures map[string]*api.ErasureRequest
+}
+
+func (s *stubPersonalData) ExportPersonalData(_ context.Context, accountID string) (*api.PersonalData, error) {
+	if accountID != "user1" {
+		return nil, api.ErrAccountNotFound
+	}
+
+	return &api.PersonalData{
+		AccountID:    accountID,
+		Balances:     []*api.Account{{AccountID: accountID, Currency: "USD"}},
+		Transactions: []*api.Transaction{{TxID: "tx1", AccountID: accountID, Remarks: "rent"}},
+		Erasure:      s.erasures[accountID],
+	}, nil
+}
+
+func (s *stubPersonalData) RequestErasure(_ context.Context, accountID, operator string) (*api.ErasureRequest, error) {
+	if accountID == api.CompanyAccountID {
+		return nil, api.ErrProtectedAccount
+	}
+
+	erasure := &api.ErasureRequest{
+		ID:          "00000000-0000-4000-8000-000000000030",
+		AccountID:   accountID,
+		Status:      api.ErasurePending,
+		RequestedBy: operator,
+	}
+	s.erasures[accountID] = erasure
+
+	return erasure, nil
+}
+
+func (s *stubPersonalData) GetErasure(_ context.Context, accountID string) (*api.ErasureRequest, error) {
+	erasure, ok := s.erasures[accountID]
+	if !ok {
+		return nil, api.ErrErasureNotFound
+	}
+
+	return erasure, nil
+}
+
+func TestPersonalDataEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	personalData := &stubPersonalData{erasures: map[string]*api.ErasureRequest{}}
+
+	cache := &stubCache{entries: map[string]string{
+		"/transactions/tx1": `{"tx_id":"tx1","account_id":"user1","remarks":"rent"}`,
+		"/transactions/tx2": `{"tx_id":"tx2","account_id":"user2","remarks":"rent"}`,
+	}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithPersonalData(middlewares.NewAPIKeyAuth([]string{hash}), personalData).
+		WithCacheInvalidation(middlewares.NewAPIKeyAuth([]string{hash}), cache).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/accounts/user1/erasure", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, personalData.erasures)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/accounts/user1/personal-data", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		data := &api.PersonalData{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(data))
+		require.Equal(t, "rent", data.Transactions[0].Remarks)
+		require.Nil(t, data.Erasure)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/accounts/user2/personal-data", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Erasure", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/admin/accounts/user1/erasure", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/accounts/user1/erasure", nil))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+
+		erasure := &api.ErasureRequest{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(erasure))
+		require.Equal(t, api.ErasurePending, erasure.Status)
+		require.NotEmpty(t, erasure.RequestedBy)
+		require.Equal(t, map[string]string{
+			"/transactions/tx2": `{"tx_id":"tx2","account_id":"user2","remarks":"rent"}`,
+		}, cache.entries, "the cached transactions of the account are purged")
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/accounts/user1/erasure", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/accounts/company/erasure", nil))
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+}
+
+type activityFunc func(ctx context.Context, accountID, currency string, limit int) ([]*api.AccountActivity, error)
+
+func (f activityFunc) GetAccountActivity(ctx context.Context, accountID, currency string, limit int) ([]*api.AccountActivity, error) {
+	return f(ctx, accountID, currency, limit)
+}
+
+func TestActivityEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		httpServer := NewAPIServer(&repository.MockRepository{}).HTTPServer(8080, time.Second, time.Second)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account/user1/USD/activity", nil))
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	var limits []int
+
+	feed := activityFunc(func(_ context.Context, accountID, currency string, limit int) ([]*api.AccountActivity, error) {
+		limits = append(limits, limit)
+
+		if currency == "XXXXXXXXXXX" {
+			return nil, api.ErrInvalidCurrency
+		}
+
+		return []*api.AccountActivity{{
+			TxID:           "tx1",
+			AccountID:      accountID,
+			Currency:       currency,
+			Direction:      api.ActivityIn,
+			CounterpartyID: api.CompanyAccountID,
+			Amount:         decimal.RequireFromString("10.005"),
+			RunningBalance: decimal.RequireFromString("10.005"),
+		}}, nil
+	})
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithRounding(rounding.New(map[string]api.RoundingPolicy{"USD": {Mode: api.RoundHalfEven, Scale: 2}})).
+		WithActivityFeed(feed).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	rec := serve("/account/user1/USD/activity?limit=10")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	activities := []*api.AccountActivity{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&activities))
+	require.Len(t, activities, 1)
+	require.Equal(t, api.ActivityIn, activities[0].Direction)
+	require.Equal(t, "10", activities[0].RunningBalance.String(), "rounded")
+	require.Equal(t, []int{10}, limits)
+
+	rec = serve("/account/user1/USD/activity")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, []int{10, defaultReviewsLimit}, limits)
+
+	rec = serve("/account/user1/USD/activity?limit=0")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve("/account/user1/XXXXXXXXXXX/activity")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// stubProvisioning opens the accounts in memory.
+type stubProvisioning struct {
+	opened map[string]bool
+}
+
+func (s *stubProvisioning) OpenAccount(_ context.Context, accountID, currency string) (*api.Account, error) {
+	if currency == "" || len(currency) > 10 {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	s.opened[accountID+"/"+currency] = true
+
+	return &api.Account{AccountID: accountID, Currency: currency, Balance: decimal.Zero}, nil
+}
+
+func TestProvisioningEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	provisioning := &stubProvisioning{opened: map[string]bool{}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithAccountProvisioning(middlewares.NewAPIKeyAuth([]string{hash}), provisioning).
+		HTTPServer(8080, time.Second, time.Second)
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/accounts/merchant/USD", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, provisioning.opened)
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/accounts/merchant/USD", nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.True(t, provisioning.opened["merchant/USD"])
+
+		account := &api.Account{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(account))
+		require.Equal(t, "merchant", account.AccountID)
+	})
+
+	t.Run("Invalid currency", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/accounts/merchant/CURRENCYCODE", nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidCurrency))
+	})
+}
+
+// stubRebuilder rebuilds the balances of the known accounts to their ledger balance.
+type stubRebuilder struct {
+	ledger map[string]decimal.Decimal
+}
+
+func (s *stubRebuilder) RebuildBalance(_ context.Context, accountID, currency string) (*api.BalanceRebuild, error) {
+	balance, ok := s.ledger[accountID+"/"+currency]
+	if !ok {
+		return nil, api.ErrAccountNotFound
+	}
+
+	previous := balance.Add(decimal.NewFromInt(1))
+
+	return &api.BalanceRebuild{AccountID: accountID, Currency: currency, PreviousBalance: previous, Balance: balance, Drift: previous.Sub(balance)}, nil
+}
+
+func TestBalanceRebuildEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	rebuilder := &stubRebuilder{ledger: map[string]decimal.Decimal{"user1/USD": decimal.NewFromInt(100)}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithBalanceRebuilds(middlewares.NewAPIKeyAuth([]string{hash}), rebuilder).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/accounts/user1/USD/rebuild-balance", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Rebuild", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/accounts/user1/USD/rebuild-balance", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rebuild := &api.BalanceRebuild{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(rebuild))
+		require.True(t, decimal.NewFromInt(100).Equal(rebuild.Balance))
+		require.True(t, decimal.NewFromInt(1).Equal(rebuild.Drift))
+	})
+
+	t.Run("Unknown account", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/admin/accounts/user2/USD/rebuild-balance", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeAccountNotFound))
+	})
+}
+
+// stubReadOnly keeps the switch in memory.
+type stubReadOnly struct {
+	enabled bool
+}
+
+func (s *stubReadOnly) ReadOnly(context.Context) bool {
+	return s.enabled
+}
+
+func (s *stubReadOnly) Status(context.Context) (*api.ReadOnlyStatus, error) {
+	return &api.ReadOnlyStatus{Enabled: s.enabled}, nil
+}
+
+func (s *stubReadOnly) Set(_ context.Context, enabled bool) (*api.ReadOnlyStatus, error) {
+	s.enabled = enabled
+
+	return &api.ReadOnlyStatus{Enabled: enabled}, nil
+}
+
+func TestReadOnly(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	mode := &stubReadOnly{enabled: true}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithBalanceRebuilds(middlewares.NewAPIKeyAuth([]string{hash}), &stubRebuilder{}).
+		WithReadOnly(mode).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Mutations rejected", func(t *testing.T) {
+		for _, path := range []string{"/deposit", "/withdraw", "/transfer", "/admin/accounts/user1/USD/rebuild-balance"} {
+			rec := serve(httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}")))
+			require.Equal(t, http.StatusServiceUnavailable, rec.Code, path)
+			require.Contains(t, rec.Body.String(), string(api.CodeReadOnly), path)
+		}
+	})
+
+	t.Run("Rejected in MessagePack", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/deposit", strings.NewReader("{}"))
+		req.Header.Set("Accept", msgpack.ContentType)
+
+		rec := serve(req)
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Equal(t, msgpack.ContentType, rec.Header().Get("Content-Type"))
+
+		var response api.ErrorResponse
+		require.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &response))
+		require.Equal(t, api.CodeReadOnly, response.Code)
+	})
+
+	t.Run("Reads served", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/health", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(httptest.NewRequest(http.MethodGet, "/admin/read-only", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		status := &api.ReadOnlyStatus{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(status))
+		require.True(t, status.Enabled)
+	})
+
+	t.Run("Switched off", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPut, "/admin/read-only", strings.NewReader(`{"enabled":false}`)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.False(t, mode.enabled)
+
+		rec = serve(httptest.NewRequest(http.MethodPost, "/admin/accounts/user1/USD/rebuild-balance", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/read-only", strings.NewReader(`{"enabled":true}`)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.False(t, mode.enabled)
+	})
+}
+
+// stubConflicts keeps the idempotency conflicts in memory.
+type stubConflicts struct {
+	conflicts []*api.IdempotencyConflict
+}
+
+func (s *stubConflicts) RecordIdempotencyConflict(_ context.Context, conflict *api.IdempotencyConflict) error {
+	conflict.ID = strconv.Itoa(len(s.conflicts) + 1)
+	s.conflicts = append(s.conflicts, conflict)
+
+	return nil
+}
+
+func (s *stubConflicts) GetIdempotencyConflicts(_ context.Context, idempotencyKey string, limit int) ([]*api.IdempotencyConflict, error) {
+	conflicts := []*api.IdempotencyConflict{}
+
+	for _, conflict := range s.conflicts {
+		if (idempotencyKey == "" || conflict.IdempotencyKey == idempotencyKey) && len(conflicts) < limit {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	return conflicts, nil
+}
+
+func TestIdempotencyConflicts(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	mockRepo := repository.NewMockRepository(t)
+	mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "order-42").
+		Return(nil, &api.DuplicateTransactionError{GroupID: "order-42"}).Once()
+	mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "order-43").
+		Return(nil, api.ErrTransferInProgress).Once()
+	mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "order-44").
+		Return(nil, api.ErrInsufficientBalance).Once()
+
+	conflicts := &stubConflicts{}
+
+	httpServer := NewAPIServer(mockRepo).
+		WithCustomLogger(logging.Discard()).
+		WithIdempotencyConflicts(middlewares.NewAPIKeyAuth([]string{hash}), conflicts).
+		WithTransferBatches(&stubBatches{}).
+		WithHolds(&stubHolds{holds: map[string]*api.Hold{}}).
+		HTTPServer(8080, time.Second, time.Second)
+
+	transfer := func(idempotencyKey string, amount string) int {
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(
+			`{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "`+amount+`"}`))
+		req.RemoteAddr = "203.0.113.7:51234"
+		req.Header.Set("X-Idempotency-Key", idempotencyKey)
+		req.Header.Set("Authorization", "Bearer partner-key")
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusConflict, transfer("order-42", "10"))
+	require.Equal(t, http.StatusConflict, transfer("order-43", "10.0"))
+	require.Equal(t, http.StatusUnprocessableEntity, transfer("order-44", "10"))
+
+	t.Run("Recorded", func(t *testing.T) {
+		require.Len(t, conflicts.conflicts, 2, "only the idempotency conflicts")
+
+		duplicate, inProgress := conflicts.conflicts[0], conflicts.conflicts[1]
+		require.Equal(t, "order-42", duplicate.IdempotencyKey)
+		require.Equal(t, api.CodeDuplicateTransaction, duplicate.Code)
+		require.Equal(t, "203.0.113.7", duplicate.ClientIP)
+		require.NotEmpty(t, duplicate.APIKeyID)
+		require.NotEmpty(t, duplicate.RequestID)
+		require.Len(t, duplicate.Fingerprint, 64)
+
+		require.Equal(t, api.CodeTransferInProgress, inProgress.Code)
+		require.Equal(t, duplicate.Fingerprint, inProgress.Fingerprint, "the same request")
+	})
+
+	t.Run("Batches and holds", func(t *testing.T) {
+		post := func(path, body, idempotencyKey string) int {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+			req.Header.Set("X-Idempotency-Key", idempotencyKey)
+
+			rec := httptest.NewRecorder()
+			httpServer.Handler.ServeHTTP(rec, req)
+
+			return rec.Code
+		}
+
+		transfer := `{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"}`
+		batch := `{"transfers": [` + transfer + `, ` + transfer + `]}`
+
+		require.Equal(t, http.StatusCreated, post("/transfers/batch", batch, "payroll-1"))
+		require.Equal(t, http.StatusConflict, post("/transfers/batch", batch, "payroll-1"))
+		require.Equal(t, http.StatusCreated, post("/holds", transfer, "card-1"))
+		require.Equal(t, http.StatusConflict, post("/holds", transfer, "card-1"))
+
+		require.Len(t, conflicts.conflicts, 4)
+
+		batchConflict, holdConflict := conflicts.conflicts[2], conflicts.conflicts[3]
+		require.Equal(t, "payroll-1", batchConflict.IdempotencyKey)
+		require.Equal(t, api.CodeDuplicateTransaction, batchConflict.Code)
+		require.Len(t, batchConflict.Fingerprint, 64)
+		require.Equal(t, "card-1", holdConflict.IdempotencyKey)
+		require.Equal(t, conflicts.conflicts[0].Fingerprint, holdConflict.Fingerprint, "the same transfer as order-42")
+		require.NotEqual(t, holdConflict.Fingerprint, batchConflict.Fingerprint)
+	})
+
+	t.Run("Listed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/idempotency-conflicts?idempotency_key=order-43", nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		listed := []*api.IdempotencyConflict{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+		require.Len(t, listed, 1)
+		require.Equal(t, api.CodeTransferInProgress, listed[0].Code)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/idempotency-conflicts", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+// stubDownloads renders the entries to the writer, failing after them if err is set.
+type stubDownloads struct {
+	entries []*api.Transaction
+	err     error
+	from    time.Time
+	to      time.Time
+}
+
+func (s *stubDownloads) DownloadStatement(_ context.Context, accountID, currency string, from, to time.Time, w api.StatementWriter) error {
+	s.from, s.to = from, to
+
+	if !from.Before(to) {
+		return api.ErrInvalidStatementPeriod
+	}
+
+	statement := &api.Statement{AccountID: accountID, Currency: currency, PeriodStart: from, PeriodEnd: to}
+	if err := w.Begin(statement); err != nil {
+		return err
+	}
+
+	for _, entry := range s.entries {
+		if err := w.Entry(entry); err != nil {
+			return err
+		}
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return w.End(statement)
+}
+
+func TestDownloadStatement(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	downloads := &stubDownloads{entries: []*api.Transaction{{
+		TxID: "tx1", Type: api.CREDIT, Amount: decimal.NewFromInt(10), RunningBalance: decimal.NewFromInt(10),
+	}}}
+
+	httpServer := NewAPIServer(repository.NewMockRepository(t)).
+		WithCustomLogger(logging.Discard()).
+		WithStatementDownloads(downloads).
+		HTTPServer(8080, time.Second, time.Second)
+
+	download := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/statements/user1/USD?"+query, nil)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		rec := download("from=2024-01-01&to=2024-02-01T00:00:00Z")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Equal(t, `attachment; filename=statement-user1-USD-2024-01-01.csv`, rec.Header().Get("Content-Disposition"))
+		require.Contains(t, rec.Body.String(), "tx1,CREDIT,10,10")
+		require.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), downloads.to)
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		rec := download("from=2024-01-01&to=2024-02-01&format=PDF")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+		require.True(t, strings.HasPrefix(rec.Body.String(), "%PDF-"))
+	})
+
+	invalid := map[string]string{
+		"Unknown format":  "from=2024-01-01&to=2024-02-01&format=xlsx",
+		"Missing period":  "from=2024-01-01",
+		"Reversed period": "from=2024-02-01&to=2024-01-01",
+	}
+
+	for name, query := range invalid {
+		t.Run(name, func(t *testing.T) {
+			rec := download(query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Empty(t, rec.Header().Get("Content-Disposition"))
+		})
+	}
+
+	t.Run("Failed midway", func(t *testing.T) {
+		downloads.err = errors.New("connection reset")
+
+		rec := download("from=2024-01-01&to=2024-02-01")
+		require.Equal(t, http.StatusOK, rec.Code, "the headers were sent with the first entries")
+		require.NotContains(t, rec.Body.String(), api.CodeUnexpected, "the statement is truncated")
+	})
+}
+
+type stubSubscriptions struct {
+	entries []*api.Transaction
+	// resumed is closed once polled after the last entry
+	resumed chan struct{}
+}
+
+func (s *stubSubscriptions) GetTransactionsAfter(_ context.Context, _, accountID, cursor string, _ time.Time, _ int) ([]*api.Transaction, error) {
+	if _, _, err := api.ParseTransactionCursor(cursor); err != nil {
+		return nil, err
+	}
+
+	if accountID == "" || strings.ContainsRune(accountID, ' ') {
+		return nil, api.ErrInvalidAccountID
+	}
+
+	for i, entry := range s.entries {
+		if api.TransactionCursor(entry) == cursor {
+			if i == len(s.entries)-1 {
+				select {
+				case <-s.resumed:
+				default:
+					close(s.resumed)
+				}
+			}
+
+			return s.entries[i+1:], nil
+		}
+	}
+
+	return s.entries, nil
+}
+
+func TestSubscribeTransactions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	postedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	subscriptions := &stubSubscriptions{entries: []*api.Transaction{
+		{TxID: "tx1", Type: api.CREDIT, Amount: decimal.NewFromInt(10), Time: postedAt},
+		{TxID: "tx2", Type: api.DEBIT, Amount: decimal.NewFromInt(3), Time: postedAt},
+	}, resumed: make(chan struct{})}
+
+	httpServer := NewAPIServer(repository.NewMockRepository(t)).
+		WithCustomLogger(logging.Discard()).
+		WithTransactionSubscriptions(subscriptions, 10*time.Millisecond).
+		HTTPServer(8080, time.Second, time.Second)
+
+	t.Run("Streams the entries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req := httptest.NewRequest(http.MethodGet, "/transactions/user1/USD/events", nil).WithContext(ctx)
+		req.Header.Set(api.LastEventIDHeader, api.NewTransactionCursor(postedAt, ""))
+
+		rec := httptest.NewRecorder()
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			httpServer.Handler.ServeHTTP(rec, req)
+		}()
+
+		select {
+		case <-subscriptions.resumed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the subscription didn't poll after the last entry")
+		}
+
+		cancel()
+		<-done
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+		body := rec.Body.String()
+		require.Contains(t, body, "id: "+api.TransactionCursor(subscriptions.entries[0])+"\nevent: transaction\ndata: {")
+		require.Contains(t, body, "id: "+api.TransactionCursor(subscriptions.entries[1])+"\nevent: transaction\ndata: {")
+		require.Less(t, strings.Index(body, `"tx1"`), strings.Index(body, `"tx2"`))
+	})
+
+	invalid := map[string]string{
+		"Invalid cursor":     "/transactions/user1/USD/events?since=not-a-cursor!",
+		"Invalid account id": "/transactions/user%201/USD/events",
+	}
+
+	for name, target := range invalid {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+
+			rec := httptest.NewRecorder()
+			httpServer.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.NotEqual(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+type stubStreams struct {
+	entries []*api.Transaction
+	// err fails the stream after its entries
+	err error
+}
+
+func (s *stubStreams) StreamTransactions(_ context.Context, _, accountID string, filter api.TransactionFilter, cursor string, fn func(*api.Transaction) error) error {
+	if cursor != "" {
+		if _, _, err := api.ParseTransactionCursor(cursor); err != nil {
+			return err
+		}
+	}
+
+	if accountID == "" || strings.ContainsRune(accountID, ' ') {
+		return api.ErrInvalidAccountID
+	}
+
+	for _, entry := range s.entries {
+		if (filter.Type != "" && entry.Type != filter.Type) || entry.Time.Before(filter.From) {
+			continue
+		}
+
+		// a copy, as the handler rounds and renders it
+		transaction := *entry
+		if err := fn(&transaction); err != nil {
+			return err
+		}
+	}
+
+	return s.err
+}
+
+func TestStreamTransactions(t *testing.T) {
+	postedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	streams := &stubStreams{entries: []*api.Transaction{
+		{TxID: "tx1", Type: api.CREDIT, Amount: decimal.NewFromInt(10), Time: postedAt},
+		{TxID: "tx2", Type: api.DEBIT, Amount: decimal.NewFromInt(3), Time: postedAt.Add(time.Hour)},
+	}}
+
+	httpServer := NewAPIServer(repository.NewMockRepository(t)).
+		WithCustomLogger(logging.Discard()).
+		WithTransactionStreams(streams).
+		HTTPServer(8080, time.Second, time.Second)
+
+	stream := func(target string) (*httptest.ResponseRecorder, []*api.Transaction) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		// the streams aren't transcoded
+		req.Header.Set("Accept", "application/msgpack")
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		var transactions []*api.Transaction
+
+		if rec.Code == http.StatusOK {
+			for _, line := range strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n") {
+				if line == "" {
+					continue
+				}
+
+				transaction := &api.Transaction{}
+				require.NoError(t, json.Unmarshal([]byte(line), transaction))
+
+				transactions = append(transactions, transaction)
+			}
+		}
+
+		return rec, transactions
+	}
+
+	t.Run("Streams the entries", func(t *testing.T) {
+		rec, transactions := stream("/transactions/user1/USD/stream?tz=Asia/Manila")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, api.TransactionStreamContentType, rec.Header().Get("Content-Type"))
+		require.Equal(t, api.StreamComplete, rec.Result().Trailer.Get(api.StreamStatusTrailer))
+		require.True(t, rec.Flushed)
+
+		require.Len(t, transactions, 2)
+		require.Equal(t, "tx1", transactions[0].TxID)
+		require.Equal(t, "tx2", transactions[1].TxID)
+		require.Equal(t, "+08:00", transactions[0].Time.Format("-07:00"))
+		require.Equal(t, api.TransactionCursor(streams.entries[0]), api.TransactionCursor(transactions[0]),
+			"the cursors don't depend on the time zone")
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		rec, transactions := stream("/transactions/user1/USD/stream?type=debit")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, transactions, 1)
+		require.Equal(t, "tx2", transactions[0].TxID)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		rec, transactions := stream("/transactions/user1/USD/stream?from=2025-01-01")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, api.TransactionStreamContentType, rec.Header().Get("Content-Type"))
+		require.Equal(t, api.StreamComplete, rec.Result().Trailer.Get(api.StreamStatusTrailer))
+		require.Empty(t, transactions)
+	})
+
+	invalid := map[string]string{
+		"Invalid cursor":     "/transactions/user1/USD/stream?cursor=not-a-cursor!",
+		"Invalid account id": "/transactions/user%201/USD/stream",
+		"Invalid filter":     "/transactions/user1/USD/stream?from=yesterday",
+		"Invalid time zone":  "/transactions/user1/USD/stream?tz=Mars/Olympus",
+	}
+
+	for name, target := range invalid {
+		t.Run(name, func(t *testing.T) {
+			rec, _ := stream(target)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.NotEqual(t, api.TransactionStreamContentType, rec.Header().Get("Content-Type"))
+		})
+	}
+
+	t.Run("Failed midway", func(t *testing.T) {
+		streams.err = errors.New("connection reset")
+		defer func() { streams.err = nil }()
+
+		rec, transactions := stream("/transactions/user1/USD/stream")
+		require.Equal(t, http.StatusOK, rec.Code, "the headers were sent with the first entries")
+		require.Len(t, transactions, 2)
+		require.Equal(t, string(api.CodeFailedToGetTransaction), rec.Result().Trailer.Get(api.StreamStatusTrailer))
+	})
+}
+
+// stubCache keeps the cached responses in a map, scanned in a single batch.
+type stubCache struct {
+	entries map[string]string
+	err     error
+}
+
+func (s *stubCache) Scan(_ context.Context, _ uint64, match string, _ int64) *redis.ScanCmd {
+	var keys []string
+
+	for key := range s.entries {
+		if matched, _ := path.Match(match, key); matched {
+			keys = append(keys, key)
+		}
+	}
+
+	return redis.NewScanCmdResult(keys, 0, s.err)
+}
+
+func (s *stubCache) Get(_ context.Context, key string) *redis.StringCmd {
+	value, ok := s.entries[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+
+	return redis.NewStringResult(value, nil)
+}
+
+func (s *stubCache) Del(_ context.Context, keys ...string) *redis.IntCmd {
+	var deleted int64
+
+	for _, key := range keys {
+		if _, ok := s.entries[key]; ok {
+			delete(s.entries, key)
+			deleted++
+		}
+	}
+
+	return redis.NewIntResult(deleted, nil)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	cache := &stubCache{}
+
+	httpServer := NewAPIServer(repository.NewMockRepository(t)).
+		WithCustomLogger(logging.Discard()).
+		WithCacheInvalidation(middlewares.NewAPIKeyAuth([]string{hash}), cache).
+		HTTPServer(8080, time.Second, time.Second)
+
+	invalidate := func(body string) *httptest.ResponseRecorder {
+		cache.entries = map[string]string{
+			"/transactions/tx1":             `{"tx_id":"tx1","account_id":"user1"}`,
+			"/transactions/tx1?tz=UTC":      `{"tx_id":"tx1","account_id":"user1"}`,
+			"/transactions/tx2":             `{"tx_id":"tx2","account_id":"user2"}`,
+			"/transactions/tx3":             `{"tx_id":"tx3","account_id":"user3"}`,
+			"wallet:read_only":              "1",
+			"wallet:idempotency:transfer-1": "posted",
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/cache/invalidate", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	remaining := func() []string {
+		keys := make([]string, 0, len(cache.entries))
+		for key := range cache.entries {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		return keys
+	}
+
+	t.Run("Paths", func(t *testing.T) {
+		rec := invalidate(`{"paths": ["/transactions/tx1*"]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"deleted": 2}`, rec.Body.String())
+		require.Equal(t, []string{"/transactions/tx2", "/transactions/tx3", "wallet:idempotency:transfer-1", "wallet:read_only"}, remaining())
+	})
+
+	t.Run("Accounts", func(t *testing.T) {
+		rec := invalidate(`{"account_ids": ["user1", "user2"]}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"deleted": 3}`, rec.Body.String())
+		require.Equal(t, []string{"/transactions/tx3", "wallet:idempotency:transfer-1", "wallet:read_only"}, remaining())
+	})
+
+	invalid := map[string]string{
+		"No pattern":        `{}`,
+		"Outside the cache": `{"paths": ["*"]}`,
+		"Empty account":     `{"account_ids": [" "]}`,
+		"Malformed":         `{"paths": "/transactions/*"}`,
+	}
+
+	for name, body := range invalid {
+		t.Run(name, func(t *testing.T) {
+			rec := invalidate(body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Len(t, cache.entries, 6)
+		})
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/cache/invalidate", strings.NewReader(`{"paths": ["/*"]}`))
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Redis down", func(t *testing.T) {
+		cache.err = redis.ErrClosed
+		defer func() { cache.err = nil }()
+
+		rec := invalidate(`{"paths": ["/*"]}`)
+		require.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+// stubBatches posts the batches without a ledger, failing the ones debiting the broke account, and the ones reusing a key.
+type stubBatches struct {
+	posted []*api.TransferRequest
+	keys   []string
+}
+
+func (s *stubBatches) TransferBatch(_ context.Context, requests []*api.TransferRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	if slices.Contains(s.keys, idempotencyKey) {
+		return nil, &api.DuplicateTransactionError{GroupID: api.BatchGroupID(idempotencyKey, 0)}
+	}
+
+	receipt := &api.TransferBatchReceipt{IdempotencyKey: idempotencyKey}
+
+	for i, request := range requests {
+		if request.FromAccountID == "broke" {
+			return nil, &api.BatchTransferError{Index: i, Err: api.ErrInsufficientBalance}
+		}
+
+		groupID := api.BatchGroupID(idempotencyKey, i)
+
+		transfer, err := api.NewTransferReceipt(request, groupID, []*api.Transaction{
+			{TxID: groupID + "-debit", AccountID: request.FromAccountID, Currency: request.Currency, Amount: request.Amount, Type: api.DEBIT, GroupID: groupID},
+			{TxID: groupID + "-credit", AccountID: request.ToAccountID, Currency: request.Currency, Amount: request.Amount, Type: api.CREDIT, GroupID: groupID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		receipt.Transfers = append(receipt.Transfers, transfer)
+	}
+
+	s.posted = requests
+	s.keys = append(s.keys, idempotencyKey)
+
+	return receipt, nil
+}
+
+func TestTransferBatch(t *testing.T) {
+	batches := &stubBatches{}
+
+	repo := repository.NewMockRepository(t)
+	repo.EXPECT().ResolveAlias(mock.Anything, "payee").Return(&api.AccountAlias{Alias: "payee", AccountID: "user3"}, nil).Maybe()
+	repo.EXPECT().ResolveAlias(mock.Anything, "unknown").Return(nil, api.ErrAliasNotFound).Maybe()
+
+	httpServer := NewAPIServer(repo).
+		WithCustomLogger(logging.Discard()).
+		WithTransferBatches(batches).
+		HTTPServer(8080, time.Second, time.Second)
+
+	post := func(body, idempotencyKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transfers/batch", strings.NewReader(body))
+		if idempotencyKey != "" {
+			req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+		}
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Posts the batch", func(t *testing.T) {
+		rec := post(`{"transfers": [
+			{"from_account_id": " user1 ", "to_account_id": "user2", "currency": "USD", "amount": "10", "remarks": " May "},
+			{"from_account_id": "user1", "to_alias": "payee", "currency": "USD", "amount": "5"}
+		]}`, "payroll-1")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		receipt := &api.TransferBatchReceipt{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(receipt))
+		require.Equal(t, "payroll-1", receipt.IdempotencyKey)
+		require.Len(t, receipt.Transfers, 2)
+		require.Equal(t, "payroll-1:1", receipt.Transfers[1].GroupID)
+		require.Equal(t, "user3", receipt.Transfers[1].Credit.AccountID)
+
+		require.Equal(t, "user1", batches.posted[0].FromAccountID)
+		require.Equal(t, "May", batches.posted[0].Remarks)
+	})
+
+	t.Run("Reports the failing transfer", func(t *testing.T) {
+		rec := post(`{"transfers": [
+			{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"},
+			{"from_account_id": "broke", "to_account_id": "user2", "currency": "USD", "amount": "10"}
+		]}`, "payroll-2")
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		require.JSONEq(t, `{"error_code":422,"code":"INSUFFICIENT_BALANCE","message":"transfer 1 of the batch: insufficient balance","index":1}`, rec.Body.String())
+	})
+
+	invalid := map[string]struct {
+		body   string
+		status int
+		code   api.ErrorCode
+		index  int
+	}{
+		"Same accounts":   {`{"transfers": [{"from_account_id": "user1", "to_account_id": "user1", "currency": "USD", "amount": "10"}]}`, http.StatusBadRequest, api.CodeSameAccountIDs, 0},
+		"Company account": {`{"transfers": [{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "1"}, {"from_account_id": "user1", "to_account_id": "` + api.CompanyAccountID + `", "currency": "USD", "amount": "10"}]}`, http.StatusBadRequest, api.CodeCompanyAccount, 1},
+		"Missing amount":  {`{"transfers": [{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD"}]}`, http.StatusBadRequest, api.CodeInvalidRequest, 0},
+		"Unknown alias":   {`{"transfers": [{"from_account_id": "user1", "to_alias": "unknown", "currency": "USD", "amount": "10"}]}`, http.StatusUnprocessableEntity, api.CodeAliasNotFound, 0},
+		"Empty":           {`{"transfers": []}`, http.StatusBadRequest, api.CodeInvalidBatch, -1},
+		"Malformed":       {`{"transfers": {}}`, http.StatusBadRequest, api.CodeInvalidRequest, -1},
+	}
+
+	for name, tc := range invalid {
+		t.Run(name, func(t *testing.T) {
+			batches.posted = nil
+
+			rec := post(tc.body, "payroll-3")
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+
+			response := &api.ErrorResponse{}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(response))
+			require.Equal(t, tc.code, response.Code)
+
+			if tc.index < 0 {
+				require.Nil(t, response.Index)
+			} else {
+				require.Equal(t, tc.index, *response.Index)
+			}
+
+			require.Nil(t, batches.posted)
+		})
+	}
+
+	t.Run("Missing idempotency key", func(t *testing.T) {
+		rec := post(`{"transfers": [{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"}]}`, "")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+// stubHolds keeps the holds in memory, capturing them without a ledger.
+type stubHolds struct {
+	holds map[string]*api.Hold
+}
+
+func (s *stubHolds) PlaceHold(_ context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error) {
+	if request.FromAccountID == "broke" {
+		return nil, api.ErrInsufficientBalance
+	}
+
+	for _, hold := range s.holds {
+		if hold.IdempotencyKey == idempotencyKey {
+			return nil, &api.DuplicateTransactionError{GroupID: idempotencyKey}
+		}
+	}
+
+	hold := &api.Hold{
+		ID:             fmt.Sprintf("hold-%d", len(s.holds)+1),
+		IdempotencyKey: idempotencyKey,
+		FromAccountID:  request.FromAccountID,
+		ToAccountID:    request.ToAccountID,
+		Currency:       request.Currency,
+		Amount:         request.Amount,
+		Remarks:        request.Remarks,
+		Status:         api.HoldAuthorized,
+	}
+
+	s.holds[hold.ID] = hold
+
+	return hold, nil
+}
+
+func (s *stubHolds) GetHold(_ context.Context, id string) (*api.Hold, error) {
+	hold, ok := s.holds[id]
+	if !ok {
+		return nil, api.ErrHoldNotFound
+	}
+
+	return hold, nil
+}
+
+func (s *stubHolds) close(id string, status api.HoldStatus) (*api.Hold, error) {
+	hold, ok := s.holds[id]
+	if !ok {
+		return nil, api.ErrHoldNotFound
+	}
+
+	if hold.Status != api.HoldAuthorized {
+		return nil, api.ErrHoldClosed
+	}
+
+	hold.Status = status
+
+	return hold, nil
+}
+
+func (s *stubHolds) CaptureHold(_ context.Context, id string) (*api.TransferReceipt, error) {
+	hold, err := s.close(id, api.HoldCaptured)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.NewTransferReceipt(hold.Transfer(), hold.IdempotencyKey, []*api.Transaction{
+		{TxID: id + "-debit", AccountID: hold.FromAccountID, Currency: hold.Currency, Amount: hold.Amount, Type: api.DEBIT, GroupID: hold.IdempotencyKey},
+		{TxID: id + "-credit", AccountID: hold.ToAccountID, Currency: hold.Currency, Amount: hold.Amount, Type: api.CREDIT, GroupID: hold.IdempotencyKey},
+	})
+}
+
+func (s *stubHolds) ReleaseHold(_ context.Context, id string) (*api.Hold, error) {
+	return s.close(id, api.HoldReleased)
+}
+
+func TestHolds(t *testing.T) {
+	holds := &stubHolds{holds: map[string]*api.Hold{}}
+
+	repo := repository.NewMockRepository(t)
+
+	httpServer := NewAPIServer(repo).
+		WithCustomLogger(logging.Discard()).
+		WithHolds(holds).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(method, path, body, idempotencyKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if idempotencyKey != "" {
+			req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+		}
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	place := func(t *testing.T, idempotencyKey string) *api.Hold {
+		t.Helper()
+
+		rec := serve(http.MethodPost, "/holds", `{"from_account_id": " user1 ", "to_account_id": "user2", "currency": "USD", "amount": "10", "remarks": " fuel "}`, idempotencyKey)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		hold := &api.Hold{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(hold))
+
+		return hold
+	}
+
+	t.Run("Places then captures the hold", func(t *testing.T) {
+		hold := place(t, "auth-1")
+		require.Equal(t, api.HoldAuthorized, hold.Status)
+		require.Equal(t, "user1", hold.FromAccountID)
+		require.Equal(t, "fuel", hold.Remarks)
+
+		rec := serve(http.MethodGet, "/holds/"+hold.ID, "", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(http.MethodPost, "/holds/"+hold.ID+"/capture", "", "")
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		receipt := &api.TransferReceipt{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(receipt))
+		require.Equal(t, "auth-1", receipt.GroupID)
+		require.Equal(t, "user2", receipt.Credit.AccountID)
+
+		rec = serve(http.MethodPost, "/holds/"+hold.ID+"/release", "", "")
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeHoldClosed))
+	})
+
+	t.Run("Releases the hold", func(t *testing.T) {
+		hold := place(t, "auth-2")
+
+		rec := serve(http.MethodPost, "/holds/"+hold.ID+"/release", "", "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		released := &api.Hold{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(released))
+		require.Equal(t, api.HoldReleased, released.Status)
+
+		rec = serve(http.MethodPost, "/holds/"+hold.ID+"/capture", "", "")
+		require.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("Unknown hold", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/holds/unknown/capture", "", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeHoldNotFound))
+
+		rec = serve(http.MethodGet, "/holds/unknown", "", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	invalid := map[string]struct {
+		body           string
+		idempotencyKey string
+		status         int
+	}{
+		"Insufficient balance":    {`{"from_account_id": "broke", "to_account_id": "user2", "currency": "USD", "amount": "10"}`, "auth-3", http.StatusUnprocessableEntity},
+		"Same accounts":           {`{"from_account_id": "user1", "to_account_id": "user1", "currency": "USD", "amount": "10"}`, "auth-3", http.StatusBadRequest},
+		"Missing amount":          {`{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD"}`, "auth-3", http.StatusBadRequest},
+		"Malformed":               {`{"from_account_id": 1}`, "auth-3", http.StatusBadRequest},
+		"Missing idempotency key": {`{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"}`, "", http.StatusBadRequest},
+	}
+
+	for name, tc := range invalid {
+		t.Run(name, func(t *testing.T) {
+			rec := serve(http.MethodPost, "/holds", tc.body, tc.idempotencyKey)
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// stubQueue queues the transfers in memory, without a worker posting them.
+type stubQueue struct {
+	queued map[string]*api.QueuedTransfer
+}
+
+func (s *stubQueue) EnqueueTransfer(_ context.Context, request *api.TransferRequest, idempotencyKey string) (*api.QueuedTransfer, error) {
+	queued := &api.QueuedTransfer{
+		ID:             fmt.Sprintf("queued-%d", len(s.queued)+1),
+		IdempotencyKey: idempotencyKey,
+		FromAccountID:  request.FromAccountID,
+		ToAccountID:    request.ToAccountID,
+		Currency:       request.Currency,
+		Amount:         request.Amount,
+		Status:         api.QueuedTransferQueued,
+	}
+
+	s.queued[queued.ID] = queued
+
+	return queued, nil
+}
+
+func (s *stubQueue) GetQueuedTransfer(_ context.Context, id string) (*api.QueuedTransfer, error) {
+	queued, ok := s.queued[id]
+	if !ok {
+		return nil, api.ErrQueuedTransferNotFound
+	}
+
+	return queued, nil
+}
+
+func TestTransferQueue(t *testing.T) {
+	queue := &stubQueue{queued: map[string]*api.QueuedTransfer{}}
+
+	repo := repository.NewMockRepository(t)
+	repo.EXPECT().Transfer(mock.Anything, mock.Anything, "sync-1").Return(nil, api.ErrInsufficientBalance).Twice()
+
+	server := func(queue TransferQueue) *http.Server {
+		apiServer := NewAPIServer(repo).WithCustomLogger(logging.Discard())
+		if queue != nil {
+			apiServer.WithTransferQueue(queue)
+		}
+
+		return apiServer.HTTPServer(8080, time.Second, time.Second)
+	}
+
+	transfer := func(httpServer *http.Server, body, idempotencyKey, prefer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transfer", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+
+		if prefer != "" {
+			req.Header.Set(PreferHeader, prefer)
+		}
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	queuing := server(queue)
+
+	t.Run("Queues the transfer", func(t *testing.T) {
+		rec := transfer(queuing, `{"from_account_id": " user1 ", "to_account_id": "user2", "currency": "USD", "amount": "10"}`, "burst-1", "wait=5, respond-async")
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		require.Equal(t, "respond-async", rec.Header().Get("Preference-Applied"))
+		require.Equal(t, "/transfers/queued-1/status", rec.Header().Get("Location"))
+
+		queued := &api.QueuedTransfer{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(queued))
+		require.Equal(t, api.QueuedTransferQueued, queued.Status)
+		require.Equal(t, "user1", queued.FromAccountID)
+
+		req := httptest.NewRequest(http.MethodGet, "/transfers/queued-1/status", nil)
+		rec = httptest.NewRecorder()
+		queuing.Handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Validates the transfer first", func(t *testing.T) {
+		rec := transfer(queuing, `{"from_account_id": "user1", "to_account_id": "user1", "currency": "USD", "amount": "10"}`, "burst-2", "respond-async")
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Len(t, queue.queued, 1)
+	})
+
+	t.Run("Unknown transfer", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/transfers/unknown/status", nil)
+		rec := httptest.NewRecorder()
+		queuing.Handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeQueuedTransferNotFound))
+	})
+
+	t.Run("Posts the transfers without the preference", func(t *testing.T) {
+		rec := transfer(queuing, `{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"}`, "sync-1", "")
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	})
+
+	t.Run("Posts the transfers without the queue", func(t *testing.T) {
+		rec := transfer(server(nil), `{"from_account_id": "user1", "to_account_id": "user2", "currency": "USD", "amount": "10"}`, "sync-1", "respond-async")
+		require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		require.Empty(t, rec.Header().Get("Preference-Applied"))
+	})
+}
+
+// stubScheduled schedules the transfers in memory, validating only their execute_at.
+type stubScheduled struct {
+	scheduled map[string]*api.ScheduledTransfer
+}
+
+func (s *stubScheduled) ScheduleTransfer(_ context.Context, request *api.ScheduleTransferRequest, idempotencyKey string) (*api.ScheduledTransfer, error) {
+	if !request.ExecuteAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: execute_at must be in the future", api.ErrInvalidRequest)
+	}
+
+	scheduled := &api.ScheduledTransfer{
+		ID:             fmt.Sprintf("scheduled-%d", len(s.scheduled)+1),
+		IdempotencyKey: idempotencyKey,
+		FromAccountID:  request.FromAccountID,
+		ToAccountID:    request.ToAccountID,
+		Currency:       request.Currency,
+		Amount:         request.Amount,
+		ExecuteAt:      request.ExecuteAt,
+		Status:         api.ScheduledTransferScheduled,
+	}
+	s.scheduled[scheduled.ID] = scheduled
+
+	return scheduled, nil
+}
+
+func (s *stubScheduled) GetScheduledTransfer(_ context.Context, id string) (*api.ScheduledTransfer, error) {
+	scheduled, ok := s.scheduled[id]
+	if !ok {
+		return nil, api.ErrScheduledTransferNotFound
+	}
+
+	return scheduled, nil
+}
+
+func (s *stubScheduled) ListScheduledTransfers(_ context.Context, accountID string, status api.ScheduledTransferStatus, _ int) ([]*api.ScheduledTransfer, error) {
+	list := []*api.ScheduledTransfer{}
+
+	for _, scheduled := range s.scheduled {
+		if scheduled.Status == status && (scheduled.FromAccountID == accountID || scheduled.ToAccountID == accountID) {
+			list = append(list, scheduled)
+		}
+	}
+
+	return list, nil
+}
+
+func (s *stubScheduled) CancelScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error) {
+	scheduled, err := s.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduled.Status != api.ScheduledTransferScheduled {
+		return nil, api.ErrScheduledTransferClosed
+	}
+
+	scheduled.Status = api.ScheduledTransferCancelled
+
+	return scheduled, nil
+}
+
+func TestScheduledTransfers(t *testing.T) {
+	scheduled := &stubScheduled{scheduled: map[string]*api.ScheduledTransfer{}}
+
+	httpServer := NewAPIServer(repository.NewMockRepository(t)).
+		WithCustomLogger(logging.Discard()).
+		WithTransferQueue(&stubQueue{queued: map[string]*api.QueuedTransfer{}}).
+		WithScheduledTransfers(scheduled).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "rent-2026-11")
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	executeAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	t.Run("Schedules the transfer", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/transfers/scheduled",
+			`{"from_account_id": " tenant ", "to_account_id": "landlord", "currency": "USD", "amount": "900", "execute_at": "`+executeAt+`"}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		require.Equal(t, "/transfers/scheduled/scheduled-1/status", rec.Header().Get("Location"))
+
+		transfer := &api.ScheduledTransfer{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(transfer))
+		require.Equal(t, api.ScheduledTransferScheduled, transfer.Status)
+		require.Equal(t, "tenant", transfer.FromAccountID)
+
+		rec = serve(http.MethodGet, "/transfers/scheduled/scheduled-1/status", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(http.MethodGet, "/transfers/scheduled?account_id=landlord", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		list := []*api.ScheduledTransfer{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+		require.Len(t, list, 1)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"from_account_id": "tenant", "to_account_id": "landlord", "currency": "USD", "amount": "900"}`,
+			`{"from_account_id": "tenant", "to_account_id": "tenant", "currency": "USD", "amount": "900", "execute_at": "` + executeAt + `"}`,
+			`{"from_account_id": "tenant", "to_account_id": "landlord", "currency": "USD", "amount": "900", "execute_at": "2020-01-01T00:00:00Z"}`,
+		} {
+			rec := serve(http.MethodPost, "/transfers/scheduled", body)
+			require.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+
+		for _, target := range []string{"/transfers/scheduled", "/transfers/scheduled?account_id=tenant&status=unknown"} {
+			rec := serve(http.MethodGet, target, "")
+			require.Equal(t, http.StatusBadRequest, rec.Code, target)
+		}
+
+		require.Len(t, scheduled.scheduled, 1)
+	})
+
+	t.Run("Cancels the transfer once", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/transfers/scheduled/scheduled-1/cancel", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.ScheduledTransferCancelled))
+
+		rec = serve(http.MethodPost, "/transfers/scheduled/scheduled-1/cancel", "")
+		require.Equal(t, http.StatusConflict, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeScheduledTransferClosed))
+	})
+
+	t.Run("Unknown transfer", func(t *testing.T) {
+		rec := serve(http.MethodGet, "/transfers/scheduled/unknown/status", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeScheduledTransferNotFound))
+	})
+}
+
+// stubReports reports a single transfer of 10 USD from payer to payee, posted on 2026-10-01.
+type stubReports struct{}
+
+func (stubReports) TrialBalance(_ context.Context, currency string, asOf time.Time) (*api.TrialBalance, error) {
+	if currency != "" && currency != "USD" {
+		return nil, api.ErrInvalidCurrency
+	}
+
+	ten := decimal.NewFromInt(10)
+
+	return &api.TrialBalance{
+		Currency: currency,
+		AsOf:     asOf,
+		Totals:   []*api.TrialBalanceTotal{{Currency: "USD", Debits: ten, Credits: ten, Balanced: true}},
+		Accounts: []*api.TrialBalanceLine{
+			{AccountID: "payee", Currency: "USD", Debits: decimal.Zero, Credits: ten, Balance: ten},
+			{AccountID: "payer", Currency: "USD", Debits: ten, Credits: decimal.Zero, Balance: ten.Neg()},
+		},
+	}, nil
+}
+
+func (stubReports) ExportGeneralLedger(_ context.Context, _ string, from, to time.Time, w api.GeneralLedgerWriter) error {
+	if !from.Before(to) {
+		return api.ErrInvalidReportPeriod
+	}
+
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if day.Before(from) || !day.Before(to) {
+		return nil
+	}
+
+	ten := decimal.NewFromInt(10)
+
+	if err := w.Line(&api.GeneralLedgerLine{Date: day, AccountID: "payee", Currency: "USD", Debits: decimal.Zero, Credits: ten, Entries: 1}); err != nil {
+		return err
+	}
+
+	return w.Line(&api.GeneralLedgerLine{Date: day, AccountID: "payer", Currency: "USD", Debits: ten, Credits: decimal.Zero, Entries: 1})
+}
+
+func TestReportEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithReports(middlewares.NewAPIKeyAuth([]string{hash}), stubReports{}).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reports/trial-balance", nil))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Trial balance", func(t *testing.T) {
+		rec := serve("/admin/reports/trial-balance?currency=USD&as_of=2026-11-01")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		report := &api.TrialBalance{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(report))
+		require.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), report.AsOf)
+		require.Len(t, report.Accounts, 2)
+		require.True(t, report.Totals[0].Balanced)
+
+		rec = serve("/admin/reports/trial-balance?as_of=2026-11-01&format=csv")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Header().Get("Content-Disposition"), "trial-balance-2026-11-01.csv")
+		require.Equal(t, "account_id,currency,debits,credits,balance\npayee,USD,0,10,10\npayer,USD,10,0,-10\n", rec.Body.String())
+	})
+
+	t.Run("General ledger", func(t *testing.T) {
+		rec := serve("/admin/reports/general-ledger?from=2026-10-01&to=2026-11-01")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Contains(t, rec.Header().Get("Content-Disposition"), "general-ledger-2026-10-01-2026-11-01.csv")
+		require.Equal(t, "date,account_id,currency,debits,credits,entries\n"+
+			"2026-10-01,payee,USD,0,10,1\n"+
+			"2026-10-01,payer,USD,10,0,1\n", rec.Body.String())
+
+		rec = serve("/admin/reports/general-ledger?from=2026-11-01&to=2026-12-01")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "date,account_id,currency,debits,credits,entries\n", rec.Body.String(), "the header row alone")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, target := range []string{
+			"/admin/reports/trial-balance?as_of=yesterday",
+			"/admin/reports/trial-balance?format=pdf",
+			"/admin/reports/trial-balance?currency=EUR",
+			"/admin/reports/general-ledger?from=2026-10-01",
+			"/admin/reports/general-ledger?from=2026-11-01&to=2026-10-01",
+		} {
+			rec := serve(target)
+			require.Equal(t, http.StatusBadRequest, rec.Code, target)
+		}
+	})
+}
+
+// stubFeeRules keeps the rules in memory, validating only the operation.
+type stubFeeRules struct {
+	rules map[string]*api.FeeRule
+}
+
+func (s *stubFeeRules) SetFeeRule(_ context.Context, operation api.FeeOperation, currency string, request *api.SetFeeRuleRequest, operator string) (*api.FeeRule, error) {
+	operation, err := api.ParseFeeOperation(string(operation))
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &api.FeeRule{Operation: operation, Currency: currency, Flat: request.Flat, Percentage: request.Percentage, UpdatedBy: operator}
+	s.rules[string(operation)+"/"+currency] = rule
+
+	return rule, nil
+}
+
+func (s *stubFeeRules) GetFeeRules(context.Context) ([]*api.FeeRule, error) {
+	rules := []*api.FeeRule{}
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (s *stubFeeRules) DeleteFeeRule(_ context.Context, operation api.FeeOperation, currency string) error {
+	if _, ok := s.rules[string(operation)+"/"+currency]; !ok {
+		return api.ErrFeeRuleNotFound
+	}
+
+	delete(s.rules, string(operation)+"/"+currency)
+
+	return nil
+}
+
+func TestFeeRuleEndpoints(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	rules := &stubFeeRules{rules: map[string]*api.FeeRule{}}
+
+	httpServer := NewAPIServer(&repository.MockRepository{}).
+		WithCustomLogger(logging.Discard()).
+		WithFeeRules(middlewares.NewAPIKeyAuth([]string{hash}), rules).
+		HTTPServer(8080, time.Second, time.Second)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/fees/TRANSFER/USD", strings.NewReader(`{"flat":"1"}`)))
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Empty(t, rules.rules)
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/admin/fees/TRANSFER/USD", `{"flat":"0.3","percentage":"1.5"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rule := &api.FeeRule{}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(rule))
+		require.Equal(t, api.FeeTransfer, rule.Operation)
+		require.True(t, decimal.RequireFromString("1.5").Equal(rule.Percentage))
+		require.Equal(t, middlewares.OperatorID(hash), rule.UpdatedBy)
+
+		rec = serve(http.MethodGet, "/admin/fees", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "1", rec.Header().Get(api.TotalCountHeader))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		rec := serve(http.MethodPut, "/admin/fees/DEPOSIT/USD", `{"flat":"1"}`)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeInvalidFeeRule))
+
+		rec = serve(http.MethodPut, "/admin/fees/TRANSFER/USD", `{`)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		rec := serve(http.MethodDelete, "/admin/fees/TRANSFER/USD", "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = serve(http.MethodDelete, "/admin/fees/TRANSFER/USD", "")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), string(api.CodeFeeRuleNotFound))
+	})
 }
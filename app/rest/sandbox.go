@@ -0,0 +1,23 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+)
+
+// WithSandbox marks every response with the api.SandboxHeader, so the partners can't mistake the sandbox for production.
+func (r *APIServer) WithSandbox() *APIServer {
+	r.sandbox = true
+
+	return r
+}
+
+// markSandbox sets the header before the handlers write their response.
+func markSandbox(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(api.SandboxHeader, "true")
+
+		next.ServeHTTP(w, req)
+	})
+}
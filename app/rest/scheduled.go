@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+)
+
+// ScheduledTransfers is implemented by repository.PostgresRepository.
+type ScheduledTransfers interface {
+	ScheduleTransfer(ctx context.Context, request *api.ScheduleTransferRequest, idempotencyKey string) (*api.ScheduledTransfer, error)
+	GetScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error)
+	ListScheduledTransfers(ctx context.Context, accountID string, status api.ScheduledTransferStatus, limit int) ([]*api.ScheduledTransfer, error)
+	CancelScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error)
+}
+
+// WithScheduledTransfers serves the transfers to post later under /transfers/scheduled, executed by the worker once due.
+func (r *APIServer) WithScheduledTransfers(scheduled ScheduledTransfers) *APIServer {
+	r.scheduled = scheduled
+
+	return r
+}
+
+func (r *APIServer) registerScheduledTransferEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.scheduled == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /transfers/scheduled", handler.HandleScheduleTransfer)
+	mux.HandleFunc("GET /transfers/scheduled", handler.HandleListScheduledTransfers)
+	// not /transfers/scheduled/{id}, which GET /transfers/{id}/status of the queue would overlap
+	mux.HandleFunc("GET /transfers/scheduled/{id}/status", handler.HandleGetScheduledTransfer)
+	mux.HandleFunc("POST /transfers/scheduled/{id}/cancel", handler.HandleCancelScheduledTransfer)
+}
+
+// HandleScheduleTransfer schedules the transfer at its execute_at, and responds with the scheduled transfer.
+// The transfer is validated like the ones of HandleTransfer, and the idempotency key is its group once executed.
+func (h *Handlers) HandleScheduleTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.ScheduleTransferRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil || request.ExecuteAt.IsZero() {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	// idempotency key is required
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if idempotencyKey == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrMissingIdempotencyKey)
+
+		return
+	}
+
+	payload, err := h.transferPayload(ctx, &request.TransferRequest)
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	scheduled, err := h.scheduled.ScheduleTransfer(ctx, &api.ScheduleTransferRequest{TransferRequest: *payload, ExecuteAt: request.ExecuteAt}, idempotencyKey)
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "transfer scheduled", slog.String("id", scheduled.ID), slog.Time("execute_at", scheduled.ExecuteAt))
+
+	w.Header().Set("Location", "/transfers/scheduled/"+scheduled.ID+"/status")
+
+	h.writeScheduled(ctx, w, http.StatusCreated, scheduled)
+}
+
+// HandleListScheduledTransfers responds with the scheduled transfers of the account_id, sent or received,
+// the earliest due first. They're the pending ones, unless the status says otherwise.
+func (h *Handlers) HandleListScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	accountID := strings.TrimSpace(query.Get("account_id"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	status := api.ScheduledTransferScheduled
+
+	if value := query.Get("status"); value != "" {
+		status = api.ScheduledTransferStatus(strings.ToUpper(value))
+
+		switch status {
+		case api.ScheduledTransferScheduled, api.ScheduledTransferCompleted, api.ScheduledTransferHeld,
+			api.ScheduledTransferFailed, api.ScheduledTransferCancelled:
+		default:
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	scheduled, err := h.scheduled.ListScheduledTransfers(ctx, accountID, status, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list scheduled transfers", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.writeScheduled(ctx, w, http.StatusOK, scheduled)
+}
+
+// HandleGetScheduledTransfer responds with the status of the scheduled transfer, and its error once failed or held.
+func (h *Handlers) HandleGetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	scheduled, err := h.scheduled.GetScheduledTransfer(r.Context(), r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.writeScheduled(r.Context(), w, http.StatusOK, scheduled)
+}
+
+// HandleCancelScheduledTransfer cancels the transfer before it's due, and responds with the cancelled transfer.
+func (h *Handlers) HandleCancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	scheduled, err := h.scheduled.CancelScheduledTransfer(ctx, r.PathValue("id"))
+	if h.HandleTransferError(w, err) {
+		return
+	}
+
+	h.logger.InfoContext(ctx, "scheduled transfer cancelled", slog.String("id", scheduled.ID))
+
+	h.writeScheduled(ctx, w, http.StatusOK, scheduled)
+}
+
+// writeScheduled responds with a scheduled transfer, or with a list of them.
+func (h *Handlers) writeScheduled(ctx context.Context, w http.ResponseWriter, status int, scheduled any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(scheduled)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
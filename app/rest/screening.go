@@ -0,0 +1,195 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+const (
+	defaultReviewsLimit = 50
+	maxReviewsLimit     = 500
+)
+
+// TransferReviews is implemented by repository.PostgresRepository.
+type TransferReviews interface {
+	GetTransferReview(ctx context.Context, idempotencyKey string) (*api.TransferReview, error)
+	ListTransferReviews(ctx context.Context, status api.ReviewStatus, limit int) ([]*api.TransferReview, error)
+	ApproveTransferReview(ctx context.Context, idempotencyKey string) ([]*api.Transaction, error)
+	RejectTransferReview(ctx context.Context, idempotencyKey string) error
+}
+
+// WithTransferReviews serves the transfers held by the screening under /admin/reviews.
+// They're decided by the compliance team, so they're only served behind the given authentication.
+func (r *APIServer) WithTransferReviews(auth middlewares.Middleware, reviews TransferReviews) *APIServer {
+	r.adminAuth = auth
+	r.reviews = reviews
+
+	return r
+}
+
+func (r *APIServer) registerReviewEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.reviews == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /admin/reviews", r.adminAuth(handler.HandleListReviews))
+	mux.HandleFunc("GET /admin/reviews/{key}", r.adminAuth(handler.HandleGetReview))
+	mux.HandleFunc("POST /admin/reviews/{key}/approve", r.adminAuth(handler.HandleApproveReview))
+	mux.HandleFunc("POST /admin/reviews/{key}/reject", r.adminAuth(handler.HandleRejectReview))
+}
+
+// HandleListReviews responds with the reviews in the status parameter, the pending ones by default, the oldest first.
+func (h *Handlers) HandleListReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status := api.ReviewPending
+
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = api.ReviewStatus(strings.ToUpper(value))
+
+		if status != api.ReviewPending && status != api.ReviewApproved && status != api.ReviewRejected {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	reviews, err := h.reviews.ListTransferReviews(ctx, status, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to list reviews", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(reviews)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetReview responds with the review of the transfer with the idempotency key.
+func (h *Handlers) HandleGetReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	review, err := h.reviews.GetTransferReview(ctx, r.PathValue("key"))
+	if errors.Is(err, api.ErrReviewNotFound) {
+		h.HandleError(w, http.StatusNotFound, api.ErrReviewNotFound)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get review", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(review)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleApproveReview posts the held transfer, and responds with both of its ledger entries.
+func (h *Handlers) HandleApproveReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tx, err := h.reviews.ApproveTransferReview(ctx, r.PathValue("key"))
+	if h.handleReviewError(w, err) {
+		return
+	}
+
+	h.rounding.Transactions(tx...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(tx)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleRejectReview rejects the held transfer, and responds with the decided review.
+func (h *Handlers) HandleRejectReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	key := r.PathValue("key")
+
+	if h.handleReviewError(w, h.reviews.RejectTransferReview(ctx, key)) {
+		return
+	}
+
+	review, err := h.reviews.GetTransferReview(ctx, key)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get review", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(review)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// returns true if response is handled, false otherwise ie no errors
+func (h *Handlers) handleReviewError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, api.ErrReviewNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return true
+	case errors.Is(err, api.ErrReviewDecided):
+		h.HandleError(w, http.StatusConflict, err)
+
+		return true
+	default:
+		// the approval posts the transfer, which fails like any other transfer
+		return h.HandleTransferError(w, err)
+	}
+}
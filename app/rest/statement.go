@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Statements is implemented by repository.PostgresRepository.
+type Statements interface {
+	SubscribeStatements(ctx context.Context, request *api.SubscribeStatementsRequest) (*api.StatementSubscription, error)
+	GetStatementSubscriptions(ctx context.Context, accountID string) ([]*api.StatementSubscription, error)
+	DeleteStatementSubscription(ctx context.Context, id string) error
+	GetStatements(ctx context.Context, accountID string, limit int) ([]*api.Statement, error)
+}
+
+// WithStatements serves the statement subscriptions and the delivery status of the statements under /admin/statements.
+// The destinations are called by the worker, so only the operators subscribe the accounts.
+func (r *APIServer) WithStatements(auth middlewares.Middleware, statements Statements) *APIServer {
+	r.adminAuth = auth
+	r.statements = statements
+
+	return r
+}
+
+func (r *APIServer) registerStatementEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.statements == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/statements/subscriptions", r.adminAuth(handler.HandleSubscribeStatements))
+	mux.HandleFunc("GET /admin/statements/subscriptions", r.adminAuth(handler.HandleGetStatementSubscriptions))
+	mux.HandleFunc("DELETE /admin/statements/subscriptions/{id}", r.adminAuth(handler.HandleDeleteStatementSubscription))
+	mux.HandleFunc("GET /admin/statements", r.adminAuth(handler.HandleGetStatements))
+}
+
+// HandleSubscribeStatements subscribes the account in the request to its monthly statements.
+func (h *Handlers) HandleSubscribeStatements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.SubscribeStatementsRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	subscription, err := h.statements.SubscribeStatements(ctx, request)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidStatementChannel),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to subscribe statements", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "statements subscribed", slog.String("id", subscription.ID),
+		slog.String("account_id", subscription.AccountID), slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(subscription)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetStatementSubscriptions responds with the subscriptions of the account_id parameter.
+func (h *Handlers) HandleGetStatementSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.URL.Query().Get("account_id"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidAccountID)
+
+		return
+	}
+
+	subscriptions, err := h.statements.GetStatementSubscriptions(ctx, accountID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get statement subscriptions", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(subscriptions))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(subscriptions)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleDeleteStatementSubscription unsubscribes, the statements already generated aren't delivered anymore.
+func (h *Handlers) HandleDeleteStatementSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.statements.DeleteStatementSubscription(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrSubscriptionNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to delete statement subscription", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetStatements responds with the statements of the account_id parameter and their delivery status, the latest first.
+func (h *Handlers) HandleGetStatements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accountID := strings.TrimSpace(r.URL.Query().Get("account_id"))
+	if accountID == "" {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidAccountID)
+
+		return
+	}
+
+	limit := defaultReviewsLimit
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxReviewsLimit {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+
+		limit = parsed
+	}
+
+	statements, err := h.statements.GetStatements(ctx, accountID, limit)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get statements", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(statements)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
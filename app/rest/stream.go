@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// the entries are flushed to the client by this many, and when the stream ends
+	streamFlushSize = 100
+	// how long each flush may take, the write timeout of the server doesn't apply to the streams of the large ledgers
+	streamWriteTimeout = 10 * time.Second
+)
+
+// TransactionStreams is implemented by repository.PostgresRepository.
+type TransactionStreams interface {
+	StreamTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, fn func(*api.Transaction) error) error
+}
+
+// WithTransactionStreams serves the entries of an account as newline-delimited JSON under
+// /transactions/{accountId}/{currency}/stream, written as they're read from the ledger, whatever its size.
+func (r *APIServer) WithTransactionStreams(streams TransactionStreams) *APIServer {
+	r.streams = streams
+
+	return r
+}
+
+func (r *APIServer) registerTransactionStreamEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.streams == nil {
+		return
+	}
+
+	// not cached, as the ledgers grow
+	mux.HandleFunc("GET /transactions/{accountId}/{currency}/stream", handler.HandleStreamTransactions)
+}
+
+// HandleStreamTransactions streams the entries of the account in the currency, the oldest first, a JSON transaction per line.
+// The cursor parameter resumes after an entry, see api.TransactionCursor, and the entries are filtered like the list's.
+// The api.StreamStatusTrailer tells the clients the streams sent whole from the ones cut short.
+func (h *Handlers) HandleStreamTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID, currency := r.PathValue("accountId"), r.PathValue("currency")
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	location, err := timeZone(r)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	query := r.URL.Query()
+
+	filter, err := transactionFilter(query, location)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	stream := &transactionStream{w: w, controller: http.NewResponseController(w)}
+
+	err = h.streams.StreamTransactions(ctx, currency, accountID, filter, query.Get("cursor"), func(transaction *api.Transaction) error {
+		h.rounding.Transactions(transaction)
+		inTimeZone(location, transaction)
+
+		return stream.send(transaction)
+	})
+
+	switch {
+	case err == nil:
+		// an empty stream only sends its headers now
+		stream.begin()
+
+		if err = stream.flush(); err == nil {
+			w.Header().Set(api.StreamStatusTrailer, api.StreamComplete)
+		}
+	case stream.begun:
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it, the trailer tells the client
+		if ctx.Err() == nil {
+			h.logger.ErrorContext(ctx, "failed to stream transactions", slog.String("account_id", accountID), slog.Any("error", err))
+		}
+
+		w.Header().Set(api.StreamStatusTrailer, string(api.CodeFailedToGetTransaction))
+	case errors.Is(err, api.ErrInvalidCursor),
+		errors.Is(err, api.ErrInvalidTag),
+		errors.Is(err, api.ErrInvalidTransactionFilter),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+	default:
+		h.logger.ErrorContext(ctx, "failed to stream transactions", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrFailedToGetTransaction)
+	}
+}
+
+// transactionStream writes the transactions a line each, sending the headers with the first one
+// so the errors before can still be answered.
+type transactionStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	begun      bool
+	pending    int
+}
+
+func (s *transactionStream) begin() {
+	if s.begun {
+		return
+	}
+
+	s.begun = true
+
+	// the server doesn't support deadlines, i.e. in the tests, then its write timeout applies
+	_ = s.controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	s.w.Header().Set("Content-Type", api.TransactionStreamContentType)
+	s.w.Header().Set("Cache-Control", "no-cache")
+	// nginx would buffer the whole stream otherwise
+	s.w.Header().Set("X-Accel-Buffering", "no")
+	s.w.Header().Set("Trailer", api.StreamStatusTrailer)
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func (s *transactionStream) send(transaction *api.Transaction) error {
+	s.begin()
+
+	data, err := json.Marshal(transaction)
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	if _, err = s.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	if s.pending++; s.pending < streamFlushSize {
+		return nil
+	}
+
+	return s.flush()
+}
+
+// flush sends the pending entries, and gives the next ones another write timeout.
+func (s *transactionStream) flush() error {
+	s.pending = 0
+
+	if err := s.controller.Flush(); err != nil {
+		return fmt.Errorf("failed to flush transactions: %w", err)
+	}
+
+	_ = s.controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devshark/wallet/api"
+)
+
+const (
+	// the entries are read this long after they're posted, for the transfers in flight to commit
+	subscriptionSettleDelay = 2 * time.Second
+	// the most entries sent per poll, the rest follow right away
+	subscriptionBatchSize = 100
+	// a comment is sent when there is nothing new for this long, so the proxies keep the connection open
+	subscriptionKeepAlive = 15 * time.Second
+	// how long each write may take, the write timeout of the server doesn't apply to the subscriptions
+	subscriptionWriteTimeout = 10 * time.Second
+)
+
+// TransactionSubscriptions is implemented by repository.PostgresRepository.
+type TransactionSubscriptions interface {
+	GetTransactionsAfter(ctx context.Context, currency, accountID, cursor string, until time.Time, limit int) ([]*api.Transaction, error)
+}
+
+// subscriptions polls the ledger for the subscribers, until the server shuts down.
+type subscriptions struct {
+	source   TransactionSubscriptions
+	interval time.Duration
+	closing  chan struct{}
+	close    sync.Once
+}
+
+// WithTransactionSubscriptions serves the new entries of an account as server-sent events
+// under /transactions/{accountId}/{currency}/events, polling the ledger every interval for each subscriber.
+func (r *APIServer) WithTransactionSubscriptions(source TransactionSubscriptions, interval time.Duration) *APIServer {
+	r.subscriptions = &subscriptions{source: source, interval: interval, closing: make(chan struct{})}
+
+	return r
+}
+
+func (r *APIServer) registerSubscriptionEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.subscriptions == nil {
+		return
+	}
+
+	mux.HandleFunc("GET /transactions/{accountId}/{currency}/events", handler.HandleSubscribeTransactions)
+}
+
+// shutdown ends the subscriptions, which would otherwise hold the shutdown of the server until its timeout.
+func (s *subscriptions) shutdown() {
+	s.close.Do(func() {
+		close(s.closing)
+	})
+}
+
+// HandleSubscribeTransactions streams the entries of the account in the currency as they're posted,
+// each as a transaction event with its cursor as the id. The subscribers reconnecting with the Last-Event-ID header,
+// or the since parameter, resume after that entry, the others get the entries posted from now on.
+func (h *Handlers) HandleSubscribeTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	accountID, currency := r.PathValue("accountId"), r.PathValue("currency")
+
+	if err := h.validateAccountIDs(ctx, accountID); err != nil {
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	cursor := strings.TrimSpace(r.Header.Get(api.LastEventIDHeader))
+	if cursor == "" {
+		cursor = strings.TrimSpace(r.URL.Query().Get("since"))
+	}
+
+	if cursor == "" {
+		cursor = api.NewTransactionCursor(time.Now().Add(-subscriptionSettleDelay), "")
+	}
+
+	// the first poll validates the subscription, while an error can still be answered
+	transactions, err := h.pollTransactions(ctx, currency, accountID, cursor)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidCursor),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to subscribe to transactions", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrFailedToGetTransaction)
+
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	// the server doesn't support deadlines, i.e. in the tests, then its write timeout applies
+	_ = controller.SetWriteDeadline(time.Now().Add(subscriptionWriteTimeout))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx would buffer the events otherwise
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// the subscriber gets the headers right away, not with the first event
+	if err = controller.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.subscriptions.interval)
+	defer ticker.Stop()
+
+	idle := time.Now()
+
+	for {
+		if len(transactions) > 0 || time.Since(idle) >= subscriptionKeepAlive {
+			idle = time.Now()
+
+			if cursor, err = h.sendTransactions(controller, w, transactions, cursor); err != nil {
+				// the subscriber is gone
+				return
+			}
+		}
+
+		// a full batch is followed by the next one right away
+		if len(transactions) < subscriptionBatchSize {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.subscriptions.closing:
+				return
+			case <-ticker.C:
+			}
+		}
+
+		transactions, err = h.pollTransactions(ctx, currency, accountID, cursor)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.logger.ErrorContext(ctx, "failed to poll transactions", slog.String("account_id", accountID), slog.Any("error", err))
+			}
+
+			// the subscriber reconnects and resumes after the last event it got
+			return
+		}
+	}
+}
+
+func (h *Handlers) pollTransactions(ctx context.Context, currency, accountID, cursor string) ([]*api.Transaction, error) {
+	transactions, err := h.subscriptions.source.GetTransactionsAfter(ctx, currency, accountID, cursor,
+		time.Now().Add(-subscriptionSettleDelay), subscriptionBatchSize)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the domain errors are answered as is
+	}
+
+	return transactions, nil
+}
+
+// sendTransactions writes the transactions as events, or a keep-alive comment without any, then flushes them.
+// It returns the cursor of the last transaction sent.
+func (h *Handlers) sendTransactions(controller *http.ResponseController, w http.ResponseWriter, transactions []*api.Transaction, cursor string) (string, error) {
+	_ = controller.SetWriteDeadline(time.Now().Add(subscriptionWriteTimeout))
+
+	if len(transactions) == 0 {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return cursor, fmt.Errorf("failed to send keep-alive: %w", err)
+		}
+	}
+
+	for _, transaction := range transactions {
+		// the cursor is the position in the ledger, before the rounding and the time zone of the response
+		cursor = api.TransactionCursor(transaction)
+
+		h.rounding.Transactions(transaction)
+
+		data, err := json.Marshal(transaction)
+		if err != nil {
+			return cursor, fmt.Errorf("failed to encode transaction: %w", err)
+		}
+
+		if _, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", cursor, api.TransactionEventType, data); err != nil {
+			return cursor, fmt.Errorf("failed to send transaction: %w", err)
+		}
+	}
+
+	if err := controller.Flush(); err != nil {
+		return cursor, fmt.Errorf("failed to flush events: %w", err)
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+)
+
+// Webhooks is implemented by repository.PostgresRepository.
+type Webhooks interface {
+	SubscribeWebhook(ctx context.Context, request *api.SubscribeWebhookRequest) (*api.WebhookSubscription, error)
+	GetWebhookSubscriptions(ctx context.Context, accountID string) ([]*api.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	RotateWebhookSecret(ctx context.Context, id string, request *api.RotateWebhookSecretRequest, operator string) (*api.WebhookSecretRotation, error)
+	GetWebhookSecretRotations(ctx context.Context, id string) ([]*api.WebhookSecretRotation, error)
+}
+
+// WithWebhooks serves the webhook subscriptions to the ledger events under /admin/webhooks.
+// The urls are called by the worker, so only the operators subscribe them.
+func (r *APIServer) WithWebhooks(auth middlewares.Middleware, webhooks Webhooks) *APIServer {
+	r.adminAuth = auth
+	r.webhooks = webhooks
+
+	return r
+}
+
+func (r *APIServer) registerWebhookEndpoints(mux *http.ServeMux, handler *Handlers) {
+	if r.adminAuth == nil || r.webhooks == nil {
+		return
+	}
+
+	mux.HandleFunc("POST /admin/webhooks", r.adminAuth(handler.HandleSubscribeWebhook))
+	mux.HandleFunc("GET /admin/webhooks", r.adminAuth(handler.HandleGetWebhookSubscriptions))
+	mux.HandleFunc("DELETE /admin/webhooks/{id}", r.adminAuth(handler.HandleDeleteWebhookSubscription))
+	mux.HandleFunc("POST /admin/webhooks/{id}/secret", r.adminAuth(handler.HandleRotateWebhookSecret))
+	mux.HandleFunc("GET /admin/webhooks/{id}/rotations", r.adminAuth(handler.HandleGetWebhookSecretRotations))
+}
+
+// HandleSubscribeWebhook subscribes the url in the request to the ledger events, of every account or of the one in the request.
+func (h *Handlers) HandleSubscribeWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	request := &api.SubscribeWebhookRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(request)
+	if err != nil {
+		h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+		return
+	}
+
+	subscription, err := h.webhooks.SubscribeWebhook(ctx, request)
+
+	switch {
+	case errors.Is(err, api.ErrInvalidWebhook),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to subscribe webhook", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "webhook subscribed", slog.String("id", subscription.ID),
+		slog.String("account_id", subscription.AccountID), slog.String("operator", middlewares.Operator(ctx)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(subscription)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetWebhookSubscriptions responds with the subscriptions of the account_id parameter, or all of them without it.
+func (h *Handlers) HandleGetWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	subscriptions, err := h.webhooks.GetWebhookSubscriptions(ctx, r.URL.Query().Get("account_id"))
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get webhook subscriptions", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(subscriptions))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(subscriptions)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleDeleteWebhookSubscription unsubscribes, the next events aren't posted to it anymore.
+func (h *Handlers) HandleDeleteWebhookSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.webhooks.DeleteWebhookSubscription(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrWebhookNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to delete webhook subscription", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRotateWebhookSecret replaces the signing secret of the subscription, and responds with the new one.
+// The previous secret keeps signing the deliveries for the grace period of the request.
+func (h *Handlers) HandleRotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	operator := middlewares.Operator(ctx)
+
+	request := &api.RotateWebhookSecretRequest{}
+
+	// the body is optional, for the default grace period
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			h.HandleError(w, http.StatusBadRequest, api.ErrInvalidRequest)
+
+			return
+		}
+	}
+
+	rotation, err := h.webhooks.RotateWebhookSecret(ctx, r.PathValue("id"), request, operator)
+
+	switch {
+	case errors.Is(err, api.ErrWebhookNotFound):
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	case errors.Is(err, api.ErrInvalidGracePeriod):
+		h.HandleError(w, http.StatusBadRequest, err)
+
+		return
+	case err != nil:
+		h.logger.ErrorContext(ctx, "failed to rotate webhook secret", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	h.logger.InfoContext(ctx, "webhook secret rotated", slog.String("id", rotation.SubscriptionID), slog.String("operator", operator))
+
+	w.Header().Set("Content-Type", "application/json")
+	// the secret is only shown once, it mustn't be kept by a cache on the way
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(rotation)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
+
+// HandleGetWebhookSecretRotations responds with the audit trail of the rotations of the secret of the subscription.
+func (h *Handlers) HandleGetWebhookSecretRotations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rotations, err := h.webhooks.GetWebhookSecretRotations(ctx, r.PathValue("id"))
+	if errors.Is(err, api.ErrWebhookNotFound) {
+		h.HandleError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to get webhook secret rotations", slog.Any("error", err))
+		h.HandleError(w, http.StatusInternalServerError, api.ErrUnexpected)
+
+		return
+	}
+
+	setTotalCount(w, len(rotations))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(rotations)
+	if err != nil {
+		// we can't respond with an error payload anymore, because the headers have already been sent
+		// headers must be written before the content, so if writing the content fails, we can't go back
+		// just log it
+		h.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/api/walletpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func toAccount(account *api.Account) *walletpb.Account {
+	return &walletpb.Account{
+		AccountId: account.AccountID,
+		Currency:  account.Currency,
+		Balance:   account.Balance.String(),
+		Rounding:  toRoundingPolicy(account.Rounding),
+	}
+}
+
+func toTransaction(tx *api.Transaction) *walletpb.Transaction {
+	return &walletpb.Transaction{
+		TxId:           tx.TxID,
+		AccountId:      tx.AccountID,
+		Type:           toEntryType(tx.Type),
+		Amount:         tx.Amount.String(),
+		Currency:       tx.Currency,
+		RunningBalance: tx.RunningBalance.String(),
+		Remarks:        tx.Remarks,
+		Time:           tx.Time.UTC().Format(time.RFC3339Nano),
+		Tags:           tx.Tags,
+		Rounding:       toRoundingPolicy(tx.Rounding),
+	}
+}
+
+func toRoundingPolicy(policy *api.RoundingPolicy) *walletpb.RoundingPolicy {
+	if policy == nil {
+		return nil
+	}
+
+	return &walletpb.RoundingPolicy{
+		Mode:  string(policy.Mode),
+		Scale: policy.Scale,
+	}
+}
+
+func toEntryType(t api.DebitOrCreditType) walletpb.EntryType {
+	switch t {
+	case api.DEBIT:
+		return walletpb.EntryType_ENTRY_TYPE_DEBIT
+	case api.CREDIT:
+		return walletpb.EntryType_ENTRY_TYPE_CREDIT
+	default:
+		return walletpb.EntryType_ENTRY_TYPE_UNSPECIFIED
+	}
+}
+
+// toStatus maps the domain errors to the gRPC status codes, like HandleTransferError does to the HTTP status codes.
+// The unknown errors are logged and hidden behind the fallback error.
+func (s *Server) toStatus(ctx context.Context, msg string, err error, fallback error) error {
+	switch {
+	case errors.Is(err, api.ErrAccountNotFound):
+		return status.Error(codes.NotFound, api.ErrAccountNotFound.Error())
+	case errors.Is(err, api.ErrTransactionNotFound):
+		return status.Error(codes.NotFound, api.ErrTransactionNotFound.Error())
+	case errors.Is(err, api.ErrDuplicateTransaction):
+		return status.Error(codes.AlreadyExists, api.ErrDuplicateTransaction.Error())
+	case errors.Is(err, api.ErrTransferInProgress):
+		return status.Error(codes.Aborted, api.ErrTransferInProgress.Error())
+	case errors.Is(err, api.ErrSandboxQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, api.ErrInsufficientBalance):
+		return status.Error(codes.FailedPrecondition, api.ErrInsufficientBalance.Error())
+	case errors.Is(err, api.ErrOutsideHierarchy):
+		return status.Error(codes.FailedPrecondition, api.ErrOutsideHierarchy.Error())
+	case errors.Is(err, api.ErrAccountNotProvisioned),
+		errors.Is(err, api.ErrAccountRequiresDeposit):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, api.ErrTransferUnderReview):
+		return status.Error(codes.FailedPrecondition, api.ErrTransferUnderReview.Error())
+	case errors.Is(err, api.ErrTransferPendingApproval):
+		return status.Error(codes.FailedPrecondition, api.ErrTransferPendingApproval.Error())
+	case errors.Is(err, api.ErrTransferRejected):
+		return status.Error(codes.PermissionDenied, api.ErrTransferRejected.Error())
+	case errors.Is(err, api.ErrSameAccountIDs),
+		errors.Is(err, api.ErrCompanyAccount),
+		errors.Is(err, api.ErrInvalidAmount),
+		errors.Is(err, api.ErrNegativeAmount),
+		errors.Is(err, api.ErrAmountOutOfRange),
+		errors.Is(err, api.ErrInvalidAccountID),
+		errors.Is(err, api.ErrInvalidCurrency),
+		errors.Is(err, api.ErrInvalidTxID),
+		errors.Is(err, api.ErrInvalidTag),
+		errors.Is(err, api.ErrInvalidRemarks),
+		errors.Is(err, api.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		s.logger.ErrorContext(ctx, msg, slog.Any("error", err))
+
+		return status.Error(codes.Internal, fallback.Error())
+	}
+}
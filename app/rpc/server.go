@@ -0,0 +1,415 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/api/walletpb"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/remarks"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/internal/rounding"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// IdempotencyKeyMetadata is the gRPC counterpart of the X-Idempotency-Key header.
+const IdempotencyKeyMetadata = "x-idempotency-key"
+
+// Server implements the wallet gRPC service on top of the same repository as the REST API.
+type Server struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	repo     repository.Repository
+	logger   *slog.Logger
+	rounding rounding.Policies
+	remarks  remarks.Policy
+	features features.Features
+	// companyAccountID funds the deposits and receives the withdrawals, the transfers can't use it
+	companyAccountID string
+	readOnly         ReadOnlyMode
+}
+
+// ReadOnlyMode is implemented by readonly.Mode.
+type ReadOnlyMode interface {
+	ReadOnly(ctx context.Context) bool
+}
+
+func NewServer(repo repository.Repository) *Server {
+	return &Server{
+		repo:     repo,
+		logger:   logging.Component(slog.Default(), "grpc"),
+		rounding: rounding.None(),
+		features: features.Disabled(),
+
+		companyAccountID: api.CompanyAccountID,
+	}
+}
+
+func (s *Server) WithCustomLogger(logger *slog.Logger) *Server {
+	s.logger = logging.Component(logger, "grpc")
+
+	return s
+}
+
+// WithRounding sets the rounding policies of the amounts in the responses, like the REST API.
+func (s *Server) WithRounding(policies rounding.Policies) *Server {
+	s.rounding = policies
+
+	return s
+}
+
+// WithRemarksPolicy limits the length and screens the content of the remarks, like the REST API.
+func (s *Server) WithRemarksPolicy(policy remarks.Policy) *Server {
+	s.remarks = policy
+
+	return s
+}
+
+// WithFeatures sets the feature flags, i.e. the account id policy, like the REST API.
+func (s *Server) WithFeatures(f features.Features) *Server {
+	s.features = f
+
+	return s
+}
+
+// WithCompanyAccount overrides the settlement account of the deposits and withdrawals, like the REST API.
+func (s *Server) WithCompanyAccount(accountID string) *Server {
+	s.companyAccountID = accountID
+
+	return s
+}
+
+// WithReadOnly rejects the deposits, withdrawals and transfers as Unavailable while the mode is read-only, like the REST API.
+func (s *Server) WithReadOnly(mode ReadOnlyMode) *Server {
+	s.readOnly = mode
+
+	return s
+}
+
+// rejectWrites returns an Unavailable status while the wallet is read-only.
+func (s *Server) rejectWrites(ctx context.Context) error {
+	if s.readOnly != nil && s.readOnly.ReadOnly(ctx) {
+		return status.Error(codes.Unavailable, api.ErrReadOnly.Error())
+	}
+
+	return nil
+}
+
+// isCompanyAccount reports whether the account of the request is the settlement account.
+func (s *Server) isCompanyAccount(accountID string) bool {
+	return strings.EqualFold(strings.TrimSpace(accountID), s.companyAccountID)
+}
+
+// validateAccountIDs returns an InvalidArgument status, explaining the format, when an account id doesn't follow the
+// account id policy of the deployment. The settlement, disputes and fees accounts are exempt, like the REST API.
+func (s *Server) validateAccountIDs(ctx context.Context, accountIDs ...string) error {
+	for _, accountID := range accountIDs {
+		accountID = strings.TrimSpace(accountID)
+
+		if s.isCompanyAccount(accountID) || strings.EqualFold(accountID, api.DisputesAccountID) ||
+			strings.EqualFold(accountID, api.FeesAccountID) {
+			continue
+		}
+
+		if err := s.features.ValidateAccountID(ctx, accountID); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// GRPCServer returns a gRPC server with the wallet service registered.
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	walletpb.RegisterWalletServiceServer(server, s)
+
+	return server
+}
+
+func (s *Server) GetBalance(ctx context.Context, request *walletpb.GetBalanceRequest) (*walletpb.GetBalanceResponse, error) {
+	if request.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidAccountID.Error())
+	}
+
+	if request.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidCurrency.Error())
+	}
+
+	if err := s.validateAccountIDs(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.GetAccountBalance(ctx, request.GetCurrency(), request.GetAccountId())
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to get account balance", err, api.ErrFailedToGetTransaction)
+	}
+
+	s.rounding.Account(account)
+
+	return &walletpb.GetBalanceResponse{Account: toAccount(account)}, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, request *walletpb.GetTransactionRequest) (*walletpb.GetTransactionResponse, error) {
+	if request.GetTxId() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidTxID.Error())
+	}
+
+	tx, err := s.repo.GetTransaction(ctx, request.GetTxId())
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to get transaction", err, api.ErrFailedToGetTransaction)
+	}
+
+	s.rounding.Transactions(tx)
+
+	return &walletpb.GetTransactionResponse{Transaction: toTransaction(tx)}, nil
+}
+
+func (s *Server) ListTransactions(ctx context.Context, request *walletpb.ListTransactionsRequest) (*walletpb.ListTransactionsResponse, error) {
+	if request.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidAccountID.Error())
+	}
+
+	if request.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidCurrency.Error())
+	}
+
+	if err := s.validateAccountIDs(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	var (
+		transactions []*api.Transaction
+		err          error
+	)
+
+	if len(request.GetTags()) > 0 {
+		transactions, err = s.repo.FilterTransactions(ctx, request.GetCurrency(), request.GetAccountId(), api.TransactionFilter{Tags: request.GetTags()})
+	} else {
+		transactions, err = s.repo.GetTransactions(ctx, request.GetCurrency(), request.GetAccountId())
+	}
+
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to get transactions", err, api.ErrFailedToGetTransaction)
+	}
+
+	s.rounding.Transactions(transactions...)
+
+	response := &walletpb.ListTransactionsResponse{
+		Transactions: make([]*walletpb.Transaction, 0, len(transactions)),
+	}
+
+	for _, tx := range transactions {
+		response.Transactions = append(response.Transactions, toTransaction(tx))
+	}
+
+	return response, nil
+}
+
+func (s *Server) Deposit(ctx context.Context, request *walletpb.DepositRequest) (*walletpb.DepositResponse, error) {
+	if err := s.rejectWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	idempotencyKey, err := idempotencyKeyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(request.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	if request.GetAccountId() == "" || request.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidRequest.Error())
+	}
+
+	if s.isCompanyAccount(request.GetAccountId()) {
+		return nil, status.Error(codes.InvalidArgument, api.ErrCompanyAccount.Error())
+	}
+
+	if err = s.validateAccountIDs(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.remarks.Validate(strings.TrimSpace(request.GetRemarks())); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	txs, err := s.repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: s.companyAccountID,
+		ToAccountID:   strings.TrimSpace(request.GetAccountId()),
+		Currency:      strings.TrimSpace(request.GetCurrency()),
+		Amount:        amount,
+		Remarks:       strings.TrimSpace(request.GetRemarks()),
+		Tags:          request.GetTags(),
+	}, idempotencyKey)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to deposit", err, api.ErrTransferFailed)
+	}
+
+	s.rounding.Transactions(txs...)
+
+	credit := findLeg(txs, api.CREDIT)
+	if credit == nil {
+		return nil, status.Error(codes.Internal, api.ErrIncompleteTransaction.Error())
+	}
+
+	return &walletpb.DepositResponse{Transaction: toTransaction(credit)}, nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, request *walletpb.WithdrawRequest) (*walletpb.WithdrawResponse, error) {
+	if err := s.rejectWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	idempotencyKey, err := idempotencyKeyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(request.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	if request.GetAccountId() == "" || request.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidRequest.Error())
+	}
+
+	if s.isCompanyAccount(request.GetAccountId()) {
+		return nil, status.Error(codes.InvalidArgument, api.ErrCompanyAccount.Error())
+	}
+
+	if err = s.validateAccountIDs(ctx, request.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.remarks.Validate(strings.TrimSpace(request.GetRemarks())); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	txs, err := s.repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: strings.TrimSpace(request.GetAccountId()),
+		ToAccountID:   s.companyAccountID,
+		Currency:      strings.TrimSpace(request.GetCurrency()),
+		Amount:        amount,
+		Remarks:       strings.TrimSpace(request.GetRemarks()),
+		Tags:          request.GetTags(),
+	}, idempotencyKey)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to withdraw", err, api.ErrTransferFailed)
+	}
+
+	s.rounding.Transactions(txs...)
+
+	debit := findLeg(txs, api.DEBIT)
+	if debit == nil {
+		return nil, status.Error(codes.Internal, api.ErrIncompleteTransaction.Error())
+	}
+
+	return &walletpb.WithdrawResponse{Transaction: toTransaction(debit)}, nil
+}
+
+func (s *Server) Transfer(ctx context.Context, request *walletpb.TransferRequest) (*walletpb.TransferResponse, error) {
+	if err := s.rejectWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	idempotencyKey, err := idempotencyKeyFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := parseAmount(request.GetAmount())
+	if err != nil {
+		return nil, err
+	}
+
+	if request.GetFromAccountId() == "" || request.GetToAccountId() == "" || request.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, api.ErrInvalidRequest.Error())
+	}
+
+	if strings.EqualFold(request.GetFromAccountId(), request.GetToAccountId()) {
+		return nil, status.Error(codes.InvalidArgument, api.ErrSameAccountIDs.Error())
+	}
+
+	if s.isCompanyAccount(request.GetFromAccountId()) || s.isCompanyAccount(request.GetToAccountId()) {
+		return nil, status.Error(codes.InvalidArgument, api.ErrCompanyAccount.Error())
+	}
+
+	if err = s.validateAccountIDs(ctx, request.GetFromAccountId(), request.GetToAccountId()); err != nil {
+		return nil, err
+	}
+
+	if err = s.remarks.Validate(strings.TrimSpace(request.GetRemarks())); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	txs, err := s.repo.Transfer(ctx, &api.TransferRequest{
+		FromAccountID: strings.TrimSpace(request.GetFromAccountId()),
+		ToAccountID:   strings.TrimSpace(request.GetToAccountId()),
+		Currency:      strings.TrimSpace(request.GetCurrency()),
+		Amount:        amount,
+		Remarks:       strings.TrimSpace(request.GetRemarks()),
+		Tags:          request.GetTags(),
+	}, idempotencyKey)
+	if err != nil {
+		return nil, s.toStatus(ctx, "failed to transfer", err, api.ErrTransferFailed)
+	}
+
+	s.rounding.Transactions(txs...)
+
+	debit, credit := findLeg(txs, api.DEBIT), findLeg(txs, api.CREDIT)
+	if debit == nil || credit == nil {
+		return nil, status.Error(codes.Internal, api.ErrIncompleteTransaction.Error())
+	}
+
+	return &walletpb.TransferResponse{
+		Debit:  toTransaction(debit),
+		Credit: toTransaction(credit),
+	}, nil
+}
+
+func idempotencyKeyFrom(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	for _, key := range md.Get(IdempotencyKeyMetadata) {
+		if key = strings.TrimSpace(key); key != "" {
+			return key, nil
+		}
+	}
+
+	return "", status.Error(codes.InvalidArgument, api.ErrMissingIdempotencyKey.Error())
+}
+
+func parseAmount(value string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil || !amount.IsPositive() {
+		return decimal.Zero, status.Error(codes.InvalidArgument, api.ErrInvalidAmount.Error())
+	}
+
+	if err = api.ValidateAmount(amount); err != nil {
+		return decimal.Zero, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return amount, nil
+}
+
+func findLeg(txs []*api.Transaction, entryType api.DebitOrCreditType) *api.Transaction {
+	for _, tx := range txs {
+		if strings.EqualFold(string(tx.Type), string(entryType)) {
+			return tx
+		}
+	}
+
+	return nil
+}
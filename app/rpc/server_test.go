@@ -0,0 +1,307 @@
+package rpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/api/walletpb"
+	"github.com/devshark/wallet/app/internal/accountid"
+	"github.com/devshark/wallet/app/internal/features"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/rpc"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, repo repository.Repository) walletpb.WalletServiceClient {
+	t.Helper()
+
+	return serveClient(t, rpc.NewServer(repo).WithCustomLogger(logging.Discard()))
+}
+
+func serveClient(t *testing.T, wallet *rpc.Server) walletpb.WalletServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := wallet.GRPCServer()
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return walletpb.NewWalletServiceClient(conn)
+}
+
+func withIdempotencyKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), rpc.IdempotencyKeyMetadata, key)
+}
+
+func TestGetBalance(t *testing.T) {
+	// registered first, so it runs after the client and the server are closed
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	mockRepo := repository.NewMockRepository(t)
+	client := newClient(t, mockRepo)
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo.EXPECT().GetAccountBalance(mock.Anything, "USD", "user1").Return(&api.Account{
+			AccountID: "user1",
+			Currency:  "USD",
+			Balance:   decimal.RequireFromString("10.5"),
+		}, nil).Once()
+
+		response, err := client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{AccountId: "user1", Currency: "USD"})
+		require.NoError(t, err)
+		require.Equal(t, "10.5", response.GetAccount().GetBalance())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo.EXPECT().GetAccountBalance(mock.Anything, "USD", "ghost").Return(nil, api.ErrAccountNotFound).Once()
+
+		_, err := client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{AccountId: "ghost", Currency: "USD"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		_, err := client.GetBalance(context.Background(), &walletpb.GetBalanceRequest{AccountId: "user1"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Invalid account id", func(t *testing.T) {
+		policy, err := accountid.New(accountid.UUID, 0, 0)
+		require.NoError(t, err)
+
+		strict := serveClient(t, rpc.NewServer(mockRepo).
+			WithCustomLogger(logging.Discard()).
+			WithFeatures(features.Disabled().WithAccountIDPolicy(policy)))
+
+		_, err = strict.GetBalance(context.Background(), &walletpb.GetBalanceRequest{AccountId: "user1", Currency: "USD"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "must match")
+	})
+}
+
+func TestGetTransaction(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	mockRepo := repository.NewMockRepository(t)
+	client := newClient(t, mockRepo)
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransaction(mock.Anything, "tx1").Return(&api.Transaction{
+			TxID: "tx1", AccountID: "user1", Type: api.DEBIT, Amount: decimal.RequireFromString("2.5"), Currency: "USD",
+		}, nil).Once()
+
+		response, err := client.GetTransaction(context.Background(), &walletpb.GetTransactionRequest{TxId: "tx1"})
+		require.NoError(t, err)
+		require.Equal(t, "user1", response.GetTransaction().GetAccountId())
+		require.Equal(t, walletpb.EntryType_ENTRY_TYPE_DEBIT, response.GetTransaction().GetType())
+		require.Equal(t, "2.5", response.GetTransaction().GetAmount())
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockRepo.EXPECT().GetTransaction(mock.Anything, "ghost").Return(nil, api.ErrTransactionNotFound).Once()
+
+		_, err := client.GetTransaction(context.Background(), &walletpb.GetTransactionRequest{TxId: "ghost"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		_, err := client.GetTransaction(context.Background(), &walletpb.GetTransactionRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestListTransactions(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	mockRepo := repository.NewMockRepository(t)
+	client := newClient(t, mockRepo)
+
+	mockRepo.EXPECT().GetTransactions(mock.Anything, "USD", "user1").Return([]*api.Transaction{
+		{TxID: "tx1", Type: api.CREDIT, Amount: decimal.NewFromInt(5)},
+		{TxID: "tx2", Type: api.DEBIT, Amount: decimal.NewFromInt(2)},
+	}, nil).Once()
+
+	response, err := client.ListTransactions(context.Background(), &walletpb.ListTransactionsRequest{AccountId: "user1", Currency: "USD"})
+	require.NoError(t, err)
+	require.Len(t, response.GetTransactions(), 2)
+	require.Equal(t, walletpb.EntryType_ENTRY_TYPE_CREDIT, response.GetTransactions()[0].GetType())
+	require.Equal(t, "2", response.GetTransactions()[1].GetAmount())
+}
+
+func TestTransfer(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	mockRepo := repository.NewMockRepository(t)
+	client := newClient(t, mockRepo)
+
+	t.Run("OK", func(t *testing.T) {
+		mockRepo.EXPECT().Transfer(mock.Anything, &api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user2",
+			Currency:      "USD",
+			Amount:        decimal.RequireFromString("1.25"),
+			Remarks:       "lunch",
+		}, "key-1").Return([]*api.Transaction{
+			{TxID: "tx1", AccountID: "user1", Type: api.DEBIT},
+			{TxID: "tx2", AccountID: "user2", Type: api.CREDIT},
+		}, nil).Once()
+
+		response, err := client.Transfer(withIdempotencyKey("key-1"), &walletpb.TransferRequest{
+			FromAccountId: "user1",
+			ToAccountId:   "user2",
+			Currency:      "USD",
+			Amount:        "1.25",
+			Remarks:       " lunch ",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "tx1", response.GetDebit().GetTxId())
+		require.Equal(t, "tx2", response.GetCredit().GetTxId())
+	})
+
+	t.Run("Missing idempotency key", func(t *testing.T) {
+		_, err := client.Transfer(context.Background(), &walletpb.TransferRequest{
+			FromAccountId: "user1",
+			ToAccountId:   "user2",
+			Currency:      "USD",
+			Amount:        "1",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Contains(t, err.Error(), api.ErrMissingIdempotencyKey.Error())
+	})
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		_, err := client.Transfer(withIdempotencyKey("key-2"), &walletpb.TransferRequest{
+			FromAccountId: "user1",
+			ToAccountId:   "user2",
+			Currency:      "USD",
+			Amount:        "-1",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Company account", func(t *testing.T) {
+		_, err := client.Transfer(withIdempotencyKey("key-3"), &walletpb.TransferRequest{
+			FromAccountId: api.CompanyAccountID,
+			ToAccountId:   "user2",
+			Currency:      "USD",
+			Amount:        "1",
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Domain errors", func(t *testing.T) {
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "duplicate").Return(nil, api.ErrDuplicateTransaction).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "broke").Return(nil, api.ErrInsufficientBalance).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "flagged").Return(nil, api.ErrTransferUnderReview).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "rejected").Return(nil, api.ErrTransferRejected).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "in-progress").Return(nil, api.ErrTransferInProgress).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "disputes").Return(nil, api.ErrCompanyAccount).Once()
+		mockRepo.EXPECT().Transfer(mock.Anything, mock.Anything, "db-down").Return(nil, api.ErrUnhandledDatabaseError).Once()
+
+		request := &walletpb.TransferRequest{FromAccountId: "user1", ToAccountId: "user2", Currency: "USD", Amount: "1"}
+
+		_, err := client.Transfer(withIdempotencyKey("duplicate"), request)
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("broke"), request)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("flagged"), request)
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("rejected"), request)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("in-progress"), request)
+		require.Equal(t, codes.Aborted, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("disputes"), request)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Transfer(withIdempotencyKey("db-down"), request)
+		require.Equal(t, codes.Internal, status.Code(err))
+		require.NotContains(t, err.Error(), "database")
+	})
+}
+
+func TestDepositAndWithdraw(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	mockRepo := repository.NewMockRepository(t)
+	client := newClient(t, mockRepo)
+
+	legs := []*api.Transaction{
+		{TxID: "debit", Type: api.DEBIT},
+		{TxID: "credit", Type: api.CREDIT},
+	}
+
+	mockRepo.EXPECT().Transfer(mock.Anything, mock.MatchedBy(func(r *api.TransferRequest) bool {
+		return r.FromAccountID == api.CompanyAccountID && r.ToAccountID == "user1"
+	}), "deposit-key").Return(legs, nil).Once()
+
+	deposit, err := client.Deposit(withIdempotencyKey("deposit-key"), &walletpb.DepositRequest{AccountId: "user1", Currency: "USD", Amount: "3"})
+	require.NoError(t, err)
+	require.Equal(t, "credit", deposit.GetTransaction().GetTxId())
+
+	mockRepo.EXPECT().Transfer(mock.Anything, mock.MatchedBy(func(r *api.TransferRequest) bool {
+		return r.FromAccountID == "user1" && r.ToAccountID == api.CompanyAccountID
+	}), "withdraw-key").Return(legs, nil).Once()
+
+	withdrawal, err := client.Withdraw(withIdempotencyKey("withdraw-key"), &walletpb.WithdrawRequest{AccountId: "user1", Currency: "USD", Amount: "3"})
+	require.NoError(t, err)
+	require.Equal(t, "debit", withdrawal.GetTransaction().GetTxId())
+
+	_, err = client.Deposit(withIdempotencyKey("key"), &walletpb.DepositRequest{AccountId: api.CompanyAccountID, Currency: "USD", Amount: "3"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+type readOnly bool
+
+func (r readOnly) ReadOnly(context.Context) bool {
+	return bool(r)
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	// the repository isn't called
+	mockRepo := repository.NewMockRepository(t)
+	client := serveClient(t, rpc.NewServer(mockRepo).WithCustomLogger(logging.Discard()).WithReadOnly(readOnly(true)))
+
+	_, err := client.Deposit(withIdempotencyKey("read-only-1"), &walletpb.DepositRequest{AccountId: "user1", Currency: "USD", Amount: "10"})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = client.Withdraw(withIdempotencyKey("read-only-2"), &walletpb.WithdrawRequest{AccountId: "user1", Currency: "USD", Amount: "10"})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = client.Transfer(withIdempotencyKey("read-only-3"), &walletpb.TransferRequest{
+		FromAccountId: "user1", ToAccountId: "user2", Currency: "USD", Amount: "10",
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,161 @@
+package testing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultFixtureCurrency = "USD"
+
+	// group_id is limited to 50 characters
+	idempotencyKeyBytes = 16
+)
+
+// AccountFixture builds an account through the repository, so the ledger stays consistent
+// i.e. every balance is backed by transactions against the company account.
+type AccountFixture struct {
+	accountID string
+	currency  string
+	balance   decimal.Decimal
+}
+
+// NewAccountFixture starts building an account with zero USD balance.
+func NewAccountFixture(accountID string) *AccountFixture {
+	return &AccountFixture{
+		accountID: accountID,
+		currency:  defaultFixtureCurrency,
+		balance:   decimal.Zero,
+	}
+}
+
+func (f *AccountFixture) WithCurrency(currency string) *AccountFixture {
+	f.currency = currency
+
+	return f
+}
+
+func (f *AccountFixture) WithBalance(balance decimal.Decimal) *AccountFixture {
+	f.balance = balance
+
+	return f
+}
+
+// Create persists the account and returns it as read back from the repository.
+// Accounts only exist once they took part in a transfer, so a zero balance account
+// is created with a deposit and a withdrawal of the same amount.
+func (f *AccountFixture) Create(t testing.TB, repo repository.Repository) *api.Account {
+	t.Helper()
+
+	if f.balance.IsZero() {
+		SeedBalance(t, repo, f.accountID, f.currency, decimal.NewFromInt(1))
+		NewTransferFixture(f.accountID, api.CompanyAccountID).
+			WithCurrency(f.currency).
+			WithAmount(decimal.NewFromInt(1)).
+			Create(t, repo)
+	} else {
+		SeedBalance(t, repo, f.accountID, f.currency, f.balance)
+	}
+
+	account, err := repo.GetAccountBalance(context.Background(), f.currency, f.accountID)
+	if err != nil {
+		t.Fatalf("failed to read account fixture %s: %v", f.accountID, err)
+	}
+
+	return account
+}
+
+// TransferFixture builds a transfer through the repository.
+type TransferFixture struct {
+	request        *api.TransferRequest
+	idempotencyKey string
+}
+
+// NewTransferFixture starts building a transfer of 1 USD with a random idempotency key.
+func NewTransferFixture(fromAccountID, toAccountID string) *TransferFixture {
+	return &TransferFixture{
+		request: &api.TransferRequest{
+			FromAccountID: fromAccountID,
+			ToAccountID:   toAccountID,
+			Currency:      defaultFixtureCurrency,
+			Amount:        decimal.NewFromInt(1),
+		},
+	}
+}
+
+func (f *TransferFixture) WithCurrency(currency string) *TransferFixture {
+	f.request.Currency = currency
+
+	return f
+}
+
+func (f *TransferFixture) WithAmount(amount decimal.Decimal) *TransferFixture {
+	f.request.Amount = amount
+
+	return f
+}
+
+func (f *TransferFixture) WithRemarks(remarks string) *TransferFixture {
+	f.request.Remarks = remarks
+
+	return f
+}
+
+func (f *TransferFixture) WithIdempotencyKey(key string) *TransferFixture {
+	f.idempotencyKey = key
+
+	return f
+}
+
+// Request returns a copy of the transfer request that will be sent.
+func (f *TransferFixture) Request() *api.TransferRequest {
+	request := *f.request
+
+	return &request
+}
+
+// Create performs the transfer and returns both legs of the double entry.
+func (f *TransferFixture) Create(t testing.TB, repo repository.Repository) []*api.Transaction {
+	t.Helper()
+
+	key := f.idempotencyKey
+	if key == "" {
+		key = NewIdempotencyKey(t)
+	}
+
+	transactions, err := repo.Transfer(context.Background(), f.Request(), key)
+	if err != nil {
+		t.Fatalf("failed to create transfer fixture from %s to %s: %v", f.request.FromAccountID, f.request.ToAccountID, err)
+	}
+
+	return transactions
+}
+
+// SeedBalance deposits the amount from the company account into the account.
+func SeedBalance(t testing.TB, repo repository.Repository, accountID, currency string, amount decimal.Decimal) []*api.Transaction {
+	t.Helper()
+
+	return NewTransferFixture(api.CompanyAccountID, accountID).
+		WithCurrency(currency).
+		WithAmount(amount).
+		WithRemarks("seed balance").
+		Create(t, repo)
+}
+
+// NewIdempotencyKey returns a random idempotency key that fits the group_id column.
+func NewIdempotencyKey(t testing.TB) string {
+	t.Helper()
+
+	buf := make([]byte, idempotencyKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatalf("failed to generate idempotency key: %v", err)
+	}
+
+	return "fixture-" + hex.EncodeToString(buf)
+}
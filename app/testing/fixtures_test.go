@@ -0,0 +1,71 @@
+package testing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	wallettesting "github.com/devshark/wallet/app/testing"
+	containers "github.com/devshark/wallet/pkg/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtures(t *testing.T) {
+	walletAPI := wallettesting.SetupWalletAPI(t)
+	repo := walletAPI.Repository
+	ctx := context.Background()
+
+	empty := wallettesting.NewAccountFixture("fixture_empty").Create(t, repo)
+	require.True(t, empty.Balance.IsZero())
+
+	funded := wallettesting.NewAccountFixture("fixture_funded").
+		WithCurrency("EUR").
+		WithBalance(decimal.NewFromInt(50)).
+		Create(t, repo)
+	require.Equal(t, "EUR", funded.Currency)
+	require.True(t, decimal.NewFromInt(50).Equal(funded.Balance))
+
+	transactions := wallettesting.NewTransferFixture("fixture_funded", "fixture_other").
+		WithCurrency("EUR").
+		WithAmount(decimal.NewFromInt(20)).
+		WithRemarks("lunch").
+		Create(t, repo)
+	require.Len(t, transactions, 2)
+
+	other, err := repo.GetAccountBalance(ctx, "EUR", "fixture_other")
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(20).Equal(other.Balance))
+
+	company, err := repo.GetAccountBalance(ctx, "EUR", api.CompanyAccountID)
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(-50).Equal(company.Balance))
+}
+
+func TestNewIdempotencyKey(t *testing.T) {
+	key1 := wallettesting.NewIdempotencyKey(t)
+	key2 := wallettesting.NewIdempotencyKey(t)
+
+	require.NotEqual(t, key1, key2)
+	require.LessOrEqual(t, len(key1), 50)
+}
+
+func TestDeterministicTransactions(t *testing.T) {
+	now := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	walletAPI := wallettesting.SetupWalletAPI(t,
+		wallettesting.WithClock(containers.NewFakeClock(now)),
+		wallettesting.WithIDGenerator(containers.NewSequentialIDs()),
+	)
+
+	transactions := wallettesting.SeedBalance(t, walletAPI.Repository, "deterministic", "USD", decimal.NewFromInt(5))
+	require.Len(t, transactions, 2)
+
+	ids := []string{transactions[0].TxID, transactions[1].TxID}
+	require.ElementsMatch(t, []string{containers.SequentialID(1), containers.SequentialID(2)}, ids)
+
+	for _, transaction := range transactions {
+		require.Equal(t, now, transaction.Time)
+	}
+}
@@ -0,0 +1,191 @@
+// Package testing wires the whole wallet application for end-to-end tests.
+// It lives under app, instead of pkg/testing, so it can reach the internal packages.
+package testing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/app/internal/migration"
+	"github.com/devshark/wallet/app/internal/repository"
+	"github.com/devshark/wallet/app/rest"
+	"github.com/devshark/wallet/client"
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/idgen"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	defaultCacheExpiry = time.Minute
+	httpTimeout        = 10 * time.Second
+)
+
+// WalletAPI is a running wallet API backed by real dependencies.
+type WalletAPI struct {
+	Server     *httptest.Server
+	DB         *sql.DB
+	Repository *repository.PostgresRepository
+	Reader     *client.AccountReaderClient
+	Operator   *client.AccountOperatorClient
+
+	// RedisClient is nil unless WithRedis was given.
+	RedisClient *redis.Client
+}
+
+type options struct {
+	db          *sql.DB
+	clock       clock.Clock
+	idGenerator idgen.Generator
+	withRedis   bool
+	cacheExpiry time.Duration
+	logger      *slog.Logger
+}
+
+type Option func(*options)
+
+// WithRedis starts a Redis container as well and enables the cache middleware.
+func WithRedis() Option {
+	return func(o *options) {
+		o.withRedis = true
+	}
+}
+
+// WithCacheExpiry overrides the cache expiry used by the cache middleware.
+func WithCacheExpiry(expiry time.Duration) Option {
+	return func(o *options) {
+		o.cacheExpiry = expiry
+	}
+}
+
+// WithDatabase uses the given database instead of starting a new Postgres container,
+// i.e. a disposable database from SetupWalletTemplateDB.
+func WithDatabase(db *sql.DB) Option {
+	return func(o *options) {
+		o.db = db
+	}
+}
+
+// WithClock makes the repository timestamp the transactions with the given clock i.e. a FakeClock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithIDGenerator makes the repository use the given transaction ids i.e. SequentialIDs.
+func WithIDGenerator(generator idgen.Generator) Option {
+	return func(o *options) {
+		o.idGenerator = generator
+	}
+}
+
+// WithLogger overrides the logger given to the application components.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// SetupWalletAPI starts Postgres (and optionally Redis), runs the migrations, and serves the API
+// with httptest. Everything is torn down by t.Cleanup.
+func SetupWalletAPI(t testing.TB, opts ...Option) *WalletAPI {
+	t.Helper()
+
+	o := &options{
+		cacheExpiry: defaultCacheExpiry,
+		logger:      slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	db := o.db
+	if db == nil {
+		db, _ = wallettesting.SetupTestDB(t)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+	defer cancel()
+
+	if err := migration.NewMigrator(db, MigrationsPath()).WithCustomLogger(o.logger).Up(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	repo := repository.NewPostgresRepository(db).WithCustomLogger(o.logger)
+
+	if o.clock != nil {
+		repo.WithClock(o.clock)
+	}
+
+	if o.idGenerator != nil {
+		repo.WithIDGenerator(o.idGenerator)
+	}
+
+	apiServer := rest.NewAPIServer(repo).
+		AddPinger(func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		}).
+		WithCustomLogger(o.logger)
+
+	walletAPI := &WalletAPI{
+		DB:         db,
+		Repository: repo,
+	}
+
+	if o.withRedis {
+		addr, _ := wallettesting.SetupTestRedis(t)
+
+		walletAPI.RedisClient = redis.NewClient(&redis.Options{Addr: addr})
+		t.Cleanup(func() {
+			_ = walletAPI.RedisClient.Close()
+		})
+
+		apiServer.
+			AddPinger(func(ctx context.Context) error {
+				return walletAPI.RedisClient.Ping(ctx).Err()
+			}).
+			WithCacheMiddleware(walletAPI.RedisClient, o.cacheExpiry)
+	}
+
+	// only the handler is needed, httptest takes care of the listener
+	walletAPI.Server = httptest.NewServer(apiServer.Handler())
+	t.Cleanup(walletAPI.Server.Close)
+
+	walletAPI.Reader = client.NewAccountReaderClient(walletAPI.Server.URL)
+	walletAPI.Operator = client.NewAccountOperatorClient(walletAPI.Server.URL)
+
+	return walletAPI
+}
+
+// SetupWalletTemplateDB creates a template database with the wallet migrations applied.
+// Use NewDatabase on the result to get an isolated database per test, so tests can use t.Parallel().
+func SetupWalletTemplateDB(t testing.TB) *wallettesting.TemplateDB {
+	t.Helper()
+
+	return wallettesting.SetupTemplateDB(t, Migrate)
+}
+
+// Migrate applies the wallet migrations to the database.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if err := migration.NewMigrator(db, MigrationsPath()).Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return nil
+}
+
+// MigrationsPath returns the absolute path of the migrations folder of this repository.
+func MigrationsPath() string {
+	_, file, _, _ := runtime.Caller(0)
+
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
@@ -0,0 +1,50 @@
+package testing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	wallettesting "github.com/devshark/wallet/app/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupWalletAPI(t *testing.T) {
+	walletAPI := wallettesting.SetupWalletAPI(t, wallettesting.WithRedis())
+	ctx := context.Background()
+
+	resp, err := http.Get(walletAPI.Server.URL + "/health")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deposit, err := walletAPI.Operator.Deposit(ctx, &api.DepositRequest{
+		Currency:    "USD",
+		ToAccountID: "e2e_user",
+		Amount:      decimal.NewFromInt(100),
+	}, "e2e-deposit-1")
+	require.NoError(t, err)
+	require.Equal(t, api.CREDIT, deposit.Type)
+
+	withdrawal, err := walletAPI.Operator.Withdraw(ctx, &api.WithdrawRequest{
+		Currency:      "USD",
+		FromAccountID: "e2e_user",
+		Amount:        decimal.NewFromInt(30),
+	}, "e2e-withdraw-1")
+	require.NoError(t, err)
+	require.Equal(t, api.DEBIT, withdrawal.Type)
+
+	account, err := walletAPI.Reader.GetAccountBalance(ctx, "USD", "e2e_user")
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(70).Equal(account.Balance))
+
+	transactions, err := walletAPI.Reader.GetTransactions(ctx, "USD", "e2e_user")
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+
+	transaction, err := walletAPI.Reader.GetTransaction(ctx, deposit.TxID)
+	require.NoError(t, err)
+	require.Equal(t, deposit.TxID, transaction.TxID)
+}
@@ -0,0 +1,13 @@
+package testing_test
+
+import (
+	"os"
+	"testing"
+
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+)
+
+// all tests of this package share one Postgres container, each with its own database
+func TestMain(m *testing.M) {
+	os.Exit(wallettesting.RunShared(m))
+}
@@ -0,0 +1,33 @@
+package testing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	wallettesting "github.com/devshark/wallet/app/testing"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupWalletTemplateDB(t *testing.T) {
+	template := wallettesting.SetupWalletTemplateDB(t)
+
+	for i := range 3 {
+		t.Run(fmt.Sprintf("isolated database %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			walletAPI := wallettesting.SetupWalletAPI(t, wallettesting.WithDatabase(template.NewDatabase(t)))
+
+			// every test sees only its own ledger, even with the same account ids
+			account := wallettesting.NewAccountFixture("isolated").
+				WithBalance(decimal.NewFromInt(10)).
+				Create(t, walletAPI.Repository)
+			require.True(t, decimal.NewFromInt(10).Equal(account.Balance))
+
+			transactions, err := walletAPI.Repository.GetTransactions(context.Background(), "USD", "isolated")
+			require.NoError(t, err)
+			require.Len(t, transactions, 1)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/clock"
+)
+
+// transactionCache keeps the most recently read transactions, up to maxEntries, for ttl each.
+type transactionCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	clock      clock.Clock
+	// the front is the most recently used
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedTransaction struct {
+	txID        string
+	transaction api.Transaction
+	expiresAt   time.Time
+}
+
+func newTransactionCache(maxEntries int, ttl time.Duration) *transactionCache {
+	return &transactionCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		clock:      clock.NewSystemClock(),
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// get returns a copy of the transaction, so the callers can't alter the cached one.
+func (c *transactionCache) get(txID string) (*api.Transaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[txID]
+	if !ok {
+		return nil, false
+	}
+
+	cached, _ := element.Value.(*cachedTransaction)
+	if !c.clock.Now().Before(cached.expiresAt) {
+		c.remove(element)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+
+	transaction := copyTransaction(&cached.transaction)
+
+	return &transaction, true
+}
+
+// put caches a copy of the transaction, evicting the least recently used one when full.
+func (c *transactionCache) put(transaction *api.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached := &cachedTransaction{
+		txID:        transaction.TxID,
+		transaction: copyTransaction(transaction),
+		expiresAt:   c.clock.Now().Add(c.ttl),
+	}
+
+	if element, ok := c.entries[transaction.TxID]; ok {
+		element.Value = cached
+		c.order.MoveToFront(element)
+
+		return
+	}
+
+	c.entries[transaction.TxID] = c.order.PushFront(cached)
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *transactionCache) remove(element *list.Element) {
+	cached, _ := element.Value.(*cachedTransaction)
+
+	c.order.Remove(element)
+	delete(c.entries, cached.txID)
+}
+
+// copyTransaction copies the transaction along with its tags and the values of its pointers,
+// so the copy shares nothing with the original.
+func copyTransaction(transaction *api.Transaction) api.Transaction {
+	copied := *transaction
+	copied.Tags = append([]string(nil), transaction.Tags...)
+
+	if transaction.Rounding != nil {
+		rounding := *transaction.Rounding
+		copied.Rounding = &rounding
+	}
+
+	if transaction.FXRate != nil {
+		rate := *transaction.FXRate
+		copied.FXRate = &rate
+	}
+
+	if transaction.Fee != nil {
+		fee := *transaction.Fee
+		copied.Fee = &fee
+	}
+
+	return copied
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/devshark/wallet/pkg/msgpack"
+)
+
+// Codec decodes the payloads of the responses in the media type the clients ask for, see WithCodec.
+type Codec interface {
+	ContentType() string
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec is the default codec of the clients.
+	JSONCodec Codec = jsonCodec{}
+	// MessagePackCodec decodes the responses in MessagePack, with the fields of their JSON encoding.
+	MessagePackCodec Codec = messagePackCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v) //nolint:wrapcheck // wrapped by the clients
+}
+
+type messagePackCodec struct{}
+
+func (messagePackCodec) ContentType() string { return msgpack.ContentType }
+
+func (messagePackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v) //nolint:wrapcheck // wrapped by the clients
+}
+
+// acceptHeader asks for the media type of the codec, or JSON from the servers that don't support it.
+func acceptHeader(codec Codec) string {
+	if codec.ContentType() == JSONCodec.ContentType() {
+		return codec.ContentType()
+	}
+
+	return codec.ContentType() + ", application/json;q=0.5"
+}
+
+// decodeResponse decodes the payload of the response into v, with the codec if the server responded
+// in its media type, as JSON otherwise.
+func decodeResponse(codec Codec, resp *http.Response, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != codec.ContentType() || codec == JSONCodec {
+		return json.NewDecoder(resp.Body).Decode(v) //nolint:wrapcheck // wrapped by the callers
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return codec.Unmarshal(data, v) //nolint:wrapcheck // wrapped by the callers
+}
@@ -15,6 +15,7 @@ type AccountOperatorClient struct {
 	baseURL    string
 	httpClient *http.Client
 	clientName string
+	codec      Codec
 }
 
 // NewAccountOperatorClient creates a new AccountOperatorClient.
@@ -23,6 +24,7 @@ func NewAccountOperatorClient(baseURL string) *AccountOperatorClient {
 		baseURL:    baseURL,
 		httpClient: &http.Client{},
 		clientName: "AccountOperatorClient",
+		codec:      JSONCodec,
 	}
 }
 
@@ -32,29 +34,214 @@ func (c *AccountOperatorClient) WithName(name string) *AccountOperatorClient {
 	return c
 }
 
+// WithCodec asks the server for the responses in the media type of the codec, see AccountReaderClient.WithCodec.
+// The requests are sent as JSON whatever the codec.
+func (c *AccountOperatorClient) WithCodec(codec Codec) *AccountOperatorClient {
+	c.codec = codec
+
+	return c
+}
+
 // Deposit performs a deposit operation.
 func (c *AccountOperatorClient) Deposit(ctx context.Context, request *api.DepositRequest, idempotencyKey string) (*api.Transaction, error) {
 	url := fmt.Sprintf("%s/deposit", c.baseURL)
 
-	return c.postAndDecode(ctx, url, request, idempotencyKey)
+	transaction := &api.Transaction{}
+	if err := c.postAndDecode(ctx, url, request, transaction, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
 }
 
 // Withdraw performs a withdrawal operation.
 func (c *AccountOperatorClient) Withdraw(ctx context.Context, request *api.WithdrawRequest, idempotencyKey string) (*api.Transaction, error) {
 	url := fmt.Sprintf("%s/withdraw", c.baseURL)
 
-	return c.postAndDecode(ctx, url, request, idempotencyKey)
+	transaction := &api.Transaction{}
+	if err := c.postAndDecode(ctx, url, request, transaction, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// DepositAmountString deposits the amount given as a decimal string, i.e. "10.50", parsed by api.ParseAmount
+// so the callers never build it from a float. An invalid amount is returned before any request is sent.
+func (c *AccountOperatorClient) DepositAmountString(ctx context.Context, accountID, currency, amount, idempotencyKey string) (*api.Transaction, error) {
+	parsed, err := api.ParseAmount(amount)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the amount errors are returned as is
+	}
+
+	return c.Deposit(ctx, &api.DepositRequest{ToAccountID: accountID, Currency: currency, Amount: parsed}, idempotencyKey)
+}
+
+// WithdrawAmountString withdraws the amount given as a decimal string, see DepositAmountString.
+func (c *AccountOperatorClient) WithdrawAmountString(ctx context.Context, accountID, currency, amount, idempotencyKey string) (*api.Transaction, error) {
+	parsed, err := api.ParseAmount(amount)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the amount errors are returned as is
+	}
+
+	return c.Withdraw(ctx, &api.WithdrawRequest{FromAccountID: accountID, Currency: currency, Amount: parsed}, idempotencyKey)
 }
 
-// Transfer performs a transfer operation.
-func (c *AccountOperatorClient) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Transaction, error) {
+// Transfer performs a transfer operation, and returns the receipt with both legs of the transfer.
+func (c *AccountOperatorClient) Transfer(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.TransferReceipt, error) {
 	url := fmt.Sprintf("%s/transfer", c.baseURL)
 
-	return c.postAndDecode(ctx, url, request, idempotencyKey)
+	receipt := &api.TransferReceipt{}
+	if err := c.postAndDecode(ctx, url, request, receipt, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// TransferBatch posts the transfers of the batch at once, either all of them or none, and returns their receipts.
+func (c *AccountOperatorClient) TransferBatch(ctx context.Context, request *api.TransferBatchRequest, idempotencyKey string) (*api.TransferBatchReceipt, error) {
+	url := fmt.Sprintf("%s/transfers/batch", c.baseURL)
+
+	receipt := &api.TransferBatchReceipt{}
+	if err := c.postAndDecode(ctx, url, request, receipt, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// PlaceHold reserves the amount of the transfer on the sender, until the hold is captured or released.
+// The idempotency key is the group of the transfer once captured.
+func (c *AccountOperatorClient) PlaceHold(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.Hold, error) {
+	url := fmt.Sprintf("%s/holds", c.baseURL)
+
+	hold := &api.Hold{}
+	if err := c.postAndDecode(ctx, url, request, hold, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold posts the transfer of the authorized hold, and returns its receipt.
+func (c *AccountOperatorClient) CaptureHold(ctx context.Context, holdID string) (*api.TransferReceipt, error) {
+	url := fmt.Sprintf("%s/holds/%s/capture", c.baseURL, holdID)
+
+	receipt := &api.TransferReceipt{}
+	if err := c.postAndDecode(ctx, url, nil, receipt, ""); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// ReleaseHold cancels the authorized hold, and returns the released hold.
+func (c *AccountOperatorClient) ReleaseHold(ctx context.Context, holdID string) (*api.Hold, error) {
+	url := fmt.Sprintf("%s/holds/%s/release", c.baseURL, holdID)
+
+	hold := &api.Hold{}
+	if err := c.postAndDecode(ctx, url, nil, hold, ""); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ScheduleTransfer schedules the transfer at its ExecuteAt, to be posted by the workers of the server once due,
+// and returns the scheduled transfer, whose status is polled with AccountReaderClient.GetScheduledTransfer.
+// The idempotency key is the group of the transfer once executed.
+func (c *AccountOperatorClient) ScheduleTransfer(ctx context.Context, request *api.ScheduleTransferRequest, idempotencyKey string) (*api.ScheduledTransfer, error) {
+	url := fmt.Sprintf("%s/transfers/scheduled", c.baseURL)
+
+	scheduled := &api.ScheduledTransfer{}
+	if err := c.postAndDecode(ctx, url, request, scheduled, idempotencyKey); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduledTransfer cancels the scheduled transfer before it's due, and returns the cancelled transfer.
+func (c *AccountOperatorClient) CancelScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error) {
+	url := fmt.Sprintf("%s/transfers/scheduled/%s/cancel", c.baseURL, id)
+
+	scheduled := &api.ScheduledTransfer{}
+	if err := c.postAndDecode(ctx, url, nil, scheduled, ""); err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// TransferAsync queues the transfer, to be posted by the workers of the server, and returns the queued transfer,
+// whose status is polled with AccountReaderClient.GetQueuedTransfer. The server must queue the transfers.
+func (c *AccountOperatorClient) TransferAsync(ctx context.Context, request *api.TransferRequest, idempotencyKey string) (*api.QueuedTransfer, error) {
+	req, err := c.newPostRequest(ctx, fmt.Sprintf("%s/transfer", c.baseURL), request, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Prefer", "respond-async")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+	}
+
+	// held by a server that doesn't queue the transfers
+	if resp.Header.Get("Preference-Applied") != "respond-async" {
+		return nil, heldTransferError(c.codec, resp)
+	}
+
+	queued := &api.QueuedTransfer{}
+	if err := decodeResponse(c.codec, resp, queued); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return queued, nil
 }
 
-// postAndDecode performs a POST request and decodes the response into a Transaction.
-func (c *AccountOperatorClient) postAndDecode(ctx context.Context, url string, payload interface{}, idempotencyKey string) (*api.Transaction, error) {
+// postAndDecode performs a POST request and decodes the response into v.
+func (c *AccountOperatorClient) postAndDecode(ctx context.Context, url string, payload, v interface{}, idempotencyKey string) error {
+	req, err := c.newPostRequest(ctx, url, payload, idempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// the transfers flagged by the screening or above the approval threshold are held, and may be rejected
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return heldTransferError(c.codec, resp)
+	case http.StatusForbidden:
+		return api.ErrTransferRejected
+	}
+
+	// the server responds with 201 Created for new transactions
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+	}
+
+	if err := decodeResponse(c.codec, resp, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// newPostRequest creates a POST request of the JSON payload.
+func (c *AccountOperatorClient) newPostRequest(ctx context.Context, url string, payload interface{}, idempotencyKey string) (*http.Request, error) {
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -69,21 +256,23 @@ func (c *AccountOperatorClient) postAndDecode(ctx context.Context, url string, p
 	req.Header.Set("X-Idempotency-Key", idempotencyKey)
 	req.Header.Set("Client-Name", c.clientName)
 	req.Header.Set("User-Agent", c.clientName)
+	req.Header.Set("Accept", acceptHeader(c.codec))
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	return req, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+// heldTransferError tells the transfers held for their approval from the ones held for a review.
+// The servers without the error codes are told by their message.
+func heldTransferError(codec Codec, resp *http.Response) error {
+	var response api.ErrorResponse
+	if err := decodeResponse(codec, resp, &response); err != nil {
+		return api.ErrTransferUnderReview
 	}
 
-	var transaction api.Transaction
-	if err := json.NewDecoder(resp.Body).Decode(&transaction); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if response.Code == api.CodeTransferPendingApproval ||
+		(response.Code == "" && response.Message == api.ErrTransferPendingApproval.Error()) {
+		return api.ErrTransferPendingApproval
 	}
 
-	return &transaction, nil
+	return api.ErrTransferUnderReview
 }
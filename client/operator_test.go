@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 )
@@ -191,21 +193,72 @@ func TestAccountOperatorClient_Withdraw(t *testing.T) {
 	})
 }
 
+func TestAccountOperatorClient_AmountString(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		var request struct {
+			AccountID string          `json:"account_id"`
+			Currency  string          `json:"currency"`
+			Amount    decimal.Decimal `json:"amount"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "acc123", request.AccountID)
+		require.Equal(t, "USD", request.Currency)
+		require.True(t, decimal.RequireFromString("10.50").Equal(request.Amount))
+
+		err := json.NewEncoder(w).Encode(&api.Transaction{TxID: r.URL.Path, Amount: request.Amount})
+
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewAccountOperatorClient(server.URL)
+
+	deposit, err := client.DepositAmountString(context.Background(), "acc123", "USD", "10.50", "test-key-1")
+	require.NoError(t, err)
+	require.Equal(t, "/deposit", deposit.TxID)
+
+	withdrawal, err := client.WithdrawAmountString(context.Background(), "acc123", "USD", "10.50", "test-key-2")
+	require.NoError(t, err)
+	require.Equal(t, "/withdraw", withdrawal.TxID)
+
+	for amount, expected := range map[string]error{"10,50": api.ErrInvalidAmount, "-10.50": api.ErrNegativeAmount, "1e-30": api.ErrAmountOutOfRange} {
+		_, err = client.DepositAmountString(context.Background(), "acc123", "USD", amount, "test-key-3")
+		require.ErrorIs(t, err, expected, amount)
+
+		_, err = client.WithdrawAmountString(context.Background(), "acc123", "USD", amount, "test-key-4")
+		require.ErrorIs(t, err, expected, amount)
+	}
+
+	require.Equal(t, int32(2), requests.Load(), "the invalid amounts aren't sent")
+}
+
 func TestAccountOperatorClient_Transfer(t *testing.T) {
 	t.Run("Successful transfer", func(t *testing.T) {
-		mockTransaction := &api.Transaction{
-			TxID:      "tx125",
-			AccountID: "acc124",
-			Amount:    decimal.NewFromFloat(75.00),
-			Type:      api.CREDIT,
+		request := &api.TransferRequest{
+			FromAccountID: "acc123",
+			ToAccountID:   "acc124",
+			Amount:        decimal.NewFromFloat(75.00),
+			Currency:      "USD",
 		}
 
+		mockReceipt, err := api.NewTransferReceipt(request, "test-key-3", []*api.Transaction{
+			{TxID: "tx124", AccountID: "acc123", Amount: decimal.NewFromFloat(75.00), Type: api.DEBIT},
+			{TxID: "tx125", AccountID: "acc124", Amount: decimal.NewFromFloat(75.00), Type: api.CREDIT},
+		})
+		require.NoError(t, err)
+
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			require.Equal(t, "/transfer", r.URL.Path)
 			require.Equal(t, http.MethodPost, r.Method)
 			require.Equal(t, "test-key-3", r.Header.Get("X-Idempotency-Key"))
 
-			err := json.NewEncoder(w).Encode(mockTransaction)
+			w.WriteHeader(http.StatusCreated)
+			err := json.NewEncoder(w).Encode(mockReceipt)
 
 			require.NoError(t, err)
 		}))
@@ -214,20 +267,14 @@ func TestAccountOperatorClient_Transfer(t *testing.T) {
 		client := NewAccountOperatorClient(server.URL)
 		client.WithName("AccountOperatorClient")
 
-		request := &api.TransferRequest{
-			FromAccountID: "acc123",
-			ToAccountID:   "acc124",
-			Amount:        decimal.NewFromFloat(75.00),
-			Currency:      "USD",
-		}
-
-		transaction, err := client.Transfer(context.Background(), request, "test-key-3")
+		receipt, err := client.Transfer(context.Background(), request, "test-key-3")
 
 		require.NoError(t, err)
-		require.Equal(t, mockTransaction.TxID, transaction.TxID)
-		require.Equal(t, mockTransaction.AccountID, transaction.AccountID)
-		require.True(t, mockTransaction.Amount.Equal(transaction.Amount))
-		require.Equal(t, mockTransaction.Type, transaction.Type)
+		require.Equal(t, "test-key-3", receipt.GroupID)
+		require.Equal(t, "tx124", receipt.Debit.TxID)
+		require.Equal(t, "tx125", receipt.Credit.TxID)
+		require.Equal(t, "acc124", receipt.Request.ToAccountID)
+		require.True(t, request.Amount.Equal(receipt.Credit.Amount))
 	})
 
 	t.Run("Server error", func(t *testing.T) {
@@ -252,6 +299,65 @@ func TestAccountOperatorClient_Transfer(t *testing.T) {
 		require.Contains(t, err.Error(), "unexpected error: 500")
 	})
 
+	t.Run("Held transfers", func(t *testing.T) {
+		for _, tc := range []struct {
+			code      int
+			errorCode api.ErrorCode
+			message   string
+			expected  error
+		}{
+			{http.StatusAccepted, "", api.ErrTransferUnderReview.Error(), api.ErrTransferUnderReview},
+			{http.StatusAccepted, "", api.ErrTransferPendingApproval.Error(), api.ErrTransferPendingApproval},
+			{http.StatusAccepted, api.CodeTransferPendingApproval, "reworded", api.ErrTransferPendingApproval},
+			{http.StatusAccepted, api.CodeTransferUnderReview, api.ErrTransferPendingApproval.Error(), api.ErrTransferUnderReview},
+			{http.StatusForbidden, "", api.ErrTransferRejected.Error(), api.ErrTransferRejected},
+		} {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.code)
+
+				err := json.NewEncoder(w).Encode(api.ErrorResponse{ErrorCode: tc.code, Code: tc.errorCode, Message: tc.message})
+
+				require.NoError(t, err)
+			}))
+
+			client := NewAccountOperatorClient(server.URL)
+
+			_, err := client.Transfer(context.Background(), &api.TransferRequest{
+				FromAccountID: "acc123",
+				ToAccountID:   "acc456",
+				Currency:      "USD",
+				Amount:        decimal.NewFromFloat(100.50),
+			}, "test-key-1")
+
+			require.ErrorIs(t, err, tc.expected)
+
+			server.Close()
+		}
+	})
+
+	t.Run("Held transfer in MessagePack", func(t *testing.T) {
+		server := httptest.NewServer(middlewares.NewMessagePack()(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+
+			err := json.NewEncoder(w).Encode(api.ErrorResponse{Code: api.CodeTransferPendingApproval, Message: "reworded"})
+
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		client := NewAccountOperatorClient(server.URL).WithCodec(MessagePackCodec)
+
+		_, err := client.Transfer(context.Background(), &api.TransferRequest{
+			FromAccountID: "acc123",
+			ToAccountID:   "acc456",
+			Currency:      "USD",
+			Amount:        decimal.NewFromFloat(100.50),
+		}, "test-key-1")
+
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+	})
+
 	t.Run("Context cancellation", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			time.Sleep(100 * time.Millisecond)
@@ -370,3 +476,191 @@ func TestAccountOperatorClient_NoSuchHost(t *testing.T) {
 		require.Contains(t, err.Error(), "no such host")
 	})
 }
+
+func TestAccountOperatorClient_TransferBatch(t *testing.T) {
+	request := &api.TransferBatchRequest{Transfers: []api.TransferRequest{
+		{FromAccountID: "acc123", ToAccountID: "acc124", Currency: "USD", Amount: decimal.NewFromFloat(75.00)},
+	}}
+
+	transfer, err := api.NewTransferReceipt(&request.Transfers[0], "payroll-1:0", []*api.Transaction{
+		{TxID: "tx124", AccountID: "acc123", Amount: decimal.NewFromFloat(75.00), Type: api.DEBIT},
+		{TxID: "tx125", AccountID: "acc124", Amount: decimal.NewFromFloat(75.00), Type: api.CREDIT},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/transfers/batch", r.URL.Path)
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "payroll-1", r.Header.Get("X-Idempotency-Key"))
+
+		w.WriteHeader(http.StatusCreated)
+		err := json.NewEncoder(w).Encode(&api.TransferBatchReceipt{IdempotencyKey: "payroll-1", Transfers: []*api.TransferReceipt{transfer}})
+
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	receipt, err := NewAccountOperatorClient(server.URL).TransferBatch(context.Background(), request, "payroll-1")
+
+	require.NoError(t, err)
+	require.Equal(t, "payroll-1", receipt.IdempotencyKey)
+	require.Len(t, receipt.Transfers, 1)
+	require.Equal(t, "payroll-1:0", receipt.Transfers[0].GroupID)
+	require.Equal(t, "tx125", receipt.Transfers[0].Credit.TxID)
+}
+
+func TestAccountOperatorClient_Holds(t *testing.T) {
+	request := &api.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc124", Currency: "USD", Amount: decimal.NewFromFloat(75.00)}
+	hold := &api.Hold{ID: "hold-1", IdempotencyKey: "auth-1", FromAccountID: "acc123", ToAccountID: "acc124", Currency: "USD", Amount: request.Amount, Status: api.HoldAuthorized}
+
+	receipt, err := api.NewTransferReceipt(request, "auth-1", []*api.Transaction{
+		{TxID: "tx124", AccountID: "acc123", Amount: request.Amount, Type: api.DEBIT},
+		{TxID: "tx125", AccountID: "acc124", Amount: request.Amount, Type: api.CREDIT},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var response any
+
+		switch r.URL.Path {
+		case "/holds":
+			require.Equal(t, "auth-1", r.Header.Get("X-Idempotency-Key"))
+			w.WriteHeader(http.StatusCreated)
+
+			response = hold
+		case "/holds/hold-1/capture":
+			w.WriteHeader(http.StatusCreated)
+
+			response = receipt
+		case "/holds/hold-1/release":
+			w.WriteHeader(http.StatusOK)
+
+			response = &api.Hold{ID: "hold-1", Status: api.HoldReleased}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer server.Close()
+
+	client := NewAccountOperatorClient(server.URL)
+	ctx := context.Background()
+
+	placed, err := client.PlaceHold(ctx, request, "auth-1")
+	require.NoError(t, err)
+	require.Equal(t, "hold-1", placed.ID)
+	require.Equal(t, api.HoldAuthorized, placed.Status)
+
+	captured, err := client.CaptureHold(ctx, placed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "auth-1", captured.GroupID)
+	require.Equal(t, "tx125", captured.Credit.TxID)
+
+	released, err := client.ReleaseHold(ctx, placed.ID)
+	require.NoError(t, err)
+	require.Equal(t, api.HoldReleased, released.Status)
+}
+
+func TestAccountOperatorClient_ScheduledTransfers(t *testing.T) {
+	request := &api.ScheduleTransferRequest{
+		TransferRequest: api.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc124", Currency: "USD", Amount: decimal.NewFromFloat(75.00)},
+		ExecuteAt:       time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+
+		var response any
+
+		switch r.URL.Path {
+		case "/transfers/scheduled":
+			require.Equal(t, "rent-1", r.Header.Get("X-Idempotency-Key"))
+
+			received := &api.ScheduleTransferRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+			require.True(t, request.ExecuteAt.Equal(received.ExecuteAt))
+
+			w.WriteHeader(http.StatusCreated)
+
+			response = &api.ScheduledTransfer{ID: "scheduled-1", IdempotencyKey: "rent-1", ExecuteAt: received.ExecuteAt, Status: api.ScheduledTransferScheduled}
+		case "/transfers/scheduled/scheduled-1/cancel":
+			w.WriteHeader(http.StatusOK)
+
+			response = &api.ScheduledTransfer{ID: "scheduled-1", Status: api.ScheduledTransferCancelled}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer server.Close()
+
+	client := NewAccountOperatorClient(server.URL)
+	ctx := context.Background()
+
+	scheduled, err := client.ScheduleTransfer(ctx, request, "rent-1")
+	require.NoError(t, err)
+	require.Equal(t, "scheduled-1", scheduled.ID)
+	require.Equal(t, api.ScheduledTransferScheduled, scheduled.Status)
+
+	cancelled, err := client.CancelScheduledTransfer(ctx, scheduled.ID)
+	require.NoError(t, err)
+	require.Equal(t, api.ScheduledTransferCancelled, cancelled.Status)
+}
+
+func TestAccountOperatorClient_TransferAsync(t *testing.T) {
+	request := &api.TransferRequest{FromAccountID: "acc123", ToAccountID: "acc124", Currency: "USD", Amount: decimal.NewFromFloat(75.00)}
+
+	t.Run("Queued", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/transfer", r.URL.Path)
+			require.Equal(t, "respond-async", r.Header.Get("Prefer"))
+			require.Equal(t, "burst-1", r.Header.Get("X-Idempotency-Key"))
+
+			w.Header().Set("Preference-Applied", "respond-async")
+			w.WriteHeader(http.StatusAccepted)
+
+			err := json.NewEncoder(w).Encode(&api.QueuedTransfer{ID: "queued-1", IdempotencyKey: "burst-1", Status: api.QueuedTransferQueued})
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		queued, err := NewAccountOperatorClient(server.URL).TransferAsync(context.Background(), request, "burst-1")
+
+		require.NoError(t, err)
+		require.Equal(t, "queued-1", queued.ID)
+		require.Equal(t, api.QueuedTransferQueued, queued.Status)
+	})
+
+	t.Run("Held by a server without the queue", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+
+			err := json.NewEncoder(w).Encode(&api.ErrorResponse{ErrorCode: http.StatusAccepted, Code: api.CodeTransferPendingApproval})
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		_, err := NewAccountOperatorClient(server.URL).TransferAsync(context.Background(), request, "burst-2")
+
+		require.ErrorIs(t, err, api.ErrTransferPendingApproval)
+	})
+
+	t.Run("Posted", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		_, err := NewAccountOperatorClient(server.URL).TransferAsync(context.Background(), request, "burst-3")
+
+		require.ErrorIs(t, err, api.ErrUnexpected)
+	})
+}
@@ -2,11 +2,15 @@ package client
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/retry"
 )
 
 // AccountReaderClient implements the AccountReader interface.
@@ -14,6 +18,11 @@ type AccountReaderClient struct {
 	baseURL    string
 	httpClient *http.Client
 	clientName string
+	// cache keeps the transactions read by GetTransaction, see WithTransactionCache
+	cache *transactionCache
+	// reconnect is the backoff of the subscriptions reconnecting, see SubscribeTransactions
+	reconnect retry.Policy
+	codec     Codec
 }
 
 // NewAccountReaderClient creates a new AccountReaderClient.
@@ -22,9 +31,34 @@ func NewAccountReaderClient(baseURL string) *AccountReaderClient {
 		baseURL:    baseURL,
 		httpClient: &http.Client{},
 		clientName: "AccountOperatorClient",
+		reconnect:  retry.DefaultPolicy(),
+		codec:      JSONCodec,
 	}
 }
 
+// WithCodec asks the server for the responses in the media type of the codec, i.e. MessagePackCodec
+// to save bandwidth, falling back to JSON with the servers that don't support it.
+func (c *AccountReaderClient) WithCodec(codec Codec) *AccountReaderClient {
+	c.codec = codec
+
+	return c
+}
+
+// WithTransactionCache keeps up to maxEntries transactions read by GetTransaction in memory, for ttl each,
+// evicting the least recently read first, i.e. for the reconciliation jobs reading the same receipts again.
+// The entries are immutable, but the running balance is the current balance of the account,
+// and the remarks and the reference may be amended, so the ttl bounds how stale they get.
+// The cache is disabled if either bound isn't positive.
+func (c *AccountReaderClient) WithTransactionCache(maxEntries int, ttl time.Duration) *AccountReaderClient {
+	c.cache = nil
+
+	if maxEntries > 0 && ttl > 0 {
+		c.cache = newTransactionCache(maxEntries, ttl)
+	}
+
+	return c
+}
+
 // GetAccountBalance retrieves the account balance for a given currency and account ID.
 func (c *AccountReaderClient) GetAccountBalance(ctx context.Context, currency, accountID string) (*api.Account, error) {
 	url := fmt.Sprintf("%s/account/%s/%s", c.baseURL, accountID, currency)
@@ -34,15 +68,54 @@ func (c *AccountReaderClient) GetAccountBalance(ctx context.Context, currency, a
 	return account, err
 }
 
-// GetTransaction retrieves a transaction by its ID.
+// GetAccountSummary retrieves the balances of the account in every currency it holds, in a single request.
+func (c *AccountReaderClient) GetAccountSummary(ctx context.Context, accountID string) (*api.AccountSummary, error) {
+	endpoint := fmt.Sprintf("%s/account/%s", c.baseURL, url.PathEscape(accountID))
+	summary := &api.AccountSummary{}
+	err := c.getAndDecode(ctx, endpoint, summary)
+
+	return summary, err
+}
+
+// GetTransaction retrieves a transaction by its ID, from the cache if enabled, see WithTransactionCache.
 func (c *AccountReaderClient) GetTransaction(ctx context.Context, txID string) (*api.Transaction, error) {
+	if c.cache != nil {
+		if transaction, ok := c.cache.get(txID); ok {
+			return transaction, nil
+		}
+	}
+
 	url := fmt.Sprintf("%s/transactions/%s", c.baseURL, txID)
 	transaction := &api.Transaction{}
+
 	err := c.getAndDecode(ctx, url, transaction)
+	if err == nil && c.cache != nil {
+		c.cache.put(transaction)
+	}
 
 	return transaction, err
 }
 
+// GetQueuedTransfer retrieves the status of a transfer queued with AccountOperatorClient.TransferAsync.
+func (c *AccountReaderClient) GetQueuedTransfer(ctx context.Context, id string) (*api.QueuedTransfer, error) {
+	url := fmt.Sprintf("%s/transfers/%s/status", c.baseURL, id)
+	queued := &api.QueuedTransfer{}
+
+	err := c.getAndDecode(ctx, url, queued)
+
+	return queued, err
+}
+
+// GetScheduledTransfer retrieves the status of a transfer scheduled with AccountOperatorClient.ScheduleTransfer.
+func (c *AccountReaderClient) GetScheduledTransfer(ctx context.Context, id string) (*api.ScheduledTransfer, error) {
+	url := fmt.Sprintf("%s/transfers/scheduled/%s/status", c.baseURL, id)
+	scheduled := &api.ScheduledTransfer{}
+
+	err := c.getAndDecode(ctx, url, scheduled)
+
+	return scheduled, err
+}
+
 // GetTransactions retrieves all transactions for a given currency and account ID.
 func (c *AccountReaderClient) GetTransactions(ctx context.Context, currency, accountID string) ([]*api.Transaction, error) {
 	url := fmt.Sprintf("%s/transactions/%s/%s", c.baseURL, accountID, currency)
@@ -54,6 +127,123 @@ func (c *AccountReaderClient) GetTransactions(ctx context.Context, currency, acc
 	return transactions, err
 }
 
+// FilterTransactions retrieves the transactions for a given currency and account ID matching the filter, most recent first.
+func (c *AccountReaderClient) FilterTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter) ([]*api.Transaction, error) {
+	endpoint := fmt.Sprintf("%s/transactions/%s/%s?%s", c.baseURL, url.PathEscape(accountID), url.PathEscape(currency), filterQuery(filter).Encode())
+
+	var transactions []*api.Transaction
+
+	err := c.getAndDecodeSlice(ctx, endpoint, &transactions)
+
+	return transactions, err
+}
+
+// GetTransactionsPage retrieves at most limit transactions for a given currency and account ID matching the filter,
+// most recent first, from the cursor of the previous page, or from the most recent with an empty cursor.
+// The next cursor is empty on the last page.
+func (c *AccountReaderClient) GetTransactionsPage(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string, limit int) (*api.TransactionPage, error) {
+	query := filterQuery(filter)
+	query.Set("limit", strconv.Itoa(limit))
+
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	endpoint := fmt.Sprintf("%s/transactions/%s/%s?%s", c.baseURL, url.PathEscape(accountID), url.PathEscape(currency), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Client-Name", c.clientName)
+	req.Header.Set("User-Agent", c.clientName)
+	req.Header.Set("Accept", acceptHeader(c.codec))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+	}
+
+	page := &api.TransactionPage{NextCursor: resp.Header.Get(api.NextCursorHeader)}
+
+	if page.TotalCount, err = strconv.Atoi(resp.Header.Get(api.TotalCountHeader)); err != nil {
+		return nil, fmt.Errorf("failed to read total count: %w", err)
+	}
+
+	if err = decodeResponse(c.codec, resp, &page.Transactions); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return page, nil
+}
+
+// filterQuery is the query of the transactions matching the filter, the times in UTC.
+func filterQuery(filter api.TransactionFilter) url.Values {
+	query := url.Values{}
+
+	for _, tag := range filter.Tags {
+		query.Add("tag", tag)
+	}
+
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.UTC().Format(time.RFC3339Nano))
+	}
+
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.UTC().Format(time.RFC3339Nano))
+	}
+
+	if filter.Type != "" {
+		query.Set("type", string(filter.Type))
+	}
+
+	return query
+}
+
+// DownloadStatement streams the statement of the account in the currency, from from included to to excluded,
+// in the format, to w as it's received, so the statements of any size aren't held in memory.
+// The statement may be partially written when the download fails midway.
+func (c *AccountReaderClient) DownloadStatement(ctx context.Context, w io.Writer, currency, accountID string, from, to time.Time, format api.StatementFormat) error {
+	query := url.Values{}
+	query.Set("from", from.UTC().Format(time.RFC3339))
+	query.Set("to", to.UTC().Format(time.RFC3339))
+	query.Set("format", string(format))
+
+	endpoint := fmt.Sprintf("%s/statements/%s/%s?%s", c.baseURL, url.PathEscape(accountID), url.PathEscape(currency), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Client-Name", c.clientName)
+	req.Header.Set("User-Agent", c.clientName)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download statement: %w", err)
+	}
+
+	return nil
+}
+
 // getAndDecode performs a GET request and decodes the response into the provided interface.
 func (c *AccountReaderClient) getAndDecode(ctx context.Context, url string, v interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -63,6 +253,7 @@ func (c *AccountReaderClient) getAndDecode(ctx context.Context, url string, v in
 
 	req.Header.Set("Client-Name", c.clientName)
 	req.Header.Set("User-Agent", c.clientName)
+	req.Header.Set("Accept", acceptHeader(c.codec))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -75,7 +266,7 @@ func (c *AccountReaderClient) getAndDecode(ctx context.Context, url string, v in
 		return fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+	if err := decodeResponse(c.codec, resp, v); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -91,6 +282,7 @@ func (c *AccountReaderClient) getAndDecodeSlice(ctx context.Context, url string,
 
 	req.Header.Set("Client-Name", c.clientName)
 	req.Header.Set("User-Agent", c.clientName)
+	req.Header.Set("Accept", acceptHeader(c.codec))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -103,7 +295,7 @@ func (c *AccountReaderClient) getAndDecodeSlice(ctx context.Context, url string,
 		return fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+	if err := decodeResponse(c.codec, resp, v); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -6,10 +6,15 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/devshark/wallet/pkg/retry"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 )
@@ -90,6 +95,46 @@ func TestAccountReaderClient_GetAccountBalance(t *testing.T) {
 	})
 }
 
+func TestAccountReaderClient_GetAccountSummary(t *testing.T) {
+	t.Run("Successful request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/account/merchant%2F1", r.URL.EscapedPath())
+
+			err := json.NewEncoder(w).Encode(&api.AccountSummary{
+				AccountID: "merchant/1",
+				Balances: []*api.Account{
+					{AccountID: "merchant/1", Currency: "EUR", Balance: decimal.NewFromInt(20)},
+					{AccountID: "merchant/1", Currency: "USD", Balance: decimal.NewFromInt(100)},
+				},
+			})
+
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		summary, err := client.GetAccountSummary(context.Background(), "merchant/1")
+
+		require.NoError(t, err)
+		require.Equal(t, "merchant/1", summary.AccountID)
+		require.Len(t, summary.Balances, 2)
+		require.Equal(t, "USD", summary.Balances[1].Currency)
+		require.True(t, summary.Balances[1].Balance.Equal(decimal.NewFromInt(100)))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		_, err := client.GetAccountSummary(context.Background(), "unknown")
+
+		require.Error(t, err)
+	})
+}
+
 func TestAccountReaderClient_GetTransaction(t *testing.T) {
 	t.Run("Successful request", func(t *testing.T) {
 		mockTransaction := &api.Transaction{
@@ -132,6 +177,341 @@ func TestAccountReaderClient_GetTransaction(t *testing.T) {
 	})
 }
 
+func TestAccountReaderClient_TransactionCache(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.URL.Path == "/transactions/missing" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		err := json.NewEncoder(w).Encode(&api.Transaction{
+			TxID:   strings.TrimPrefix(r.URL.Path, "/transactions/"),
+			Amount: decimal.NewFromInt(10),
+			Tags:   []string{"payout"},
+			Fee:    &api.Fee{Currency: "USD", Amount: decimal.NewFromInt(1)},
+		})
+
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	clk := wallettesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	client := NewAccountReaderClient(server.URL).WithTransactionCache(2, time.Minute)
+	client.cache.clock = clk
+
+	t.Run("Hit", func(t *testing.T) {
+		requests.Store(0)
+
+		first, err := client.GetTransaction(ctx, "tx1")
+		require.NoError(t, err)
+
+		first.Tags[0] = "altered"
+
+		second, err := client.GetTransaction(ctx, "tx1")
+		require.NoError(t, err)
+		require.Equal(t, []string{"payout"}, second.Tags, "the cached transaction can't be altered")
+		require.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("Hit with its fee", func(t *testing.T) {
+		first, err := client.GetTransaction(ctx, "tx1")
+		require.NoError(t, err)
+
+		first.Fee.Amount = decimal.NewFromInt(100)
+
+		second, err := client.GetTransaction(ctx, "tx1")
+		require.NoError(t, err)
+		require.True(t, decimal.NewFromInt(1).Equal(second.Fee.Amount), "the cached fee can't be altered")
+	})
+
+	t.Run("Least recently read evicted", func(t *testing.T) {
+		requests.Store(0)
+
+		// tx3 evicts tx2, as tx1 was read again after it
+		for _, txID := range []string{"tx2", "tx1", "tx3", "tx1"} {
+			_, err := client.GetTransaction(ctx, txID)
+			require.NoError(t, err)
+		}
+
+		require.EqualValues(t, 2, requests.Load())
+
+		_, err := client.GetTransaction(ctx, "tx2")
+		require.NoError(t, err)
+		require.EqualValues(t, 3, requests.Load())
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		requests.Store(0)
+
+		clk.Advance(time.Minute)
+
+		_, err := client.GetTransaction(ctx, "tx1")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, requests.Load())
+	})
+
+	t.Run("Errors aren't cached", func(t *testing.T) {
+		requests.Store(0)
+
+		for range 2 {
+			_, err := client.GetTransaction(ctx, "missing")
+			require.Error(t, err)
+		}
+
+		require.EqualValues(t, 2, requests.Load())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		requests.Store(0)
+
+		client := NewAccountReaderClient(server.URL).WithTransactionCache(0, time.Minute)
+
+		for range 2 {
+			_, err := client.GetTransaction(ctx, "tx1")
+			require.NoError(t, err)
+		}
+
+		require.EqualValues(t, 2, requests.Load())
+	})
+}
+
+func TestAccountReaderClient_Codec(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/msgpack, application/json;q=0.5", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&api.Transaction{TxID: "tx123", Amount: decimal.RequireFromString("10.25"), Tags: []string{"food"}})
+	}
+
+	servers := map[string]http.HandlerFunc{
+		"MessagePack server": middlewares.NewMessagePack()(handler),
+		"JSON server":        handler,
+	}
+
+	for name, serve := range servers {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(serve)
+			defer server.Close()
+
+			client := NewAccountReaderClient(server.URL).WithCodec(MessagePackCodec)
+			transaction, err := client.GetTransaction(context.Background(), "tx123")
+
+			require.NoError(t, err)
+			require.Equal(t, "tx123", transaction.TxID)
+			require.True(t, decimal.RequireFromString("10.25").Equal(transaction.Amount))
+			require.Equal(t, []string{"food"}, transaction.Tags)
+		})
+	}
+}
+
+func TestAccountReaderClient_DownloadStatement(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	t.Run("Streamed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/statements/user 1/USD", r.URL.Path)
+			require.Equal(t, "2024-01-01T00:00:00Z", r.URL.Query().Get("from"))
+			require.Equal(t, "2024-02-01T00:00:00Z", r.URL.Query().Get("to"))
+			require.Equal(t, "csv", r.URL.Query().Get("format"))
+
+			w.Header().Set("Content-Type", "text/csv")
+
+			_, err := w.Write([]byte("time,tx_id\n2024-01-02T00:00:00Z,tx1\n"))
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		var out strings.Builder
+
+		err := NewAccountReaderClient(server.URL).DownloadStatement(context.Background(), &out, "USD", "user 1", from, to, api.StatementCSV)
+		require.NoError(t, err)
+		require.Equal(t, "time,tx_id\n2024-01-02T00:00:00Z,tx1\n", out.String())
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		var out strings.Builder
+
+		err := NewAccountReaderClient(server.URL).DownloadStatement(context.Background(), &out, "USD", "user1", to, from, api.StatementPDF)
+		require.ErrorIs(t, err, api.ErrUnexpected)
+		require.Empty(t, out.String())
+	})
+}
+
+func TestAccountReaderClient_SubscribeTransactions(t *testing.T) {
+	t.Run("Reconnects and resumes", func(t *testing.T) {
+		var connections atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/transactions/test123/USD/events", r.URL.Path)
+
+			w.Header().Set("Content-Type", "text/event-stream")
+
+			// the first connection drops after one event, the second resumes after it
+			switch connections.Add(1) {
+			case 1:
+				require.Empty(t, r.Header.Get(api.LastEventIDHeader))
+				_, _ = w.Write([]byte(": keep-alive\n\nid: c1\nevent: transaction\ndata: {\"tx_id\":\"tx1\"}\n\n"))
+			default:
+				require.Equal(t, "c1", r.Header.Get(api.LastEventIDHeader))
+				_, _ = w.Write([]byte("id: c2\nevent: transaction\ndata: {\"tx_id\":\"tx2\"}\n\n"))
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		client := NewAccountReaderClient(server.URL).WithSubscriptionReconnect(fastReconnect())
+		transactions, err := client.SubscribeTransactions(ctx, "USD", "test123")
+		require.NoError(t, err)
+
+		require.Equal(t, "tx1", (<-transactions).TxID)
+		require.Equal(t, "tx2", (<-transactions).TxID)
+
+		cancel()
+
+		_, open := <-transactions
+		require.False(t, open)
+	})
+
+	t.Run("Rejected subscription", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		_, err := client.SubscribeTransactions(context.Background(), "USD", "test123")
+
+		require.ErrorIs(t, err, api.ErrUnexpected)
+		require.Contains(t, err.Error(), "400")
+	})
+
+	t.Run("Closed once rejected on reconnect", func(t *testing.T) {
+		var connections atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if connections.Add(1) > 1 {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("id: c1\nevent: transaction\ndata: {\"tx_id\":\"tx1\"}\n\n"))
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL).WithSubscriptionReconnect(fastReconnect())
+		transactions, err := client.SubscribeTransactions(context.Background(), "USD", "test123")
+		require.NoError(t, err)
+
+		require.Equal(t, "tx1", (<-transactions).TxID)
+
+		_, open := <-transactions
+		require.False(t, open)
+		require.Equal(t, int32(2), connections.Load())
+	})
+}
+
+func fastReconnect() retry.Policy {
+	return retry.Policy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Multiplier: 1}
+}
+
+func TestAccountReaderClient_StreamTransactions(t *testing.T) {
+	postedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*api.Transaction{
+		{TxID: "tx1", Type: api.CREDIT, Time: postedAt},
+		{TxID: "tx2", Type: api.CREDIT, Time: postedAt.Add(time.Minute)},
+		{TxID: "tx3", Type: api.CREDIT, Time: postedAt.Add(time.Hour)},
+	}
+
+	// the first stream is cut short after tx2, the others resume after their cursor
+	cut := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/transactions/acc123/USD/stream", r.URL.Path)
+		require.Equal(t, "CREDIT", r.URL.Query().Get("type"))
+
+		w.Header().Set("Content-Type", api.TransactionStreamContentType)
+		w.Header().Set("Trailer", api.StreamStatusTrailer)
+
+		cursor := r.URL.Query().Get("cursor")
+		encoder := json.NewEncoder(w)
+
+		for _, entry := range entries {
+			if cursor != "" {
+				if api.TransactionCursor(entry) == cursor {
+					cursor = ""
+				}
+
+				continue
+			}
+
+			require.NoError(t, encoder.Encode(entry))
+
+			if cut && entry.TxID == "tx2" {
+				cut = false
+
+				return
+			}
+		}
+
+		w.Header().Set(api.StreamStatusTrailer, api.StreamComplete)
+	}))
+	defer server.Close()
+
+	client := NewAccountReaderClient(server.URL)
+
+	read := func(cursor string) ([]string, *TransactionIterator) {
+		it, err := client.StreamTransactions(context.Background(), "USD", "acc123", api.TransactionFilter{Type: api.CREDIT}, cursor)
+		require.NoError(t, err)
+
+		defer it.Close()
+
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Transaction().TxID)
+		}
+
+		return ids, it
+	}
+
+	ids, it := read("")
+	require.Equal(t, []string{"tx1", "tx2"}, ids)
+	require.ErrorIs(t, it.Err(), ErrStreamIncomplete, "without the trailer")
+	require.Equal(t, api.TransactionCursor(entries[1]), it.Cursor())
+
+	ids, it = read(it.Cursor())
+	require.Equal(t, []string{"tx3"}, ids)
+	require.NoError(t, it.Err())
+
+	t.Run("Rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := NewAccountReaderClient(server.URL).StreamTransactions(context.Background(), "USD", "acc123", api.TransactionFilter{}, "")
+		require.ErrorIs(t, err, api.ErrUnexpected)
+	})
+}
+
 func TestAccountReaderClient_GetTransactions(t *testing.T) {
 	t.Run("Successful request", func(t *testing.T) {
 		mockTransactions := []*api.Transaction{
@@ -185,6 +565,93 @@ func TestAccountReaderClient_GetTransactions(t *testing.T) {
 	})
 }
 
+func TestAccountReaderClient_FilterTransactions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/transactions/acc123/USD", r.URL.Path)
+		require.Equal(t, []string{"food", "travel"}, r.URL.Query()["tag"])
+		require.Equal(t, "2024-07-01T00:00:00Z", r.URL.Query().Get("from"))
+		require.Equal(t, "2024-07-01T16:00:00Z", r.URL.Query().Get("to"), "in UTC")
+		require.Equal(t, "DEBIT", r.URL.Query().Get("type"))
+
+		err := json.NewEncoder(w).Encode([]*api.Transaction{{TxID: "tx1", Type: api.DEBIT}})
+
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	manila := time.FixedZone("Asia/Manila", 8*60*60)
+
+	client := NewAccountReaderClient(server.URL)
+	transactions, err := client.FilterTransactions(context.Background(), "USD", "acc123", api.TransactionFilter{
+		Tags: []string{"food", "travel"},
+		From: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 7, 2, 0, 0, 0, 0, manila),
+		Type: api.DEBIT,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	require.Equal(t, "tx1", transactions[0].TxID)
+}
+
+func TestAccountReaderClient_GetTransactionsPage(t *testing.T) {
+	t.Run("Successful request", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/transactions/acc123/USD", r.URL.Path)
+			require.Equal(t, "2", r.URL.Query().Get("limit"))
+			require.Equal(t, "previous", r.URL.Query().Get("cursor"))
+			require.Equal(t, "CREDIT", r.URL.Query().Get("type"))
+
+			w.Header().Set(api.TotalCountHeader, "5")
+			w.Header().Set(api.NextCursorHeader, "next")
+
+			err := json.NewEncoder(w).Encode([]*api.Transaction{{TxID: "tx3"}, {TxID: "tx4"}})
+
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		page, err := client.GetTransactionsPage(context.Background(), "USD", "acc123", api.TransactionFilter{Type: api.CREDIT}, "previous", 2)
+
+		require.NoError(t, err)
+		require.Len(t, page.Transactions, 2)
+		require.Equal(t, "tx3", page.Transactions[0].TxID)
+		require.Equal(t, 5, page.TotalCount)
+		require.Equal(t, "next", page.NextCursor)
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.False(t, r.URL.Query().Has("cursor"))
+
+			w.Header().Set(api.TotalCountHeader, "0")
+			_, _ = w.Write([]byte("[]"))
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		page, err := client.GetTransactionsPage(context.Background(), "USD", "acc123", api.TransactionFilter{}, "", 10)
+
+		require.NoError(t, err)
+		require.Empty(t, page.Transactions)
+		require.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		client := NewAccountReaderClient(server.URL)
+		_, err := client.GetTransactionsPage(context.Background(), "USD", "acc123", api.TransactionFilter{}, "garbage", 10)
+
+		require.ErrorIs(t, err, api.ErrUnexpected)
+		require.Contains(t, err.Error(), "400")
+	})
+}
+
 func TestAccountReaderClient_ErrorHandling(t *testing.T) {
 	t.Run("Network error", func(t *testing.T) {
 		client := NewAccountReaderClient("http://nonexistent.example.com")
@@ -314,3 +781,32 @@ func TestOppositeType(t *testing.T) {
 	require.Equal(t, api.DEBIT, api.OppositeType(api.CREDIT))
 	require.Equal(t, api.CREDIT, api.OppositeType(api.DEBIT))
 }
+
+func TestAccountReaderClient_GetQueuedTransfer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transfers/queued-1/status" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		err := json.NewEncoder(w).Encode(&api.QueuedTransfer{
+			ID:     "queued-1",
+			Status: api.QueuedTransferFailed,
+			Code:   api.CodeInsufficientBalance,
+			Error:  "insufficient balance",
+		})
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := NewAccountReaderClient(server.URL)
+
+	queued, err := client.GetQueuedTransfer(context.Background(), "queued-1")
+	require.NoError(t, err)
+	require.Equal(t, api.QueuedTransferFailed, queued.Status)
+	require.Equal(t, api.CodeInsufficientBalance, queued.Code)
+
+	_, err = client.GetQueuedTransfer(context.Background(), "unknown")
+	require.ErrorIs(t, err, api.ErrUnexpected)
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/devshark/wallet/api"
+)
+
+// ErrStreamIncomplete is a stream of transactions cut short, resumed from TransactionIterator.Cursor.
+var ErrStreamIncomplete = errors.New("transactions stream ended before its last entry")
+
+// the longest line of a stream, a transaction with its remarks and tags
+const maxStreamLine = 1 << 20
+
+// TransactionIterator reads the transactions of a stream one at a time, like a sql.Rows:
+//
+//	for it.Next() {
+//		transaction := it.Transaction()
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		// resume from it.Cursor()
+//	}
+type TransactionIterator struct {
+	resp        *http.Response
+	scanner     *bufio.Scanner
+	transaction *api.Transaction
+	cursor      string
+	err         error
+}
+
+// StreamTransactions reads the entries of the account in the currency matching the filter, the oldest first,
+// from /transactions/{accountId}/{currency}/stream, after the cursor or from the first one without a cursor.
+// The entries are decoded as they're read, so the ledgers of any size aren't held in memory.
+// The iterator must be closed, and its error checked once Next returns false.
+func (c *AccountReaderClient) StreamTransactions(ctx context.Context, currency, accountID string, filter api.TransactionFilter, cursor string) (*TransactionIterator, error) {
+	query := filterQuery(filter)
+
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	endpoint := fmt.Sprintf("%s/transactions/%s/%s/stream?%s", c.baseURL, url.PathEscape(accountID), url.PathEscape(currency), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", api.TransactionStreamContentType)
+	req.Header.Set("Client-Name", c.clientName)
+	req.Header.Set("User-Agent", c.clientName)
+
+	// the streams of the large ledgers last, the timeout of the client would cut them
+	resp, err := c.streamClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxStreamLine)
+
+	return &TransactionIterator{resp: resp, scanner: scanner, cursor: cursor}, nil
+}
+
+// Next reads the next transaction, it returns false at the end of the stream or on an error.
+func (it *TransactionIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for it.scanner.Scan() {
+		line := it.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		transaction := &api.Transaction{}
+		if err := json.Unmarshal(line, transaction); err != nil {
+			it.err = fmt.Errorf("failed to decode transaction: %w", err)
+
+			return false
+		}
+
+		it.transaction = transaction
+		it.cursor = api.TransactionCursor(transaction)
+
+		return true
+	}
+
+	it.transaction = nil
+
+	if err := it.scanner.Err(); err != nil {
+		it.err = fmt.Errorf("%w: %w", ErrStreamIncomplete, err)
+
+		return false
+	}
+
+	// the trailer is only read once the body is
+	if it.resp.Trailer.Get(api.StreamStatusTrailer) != api.StreamComplete {
+		it.err = ErrStreamIncomplete
+	}
+
+	return false
+}
+
+// Transaction is the transaction read by the last call to Next.
+func (it *TransactionIterator) Transaction() *api.Transaction {
+	return it.transaction
+}
+
+// Cursor is the position after the last transaction read, to resume the stream from after an error.
+func (it *TransactionIterator) Cursor() string {
+	return it.cursor
+}
+
+// Err is the error that ended the iteration, nil once the stream is read whole.
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
+
+// Close releases the connection, the iteration may stop before the end of the stream.
+func (it *TransactionIterator) Close() error {
+	return it.resp.Body.Close() //nolint:wrapcheck // the close errors are returned as is
+}
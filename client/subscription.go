@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/retry"
+)
+
+// WithSubscriptionReconnect sets the backoff of the subscriptions reconnecting after their connection drops.
+// By default they reconnect until their context is done.
+func (c *AccountReaderClient) WithSubscriptionReconnect(policy retry.Policy) *AccountReaderClient {
+	c.reconnect = policy
+
+	return c
+}
+
+// SubscribeTransactions streams the entries of the account in the currency posted from now on, from the server-sent events
+// of /transactions/{accountId}/{currency}/events. When the connection drops, it reconnects with a backoff and resumes
+// after the last entry received, so none is missed nor received twice.
+// The channel is closed once the context is done, or when the server rejects the subscription.
+// Only the first connection's error is returned, i.e. an invalid account id.
+func (c *AccountReaderClient) SubscribeTransactions(ctx context.Context, currency, accountID string) (<-chan *api.Transaction, error) {
+	endpoint := fmt.Sprintf("%s/transactions/%s/%s/events", c.baseURL, url.PathEscape(accountID), url.PathEscape(currency))
+
+	resp, err := c.connect(ctx, endpoint, "")
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make(chan *api.Transaction)
+
+	go func() {
+		defer close(transactions)
+
+		cursor := ""
+
+		// the first connection is open, the retries reconnect
+		_ = retry.Do(ctx, c.reconnect, func(ctx context.Context) error {
+			if resp == nil {
+				if resp, err = c.connect(ctx, endpoint, cursor); err != nil {
+					return err
+				}
+			}
+
+			defer func() {
+				resp.Body.Close()
+				resp = nil
+			}()
+
+			return readEvents(ctx, resp, transactions, &cursor)
+		})
+	}()
+
+	return transactions, nil
+}
+
+// connect opens the event stream, resuming after the cursor. The rejected subscriptions aren't retried.
+func (c *AccountReaderClient) connect(ctx context.Context, endpoint, cursor string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, retry.Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Client-Name", c.clientName)
+	req.Header.Set("User-Agent", c.clientName)
+
+	if cursor != "" {
+		req.Header.Set(api.LastEventIDHeader, cursor)
+	}
+
+	// the subscriptions last, the timeout of the client would cut them
+	resp, err := c.streamClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		err = fmt.Errorf("%w: %d", api.ErrUnexpected, resp.StatusCode)
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, retry.Permanent(err)
+		}
+
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// streamClient is the HTTP client without its timeout.
+func (c *AccountReaderClient) streamClient() *http.Client {
+	if c.httpClient.Timeout == 0 {
+		return c.httpClient
+	}
+
+	client := *c.httpClient
+	client.Timeout = 0
+
+	return &client
+}
+
+// readEvents sends the transactions of the events on the channel until the stream ends,
+// keeping the cursor of the last one sent. A stream only ends on an error, which is retried.
+func readEvents(ctx context.Context, resp *http.Response, transactions chan<- *api.Transaction, cursor *string) error {
+	scanner := bufio.NewScanner(resp.Body)
+
+	var id, event string
+
+	data := strings.Builder{}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// a blank line dispatches the event, the lines starting with a colon are comments, i.e. the keep-alives
+		switch field, value, _ := strings.Cut(line, ":"); {
+		case line == "":
+			if event == api.TransactionEventType && data.Len() > 0 {
+				transaction := &api.Transaction{}
+				if err := json.Unmarshal([]byte(data.String()), transaction); err != nil {
+					return retry.Permanent(fmt.Errorf("failed to decode event: %w", err))
+				}
+
+				select {
+				case transactions <- transaction:
+				case <-ctx.Done():
+					return retry.Permanent(ctx.Err())
+				}
+
+				if id != "" {
+					*cursor = id
+				}
+			}
+
+			id, event = "", ""
+			data.Reset()
+		case field == "id":
+			id = strings.TrimPrefix(value, " ")
+		case field == "event":
+			event = strings.TrimPrefix(value, " ")
+		case field == "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+
+	return errSubscriptionEnded
+}
+
+// errSubscriptionEnded is the stream closed by the server, i.e. on shutdown, which is reconnected like the errors.
+var errSubscriptionEnded = errors.New("subscription ended by the server")
@@ -0,0 +1,2 @@
+-- outbox_events
+DROP TABLE IF EXISTS public."outbox_events";
@@ -0,0 +1,15 @@
+-- outbox_events are the ledger events written in the same transaction as the ledger entries,
+-- then relayed to the event broker by the worker, so an event is never lost nor emitted for a rolled back transfer.
+CREATE TABLE IF NOT EXISTS public."outbox_events" (
+    "seq" BIGSERIAL PRIMARY KEY, -- the order of the events
+    "id" UUID NOT NULL UNIQUE,
+    "type" VARCHAR(100) NOT NULL,
+    "version" INT NOT NULL,
+    "key" VARCHAR(255) NOT NULL, -- the partition key, i.e. the account id
+    "payload" JSONB NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "published_at" TIMESTAMP(3)
+);
+
+-- the relay only scans the events not yet published
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON public."outbox_events" (seq) WHERE published_at IS NULL;
@@ -0,0 +1,3 @@
+-- reconciliations
+DROP TABLE IF EXISTS public."reconciliation_items";
+DROP TABLE IF EXISTS public."reconciliations";
@@ -0,0 +1,32 @@
+-- reconciliations are the reports of the external statements matched against the company account ledger
+CREATE TABLE IF NOT EXISTS public."reconciliations" (
+    "id" UUID PRIMARY KEY,
+    "format" VARCHAR(20) NOT NULL,
+    "file_name" VARCHAR(255),
+    "currency" VARCHAR(10) NOT NULL,
+    "period_start" TIMESTAMP(3) NOT NULL,
+    "period_end" TIMESTAMP(3) NOT NULL,
+    "matched" INT NOT NULL,
+    "unmatched_statement" INT NOT NULL,
+    "unmatched_ledger" INT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS reconciliations_created_at_idx ON public."reconciliations" (created_at);
+
+-- the entries of a reconciliation, either side missing when unmatched
+CREATE TABLE IF NOT EXISTS public."reconciliation_items" (
+    "id" BIGSERIAL PRIMARY KEY, -- the order of the items
+    "reconciliation_id" UUID NOT NULL,
+    "status" VARCHAR(30) NOT NULL,
+    "reference" VARCHAR(255),
+    "debit_credit" "DebitCredit" NOT NULL, -- from the statement's point of view
+    "amount" NUMERIC NOT NULL,
+    "booked_at" TIMESTAMP(3) NOT NULL,
+    "transaction_id" UUID, -- not a foreign key, the report outlives the ledger entries
+    "remarks" VARCHAR(255),
+
+    CONSTRAINT reconciliation_fk FOREIGN KEY (reconciliation_id) REFERENCES reconciliations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_items_reconciliation_id_idx ON public."reconciliation_items" (reconciliation_id);
@@ -0,0 +1,2 @@
+-- account_parents
+DROP TABLE IF EXISTS public."account_parents";
@@ -0,0 +1,12 @@
+-- account_parents declares the sub-accounts, i.e. the merchant sub-balances of a marketplace.
+-- The hierarchy is by account id, so it applies to every currency of the account.
+CREATE TABLE IF NOT EXISTS public."account_parents" (
+    "account_id" VARCHAR(255) PRIMARY KEY, -- an account has at most one parent
+    "parent_id" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT not_own_parent CHECK (account_id <> parent_id)
+);
+
+-- the roll-up balances look up the children of an account
+CREATE INDEX IF NOT EXISTS account_parents_parent_id_idx ON public."account_parents" (parent_id);
@@ -0,0 +1,2 @@
+-- transfer_reviews
+DROP TABLE IF EXISTS public."transfer_reviews";
@@ -0,0 +1,16 @@
+-- transfer_reviews holds the transfers flagged by the screening, until they're approved and posted, or rejected
+CREATE TABLE IF NOT EXISTS public."transfer_reviews" (
+    "idempotency_key" VARCHAR(255) PRIMARY KEY, -- the group_id of the ledger entries once approved
+    "from_account_id" VARCHAR(255) NOT NULL,
+    "to_account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "remarks" VARCHAR(255),
+    "reason" TEXT NOT NULL, -- from the screening provider, never returned to the client
+    "status" VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "decided_at" TIMESTAMP(3)
+);
+
+-- the reviewers list the pending transfers, the oldest first
+CREATE INDEX IF NOT EXISTS transfer_reviews_status_created_at_idx ON public."transfer_reviews" (status, created_at);
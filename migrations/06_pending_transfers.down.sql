@@ -0,0 +1,2 @@
+-- pending_transfers
+DROP TABLE IF EXISTS public."pending_transfers";
@@ -0,0 +1,17 @@
+-- pending_transfers holds the transfers above the approval threshold, until an operator approves and posts them, or rejects them
+CREATE TABLE IF NOT EXISTS public."pending_transfers" (
+    "id" UUID PRIMARY KEY,
+    "idempotency_key" VARCHAR(255) NOT NULL UNIQUE, -- the group_id of the ledger entries once approved
+    "from_account_id" VARCHAR(255) NOT NULL,
+    "to_account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "remarks" VARCHAR(255),
+    "status" VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "decided_at" TIMESTAMP(3),
+    "decided_by" VARCHAR(255) -- the operator, identified by its admin key
+);
+
+-- the operators list the pending transfers, the oldest first
+CREATE INDEX IF NOT EXISTS pending_transfers_status_created_at_idx ON public."pending_transfers" (status, created_at);
@@ -0,0 +1,3 @@
+-- disputes
+DROP TABLE IF EXISTS public."dispute_events";
+DROP TABLE IF EXISTS public."disputes";
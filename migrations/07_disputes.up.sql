@@ -0,0 +1,35 @@
+-- disputes are the chargebacks opened against a transfer, holding the disputed amount on the disputes account
+-- until it's either reversed to the sender or released back to the recipient
+CREATE TABLE IF NOT EXISTS public."disputes" (
+    "id" UUID PRIMARY KEY,
+    "group_id" VARCHAR(50) NOT NULL, -- the disputed transfer
+    "tx_id" UUID NOT NULL, -- the disputed ledger entry, not a foreign key like the reconciliation items
+    "from_account_id" VARCHAR(255) NOT NULL, -- the sender, credited by a reversal
+    "to_account_id" VARCHAR(255) NOT NULL, -- the recipient, debited by the hold
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "reason" VARCHAR(255),
+    "status" VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "resolved_at" TIMESTAMP(3)
+);
+
+-- a transfer has at most one open dispute at a time
+CREATE UNIQUE INDEX IF NOT EXISTS disputes_open_group_id_idx ON public."disputes" (group_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS disputes_group_id_idx ON public."disputes" (group_id);
+-- the operators list the open disputes, the oldest first
+CREATE INDEX IF NOT EXISTS disputes_status_created_at_idx ON public."disputes" (status, created_at);
+
+-- the status history of the disputes
+CREATE TABLE IF NOT EXISTS public."dispute_events" (
+    "id" BIGSERIAL PRIMARY KEY, -- the order of the events
+    "dispute_id" UUID NOT NULL,
+    "status" VARCHAR(20) NOT NULL,
+    "group_id" VARCHAR(50) NOT NULL, -- the ledger entries posted by the change
+    "operator" VARCHAR(255), -- identified by its admin key
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT dispute_fk FOREIGN KEY (dispute_id) REFERENCES disputes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS dispute_events_dispute_id_idx ON public."dispute_events" (dispute_id);
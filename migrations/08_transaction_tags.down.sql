@@ -0,0 +1,5 @@
+-- transaction tags
+ALTER TABLE public."pending_transfers" DROP COLUMN IF EXISTS "tags";
+ALTER TABLE public."transfer_reviews" DROP COLUMN IF EXISTS "tags";
+DROP INDEX IF EXISTS transactions_tags_idx;
+ALTER TABLE public."transactions" DROP COLUMN IF EXISTS "tags";
@@ -0,0 +1,9 @@
+-- the tags of the transfers, i.e. their spend categories, stored on both ledger entries
+ALTER TABLE public."transactions" ADD COLUMN IF NOT EXISTS "tags" TEXT[] NOT NULL DEFAULT '{}';
+
+-- the transactions listing filters by the tags
+CREATE INDEX IF NOT EXISTS transactions_tags_idx ON public."transactions" USING GIN (tags);
+
+-- the held transfers keep their tags until they're posted
+ALTER TABLE public."transfer_reviews" ADD COLUMN IF NOT EXISTS "tags" TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE public."pending_transfers" ADD COLUMN IF NOT EXISTS "tags" TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- account_aliases
+DROP TABLE IF EXISTS public."account_aliases";
@@ -0,0 +1,11 @@
+-- account_aliases maps the external identifiers of the partners, i.e. an email or a customer number, to the accounts.
+-- The aliases are by account id, so they apply to every currency of the account.
+CREATE TABLE IF NOT EXISTS public."account_aliases" (
+    "alias" VARCHAR(254) PRIMARY KEY, -- lowercased and without spaces, an alias resolves to a single account
+    "kind" VARCHAR(20) NOT NULL,
+    "account_id" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the aliases of an account are listed by account
+CREATE INDEX IF NOT EXISTS account_aliases_account_id_idx ON public."account_aliases" (account_id);
@@ -0,0 +1,4 @@
+-- statements
+DROP TABLE IF EXISTS public."statements";
+-- statement_subscriptions
+DROP TABLE IF EXISTS public."statement_subscriptions";
@@ -0,0 +1,39 @@
+-- statement_subscriptions are the accounts getting their monthly statements in a currency, and where they're delivered
+CREATE TABLE IF NOT EXISTS public."statement_subscriptions" (
+    "id" UUID PRIMARY KEY,
+    "account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "channel" VARCHAR(20) NOT NULL, -- WEBHOOK or EMAIL
+    "destination" VARCHAR(2048) NOT NULL, -- the webhook URL or the email address
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_statement_subscription UNIQUE (account_id, currency, channel, destination)
+);
+
+-- statements are generated once per subscription and period, then delivered with retries
+CREATE TABLE IF NOT EXISTS public."statements" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "subscription_id" UUID, -- unset once unsubscribed, the statement is kept but no longer delivered
+    "account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "channel" VARCHAR(20) NOT NULL,
+    "destination" VARCHAR(2048) NOT NULL,
+    "period_start" TIMESTAMP(3) NOT NULL,
+    "period_end" TIMESTAMP(3) NOT NULL,
+    "opening_balance" NUMERIC NOT NULL,
+    "closing_balance" NUMERIC NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_error" VARCHAR(1024),
+    "next_attempt_at" TIMESTAMP(3) NOT NULL,
+    "delivered_at" TIMESTAMP(3),
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- the scheduler runs repeatedly, a period is only generated once
+    CONSTRAINT unique_statement_period UNIQUE (subscription_id, period_start),
+    CONSTRAINT subscription_fk FOREIGN KEY (subscription_id) REFERENCES statement_subscriptions(id) ON DELETE SET NULL
+);
+
+-- the worker delivers the pending statements, the most overdue first
+CREATE INDEX IF NOT EXISTS statements_pending_idx ON public."statements" (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS statements_account_id_idx ON public."statements" (account_id, period_start);
@@ -0,0 +1,5 @@
+-- erasure_requests
+DROP TABLE IF EXISTS public."erasure_requests";
+-- account_keys, the remarks encrypted with them can't be read anymore
+DROP TABLE IF EXISTS public."account_keys";
+-- the description is left wider, as the remarks may not fit anymore
@@ -0,0 +1,26 @@
+-- account_keys are the data keys encrypting the remarks of the accounts, wrapped by the master keys of the configuration.
+-- Deleting the key of an account crypto-shreds its remarks, including in the backups and the replicas.
+CREATE TABLE IF NOT EXISTS public."account_keys" (
+    "key_id" UUID PRIMARY KEY, -- referenced by the encrypted remarks
+    "account_id" VARCHAR(255) NOT NULL UNIQUE, -- every currency of the account shares the key
+    "wrapped_key" TEXT NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- erasure_requests are the data subject requests, the data other than the remarks are removed by the worker.
+CREATE TABLE IF NOT EXISTS public."erasure_requests" (
+    "id" UUID PRIMARY KEY,
+    "account_id" VARCHAR(255) NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    "requested_by" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "completed_at" TIMESTAMP(3)
+);
+
+-- the worker picks the pending ones, the oldest first
+CREATE INDEX IF NOT EXISTS erasure_requests_pending_idx ON public."erasure_requests" (created_at) WHERE status = 'PENDING';
+-- the operators check the erasure of an account
+CREATE INDEX IF NOT EXISTS erasure_requests_account_id_idx ON public."erasure_requests" (account_id, created_at);
+
+-- the encrypted remarks are longer than the remarks
+ALTER TABLE public."transactions" ALTER COLUMN "description" TYPE VARCHAR(1024);
@@ -0,0 +1,5 @@
+-- outbox_events
+DROP INDEX IF EXISTS public."outbox_events_unprojected_idx";
+ALTER TABLE public."outbox_events" DROP COLUMN IF EXISTS "projected_at";
+-- account_activity
+DROP TABLE IF EXISTS public."account_activity";
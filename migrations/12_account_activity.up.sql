@@ -0,0 +1,23 @@
+-- account_activity is the read model of the activity feeds, projected by the worker from the outbox events,
+-- so the feed is read without joining the ledger entries and their accounts.
+CREATE TABLE IF NOT EXISTS public."account_activity" (
+    "tx_id" UUID PRIMARY KEY, -- the ledger entry, so an event projected twice is only recorded once
+    "seq" BIGINT NOT NULL, -- the order of the outbox events
+    "transfer_id" VARCHAR(50) NOT NULL,
+    "account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "direction" VARCHAR(3) NOT NULL, -- IN or OUT
+    "counterparty_id" VARCHAR(255) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "running_balance" NUMERIC NOT NULL,
+    "tags" TEXT[] NOT NULL DEFAULT '{}',
+    "created_at" TIMESTAMP(3) NOT NULL
+);
+
+-- the feed of an account, the latest first
+CREATE INDEX IF NOT EXISTS account_activity_account_idx ON public."account_activity" (account_id, currency, seq DESC);
+
+-- the events are projected once, independently of their publication
+ALTER TABLE public."outbox_events" ADD COLUMN IF NOT EXISTS "projected_at" TIMESTAMP(3);
+
+CREATE INDEX IF NOT EXISTS outbox_events_unprojected_idx ON public."outbox_events" (seq) WHERE projected_at IS NULL;
@@ -0,0 +1,5 @@
+-- transaction_metadata_edits
+DROP INDEX IF EXISTS public."transaction_metadata_edits_tx_id_idx";
+DROP TABLE IF EXISTS public."transaction_metadata_edits";
+-- transaction_metadata
+DROP TABLE IF EXISTS public."transaction_metadata";
@@ -0,0 +1,22 @@
+-- transaction_metadata are the amendments of the non-financial fields of the ledger entries, i.e. a remarks correction
+-- or a reference number. They're overlaid on the ledger entries when they're read, so the ledger is never updated.
+CREATE TABLE IF NOT EXISTS public."transaction_metadata" (
+    "tx_id" UUID PRIMARY KEY REFERENCES public."transactions" (id),
+    "remarks" VARCHAR(1024), -- the amended remarks, encrypted like the ledger entry, NULL keeps the ones of the entry
+    "reference" VARCHAR(255),
+    "updated_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- transaction_metadata_edits are the audit trail of the amendments, one row by amended field.
+CREATE TABLE IF NOT EXISTS public."transaction_metadata_edits" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "tx_id" UUID NOT NULL REFERENCES public."transactions" (id),
+    "field" VARCHAR(20) NOT NULL, -- remarks or reference
+    "previous" VARCHAR(1024) NOT NULL,
+    "value" VARCHAR(1024) NOT NULL,
+    "operator" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- the history of a ledger entry, the oldest first
+CREATE INDEX IF NOT EXISTS transaction_metadata_edits_tx_id_idx ON public."transaction_metadata_edits" (tx_id, id);
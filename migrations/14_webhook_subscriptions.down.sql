@@ -0,0 +1,3 @@
+-- webhook_subscriptions
+DROP INDEX IF EXISTS public."webhook_subscriptions_account_id_idx";
+DROP TABLE IF EXISTS public."webhook_subscriptions";
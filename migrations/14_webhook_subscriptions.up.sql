@@ -0,0 +1,14 @@
+-- webhook_subscriptions are the urls the ledger events are posted to, filtered at delivery time by their scope.
+-- Without an account, the subscription is global and gets the events of every account.
+CREATE TABLE IF NOT EXISTS public."webhook_subscriptions" (
+    "id" UUID PRIMARY KEY,
+    "url" VARCHAR(2048) NOT NULL,
+    "account_id" VARCHAR(255) NOT NULL DEFAULT '', -- empty for a global subscription
+    "currency" VARCHAR(10) NOT NULL DEFAULT '', -- empty for every currency
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_webhook_subscription UNIQUE (url, account_id, currency)
+);
+
+-- the subscriptions of an account
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_account_id_idx ON public."webhook_subscriptions" (account_id);
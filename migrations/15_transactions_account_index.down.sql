@@ -0,0 +1,2 @@
+-- transactions_account_id_created_at_idx
+DROP INDEX IF EXISTS public."transactions_account_id_created_at_idx";
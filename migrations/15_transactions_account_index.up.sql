@@ -0,0 +1,2 @@
+-- the ledger entries of an account, counted and bounded by date for the activity summaries without reading them all
+CREATE INDEX IF NOT EXISTS transactions_account_id_created_at_idx ON public."transactions" (account_id, created_at);
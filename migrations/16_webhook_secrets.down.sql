@@ -0,0 +1,9 @@
+-- webhook_secret_rotations
+DROP INDEX IF EXISTS public."webhook_secret_rotations_subscription_id_idx";
+DROP TABLE IF EXISTS public."webhook_secret_rotations";
+
+-- the signing secrets of the webhook subscriptions
+ALTER TABLE public."webhook_subscriptions"
+    DROP COLUMN IF EXISTS "previous_secret_expires_at",
+    DROP COLUMN IF EXISTS "previous_secret",
+    DROP COLUMN IF EXISTS "secret";
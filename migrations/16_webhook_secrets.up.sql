@@ -0,0 +1,18 @@
+-- the signing secrets of the webhook subscriptions, the deliveries are signed with the secret of the server until
+-- the secret of the subscription is rotated. The previous secret keeps signing them until it expires.
+ALTER TABLE public."webhook_subscriptions"
+    ADD COLUMN IF NOT EXISTS "secret" VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS "previous_secret" VARCHAR(255), -- NULL for the secret of the server
+    ADD COLUMN IF NOT EXISTS "previous_secret_expires_at" TIMESTAMP(3);
+
+-- webhook_secret_rotations are the audit trail of the rotations, without the secrets.
+CREATE TABLE IF NOT EXISTS public."webhook_secret_rotations" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "subscription_id" UUID NOT NULL REFERENCES public."webhook_subscriptions" (id) ON DELETE CASCADE,
+    "operator" VARCHAR(255) NOT NULL,
+    "rotated_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "previous_expires_at" TIMESTAMP(3)
+);
+
+-- the rotations of a subscription, the oldest first
+CREATE INDEX IF NOT EXISTS webhook_secret_rotations_subscription_id_idx ON public."webhook_secret_rotations" (subscription_id, id);
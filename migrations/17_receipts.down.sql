@@ -0,0 +1,7 @@
+-- outbox_events
+DROP INDEX IF EXISTS public."outbox_events_unreceipted_idx";
+ALTER TABLE public."outbox_events" DROP COLUMN IF EXISTS "receipted_at";
+-- receipts
+DROP TABLE IF EXISTS public."receipts";
+-- receipt_subscriptions
+DROP TABLE IF EXISTS public."receipt_subscriptions";
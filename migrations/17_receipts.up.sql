@@ -0,0 +1,42 @@
+-- receipt_subscriptions are the accounts getting a receipt of their deposits, withdrawals and transfers, and where it's sent
+CREATE TABLE IF NOT EXISTS public."receipt_subscriptions" (
+    "id" UUID PRIMARY KEY,
+    "account_id" VARCHAR(255) NOT NULL,
+    "destination" VARCHAR(254) NOT NULL, -- the email address
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT unique_receipt_subscription UNIQUE (account_id, destination)
+);
+
+-- receipts are queued by the worker from the outbox events, once per ledger entry and subscription, then sent with retries
+CREATE TABLE IF NOT EXISTS public."receipts" (
+    "id" UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    "subscription_id" UUID, -- unset once unsubscribed, the receipt is kept but no longer sent
+    "tx_id" UUID NOT NULL,
+    "transfer_id" VARCHAR(50) NOT NULL,
+    "kind" VARCHAR(20) NOT NULL, -- DEPOSIT, WITHDRAWAL, TRANSFER_SENT or TRANSFER_RECEIVED
+    "account_id" VARCHAR(255) NOT NULL,
+    "destination" VARCHAR(254) NOT NULL,
+    "counterparty_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_error" VARCHAR(1024),
+    "next_attempt_at" TIMESTAMP(3) NOT NULL,
+    "sent_at" TIMESTAMP(3),
+    "created_at" TIMESTAMP(3) NOT NULL,
+
+    -- an event queued twice only sends its receipts once
+    CONSTRAINT unique_receipt UNIQUE (subscription_id, tx_id),
+    CONSTRAINT receipt_subscription_fk FOREIGN KEY (subscription_id) REFERENCES receipt_subscriptions(id) ON DELETE SET NULL
+);
+
+-- the worker sends the pending receipts, the most overdue first
+CREATE INDEX IF NOT EXISTS receipts_pending_idx ON public."receipts" (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS receipts_account_id_idx ON public."receipts" (account_id, created_at);
+
+-- the events are queued once, independently of their publication and projection
+ALTER TABLE public."outbox_events" ADD COLUMN IF NOT EXISTS "receipted_at" TIMESTAMP(3);
+
+CREATE INDEX IF NOT EXISTS outbox_events_unreceipted_idx ON public."outbox_events" (seq) WHERE receipted_at IS NULL;
@@ -0,0 +1,2 @@
+-- idempotency_conflicts
+DROP TABLE IF EXISTS public."idempotency_conflicts";
@@ -0,0 +1,15 @@
+-- idempotency_conflicts are the transfers rejected because their idempotency key was already used, for the partners to debug their retries
+CREATE TABLE IF NOT EXISTS public."idempotency_conflicts" (
+    "id" UUID PRIMARY KEY,
+    "idempotency_key" VARCHAR(255) NOT NULL,
+    "code" VARCHAR(50) NOT NULL, -- DUPLICATE_TRANSACTION or TRANSFER_IN_PROGRESS
+    "fingerprint" CHAR(64) NOT NULL, -- the SHA-256 of the conflicting request
+    "client_ip" VARCHAR(64) NOT NULL,
+    "api_key_id" VARCHAR(32) NOT NULL DEFAULT '',
+    "request_id" VARCHAR(128) NOT NULL DEFAULT '',
+    "original_created_at" TIMESTAMP(3), -- when the transfer of the key was posted, unset while it's in progress
+    "created_at" TIMESTAMP(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_conflicts_key_idx ON public."idempotency_conflicts" (idempotency_key, created_at);
+CREATE INDEX IF NOT EXISTS idempotency_conflicts_created_at_idx ON public."idempotency_conflicts" (created_at);
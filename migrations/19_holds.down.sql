@@ -0,0 +1,3 @@
+-- holds
+DROP TABLE IF EXISTS public."holds";
+ALTER TABLE public."accounts" DROP COLUMN IF EXISTS "held";
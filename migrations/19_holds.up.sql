@@ -0,0 +1,20 @@
+-- the amounts reserved on the accounts by their authorized holds, not available to their transfers
+ALTER TABLE public."accounts" ADD COLUMN IF NOT EXISTS "held" NUMERIC NOT NULL DEFAULT 0;
+
+-- holds are the authorized transfers, reserving their amount on the sender until they're captured, i.e. posted, or released
+CREATE TABLE IF NOT EXISTS public."holds" (
+    "id" UUID PRIMARY KEY,
+    "idempotency_key" VARCHAR(50) NOT NULL UNIQUE, -- the group_id of the ledger entries once captured
+    "from_account_id" VARCHAR(255) NOT NULL,
+    "to_account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "remarks" VARCHAR(255),
+    "tags" TEXT[] NOT NULL DEFAULT '{}',
+    "status" VARCHAR(20) NOT NULL DEFAULT 'AUTHORIZED',
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "closed_at" TIMESTAMP(3) -- when it was captured or released
+);
+
+-- the authorized holds of an account
+CREATE INDEX IF NOT EXISTS holds_authorized_idx ON public."holds" (from_account_id, currency) WHERE status = 'AUTHORIZED';
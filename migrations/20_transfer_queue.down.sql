@@ -0,0 +1,2 @@
+-- queued_transfers
+DROP TABLE IF EXISTS public."queued_transfers";
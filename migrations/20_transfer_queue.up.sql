@@ -0,0 +1,19 @@
+-- queued_transfers are the transfers posted asynchronously, in the order they were queued, by the workers
+CREATE TABLE IF NOT EXISTS public."queued_transfers" (
+    "id" UUID PRIMARY KEY,
+    "idempotency_key" VARCHAR(50) NOT NULL UNIQUE, -- the group_id of the ledger entries once posted
+    "from_account_id" VARCHAR(255) NOT NULL,
+    "to_account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "remarks" VARCHAR(255),
+    "tags" TEXT[] NOT NULL DEFAULT '{}',
+    "status" VARCHAR(20) NOT NULL DEFAULT 'QUEUED',
+    "error_code" VARCHAR(50), -- the code of the error failing or holding the transfer
+    "error_message" TEXT,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "processed_at" TIMESTAMP(3)
+);
+
+-- the workers dequeue the oldest transfers first
+CREATE INDEX IF NOT EXISTS queued_transfers_queued_idx ON public."queued_transfers" (created_at) WHERE status = 'QUEUED';
@@ -0,0 +1,3 @@
+-- reversals
+DROP INDEX IF EXISTS transactions_reversal_of_idx;
+ALTER TABLE public."transactions" DROP COLUMN IF EXISTS "reversal_of";
@@ -0,0 +1,5 @@
+-- the entries of a reversal reference the entries they compensate, the credit reversing the debit and the debit the credit
+ALTER TABLE public."transactions" ADD COLUMN IF NOT EXISTS "reversal_of" UUID REFERENCES public."transactions" (id);
+
+-- an entry is reversed at most once
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_reversal_of_idx ON public."transactions" (reversal_of) WHERE reversal_of IS NOT NULL;
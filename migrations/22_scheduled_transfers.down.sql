@@ -0,0 +1,2 @@
+-- scheduled transfers
+DROP TABLE IF EXISTS public."scheduled_transfers";
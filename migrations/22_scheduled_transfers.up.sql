@@ -0,0 +1,22 @@
+-- scheduled_transfers are the transfers posted by the workers once they're due
+CREATE TABLE IF NOT EXISTS public."scheduled_transfers" (
+    "id" UUID PRIMARY KEY,
+    "idempotency_key" VARCHAR(50) NOT NULL UNIQUE, -- the group_id of the ledger entries once executed
+    "from_account_id" VARCHAR(255) NOT NULL,
+    "to_account_id" VARCHAR(255) NOT NULL,
+    "currency" VARCHAR(10) NOT NULL,
+    "amount" NUMERIC NOT NULL,
+    "remarks" VARCHAR(255),
+    "tags" TEXT[] NOT NULL DEFAULT '{}',
+    "execute_at" TIMESTAMP(3) NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'SCHEDULED',
+    "error_code" VARCHAR(50), -- the code of the error failing or holding the transfer
+    "error_message" TEXT,
+    "created_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "closed_at" TIMESTAMP(3) -- when it was executed or cancelled
+);
+
+-- the workers execute the due transfers, the earliest first
+CREATE INDEX IF NOT EXISTS scheduled_transfers_due_idx ON public."scheduled_transfers" (execute_at) WHERE status = 'SCHEDULED';
+
+CREATE INDEX IF NOT EXISTS scheduled_transfers_from_idx ON public."scheduled_transfers" (from_account_id, execute_at);
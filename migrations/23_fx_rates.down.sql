@@ -0,0 +1,4 @@
+-- fx rates
+ALTER TABLE public."pending_transfers" DROP COLUMN IF EXISTS "to_currency";
+ALTER TABLE public."transfer_reviews" DROP COLUMN IF EXISTS "to_currency";
+ALTER TABLE public."transactions" DROP COLUMN IF EXISTS "fx_rate";
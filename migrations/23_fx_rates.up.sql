@@ -0,0 +1,6 @@
+-- both double entries of a cross-currency transfer record the rate it was converted at
+ALTER TABLE public."transactions" ADD COLUMN IF NOT EXISTS "fx_rate" NUMERIC;
+
+-- the held transfers are converted once they're approved
+ALTER TABLE public."transfer_reviews" ADD COLUMN IF NOT EXISTS "to_currency" VARCHAR(10);
+ALTER TABLE public."pending_transfers" ADD COLUMN IF NOT EXISTS "to_currency" VARCHAR(10);
@@ -0,0 +1,2 @@
+-- fee rules
+DROP TABLE IF EXISTS public."fee_rules";
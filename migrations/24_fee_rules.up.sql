@@ -0,0 +1,12 @@
+-- fee_rules are the fees charged to the senders of the transfers and withdrawals, by currency,
+-- a flat amount plus a percentage of the amount
+CREATE TABLE IF NOT EXISTS public."fee_rules" (
+    "operation" VARCHAR(20) NOT NULL, -- TRANSFER or WITHDRAWAL
+    "currency" VARCHAR(10) NOT NULL,
+    "flat" NUMERIC NOT NULL DEFAULT 0 CHECK ("flat" >= 0),
+    "percentage" NUMERIC NOT NULL DEFAULT 0 CHECK ("percentage" >= 0 AND "percentage" <= 100),
+    "updated_by" VARCHAR(255) NOT NULL, -- the operator who last set the rule
+    "updated_at" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY ("operation", "currency")
+);
@@ -0,0 +1,4 @@
+-- hold fees
+ALTER TABLE public."holds" DROP COLUMN IF EXISTS "fee_percentage";
+ALTER TABLE public."holds" DROP COLUMN IF EXISTS "fee_flat";
+ALTER TABLE public."holds" DROP COLUMN IF EXISTS "fee";
@@ -0,0 +1,5 @@
+-- the fee quoted when the hold was placed, reserved along with its amount and charged once it's captured,
+-- NULL when the transfer is free
+ALTER TABLE public."holds" ADD COLUMN IF NOT EXISTS "fee" NUMERIC;
+ALTER TABLE public."holds" ADD COLUMN IF NOT EXISTS "fee_flat" NUMERIC;
+ALTER TABLE public."holds" ADD COLUMN IF NOT EXISTS "fee_percentage" NUMERIC;
@@ -0,0 +1,2 @@
+-- hold remarks
+ALTER TABLE public."holds" ALTER COLUMN "remarks" TYPE VARCHAR(255);
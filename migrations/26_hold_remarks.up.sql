@@ -0,0 +1,2 @@
+-- the remarks of the holds are encrypted like the ones of the ledger entries, which are longer
+ALTER TABLE public."holds" ALTER COLUMN "remarks" TYPE VARCHAR(1024);
@@ -0,0 +1,2 @@
+-- queued transfer remarks
+ALTER TABLE public."queued_transfers" ALTER COLUMN "remarks" TYPE VARCHAR(255);
@@ -0,0 +1,2 @@
+-- the remarks of the queued transfers are encrypted like the ones of the ledger entries, which are longer
+ALTER TABLE public."queued_transfers" ALTER COLUMN "remarks" TYPE VARCHAR(1024);
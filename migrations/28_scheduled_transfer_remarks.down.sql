@@ -0,0 +1,2 @@
+-- scheduled transfer remarks
+ALTER TABLE public."scheduled_transfers" ALTER COLUMN "remarks" TYPE VARCHAR(255);
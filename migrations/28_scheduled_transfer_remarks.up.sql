@@ -0,0 +1,2 @@
+-- the remarks of the scheduled transfers are encrypted like the ones of the ledger entries, which are longer
+ALTER TABLE public."scheduled_transfers" ALTER COLUMN "remarks" TYPE VARCHAR(1024);
@@ -0,0 +1,77 @@
+package buildinfo
+
+import (
+	"expvar"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// set at build time, i.e.
+// go build -ldflags "-X github.com/devshark/wallet/pkg/buildinfo.version=v1.2.3 -X github.com/devshark/wallet/pkg/buildinfo.commit=abc123".
+//
+//nolint:gochecknoglobals // the linker can only set package variables
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+//nolint:gochecknoglobals // expvar panics when a name is published twice
+var publishOnce sync.Once
+
+// Info identifies the build serving the traffic.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info set with the ldflags.
+// Without them i.e. with go run or go install, the commit and date fall back to the VCS stamp of the go toolchain.
+func Get() Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, setting := range build.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && info.Commit == "":
+			info.Commit = setting.Value
+		case setting.Key == "vcs.time" && info.Date == "":
+			info.Date = setting.Value
+		}
+	}
+
+	return info
+}
+
+// Attr groups the build info under the build key, to be added to the startup logs.
+func (i Info) Attr() slog.Attr {
+	return slog.Group("build",
+		slog.String("version", i.Version),
+		slog.String("commit", i.Commit),
+		slog.String("date", i.Date),
+		slog.String("go_version", i.GoVersion),
+	)
+}
+
+// Publish exposes the build info as the build expvar variable, served under /debug/vars.
+// It's safe to call more than once.
+func Publish() {
+	publishOnce.Do(func() {
+		expvar.Publish("build", expvar.Func(func() any {
+			return Get()
+		}))
+	})
+}
@@ -0,0 +1,39 @@
+package buildinfo_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"runtime"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/buildinfo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	info := buildinfo.Get()
+
+	// not set with ldflags in the tests
+	require.Equal(t, "dev", info.Version)
+	require.Equal(t, runtime.Version(), info.GoVersion)
+}
+
+func TestAttr(t *testing.T) {
+	attr := buildinfo.Get().Attr()
+
+	require.Equal(t, "build", attr.Key)
+	require.Len(t, attr.Value.Group(), 4)
+}
+
+func TestPublish(t *testing.T) {
+	buildinfo.Publish()
+	buildinfo.Publish() // must not panic on the duplicate name
+
+	variable := expvar.Get("build")
+	require.NotNil(t, variable)
+
+	var info buildinfo.Info
+	err := json.Unmarshal([]byte(variable.String()), &info)
+	require.NoError(t, err)
+	require.Equal(t, buildinfo.Get(), info)
+}
@@ -0,0 +1,19 @@
+package clock
+
+import "time"
+
+// Clock abstracts the current time, so components that depend on it can be tested without time.Sleep.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real wall clock, always in UTC.
+type SystemClock struct{}
+
+func NewSystemClock() SystemClock {
+	return SystemClock{}
+}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
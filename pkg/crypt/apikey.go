@@ -0,0 +1,126 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrInvalidHash         = errors.New("invalid api key hash")
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+	ErrEmptyAPIKey         = errors.New("empty api key")
+)
+
+const (
+	// APIKeyPrefix makes the keys recognizable in logs and secret scanners.
+	APIKeyPrefix = "wk_"
+
+	apiKeyBytes = 32
+	saltBytes   = 16
+
+	// the recommended argon2id parameters from RFC 9106, second recommended option
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+
+	// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+	hashParts = 6
+
+	// argon2 requires 8 KiB of memory per thread, the hashes are verified on every request, so up to 4 GiB
+	// and 16 passes, well above the recommended ones
+	minMemoryPerThread = 8
+	maxMemory          = 1 << 22
+	maxTime            = 16
+)
+
+// GenerateAPIKey returns a new random, URL-safe API key.
+// The plaintext key must be shown once to the owner and only its hash should be persisted.
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, apiKeyBytes)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashAPIKey hashes the key with argon2id and a random salt.
+// The result is encoded in the PHC string format, so the parameters can be changed later
+// without invalidating the existing hashes.
+func HashAPIKey(key string) (string, error) {
+	if key == "" {
+		return "", ErrEmptyAPIKey
+	}
+
+	salt := make([]byte, saltBytes)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	hash := argon2.IDKey([]byte(key), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		argonMemory,
+		argonTime,
+		argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// VerifyAPIKey reports whether the key matches the encoded hash produced by HashAPIKey.
+// The comparison is done in constant time.
+func VerifyAPIKey(key, encodedHash string) (bool, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != hashParts || parts[1] != "argon2id" {
+		return false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	if version != argon2.Version {
+		return false, ErrIncompatibleVersion
+	}
+
+	var (
+		memory  uint32
+		time    uint32
+		threads uint8
+	)
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	// argon2 panics on these, and allocates the memory and iterates as is
+	if time < 1 || time > maxTime || threads < 1 || memory < minMemoryPerThread*uint32(threads) || memory > maxMemory {
+		return false, fmt.Errorf("%w: unsupported parameters %s", ErrInvalidHash, parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return false, fmt.Errorf("%w: invalid salt", ErrInvalidHash)
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false, fmt.Errorf("%w: invalid hash", ErrInvalidHash)
+	}
+
+	actual := argon2.IDKey([]byte(key), salt, time, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(expected, actual) == 1, nil
+}
@@ -0,0 +1,93 @@
+package crypt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key1, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	key2, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	require.True(t, strings.HasPrefix(key1, crypt.APIKeyPrefix))
+	require.NotEqual(t, key1, key2)
+	require.NotContains(t, key1, "+")
+	require.NotContains(t, key1, "/")
+	require.NotContains(t, key1, "=")
+}
+
+func TestHashAPIKey(t *testing.T) {
+	t.Run("Verify matching key", func(t *testing.T) {
+		key, err := crypt.GenerateAPIKey()
+		require.NoError(t, err)
+
+		hash, err := crypt.HashAPIKey(key)
+		require.NoError(t, err)
+		require.NotContains(t, hash, key)
+		require.True(t, strings.HasPrefix(hash, "$argon2id$"))
+
+		ok, err := crypt.VerifyAPIKey(key, hash)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("Reject wrong key", func(t *testing.T) {
+		hash, err := crypt.HashAPIKey("wk_correct")
+		require.NoError(t, err)
+
+		ok, err := crypt.VerifyAPIKey("wk_incorrect", hash)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("Same key produces different hashes", func(t *testing.T) {
+		hash1, err := crypt.HashAPIKey("wk_same")
+		require.NoError(t, err)
+
+		hash2, err := crypt.HashAPIKey("wk_same")
+		require.NoError(t, err)
+
+		require.NotEqual(t, hash1, hash2)
+	})
+
+	t.Run("Empty key", func(t *testing.T) {
+		_, err := crypt.HashAPIKey("")
+		require.ErrorIs(t, err, crypt.ErrEmptyAPIKey)
+	})
+
+	t.Run("Malformed hash", func(t *testing.T) {
+		_, err := crypt.VerifyAPIKey("wk_key", "not-a-hash")
+		require.ErrorIs(t, err, crypt.ErrInvalidHash)
+
+		_, err = crypt.VerifyAPIKey("wk_key", "$argon2id$v=1$m=65536,t=3,p=4$c2FsdA$aGFzaA")
+		require.ErrorIs(t, err, crypt.ErrIncompatibleVersion)
+
+		_, err = crypt.VerifyAPIKey("wk_key", "$argon2id$v=19$m=65536,t=3,p=4$!!!$aGFzaA")
+		require.ErrorIs(t, err, crypt.ErrInvalidHash)
+	})
+
+	t.Run("Unsupported parameters", func(t *testing.T) {
+		for name, hash := range map[string]string{
+			"no time":           "$argon2id$v=19$m=65536,t=0,p=4$c2FsdA$aGFzaA",
+			"no parallelism":    "$argon2id$v=19$m=65536,t=3,p=0$c2FsdA$aGFzaA",
+			"too little memory": "$argon2id$v=19$m=31,t=3,p=4$c2FsdA$aGFzaA",
+			"too much memory":   "$argon2id$v=19$m=4294967295,t=3,p=4$c2FsdA$aGFzaA",
+			"too much time":     "$argon2id$v=19$m=65536,t=4294967295,p=4$c2FsdA$aGFzaA",
+			"parallelism range": "$argon2id$v=19$m=65536,t=3,p=256$c2FsdA$aGFzaA",
+			"empty salt":        "$argon2id$v=19$m=65536,t=3,p=4$$aGFzaA",
+			"empty hash":        "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$",
+		} {
+			require.NotPanics(t, func() {
+				ok, err := crypt.VerifyAPIKey("wk_key", hash)
+				require.ErrorIs(t, err, crypt.ErrInvalidHash, name)
+				require.False(t, ok, name)
+			}, name)
+		}
+	})
+}
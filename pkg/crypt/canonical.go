@@ -0,0 +1,131 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrUnsupportedJSONValue = errors.New("unsupported json value")
+
+// HashStruct returns the hex encoded SHA256 digest of the canonical JSON form of v.
+// Two values that are semantically the same JSON document produce the same digest,
+// regardless of field ordering, whitespace or how the numbers were written i.e. 10.50 vs 10.5.
+func HashStruct(v any) (string, error) {
+	canonical, err := CanonicalJSON(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalJSON encodes v into JSON with sorted object keys, no insignificant whitespace,
+// and numbers normalized into their shortest decimal representation.
+func CanonicalJSON(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var generic any
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = writeCanonical(buf, generic); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if value {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		number, err := decimal.NewFromString(value.String())
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnsupportedJSONValue, err)
+		}
+
+		buf.WriteString(number.String())
+	case string:
+		return writeString(buf, value)
+	case []any:
+		buf.WriteByte('[')
+
+		for i, item := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeString(buf, key); err != nil {
+				return err
+			}
+
+			buf.WriteByte(':')
+
+			if err := writeCanonical(buf, value[key]); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedJSONValue, v)
+	}
+
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) error {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal string: %w", err)
+	}
+
+	buf.Write(encoded)
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package crypt_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	t.Run("Sorted keys and normalized numbers", func(t *testing.T) {
+		canonical, err := crypt.CanonicalJSON(json.RawMessage(`{ "b": 1.50, "a": [3, {"z": true, "y": null}], "c": "x" }`))
+		require.NoError(t, err)
+		require.Equal(t, `{"a":[3,{"y":null,"z":true}],"b":1.5,"c":"x"}`, string(canonical))
+	})
+
+	t.Run("Unsupported value", func(t *testing.T) {
+		_, err := crypt.CanonicalJSON(make(chan int))
+		require.Error(t, err)
+	})
+}
+
+func TestHashStruct(t *testing.T) {
+	t.Run("Stable across decimal representations", func(t *testing.T) {
+		request1 := &api.TransferRequest{
+			FromAccountID: "user1",
+			ToAccountID:   "user2",
+			Amount:        decimal.RequireFromString("10.50"),
+			Currency:      "USD",
+		}
+
+		request2 := &api.TransferRequest{}
+		err := json.Unmarshal([]byte(`{"currency":"USD","amount":10.5,"to_account_id":"user2","from_account_id":"user1"}`), request2)
+		require.NoError(t, err)
+
+		hash1, err := crypt.HashStruct(request1)
+		require.NoError(t, err)
+
+		hash2, err := crypt.HashStruct(request2)
+		require.NoError(t, err)
+
+		require.Equal(t, hash1, hash2)
+		require.Len(t, hash1, 64)
+	})
+
+	t.Run("Stable across field ordering", func(t *testing.T) {
+		hash1, err := crypt.HashStruct(json.RawMessage(`{"amount":"1.00","currency":"USD"}`))
+		require.NoError(t, err)
+
+		hash2, err := crypt.HashStruct(map[string]any{"currency": "USD", "amount": "1.00"})
+		require.NoError(t, err)
+
+		require.Equal(t, hash1, hash2)
+	})
+
+	t.Run("Different values", func(t *testing.T) {
+		hash1, err := crypt.HashStruct(&api.TransferRequest{Amount: decimal.NewFromInt(1)})
+		require.NoError(t, err)
+
+		hash2, err := crypt.HashStruct(&api.TransferRequest{Amount: decimal.NewFromInt(2)})
+		require.NoError(t, err)
+
+		require.NotEqual(t, hash1, hash2)
+	})
+}
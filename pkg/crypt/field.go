@@ -0,0 +1,209 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidKey       = errors.New("invalid encryption key")
+	ErrUnknownKeyID     = errors.New("unknown encryption key id")
+	ErrInvalidEnvelope  = errors.New("invalid ciphertext envelope")
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+const (
+	// EnvelopePrefix marks a value as encrypted by the Keyring, so plaintext and ciphertext can coexist in a column.
+	EnvelopePrefix = "enc:v1:"
+
+	// AES-256
+	fieldKeySize = 32
+
+	// enc:v1:<key id>:<base64 nonce+ciphertext>
+	envelopeParts = 2
+)
+
+// Keyring encrypts fields with the active key and decrypts with any known key,
+// which allows rotating keys without re-encrypting existing values at once.
+// The key id is embedded in the ciphertext envelope.
+type Keyring struct {
+	activeKeyID string
+	ciphers     map[string]cipher.AEAD
+}
+
+// NewKeyring creates a Keyring from AES-256 keys indexed by their key id.
+func NewKeyring(activeKeyID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, activeKeyID)
+	}
+
+	keyring := &Keyring{
+		activeKeyID: activeKeyID,
+		ciphers:     make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for keyID, key := range keys {
+		if keyID == "" || strings.Contains(keyID, ":") {
+			return nil, fmt.Errorf("%w: key id %q must be non-empty and must not contain ':'", ErrInvalidKey, keyID)
+		}
+
+		if len(key) != fieldKeySize {
+			return nil, fmt.Errorf("%w: key %s must be %d bytes", ErrInvalidKey, keyID, fieldKeySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+
+		keyring.ciphers[keyID] = gcm
+	}
+
+	return keyring, nil
+}
+
+// ParseKeyring creates a Keyring from "<key id>:<base64 key>" specs, usually read from the environment.
+func ParseKeyring(activeKeyID string, specs []string) (*Keyring, error) {
+	keys := make(map[string][]byte, len(specs))
+
+	for _, spec := range specs {
+		keyID, encoded, found := strings.Cut(strings.TrimSpace(spec), ":")
+		if !found {
+			return nil, fmt.Errorf("%w: expected <key id>:<base64 key>", ErrInvalidKey)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, keyID, err)
+		}
+
+		keys[keyID] = key
+	}
+
+	return NewKeyring(activeKeyID, keys)
+}
+
+// GenerateFieldKey returns a new random AES-256 key, base64 encoded for configuration.
+func GenerateFieldKey() (string, error) {
+	key := make([]byte, fieldKeySize)
+
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ActiveKeyID returns the key id used for new encryptions.
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeKeyID
+}
+
+// Encrypt seals the plaintext with the active key and returns the envelope.
+// Empty strings are kept as-is, as there is nothing to protect.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	gcm := k.ciphers[k.activeKeyID]
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// the key id is authenticated, so the envelope can't be moved to another key
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(k.activeKeyID))
+
+	return EnvelopePrefix + k.activeKeyID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens an envelope produced by Encrypt with the key referenced in it.
+// Values that are not envelopes are returned unchanged, to support columns written before encryption was enabled.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	keyID, sealed, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, ok := k.ciphers[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKeyID, keyID)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidEnvelope
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Reencrypt decrypts the value and encrypts it again with the active key,
+// unless it is already encrypted with the active key. Used by key rotation jobs.
+func (k *Keyring) Reencrypt(value string) (string, error) {
+	if IsEncrypted(value) {
+		keyID, _, err := parseEnvelope(value)
+		if err != nil {
+			return "", err
+		}
+
+		if keyID == k.activeKeyID {
+			return value, nil
+		}
+	}
+
+	plaintext, err := k.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+
+	return k.Encrypt(plaintext)
+}
+
+// IsEncrypted reports whether the value is a ciphertext envelope.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EnvelopePrefix)
+}
+
+// EnvelopeKeyID returns the key id referenced by the envelope.
+func EnvelopeKeyID(value string) (string, error) {
+	keyID, _, err := parseEnvelope(value)
+
+	return keyID, err
+}
+
+func parseEnvelope(value string) (string, []byte, error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, EnvelopePrefix), ":", envelopeParts)
+	if !IsEncrypted(value) || len(parts) != envelopeParts || parts[0] == "" {
+		return "", nil, ErrInvalidEnvelope
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidEnvelope, err)
+	}
+
+	return parts[0], sealed, nil
+}
@@ -0,0 +1,133 @@
+package crypt_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	t.Run("Round trip", func(t *testing.T) {
+		keyring, err := crypt.NewKeyring("k1", map[string][]byte{"k1": oldKey})
+		require.NoError(t, err)
+
+		envelope, err := keyring.Encrypt("salary for october")
+		require.NoError(t, err)
+		require.True(t, crypt.IsEncrypted(envelope))
+		require.NotContains(t, envelope, "salary")
+
+		keyID, err := crypt.EnvelopeKeyID(envelope)
+		require.NoError(t, err)
+		require.Equal(t, "k1", keyID)
+
+		plaintext, err := keyring.Decrypt(envelope)
+		require.NoError(t, err)
+		require.Equal(t, "salary for october", plaintext)
+	})
+
+	t.Run("Plaintext and empty values pass through", func(t *testing.T) {
+		keyring, err := crypt.NewKeyring("k1", map[string][]byte{"k1": oldKey})
+		require.NoError(t, err)
+
+		plaintext, err := keyring.Decrypt("legacy remarks")
+		require.NoError(t, err)
+		require.Equal(t, "legacy remarks", plaintext)
+
+		envelope, err := keyring.Encrypt("")
+		require.NoError(t, err)
+		require.Empty(t, envelope)
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		oldKeyring, err := crypt.NewKeyring("k1", map[string][]byte{"k1": oldKey})
+		require.NoError(t, err)
+
+		oldEnvelope, err := oldKeyring.Encrypt("remarks")
+		require.NoError(t, err)
+
+		rotated, err := crypt.NewKeyring("k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+		require.NoError(t, err)
+		require.Equal(t, "k2", rotated.ActiveKeyID())
+
+		plaintext, err := rotated.Decrypt(oldEnvelope)
+		require.NoError(t, err)
+		require.Equal(t, "remarks", plaintext)
+
+		newEnvelope, err := rotated.Reencrypt(oldEnvelope)
+		require.NoError(t, err)
+
+		keyID, err := crypt.EnvelopeKeyID(newEnvelope)
+		require.NoError(t, err)
+		require.Equal(t, "k2", keyID)
+
+		unchanged, err := rotated.Reencrypt(newEnvelope)
+		require.NoError(t, err)
+		require.Equal(t, newEnvelope, unchanged)
+
+		// the old keyring can't read values encrypted by the new key
+		_, err = oldKeyring.Decrypt(newEnvelope)
+		require.ErrorIs(t, err, crypt.ErrUnknownKeyID)
+	})
+
+	t.Run("Tampered envelope", func(t *testing.T) {
+		keyring, err := crypt.NewKeyring("k1", map[string][]byte{"k1": oldKey})
+		require.NoError(t, err)
+
+		envelope, err := keyring.Encrypt("remarks")
+		require.NoError(t, err)
+
+		last := envelope[len(envelope)-1]
+		replacement := "A"
+		if last == 'A' {
+			replacement = "B"
+		}
+
+		_, err = keyring.Decrypt(envelope[:len(envelope)-1] + replacement)
+		require.ErrorIs(t, err, crypt.ErrDecryptionFailed)
+
+		_, err = keyring.Decrypt(crypt.EnvelopePrefix + "k1")
+		require.ErrorIs(t, err, crypt.ErrInvalidEnvelope)
+
+		_, err = keyring.Decrypt(crypt.EnvelopePrefix + "k1:!!!")
+		require.ErrorIs(t, err, crypt.ErrInvalidEnvelope)
+
+		_, err = keyring.Decrypt(crypt.EnvelopePrefix + "k1:AAAA")
+		require.ErrorIs(t, err, crypt.ErrInvalidEnvelope)
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		_, err := crypt.NewKeyring("missing", map[string][]byte{"k1": oldKey})
+		require.ErrorIs(t, err, crypt.ErrUnknownKeyID)
+
+		_, err = crypt.NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+		require.ErrorIs(t, err, crypt.ErrInvalidKey)
+
+		_, err = crypt.NewKeyring("k:1", map[string][]byte{"k:1": oldKey})
+		require.ErrorIs(t, err, crypt.ErrInvalidKey)
+	})
+
+	t.Run("Parse from configuration", func(t *testing.T) {
+		generated, err := crypt.GenerateFieldKey()
+		require.NoError(t, err)
+
+		keyring, err := crypt.ParseKeyring("k2", []string{
+			"k1:" + base64.StdEncoding.EncodeToString(oldKey),
+			" k2:" + generated,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "k2", keyring.ActiveKeyID())
+
+		_, err = crypt.ParseKeyring("k1", []string{"k1"})
+		require.ErrorIs(t, err, crypt.ErrInvalidKey)
+
+		_, err = crypt.ParseKeyring("k1", []string{"k1:" + strings.Repeat("!", 4)})
+		require.ErrorIs(t, err, crypt.ErrInvalidKey)
+	})
+}
@@ -0,0 +1,102 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// StreamHasher is an io.Writer that hashes everything written into it,
+// so payloads can be hashed while they are streamed instead of buffered in memory.
+type StreamHasher struct {
+	hash    hash.Hash
+	written int64
+}
+
+// NewSHA256Hasher creates a StreamHasher producing a plain SHA256 digest.
+func NewSHA256Hasher() *StreamHasher {
+	return &StreamHasher{
+		hash: sha256.New(),
+	}
+}
+
+// NewHMACHasher creates a StreamHasher producing a HMAC-SHA256 signature with the given key.
+func NewHMACHasher(key []byte) *StreamHasher {
+	return &StreamHasher{
+		hash: hmac.New(sha256.New, key),
+	}
+}
+
+// Write never returns an error, as per the hash.Hash contract.
+func (h *StreamHasher) Write(p []byte) (int, error) {
+	n, _ := h.hash.Write(p)
+	h.written += int64(n)
+
+	return n, nil
+}
+
+// Tee returns a writer that writes to w and hashes the same bytes at the same time.
+func (h *StreamHasher) Tee(w io.Writer) io.Writer {
+	return io.MultiWriter(w, h)
+}
+
+// TeeReader returns a reader that hashes the bytes as they are read from r.
+func (h *StreamHasher) TeeReader(r io.Reader) io.Reader {
+	return io.TeeReader(r, h)
+}
+
+// Written returns the number of bytes hashed so far.
+func (h *StreamHasher) Written() int64 {
+	return h.written
+}
+
+// Sum returns the digest of the bytes written so far. It does not change the underlying state.
+func (h *StreamHasher) Sum() []byte {
+	return h.hash.Sum(nil)
+}
+
+// HexSum returns the hex encoded digest of the bytes written so far.
+func (h *StreamHasher) HexSum() string {
+	return hex.EncodeToString(h.Sum())
+}
+
+// Equal compares the digest with the hex encoded expected digest in constant time.
+func (h *StreamHasher) Equal(expectedHex string) bool {
+	expected, err := hex.DecodeString(expectedHex)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(h.Sum(), expected)
+}
+
+// Reset clears the written bytes, so the hasher can be reused.
+func (h *StreamHasher) Reset() {
+	h.hash.Reset()
+	h.written = 0
+}
+
+// HashReader consumes r until EOF and returns the hex encoded SHA256 digest.
+func HashReader(r io.Reader) (string, error) {
+	hasher := NewSHA256Hasher()
+
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", fmt.Errorf("failed to hash stream: %w", err)
+	}
+
+	return hasher.HexSum(), nil
+}
+
+// SignReader consumes r until EOF and returns the hex encoded HMAC-SHA256 signature.
+func SignReader(key []byte, r io.Reader) (string, error) {
+	hasher := NewHMACHasher(key)
+
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", fmt.Errorf("failed to sign stream: %w", err)
+	}
+
+	return hasher.HexSum(), nil
+}
@@ -0,0 +1,83 @@
+package crypt_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHasher(t *testing.T) {
+	payload := strings.Repeat("statement line\n", 1000)
+
+	expectedSHA := sha256.Sum256([]byte(payload))
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write([]byte(payload))
+	expectedHMAC := hex.EncodeToString(mac.Sum(nil))
+
+	t.Run("SHA256 while streaming to a writer", func(t *testing.T) {
+		hasher := crypt.NewSHA256Hasher()
+		out := &bytes.Buffer{}
+
+		_, err := io.Copy(hasher.Tee(out), strings.NewReader(payload))
+		require.NoError(t, err)
+
+		require.Equal(t, payload, out.String())
+		require.Equal(t, hex.EncodeToString(expectedSHA[:]), hasher.HexSum())
+		require.Equal(t, int64(len(payload)), hasher.Written())
+		require.True(t, hasher.Equal(hex.EncodeToString(expectedSHA[:])))
+	})
+
+	t.Run("HMAC while reading", func(t *testing.T) {
+		hasher := crypt.NewHMACHasher([]byte("secret"))
+
+		content, err := io.ReadAll(hasher.TeeReader(strings.NewReader(payload)))
+		require.NoError(t, err)
+
+		require.Equal(t, payload, string(content))
+		require.Equal(t, expectedHMAC, hasher.HexSum())
+		require.False(t, hasher.Equal("not-hex"))
+		require.False(t, hasher.Equal(hex.EncodeToString(expectedSHA[:])))
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		hasher := crypt.NewSHA256Hasher()
+		_, _ = hasher.Write([]byte("garbage"))
+		hasher.Reset()
+		_, _ = hasher.Write([]byte(payload))
+
+		require.Equal(t, hex.EncodeToString(expectedSHA[:]), hasher.HexSum())
+	})
+
+	t.Run("HashReader and SignReader", func(t *testing.T) {
+		sum, err := crypt.HashReader(strings.NewReader(payload))
+		require.NoError(t, err)
+		require.Equal(t, hex.EncodeToString(expectedSHA[:]), sum)
+
+		signature, err := crypt.SignReader([]byte("secret"), strings.NewReader(payload))
+		require.NoError(t, err)
+		require.Equal(t, expectedHMAC, signature)
+	})
+
+	t.Run("Reader error", func(t *testing.T) {
+		_, err := crypt.HashReader(&failingReader{})
+		require.Error(t, err)
+
+		_, err = crypt.SignReader([]byte("secret"), &failingReader{})
+		require.Error(t, err)
+	})
+}
+
+type failingReader struct{}
+
+func (*failingReader) Read(_ []byte) (int, error) {
+	return 0, errors.New("read failed")
+}
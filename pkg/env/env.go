@@ -1,106 +1,40 @@
 package env
 
 import (
-	"fmt"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 )
 
-func GetEnv(key, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
+// the package level functions only read the process environment
 
-	return value
+func GetEnv(key, defaultValue string) string {
+	return NewLoader(os.LookupEnv).GetEnv(key, defaultValue)
 }
 
 func GetEnvBool(key string, defaultValue bool) bool {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-
-	parse, err := strconv.ParseBool(value)
-	if err != nil {
-		return defaultValue
-	}
-
-	return parse
+	return NewLoader(os.LookupEnv).GetEnvBool(key, defaultValue)
 }
 
 func GetEnvInt64(key string, defaultValue int64) int64 {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-
-	parse, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return defaultValue
-	}
-
-	return parse
+	return NewLoader(os.LookupEnv).GetEnvInt64(key, defaultValue)
 }
 
 func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-
-	parse, err := time.ParseDuration(value)
-	if err != nil {
-		return defaultValue
-	}
-
-	return parse
+	return NewLoader(os.LookupEnv).GetEnvDuration(key, defaultValue)
 }
 
 func GetEnvValues(key string) []string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return []string{}
-	}
-
-	return strings.Split(value, ",")
+	return NewLoader(os.LookupEnv).GetEnvValues(key)
 }
 
 func RequireEnv(key string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		panic(fmt.Sprintf("required env variable %s not found", key))
-	}
-
-	return value
+	return NewLoader(os.LookupEnv).RequireEnv(key)
 }
 
 func RequireEnvInt64(key string) int64 {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		panic(fmt.Sprintf("required env variable %s not found", key))
-	}
-
-	parse, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse env variable %s: %v", key, err))
-	}
-
-	return parse
+	return NewLoader(os.LookupEnv).RequireEnvInt64(key)
 }
 
 func RequireEnvBool(key string) bool {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		panic(fmt.Sprintf("required env variable %s not found", key))
-	}
-
-	parse, err := strconv.ParseBool(value)
-	if err != nil {
-		panic(fmt.Sprintf("failed to parse env variable %s: %v", key, err))
-	}
-
-	return parse
+	return NewLoader(os.LookupEnv).RequireEnvBool(key)
 }
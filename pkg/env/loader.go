@@ -0,0 +1,186 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LookupFunc returns the value of the key, and whether it was found. os.LookupEnv is one.
+type LookupFunc func(key string) (string, bool)
+
+// Loader reads settings from several sources by their env variable names.
+// The sources are checked in order, the first one that has the key wins.
+type Loader struct {
+	lookups []LookupFunc
+}
+
+func NewLoader(lookups ...LookupFunc) *Loader {
+	return &Loader{
+		lookups: lookups,
+	}
+}
+
+// MapLookup looks up keys from a map i.e. values from command-line flags or a config file.
+func MapLookup(values map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		value, exists := values[key]
+
+		return value, exists
+	}
+}
+
+// LoadYAMLFile reads a YAML file into env-like keys, by joining nested keys with "_" in uppercase.
+// i.e. postgres: { host: localhost } becomes POSTGRES_HOST=localhost, and lists are comma separated.
+func LoadYAMLFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	document := map[string]any{}
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", document, values)
+
+	return values, nil
+}
+
+func flatten(prefix string, document map[string]any, values map[string]string) {
+	for key, value := range document {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(name, v, values)
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+
+			values[name] = strings.Join(items, ",")
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+func (l *Loader) Lookup(key string) (string, bool) {
+	for _, lookup := range l.lookups {
+		if value, exists := lookup(key); exists {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+func (l *Loader) GetEnv(key, defaultValue string) string {
+	value, exists := l.Lookup(key)
+	if !exists {
+		return defaultValue
+	}
+
+	return value
+}
+
+func (l *Loader) GetEnvBool(key string, defaultValue bool) bool {
+	value, exists := l.Lookup(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parse, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parse
+}
+
+func (l *Loader) GetEnvInt64(key string, defaultValue int64) int64 {
+	value, exists := l.Lookup(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parse, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parse
+}
+
+func (l *Loader) GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := l.Lookup(key)
+	if !exists {
+		return defaultValue
+	}
+
+	parse, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+
+	return parse
+}
+
+func (l *Loader) GetEnvValues(key string) []string {
+	value, exists := l.Lookup(key)
+	if !exists {
+		return []string{}
+	}
+
+	return strings.Split(value, ",")
+}
+
+func (l *Loader) RequireEnv(key string) string {
+	value, exists := l.Lookup(key)
+	if !exists {
+		panic(fmt.Sprintf("required env variable %s not found", key))
+	}
+
+	return value
+}
+
+func (l *Loader) RequireEnvInt64(key string) int64 {
+	value, exists := l.Lookup(key)
+	if !exists {
+		panic(fmt.Sprintf("required env variable %s not found", key))
+	}
+
+	parse, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse env variable %s: %v", key, err))
+	}
+
+	return parse
+}
+
+func (l *Loader) RequireEnvBool(key string) bool {
+	value, exists := l.Lookup(key)
+	if !exists {
+		panic(fmt.Sprintf("required env variable %s not found", key))
+	}
+
+	parse, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse env variable %s: %v", key, err))
+	}
+
+	return parse
+}
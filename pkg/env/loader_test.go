@@ -0,0 +1,75 @@
+package env_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader(t *testing.T) {
+	t.Run("first source wins", func(t *testing.T) {
+		loader := env.NewLoader(
+			env.MapLookup(map[string]string{"PORT": "9090"}),
+			env.MapLookup(map[string]string{"PORT": "8080", "TIMEOUT": "5s"}),
+		)
+
+		assert.Equal(t, "9090", loader.GetEnv("PORT", ""))
+		assert.Equal(t, int64(9090), loader.RequireEnvInt64("PORT"))
+		assert.Equal(t, 5*time.Second, loader.GetEnvDuration("TIMEOUT", 0))
+		assert.Equal(t, "default", loader.GetEnv("MISSING", "default"))
+	})
+
+	t.Run("missing required values", func(t *testing.T) {
+		loader := env.NewLoader(env.MapLookup(map[string]string{"FLAG": "yes"}))
+
+		assert.Panics(t, func() {
+			loader.RequireEnv("MISSING")
+		})
+		assert.Panics(t, func() {
+			loader.RequireEnvBool("FLAG")
+		})
+		assert.True(t, loader.GetEnvBool("FLAG", true))
+	})
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet.yaml")
+
+	err := os.WriteFile(path, []byte(`
+port: 8080
+postgres:
+  host: localhost
+  port: 5433
+redis:
+  address: localhost:6389
+  sentinel-addresses:
+    - sentinel1:26379
+    - sentinel2:26379
+`), 0o600)
+	require.NoError(t, err)
+
+	values, err := env.LoadYAMLFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"PORT":                     "8080",
+		"POSTGRES_HOST":            "localhost",
+		"POSTGRES_PORT":            "5433",
+		"REDIS_ADDRESS":            "localhost:6389",
+		"REDIS_SENTINEL_ADDRESSES": "sentinel1:26379,sentinel2:26379",
+	}, values)
+
+	_, err = env.LoadYAMLFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("- not a map"), 0o600))
+
+	_, err = env.LoadYAMLFile(invalid)
+	require.Error(t, err)
+}
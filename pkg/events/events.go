@@ -0,0 +1,51 @@
+// Package events publishes the ledger events to a message broker.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/devshark/wallet/api"
+)
+
+var ErrPublishFailed = errors.New("failed to publish events")
+
+// The headers carrying the envelope, so the consumers can route and deduplicate without decoding the payload.
+const (
+	HeaderID      = "Event-Id"
+	HeaderType    = "Event-Type"
+	HeaderVersion = "Event-Version"
+)
+
+// DefaultTopicPrefix is prepended to the event type, i.e. wallet.transfer.created.
+const DefaultTopicPrefix = "wallet"
+
+// Publisher sends the events to a broker. The events of a batch are published in order,
+// and Publish only returns once the broker has acknowledged all of them.
+type Publisher interface {
+	Publish(ctx context.Context, events ...api.Event) error
+	Close() error
+}
+
+// Topic is the topic, or the subject, the events of the type are published to.
+func Topic(prefix, eventType string) string {
+	if prefix == "" {
+		return eventType
+	}
+
+	return prefix + "." + eventType
+}
+
+func headers(event *api.Event) map[string]string {
+	return map[string]string{
+		HeaderID:      event.ID,
+		HeaderType:    event.Type,
+		HeaderVersion: strconv.Itoa(event.Version),
+	}
+}
+
+func publishError(broker string, err error) error {
+	return fmt.Errorf("%w to %s: %w", ErrPublishFailed, broker, err)
+}
@@ -0,0 +1,187 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/events"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+var errBroker = errors.New("broker down")
+
+func testEvents() []api.Event {
+	createdAt := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	return []api.Event{
+		{
+			ID:      "5f0c6d0e-9f4e-4d8a-9a57-8a3f0c1b2e01",
+			Type:    api.EventAccountCreated,
+			Version: api.AccountCreatedVersion,
+			Key:     "user1",
+			Time:    createdAt,
+			Data:    json.RawMessage(`{"account_id":"user1","currency":"USD"}`),
+		},
+		{
+			ID:      "5f0c6d0e-9f4e-4d8a-9a57-8a3f0c1b2e02",
+			Type:    api.EventTransferCreated,
+			Version: api.TransferCreatedVersion,
+			Key:     "user1",
+			Time:    createdAt,
+			Data:    json.RawMessage(`{"transfer_id":"tx1","amount":"10"}`),
+		},
+	}
+}
+
+func TestTopic(t *testing.T) {
+	require.Equal(t, "wallet.transfer.created", events.Topic(events.DefaultTopicPrefix, api.EventTransferCreated))
+	require.Equal(t, "transfer.created", events.Topic("", api.EventTransferCreated))
+}
+
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.messages = append(w.messages, messages...)
+
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+
+	return nil
+}
+
+func kafkaHeader(message kafka.Message, key string) string {
+	for _, header := range message.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	t.Run("Messages", func(t *testing.T) {
+		writer := &fakeKafkaWriter{}
+		publisher := events.NewKafkaPublisher(writer).WithTopicPrefix("ledger")
+
+		require.NoError(t, publisher.Publish(context.Background(), testEvents()...))
+		require.Len(t, writer.messages, 2)
+
+		message := writer.messages[1]
+		require.Equal(t, "ledger.transfer.created", message.Topic)
+		require.Equal(t, "user1", string(message.Key))
+		require.Equal(t, "5f0c6d0e-9f4e-4d8a-9a57-8a3f0c1b2e02", kafkaHeader(message, events.HeaderID))
+		require.Equal(t, api.EventTransferCreated, kafkaHeader(message, events.HeaderType))
+		require.Equal(t, "1", kafkaHeader(message, events.HeaderVersion))
+
+		var event api.Event
+		require.NoError(t, json.Unmarshal(message.Value, &event))
+		require.Equal(t, testEvents()[1].ID, event.ID)
+		require.JSONEq(t, `{"transfer_id":"tx1","amount":"10"}`, string(event.Data))
+
+		require.NoError(t, publisher.Close())
+		require.True(t, writer.closed)
+	})
+
+	t.Run("Nothing to publish", func(t *testing.T) {
+		writer := &fakeKafkaWriter{err: errBroker}
+
+		require.NoError(t, events.NewKafkaPublisher(writer).Publish(context.Background()))
+	})
+
+	t.Run("Broker failure", func(t *testing.T) {
+		writer := &fakeKafkaWriter{err: errBroker}
+
+		err := events.NewKafkaPublisher(writer).Publish(context.Background(), testEvents()...)
+		require.ErrorIs(t, err, events.ErrPublishFailed)
+		require.ErrorIs(t, err, errBroker)
+	})
+}
+
+type fakeNATSConn struct {
+	messages   []*nats.Msg
+	publishErr error
+	flushErr   error
+	flushed    bool
+	closed     bool
+}
+
+func (c *fakeNATSConn) PublishMsg(msg *nats.Msg) error {
+	if c.publishErr != nil {
+		return c.publishErr
+	}
+
+	c.messages = append(c.messages, msg)
+
+	return nil
+}
+
+func (c *fakeNATSConn) FlushWithContext(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return nats.ErrNoDeadlineContext
+	}
+
+	c.flushed = true
+
+	return c.flushErr
+}
+
+func (c *fakeNATSConn) Close() {
+	c.closed = true
+}
+
+func TestNATSPublisher(t *testing.T) {
+	t.Run("Messages", func(t *testing.T) {
+		conn := &fakeNATSConn{}
+		publisher := events.NewNATSPublisher(conn)
+
+		require.NoError(t, publisher.Publish(context.Background(), testEvents()...))
+		require.True(t, conn.flushed)
+		require.Len(t, conn.messages, 2)
+
+		msg := conn.messages[0]
+		require.Equal(t, "wallet.account.created", msg.Subject)
+		require.Equal(t, "5f0c6d0e-9f4e-4d8a-9a57-8a3f0c1b2e01", msg.Header.Get(nats.MsgIdHdr))
+		require.Equal(t, api.EventAccountCreated, msg.Header.Get(events.HeaderType))
+		require.Equal(t, "1", msg.Header.Get(events.HeaderVersion))
+
+		var event api.Event
+		require.NoError(t, json.Unmarshal(msg.Data, &event))
+		require.Equal(t, "user1", event.Key)
+
+		require.NoError(t, publisher.Close())
+		require.True(t, conn.closed)
+	})
+
+	t.Run("Publish failure", func(t *testing.T) {
+		conn := &fakeNATSConn{publishErr: errBroker}
+
+		err := events.NewNATSPublisher(conn).Publish(context.Background(), testEvents()...)
+		require.ErrorIs(t, err, events.ErrPublishFailed)
+		require.False(t, conn.flushed)
+	})
+
+	t.Run("Flush failure", func(t *testing.T) {
+		conn := &fakeNATSConn{flushErr: errBroker}
+
+		err := events.NewNATSPublisher(conn).Publish(context.Background(), testEvents()...)
+		require.ErrorIs(t, err, errBroker)
+	})
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/devshark/wallet/api"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaWriter is implemented by kafka.Writer.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// KafkaPublisher publishes every event to the topic of its type, keyed by the event key,
+// so the events of an account land on the same partition and keep their order.
+type KafkaPublisher struct {
+	writer KafkaWriter
+	prefix string
+}
+
+func NewKafkaPublisher(writer KafkaWriter) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: writer,
+		prefix: DefaultTopicPrefix,
+	}
+}
+
+// WithTopicPrefix overrides the prefix of the topics.
+func (p *KafkaPublisher) WithTopicPrefix(prefix string) *KafkaPublisher {
+	p.prefix = prefix
+
+	return p
+}
+
+// NewKafkaWriter writes to the given brokers, waiting for every in-sync replica to acknowledge the messages.
+// The topics must exist, or the brokers must allow creating them.
+func NewKafkaWriter(brokers ...string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, events ...api.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, len(events))
+
+	for i := range events {
+		value, err := json.Marshal(&events[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", events[i].ID, err)
+		}
+
+		messageHeaders := make([]kafka.Header, 0, 3)
+		for key, headerValue := range headers(&events[i]) {
+			messageHeaders = append(messageHeaders, kafka.Header{Key: key, Value: []byte(headerValue)})
+		}
+
+		messages[i] = kafka.Message{
+			Topic:   Topic(p.prefix, events[i].Type),
+			Key:     []byte(events[i].Key),
+			Value:   value,
+			Headers: messageHeaders,
+			Time:    events[i].Time,
+		}
+	}
+
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		return publishError("kafka", err)
+	}
+
+	return nil
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/nats-io/nats.go"
+)
+
+// the flush requires a deadline, used when the context has none.
+const defaultFlushTimeout = 10 * time.Second
+
+// NATSConn is implemented by nats.Conn.
+type NATSConn interface {
+	PublishMsg(msg *nats.Msg) error
+	FlushWithContext(ctx context.Context) error
+	Close()
+}
+
+// NATSPublisher publishes every event to the subject of its type.
+// The event id is sent as the message id, so a JetStream stream on the subjects drops the events relayed twice.
+type NATSPublisher struct {
+	conn   NATSConn
+	prefix string
+}
+
+func NewNATSPublisher(conn NATSConn) *NATSPublisher {
+	return &NATSPublisher{
+		conn:   conn,
+		prefix: DefaultTopicPrefix,
+	}
+}
+
+// WithTopicPrefix overrides the prefix of the subjects.
+func (p *NATSPublisher) WithTopicPrefix(prefix string) *NATSPublisher {
+	p.prefix = prefix
+
+	return p
+}
+
+// Publish sends the events and flushes them, so they have reached the server once it returns.
+func (p *NATSPublisher) Publish(ctx context.Context, events ...api.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	for i := range events {
+		data, err := json.Marshal(&events[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s: %w", events[i].ID, err)
+		}
+
+		msg := nats.NewMsg(Topic(p.prefix, events[i].Type))
+		msg.Data = data
+
+		for key, value := range headers(&events[i]) {
+			msg.Header.Set(key, value)
+		}
+
+		msg.Header.Set(nats.MsgIdHdr, events[i].ID)
+
+		if err = p.conn.PublishMsg(msg); err != nil {
+			return publishError("nats", err)
+		}
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, defaultFlushTimeout)
+		defer cancel()
+	}
+
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return publishError("nats", err)
+	}
+
+	return nil
+}
+
+func (p *NATSPublisher) Close() error {
+	p.conn.Close()
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/clock"
+)
+
+// WebhookSubscriptions is implemented by repository.PostgresRepository.
+type WebhookSubscriptions interface {
+	// GetWebhookSubscriptions returns every subscription for an empty account id.
+	GetWebhookSubscriptions(ctx context.Context, accountID string) ([]*api.WebhookSubscription, error)
+}
+
+// WebhookPoster is implemented by webhook.Sender.
+type WebhookPoster interface {
+	// Post signs the payload with the secrets, or with the secret of the poster without any or for a nil one.
+	Post(ctx context.Context, url string, payload any, secrets ...[]byte) error
+}
+
+// WebhookPublisher posts every event to the webhook subscriptions in its scope, i.e. the global subscriptions
+// and those of the accounts of the event. The subscriptions are read at every batch, so they apply right away.
+// A failed delivery is logged and not retried, so a receiver that is down doesn't hold back the others.
+// The deliveries are signed with the secrets of their subscription, see api.WebhookSubscription.SigningSecrets.
+type WebhookPublisher struct {
+	subscriptions WebhookSubscriptions
+	poster        WebhookPoster
+	logger        *slog.Logger
+	clock         clock.Clock
+}
+
+func NewWebhookPublisher(subscriptions WebhookSubscriptions, poster WebhookPoster) *WebhookPublisher {
+	return &WebhookPublisher{
+		subscriptions: subscriptions,
+		poster:        poster,
+		logger:        slog.Default(),
+		clock:         clock.NewSystemClock(),
+	}
+}
+
+// WithClock overrides the clock expiring the previous secrets of the subscriptions.
+func (p *WebhookPublisher) WithClock(c clock.Clock) *WebhookPublisher {
+	p.clock = c
+
+	return p
+}
+
+func (p *WebhookPublisher) WithLogger(logger *slog.Logger) *WebhookPublisher {
+	p.logger = logger
+
+	return p
+}
+
+// Publish posts the events in order. It only fails when the subscriptions can't be read, so the batch is relayed again.
+func (p *WebhookPublisher) Publish(ctx context.Context, events ...api.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	subscriptions, err := p.subscriptions.GetWebhookSubscriptions(ctx, "")
+	if err != nil {
+		return publishError("webhooks", err)
+	}
+
+	now := p.clock.Now()
+
+	for i := range events {
+		accountIDs, currency, err := eventScope(&events[i])
+		if err != nil {
+			// only posted to the global subscriptions then, the scoped ones can't match it
+			p.logger.WarnContext(ctx, "failed to read the scope of the event", slog.String("id", events[i].ID), slog.Any("error", err))
+		}
+
+		for _, subscription := range subscriptions {
+			if !subscription.Matches(accountIDs, currency) {
+				continue
+			}
+
+			if postErr := p.poster.Post(ctx, subscription.URL, &events[i], subscription.SigningSecrets(now)...); postErr != nil {
+				p.logger.WarnContext(ctx, "failed to deliver the event", slog.String("id", events[i].ID),
+					slog.String("subscription", subscription.ID), slog.Any("error", postErr))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *WebhookPublisher) Close() error {
+	return nil
+}
+
+// eventScope returns the accounts and the currency of the event, from its data.
+func eventScope(event *api.Event) ([]string, string, error) {
+	switch event.Type {
+	case api.EventTransferCreated:
+		data := api.TransferCreated{}
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+
+		return []string{data.FromAccountID, data.ToAccountID}, data.Currency, nil
+	case api.EventAccountCreated:
+		data := api.AccountCreated{}
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+
+		return []string{data.AccountID}, data.Currency, nil
+	default:
+		return nil, "", fmt.Errorf("unknown event type %s", event.Type)
+	}
+}
@@ -0,0 +1,99 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/events"
+	"github.com/devshark/wallet/pkg/logging"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhookSubscriptions struct {
+	subscriptions []*api.WebhookSubscription
+	err           error
+}
+
+func (s *fakeWebhookSubscriptions) GetWebhookSubscriptions(context.Context, string) ([]*api.WebhookSubscription, error) {
+	return s.subscriptions, s.err
+}
+
+// fakeWebhookPoster records the event ids posted to every url and the secrets signing them,
+// and fails the posts to failing.
+type fakeWebhookPoster struct {
+	posted  map[string][]string
+	secrets map[string][][]byte
+	failing string
+}
+
+func (p *fakeWebhookPoster) Post(_ context.Context, url string, payload any, secrets ...[]byte) error {
+	if url == p.failing {
+		return errBroker
+	}
+
+	p.posted[url] = append(p.posted[url], payload.(*api.Event).ID)
+	p.secrets[url] = secrets
+
+	return nil
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	published := testEvents()
+	published[1].Data = json.RawMessage(`{"transfer_id":"tx1","from_account_id":"company","to_account_id":"user2","currency":"EUR"}`)
+
+	subscriptions := &fakeWebhookSubscriptions{subscriptions: []*api.WebhookSubscription{
+		{ID: "global", URL: "https://global.example.com"},
+		{ID: "user1", URL: "https://user1.example.com", AccountID: "user1"},
+		{ID: "user2-usd", URL: "https://user2-usd.example.com", AccountID: "user2", Currency: "USD"},
+		{ID: "user2-eur", URL: "https://user2-eur.example.com", AccountID: "user2", Currency: "EUR"},
+		{ID: "down", URL: "https://down.example.com"},
+	}}
+
+	poster := &fakeWebhookPoster{posted: map[string][]string{}, secrets: map[string][][]byte{}, failing: "https://down.example.com"}
+
+	publisher := events.NewWebhookPublisher(subscriptions, poster).WithLogger(logging.Discard())
+
+	require.NoError(t, publisher.Publish(context.Background(), published...), "the failed deliveries are skipped")
+	require.Equal(t, map[string][]string{
+		"https://global.example.com":    {published[0].ID, published[1].ID},
+		"https://user1.example.com":     {published[0].ID},
+		"https://user2-eur.example.com": {published[1].ID},
+	}, poster.posted)
+
+	t.Run("Rotated secrets", func(t *testing.T) {
+		now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+		expiresAt := now.Add(time.Hour)
+
+		subscriptions := &fakeWebhookSubscriptions{subscriptions: []*api.WebhookSubscription{
+			{ID: "server", URL: "https://server.example.com"},
+			{ID: "first", URL: "https://first.example.com", Secret: "new", PreviousSecretExpiresAt: &expiresAt},
+			{ID: "grace", URL: "https://grace.example.com", Secret: "new", PreviousSecret: "old", PreviousSecretExpiresAt: &expiresAt},
+			{ID: "revoked", URL: "https://revoked.example.com", Secret: "new", PreviousSecret: "old"},
+		}}
+
+		poster := &fakeWebhookPoster{posted: map[string][]string{}, secrets: map[string][][]byte{}}
+
+		publisher := events.NewWebhookPublisher(subscriptions, poster).
+			WithLogger(logging.Discard()).
+			WithClock(wallettesting.NewFakeClock(now))
+
+		require.NoError(t, publisher.Publish(context.Background(), published[0]))
+		require.Equal(t, map[string][][]byte{
+			"https://server.example.com":  nil,
+			"https://first.example.com":   {[]byte("new"), nil},
+			"https://grace.example.com":   {[]byte("new"), []byte("old")},
+			"https://revoked.example.com": {[]byte("new")},
+		}, poster.secrets)
+	})
+
+	t.Run("Subscriptions unavailable", func(t *testing.T) {
+		subscriptions.err = errBroker
+
+		err := publisher.Publish(context.Background(), published...)
+		require.ErrorIs(t, err, events.ErrPublishFailed)
+	})
+}
@@ -0,0 +1,113 @@
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/pkg/env"
+)
+
+// Flag is the name of a feature, in snake case i.e. strict_account_creation.
+type Flag string
+
+// Source is where the state of the flags is read from.
+type Source interface {
+	// Lookup returns whether the flag is enabled, and whether the source has a value for it at all.
+	Lookup(ctx context.Context, flag Flag) (enabled bool, found bool, err error)
+}
+
+// Flags resolves the flags from its sources in order, the first one that has a value wins.
+// Without any value, the flag is disabled unless given another default.
+type Flags struct {
+	sources  []Source
+	defaults map[Flag]bool
+	logger   *slog.Logger
+}
+
+func New(sources ...Source) *Flags {
+	return &Flags{
+		sources:  sources,
+		defaults: map[Flag]bool{},
+		logger:   slog.Default(),
+	}
+}
+
+// WithDefault sets the state of the flag when none of the sources has a value.
+func (f *Flags) WithDefault(flag Flag, enabled bool) *Flags {
+	f.defaults[flag] = enabled
+
+	return f
+}
+
+func (f *Flags) WithLogger(logger *slog.Logger) *Flags {
+	f.logger = logger
+
+	return f
+}
+
+// Enabled reports whether the flag is enabled.
+// A source failing is logged and skipped, so an unreachable Redis falls back to the next source instead of failing the request.
+func (f *Flags) Enabled(ctx context.Context, flag Flag) bool {
+	for _, source := range f.sources {
+		enabled, found, err := source.Lookup(ctx, flag)
+		if err != nil {
+			f.logger.WarnContext(ctx, "failed to look up feature flag", slog.String("flag", string(flag)), slog.Any("error", err))
+
+			continue
+		}
+
+		if found {
+			return enabled
+		}
+	}
+
+	return f.defaults[flag]
+}
+
+// MapSource is a static source, i.e. for tests.
+type MapSource map[Flag]bool
+
+func (s MapSource) Lookup(_ context.Context, flag Flag) (bool, bool, error) {
+	enabled, found := s[flag]
+
+	return enabled, found, nil
+}
+
+// EnvSource reads the flags from the settings named FEATURE_<FLAG> in uppercase,
+// i.e. FEATURE_STRICT_ACCOUNT_CREATION=true for strict_account_creation.
+type EnvSource struct {
+	lookup env.LookupFunc
+}
+
+// NewEnvSource reads the flags with the given lookup, i.e. os.LookupEnv or env.Loader.Lookup.
+func NewEnvSource(lookup env.LookupFunc) *EnvSource {
+	return &EnvSource{
+		lookup: lookup,
+	}
+}
+
+func (s *EnvSource) Lookup(_ context.Context, flag Flag) (bool, bool, error) {
+	value, found := s.lookup(EnvKey(flag))
+	if !found {
+		return false, false, nil
+	}
+
+	return parseValue(value)
+}
+
+// EnvKey is the setting read by EnvSource for the flag.
+func EnvKey(flag Flag) string {
+	return "FEATURE_" + strings.ToUpper(string(flag))
+}
+
+func parseValue(value string) (bool, bool, error) {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, false, fmt.Errorf("invalid feature flag value %q: %w", value, err)
+	}
+
+	return enabled, true, nil
+}
@@ -0,0 +1,141 @@
+package featureflags_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/env"
+	"github.com/devshark/wallet/pkg/featureflags"
+	"github.com/devshark/wallet/pkg/logging"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	strict featureflags.Flag = "strict_mode"
+	other  featureflags.Flag = "other_feature"
+)
+
+type failingSource struct{}
+
+func (failingSource) Lookup(context.Context, featureflags.Flag) (bool, bool, error) {
+	return false, false, errors.New("unreachable")
+}
+
+func TestFlags(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Disabled without any value", func(t *testing.T) {
+		flags := featureflags.New()
+
+		require.False(t, flags.Enabled(ctx, strict))
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		flags := featureflags.New(featureflags.MapSource{}).
+			WithDefault(strict, true)
+
+		require.True(t, flags.Enabled(ctx, strict))
+		require.False(t, flags.Enabled(ctx, other))
+	})
+
+	t.Run("First source with a value wins", func(t *testing.T) {
+		flags := featureflags.New(
+			featureflags.MapSource{strict: false},
+			featureflags.MapSource{strict: true, other: true},
+		).WithDefault(strict, true)
+
+		require.False(t, flags.Enabled(ctx, strict))
+		require.True(t, flags.Enabled(ctx, other))
+	})
+
+	t.Run("Failing source is skipped", func(t *testing.T) {
+		flags := featureflags.New(failingSource{}, featureflags.MapSource{strict: true}).
+			WithLogger(logging.Discard())
+
+		require.True(t, flags.Enabled(ctx, strict))
+	})
+}
+
+func TestEnvSource(t *testing.T) {
+	ctx := context.Background()
+
+	require.Equal(t, "FEATURE_STRICT_MODE", featureflags.EnvKey(strict))
+
+	source := featureflags.NewEnvSource(env.MapLookup(map[string]string{
+		"FEATURE_STRICT_MODE":   " TRUE ",
+		"FEATURE_OTHER_FEATURE": "maybe",
+	}))
+
+	enabled, found, err := source.Lookup(ctx, strict)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.True(t, enabled)
+
+	_, _, err = source.Lookup(ctx, other)
+	require.Error(t, err)
+
+	_, found, err = source.Lookup(ctx, "missing")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+type fakeHash struct {
+	values map[string]string
+	err    error
+	calls  int
+}
+
+func (h *fakeHash) HGetAll(context.Context, string) *redis.StringStringMapCmd {
+	h.calls++
+
+	return redis.NewStringStringMapResult(h.values, h.err)
+}
+
+func TestRedisSource(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Cached for the TTL", func(t *testing.T) {
+		hash := &fakeHash{values: map[string]string{"strict_mode": "true"}}
+		clock := wallettesting.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		source := featureflags.NewRedisSource(hash, featureflags.DefaultRedisKey, time.Minute).
+			WithClock(clock)
+
+		enabled, found, err := source.Lookup(ctx, strict)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.True(t, enabled)
+
+		// toggled in redis, but still cached
+		hash.values = map[string]string{"strict_mode": "false"}
+
+		enabled, _, err = source.Lookup(ctx, strict)
+		require.NoError(t, err)
+		require.True(t, enabled)
+		require.Equal(t, 1, hash.calls)
+
+		clock.Advance(time.Minute)
+
+		enabled, _, err = source.Lookup(ctx, strict)
+		require.NoError(t, err)
+		require.False(t, enabled)
+		require.Equal(t, 2, hash.calls)
+
+		_, found, err = source.Lookup(ctx, other)
+		require.NoError(t, err)
+		require.False(t, found)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		hash := &fakeHash{err: errors.New("connection refused")}
+
+		source := featureflags.NewRedisSource(hash, featureflags.DefaultRedisKey, time.Minute)
+
+		_, _, err := source.Lookup(ctx, strict)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,81 @@
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey is the hash holding the flags, i.e. HSET wallet:features strict_account_creation true.
+const DefaultRedisKey = "wallet:features"
+
+type HashGetter interface {
+	HGetAll(ctx context.Context, key string) *redis.StringStringMapCmd
+}
+
+// RedisSource reads the flags from a Redis hash, so they can be toggled at runtime for every instance at once.
+// The whole hash is cached for the TTL, so the flags don't cost a round trip per request.
+type RedisSource struct {
+	client HashGetter
+	key    string
+	ttl    time.Duration
+	clock  clock.Clock
+
+	mu        sync.Mutex
+	values    map[string]string
+	fetchedAt time.Time
+}
+
+func NewRedisSource(client HashGetter, key string, ttl time.Duration) *RedisSource {
+	return &RedisSource{
+		client: client,
+		key:    key,
+		ttl:    ttl,
+		clock:  clock.NewSystemClock(),
+	}
+}
+
+// WithClock overrides the clock used to expire the cached flags.
+func (s *RedisSource) WithClock(c clock.Clock) *RedisSource {
+	s.clock = c
+
+	return s
+}
+
+func (s *RedisSource) Lookup(ctx context.Context, flag Flag) (bool, bool, error) {
+	values, err := s.load(ctx)
+	if err != nil {
+		return false, false, err
+	}
+
+	value, found := values[string(flag)]
+	if !found {
+		return false, false, nil
+	}
+
+	return parseValue(value)
+}
+
+func (s *RedisSource) load(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.values != nil && now.Sub(s.fetchedAt) < s.ttl {
+		return s.values, nil
+	}
+
+	values, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature flags from %s: %w", s.key, err)
+	}
+
+	s.values = values
+	s.fetchedAt = now
+
+	return values, nil
+}
@@ -0,0 +1,38 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// Generator abstracts the creation of unique ids, so tests can assert exact ids instead of matching patterns.
+type Generator interface {
+	NewID() string
+}
+
+// UUIDGenerator generates random (version 4) UUIDs.
+type UUIDGenerator struct{}
+
+func NewUUIDGenerator() UUIDGenerator {
+	return UUIDGenerator{}
+}
+
+// NewID returns a random UUID as defined by RFC 9562.
+// It panics if the system random source fails, as there is no safe way to continue.
+func (UUIDGenerator) NewID() string {
+	var uuid [16]byte
+
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // variant 10
+
+	return Format(uuid)
+}
+
+// Format returns the canonical textual representation of the UUID.
+func Format(uuid [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
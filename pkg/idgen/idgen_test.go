@@ -0,0 +1,33 @@
+package idgen_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/idgen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDGenerator(t *testing.T) {
+	generator := idgen.NewUUIDGenerator()
+
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := map[string]struct{}{}
+
+	for range 100 {
+		id := generator.NewID()
+		require.Regexp(t, pattern, id)
+
+		_, duplicate := seen[id]
+		require.False(t, duplicate)
+
+		seen[id] = struct{}{}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	uuid := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0x4d, 0xef, 0x80, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}
+
+	require.Equal(t, "12345678-9abc-4def-8001-020304050607", idgen.Format(uuid))
+}
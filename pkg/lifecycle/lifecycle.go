@@ -0,0 +1,206 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+// Task is a long running unit of work, i.e. a server or a background job.
+// It must return once the context is cancelled.
+type Task func(ctx context.Context) error
+
+// CloseFunc releases a resource, giving up once the context is done.
+type CloseFunc func(ctx context.Context) error
+
+type namedTask struct {
+	name string
+	task Task
+}
+
+type namedCloser struct {
+	name  string
+	close CloseFunc
+}
+
+// Manager coordinates the startup and the shutdown of the application.
+// The tasks run concurrently until the context is cancelled or one of them fails,
+// then the closers run in reverse order of registration, once every task has returned.
+type Manager struct {
+	logger          *slog.Logger
+	shutdownTimeout time.Duration
+	tasks           []namedTask
+	closers         []namedCloser
+}
+
+func NewManager(shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		logger:          slog.Default(),
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+func (m *Manager) WithLogger(logger *slog.Logger) *Manager {
+	m.logger = logger
+
+	return m
+}
+
+// Go registers a task to be started by Run.
+func (m *Manager) Go(name string, task Task) *Manager {
+	m.tasks = append(m.tasks, namedTask{name: name, task: task})
+
+	return m
+}
+
+// OnShutdown registers a closer. Closers run last-in first-out, so the resources
+// registered first, like the database, are released after the components using them.
+func (m *Manager) OnShutdown(name string, closeFunc CloseFunc) *Manager {
+	m.closers = append(m.closers, namedCloser{name: name, close: closeFunc})
+
+	return m
+}
+
+// Run starts the tasks and blocks until all of them have stopped and the resources are closed.
+// The shutdown timeout bounds the time spent on waiting for the tasks and on closing the resources.
+func (m *Manager) Run(ctx context.Context) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	for _, t := range m.tasks {
+		group.Go(func() error {
+			m.logger.InfoContext(ctx, "starting", slog.String("task", t.name))
+
+			if err := t.task(groupCtx); err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+
+			m.logger.InfoContext(ctx, "stopped", slog.String("task", t.name))
+
+			return nil
+		})
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- group.Wait()
+	}()
+
+	var runErr error
+
+	select {
+	case runErr = <-done:
+	case <-groupCtx.Done():
+		m.logger.InfoContext(ctx, "shutting down", slog.Duration("timeout", m.shutdownTimeout))
+
+		select {
+		case runErr = <-done:
+		case <-time.After(m.shutdownTimeout):
+			runErr = ErrShutdownTimeout
+		}
+	}
+
+	// the parent context is most likely cancelled already, the closers still get their own deadline
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.shutdownTimeout)
+	defer cancel()
+
+	return errors.Join(runErr, m.close(closeCtx))
+}
+
+func (m *Manager) close(ctx context.Context) error {
+	var errs []error
+
+	for i := len(m.closers) - 1; i >= 0; i-- {
+		closer := m.closers[i]
+
+		if err := closer.close(ctx); err != nil {
+			m.logger.ErrorContext(ctx, "failed to close", slog.String("resource", closer.name), slog.Any("error", err))
+			errs = append(errs, fmt.Errorf("%s: %w", closer.name, err))
+
+			continue
+		}
+
+		m.logger.InfoContext(ctx, "closed", slog.String("resource", closer.name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// HTTPServer runs the server with the given serve function, i.e. ListenAndServe or ListenAndServeTLS.
+// Once the context is cancelled, it stops accepting connections and waits for the in-flight requests
+// up to the shutdown timeout.
+func HTTPServer(server *http.Server, serve func() error, shutdownTimeout time.Duration) Task {
+	return func(ctx context.Context) error {
+		serveErr := make(chan error, 1)
+
+		go func() {
+			serveErr <- serve()
+		}()
+
+		select {
+		case err := <-serveErr:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+
+			return fmt.Errorf("http server failed: %w", err)
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("http server shutdown: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// Closer adapts an io.Closer, i.e. *sql.DB or *redis.Client, which doesn't accept a context.
+// It stops waiting once the context is done, leaving the close to finish in the background.
+func Closer(c io.Closer) CloseFunc {
+	return func(ctx context.Context) error {
+		closeErr := make(chan error, 1)
+
+		go func() {
+			closeErr <- c.Close()
+		}()
+
+		select {
+		case err := <-closeErr:
+			return err //nolint:wrapcheck // the caller adds the resource name
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrShutdownTimeout, ctx.Err())
+		}
+	}
+}
+
+// Every runs the job on each tick of the interval until the context is cancelled.
+// A failing run is logged and retried on the next tick, so one bad run doesn't stop the job.
+func Every(interval time.Duration, logger *slog.Logger, job Task) Task {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := job(ctx); err != nil && ctx.Err() == nil {
+					logger.ErrorContext(ctx, "job failed", slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
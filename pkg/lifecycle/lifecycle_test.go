@@ -0,0 +1,210 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/lifecycle"
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func TestManager(t *testing.T) {
+	t.Run("Stops tasks then closes resources in reverse order", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		var (
+			mu    sync.Mutex
+			order []string
+		)
+
+		record := func(step string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			order = append(order, step)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+
+		manager := lifecycle.NewManager(time.Second).
+			WithLogger(logging.Discard()).
+			Go("worker", func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				record("worker")
+
+				return nil
+			}).
+			OnShutdown("db", func(context.Context) error {
+				record("db")
+
+				return nil
+			}).
+			OnShutdown("redis", lifecycle.Closer(closerFunc(func() error {
+				record("redis")
+
+				return nil
+			})))
+
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		require.NoError(t, manager.Run(ctx))
+		require.Equal(t, []string{"worker", "redis", "db"}, order)
+	})
+
+	t.Run("Failing task stops the others", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		errBoom := errors.New("boom")
+		closed := false
+
+		manager := lifecycle.NewManager(time.Second).
+			WithLogger(logging.Discard()).
+			Go("failing", func(context.Context) error {
+				return errBoom
+			}).
+			Go("waiting", func(ctx context.Context) error {
+				<-ctx.Done()
+
+				return nil
+			}).
+			OnShutdown("db", func(context.Context) error {
+				closed = true
+
+				return nil
+			})
+
+		err := manager.Run(context.Background())
+		require.ErrorIs(t, err, errBoom)
+		require.True(t, closed)
+	})
+
+	t.Run("Task ignoring cancellation times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		manager := lifecycle.NewManager(10*time.Millisecond).
+			WithLogger(logging.Discard()).
+			Go("stuck", func(context.Context) error {
+				<-release
+
+				return nil
+			})
+
+		err := manager.Run(ctx)
+		require.ErrorIs(t, err, lifecycle.ErrShutdownTimeout)
+	})
+
+	t.Run("Closer errors are reported", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		errClose := errors.New("close failed")
+
+		manager := lifecycle.NewManager(time.Second).
+			WithLogger(logging.Discard()).
+			OnShutdown("db", lifecycle.Closer(closerFunc(func() error {
+				return errClose
+			})))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := manager.Run(ctx)
+		require.ErrorIs(t, err, errClose)
+		require.ErrorContains(t, err, "db")
+	})
+}
+
+func TestHTTPServer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inFlight := make(chan struct{})
+	finish := make(chan struct{})
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			close(inFlight)
+			<-finish
+			w.WriteHeader(http.StatusCreated)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	task := lifecycle.HTTPServer(server, func() error {
+		return server.Serve(listener)
+	}, time.Second)
+
+	taskErr := make(chan error, 1)
+
+	go func() {
+		taskErr <- task(ctx)
+	}()
+
+	status := make(chan int, 1)
+
+	go func() {
+		resp, err := http.Post("http://"+listener.Addr().String(), "application/json", nil) //nolint:noctx // test only
+		if err != nil {
+			status <- 0
+
+			return
+		}
+		defer resp.Body.Close()
+
+		status <- resp.StatusCode
+	}()
+
+	// shutting down while a request is in flight must wait for it to complete
+	<-inFlight
+	cancel()
+	close(finish)
+
+	require.Equal(t, http.StatusCreated, <-status)
+	require.NoError(t, <-taskErr)
+
+	http.DefaultClient.CloseIdleConnections()
+}
+
+func TestEvery(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+
+	task := lifecycle.Every(time.Millisecond, logging.Discard(), func(context.Context) error {
+		runs++
+		if runs == 3 {
+			cancel()
+		}
+
+		// failures must not stop the job
+		return errors.New("failed")
+	})
+
+	require.NoError(t, task(ctx))
+	require.Equal(t, 3, runs)
+}
@@ -0,0 +1,243 @@
+// Package lock elects a single owner for a named job across the instances sharing a Redis,
+// i.e. so only one worker runs the ledger check at a time however many are deployed.
+//
+// A lock expires unless it's renewed, so a crashed owner doesn't hold it forever. Each acquisition is given
+// a fencing token, greater than the ones before it, so the storages can reject the writes of an owner which
+// lost the lock without noticing, i.e. while it was paused past the expiry.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devshark/wallet/pkg/idgen"
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultPrefix namespaces the locks in Redis.
+const DefaultPrefix = "wallet:lock:"
+
+var (
+	ErrNotAcquired = errors.New("lock held by another owner")
+	ErrLockLost    = errors.New("lock lost to another owner")
+)
+
+//nolint:gochecknoglobals // stateless
+var (
+	// sets the lock if it's free, and draws the next token of its fence
+	acquireScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0`)
+
+	// extends the lock as long as it's still held by the owner
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`)
+
+	// deletes the lock as long as it's still held by the owner, not the one of the next owner
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+// Locker acquires the locks, it's implemented over redis.UniversalClient.
+type Locker struct {
+	client redis.Scripter
+	prefix string
+	logger *slog.Logger
+}
+
+func NewLocker(client redis.Scripter) *Locker {
+	return &Locker{
+		client: client,
+		prefix: DefaultPrefix,
+		logger: slog.Default(),
+	}
+}
+
+// WithPrefix namespaces the locks, i.e. for the deployments sharing a Redis without sharing their jobs.
+func (l *Locker) WithPrefix(prefix string) *Locker {
+	l.prefix = prefix
+
+	return l
+}
+
+func (l *Locker) WithLogger(logger *slog.Logger) *Locker {
+	l.logger = logger
+
+	return l
+}
+
+// Lock is held until it's released or its TTL passes without a renewal.
+type Lock struct {
+	client redis.Scripter
+	name   string
+	key    string
+	owner  string
+	token  int64
+	ttl    time.Duration
+}
+
+// Acquire takes the lock of the name for the TTL, or returns ErrNotAcquired if another owner holds it.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	// the braces keep the lock and its fence in the same slot of a cluster, as the scripts use both
+	key := l.prefix + "{" + name + "}"
+	owner := idgen.NewUUIDGenerator().NewID()
+
+	token, err := acquireScript.Run(ctx, l.client, []string{key, key + ":fence"}, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the lock %s: %w", name, err)
+	}
+
+	if token == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotAcquired, name)
+	}
+
+	return &Lock{
+		client: l.client,
+		name:   name,
+		key:    key,
+		owner:  owner,
+		token:  token,
+		ttl:    ttl,
+	}, nil
+}
+
+// Name is the name the lock was acquired with.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Token is the fencing token of the acquisition, greater than the tokens of the previous owners of the lock.
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Renew holds the lock for another TTL, or returns ErrLockLost if it expired and may be held by another owner.
+func (l *Lock) Renew(ctx context.Context) error {
+	renewed, err := renewScript.Run(ctx, l.client, []string{l.key}, l.owner, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to renew the lock %s: %w", l.name, err)
+	}
+
+	if renewed == 0 {
+		return fmt.Errorf("%w: %s", ErrLockLost, l.name)
+	}
+
+	return nil
+}
+
+// Release frees the lock for the other owners, or returns ErrLockLost if it had already expired.
+func (l *Lock) Release(ctx context.Context) error {
+	released, err := releaseScript.Run(ctx, l.client, []string{l.key}, l.owner).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to release the lock %s: %w", l.name, err)
+	}
+
+	if released == 0 {
+		return fmt.Errorf("%w: %s", ErrLockLost, l.name)
+	}
+
+	return nil
+}
+
+type lockKey struct{}
+
+// FromContext returns the lock held by the job of Exclusive, to pass its token along with the writes.
+func FromContext(ctx context.Context) (*Lock, bool) {
+	lock, ok := ctx.Value(lockKey{}).(*Lock)
+
+	return lock, ok
+}
+
+// Exclusive runs the job only if the lock of the name is acquired, so the runs of the other instances are skipped,
+// i.e. for the scheduler. The lock is renewed every third of its TTL while the job runs, and released afterwards.
+// The context of the job is cancelled once the lock is lost, and the run fails with ErrLockLost.
+func (l *Locker) Exclusive(name string, ttl time.Duration, job func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		lock, err := l.Acquire(ctx, name, ttl)
+		if errors.Is(err, ErrNotAcquired) {
+			l.logger.DebugContext(ctx, "skipped, the lock is held by another instance", slog.String("lock", name))
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		jobCtx, cancel := context.WithCancelCause(context.WithValue(ctx, lockKey{}, lock))
+		defer cancel(nil)
+
+		renewed := make(chan struct{})
+
+		go func() {
+			defer close(renewed)
+
+			l.keepAlive(jobCtx, lock, cancel)
+		}()
+
+		err = job(jobCtx)
+
+		cancel(nil)
+		<-renewed
+
+		// the lost locks are reported with the run
+		if cause := context.Cause(jobCtx); errors.Is(cause, ErrLockLost) {
+			return errors.Join(err, cause)
+		}
+
+		// the context may be cancelled by the shutdown, the lock must be freed regardless
+		if errRelease := lock.Release(context.WithoutCancel(ctx)); errRelease != nil {
+			// the next run waits for the lock to expire
+			l.logger.WarnContext(ctx, "failed to release the lock", slog.String("lock", name), slog.Any("error", errRelease))
+		}
+
+		return err
+	}
+}
+
+// keepAlive renews the lock until the job returns, and cancels the job once the lock is lost.
+// The renewals failing on Redis are retried until the lock would have expired.
+func (l *Locker) keepAlive(ctx context.Context, lock *Lock, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(lock.ttl / 3) //nolint:mnd // a couple of renewals may fail before the lock expires
+	defer ticker.Stop()
+
+	expiry := time.Now().Add(lock.ttl)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := lock.Renew(ctx)
+
+		switch {
+		case err == nil:
+			expiry = time.Now().Add(lock.ttl)
+		case errors.Is(err, ErrLockLost):
+			cancel(err)
+
+			return
+		case ctx.Err() != nil:
+			return
+		case time.Now().After(expiry):
+			cancel(fmt.Errorf("%w: %w", ErrLockLost, err))
+
+			return
+		default:
+			l.logger.WarnContext(ctx, "failed to renew the lock", slog.String("lock", lock.name), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/lock"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocker(t *testing.T) (*lock.Locker, *redis.Client) {
+	t.Helper()
+
+	addr, cleanup := wallettesting.SetupTestRedis(t)
+	t.Cleanup(cleanup)
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+
+	return lock.NewLocker(client), client
+}
+
+func TestLocker(t *testing.T) {
+	locker, client := newLocker(t)
+	ctx := context.Background()
+
+	first, err := locker.Acquire(ctx, "ledger-check", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, "ledger-check", first.Name())
+
+	_, err = locker.Acquire(ctx, "ledger-check", time.Minute)
+	require.ErrorIs(t, err, lock.ErrNotAcquired)
+
+	other, err := locker.Acquire(ctx, "erasures", time.Minute)
+	require.NoError(t, err, "the locks are independent")
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, first.Renew(ctx))
+	require.NoError(t, first.Release(ctx))
+	require.ErrorIs(t, first.Release(ctx), lock.ErrLockLost, "released already")
+
+	second, err := locker.Acquire(ctx, "ledger-check", time.Minute)
+	require.NoError(t, err)
+	require.Greater(t, second.Token(), first.Token(), "the fence only moves forward")
+
+	// the lock expires, and is acquired by another owner
+	require.NoError(t, client.Del(ctx, lock.DefaultPrefix+"{ledger-check}").Err())
+
+	third, err := locker.Acquire(ctx, "ledger-check", time.Minute)
+	require.NoError(t, err)
+	require.Greater(t, third.Token(), second.Token())
+
+	require.ErrorIs(t, second.Renew(ctx), lock.ErrLockLost)
+	require.ErrorIs(t, second.Release(ctx), lock.ErrLockLost)
+	require.NoError(t, third.Renew(ctx), "not released by the previous owner")
+}
+
+func TestLockerExclusive(t *testing.T) {
+	locker, client := newLocker(t)
+	ctx := context.Background()
+
+	t.Run("runs once at a time", func(t *testing.T) {
+		started, finish := make(chan struct{}), make(chan struct{})
+
+		job := locker.Exclusive("statements", time.Minute, func(ctx context.Context) error {
+			held, ok := lock.FromContext(ctx)
+			require.True(t, ok)
+			require.Positive(t, held.Token())
+
+			close(started)
+			<-finish
+
+			return nil
+		})
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- job(ctx)
+		}()
+
+		<-started
+
+		skipped := locker.Exclusive("statements", time.Minute, func(context.Context) error {
+			return errors.New("not expected to run")
+		})
+		require.NoError(t, skipped(ctx), "skipped while held")
+
+		close(finish)
+		require.NoError(t, <-done)
+
+		_, err := locker.Acquire(ctx, "statements", time.Minute)
+		require.NoError(t, err, "released after the run")
+	})
+
+	t.Run("cancels the job once the lock is lost", func(t *testing.T) {
+		job := locker.Exclusive("erasures", 300*time.Millisecond, func(ctx context.Context) error {
+			require.NoError(t, client.Del(ctx, lock.DefaultPrefix+"{erasures}").Err())
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("not cancelled")
+			}
+		})
+
+		require.ErrorIs(t, job(ctx), lock.ErrLockLost)
+	})
+
+	t.Run("renews the lock of the long runs", func(t *testing.T) {
+		job := locker.Exclusive("receipts", 300*time.Millisecond, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+
+			return ctx.Err()
+		})
+
+		require.NoError(t, job(ctx))
+	})
+}
@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RequestIDKey is the attribute correlating the log lines of a request, across the parts of the application.
+const RequestIDKey = "request_id"
+
+type attrsContextKey struct{}
+
+// WithAttrs returns a copy of ctx carrying the attributes, on top of those it already carries.
+// They're added to every record logged with the context, i.e. with InfoContext, by the loggers of New.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	current := Attrs(ctx)
+
+	merged := make([]slog.Attr, 0, len(current)+len(attrs))
+	merged = append(merged, current...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsContextKey{}, merged)
+}
+
+// Attrs returns the attributes carried by the context, if any.
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+
+	attrs, _ := ctx.Value(attrsContextKey{}).([]slog.Attr)
+
+	return attrs
+}
+
+// NewContextHandler wraps the handler, so the records logged with a context carry its attributes.
+// The loggers of New are already wrapped.
+func NewContextHandler(handler slog.Handler) slog.Handler {
+	if _, ok := handler.(*contextHandler); ok {
+		return handler
+	}
+
+	return &contextHandler{Handler: handler}
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, record) //nolint:wrapcheck // the handler's own error
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
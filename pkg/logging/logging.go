@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+var (
+	ErrInvalidLevel  = errors.New("invalid log level")
+	ErrInvalidFormat = errors.New("invalid log format")
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	// ComponentKey is the attribute used to tell apart the log lines of each part of the application.
+	ComponentKey = "component"
+)
+
+// New returns a structured logger writing to w.
+// The level is one of debug, info, warn or error, and the format is either text or json.
+// The records logged with a context carry its attributes, see WithAttrs.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatText, "":
+		return slog.New(NewContextHandler(slog.NewTextHandler(w, opts))), nil
+	case FormatJSON:
+		return slog.New(NewContextHandler(slog.NewJSONHandler(w, opts))), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidFormat, format)
+	}
+}
+
+// ParseLevel converts a level name into a slog level. An empty name defaults to info.
+func ParseLevel(level string) (slog.Level, error) {
+	if strings.TrimSpace(level) == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var lvl slog.Level
+
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("%w: %q", ErrInvalidLevel, level)
+	}
+
+	return lvl, nil
+}
+
+// Component returns a child logger tagged with the given component name.
+func Component(logger *slog.Logger, name string) *slog.Logger {
+	return logger.With(slog.String(ComponentKey, name))
+}
+
+// Discard returns a logger that drops every record, useful when the output is irrelevant.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,88 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("JSON output with component", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		logger, err := logging.New(buf, "info", "json")
+		require.NoError(t, err)
+
+		logging.Component(logger, "rest").Info("hello", slog.String("account", "acc1"))
+
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		require.Equal(t, "hello", line["msg"])
+		require.Equal(t, "INFO", line["level"])
+		require.Equal(t, "rest", line[logging.ComponentKey])
+		require.Equal(t, "acc1", line["account"])
+	})
+
+	t.Run("Text output", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		logger, err := logging.New(buf, "", "")
+		require.NoError(t, err)
+
+		logger.Info("hello")
+		require.Contains(t, buf.String(), "msg=hello")
+	})
+
+	t.Run("Level filters lower records", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		logger, err := logging.New(buf, "WARN", "text")
+		require.NoError(t, err)
+
+		logger.Info("ignored")
+		logger.Debug("ignored")
+		require.Empty(t, buf.String())
+
+		logger.Error("kept")
+		require.Contains(t, buf.String(), "kept")
+	})
+
+	t.Run("Invalid level", func(t *testing.T) {
+		_, err := logging.New(&bytes.Buffer{}, "verbose", "text")
+		require.ErrorIs(t, err, logging.ErrInvalidLevel)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		_, err := logging.New(&bytes.Buffer{}, "info", "xml")
+		require.ErrorIs(t, err, logging.ErrInvalidFormat)
+	})
+}
+
+func TestContextAttrs(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	logger, err := logging.New(buf, "info", "json")
+	require.NoError(t, err)
+
+	ctx := logging.WithAttrs(context.Background(), slog.String(logging.RequestIDKey, "req-1"))
+	ctx = logging.WithAttrs(ctx, slog.String("account", "acc1"))
+
+	logging.Component(logger, "repository").ErrorContext(ctx, "failed")
+
+	line := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "req-1", line[logging.RequestIDKey])
+	require.Equal(t, "acc1", line["account"])
+	require.Equal(t, "repository", line[logging.ComponentKey])
+
+	buf.Reset()
+	logger.Info("without context")
+	require.NotContains(t, buf.String(), logging.RequestIDKey)
+
+	require.Empty(t, logging.Attrs(context.Background()))
+}
@@ -0,0 +1,130 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/devshark/wallet/api"
+	"github.com/devshark/wallet/pkg/crypt"
+)
+
+// AdminKeyHeader carries the admin API key, as an alternative to the Authorization bearer token.
+const AdminKeyHeader = "X-Admin-Key"
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// operatorFingerprintBytes is the length of the operator ids, enough to tell the keys apart.
+const operatorFingerprintBytes = 6
+
+type operatorContextKey struct{}
+
+// Operator returns the id of the admin key authenticated by NewAPIKeyAuth, or an empty string.
+// The id is a fingerprint of the key's hash, so it can be logged and stored, and is stable until the key is rotated.
+func Operator(ctx context.Context) string {
+	operator, _ := ctx.Value(operatorContextKey{}).(string)
+
+	return operator
+}
+
+// OperatorID returns the id of the operator holding the key of the argon2id hash.
+func OperatorID(hash string) string {
+	digest := sha256.Sum256([]byte(hash))
+
+	return "key-" + hex.EncodeToString(digest[:operatorFingerprintBytes])
+}
+
+// ClientKeyID returns the id of the API key presented with the request, i.e. by a partner to the gateway in front
+// of the wallet, whether it's been checked or not, or an empty string. It's a fingerprint of the key, so it can be stored.
+func ClientKeyID(r *http.Request) string {
+	key := requestAPIKey(r)
+	if key == "" {
+		return ""
+	}
+
+	digest := sha256.Sum256([]byte(key))
+
+	return "key-" + hex.EncodeToString(digest[:operatorFingerprintBytes])
+}
+
+// APIKeyAuth only lets through the requests presenting a key that matches one of the argon2id hashes.
+type APIKeyAuth struct {
+	hashes []string
+
+	// argon2id is deliberately expensive, so the keys already verified are remembered by their digest,
+	// with their operator id, to keep a profiling session from burning a hash per request.
+	verified sync.Map
+}
+
+// NewAPIKeyAuth returns a middleware that rejects with 401 the requests without a valid admin key.
+// The hashes are produced by crypt.HashAPIKey. With no hashes, every request is rejected.
+func NewAPIKeyAuth(hashes []string) Middleware {
+	auth := &APIKeyAuth{
+		hashes: hashes,
+	}
+
+	return auth.Wrap
+}
+
+func (a *APIKeyAuth) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		operator, ok := a.authorize(requestAPIKey(r))
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+
+			// nothing to do if it fails, the status has already been sent
+			_ = json.NewEncoder(w).Encode(api.ErrorResponse{
+				ErrorCode: http.StatusUnauthorized,
+				Code:      api.CodeUnauthorized,
+				Message:   ErrUnauthorized.Error(),
+			})
+
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), operatorContextKey{}, operator)))
+	}
+}
+
+// authorize returns the operator id of the key, if it matches one of the hashes.
+func (a *APIKeyAuth) authorize(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	digest := sha256.Sum256([]byte(key))
+	if operator, ok := a.verified.Load(digest); ok {
+		return operator.(string), true //nolint:forcetypeassert // always an operator id
+	}
+
+	for _, hash := range a.hashes {
+		// a malformed hash is treated as a mismatch, the config is validated at startup
+		if ok, err := crypt.VerifyAPIKey(key, hash); err == nil && ok {
+			operator := OperatorID(hash)
+			a.verified.Store(digest, operator)
+
+			return operator, true
+		}
+	}
+
+	return "", false
+}
+
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(AdminKeyHeader); key != "" {
+		return key
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
@@ -0,0 +1,99 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/crypt"
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	key, err := crypt.GenerateAPIKey()
+	require.NoError(t, err)
+
+	hash, err := crypt.HashAPIKey(key)
+	require.NoError(t, err)
+
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		// the operator is the same whether the key was just verified or remembered
+		require.Equal(t, middlewares.OperatorID(hash), middlewares.Operator(r.Context()))
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	auth := middlewares.NewAPIKeyAuth([]string{"not-a-hash", hash})(handler)
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected int
+	}{
+		{"No key", "", "", http.StatusUnauthorized},
+		{"Wrong key", middlewares.AdminKeyHeader, "wk_wrong", http.StatusUnauthorized},
+		{"Not a bearer token", "Authorization", "Basic " + key, http.StatusUnauthorized},
+		{"Admin key header", middlewares.AdminKeyHeader, key, http.StatusOK},
+		{"Bearer token", "Authorization", "Bearer " + key, http.StatusOK},
+		{"Verified key", middlewares.AdminKeyHeader, key, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rec := httptest.NewRecorder()
+			auth.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expected, rec.Code)
+
+			if tt.expected == http.StatusUnauthorized {
+				require.JSONEq(t, `{"error_code":401,"code":"UNAUTHORIZED","message":"unauthorized"}`, rec.Body.String())
+			}
+		})
+	}
+
+	require.Equal(t, 3, calls)
+
+	t.Run("No hashes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		req.Header.Set(middlewares.AdminKeyHeader, key)
+
+		rec := httptest.NewRecorder()
+		middlewares.NewAPIKeyAuth(nil)(handler).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Operator ids", func(t *testing.T) {
+		otherHash, err := crypt.HashAPIKey(key)
+		require.NoError(t, err)
+
+		require.Equal(t, middlewares.OperatorID(hash), middlewares.OperatorID(hash))
+		require.NotEqual(t, middlewares.OperatorID(hash), middlewares.OperatorID(otherHash))
+		require.Empty(t, middlewares.Operator(context.Background()))
+	})
+}
+
+func TestClientKeyID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/transfer", nil)
+	require.Empty(t, middlewares.ClientKeyID(req))
+
+	req.Header.Set("Authorization", "Bearer partner-key")
+	id := middlewares.ClientKeyID(req)
+	require.Regexp(t, `^key-[0-9a-f]{12}$`, id)
+	require.NotContains(t, id, "partner-key")
+
+	other := httptest.NewRequest(http.MethodPost, "/transfer", nil)
+	other.Header.Set(middlewares.AdminKeyHeader, "partner-key")
+	require.Equal(t, id, middlewares.ClientKeyID(other), "the same key whatever the header")
+}
@@ -3,20 +3,97 @@ package middlewares
 import (
 	"bytes"
 	"context"
+	"errors"
+	"expvar"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/devshark/wallet/pkg/logging"
 	"github.com/go-redis/redis/v8"
 )
 
+// The counters of the cache metrics, see cacheMetrics.
+const (
+	cacheHits        = "hits"
+	cacheMisses      = "misses"
+	cacheErrors      = "errors"
+	cacheStoredBytes = "stored_bytes"
+)
+
+//nolint:gochecknoglobals // expvar panics when a name is published twice
+var (
+	cacheMetricsOnce sync.Once
+	cacheMetricsMap  *expvar.Map
+)
+
+// cacheMetrics is the cache expvar variable, served under /debug/vars: the hits and the misses of the cached responses,
+// the errors of Redis, whose requests are served without the cache, and the bytes of the responses stored, i.e.
+// {"hits": 90, "misses": 10, "errors": 0, "stored_bytes": 4096}, shared by the middlewares.
+func cacheMetrics() *expvar.Map {
+	cacheMetricsOnce.Do(func() {
+		cacheMetricsMap = expvar.NewMap("cache")
+
+		// published from the start, so the rates can be computed before the first request
+		for _, name := range []string{cacheHits, cacheMisses, cacheErrors, cacheStoredBytes} {
+			cacheMetricsMap.Add(name, 0)
+		}
+	})
+
+	return cacheMetricsMap
+}
+
+// DefaultCacheTTL is how long the responses are cached without WithCacheTTL.
+const DefaultCacheTTL = 5 * time.Minute
+
 type RedisCacheMiddleware struct {
 	client      GetterAndSetter
 	nextHandler http.Handler
-	expiration  time.Duration
-	logger      *log.Logger
+	options     *cacheOptions
+}
+
+type cacheOptions struct {
+	logger *slog.Logger
+	key    func(r *http.Request) string
+	ttl    time.Duration
+}
+
+// CacheOption configures NewRedisCacheMiddleware.
+type CacheOption func(*cacheOptions)
+
+// WithCacheLogger logs the failures of the cache, with the default logger tagged with the cache component otherwise.
+func WithCacheLogger(logger *slog.Logger) CacheOption {
+	return func(o *cacheOptions) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithCacheKey derives the key of the cached responses from the requests, their URI by default.
+// InvalidateCache matches the keys, so its patterns follow the same scheme.
+func WithCacheKey(key func(r *http.Request) string) CacheOption {
+	return func(o *cacheOptions) {
+		if key != nil {
+			o.key = key
+		}
+	}
+}
+
+// WithCacheTTL sets how long the responses are cached, DefaultCacheTTL if it isn't positive.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
+
+// requestURI is the default key of the cached responses.
+func requestURI(r *http.Request) string {
+	return r.URL.String()
 }
 
 type GetterAndSetter interface {
@@ -24,22 +101,43 @@ type GetterAndSetter interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 }
 
-func NewRedisCacheMiddleware(client GetterAndSetter, expiration time.Duration) func(http.HandlerFunc) http.HandlerFunc {
+// ScannerAndDeleter is the Redis client of InvalidateCache, reading the payloads it matches before deleting them.
+type ScannerAndDeleter interface {
+	Scan(ctx context.Context, cursor uint64, match string, count int64) *redis.ScanCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// the keys scanned per call, so the invalidations don't block the Redis
+const invalidationBatchSize = 100
+
+// NewRedisCacheMiddleware returns a middleware caching the successful responses of the GET requests in Redis.
+// The failures of Redis or of the client are logged and counted, the requests are then served without the cache.
+func NewRedisCacheMiddleware(client GetterAndSetter, opts ...CacheOption) Middleware {
+	options := &cacheOptions{
+		logger: logging.Component(slog.Default(), "cache"),
+		key:    requestURI,
+		ttl:    DefaultCacheTTL,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// published with the first middleware
+	cacheMetrics()
+
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		obj := &RedisCacheMiddleware{
 			client:      client,
 			nextHandler: next,
-			expiration:  expiration,
+			options:     options,
 		}
 
 		return obj.serveHTTP
 	}
 }
 
-func (m *RedisCacheMiddleware) WithLogger(logger *log.Logger) {
-	m.logger = logger
-}
-
 func (m *RedisCacheMiddleware) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	// Only cache GET requests
 	if r.Method != http.MethodGet {
@@ -48,59 +146,134 @@ func (m *RedisCacheMiddleware) serveHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	key := r.URL.String()
+	key := m.options.key(r)
 	ctx := r.Context()
+	metrics := cacheMetrics()
 
 	// Try to get the cached response
 	cachedResponse, err := m.client.Get(ctx, key).Bytes()
-	if err == nil {
+
+	switch {
+	case err == nil:
+		metrics.Add(cacheHits, 1)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("X-Cache", "HIT")
 
-		_, err := w.Write(cachedResponse)
-		if err != nil {
+		if _, err := w.Write(cachedResponse); err != nil {
 			// we can't respond with an error payload anymore, because the headers have already been sent
 			// headers must be written before the content, so if writing the content fails, we can't go back
 			// just log it
-			m.logger.Printf("encoding error: %v", err)
+			m.options.logger.ErrorContext(ctx, "encoding error", slog.Any("error", err))
 		}
 
 		return
+	case errors.Is(err, redis.Nil):
+		metrics.Add(cacheMisses, 1)
+	default:
+		// Redis being down is reported by the readiness probe, so it's only logged at debug level
+		metrics.Add(cacheErrors, 1)
+		m.options.logger.DebugContext(ctx, "failed to read from the cache", slog.Any("error", err))
 	}
 
 	// Cache miss: capture the response
 	buf := &bytes.Buffer{}
-	writer := io.MultiWriter(w, buf)
-	wrappedWriter := wrapResponseWriter(w, writer)
+	wrappedWriter := wrapResponseWriter(w, buf)
 
 	m.nextHandler.ServeHTTP(wrappedWriter, r)
 
-	// Cache the response
-	if wrappedWriter.statusCode == http.StatusOK {
-		m.client.Set(ctx, key, buf.Bytes(), m.expiration)
+	// the responses cut short by the client aren't cached
+	if wrappedWriter.statusCode != http.StatusOK || wrappedWriter.failed {
+		return
 	}
+
+	if err := m.client.Set(ctx, key, buf.Bytes(), m.options.ttl).Err(); err != nil {
+		metrics.Add(cacheErrors, 1)
+		m.options.logger.DebugContext(ctx, "failed to write to the cache", slog.Any("error", err))
+
+		return
+	}
+
+	metrics.Add(cacheStoredBytes, int64(buf.Len()))
 }
 
+// responseWriterWrapper captures the response written to the client, unless writing it fails.
 type responseWriterWrapper struct {
 	http.ResponseWriter
-	writer     io.Writer
+	buffer     *bytes.Buffer
 	statusCode int
+	failed     bool
 }
 
-func wrapResponseWriter(w http.ResponseWriter, writer io.Writer) *responseWriterWrapper {
-	return &responseWriterWrapper{ResponseWriter: w, writer: writer, statusCode: http.StatusOK}
+func wrapResponseWriter(w http.ResponseWriter, buffer *bytes.Buffer) *responseWriterWrapper {
+	return &responseWriterWrapper{ResponseWriter: w, buffer: buffer, statusCode: http.StatusOK}
 }
 
 func (rww *responseWriterWrapper) Write(data []byte) (int, error) {
-	code, err := rww.writer.Write(data)
+	n, err := rww.ResponseWriter.Write(data)
 	if err != nil {
-		return code, fmt.Errorf("cache write error: %w", err)
+		rww.failed = true
+
+		return n, fmt.Errorf("cache write error: %w", err)
 	}
 
-	return code, nil
+	// never fails
+	_, _ = rww.buffer.Write(data[:n])
+
+	return n, nil
 }
 
 func (rww *responseWriterWrapper) WriteHeader(statusCode int) {
 	rww.statusCode = statusCode
 	rww.ResponseWriter.WriteHeader(statusCode)
 }
+
+// InvalidateCache deletes the responses cached by NewRedisCacheMiddleware whose request URI, i.e. /transactions/tx1?tz=UTC,
+// matches the Redis glob pattern, and whose payload satisfies match unless it's nil. It returns how many were deleted.
+// The keys are scanned, so the cache is still served meanwhile, and the responses cached during the scan may be kept.
+func InvalidateCache(ctx context.Context, client ScannerAndDeleter, pattern string, match func(payload []byte) bool) (int64, error) {
+	var (
+		deleted int64
+		cursor  uint64
+	)
+
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, invalidationBatchSize).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to scan the cache: %w", err)
+		}
+
+		if match != nil {
+			keys = matchingKeys(ctx, client, keys, match)
+		}
+
+		if len(keys) > 0 {
+			count, err := client.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, fmt.Errorf("failed to delete from the cache: %w", err)
+			}
+
+			deleted += count
+		}
+
+		if next == 0 {
+			return deleted, nil
+		}
+
+		cursor = next
+	}
+}
+
+// matchingKeys keeps the keys whose payload satisfies match, the ones expired since the scan are skipped.
+func matchingKeys(ctx context.Context, client ScannerAndDeleter, keys []string, match func(payload []byte) bool) []string {
+	matching := keys[:0]
+
+	for _, key := range keys {
+		payload, err := client.Get(ctx, key).Bytes()
+		if err == nil && match(payload) {
+			matching = append(matching, key)
+		}
+	}
+
+	return matching
+}
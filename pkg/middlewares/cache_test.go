@@ -1,13 +1,19 @@
 package middlewares_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/devshark/wallet/pkg/logging"
 	"github.com/devshark/wallet/pkg/middlewares"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,7 +32,7 @@ func TestRedisCacheMiddleware(t *testing.T) {
 			require.NoError(t, err)
 		})
 
-		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, 5*time.Minute)
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheTTL(5*time.Minute))
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		rec := httptest.NewRecorder()
 
@@ -48,7 +54,7 @@ func TestRedisCacheMiddleware(t *testing.T) {
 			t.Fatal("Handler should not be called on cache hit")
 		})
 
-		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, 5*time.Minute)
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheTTL(5*time.Minute))
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
 		rec := httptest.NewRecorder()
 
@@ -71,7 +77,7 @@ func TestRedisCacheMiddleware(t *testing.T) {
 			require.NoError(t, err)
 		})
 
-		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, 5*time.Minute)
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheTTL(5*time.Minute))
 		req := httptest.NewRequest(http.MethodPost, "/test", nil)
 		rec := httptest.NewRecorder()
 
@@ -86,3 +92,211 @@ func TestRedisCacheMiddleware(t *testing.T) {
 		mockRedis.AssertNotCalled(t, "Set")
 	})
 }
+
+// failingWriter is a client gone before the response is written.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRedisCacheMiddlewareOptions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tx_id":"tx1"}`))
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "tx:/transactions/tx1").Return(redis.NewStringResult("", redis.Nil))
+		mockRedis.On("Set", mock.Anything, "tx:/transactions/tx1", mock.Anything, middlewares.DefaultCacheTTL).Return(redis.NewStatusResult("OK", nil))
+
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheKey(func(r *http.Request) string {
+			return "tx:" + r.URL.Path
+		}))
+
+		rec := httptest.NewRecorder()
+		middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/tx1?tz=UTC", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Client gone on a hit", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult(`{"tx_id":"tx1"}`, nil))
+
+		// the default logger logs the failure
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis)
+
+		require.NotPanics(t, func() {
+			middleware(handler).ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/transactions/tx1", nil))
+		})
+	})
+
+	t.Run("Client gone on a miss", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult("", redis.Nil))
+
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheLogger(logging.Discard()))
+		middleware(handler).ServeHTTP(failingWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/transactions/tx1", nil))
+
+		mockRedis.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Redis down", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult("", redis.ErrClosed))
+		mockRedis.On("Set", mock.Anything, "/transactions/tx1", mock.Anything, middlewares.DefaultCacheTTL).Return(redis.NewStatusResult("", redis.ErrClosed))
+
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheLogger(nil), middlewares.WithCacheTTL(0))
+
+		rec := httptest.NewRecorder()
+		middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions/tx1", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, "served without the cache")
+		require.JSONEq(t, `{"tx_id":"tx1"}`, rec.Body.String())
+	})
+}
+
+// cacheCounter reads a counter of the cache expvar variable, shared by the tests.
+func cacheCounter(t *testing.T, name string) int64 {
+	t.Helper()
+
+	metrics, ok := expvar.Get("cache").(*expvar.Map)
+	require.True(t, ok, "published with the first middleware")
+
+	counter, ok := metrics.Get(name).(*expvar.Int)
+	require.True(t, ok)
+
+	return counter.Value()
+}
+
+func TestRedisCacheMiddlewareMetrics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"tx_id":"tx1"}`))
+	})
+
+	serve := func(mockRedis *middlewares.MockGetterAndSetter) {
+		middleware := middlewares.NewRedisCacheMiddleware(mockRedis, middlewares.WithCacheTTL(time.Minute))
+		middleware(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/transactions/tx1", nil))
+	}
+
+	counters := func() map[string]int64 {
+		return map[string]int64{
+			"hits":         cacheCounter(t, "hits"),
+			"misses":       cacheCounter(t, "misses"),
+			"errors":       cacheCounter(t, "errors"),
+			"stored_bytes": cacheCounter(t, "stored_bytes"),
+		}
+	}
+
+	// the counters are shared by the middlewares, so only their increments are checked
+	increments := func(before map[string]int64) map[string]int64 {
+		after := counters()
+		for name := range after {
+			after[name] -= before[name]
+		}
+
+		return after
+	}
+
+	// publishes the variable
+	middlewares.NewRedisCacheMiddleware(middlewares.NewMockGetterAndSetter(t))
+
+	t.Run("Miss", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult("", redis.Nil))
+		mockRedis.On("Set", mock.Anything, "/transactions/tx1", mock.Anything, time.Minute).Return(redis.NewStatusResult("OK", nil))
+
+		before := counters()
+		serve(mockRedis)
+
+		require.Equal(t, map[string]int64{"hits": 0, "misses": 1, "errors": 0, "stored_bytes": 15}, increments(before))
+	})
+
+	t.Run("Hit", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult(`{"tx_id":"tx1"}`, nil))
+
+		before := counters()
+		serve(mockRedis)
+
+		require.Equal(t, map[string]int64{"hits": 1, "misses": 0, "errors": 0, "stored_bytes": 0}, increments(before))
+	})
+
+	t.Run("Redis down", func(t *testing.T) {
+		mockRedis := middlewares.NewMockGetterAndSetter(t)
+		mockRedis.On("Get", mock.Anything, "/transactions/tx1").Return(redis.NewStringResult("", redis.ErrClosed))
+		mockRedis.On("Set", mock.Anything, "/transactions/tx1", mock.Anything, time.Minute).Return(redis.NewStatusResult("", redis.ErrClosed))
+
+		before := counters()
+		serve(mockRedis)
+
+		require.Equal(t, map[string]int64{"hits": 0, "misses": 0, "errors": 2, "stored_bytes": 0}, increments(before))
+	})
+}
+
+func TestRedisCacheMiddlewareWithRedis(t *testing.T) {
+	addr, cleanup := wallettesting.SetupTestRedis(t)
+	defer cleanup()
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+
+		w.WriteHeader(http.StatusOK)
+
+		err := json.NewEncoder(w).Encode(map[string]int{"calls": calls})
+		require.NoError(t, err)
+	})
+
+	middleware := middlewares.NewRedisCacheMiddleware(client, middlewares.WithCacheTTL(time.Minute))
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/transactions/tx1", nil)
+		rec := httptest.NewRecorder()
+
+		middleware(handler).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.JSONEq(t, `{"calls":1}`, rec.Body.String())
+	}
+
+	require.Equal(t, 1, calls)
+}
+
+func TestInvalidateCache(t *testing.T) {
+	addr, cleanup := wallettesting.SetupTestRedis(t)
+	defer cleanup()
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	ctx := context.Background()
+
+	for i := range 250 {
+		require.NoError(t, client.Set(ctx, fmt.Sprintf("/transactions/tx%d", i), fmt.Sprintf(`{"tx_id":"tx%d"}`, i), time.Minute).Err())
+	}
+
+	require.NoError(t, client.Set(ctx, "wallet:read_only", "1", 0).Err())
+
+	deleted, err := middlewares.InvalidateCache(ctx, client, "/transactions/tx1*", nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 111, deleted, "tx1, tx10 to tx19 and tx100 to tx199")
+
+	deleted, err = middlewares.InvalidateCache(ctx, client, "/transactions/*", func(payload []byte) bool {
+		return string(payload) == `{"tx_id":"tx2"}`
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted, "across the scanned batches")
+
+	deleted, err = middlewares.InvalidateCache(ctx, client, "/*", nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 138, deleted)
+
+	require.NoError(t, client.Get(ctx, "wallet:read_only").Err(), "outside the cache")
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/devshark/wallet/pkg/msgpack"
+)
+
+// NewMessagePack returns a middleware that responds in MessagePack to the clients preferring it to JSON in their
+// Accept header, transcoding the JSON responses of the handlers once they're written. The other responses,
+// i.e. the event streams and the downloads, are sent as they're written, whatever the client accepts.
+func NewMessagePack() Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			if !prefersMessagePack(r.Header.Values("Accept")) {
+				next(w, r)
+
+				return
+			}
+
+			writer := &messagePackWriter{ResponseWriter: w}
+			next(writer, r)
+			writer.finish()
+		}
+	}
+}
+
+// prefersMessagePack tells whether the media ranges accept MessagePack with a quality of JSON's at least.
+func prefersMessagePack(accept []string) bool {
+	var msgpackQuality, jsonQuality float64
+
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+				quality = q
+			}
+
+			switch mediaType {
+			case msgpack.ContentType, "application/x-msgpack":
+				msgpackQuality = max(msgpackQuality, quality)
+			case "application/json", "application/*", "*/*":
+				jsonQuality = max(jsonQuality, quality)
+			}
+		}
+	}
+
+	return msgpackQuality > 0 && msgpackQuality >= jsonQuality
+}
+
+// messagePackWriter buffers the JSON responses to transcode them, and passes the others through.
+type messagePackWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	// buffer holds the JSON response, nil for the ones passed through
+	buffer *bytes.Buffer
+}
+
+func (w *messagePackWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = status
+
+	if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "application/json" {
+		w.buffer = &bytes.Buffer{}
+
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *messagePackWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buffer != nil {
+		return w.buffer.Write(b) //nolint:wrapcheck // never fails
+	}
+
+	return w.ResponseWriter.Write(b) //nolint:wrapcheck // as the handler would get it
+}
+
+// Flush holds the JSON responses until they're complete, and flushes the others.
+func (w *messagePackWriter) Flush() {
+	if w.buffer != nil {
+		return
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the deadlines of the connection.
+func (w *messagePackWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish sends the buffered JSON response in MessagePack, or as is if it isn't a valid JSON document.
+func (w *messagePackWriter) finish() {
+	if w.buffer == nil {
+		return
+	}
+
+	body := w.buffer.Bytes()
+
+	if len(body) > 0 {
+		if encoded, err := msgpack.FromJSON(body); err == nil {
+			body = encoded
+
+			w.Header().Set("Content-Type", msgpack.ContentType)
+		}
+	}
+
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	// the client is gone when it fails
+	_, _ = w.ResponseWriter.Write(body)
+}
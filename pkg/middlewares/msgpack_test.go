@@ -0,0 +1,79 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/devshark/wallet/pkg/msgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagePack(t *testing.T) {
+	handler := middlewares.NewMessagePack()(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("data: {}\n\n"))
+			w.(http.Flusher).Flush()
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"tx_id":"tx1",`))
+		// the flushes of the JSON responses are held until they're complete
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(`"amount":"10.5"}`))
+	})
+
+	serve := func(path string, accept ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, value := range accept {
+			req.Header.Add("Accept", value)
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		return rec
+	}
+
+	t.Run("MessagePack", func(t *testing.T) {
+		rec := serve("/", "application/msgpack, application/json;q=0.5")
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Equal(t, msgpack.ContentType, rec.Header().Get("Content-Type"))
+		require.Equal(t, "Accept", rec.Header().Get("Vary"))
+
+		var decoded map[string]string
+		require.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &decoded))
+		require.Equal(t, map[string]string{"tx_id": "tx1", "amount": "10.5"}, decoded)
+	})
+
+	json := map[string][]string{
+		"No Accept":      nil,
+		"JSON":           {"application/json"},
+		"Any":            {"*/*"},
+		"JSON preferred": {"application/json", "application/msgpack;q=0.5"},
+		"Refused":        {"application/msgpack;q=0"},
+		"Malformed":      {"application/msgpack;;"},
+	}
+
+	for name, accept := range json {
+		t.Run(name, func(t *testing.T) {
+			rec := serve("/", accept...)
+			require.Equal(t, http.StatusCreated, rec.Code)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.JSONEq(t, `{"tx_id":"tx1","amount":"10.5"}`, rec.Body.String())
+		})
+	}
+
+	t.Run("Event stream", func(t *testing.T) {
+		rec := serve("/events", "application/msgpack")
+		require.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+		require.Equal(t, "data: {}\n\n", rec.Body.String())
+		require.True(t, rec.Flushed)
+	})
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/devshark/wallet/pkg/idgen"
+	"github.com/devshark/wallet/pkg/logging"
+)
+
+// RequestIDHeader carries the id of the request, set by the clients or the proxies, and echoed in the response.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength keeps the ids supplied by the clients from bloating the log lines.
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// RequestID returns the id assigned to the request by NewRequestID, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+
+	return id
+}
+
+// NewRequestID returns a middleware that assigns an id to every request, the one in the X-Request-Id header
+// if it's valid, or a new one. The id is echoed in the response, and carried by the context of the request,
+// so every line logged with it, down to the repository, can be tied back to the request.
+func NewRequestID(generator idgen.Generator) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = generator.NewID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey{}, id)
+			ctx = logging.WithAttrs(ctx, slog.String(logging.RequestIDKey, id))
+
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// validRequestID only accepts the printable ASCII ids, so they can't forge log lines nor response headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/middlewares"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedID string
+
+func (id fixedID) NewID() string {
+	return string(id)
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+
+	var attrs int
+
+	handler := middlewares.NewRequestID(fixedID("generated"))(func(w http.ResponseWriter, r *http.Request) {
+		seen = middlewares.RequestID(r.Context())
+		attrs = len(logging.Attrs(r.Context()))
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set(middlewares.RequestIDHeader, id)
+		}
+
+		res := httptest.NewRecorder()
+		handler(res, req)
+
+		return res
+	}
+
+	t.Run("Generated", func(t *testing.T) {
+		res := serve("")
+		require.Equal(t, "generated", res.Header().Get(middlewares.RequestIDHeader))
+		require.Equal(t, "generated", seen)
+		require.Equal(t, 1, attrs, "carried for the logs")
+	})
+
+	t.Run("Supplied", func(t *testing.T) {
+		res := serve("edge-42")
+		require.Equal(t, "edge-42", res.Header().Get(middlewares.RequestIDHeader))
+		require.Equal(t, "edge-42", seen)
+	})
+
+	t.Run("Invalid replaced", func(t *testing.T) {
+		require.Equal(t, "generated", serve(strings.Repeat("x", 129)).Header().Get(middlewares.RequestIDHeader))
+		require.Equal(t, "generated", serve("a b").Header().Get(middlewares.RequestIDHeader))
+	})
+}
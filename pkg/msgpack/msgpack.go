@@ -0,0 +1,406 @@
+// Package msgpack transcodes the JSON documents to MessagePack and back, so the payloads keep the shape and
+// the field names of their JSON encoding, i.e. the decimals as strings, while being smaller to send.
+// It covers the MessagePack types the JSON values map to: nil, booleans, integers, floats, strings, arrays
+// and maps with string keys. The binaries are decoded as base64 strings, like the JSON encoding of []byte,
+// and the extension types are rejected.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ContentType is the media type of the MessagePack payloads.
+const ContentType = "application/msgpack"
+
+// maxDepth bounds the nesting of the decoded documents, so a malformed payload can't exhaust the stack.
+const maxDepth = 1000
+
+var (
+	ErrTruncated   = errors.New("msgpack: unexpected end of data")
+	ErrUnsupported = errors.New("msgpack: unsupported type")
+	ErrTooDeep     = errors.New("msgpack: document nested too deeply")
+)
+
+// Marshal encodes v as its JSON encoding would be, in MessagePack.
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+
+	return FromJSON(data)
+}
+
+// Unmarshal decodes the MessagePack data into v, as if it were decoded from JSON.
+func Unmarshal(data []byte, v any) error {
+	document, err := ToJSON(data)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(document, v); err != nil {
+		return fmt.Errorf("msgpack: %w", err)
+	}
+
+	return nil
+}
+
+// FromJSON transcodes a JSON document to MessagePack. The numbers are encoded as the smallest integers that hold them,
+// or as 64-bit floats, and the keys of the objects are sorted.
+func FromJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.Grow(len(data))
+
+	if err := encode(buffer, value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ToJSON transcodes a MessagePack document to JSON.
+func ToJSON(data []byte) ([]byte, error) {
+	d := &decoder{data: data}
+
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.offset != len(d.data) {
+		return nil, fmt.Errorf("msgpack: %d bytes after the document", len(d.data)-d.offset)
+	}
+
+	document, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+
+	return document, nil
+}
+
+func encode(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if v {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case json.Number:
+		return encodeNumber(buffer, v)
+	case string:
+		encodeString(buffer, v)
+	case []any:
+		encodeLength(buffer, len(v), 0x90, 0xdc, 0xdd)
+
+		for _, item := range v {
+			if err := encode(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		encodeLength(buffer, len(v), 0x80, 0xde, 0xdf)
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			encodeString(buffer, key)
+
+			if err := encode(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupported, value)
+	}
+
+	return nil
+}
+
+func encodeNumber(buffer *bytes.Buffer, number json.Number) error {
+	if i, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		encodeInt(buffer, i)
+
+		return nil
+	}
+
+	// above the int64, below the uint64
+	if u, err := strconv.ParseUint(string(number), 10, 64); err == nil {
+		buffer.WriteByte(0xcf)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, u))
+
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(string(number), 64)
+	if err != nil {
+		return fmt.Errorf("msgpack: %w", err)
+	}
+
+	buffer.WriteByte(0xcb)
+	buffer.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+
+	return nil
+}
+
+func encodeInt(buffer *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buffer.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buffer.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		buffer.WriteByte(0xcc)
+		buffer.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= 0 && i <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i >= 0:
+		buffer.WriteByte(0xcf)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case i >= math.MinInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	default:
+		buffer.WriteByte(0xd3)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+func encodeString(buffer *bytes.Buffer, s string) {
+	if len(s) <= 31 {
+		buffer.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		encodeLength(buffer, len(s), 0, 0xda, 0xdb)
+	}
+
+	buffer.WriteString(s)
+}
+
+// encodeLength writes the header of a string, an array or a map, in its fixed format when it has one and the length fits.
+func encodeLength(buffer *bytes.Buffer, length int, fixed, format16, format32 byte) {
+	switch {
+	case fixed != 0 && length <= 15:
+		buffer.WriteByte(fixed | byte(length))
+	case fixed == 0 && length <= math.MaxUint8:
+		// only the strings have an 8-bit length
+		buffer.WriteByte(0xd9)
+		buffer.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buffer.WriteByte(format16)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	default:
+		buffer.WriteByte(format32)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(length)))
+	}
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.offset < n {
+		return nil, ErrTruncated
+	}
+
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+
+	return b, nil
+}
+
+// readUint reads a big-endian unsigned integer of n bytes.
+func (d *decoder) readUint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+
+	return u, nil
+}
+
+//nolint:cyclop,gocyclo // one case per format
+func (d *decoder) decode(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+
+	head, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	format := head[0]
+
+	switch {
+	case format <= 0x7f:
+		return int64(format), nil
+	case format >= 0xe0:
+		return int64(int8(format)), nil
+	case format&0xf0 == 0x80:
+		return d.decodeMap(int(format&0x0f), depth)
+	case format&0xf0 == 0x90:
+		return d.decodeArray(int(format&0x0f), depth)
+	case format&0xe0 == 0xa0:
+		return d.decodeString(int(format & 0x1f))
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := d.readUint(1 << (format - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+
+		// a copy, the documents may outlive the data
+		b, err := d.read(int(length))
+
+		return bytes.Clone(b), err
+	case 0xca:
+		bits, err := d.readUint(4)
+
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := d.readUint(8)
+
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce:
+		u, err := d.readUint(1 << (format - 0xcc))
+
+		return int64(u), err
+	case 0xcf:
+		return d.readUint(8)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (format - 0xd0)
+
+		u, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+
+		// sign-extends the integer from its size
+		shift := 64 - 8*size
+
+		return int64(u<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		length, err := d.readUint(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeString(int(length))
+	case 0xdc, 0xdd:
+		length, err := d.readUint(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeArray(int(length), depth)
+	case 0xde, 0xdf:
+		length, err := d.readUint(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.decodeMap(int(length), depth)
+	}
+
+	return nil, fmt.Errorf("%w: format 0x%02x", ErrUnsupported, format)
+}
+
+func (d *decoder) decodeString(length int) (string, error) {
+	b, err := d.read(length)
+
+	return string(b), err
+}
+
+func (d *decoder) decodeArray(length int, depth int) ([]any, error) {
+	// every item takes a byte at least, so a forged length can't allocate more than the data
+	if length > len(d.data)-d.offset {
+		return nil, ErrTruncated
+	}
+
+	items := make([]any, length)
+
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = item
+	}
+
+	return items, nil
+}
+
+func (d *decoder) decodeMap(length int, depth int) (map[string]any, error) {
+	if length > len(d.data)-d.offset {
+		return nil, ErrTruncated
+	}
+
+	entries := make(map[string]any, length)
+
+	for range length {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T key", ErrUnsupported, key)
+		}
+
+		if entries[name], err = d.decode(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+package msgpack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devshark/wallet/pkg/msgpack"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromJSON(t *testing.T) {
+	cases := map[string]struct {
+		json     string
+		msgpack  []byte
+		readBack string
+	}{
+		"Object":          {json: `{"b":[1,-1],"a":"x"}`, msgpack: []byte{0x82, 0xa1, 'a', 0xa1, 'x', 0xa1, 'b', 0x92, 0x01, 0xff}, readBack: `{"a":"x","b":[1,-1]}`},
+		"Null and bools":  {json: `[null,true,false]`, msgpack: []byte{0x93, 0xc0, 0xc3, 0xc2}},
+		"Unsigned 8":      {json: `200`, msgpack: []byte{0xcc, 0xc8}},
+		"Signed 16":       {json: `-1000`, msgpack: []byte{0xd1, 0xfc, 0x18}},
+		"Above int64":     {json: `18446744073709551615`, msgpack: []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		"Float":           {json: `0.5`, msgpack: []byte{0xcb, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		"Decimal string":  {json: `"10.25"`, msgpack: append([]byte{0xa5}, "10.25"...)},
+		"Long string":     {json: `"` + strings.Repeat("a", 40) + `"`, msgpack: append([]byte{0xd9, 40}, strings.Repeat("a", 40)...)},
+		"Large array":     {json: `[` + strings.Repeat("0,", 15) + `0]`, msgpack: append([]byte{0xdc, 0, 16}, make([]byte, 16)...)},
+		"Unicode string":  {json: `"₱"`, msgpack: []byte{0xa3, 0xe2, 0x82, 0xb1}},
+		"Empty object":    {json: `{}`, msgpack: []byte{0x80}},
+		"Nested document": {json: `{"a":{"b":[{}]}}`, msgpack: []byte{0x81, 0xa1, 'a', 0x81, 0xa1, 'b', 0x91, 0x80}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := msgpack.FromJSON([]byte(tc.json))
+			require.NoError(t, err)
+			require.Equal(t, tc.msgpack, encoded)
+
+			decoded, err := msgpack.ToJSON(encoded)
+			require.NoError(t, err)
+
+			if tc.readBack == "" {
+				tc.readBack = tc.json
+			}
+
+			require.JSONEq(t, tc.readBack, string(decoded))
+		})
+	}
+
+	_, err := msgpack.FromJSON([]byte(`{"a":`))
+	require.Error(t, err)
+}
+
+func TestToJSON(t *testing.T) {
+	t.Run("Other formats", func(t *testing.T) {
+		decoded, err := msgpack.ToJSON([]byte{0x94, 0xca, 0x3f, 0x80, 0, 0, 0xd3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe, 0xc4, 0x02, 'h', 'i', 0xe0})
+		require.NoError(t, err)
+		require.JSONEq(t, `[1,-2,"aGk=",-32]`, string(decoded))
+	})
+
+	invalid := map[string][]byte{
+		"Truncated string": {0xa5, 'a'},
+		"Truncated map":    {0x81, 0xa1, 'a'},
+		"Forged length":    {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"Integer key":      {0x81, 0x01, 0x01},
+		"Extension":        {0xd4, 0x01, 0x01},
+		"Trailing bytes":   {0xc0, 0xc0},
+		"Empty":            {},
+	}
+
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := msgpack.ToJSON(data)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("Too deep", func(t *testing.T) {
+		data := append([]byte(strings.Repeat("\x91", 2000)), 0xc0)
+
+		_, err := msgpack.ToJSON(data)
+		require.ErrorIs(t, err, msgpack.ErrTooDeep)
+	})
+}
+
+func TestMarshal(t *testing.T) {
+	type payload struct {
+		ID     string   `json:"id"`
+		Amount string   `json:"amount"`
+		Tags   []string `json:"tags,omitempty"`
+		Count  int      `json:"count"`
+	}
+
+	encoded, err := msgpack.Marshal(payload{ID: "tx1", Amount: "10.50", Tags: []string{"food"}, Count: 300})
+	require.NoError(t, err)
+
+	var decoded payload
+	require.NoError(t, msgpack.Unmarshal(encoded, &decoded))
+	require.Equal(t, payload{ID: "tx1", Amount: "10.50", Tags: []string{"food"}, Count: 300}, decoded)
+
+	require.Error(t, msgpack.Unmarshal([]byte{0xa1}, &decoded))
+}
@@ -0,0 +1,146 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var ErrExhausted = errors.New("retries exhausted")
+
+const (
+	defaultInitialInterval = 100 * time.Millisecond
+	defaultMaxInterval     = 5 * time.Second
+	defaultMultiplier      = 2
+	defaultJitter          = 0.2
+)
+
+// Policy is an exponential backoff. The zero values of MaxElapsed and MaxAttempts mean no limit,
+// so at least one of them, or a context deadline, should be set.
+type Policy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter randomizes each interval by up to this fraction, so the retrying clients don't synchronize.
+	Jitter      float64
+	MaxElapsed  time.Duration
+	MaxAttempts int
+	// OnRetry is called before sleeping for the next attempt, i.e. to log the failure.
+	OnRetry func(attempt int, err error, next time.Duration)
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		InitialInterval: defaultInitialInterval,
+		MaxInterval:     defaultMaxInterval,
+		Multiplier:      defaultMultiplier,
+		Jitter:          defaultJitter,
+	}
+}
+
+func (p Policy) WithMaxElapsed(d time.Duration) Policy {
+	p.MaxElapsed = d
+
+	return p
+}
+
+func (p Policy) WithMaxAttempts(n int) Policy {
+	p.MaxAttempts = n
+
+	return p
+}
+
+func (p Policy) WithOnRetry(fn func(attempt int, err error, next time.Duration)) Policy {
+	p.OnRetry = fn
+
+	return p
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error as not retryable, Do returns it right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the policy gives up, or the context is done.
+// When the policy gives up, the returned error wraps both ErrExhausted and the last error.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	interval := policy.InitialInterval
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrExhausted, attempt, err)
+		}
+
+		next := policy.jittered(interval)
+
+		if policy.MaxElapsed > 0 && time.Since(start)+next > policy.MaxElapsed {
+			return fmt.Errorf("%w after %s: %w", ErrExhausted, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, next)
+		}
+
+		timer := time.NewTimer(next)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+
+		interval = policy.nextInterval(interval)
+	}
+}
+
+func (p Policy) nextInterval(interval time.Duration) time.Duration {
+	next := time.Duration(float64(interval) * max(p.Multiplier, 1))
+
+	if p.MaxInterval > 0 && next > p.MaxInterval {
+		return p.MaxInterval
+	}
+
+	return next
+}
+
+func (p Policy) jittered(interval time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return interval
+	}
+
+	// spreads the interval over [1-jitter, 1+jitter]
+	factor := 1 - p.Jitter + rand.Float64()*2*p.Jitter //nolint:gosec // no need for a secure random here
+
+	return time.Duration(float64(interval) * factor)
+}
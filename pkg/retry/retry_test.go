@@ -0,0 +1,99 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/retry"
+	"github.com/stretchr/testify/require"
+)
+
+var errTransient = errors.New("transient")
+
+func fastPolicy() retry.Policy {
+	return retry.Policy{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     2 * time.Millisecond,
+		Multiplier:      2,
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Run("Succeeds after failures", func(t *testing.T) {
+		attempts := 0
+		retried := []int{}
+
+		policy := fastPolicy().WithMaxAttempts(5).WithOnRetry(func(attempt int, err error, _ time.Duration) {
+			require.ErrorIs(t, err, errTransient)
+
+			retried = append(retried, attempt)
+		})
+
+		err := retry.Do(context.Background(), policy, func(context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errTransient
+			}
+
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+		require.Equal(t, []int{1, 2}, retried)
+	})
+
+	t.Run("Max attempts", func(t *testing.T) {
+		attempts := 0
+
+		err := retry.Do(context.Background(), fastPolicy().WithMaxAttempts(3), func(context.Context) error {
+			attempts++
+
+			return errTransient
+		})
+
+		require.ErrorIs(t, err, retry.ErrExhausted)
+		require.ErrorIs(t, err, errTransient)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("Max elapsed", func(t *testing.T) {
+		err := retry.Do(context.Background(), fastPolicy().WithMaxElapsed(20*time.Millisecond), func(context.Context) error {
+			return errTransient
+		})
+
+		require.ErrorIs(t, err, retry.ErrExhausted)
+	})
+
+	t.Run("Permanent error", func(t *testing.T) {
+		errFatal := errors.New("fatal")
+		attempts := 0
+
+		err := retry.Do(context.Background(), fastPolicy().WithMaxAttempts(5), func(context.Context) error {
+			attempts++
+
+			return retry.Permanent(errFatal)
+		})
+
+		require.ErrorIs(t, err, errFatal)
+		require.NotErrorIs(t, err, retry.ErrExhausted)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		policy := retry.DefaultPolicy().WithOnRetry(func(int, error, time.Duration) {
+			cancel()
+		})
+
+		err := retry.Do(ctx, policy, func(context.Context) error {
+			return errTransient
+		})
+
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorIs(t, err, errTransient)
+	})
+}
@@ -0,0 +1,207 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next is the first time strictly after the given one that the job runs at, or the zero time if it never runs again.
+	Next(after time.Time) time.Time
+}
+
+type interval time.Duration
+
+// Every runs the job at a fixed rate, an interval after its previous scheduled time. A non-positive interval never runs.
+func Every(d time.Duration) Schedule {
+	return interval(d)
+}
+
+func (i interval) Next(after time.Time) time.Time {
+	if i <= 0 {
+		return time.Time{}
+	}
+
+	return after.Add(time.Duration(i))
+}
+
+// how far ahead a cron schedule is searched, i.e. "0 0 30 2 *" never matches
+const cronHorizon = 5
+
+// the bounds of the fields of a cron expression, in their order
+//
+//nolint:gochecknoglobals // constant
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+//nolint:gochecknoglobals // constant
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cron struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// like the cron daemon, a day matches either field when both are restricted, or both of them otherwise
+	eitherDay bool
+}
+
+// Cron parses a schedule of the crontab format, the minute, hour, day of the month, month and day of the week,
+// in the time zone of the times given to Next, i.e. "30 3 * * 1-5" at 3:30 on the weekdays, or "*/15 * * * *".
+// The fields take the lists, the ranges and the steps, and the days of the week are 0 to 7, Sunday being both.
+// The descriptors @yearly, @monthly, @weekly, @daily and @hourly are supported, and "@every 10m" is Every.
+func Cron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if after, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, expr)
+		}
+
+		return Every(d), nil
+	}
+
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: %q, expected 5 fields", ErrInvalidSchedule, expr)
+	}
+
+	bits := make([]uint64, len(fields))
+
+	for i, field := range fields {
+		var err error
+
+		bits[i], err = parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q, %s: %w", ErrInvalidSchedule, expr, cronFields[i].name, err)
+		}
+	}
+
+	// Sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cron{
+		minute:     bits[0],
+		hour:       bits[1],
+		dayOfMonth: bits[2],
+		month:      bits[3],
+		dayOfWeek:  bits[4],
+		eitherDay:  !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField sets a bit by value of the field, i.e. "1-5", "*/15" or "0,30".
+func parseCronField(field string, lowest, highest int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		values, step, stepped := strings.Cut(part, "/")
+
+		increment := 1
+
+		if stepped {
+			var err error
+
+			increment, err = strconv.Atoi(step)
+			if err != nil || increment < 1 {
+				return 0, fmt.Errorf("invalid step %q", step)
+			}
+		}
+
+		from, to := lowest, highest
+
+		switch first, last, ranged := strings.Cut(values, "-"); {
+		case values == "*":
+		case ranged:
+			var err error
+
+			if from, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+
+			if to, err = strconv.Atoi(last); err != nil {
+				return 0, fmt.Errorf("invalid value %q", last)
+			}
+		default:
+			var err error
+
+			if from, err = strconv.Atoi(values); err != nil {
+				return 0, fmt.Errorf("invalid value %q", values)
+			}
+
+			// "5/15" is from 5 to the end
+			if !stepped {
+				to = from
+			}
+		}
+
+		if from < lowest || to > highest || from > to {
+			return 0, fmt.Errorf("%q out of %d-%d", part, lowest, highest)
+		}
+
+		for value := from; value <= to; value += increment {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	horizon := t.AddDate(cronHorizon, 0, 0)
+
+	for t.Before(horizon) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := c.dayOfWeek&(1<<int(t.Weekday())) != 0
+
+	if c.eitherDay {
+		return dayOfMonth || dayOfWeek
+	}
+
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,84 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/scheduler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCron(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, time.May, 1, 10, 17, 42, 0, time.UTC)
+
+	for _, tc := range []struct {
+		expr string
+		next []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2024, time.May, 1, 10, 18, 0, 0, time.UTC),
+			time.Date(2024, time.May, 1, 10, 19, 0, 0, time.UTC),
+		}},
+		{"*/15 * * * *", []time.Time{
+			time.Date(2024, time.May, 1, 10, 30, 0, 0, time.UTC),
+			time.Date(2024, time.May, 1, 10, 45, 0, 0, time.UTC),
+			time.Date(2024, time.May, 1, 11, 0, 0, 0, time.UTC),
+		}},
+		{"30 3 * * 1-5", []time.Time{
+			time.Date(2024, time.May, 2, 3, 30, 0, 0, time.UTC),
+			time.Date(2024, time.May, 3, 3, 30, 0, 0, time.UTC),
+			time.Date(2024, time.May, 6, 3, 30, 0, 0, time.UTC),
+		}},
+		{"0 0,12 1 * *", []time.Time{
+			time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC),
+			time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 29 2 *", []time.Time{
+			time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		// either day when both are restricted
+		{"0 9 15 * 7", []time.Time{
+			time.Date(2024, time.May, 5, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, time.May, 12, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, time.May, 15, 9, 0, 0, 0, time.UTC),
+		}},
+		{"@daily", []time.Time{
+			time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		{"@every 90m", []time.Time{
+			time.Date(2024, time.May, 1, 11, 47, 42, 0, time.UTC),
+		}},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			schedule, err := scheduler.Cron(tc.expr)
+			require.NoError(t, err)
+
+			next := from
+			for _, expected := range tc.next {
+				next = schedule.Next(next)
+				require.Equal(t, expected, next)
+			}
+		})
+	}
+
+	t.Run("Never", func(t *testing.T) {
+		schedule, err := scheduler.Cron("0 0 30 2 *")
+		require.NoError(t, err)
+		require.True(t, schedule.Next(from).IsZero())
+	})
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every -1m", "@sometimes"} {
+		t.Run("Invalid "+expr, func(t *testing.T) {
+			_, err := scheduler.Cron(expr)
+			require.ErrorIs(t, err, scheduler.ErrInvalidSchedule)
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	from := time.Date(2024, time.May, 1, 10, 17, 42, 0, time.UTC)
+
+	require.Equal(t, from.Add(time.Minute), scheduler.Every(time.Minute).Next(from))
+	require.True(t, scheduler.Every(0).Next(from).IsZero())
+}
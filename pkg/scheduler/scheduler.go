@@ -0,0 +1,251 @@
+// Package scheduler runs the background jobs on their schedules, i.e. every minute or at 3:00 every day,
+// each job in its own goroutine so a slow one doesn't delay the others.
+//
+// The runs of a job never overlap: the runs due while the previous one is still running are skipped.
+// A run may be delayed by a random jitter, so the workers started together don't hit the database at once,
+// and a panicking run is recovered and counted as a failure, so it doesn't take the process down.
+// The runs of every job are counted under the scheduler expvar variable, served under /debug/vars.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var ErrPanicked = errors.New("job panicked")
+
+// The counters of the metrics of each job, see jobMetrics.
+const (
+	metricRuns         = "runs"
+	metricFailures     = "failures"
+	metricPanics       = "panics"
+	metricSkipped      = "skipped"
+	metricLastDuration = "last_duration_ms"
+	metricLastSuccess  = "last_success"
+)
+
+//nolint:gochecknoglobals // expvar panics when a name is published twice
+var (
+	schedulerMetricsOnce sync.Once
+	schedulerMetricsMap  *expvar.Map
+	schedulerMetricsMu   sync.Mutex
+)
+
+// jobMetrics is the map of the job in the scheduler expvar variable, i.e.
+// {"ledger-check": {"runs": 24, "failures": 1, "panics": 0, "skipped": 0, "last_duration_ms": 1520, "last_success": "2024-05-01T03:00:01Z"}},
+// shared by the schedulers running a job of the same name.
+func jobMetrics(name string) *expvar.Map {
+	schedulerMetricsOnce.Do(func() {
+		schedulerMetricsMap = expvar.NewMap("scheduler")
+	})
+
+	schedulerMetricsMu.Lock()
+	defer schedulerMetricsMu.Unlock()
+
+	if metrics, ok := schedulerMetricsMap.Get(name).(*expvar.Map); ok {
+		return metrics
+	}
+
+	metrics := new(expvar.Map).Init()
+
+	// published from the start, so the rates can be computed before the first run
+	for _, counter := range []string{metricRuns, metricFailures, metricPanics, metricSkipped, metricLastDuration} {
+		metrics.Add(counter, 0)
+	}
+
+	metrics.Set(metricLastSuccess, new(expvar.String))
+	schedulerMetricsMap.Set(name, metrics)
+
+	return metrics
+}
+
+type jobOptions struct {
+	jitter  time.Duration
+	timeout time.Duration
+}
+
+// JobOption configures a job added to the Scheduler.
+type JobOption func(*jobOptions)
+
+// WithJitter delays each run by a random duration up to the jitter, so the instances sharing a schedule spread their runs.
+func WithJitter(jitter time.Duration) JobOption {
+	return func(o *jobOptions) {
+		if jitter > 0 {
+			o.jitter = jitter
+		}
+	}
+}
+
+// WithTimeout cancels the runs lasting longer than the timeout, so a stuck run doesn't hold back the next ones.
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(o *jobOptions) {
+		if timeout > 0 {
+			o.timeout = timeout
+		}
+	}
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	run      func(ctx context.Context) error
+	options  jobOptions
+	metrics  *expvar.Map
+}
+
+// Scheduler runs the jobs added to it until its context is cancelled.
+type Scheduler struct {
+	jobs   []*job
+	logger *slog.Logger
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		logger: slog.Default(),
+	}
+}
+
+func (s *Scheduler) WithLogger(logger *slog.Logger) *Scheduler {
+	s.logger = logger
+
+	return s
+}
+
+// Add schedules the job under the name, which labels its logs and its metrics. It must be called before Run.
+func (s *Scheduler) Add(name string, schedule Schedule, run func(ctx context.Context) error, opts ...JobOption) *Scheduler {
+	j := &job{
+		name:     name,
+		schedule: schedule,
+		run:      run,
+		metrics:  jobMetrics(name),
+	}
+
+	for _, opt := range opts {
+		opt(&j.options)
+	}
+
+	s.jobs = append(s.jobs, j)
+
+	return s
+}
+
+// Run runs the jobs on their schedules until the context is cancelled, then waits for the runs in progress,
+// whose context is cancelled too, to return. It's a lifecycle.Task.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	for _, j := range s.jobs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			s.loop(ctx, j)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// loop runs the job on its schedule, in UTC. The next run is computed from the time the previous one was due,
+// so a job every hour keeps running on the hour whatever its runs last.
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	due := time.Now().UTC()
+
+	for {
+		next := j.schedule.Next(due)
+
+		// the runs due while the previous one was running are skipped
+		skipped := int64(0)
+
+		for now := time.Now().UTC(); !next.IsZero() && next.Before(now); next = j.schedule.Next(next) {
+			skipped++
+		}
+
+		if skipped > 0 {
+			j.metrics.Add(metricSkipped, skipped)
+			s.logger.WarnContext(ctx, "skipped the runs due while the job was running", slog.String("job", j.name), slog.Int64("skipped", skipped))
+		}
+
+		if next.IsZero() {
+			s.logger.WarnContext(ctx, "job not scheduled anymore", slog.String("job", j.name))
+
+			return
+		}
+
+		due = next
+
+		timer := time.NewTimer(time.Until(next) + jitter(j.options.jitter))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, j)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	runCtx := ctx
+
+	if j.options.timeout > 0 {
+		var cancel context.CancelFunc
+
+		runCtx, cancel = context.WithTimeout(ctx, j.options.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := s.recovered(runCtx, j)
+
+	j.metrics.Add(metricRuns, 1)
+	j.metrics.Get(metricLastDuration).(*expvar.Int).Set(time.Since(start).Milliseconds())
+
+	switch {
+	case err == nil:
+		j.metrics.Get(metricLastSuccess).(*expvar.String).Set(time.Now().UTC().Format(time.RFC3339))
+	case errors.Is(err, ErrPanicked):
+		j.metrics.Add(metricFailures, 1)
+	case ctx.Err() != nil:
+		// the shutdown cancelled the run, it's not a failure
+	default:
+		j.metrics.Add(metricFailures, 1)
+		s.logger.ErrorContext(ctx, "job failed", slog.String("job", j.name), slog.Any("error", err))
+	}
+}
+
+// recovered runs the job, turning its panic into ErrPanicked.
+func (s *Scheduler) recovered(ctx context.Context, j *job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			j.metrics.Add(metricPanics, 1)
+			s.logger.ErrorContext(ctx, "job panicked", slog.String("job", j.name), slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+
+			err = fmt.Errorf("%w: %v", ErrPanicked, p)
+		}
+	}()
+
+	return j.run(ctx)
+}
+
+// jitter is a random delay up to the given one.
+func jitter(upTo time.Duration) time.Duration {
+	if upTo <= 0 {
+		return 0
+	}
+
+	return rand.N(upTo) //nolint:gosec // the jitter only spreads the runs
+}
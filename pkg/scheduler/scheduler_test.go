@@ -0,0 +1,217 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/logging"
+	"github.com/devshark/wallet/pkg/scheduler"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// jobCounter reads a counter of the job from the scheduler expvar variable.
+func jobCounter(t *testing.T, job, name string) int64 {
+	t.Helper()
+
+	metrics, ok := expvar.Get("scheduler").(*expvar.Map)
+	require.True(t, ok)
+
+	jobMetrics, ok := metrics.Get(job).(*expvar.Map)
+	require.True(t, ok, job)
+
+	counter, ok := jobMetrics.Get(name).(*expvar.Int)
+	require.True(t, ok, name)
+
+	return counter.Value()
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("Runs the jobs until the shutdown", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var fast, failing atomic.Int64
+
+		s := scheduler.New().
+			WithLogger(logging.Discard()).
+			Add("test-fast", scheduler.Every(10*time.Millisecond), func(context.Context) error {
+				fast.Add(1)
+
+				return nil
+			}).
+			Add("test-failing", scheduler.Every(10*time.Millisecond), func(context.Context) error {
+				failing.Add(1)
+
+				return errors.New("failed")
+			}, scheduler.WithJitter(5*time.Millisecond))
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- s.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			return fast.Load() >= 3 && failing.Load() >= 3
+		}, time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		require.GreaterOrEqual(t, jobCounter(t, "test-fast", "runs"), int64(3))
+		require.Zero(t, jobCounter(t, "test-fast", "failures"))
+		require.GreaterOrEqual(t, jobCounter(t, "test-failing", "failures"), int64(3), "the failed runs are retried on schedule")
+
+		lastSuccess := expvar.Get("scheduler").(*expvar.Map).Get("test-fast").(*expvar.Map).Get("last_success").(*expvar.String).Value()
+		require.NotEmpty(t, lastSuccess)
+	})
+
+	t.Run("Recovers the panics", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var runs atomic.Int64
+
+		s := scheduler.New().
+			WithLogger(logging.Discard()).
+			Add("test-panicking", scheduler.Every(10*time.Millisecond), func(context.Context) error {
+				runs.Add(1)
+
+				panic("boom")
+			})
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- s.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, 5*time.Millisecond, "still scheduled after a panic")
+
+		cancel()
+		require.NoError(t, <-done)
+
+		require.GreaterOrEqual(t, jobCounter(t, "test-panicking", "panics"), int64(2))
+		require.GreaterOrEqual(t, jobCounter(t, "test-panicking", "failures"), int64(2))
+	})
+
+	t.Run("Skips the runs due while running", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var running, overlapped atomic.Bool
+
+		var runs atomic.Int64
+
+		s := scheduler.New().
+			WithLogger(logging.Discard()).
+			Add("test-slow", scheduler.Every(10*time.Millisecond), func(context.Context) error {
+				if !running.CompareAndSwap(false, true) {
+					overlapped.Store(true)
+				}
+
+				defer running.Store(false)
+
+				time.Sleep(35 * time.Millisecond)
+				runs.Add(1)
+
+				return nil
+			})
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- s.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		require.False(t, overlapped.Load())
+		require.Positive(t, jobCounter(t, "test-slow", "skipped"))
+	})
+
+	t.Run("Cancels the runs past their timeout", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		cancelled := make(chan error, 1)
+
+		s := scheduler.New().
+			WithLogger(logging.Discard()).
+			Add("test-stuck", scheduler.Every(10*time.Millisecond), func(ctx context.Context) error {
+				<-ctx.Done()
+
+				select {
+				case cancelled <- ctx.Err():
+				default:
+				}
+
+				return ctx.Err()
+			}, scheduler.WithTimeout(20*time.Millisecond))
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- s.Run(ctx)
+		}()
+
+		require.ErrorIs(t, <-cancelled, context.DeadlineExceeded)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("Waits for the runs in progress on shutdown", func(t *testing.T) {
+		defer goleak.VerifyNone(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		started := make(chan struct{})
+
+		var returned atomic.Bool
+
+		s := scheduler.New().
+			WithLogger(logging.Discard()).
+			Add("test-shutdown", scheduler.Every(10*time.Millisecond), func(ctx context.Context) error {
+				select {
+				case <-started:
+				default:
+					close(started)
+				}
+
+				<-ctx.Done()
+				time.Sleep(20 * time.Millisecond)
+				returned.Store(true)
+
+				return ctx.Err()
+			})
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- s.Run(ctx)
+		}()
+
+		<-started
+		cancel()
+
+		require.NoError(t, <-done)
+		require.True(t, returned.Load())
+		require.Zero(t, jobCounter(t, "test-shutdown", "failures"), "not a failure")
+	})
+}
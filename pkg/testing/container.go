@@ -0,0 +1,149 @@
+// Package testing provides helpers to run tests against real dependencies i.e. Postgres and Redis.
+// The containers are started with the docker CLI, so there is no need for extra libraries.
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var ErrDockerUnavailable = errors.New("docker is not available")
+
+const (
+	containerStartTimeout = 60 * time.Second
+	readinessInterval     = 250 * time.Millisecond
+)
+
+// ReadinessFunc returns nil once the service listening on addr accepts requests.
+type ReadinessFunc func(ctx context.Context, addr string) error
+
+type containerRequest struct {
+	image     string
+	port      string
+	env       map[string]string
+	args      []string
+	readiness ReadinessFunc
+}
+
+type container struct {
+	id      string
+	addr    string
+	cleanup func()
+}
+
+// startContainer runs the image in the background, publishing the port to a random host port,
+// and blocks until the readiness check passes.
+// The test is skipped if docker is not available, as it is the case inside the compose test container.
+func startContainer(t testing.TB, request containerRequest) *container {
+	t.Helper()
+
+	c, err := runContainer(request)
+	if errors.Is(err, ErrDockerUnavailable) {
+		t.Skip("Skipping test, as docker is not available")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(c.cleanup)
+
+	return c
+}
+
+// runContainer is the test-independent part of startContainer, so containers can outlive a single test.
+func runContainer(request containerRequest) (*container, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, ErrDockerUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerStartTimeout)
+	defer cancel()
+
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + request.port}
+	for key, value := range request.env {
+		args = append(args, "--env", key+"="+value)
+	}
+
+	args = append(args, request.image)
+	args = append(args, request.args...)
+
+	output, err := exec.CommandContext(ctx, "docker", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start container %s: %w", request.image, describeExecError(err))
+	}
+
+	id := strings.TrimSpace(string(output))
+
+	var once sync.Once
+
+	c := &container{
+		id: id,
+		cleanup: func() {
+			once.Do(func() {
+				// use a fresh context, the test context may have been cancelled already
+				_ = exec.Command("docker", "rm", "--force", id).Run() //nolint:noctx // best effort
+			})
+		},
+	}
+
+	output, err = exec.CommandContext(ctx, "docker", "port", id, request.port).Output()
+	if err != nil {
+		c.cleanup()
+
+		return nil, fmt.Errorf("failed to get published port of container %s: %w", request.image, describeExecError(err))
+	}
+
+	// docker may print one line per address family, the first one is enough
+	c.addr = strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0]) //nolint:mnd // first line only
+
+	if err = waitReady(ctx, c.addr, request.readiness); err != nil {
+		c.cleanup()
+
+		return nil, fmt.Errorf("container %s did not become ready: %w", request.image, err)
+	}
+
+	return c, nil
+}
+
+func waitReady(ctx context.Context, addr string, readiness ReadinessFunc) error {
+	ticker := time.NewTicker(readinessInterval)
+	defer ticker.Stop()
+
+	for {
+		err := readiness(ctx, addr)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: last error: %w", ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func describeExecError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return err
+}
+
+// lookupAddress returns the address from the environment, for setups that already provide the dependency
+// i.e. docker compose.
+func lookupAddress(key string) (string, bool) {
+	value, exists := os.LookupEnv(key)
+
+	return value, exists && value != ""
+}
@@ -0,0 +1,75 @@
+package testing
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/devshark/wallet/pkg/idgen"
+)
+
+// FakeClock is a clock.Clock that only moves when told to.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock frozen at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to the given time.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// SequentialIDs is an idgen.Generator returning valid, predictable UUIDs:
+// 00000000-0000-4000-8000-000000000001, 00000000-0000-4000-8000-000000000002, and so on.
+type SequentialIDs struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func NewSequentialIDs() *SequentialIDs {
+	return &SequentialIDs{next: 1}
+}
+
+func (g *SequentialIDs) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := SequentialID(g.next)
+	g.next++
+
+	return id
+}
+
+// SequentialID returns the n-th id produced by SequentialIDs, to be used in assertions.
+func SequentialID(n uint64) string {
+	var uuid [16]byte
+
+	uuid[6] = 0x40 // version 4
+	uuid[8] = 0x80 // variant 10
+	binary.BigEndian.PutUint64(uuid[8:], n|0x8000000000000000)
+
+	return idgen.Format(uuid)
+}
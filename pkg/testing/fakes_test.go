@@ -0,0 +1,35 @@
+package testing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/idgen"
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	var fakeClock clock.Clock = wallettesting.NewFakeClock(start)
+	require.Equal(t, start, fakeClock.Now())
+
+	fake, ok := fakeClock.(*wallettesting.FakeClock)
+	require.True(t, ok)
+
+	fake.Advance(time.Hour)
+	require.Equal(t, start.Add(time.Hour), fakeClock.Now())
+
+	fake.Set(start)
+	require.Equal(t, start, fakeClock.Now())
+}
+
+func TestSequentialIDs(t *testing.T) {
+	var generator idgen.Generator = wallettesting.NewSequentialIDs()
+
+	require.Equal(t, "00000000-0000-4000-8000-000000000001", generator.NewID())
+	require.Equal(t, "00000000-0000-4000-8000-000000000002", generator.NewID())
+	require.Equal(t, wallettesting.SequentialID(3), generator.NewID())
+}
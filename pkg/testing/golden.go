@@ -0,0 +1,217 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// UpdateGoldenEnv rewrites the golden files with the actual responses instead of comparing them.
+// i.e. UPDATE_GOLDEN=1 go test ./app/rest/...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+const goldenFileMode = 0o600
+
+type goldenOptions struct {
+	ignoredFields map[string]struct{}
+}
+
+type GoldenOption func(*goldenOptions)
+
+// IgnoreFields skips the object fields with the given names at any depth,
+// for generated values like ids and timestamps.
+func IgnoreFields(fields ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		for _, field := range fields {
+			o.ignoredFields[field] = struct{}{}
+		}
+	}
+}
+
+// AssertGoldenResponse asserts the status code and the JSON body of the recorded response against the golden file.
+func AssertGoldenResponse(t testing.TB, rr *httptest.ResponseRecorder, expectedStatus int, goldenFile string, opts ...GoldenOption) {
+	t.Helper()
+
+	if rr.Code != expectedStatus {
+		t.Fatalf("expected status %d, got %d: %s", expectedStatus, rr.Code, rr.Body.String())
+	}
+
+	AssertGoldenJSON(t, goldenFile, rr.Body.Bytes(), opts...)
+}
+
+// AssertGoldenJSON compares the actual JSON with the content of the golden file.
+// Numbers, and strings holding numbers like decimal.Decimal, are compared by their decimal value,
+// so "75" and "75.00" are equal. Object keys order and whitespace do not matter.
+func AssertGoldenJSON(t testing.TB, goldenFile string, actual []byte, opts ...GoldenOption) {
+	t.Helper()
+
+	o := &goldenOptions{ignoredFields: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		writeGolden(t, goldenFile, actual)
+	}
+
+	expected, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s, run with %s=1 to create it: %v", goldenFile, UpdateGoldenEnv, err)
+	}
+
+	expectedValue, err := decodeJSON(expected)
+	if err != nil {
+		t.Fatalf("invalid JSON in golden file %s: %v", goldenFile, err)
+	}
+
+	actualValue, err := decodeJSON(actual)
+	if err != nil {
+		t.Fatalf("invalid JSON in actual response: %v: %s", err, actual)
+	}
+
+	if diffs := compareJSON("$", expectedValue, actualValue, o); len(diffs) > 0 {
+		t.Fatalf("response does not match golden file %s:\n%s\nactual: %s", goldenFile, strings.Join(diffs, "\n"), actual)
+	}
+}
+
+func writeGolden(t testing.TB, goldenFile string, actual []byte) {
+	t.Helper()
+
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, actual, "", "  "); err != nil {
+		t.Fatalf("invalid JSON in actual response: %v", err)
+	}
+
+	indented.WriteByte('\n')
+
+	if err := os.MkdirAll(filepath.Dir(goldenFile), 0o750); err != nil {
+		t.Fatalf("failed to create golden directory: %v", err)
+	}
+
+	if err := os.WriteFile(goldenFile, indented.Bytes(), goldenFileMode); err != nil {
+		t.Fatalf("failed to update golden file %s: %v", goldenFile, err)
+	}
+}
+
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	return value, nil
+}
+
+func compareJSON(path string, expected, actual any, o *goldenOptions) []string {
+	switch expectedValue := expected.(type) {
+	case map[string]any:
+		actualValue, ok := actual.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %v", path, actual)}
+		}
+
+		return compareObjects(path, expectedValue, actualValue, o)
+	case []any:
+		actualValue, ok := actual.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %v", path, actual)}
+		}
+
+		if len(expectedValue) != len(actualValue) {
+			return []string{fmt.Sprintf("%s: expected %d items, got %d", path, len(expectedValue), len(actualValue))}
+		}
+
+		diffs := []string{}
+		for i := range expectedValue {
+			diffs = append(diffs, compareJSON(fmt.Sprintf("%s[%d]", path, i), expectedValue[i], actualValue[i], o)...)
+		}
+
+		return diffs
+	default:
+		if scalarEqual(expected, actual) {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%s: expected %v, got %v", path, expected, actual)}
+	}
+}
+
+func compareObjects(path string, expected, actual map[string]any, o *goldenOptions) []string {
+	keys := map[string]struct{}{}
+	for key := range expected {
+		keys[key] = struct{}{}
+	}
+
+	for key := range actual {
+		keys[key] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		if _, ignored := o.ignoredFields[key]; !ignored {
+			sorted = append(sorted, key)
+		}
+	}
+
+	sort.Strings(sorted)
+
+	diffs := []string{}
+
+	for _, key := range sorted {
+		expectedValue, inExpected := expected[key]
+		actualValue, inActual := actual[key]
+
+		switch {
+		case !inExpected:
+			diffs = append(diffs, fmt.Sprintf("%s.%s: unexpected field with value %v", path, key, actualValue))
+		case !inActual:
+			diffs = append(diffs, fmt.Sprintf("%s.%s: missing field, expected %v", path, key, expectedValue))
+		default:
+			diffs = append(diffs, compareJSON(path+"."+key, expectedValue, actualValue, o)...)
+		}
+	}
+
+	return diffs
+}
+
+func scalarEqual(expected, actual any) bool {
+	expectedDecimal, expectedIsDecimal := asDecimal(expected)
+	actualDecimal, actualIsDecimal := asDecimal(actual)
+
+	if expectedIsDecimal && actualIsDecimal {
+		return expectedDecimal.Equal(actualDecimal)
+	}
+
+	return expected == actual
+}
+
+func asDecimal(value any) (decimal.Decimal, bool) {
+	var raw string
+
+	switch v := value.(type) {
+	case json.Number:
+		raw = v.String()
+	case string:
+		raw = v
+	default:
+		return decimal.Zero, false
+	}
+
+	parsed, err := decimal.NewFromString(raw)
+	if err != nil {
+		return decimal.Zero, false
+	}
+
+	return parsed, true
+}
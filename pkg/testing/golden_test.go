@@ -0,0 +1,104 @@
+package testing_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB records the failure instead of failing the real test.
+type fakeTB struct {
+	testing.TB
+	failure string
+}
+
+type fatalCalled struct{}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.failure = fmt.Sprintf(format, args...)
+
+	panic(fatalCalled{})
+}
+
+func runFake(fn func(tb testing.TB)) (failure string) { //nolint:nonamedreturns // needed by recover
+	fake := &fakeTB{}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(fatalCalled); !ok {
+				panic(r)
+			}
+
+			failure = fake.failure
+		}
+	}()
+
+	fn(fake)
+
+	return fake.failure
+}
+
+func TestAssertGoldenJSON(t *testing.T) {
+	// the golden files of these tests must never be updated
+	t.Setenv(wallettesting.UpdateGoldenEnv, "")
+
+	t.Run("Decimal aware and ignored fields", func(t *testing.T) {
+		wallettesting.AssertGoldenJSON(t, "testdata/account.golden.json",
+			[]byte(`{"balance":"100.5","currency":"USD","account":"user1","tx_id":"random"}`),
+			wallettesting.IgnoreFields("tx_id"))
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		failure := runFake(func(tb testing.TB) {
+			wallettesting.AssertGoldenJSON(tb, "testdata/account.golden.json",
+				[]byte(`{"balance":"100.51","currency":"USD","account":"user1","extra":true}`))
+		})
+
+		require.Contains(t, failure, "$.balance: expected 100.50, got 100.51")
+		require.Contains(t, failure, "$.extra: unexpected field")
+		require.Contains(t, failure, "$.tx_id: missing field")
+	})
+
+	t.Run("Missing golden file", func(t *testing.T) {
+		failure := runFake(func(tb testing.TB) {
+			wallettesting.AssertGoldenJSON(tb, "testdata/missing.golden.json", []byte(`{}`))
+		})
+
+		require.Contains(t, failure, "UPDATE_GOLDEN=1")
+	})
+
+	t.Run("Update golden file", func(t *testing.T) {
+		t.Setenv(wallettesting.UpdateGoldenEnv, "1")
+
+		goldenFile := filepath.Join(t.TempDir(), "new.golden.json")
+		wallettesting.AssertGoldenJSON(t, goldenFile, []byte(`[{"amount":"1"}]`))
+
+		content, err := os.ReadFile(goldenFile)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"amount":"1"}]`, string(content))
+	})
+}
+
+func TestAssertGoldenResponse(t *testing.T) {
+	t.Setenv(wallettesting.UpdateGoldenEnv, "")
+
+	rr := httptest.NewRecorder()
+	rr.WriteHeader(http.StatusTeapot)
+	_, _ = rr.WriteString(`{"account":"user1","currency":"USD","balance":100.5,"tx_id":"generated"}`)
+
+	wallettesting.AssertGoldenResponse(t, rr, http.StatusTeapot, "testdata/account.golden.json")
+
+	failure := runFake(func(tb testing.TB) {
+		wallettesting.AssertGoldenResponse(tb, rr, http.StatusOK, "testdata/account.golden.json")
+	})
+
+	require.Contains(t, failure, "expected status 200, got 418")
+}
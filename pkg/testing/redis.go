@@ -0,0 +1,58 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// RedisAddressEnv skips starting a container and uses the given redis instead.
+	RedisAddressEnv = "TEST_REDIS_ADDRESS"
+
+	redisImage = "redis:7-alpine"
+	redisPort  = "6379/tcp"
+)
+
+// SetupTestRedis starts a disposable Redis container and returns its address and a cleanup function.
+// The cleanup is also registered with t.Cleanup, so calling it is only needed to stop the container early.
+// If TEST_REDIS_ADDRESS is set, that instance is flushed and used instead of a container.
+func SetupTestRedis(t testing.TB) (string, func()) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("Skipping test in short mode, as it requires redis")
+	}
+
+	if addr, ok := lookupAddress(RedisAddressEnv); ok {
+		client := redis.NewClient(&redis.Options{Addr: addr})
+		defer client.Close()
+
+		if err := client.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatalf("failed to flush redis at %s: %v", addr, err)
+		}
+
+		return addr, func() {}
+	}
+
+	c := startContainer(t, containerRequest{
+		image:     redisImage,
+		port:      redisPort,
+		readiness: pingRedis,
+	})
+
+	return c.addr, c.cleanup
+}
+
+func pingRedis(ctx context.Context, addr string) error {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package testing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+//nolint:gochecknoglobals // one container per test binary is the whole point of the shared mode
+var sharedPostgres = &sharedContainer{}
+
+// sharedContainer lazily starts a single container and keeps it until the test binary exits.
+type sharedContainer struct {
+	mu      sync.Mutex
+	enabled bool
+	c       *container
+	err     error
+}
+
+// RunShared is a TestMain helper that enables the shared mode for the test package:
+// SetupTestDB and SetupTemplateDB reuse one Postgres container, started on first use,
+// and hand out isolated databases on it instead of starting a container per test.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(wallettesting.RunShared(m))
+//	}
+func RunShared(m *testing.M) int {
+	sharedPostgres.enable()
+	defer sharedPostgres.close()
+
+	return m.Run()
+}
+
+func (s *sharedContainer) enable() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enabled = true
+}
+
+func (s *sharedContainer) isEnabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.enabled
+}
+
+func (s *sharedContainer) get(t testing.TB, request containerRequest) *container {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.c == nil && s.err == nil {
+		s.c, s.err = runContainer(request)
+	}
+
+	if errors.Is(s.err, ErrDockerUnavailable) {
+		t.Skip("Skipping test, as docker is not available")
+	}
+
+	if s.err != nil {
+		t.Fatal(s.err)
+	}
+
+	return s.c
+}
+
+func (s *sharedContainer) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.c != nil {
+		s.c.cleanup()
+	}
+
+	s.c = nil
+	s.err = nil
+	s.enabled = false
+}
+
+// createIsolatedDatabase creates an empty database on the server, dropped when the test ends.
+func createIsolatedDatabase(t testing.TB, serverDSN string) string {
+	t.Helper()
+
+	name, err := randomDatabaseName("wallet_test_")
+	if err != nil {
+		t.Fatalf("failed to generate database name: %v", err)
+	}
+
+	adminDB := openDB(t, serverDSN)
+
+	if _, err = adminDB.ExecContext(context.Background(), "CREATE DATABASE "+pq.QuoteIdentifier(name)); err != nil {
+		_ = adminDB.Close()
+
+		t.Fatalf("failed to create database %s: %v", name, err)
+	}
+
+	t.Cleanup(func() {
+		dropDatabase(adminDB, name)
+
+		_ = adminDB.Close()
+	})
+
+	dsn, err := withDatabase(serverDSN, name)
+	if err != nil {
+		t.Fatalf("failed to build dsn: %v", err)
+	}
+
+	return dsn
+}
+
+func dropDatabase(adminDB *sql.DB, name string) {
+	_, _ = adminDB.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+pq.QuoteIdentifier(name)+" WITH (FORCE)")
+}
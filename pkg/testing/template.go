@@ -0,0 +1,167 @@
+package testing
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+const (
+	databaseNameBytes = 8
+)
+
+// MigrateFunc prepares the schema of the template database.
+type MigrateFunc func(ctx context.Context, db *sql.DB) error
+
+// TemplateDB hands out disposable databases cloned from a migrated template database,
+// so integration tests can run in parallel without cleaning up after each other.
+type TemplateDB struct {
+	adminDB  *sql.DB
+	dsn      string
+	template string
+
+	// CREATE DATABASE fails if the template is used by another CREATE DATABASE at the same time
+	mu sync.Mutex
+}
+
+// SetupTemplateDB starts Postgres, creates the template database and runs migrate against it once.
+func SetupTemplateDB(t testing.TB, migrate MigrateFunc) *TemplateDB {
+	t.Helper()
+
+	dsn, containerCleanup := startPostgres(t)
+	t.Cleanup(containerCleanup)
+
+	templateDB, err := newTemplateDB(context.Background(), dsn, migrate)
+	if err != nil {
+		t.Fatalf("failed to create template database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = templateDB.Close()
+	})
+
+	return templateDB
+}
+
+func newTemplateDB(ctx context.Context, dsn string, migrate MigrateFunc) (*TemplateDB, error) {
+	adminDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// the name is random, as test packages running in parallel may share the same server
+	template, err := randomDatabaseName("wallet_template_")
+	if err != nil {
+		_ = adminDB.Close()
+
+		return nil, err
+	}
+
+	templateDB := &TemplateDB{
+		adminDB:  adminDB,
+		dsn:      dsn,
+		template: template,
+	}
+
+	if err = templateDB.create(ctx, template, ""); err != nil {
+		_ = adminDB.Close()
+
+		return nil, err
+	}
+
+	templateDSN, err := withDatabase(dsn, template)
+	if err != nil {
+		_ = adminDB.Close()
+
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", templateDSN)
+	if err != nil {
+		_ = adminDB.Close()
+
+		return nil, fmt.Errorf("failed to open template database: %w", err)
+	}
+
+	// the template must not have open connections when it is cloned
+	defer db.Close()
+
+	if err = migrate(ctx, db); err != nil {
+		_ = adminDB.Close()
+
+		return nil, fmt.Errorf("failed to migrate template database: %w", err)
+	}
+
+	return templateDB, nil
+}
+
+// NewDatabase clones the template into a new database, which is dropped when the test ends.
+func (d *TemplateDB) NewDatabase(t testing.TB) *sql.DB {
+	t.Helper()
+
+	name, err := randomDatabaseName("wallet_test_")
+	if err != nil {
+		t.Fatalf("failed to generate database name: %v", err)
+	}
+
+	if err = d.create(context.Background(), name, d.template); err != nil {
+		t.Fatalf("failed to create database from template: %v", err)
+	}
+
+	dsn, err := withDatabase(d.dsn, name)
+	if err != nil {
+		t.Fatalf("failed to build dsn: %v", err)
+	}
+
+	db := openDB(t, dsn)
+
+	t.Cleanup(func() {
+		_ = db.Close()
+
+		dropDatabase(d.adminDB, name)
+	})
+
+	return db
+}
+
+// Close drops the template and releases the admin connection. The cloned databases are dropped by their own tests.
+func (d *TemplateDB) Close() error {
+	dropDatabase(d.adminDB, d.template)
+
+	if err := d.adminDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	return nil
+}
+
+func (d *TemplateDB) create(ctx context.Context, name, template string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statement := "CREATE DATABASE " + pq.QuoteIdentifier(name)
+	if template != "" {
+		statement += " TEMPLATE " + pq.QuoteIdentifier(template)
+	}
+
+	if _, err := d.adminDB.ExecContext(ctx, statement); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func randomDatabaseName(prefix string) (string, error) {
+	buf := make([]byte, databaseNameBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+
+	return prefix + hex.EncodeToString(buf), nil
+}
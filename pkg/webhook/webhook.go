@@ -0,0 +1,194 @@
+// Package webhook posts signed JSON payloads, and verifies their signature on the receiving side.
+//
+// The signature header is "t=<unix timestamp>,v1=<hex HMAC-SHA256 of timestamp.body>", so the receivers can reject
+// the replayed payloads by their timestamp, and the forged ones by their signature. While a secret is rotated,
+// the header carries a v1 signature by secret, and the receivers accept any of them.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devshark/wallet/pkg/clock"
+	"github.com/devshark/wallet/pkg/crypt"
+)
+
+var (
+	ErrDeliveryFailed   = errors.New("webhook delivery failed")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+const (
+	// SignatureHeader carries the signature of the payload.
+	SignatureHeader = "X-Wallet-Signature"
+
+	// DefaultTimeout bounds a delivery, the receivers are expected to acknowledge before processing.
+	DefaultTimeout = 10 * time.Second
+
+	// SecretPrefix makes the secrets recognizable in logs and secret scanners.
+	SecretPrefix = "whsec_"
+
+	signatureVersion = "v1"
+	secretBytes      = 32
+)
+
+// Sender posts the payloads signed with its secret.
+type Sender struct {
+	client *http.Client
+	secret []byte
+	clock  clock.Clock
+}
+
+func NewSender(secret []byte) *Sender {
+	return &Sender{
+		client: &http.Client{Timeout: DefaultTimeout},
+		secret: secret,
+		clock:  clock.NewSystemClock(),
+	}
+}
+
+// WithHTTPClient overrides the client posting the payloads, i.e. for its transport or its timeout.
+func (s *Sender) WithHTTPClient(client *http.Client) *Sender {
+	s.client = client
+
+	return s
+}
+
+// WithClock overrides the clock used for the signature timestamps.
+func (s *Sender) WithClock(c clock.Clock) *Sender {
+	s.clock = c
+
+	return s
+}
+
+// Post sends the payload as JSON to the url, signed with the secrets, or the secret of the sender without any.
+// A nil secret is the secret of the sender. Any status other than 2xx is a failed delivery.
+func (s *Sender) Post(ctx context.Context, url string, payload any, secrets ...[]byte) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	if len(secrets) == 0 {
+		secrets = [][]byte{nil}
+	}
+
+	signing := make([][]byte, 0, len(secrets))
+
+	for _, secret := range secrets {
+		if secret == nil {
+			secret = s.secret
+		}
+
+		signing = append(signing, secret)
+	}
+
+	request.Header.Set(SignatureHeader, SignAll(signing, s.clock.Now(), body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeliveryFailed, err)
+	}
+
+	defer response.Body.Close()
+
+	// drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%w: %s responded %d", ErrDeliveryFailed, url, response.StatusCode)
+	}
+
+	return nil
+}
+
+// GenerateSecret returns a new random signing secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretBytes)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Sign returns the signature header of the body sent at the time.
+func Sign(secret []byte, at time.Time, body []byte) string {
+	return SignAll([][]byte{secret}, at, body)
+}
+
+// SignAll returns the signature header of the body sent at the time, with a signature by secret,
+// i.e. the new and the previous secret while it's rotated.
+func SignAll(secrets [][]byte, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	header := "t=" + timestamp
+
+	for _, secret := range secrets {
+		header += "," + signatureVersion + "=" + signer(secret, timestamp, body).HexSum()
+	}
+
+	return header
+}
+
+// Verify checks the signature header of the body, which must have been sent within the tolerance of now.
+// One of the signatures of the header must be by the secret.
+func Verify(secret []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var (
+		timestamp string
+		signed    []string
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "t":
+			timestamp = value
+		case signatureVersion:
+			signed = append(signed, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signed) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: sent at %s", ErrInvalidSignature, time.Unix(seconds, 0).UTC())
+	}
+
+	for _, signature := range signed {
+		if signer(secret, timestamp, body).Equal(signature) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// signer hashes the signed content, the timestamp is signed with the body so it can't be replaced.
+func signer(secret []byte, timestamp string, body []byte) *crypt.StreamHasher {
+	hasher := crypt.NewHMACHasher(secret)
+	_, _ = hasher.Write([]byte(timestamp + "."))
+	_, _ = hasher.Write(body)
+
+	return hasher
+}
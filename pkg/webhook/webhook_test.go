@@ -0,0 +1,75 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	wallettesting "github.com/devshark/wallet/pkg/testing"
+	"github.com/devshark/wallet/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSender(t *testing.T) {
+	secret := []byte("whsec")
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	received := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		received <- webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, now.Add(time.Minute), 5*time.Minute)
+
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sender := webhook.NewSender(secret).WithClock(wallettesting.NewFakeClock(now))
+
+	t.Run("Signed", func(t *testing.T) {
+		require.NoError(t, sender.Post(context.Background(), server.URL, map[string]string{"id": "1"}))
+		require.NoError(t, <-received)
+	})
+
+	t.Run("Signed while rotated", func(t *testing.T) {
+		// the previous secret is the one of the sender, the receiver still verifies with it
+		require.NoError(t, sender.Post(context.Background(), server.URL, map[string]string{"id": "1"}, []byte("rotated"), nil))
+		require.NoError(t, <-received)
+	})
+
+	t.Run("Failed delivery", func(t *testing.T) {
+		err := sender.Post(context.Background(), server.URL+"/unavailable", map[string]string{"id": "1"})
+		require.ErrorIs(t, err, webhook.ErrDeliveryFailed)
+		<-received
+	})
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("whsec")
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"1"}`)
+	header := webhook.Sign(secret, now, body)
+
+	require.NoError(t, webhook.Verify(secret, header, body, now, time.Minute))
+
+	require.ErrorIs(t, webhook.Verify([]byte("other"), header, body, now, time.Minute), webhook.ErrInvalidSignature)
+	require.ErrorIs(t, webhook.Verify(secret, header, []byte(`{"id":"2"}`), now, time.Minute), webhook.ErrInvalidSignature)
+	require.ErrorIs(t, webhook.Verify(secret, header, body, now.Add(time.Hour), time.Minute), webhook.ErrInvalidSignature, "replayed")
+	require.ErrorIs(t, webhook.Verify(secret, "v1=abc", body, now, time.Minute), webhook.ErrInvalidSignature)
+}
+
+func TestVerifyRotated(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"1"}`)
+	header := webhook.SignAll([][]byte{[]byte("new"), []byte("old")}, now, body)
+
+	require.NoError(t, webhook.Verify([]byte("new"), header, body, now, time.Minute))
+	require.NoError(t, webhook.Verify([]byte("old"), header, body, now, time.Minute), "during the grace period")
+	require.ErrorIs(t, webhook.Verify([]byte("other"), header, body, now, time.Minute), webhook.ErrInvalidSignature)
+}